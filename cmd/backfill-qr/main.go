@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/repository"
+)
+
+// backfill-qr stores the default QR payload for every device that doesn't have one yet
+func main() {
+	configPath := flag.String("config", "config.json", "path to the configuration file")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := repository.NewDatabase(&cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	updated, err := repository.NewDeviceRepository(db, &cfg.Devices).BackfillQRCodes()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	log.Printf("Assigned QR codes to %d device(s)", updated)
+}
@@ -4,21 +4,30 @@ import (
 	"encoding/json"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 	
+	applog "go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/models"
+
 	"gorm.io/gorm/logger"
 )
 
 type Config struct {
-	Database DatabaseConfig `json:"database"`
-	Server   ServerConfig   `json:"server"`
-	UI       UIConfig       `json:"ui"`
-	Email    EmailConfig    `json:"email"`
-	Invoice  InvoiceConfig  `json:"invoice"`
-	PDF      PDFConfig      `json:"pdf"`
-	Security SecurityConfig `json:"security"`
-	Logging  LoggingConfig  `json:"logging"`
-	Backup   BackupConfig   `json:"backup"`
+	Database  DatabaseConfig  `json:"database"`
+	Server    ServerConfig    `json:"server"`
+	UI        UIConfig        `json:"ui"`
+	Email     EmailConfig     `json:"email"`
+	Push      PushConfig      `json:"push"`
+	Invoice   InvoiceConfig   `json:"invoice"`
+	Jobs      JobsConfig      `json:"jobs"`
+	Devices   DevicesConfig   `json:"devices"`
+	Documents DocumentsConfig `json:"documents"`
+	Analytics AnalyticsConfig `json:"analytics"`
+	PDF       PDFConfig       `json:"pdf"`
+	Security  SecurityConfig  `json:"security"`
+	Logging   LoggingConfig   `json:"logging"`
+	Backup    BackupConfig    `json:"backup"`
 }
 
 type DatabaseConfig struct {
@@ -37,6 +46,7 @@ type DatabaseConfig struct {
 	LogLevel              logger.LogLevel `json:"-"` // Not serializable
 	PrepareStmt           bool          `json:"prepare_stmt"`
 	DisableForeignKeyConstraintWhenMigrating bool `json:"disable_fk_when_migrating"`
+	RequireCurrentSchema  bool          `json:"require_current_schema"` // Refuse to start when migrations are missing
 }
 
 type ServerConfig struct {
@@ -66,6 +76,15 @@ type EmailConfig struct {
 	UseTLS       bool   `json:"use_tls"`
 }
 
+// PushConfig holds the VAPID key pair push notifications are signed with,
+// base64url encoded as generated by common Web Push tools
+type PushConfig struct {
+	VAPIDPublicKey  string `json:"vapid_public_key"`
+	VAPIDPrivateKey string `json:"vapid_private_key"`
+	Subject         string `json:"subject"`           // mailto: or https: contact for push services
+	JobReminderTime string `json:"job_reminder_time"` // Daily HH:MM to notify about jobs ending tomorrow, empty disables
+}
+
 type InvoiceConfig struct {
 	DefaultTaxRate          float64 `json:"default_tax_rate"`
 	DefaultPaymentTerms     int     `json:"default_payment_terms"`
@@ -75,21 +94,64 @@ type InvoiceConfig struct {
 	InvoiceNumberFormat     string  `json:"invoice_number_format"`
 	CurrencySymbol          string  `json:"currency_symbol"`
 	CurrencyCode            string  `json:"currency_code"`
+	CurrencyDecimals        *int    `json:"currency_decimals"` // Report rounding; defaults to the currency's minor units
 	DateFormat              string  `json:"date_format"`
+	RemindersEnabled        bool    `json:"reminders_enabled"`
+	ReminderIntervals       []int   `json:"reminder_intervals"` // Days after due date, then between reminders
+	RecipientContactRole    string  `json:"recipient_contact_role"` // Customer contact role invoices are sent to
+}
+
+// ReportCurrency returns the currency reports are rounded and labelled in,
+// euros without invoice settings
+func (c *InvoiceConfig) ReportCurrency() models.Currency {
+	if c == nil {
+		return models.NewCurrency("EUR", "€", -1)
+	}
+	decimals := -1
+	if c.CurrencyDecimals != nil {
+		decimals = *c.CurrencyDecimals
+	}
+	return models.NewCurrency(c.CurrencyCode, c.CurrencySymbol, decimals)
+}
+
+type JobsConfig struct {
+	DefaultDiscountType string `json:"default_discount_type"` // "amount" or "percent"
+	MaxRentalDays       int    `json:"max_rental_days"`       // 0 disables the limit
+	ArchiveAfterYears   int    `json:"archive_after_years"`   // Archive completed jobs older than this, 0 disables archiving
+	RentalDayCounting   string `json:"rental_day_counting"`   // "calendar" or "business_days" (skips weekends and company holidays)
+}
+
+type DevicesConfig struct {
+	AutoAssignQRCode bool `json:"auto_assign_qr_code"` // Store a QR payload for new devices
+}
+
+type DocumentsConfig struct {
+	MaxUploadSizeMB int `json:"max_upload_size_mb"` // Largest document accepted for upload
+}
+
+type AnalyticsConfig struct {
+	UtilizationTolerance float64 `json:"utilization_tolerance"` // Percentage points below target before a category counts as over-stocked
+	UtilizationSaturated float64 `json:"utilization_saturated"` // Utilization at which a category counts as under-stocked
 }
 
 type PDFConfig struct {
-	Generator string            `json:"generator"`
+	Generator string            `json:"generator"` // auto, or the engines to use in order: chrome, wkhtmltopdf, gofpdf (comma-separated)
 	PaperSize string            `json:"paper_size"`
 	Margins   map[string]string `json:"margins"`
+	Timeout   int               `json:"timeout"` // Seconds Chrome or wkhtmltopdf may take per document before being killed
 }
 
 type SecurityConfig struct {
 	SessionTimeout    int    `json:"session_timeout"`
+	RememberMeTimeout int    `json:"remember_me_timeout"` // Lifetime of "remember me" sessions in seconds, 0 disables the option
 	PasswordMinLength int    `json:"password_min_length"`
 	MaxLoginAttempts  int    `json:"max_login_attempts"`
 	LockoutDuration   int    `json:"lockout_duration"`
 	EncryptionKey     string `json:"encryption_key"`
+	DefaultUserRole   string `json:"default_user_role"` // Role assigned to newly created users, empty to assign none
+	SlidingSessions   bool   `json:"sliding_sessions"`  // Extend sessions on activity instead of expiring them a fixed time after sign-in
+	AuditRetentionDays     int  `json:"audit_retention_days"`      // Purge audit log entries older than this daily, 0 keeps them forever
+	AuditKeepAccessChanges bool `json:"audit_keep_access_changes"` // Exempt role and role assignment changes from purging
 }
 
 type LoggingConfig struct {
@@ -126,6 +188,20 @@ func LoadConfig(path string) (*Config, error) {
 		loadFromEnvironment(config)
 	}
 
+	// Debug output stays off unless the configured level asks for it
+	applog.SetLevel(applog.ParseLogLevel(config.Logging.Level))
+
+	discountType, err := models.NormalizeDiscountType(config.Jobs.DefaultDiscountType, models.DiscountTypeAmount)
+	if err != nil {
+		return nil, err
+	}
+	config.Jobs.DefaultDiscountType = discountType
+	rentalDayCounting, err := models.NormalizeRentalDayCounting(config.Jobs.RentalDayCounting)
+	if err != nil {
+		return nil, err
+	}
+	config.Jobs.RentalDayCounting = rentalDayCounting
+
 	return config, nil
 }
 
@@ -194,6 +270,9 @@ func getDefaultConfig() *Config {
 			FromName:     "RentalCore",
 			UseTLS:       true,
 		},
+		Push: PushConfig{
+			JobReminderTime: "08:00",
+		},
 		Invoice: InvoiceConfig{
 			DefaultTaxRate:          19.0,
 			DefaultPaymentTerms:     30,
@@ -204,10 +283,28 @@ func getDefaultConfig() *Config {
 			CurrencySymbol:          "€",
 			CurrencyCode:            "EUR",
 			DateFormat:              "DD.MM.YYYY",
+			RemindersEnabled:        false,
+			ReminderIntervals:       []int{7, 14, 14},
+			RecipientContactRole:    models.ContactRoleAccounting,
+		},
+		Jobs: JobsConfig{
+			DefaultDiscountType: models.DiscountTypeAmount,
+			RentalDayCounting:   models.RentalDayCountingCalendar,
+		},
+		Devices: DevicesConfig{
+			AutoAssignQRCode: true,
+		},
+		Documents: DocumentsConfig{
+			MaxUploadSizeMB: 10,
+		},
+		Analytics: AnalyticsConfig{
+			UtilizationTolerance: 10,
+			UtilizationSaturated: 95,
 		},
 		PDF: PDFConfig{
 			Generator: "auto",
 			PaperSize: "A4",
+			Timeout:   30,
 			Margins: map[string]string{
 				"top":    "1cm",
 				"bottom": "1cm",
@@ -217,10 +314,13 @@ func getDefaultConfig() *Config {
 		},
 		Security: SecurityConfig{
 			SessionTimeout:    3600,
+			RememberMeTimeout: 30 * 24 * 3600,
 			PasswordMinLength: 8,
 			MaxLoginAttempts:  5,
 			LockoutDuration:   900,
 			EncryptionKey:     "RentalCore-Demo-Key-CHANGE-IN-PRODUCTION-256-BIT",
+			DefaultUserRole:   "viewer",
+			AuditKeepAccessChanges: true,
 		},
 		Logging: LoggingConfig{
 			Level:      "info",
@@ -258,6 +358,9 @@ func loadFromEnvironment(config *Config) {
 	if password := os.Getenv("DB_PASSWORD"); password != "" {
 		config.Database.Password = password
 	}
+	if require := os.Getenv("DB_REQUIRE_CURRENT_SCHEMA"); require != "" {
+		config.Database.RequireCurrentSchema = require == "true"
+	}
 
 	// Server configuration
 	if host := os.Getenv("SERVER_HOST"); host != "" {
@@ -278,6 +381,36 @@ func loadFromEnvironment(config *Config) {
 			config.Security.SessionTimeout = t
 		}
 	}
+	if timeout := os.Getenv("REMEMBER_ME_TIMEOUT"); timeout != "" {
+		if t, err := strconv.Atoi(timeout); err == nil {
+			config.Security.RememberMeTimeout = t
+		}
+	}
+	if sliding := os.Getenv("SESSION_SLIDING"); sliding != "" {
+		config.Security.SlidingSessions = sliding == "true"
+	}
+	if days := os.Getenv("AUDIT_RETENTION_DAYS"); days != "" {
+		if d, err := strconv.Atoi(days); err == nil {
+			config.Security.AuditRetentionDays = d
+		}
+	}
+	if keep := os.Getenv("AUDIT_KEEP_ACCESS_CHANGES"); keep != "" {
+		config.Security.AuditKeepAccessChanges = keep == "true"
+	}
+	// An empty DEFAULT_USER_ROLE disables the default role
+	if role, ok := os.LookupEnv("DEFAULT_USER_ROLE"); ok {
+		config.Security.DefaultUserRole = role
+	}
+
+	// PDF configuration
+	if generator := os.Getenv("PDF_GENERATOR"); generator != "" {
+		config.PDF.Generator = generator
+	}
+	if timeout := os.Getenv("PDF_TIMEOUT"); timeout != "" {
+		if t, err := strconv.Atoi(timeout); err == nil {
+			config.PDF.Timeout = t
+		}
+	}
 
 	// Email configuration
 	if host := os.Getenv("SMTP_HOST"); host != "" {
@@ -304,6 +437,21 @@ func loadFromEnvironment(config *Config) {
 		config.Email.UseTLS = useTLS == "true"
 	}
 
+	// Push notification configuration
+	if publicKey := os.Getenv("PUSH_VAPID_PUBLIC_KEY"); publicKey != "" {
+		config.Push.VAPIDPublicKey = publicKey
+	}
+	if privateKey := os.Getenv("PUSH_VAPID_PRIVATE_KEY"); privateKey != "" {
+		config.Push.VAPIDPrivateKey = privateKey
+	}
+	if subject := os.Getenv("PUSH_SUBJECT"); subject != "" {
+		config.Push.Subject = subject
+	}
+	// An empty PUSH_JOB_REMINDER_TIME disables job reminders
+	if reminderTime, ok := os.LookupEnv("PUSH_JOB_REMINDER_TIME"); ok {
+		config.Push.JobReminderTime = reminderTime
+	}
+
 	// Invoice configuration
 	if taxRate := os.Getenv("DEFAULT_TAX_RATE"); taxRate != "" {
 		if rate, err := strconv.ParseFloat(taxRate, 64); err == nil {
@@ -321,6 +469,71 @@ func loadFromEnvironment(config *Config) {
 	if code := os.Getenv("CURRENCY_CODE"); code != "" {
 		config.Invoice.CurrencyCode = code
 	}
+	if decimals := os.Getenv("CURRENCY_DECIMALS"); decimals != "" {
+		if d, err := strconv.Atoi(decimals); err == nil && d >= 0 {
+			config.Invoice.CurrencyDecimals = &d
+		}
+	}
+	if enabled := os.Getenv("INVOICE_REMINDERS_ENABLED"); enabled != "" {
+		config.Invoice.RemindersEnabled = enabled == "true"
+	}
+	if intervals := os.Getenv("INVOICE_REMINDER_INTERVALS"); intervals != "" {
+		var days []int
+		for _, part := range strings.Split(intervals, ",") {
+			if d, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && d > 0 {
+				days = append(days, d)
+			}
+		}
+		if len(days) > 0 {
+			config.Invoice.ReminderIntervals = days
+		}
+	}
+
+	if role := os.Getenv("INVOICE_RECIPIENT_ROLE"); role != "" {
+		config.Invoice.RecipientContactRole = role
+	}
+
+	// Job configuration
+	if discountType := os.Getenv("DEFAULT_DISCOUNT_TYPE"); discountType != "" {
+		config.Jobs.DefaultDiscountType = discountType
+	}
+	if maxDays := os.Getenv("MAX_RENTAL_DAYS"); maxDays != "" {
+		if days, err := strconv.Atoi(maxDays); err == nil {
+			config.Jobs.MaxRentalDays = days
+		}
+	}
+	if years := os.Getenv("JOB_ARCHIVE_AFTER_YEARS"); years != "" {
+		if y, err := strconv.Atoi(years); err == nil {
+			config.Jobs.ArchiveAfterYears = y
+		}
+	}
+	if counting := os.Getenv("RENTAL_DAY_COUNTING"); counting != "" {
+		config.Jobs.RentalDayCounting = counting
+	}
+
+	// Device configuration
+	if autoQR := os.Getenv("DEVICE_AUTO_QR_CODE"); autoQR != "" {
+		config.Devices.AutoAssignQRCode = autoQR == "true"
+	}
+
+	// Document configuration
+	if maxMB := os.Getenv("DOCUMENT_MAX_UPLOAD_MB"); maxMB != "" {
+		if mb, err := strconv.Atoi(maxMB); err == nil {
+			config.Documents.MaxUploadSizeMB = mb
+		}
+	}
+
+	// Analytics configuration
+	if tolerance := os.Getenv("UTILIZATION_TOLERANCE"); tolerance != "" {
+		if t, err := strconv.ParseFloat(tolerance, 64); err == nil {
+			config.Analytics.UtilizationTolerance = t
+		}
+	}
+	if saturated := os.Getenv("UTILIZATION_SATURATED"); saturated != "" {
+		if s, err := strconv.ParseFloat(saturated, 64); err == nil {
+			config.Analytics.UtilizationSaturated = s
+		}
+	}
 
 	// Logging configuration
 	if level := os.Getenv("LOG_LEVEL"); level != "" {
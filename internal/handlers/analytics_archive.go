@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"math"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/models"
+)
+
+type archivedMonth struct {
+	Month   time.Time
+	Revenue float64
+	Jobs    int64
+}
+
+// days returns the number of days in the month
+func (m archivedMonth) days() int {
+	return time.Date(m.Month.Year(), m.Month.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// overlap returns the first and last day of the month (1-based) within the
+// period, or ok false if the period doesn't touch the month
+func (m archivedMonth) overlap(startDate, endDate time.Time) (first, last int, ok bool) {
+	monthStart := time.Date(m.Month.Year(), m.Month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	start := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, time.UTC)
+
+	first, last = 1, m.days()
+	if start.After(monthStart) {
+		first = int(start.Sub(monthStart).Hours()/24) + 1
+	}
+	if end.Before(monthStart.AddDate(0, 0, last-1)) {
+		last = int(end.Sub(monthStart).Hours()/24) + 1
+	}
+	return first, last, first <= last
+}
+
+// revenueOn returns the archived revenue falling on days first to last of the
+// month. Archived totals are only kept per month, so they are spread evenly
+// over its days.
+func (m archivedMonth) revenueOn(first, last int) float64 {
+	return m.Revenue * float64(last-first+1) / float64(m.days())
+}
+
+// jobsOn returns the archived jobs falling on days first to last of the month,
+// spread like revenueOn and rounded so that the shares of a month's days add
+// up to its total
+func (m archivedMonth) jobsOn(first, last int) int64 {
+	through := func(day int) int64 {
+		return int64(math.Round(float64(m.Jobs) * float64(day) / float64(m.days())))
+	}
+	return through(last) - through(first-1)
+}
+
+// getArchivedMonths returns the monthly totals of jobs moved to the archive by
+// the retention policy, for months overlapping the period. Archived jobs are
+// no longer in the jobs table, so revenue and job figures add these to stay
+// unchanged by archiving.
+func (h *AnalyticsHandler) getArchivedMonths(startDate, endDate time.Time) []archivedMonth {
+	firstMonth := time.Date(startDate.Year(), startDate.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var rows []struct {
+		MetricName string
+		PeriodDate time.Time
+		Value      float64
+	}
+	if err := h.db.Model(&models.AnalyticsCache{}).
+		Select("metric_name, period_date, COALESCE(value, 0) as value").
+		Where("metric_name IN ? AND period_type = ? AND period_date BETWEEN ? AND ?",
+			[]string{models.MetricArchivedJobRevenue, models.MetricArchivedJobs}, "monthly",
+			firstMonth.Format("2006-01-02"), endDate.Format("2006-01-02")).
+		Scan(&rows).Error; err != nil {
+		logger.Errorf("Failed to load archived job totals: %v", err)
+		return nil
+	}
+
+	byMonth := make(map[string]*archivedMonth)
+	var months []archivedMonth
+	for _, row := range rows {
+		key := row.PeriodDate.Format("2006-01")
+		month, ok := byMonth[key]
+		if !ok {
+			month = &archivedMonth{Month: row.PeriodDate}
+			byMonth[key] = month
+		}
+		switch row.MetricName {
+		case models.MetricArchivedJobRevenue:
+			month.Revenue += row.Value
+		case models.MetricArchivedJobs:
+			month.Jobs += int64(row.Value)
+		}
+	}
+	for _, month := range byMonth {
+		if month.Jobs > 0 || month.Revenue != 0 {
+			months = append(months, *month)
+		}
+	}
+	return months
+}
+
+// getArchivedTotals sums the archived revenue and job count falling within the
+// period, prorating months the period only partly covers
+func (h *AnalyticsHandler) getArchivedTotals(startDate, endDate time.Time) (float64, int64) {
+	return archivedTotals(h.getArchivedMonths(startDate, endDate), startDate, endDate)
+}
+
+func archivedTotals(months []archivedMonth, startDate, endDate time.Time) (float64, int64) {
+	var revenue float64
+	var jobs int64
+	for _, month := range months {
+		if first, last, ok := month.overlap(startDate, endDate); ok {
+			revenue += month.revenueOn(first, last)
+			jobs += month.jobsOn(first, last)
+		}
+	}
+	return revenue, jobs
+}
+
+// addArchivedTrendPoints adds each day's share of the archived totals within
+// the period to the trend bucket containing that day
+func (h *AnalyticsHandler) addArchivedTrendPoints(points map[string]trendPoint, startDate, endDate time.Time, granularity string) {
+	addArchivedTrendPoints(points, h.getArchivedMonths(startDate, endDate), startDate, endDate, granularity)
+}
+
+func addArchivedTrendPoints(points map[string]trendPoint, months []archivedMonth, startDate, endDate time.Time, granularity string) {
+	for _, month := range months {
+		first, last, ok := month.overlap(startDate, endDate)
+		if !ok {
+			continue
+		}
+		for day := first; day <= last; day++ {
+			date := time.Date(month.Month.Year(), month.Month.Month(), day, 0, 0, 0, 0, startDate.Location())
+			key := trendBucketStart(date, granularity).Format("2006-01-02")
+			point := points[key]
+			point.Revenue += month.revenueOn(day, day)
+			point.Jobs += int(month.jobsOn(day, day))
+			points[key] = point
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func testDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestArchivedTotals(t *testing.T) {
+	// April has 30 days, May 31
+	months := []archivedMonth{
+		{Month: testDate(2024, time.April, 1), Revenue: 3000, Jobs: 10},
+		{Month: testDate(2024, time.May, 1), Revenue: 3100, Jobs: 31},
+	}
+
+	tests := []struct {
+		name        string
+		start, end  time.Time
+		wantRevenue float64
+		wantJobs    int64
+	}{
+		{"whole month", testDate(2024, time.April, 1), testDate(2024, time.April, 30), 3000, 10},
+		{"mid-month start", testDate(2024, time.April, 16), testDate(2024, time.April, 30), 1500, 5},
+		{"week inside a month", testDate(2024, time.May, 8), testDate(2024, time.May, 14), 700, 7},
+		{"across the month boundary", testDate(2024, time.April, 21), testDate(2024, time.May, 10), 2000, 13},
+		{"before the archive", testDate(2024, time.March, 1), testDate(2024, time.March, 31), 0, 0},
+		{"both months", testDate(2024, time.March, 15), testDate(2024, time.June, 15), 6100, 41},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revenue, jobs := archivedTotals(months, tt.start, tt.end)
+			if math.Abs(revenue-tt.wantRevenue) > 1e-9 || jobs != tt.wantJobs {
+				t.Errorf("archivedTotals = %.2f, %d; want %.2f, %d", revenue, jobs, tt.wantRevenue, tt.wantJobs)
+			}
+		})
+	}
+}
+
+func TestArchivedTrendPointsAddUpToTotals(t *testing.T) {
+	months := []archivedMonth{{Month: testDate(2024, time.April, 1), Revenue: 1000, Jobs: 7}}
+	start, end := testDate(2024, time.April, 10), testDate(2024, time.April, 30)
+	wantRevenue, wantJobs := archivedTotals(months, start, end)
+
+	for _, granularity := range []string{TrendGranularityDay, TrendGranularityWeek, TrendGranularityMonth} {
+		points := map[string]trendPoint{}
+		addArchivedTrendPoints(points, months, start, end, granularity)
+
+		var revenue float64
+		var jobs int
+		for _, point := range points {
+			revenue += point.Revenue
+			jobs += point.Jobs
+		}
+		if math.Abs(revenue-wantRevenue) > 1e-9 || int64(jobs) != wantJobs {
+			t.Errorf("%s trend points add up to %.2f, %d; want %.2f, %d", granularity, revenue, jobs, wantRevenue, wantJobs)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// analyticsCacheTTL is how long a cached dashboard metric is served before it
+// is computed again
+const analyticsCacheTTL = time.Hour
+
+// loadAnalyticsCache decodes the metadata of a daily cached metric into target
+// if it was stored within the TTL. It reports whether target was filled.
+func (h *AnalyticsHandler) loadAnalyticsCache(metric string, periodDate time.Time, target interface{}) bool {
+	var entry struct {
+		Metadata json.RawMessage
+	}
+	err := h.db.Model(&models.AnalyticsCache{}).
+		Select("metadata").
+		Where("metric_name = ? AND period_type = ? AND period_date = ? AND updated_at >= ?",
+			metric, "daily", periodDate.Format("2006-01-02"), time.Now().Add(-analyticsCacheTTL)).
+		Take(&entry).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warnf("Failed to read analytics cache %s: %v", metric, err)
+		}
+		return false
+	}
+	if len(entry.Metadata) == 0 {
+		return false
+	}
+
+	if err := json.Unmarshal(entry.Metadata, target); err != nil {
+		logger.Warnf("Ignoring unreadable analytics cache %s: %v", metric, err)
+		return false
+	}
+	return true
+}
+
+// storeAnalyticsCache saves a computed metric as the daily cache entry for
+// periodDate. Failures are logged; the caller already has the data.
+func (h *AnalyticsHandler) storeAnalyticsCache(metric string, periodDate time.Time, value *float64, data interface{}) {
+	metadata, err := json.Marshal(data)
+	if err != nil {
+		logger.Warnf("Failed to encode analytics cache %s: %v", metric, err)
+		return
+	}
+
+	if err := h.db.Exec(`
+		INSERT INTO analytics_cache (metric_name, period_type, period_date, value, metadata, updated_at)
+		VALUES (?, 'daily', ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE value = VALUES(value), metadata = VALUES(metadata), updated_at = VALUES(updated_at)
+	`, metric, periodDate.Format("2006-01-02"), value, string(metadata), time.Now()).Error; err != nil {
+		logger.Warnf("Failed to write analytics cache %s: %v", metric, err)
+	}
+}
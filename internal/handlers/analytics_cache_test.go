@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFailedQueriesAreNotCached checks that a failed metric query is reported
+// as empty without being stored, so the next request queries again
+func TestFailedQueriesAreNotCached(t *testing.T) {
+	var cacheWrites int
+	db := newFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		switch {
+		case strings.HasPrefix(query, "INSERT INTO analytics_cache"):
+			cacheWrites++
+		case strings.Contains(query, "FROM devices d"), strings.Contains(query, "FROM products p"):
+			return nil, nil, errors.New("connection lost")
+		}
+		// Cache lookups: no entry
+		return nil, nil, nil
+	})
+	handler := NewAnalyticsHandler(db.DB, nil, nil, nil)
+	end := time.Now()
+
+	if top := handler.getTopEquipment(end.AddDate(0, 0, -30), end, 10, false); len(top) != 0 {
+		t.Errorf("getTopEquipment = %v; want no devices", top)
+	}
+	if utilization := handler.getUtilizationMetrics(false); len(utilization["categories"].([]map[string]interface{})) != 0 {
+		t.Errorf("getUtilizationMetrics = %v; want no categories", utilization)
+	}
+	if cacheWrites != 0 {
+		t.Errorf("failed queries wrote %d cache entries; want none", cacheWrites)
+	}
+}
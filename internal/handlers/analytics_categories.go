@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CategoryRevenue is the revenue of one product category over a period
+type CategoryRevenue struct {
+	CategoryID       *uint   `json:"categoryID"`
+	CategoryName     string  `json:"categoryName"`
+	RentalCount      int     `json:"rentalCount"`
+	TotalRevenue     float64 `json:"totalRevenue"`
+	AverageDailyRate float64 `json:"averageDailyRate"`
+}
+
+// getCategoryRevenue sums the discounted device revenue of jobs ending in the
+// period per product category. Devices whose product has no category are
+// reported together with a nil category ID.
+func (h *AnalyticsHandler) getCategoryRevenue(startDate, endDate time.Time) []CategoryRevenue {
+	results := []CategoryRevenue{}
+
+	if err := h.db.Raw(`
+		SELECT
+			category_id,
+			category_name,
+			COUNT(*) as rental_count,
+			COALESCE(SUM(revenue), 0) as total_revenue,
+			COALESCE(AVG(revenue), 0) as average_daily_rate
+		FROM (
+			SELECT
+				c.categoryID as category_id,
+				COALESCE(c.name, 'Uncategorized') as category_name,
+				`+deviceRevenueSQL()+` as revenue
+			FROM jobdevices jd
+			JOIN jobs j ON j.jobID = jd.jobID
+			JOIN devices d ON d.deviceID = jd.deviceID
+			JOIN products p ON p.productID = d.productID
+			LEFT JOIN categories c ON c.categoryID = p.categoryID
+			WHERE j.endDate BETWEEN ? AND ?
+		) device_revenue
+		GROUP BY category_id, category_name
+		ORDER BY total_revenue DESC
+	`, startDate, endDate).Scan(&results).Error; err != nil {
+		logger.Errorf("Failed to load category revenue: %v", err)
+		return []CategoryRevenue{}
+	}
+
+	for i := range results {
+		results[i].TotalRevenue = h.currency.Round(results[i].TotalRevenue)
+		results[i].AverageDailyRate = h.currency.Round(results[i].AverageDailyRate)
+	}
+	return results
+}
+
+// GetCategoryRevenueAPI returns revenue, rental count and average daily rate
+// per product category for the selected period
+func (h *AnalyticsHandler) GetCategoryRevenueAPI(c *gin.Context) {
+	period, startDate, endDate := analyticsDateRange(c, "1year")
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories": h.getCategoryRevenue(startDate, endDate),
+		"period":     period,
+		"startDate":  startDate.Format("2006-01-02"),
+		"endDate":    endDate.Format("2006-01-02"),
+		"currency":   h.currency,
+	})
+}
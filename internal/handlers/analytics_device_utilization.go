@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"math"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+)
+
+// DeviceUtilizationMonth is how many days of a month within the analytics
+// period a device was booked
+type DeviceUtilizationMonth struct {
+	Month           string  `json:"month"` // YYYY-MM
+	BookedDays      int     `json:"bookedDays"`
+	AvailableDays   int     `json:"availableDays"`
+	UtilizationRate float64 `json:"utilizationRate"`
+}
+
+// getDeviceUtilization counts the days from startDate to endDate on which the
+// device was on a job, overall and per month. Each booking counts its days
+// within the period, both ends included; days covered by overlapping
+// bookings count once. Open-ended jobs run until today.
+func (h *AnalyticsHandler) getDeviceUtilization(deviceID string, startDate, endDate time.Time) (bookedDays, availableDays int, timeline []DeviceUtilizationMonth) {
+	periodStart := dateOnly(startDate)
+	periodEnd := dateOnly(endDate)
+	if periodEnd.Before(periodStart) {
+		return 0, 0, []DeviceUtilizationMonth{}
+	}
+
+	var bookings []struct {
+		StartDate time.Time
+		EndDate   time.Time
+	}
+	if err := h.db.Raw(`
+		SELECT j.startDate as start_date, COALESCE(j.endDate, NOW()) as end_date
+		FROM jobdevices jd
+		JOIN jobs j ON j.jobID = jd.jobID
+		WHERE jd.deviceID = ?
+			AND j.startDate IS NOT NULL
+			AND DATE(j.startDate) <= ?
+			AND DATE(COALESCE(j.endDate, NOW())) >= ?
+	`, deviceID, periodEnd.Format("2006-01-02"), periodStart.Format("2006-01-02")).Scan(&bookings).Error; err != nil {
+		logger.Errorf("Failed to load bookings of device %s: %v", deviceID, err)
+	}
+
+	booked := make(map[time.Time]bool)
+	for _, booking := range bookings {
+		from := dateOnly(booking.StartDate)
+		to := dateOnly(booking.EndDate)
+		if from.Before(periodStart) {
+			from = periodStart
+		}
+		if to.After(periodEnd) {
+			to = periodEnd
+		}
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			booked[day] = true
+		}
+	}
+
+	timeline = []DeviceUtilizationMonth{}
+	for day := periodStart; !day.After(periodEnd); day = day.AddDate(0, 0, 1) {
+		month := day.Format("2006-01")
+		if len(timeline) == 0 || timeline[len(timeline)-1].Month != month {
+			timeline = append(timeline, DeviceUtilizationMonth{Month: month})
+		}
+		entry := &timeline[len(timeline)-1]
+		entry.AvailableDays++
+		availableDays++
+		if booked[day] {
+			entry.BookedDays++
+			bookedDays++
+		}
+	}
+
+	for i := range timeline {
+		timeline[i].UtilizationRate = utilizationPercent(timeline[i].BookedDays, timeline[i].AvailableDays)
+	}
+	return bookedDays, availableDays, timeline
+}
+
+func utilizationPercent(booked, available int) float64 {
+	if available == 0 {
+		return 0
+	}
+	return math.Round(float64(booked)*1000/float64(available)) / 10
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
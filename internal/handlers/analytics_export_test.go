@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"testing"
+
+	"go-barcode-webapp/internal/models"
+)
+
+func TestExportHelpersMissingKeys(t *testing.T) {
+	data := map[string]interface{}{}
+
+	if value, ok := exportNumber(data["totalRevenue"]); ok || value != 0 {
+		t.Errorf("exportNumber(missing) = %v, %v; want 0, false", value, ok)
+	}
+	if value := exportFloat(data, "totalRevenue"); value != 0 {
+		t.Errorf("exportFloat(missing) = %v; want 0", value)
+	}
+	if value := exportCount(data, "totalJobs"); value != 0 {
+		t.Errorf("exportCount(missing) = %v; want 0", value)
+	}
+	if value := exportString(data, "name"); value != "" {
+		t.Errorf("exportString(missing) = %q; want empty", value)
+	}
+}
+
+func TestExportHelpersNilAndWrongTypes(t *testing.T) {
+	data := map[string]interface{}{
+		"nil":    nil,
+		"text":   "12",
+		"number": 12.5,
+	}
+
+	if value := exportFloat(data, "nil"); value != 0 {
+		t.Errorf("exportFloat(nil) = %v; want 0", value)
+	}
+	if value := exportFloat(data, "text"); value != 0 {
+		t.Errorf("exportFloat(string) = %v; want 0", value)
+	}
+	if value := exportString(data, "number"); value != "" {
+		t.Errorf("exportString(number) = %q; want empty", value)
+	}
+	if value := exportString(data, "nil"); value != "" {
+		t.Errorf("exportString(nil) = %q; want empty", value)
+	}
+}
+
+func TestExportNumberTypes(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  float64
+	}{
+		{float64(1.5), 1.5},
+		{float32(2.5), 2.5},
+		{int(3), 3},
+		{int32(4), 4},
+		{int64(5), 5},
+		{uint(6), 6},
+		{uint64(7), 7},
+	}
+	for _, tt := range tests {
+		got, ok := exportNumber(tt.value)
+		if !ok || got != tt.want {
+			t.Errorf("exportNumber(%T %v) = %v, %v; want %v, true", tt.value, tt.value, got, ok, tt.want)
+		}
+	}
+}
+
+func TestExportCountRounds(t *testing.T) {
+	data := map[string]interface{}{"jobs": 2.6, "devices": int64(4)}
+
+	if got := exportCount(data, "jobs"); got != 3 {
+		t.Errorf("exportCount(2.6) = %d; want 3", got)
+	}
+	if got := exportCount(data, "devices"); got != 4 {
+		t.Errorf("exportCount(int64 4) = %d; want 4", got)
+	}
+}
+
+func TestAnalyticsExportTablesPartialData(t *testing.T) {
+	// Sections whose queries failed come back without their keys
+	analytics := map[string]interface{}{
+		"revenue":   map[string]interface{}{},
+		"customers": map[string]interface{}{"totalCustomers": 3},
+		"equipment": map[string]interface{}{},
+		"topEquipment": []map[string]interface{}{
+			{"deviceID": "D-1"},
+		},
+	}
+
+	tables := analyticsExportTables(analytics, models.NewCurrency("EUR", "€", -1))
+	if len(tables) != 4 {
+		t.Fatalf("analyticsExportTables returned %d tables; want 4", len(tables))
+	}
+	topEquipment := tables[2]
+	if len(topEquipment.Rows) != 1 {
+		t.Fatalf("top equipment rows = %d; want 1", len(topEquipment.Rows))
+	}
+	row := topEquipment.Rows[0]
+	if row[0] != "D-1" || row[1] != "" || row[2] != int64(0) {
+		t.Errorf("top equipment row = %v; want D-1 with empty name and zero rentals", row)
+	}
+}
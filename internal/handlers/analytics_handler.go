@@ -1,39 +1,130 @@
 package handlers
 
 import (
+	"database/sql"
+	"encoding/csv"
 	"fmt"
-	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
+	"go-barcode-webapp/internal/repository"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jung-kurt/gofpdf"
+	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
 )
 
 type AnalyticsHandler struct {
-	db *gorm.DB
+	db              *gorm.DB
+	jobRepo         *repository.JobRepository
+	currency        models.Currency // Amounts are rounded to its minor units so the dashboard, API, CSV and PDF all show the same figure
+	analyticsConfig *config.AnalyticsConfig
 }
 
-func NewAnalyticsHandler(db *gorm.DB) *AnalyticsHandler {
-	return &AnalyticsHandler{db: db}
+func NewAnalyticsHandler(db *gorm.DB, jobRepo *repository.JobRepository, invoiceConfig *config.InvoiceConfig, analyticsConfig *config.AnalyticsConfig) *AnalyticsHandler {
+	return &AnalyticsHandler{db: db, jobRepo: jobRepo, currency: invoiceConfig.ReportCurrency(), analyticsConfig: analyticsConfig}
 }
 
-// Dashboard displays the main analytics dashboard
-func (h *AnalyticsHandler) Dashboard(c *gin.Context) {
-	currentUser, _ := GetCurrentUser(c)
-	
-	// Get period from query params (default: 30 days for better initial data)
-	period := c.DefaultQuery("period", "30days")
-	log.Printf("Analytics dashboard requested with period: %s", period)
-	
-	// Calculate date range
-	endDate := time.Now()
-	var startDate time.Time
-	
+// seasonalItemCostSQL resolves a product's day rate through the pricing calendar
+// for the job's start date. Expects the aliases p (products) and j (jobs); the
+// precedence, and that items without a product get no seasonal rate, match
+// models.ResolveSeasonalRate.
+const seasonalItemCostSQL = `COALESCE((
+	SELECT COALESCE(pc.override_rate, p.itemcostperday * pc.multiplier, p.itemcostperday)
+	FROM pricing_calendars pc
+	WHERE pc.is_active = TRUE
+		AND p.productID IS NOT NULL
+		AND j.startDate BETWEEN pc.start_date AND pc.end_date
+		AND (pc.product_id = p.productID
+			OR (pc.product_id IS NULL AND (pc.category_id = p.categoryID OR pc.category_id IS NULL)))
+	ORDER BY pc.product_id IS NULL, pc.category_id IS NULL, pc.calendar_id DESC
+	LIMIT 1
+), p.itemcostperday)`
+
+// devicePriceSQL is a job device's day price: its custom price, otherwise its
+// product's seasonal day rate. A custom price of 0 counts as unset, as in
+// JobRepository.grossRevenue. Expects the aliases jd (jobdevices), j (jobs)
+// and p (products).
+const devicePriceSQL = `COALESCE(NULLIF(jd.custom_price, 0), ` + seasonalItemCostSQL + `, 0)`
+
+// jobDiscountFactorSQL builds the SQL for the share of a device's price left
+// after the job discount. A percent discount reduces it by that percentage (at
+// most 100%); a fixed amount is spread over the job's devices in proportion to
+// their share of the job revenue. Expects the alias j (jobs).
+func jobDiscountFactorSQL() string {
+	return fmt.Sprintf(`CASE
+		WHEN COALESCE(j.discount, 0) <= 0 THEN 1
+		WHEN j.discount_type = '%s' THEN 1 - LEAST(100, j.discount) / 100
+		WHEN j.discount_type = '%s' AND j.revenue > 0 THEN 1 - LEAST(j.discount, j.revenue) / j.revenue
+		ELSE 1
+	END`, models.DiscountTypePercent, models.DiscountTypeAmount)
+}
+
+// deviceRevenue is a job device's revenue after the job discount, computed
+// from its day price the way deviceRevenueSQL does in SQL, for rows that are
+// already loaded. The result is never negative.
+func deviceRevenue(price, discount float64, discountType string, jobRevenue float64) float64 {
+	factor := 1.0
+	switch {
+	case discount <= 0:
+	case discountType == models.DiscountTypePercent:
+		factor = 1 - math.Min(100, discount)/100
+	case discountType == models.DiscountTypeAmount && jobRevenue > 0:
+		factor = 1 - math.Min(discount, jobRevenue)/jobRevenue
+	}
+	return math.Max(0, price*factor)
+}
+
+// deviceRevenueSQL builds the SQL for a job device's revenue after the job
+// discount, used by every per-device revenue figure so they agree. The result
+// is never negative, and NULL without a job so rows a LEFT JOIN on jobs in the
+// period left empty don't count. The seasonal rate is a correlated subquery, so
+// queries that need the revenue more than once select it in a derived table.
+func deviceRevenueSQL() string {
+	return `CASE WHEN j.jobID IS NULL THEN NULL ELSE GREATEST(0, ` + devicePriceSQL + ` * ` + jobDiscountFactorSQL() + `) END`
+}
+
+// AnalyticsPeriodCustom is the period reported when start_date and end_date
+// select the analytics window instead of a preset
+const AnalyticsPeriodCustom = "custom"
+
+// analyticsDateRange resolves the analytics window from the period preset
+// (7days, 30days, 90days or 1year, otherwise defaultPeriod). Valid start_date
+// and end_date query parameters (YYYY-MM-DD) override the preset; the end date
+// is included in the window.
+func analyticsDateRange(c *gin.Context, defaultPeriod string) (period string, startDate, endDate time.Time) {
+	startParam := strings.TrimSpace(c.Query("start_date"))
+	endParam := strings.TrimSpace(c.Query("end_date"))
+	if startParam != "" || endParam != "" {
+		start, startErr := time.ParseInLocation("2006-01-02", startParam, time.Local)
+		end, endErr := time.ParseInLocation("2006-01-02", endParam, time.Local)
+		switch {
+		case startParam == "" || endParam == "":
+			logger.Warnf("Analytics: start_date and end_date must be given together, using period preset")
+		case startErr != nil || endErr != nil:
+			logger.Warnf("Analytics: invalid custom range %q to %q, using period preset", startParam, endParam)
+		case end.Before(start):
+			logger.Warnf("Analytics: end_date %s is before start_date %s, using period preset", endParam, startParam)
+		default:
+			return AnalyticsPeriodCustom, start, end.AddDate(0, 0, 1).Add(-time.Second)
+		}
+	}
+
+	period = c.DefaultQuery("period", defaultPeriod)
+	switch period {
+	case "7days", "30days", "90days", "1year":
+	default:
+		period = defaultPeriod
+	}
+
+	endDate = time.Now()
 	switch period {
 	case "7days":
 		startDate = endDate.AddDate(0, 0, -7)
@@ -41,18 +132,69 @@ func (h *AnalyticsHandler) Dashboard(c *gin.Context) {
 		startDate = endDate.AddDate(0, 0, -30)
 	case "90days":
 		startDate = endDate.AddDate(0, 0, -90)
-	case "1year":
-		startDate = endDate.AddDate(-1, 0, 0)
 	default:
-		startDate = endDate.AddDate(0, 0, -30) // Default to 30 days
-		period = "30days"
+		startDate = endDate.AddDate(-1, 0, 0)
 	}
+	return period, startDate, endDate
+}
+
+const (
+	defaultAnalyticsTopLimit = 10
+	maxAnalyticsTopLimit     = 100
+)
 
-	log.Printf("Analytics date range: %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+// analyticsTopLimit reads how many top devices and customers to list from the
+// limit query parameter, clamped to 1-100. Non-numeric values are ignored.
+func analyticsTopLimit(c *gin.Context) int {
+	value := c.Query("limit")
+	if value == "" {
+		return defaultAnalyticsTopLimit
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Debugf("Analytics: ignoring non-numeric limit %q", value)
+		return defaultAnalyticsTopLimit
+	}
+	if limit < 1 {
+		return 1
+	}
+	if limit > maxAnalyticsTopLimit {
+		return maxAnalyticsTopLimit
+	}
+	return limit
+}
+
+// analyticsOptions are the query parameters shared by the dashboard, APIs and exports
+type analyticsOptions struct {
+	TopLimit int
+	Refresh  bool // bypass the analytics cache
+}
+
+func analyticsOptionsFromQuery(c *gin.Context) analyticsOptions {
+	return analyticsOptions{
+		TopLimit: analyticsTopLimit(c),
+		Refresh:  c.Query("refresh") == "true",
+	}
+}
+
+// Dashboard displays the main analytics dashboard
+func (h *AnalyticsHandler) Dashboard(c *gin.Context) {
+	currentUser, _ := GetCurrentUser(c)
+	
+	// Get period from query params (default: 30 days for better initial data)
+	period, startDate, endDate := analyticsDateRange(c, "30days")
+	logger.Debugf("Analytics dashboard requested with period: %s", period)
+	granularity, err := parseTrendGranularity(c.Query("granularity"))
+	if err != nil {
+		granularity = TrendGranularityDay
+	}
+
+	logger.Debugf("Analytics date range: %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 	
 	// Get analytics data with simplified approach
-	analytics := h.getSimplifiedAnalyticsData(startDate, endDate)
-	log.Printf("Analytics data retrieved for period %s", period)
+	options := analyticsOptionsFromQuery(c)
+	analytics := h.getSimplifiedAnalyticsData(startDate, endDate, granularity, options)
+	logger.Debugf("Analytics data retrieved for period %s", period)
 	
 	c.HTML(http.StatusOK, "analytics_dashboard_new.html", gin.H{
 		"title":       "Analytics Dashboard",
@@ -60,40 +202,80 @@ func (h *AnalyticsHandler) Dashboard(c *gin.Context) {
 		"user":        currentUser,
 		"analytics":   analytics,
 		"period":      period,
+		"granularity": granularity,
+		"topLimit":    options.TopLimit,
 		"startDate":   startDate.Format("2006-01-02"),
 		"endDate":     endDate.Format("2006-01-02"),
 	})
 }
 
 // getSimplifiedAnalyticsData collects simplified analytics data for the new dashboard
-func (h *AnalyticsHandler) getSimplifiedAnalyticsData(startDate, endDate time.Time) map[string]interface{} {
-	log.Printf("Getting simplified analytics data from %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+func (h *AnalyticsHandler) getSimplifiedAnalyticsData(startDate, endDate time.Time, granularity string, options analyticsOptions) map[string]interface{} {
+	logger.Debugf("Getting simplified analytics data from %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 	
 	analytics := map[string]interface{}{
 		"revenue":         h.getSimplifiedRevenue(startDate, endDate),
 		"equipment":       h.getSimplifiedEquipment(startDate, endDate),
 		"customers":       h.getSimplifiedCustomers(startDate, endDate),
 		"jobs":            h.getSimplifiedJobs(startDate, endDate),
-		"trends":          h.getSimplifiedTrends(startDate, endDate),
-		"topEquipment":    h.getTopEquipment(startDate, endDate, 10),
-		"topCustomers":    h.getTopCustomers(startDate, endDate, 10),
-		"utilization":     h.getUtilizationMetrics(),
+		"trends":          h.getSimplifiedTrends(startDate, endDate, granularity),
+		"topEquipment":    h.getTopEquipment(startDate, endDate, options.TopLimit, options.Refresh),
+		"topCustomers":    h.getTopCustomers(startDate, endDate, options.TopLimit),
+		"utilization":     h.getUtilizationMetrics(options.Refresh),
+		"utilizationTargets": h.getCategoryUtilization(startDate, endDate),
+		"currency":        h.currency,
 	}
 	
-	log.Printf("Simplified analytics data retrieved successfully")
+	logger.Debugf("Simplified analytics data retrieved successfully")
 	return analytics
 }
 
 // getSimplifiedRevenue calculates basic revenue metrics
 func (h *AnalyticsHandler) getSimplifiedRevenue(startDate, endDate time.Time) map[string]interface{} {
+	totalRevenue, totalJobs := h.getSimplifiedRevenueTotals(startDate, endDate)
+	
+	avgJobValue := float64(0)
+	if totalJobs > 0 {
+		avgJobValue = totalRevenue / float64(totalJobs)
+	}
+
+	// Previous period of the same length for comparison, as in getRevenueAnalytics
+	prevStartDate := startDate.AddDate(0, 0, -int(endDate.Sub(startDate).Hours()/24))
+	prevRevenue, prevJobs := h.getSimplifiedRevenueTotals(prevStartDate, startDate)
+
+	// Without revenue in the previous period there is nothing to compare against
+	revenueGrowth := float64(0)
+	if prevRevenue > 0 {
+		revenueGrowth = ((totalRevenue - prevRevenue) / prevRevenue) * 100
+	}
+
+	jobsGrowth := float64(0)
+	if prevJobs > 0 {
+		jobsGrowth = ((float64(totalJobs) - float64(prevJobs)) / float64(prevJobs)) * 100
+	}
+	
+	logger.Debugf("Revenue data: %.2f total, %d jobs, %.2f avg, %.1f%% growth", totalRevenue, totalJobs, avgJobValue, revenueGrowth)
+	
+	return map[string]interface{}{
+		"totalRevenue":  h.currency.Round(totalRevenue),
+		"totalJobs":     totalJobs,
+		"avgJobValue":   h.currency.Round(avgJobValue),
+		"revenueGrowth": revenueGrowth,
+		"jobsGrowth":    jobsGrowth,
+	}
+}
+
+// getSimplifiedRevenueTotals sums the revenue and counts the jobs ending in the
+// period, preferring final_revenue over revenue per job and including archived jobs
+func (h *AnalyticsHandler) getSimplifiedRevenueTotals(startDate, endDate time.Time) (float64, int64) {
 	var totalRevenue float64
 	var totalJobs int64
 	
 	// Simple query for total revenue from jobs in the period
 	result := h.db.Raw(`
 		SELECT 
-			COALESCE(SUM(COALESCE(final_revenue, revenue, 0)), 0) as total_revenue,
-			COUNT(*) as job_count
+		COALESCE(SUM(COALESCE(final_revenue, revenue, 0)), 0) as total_revenue,
+		COUNT(*) as job_count
 		FROM jobs 
 		WHERE endDate BETWEEN ? AND ?
 		AND (final_revenue > 0 OR revenue > 0)
@@ -101,19 +283,8 @@ func (h *AnalyticsHandler) getSimplifiedRevenue(startDate, endDate time.Time) ma
 	
 	result.Scan(&totalRevenue, &totalJobs)
 	
-	avgJobValue := float64(0)
-	if totalJobs > 0 {
-		avgJobValue = totalRevenue / float64(totalJobs)
-	}
-	
-	log.Printf("Revenue data: %.2f total, %d jobs, %.2f avg", totalRevenue, totalJobs, avgJobValue)
-	
-	return map[string]interface{}{
-		"totalRevenue": totalRevenue,
-		"totalJobs":    totalJobs,
-		"avgJobValue":  avgJobValue,
-		"revenueGrowth": 0.0, // Simplified - no growth calculation
-	}
+	archivedRevenue, archivedJobs := h.getArchivedTotals(startDate, endDate)
+	return totalRevenue + archivedRevenue, totalJobs + archivedJobs
 }
 
 // getSimplifiedEquipment calculates basic equipment metrics  
@@ -133,7 +304,7 @@ func (h *AnalyticsHandler) getSimplifiedEquipment(startDate, endDate time.Time)
 	
 	availableDevices := totalDevices - activeDevices
 	
-	log.Printf("Equipment data: %d total, %d active, %.1f%% utilization", totalDevices, activeDevices, utilizationRate)
+	logger.Debugf("Equipment data: %d total, %d active, %.1f%% utilization", totalDevices, activeDevices, utilizationRate)
 	
 	return map[string]interface{}{
 		"totalDevices":     totalDevices,
@@ -162,7 +333,7 @@ func (h *AnalyticsHandler) getSimplifiedCustomers(startDate, endDate time.Time)
 		retentionRate = (float64(activeCustomers) / float64(totalCustomers)) * 100
 	}
 	
-	log.Printf("Customer data: %d total, %d active, %.1f%% retention", totalCustomers, activeCustomers, retentionRate)
+	logger.Debugf("Customer data: %d total, %d active, %.1f%% retention", totalCustomers, activeCustomers, retentionRate)
 	
 	return map[string]interface{}{
 		"totalCustomers":  totalCustomers,
@@ -183,6 +354,10 @@ func (h *AnalyticsHandler) getSimplifiedJobs(startDate, endDate time.Time) map[s
 		AND statusID IN (3, 4)
 	`, startDate, endDate).Scan(&completedJobs)
 	
+	// Archived jobs are all completed
+	_, archivedJobs := h.getArchivedTotals(startDate, endDate)
+	completedJobs += archivedJobs
+	
 	// Count active jobs (statusID 1 or 2)
 	h.db.Raw(`
 		SELECT COUNT(*) 
@@ -191,7 +366,7 @@ func (h *AnalyticsHandler) getSimplifiedJobs(startDate, endDate time.Time) map[s
 		AND statusID IN (1, 2)
 	`, endDate, startDate).Scan(&activeJobs)
 	
-	log.Printf("Job data: %d completed, %d active", completedJobs, activeJobs)
+	logger.Debugf("Job data: %d completed, %d active", completedJobs, activeJobs)
 	
 	return map[string]interface{}{
 		"completedJobs": completedJobs,
@@ -200,67 +375,69 @@ func (h *AnalyticsHandler) getSimplifiedJobs(startDate, endDate time.Time) map[s
 	}
 }
 
-// getSimplifiedTrends provides basic trend data
-func (h *AnalyticsHandler) getSimplifiedTrends(startDate, endDate time.Time) map[string]interface{} {
-	// Simple daily revenue trend
-	var trends []map[string]interface{}
+// getSimplifiedTrends provides basic trend data, one entry per bucket of the
+// requested granularity including buckets without jobs
+func (h *AnalyticsHandler) getSimplifiedTrends(startDate, endDate time.Time, granularity string) map[string]interface{} {
+	points := make(map[string]trendPoint)
+	bucket := trendBucketSQL(granularity, "endDate")
 	
 	rows, err := h.db.Raw(`
 		SELECT 
-			DATE(endDate) as date,
-			COALESCE(SUM(COALESCE(final_revenue, revenue, 0)), 0) as revenue,
-			COUNT(*) as jobs
+		`+bucket+` as date,
+		COALESCE(SUM(COALESCE(final_revenue, revenue, 0)), 0) as revenue,
+		COUNT(*) as jobs
 		FROM jobs
 		WHERE endDate BETWEEN ? AND ?
-		GROUP BY DATE(endDate)
+		GROUP BY `+bucket+`
 		ORDER BY date
-		LIMIT 30
 	`, startDate, endDate).Rows()
 	
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
-			var date string
-			var revenue float64
-			var jobs int
-			
-			rows.Scan(&date, &revenue, &jobs)
-			trends = append(trends, map[string]interface{}{
-				"date":    date,
-				"revenue": revenue,
-				"jobs":    jobs,
-			})
+		var date string
+		var point trendPoint
+		
+		rows.Scan(&date, &point.Revenue, &point.Jobs)
+		points[date] = point
 		}
+	} else {
+		logger.Errorf("Failed to load trend data: %v", err)
 	}
+	h.addArchivedTrendPoints(points, startDate, endDate, granularity)
 	
-	log.Printf("Trend data: %d data points", len(trends))
+	trends := fillTrendGaps(points, startDate, endDate, granularity, h.currency)
+	logger.Debugf("Trend data: %d data points, %d %s buckets", len(points), len(trends), granularity)
 	
 	return map[string]interface{}{
-		"revenue": trends,
+		"revenue":     trends,
+		"granularity": granularity,
 	}
 }
 
 // getAnalyticsData collects all analytics data for the dashboard
-func (h *AnalyticsHandler) getAnalyticsData(startDate, endDate time.Time) map[string]interface{} {
+func (h *AnalyticsHandler) getAnalyticsData(startDate, endDate time.Time, options analyticsOptions) map[string]interface{} {
 	analytics := map[string]interface{}{
 		"revenue":         h.getRevenueAnalytics(startDate, endDate),
 		"equipment":       h.getEquipmentAnalytics(startDate, endDate),
 		"customers":       h.getCustomerAnalytics(startDate, endDate),
 		"jobs":           h.getJobAnalytics(startDate, endDate),
-		"topEquipment":   h.getTopEquipment(startDate, endDate, 10),
-		"topCustomers":   h.getTopCustomers(startDate, endDate, 10),
-		"utilization":    h.getUtilizationMetrics(),
-		"trends":         h.getTrendData(startDate, endDate),
+		"topEquipment":   h.getTopEquipment(startDate, endDate, options.TopLimit, options.Refresh),
+		"topCustomers":   h.getTopCustomers(startDate, endDate, options.TopLimit),
+		"utilization":    h.getUtilizationMetrics(options.Refresh),
+		"utilizationTargets": h.getCategoryUtilization(startDate, endDate),
+		"currency":       h.currency,
+		"trends":         h.getTrendData(startDate, endDate, TrendGranularityDay),
 	}
 	
 	// Ensure trends always has a proper structure
 	if trends, ok := analytics["trends"].(map[string]interface{}); ok {
 		if trends["revenue"] == nil {
-			trends["revenue"] = []map[string]interface{}{}
+		trends["revenue"] = []map[string]interface{}{}
 		}
 	} else {
 		analytics["trends"] = map[string]interface{}{
-			"revenue": []map[string]interface{}{},
+		"revenue": []map[string]interface{}{},
 		}
 	}
 	
@@ -309,19 +486,19 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 	
 	deviceResult := h.db.Raw(`
 		SELECT 
-			d.deviceID,
-			COALESCE(p.name, 'Unknown Product') as product_name,
-			d.serialnumber as serial_number,
-			COALESCE(c.name, 'Unknown Category') as category_name,
-			d.status
+		d.deviceID,
+		COALESCE(p.name, 'Unknown Product') as product_name,
+		d.serialnumber as serial_number,
+		COALESCE(c.name, 'Unknown Category') as category_name,
+		d.status
 		FROM devices d
 		LEFT JOIN products p ON d.productID = p.productID
 		LEFT JOIN categories c ON p.categoryID = c.categoryID
 		WHERE d.deviceID = ?
 	`, deviceID).Scan(&deviceInfo)
 	
-	log.Printf("DEBUG: Device info query error: %v", deviceResult.Error)
-	log.Printf("DEBUG: Device info - ID: %s, Name: %s, Serial: %v, Category: %s, Status: %s", 
+	logger.Errorf("Device info query error: %v", deviceResult.Error)
+	logger.Debugf("Device info - ID: %s, Name: %s, Serial: %v, Category: %s, Status: %s", 
 		deviceInfo.DeviceID, deviceInfo.ProductName, deviceInfo.SerialNumber, deviceInfo.CategoryName, deviceInfo.Status)
 
 	// Get total revenue and booking statistics
@@ -336,36 +513,15 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 	
 	h.db.Raw(`
 		SELECT 
-			COALESCE(SUM(
-				CASE 
-					WHEN jd.custom_price IS NOT NULL THEN 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								jd.custom_price * DATEDIFF(COALESCE(j.endDate, NOW()), j.startDate) * (1 - j.discount/100)
-							WHEN j.discount_type = 'amount' THEN 
-								(jd.custom_price * DATEDIFF(COALESCE(j.endDate, NOW()), j.startDate)) - j.discount
-							ELSE 
-								jd.custom_price * DATEDIFF(COALESCE(j.endDate, NOW()), j.startDate)
-						END
-					ELSE 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								p.itemcostperday * (1 - LEAST(100, j.discount)/100)
-							WHEN j.discount_type = 'amount' THEN 
-								GREATEST(0, p.itemcostperday - j.discount)
-							ELSE 
-								p.itemcostperday
-						END
-				END
-			), 0) as total_revenue,
-			COUNT(DISTINCT j.jobID) as total_bookings,
-			COUNT(DISTINCT j.jobID) as total_rentals,
-			MIN(j.startDate) as first_booking,
-			MAX(j.startDate) as last_booking
+		COALESCE(SUM(` + deviceRevenueSQL() + `), 0) as total_revenue,
+		COUNT(DISTINCT j.jobID) as total_bookings,
+		COUNT(DISTINCT j.jobID) as total_rentals,
+		MIN(j.startDate) as first_booking,
+		MAX(j.startDate) as last_booking
 		FROM jobdevices jd
 		JOIN jobs j ON jd.jobID = j.jobID
 		JOIN products p ON jd.deviceID = ? AND p.productID = (
-			SELECT productID FROM devices WHERE deviceID = ?
+		SELECT productID FROM devices WHERE deviceID = ?
 		)
 		WHERE jd.deviceID = ? 
 		AND j.startDate BETWEEN ? AND ?
@@ -389,13 +545,16 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 		DailyRate     float64   `json:"daily_rate" gorm:"column:daily_rate"`
 		Discount      float64   `json:"discount" gorm:"column:discount"`
 		DiscountType  *string   `json:"discount_type" gorm:"column:discount_type"`
+		JobRevenue    float64   `json:"-" gorm:"column:job_revenue"`
 		JobStatus     string    `json:"job_status" gorm:"column:job_status"`
 	}
 	
 	var customerBookings []CustomerBooking
 	
 	// First try: Simple query to get any bookings for this device
-	log.Printf("DEBUG: Looking for bookings for device: %s", deviceID)
+	logger.Debugf("Looking for bookings for device: %s", deviceID)
+	// The revenue is derived from the day rate below, so the seasonal rate is
+	// looked up once per booking
 	result := h.db.Raw(`
 		SELECT 
 			COALESCE(
@@ -416,32 +575,10 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 				WHEN j.endDate IS NOT NULL THEN DATEDIFF(j.endDate, j.startDate) + 1
 				ELSE DATEDIFF(NOW(), j.startDate) + 1
 			END) as rental_days,
-			CASE 
-				WHEN jd.custom_price IS NOT NULL THEN jd.custom_price
-				ELSE COALESCE(p.itemcostperday, 0)
-			END as daily_rate,
+			` + devicePriceSQL + ` as daily_rate,
 			COALESCE(j.discount, 0) as discount,
 			j.discount_type,
-			GREATEST(0, CASE 
-				WHEN jd.custom_price IS NOT NULL THEN 
-					CASE 
-						WHEN j.discount_type = 'percent' AND j.discount > 0 THEN 
-							jd.custom_price * (1 - LEAST(100, j.discount)/100)
-						WHEN j.discount_type = 'amount' AND j.discount > 0 THEN 
-							GREATEST(0, jd.custom_price - j.discount)
-						ELSE 
-							jd.custom_price
-					END
-				ELSE 
-					CASE 
-						WHEN j.discount_type = 'percent' AND j.discount > 0 THEN 
-							COALESCE(p.itemcostperday, 0) * (1 - LEAST(100, j.discount)/100)
-						WHEN j.discount_type = 'amount' AND j.discount > 0 THEN 
-							GREATEST(0, COALESCE(p.itemcostperday, 0) - j.discount)
-						ELSE 
-							COALESCE(p.itemcostperday, 0)
-					END
-			END) as revenue,
+			COALESCE(j.revenue, 0) as job_revenue,
 			COALESCE(s.status, 'Unknown Status') as job_status
 		FROM jobdevices jd
 		JOIN jobs j ON jd.jobID = j.jobID
@@ -453,21 +590,29 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 		ORDER BY j.startDate DESC
 		LIMIT 50
 	`, deviceID).Scan(&customerBookings)
+	for i := range customerBookings {
+		booking := &customerBookings[i]
+		discountType := ""
+		if booking.DiscountType != nil {
+			discountType = *booking.DiscountType
+		}
+		booking.Revenue = deviceRevenue(booking.DailyRate, booking.Discount, discountType, booking.JobRevenue)
+	}
 	
-	log.Printf("DEBUG: Query result error: %v, found %d bookings", result.Error, len(customerBookings))
-	log.Printf("DEBUG: Device ID requested: %s", deviceID)
+	logger.Errorf("Query result error: %v, found %d bookings", result.Error, len(customerBookings))
+	logger.Debugf("Device ID requested: %s", deviceID)
 	
 	// Debug: print first booking details if any found
 	if len(customerBookings) > 0 {
 		first := customerBookings[0]
-		log.Printf("DEBUG: First booking - Customer: %s, JobID: %s, Start: %v, End: %v, Days: %d, Rate: %.2f, Discount: %.2f (%s), Revenue: %.2f, Status: %s", 
+		logger.Debugf("First booking - Customer: %s, JobID: %s, Start: %v, End: %v, Days: %d, Rate: %.2f, Discount: %.2f (%s), Revenue: %.2f, Status: %s", 
 			first.CustomerName, first.JobID, first.StartDate, first.EndDate, first.RentalDays, first.DailyRate, first.Discount, 
 			*first.DiscountType, first.Revenue, first.JobStatus)
 	}
 	
 	// If no bookings found, try even simpler query
 	if len(customerBookings) == 0 {
-		log.Printf("DEBUG: No bookings found, trying simpler query")
+		logger.Debugf("No bookings found, trying simpler query")
 		h.db.Raw(`
 			SELECT 
 				COALESCE(
@@ -496,10 +641,10 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 			WHERE jd.deviceID = ?
 			LIMIT 5
 		`, deviceID).Scan(&customerBookings)
-		log.Printf("DEBUG: Simpler query found %d bookings", len(customerBookings))
+		logger.Debugf("Simpler query found %d bookings", len(customerBookings))
 		if len(customerBookings) > 0 {
 			first := customerBookings[0]
-			log.Printf("DEBUG: First booking from simpler query - Customer: %s, JobID: %s, Start: %v, End: %v, Days: %d, Rate: %.2f, Revenue: %.2f, Status: %s", 
+			logger.Debugf("First booking from simpler query - Customer: %s, JobID: %s, Start: %v, End: %v, Days: %d, Rate: %.2f, Revenue: %.2f, Status: %s", 
 				first.CustomerName, first.JobID, first.StartDate, first.EndDate, first.RentalDays, first.DailyRate, first.Revenue, first.JobStatus)
 		}
 	}
@@ -515,28 +660,7 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 	h.db.Raw(`
 		SELECT 
 			DATE_FORMAT(j.startDate, '%Y-%m') as month,
-			COALESCE(SUM(
-				CASE 
-					WHEN jd.custom_price IS NOT NULL THEN 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								jd.custom_price * DATEDIFF(COALESCE(j.endDate, NOW()), j.startDate) * (1 - j.discount/100)
-							WHEN j.discount_type = 'amount' THEN 
-								(jd.custom_price * DATEDIFF(COALESCE(j.endDate, NOW()), j.startDate)) - j.discount
-							ELSE 
-								jd.custom_price * DATEDIFF(COALESCE(j.endDate, NOW()), j.startDate)
-						END
-					ELSE 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								p.itemcostperday * (1 - LEAST(100, j.discount)/100)
-							WHEN j.discount_type = 'amount' THEN 
-								GREATEST(0, p.itemcostperday - j.discount)
-							ELSE 
-								p.itemcostperday
-						END
-				END
-			), 0) as revenue,
+			COALESCE(SUM(` + deviceRevenueSQL() + `), 0) as revenue,
 			COUNT(DISTINCT j.jobID) as bookings
 		FROM jobdevices jd
 		JOIN jobs j ON jd.jobID = j.jobID
@@ -549,20 +673,9 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 		LIMIT 12
 	`, deviceID, startDate, endDate).Scan(&monthlyRevenue)
 
-	// Get utilization metrics
-	var utilizationStats struct {
-		DaysBooked     int     `json:"daysBooked"`
-		DaysAvailable  int     `json:"daysAvailable"`
-		UtilizationRate float64 `json:"utilizationRate"`
-	}
-	
-	totalDaysInPeriod := int(endDate.Sub(startDate).Hours() / 24)
-	utilizationStats.DaysAvailable = totalDaysInPeriod
-	utilizationStats.DaysBooked = revenueStats.TotalRentals
-	
-	if totalDaysInPeriod > 0 {
-		utilizationStats.UtilizationRate = (float64(revenueStats.TotalRentals) / float64(totalDaysInPeriod)) * 100
-	}
+	// Get utilization metrics from the days actually booked in the period
+	daysBooked, daysAvailable, utilizationTimeline := h.getDeviceUtilization(deviceID, startDate, endDate)
+	utilizationRate := utilizationPercent(daysBooked, daysAvailable)
 
 	// Transform data for frontend compatibility
 	var bookingCount int = revenueStats.TotalBookings
@@ -582,7 +695,7 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 	for _, monthly := range monthlyRevenue {
 		revenueTrends = append(revenueTrends, map[string]interface{}{
 			"date":    monthly.Month + "-01", // Add day for proper date parsing
-			"revenue": monthly.Revenue,
+			"revenue": h.currency.Round(monthly.Revenue),
 			"jobs":    monthly.Bookings,
 		})
 	}
@@ -620,7 +733,7 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 				}
 				return time.Now().Format("2006-01-02")
 			}(),
-			"revenue": booking.Revenue,
+			"revenue": h.currency.Round(booking.Revenue),
 			"status":  booking.JobStatus,
 		})
 	}
@@ -634,12 +747,18 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 			"status":       deviceInfo.Status,
 		},
 		"revenue": map[string]interface{}{
-			"totalRevenue":     revenueStats.TotalRevenue,
+			"totalRevenue":     h.currency.Round(revenueStats.TotalRevenue),
 			"bookingCount":     bookingCount,
 			"avgDuration":      avgDuration,
-			"avgBookingValue":  avgBookingValue,
-			"utilizationRate":  utilizationStats.UtilizationRate,
+			"avgBookingValue":  h.currency.Round(avgBookingValue),
+			"utilizationRate":  utilizationRate,
 		},
+		"utilization": map[string]interface{}{
+			"daysBooked":      daysBooked,
+			"daysAvailable":   daysAvailable,
+			"utilizationRate": utilizationRate,
+		},
+		"timeline": utilizationTimeline,
 		"trends": map[string]interface{}{
 			"revenue": revenueTrends,
 		},
@@ -649,6 +768,7 @@ func (h *AnalyticsHandler) getDeviceAnalyticsData(deviceID string, startDate, en
 		},
 		"bookings": transformedBookings,
 		"period":   period,
+		"currency": h.currency,
 		"date_range": map[string]interface{}{
 			"start": startDate.Format("2006-01-02"),
 			"end":   endDate.Format("2006-01-02"),
@@ -676,6 +796,14 @@ func (h *AnalyticsHandler) getRevenueAnalytics(startDate, endDate time.Time) map
 			Row().Scan(&totalRevenue, &totalJobs, &avgJobValue)
 	}
 
+	if archivedRevenue, archivedJobs := h.getArchivedTotals(startDate, endDate); archivedJobs > 0 || archivedRevenue != 0 {
+		totalRevenue += archivedRevenue
+		totalJobs += archivedJobs
+		if totalJobs > 0 {
+			avgJobValue = totalRevenue / float64(totalJobs)
+		}
+	}
+
 	// Previous period for comparison
 	prevStartDate := startDate.AddDate(0, 0, -int(endDate.Sub(startDate).Hours()/24))
 	prevEndDate := startDate
@@ -694,6 +822,9 @@ func (h *AnalyticsHandler) getRevenueAnalytics(startDate, endDate time.Time) map
 			Select("COALESCE(SUM(revenue), 0) as total, COUNT(*) as count").
 			Row().Scan(&prevRevenue, &prevJobs)
 	}
+	archivedRevenue, archivedJobs := h.getArchivedTotals(prevStartDate, prevEndDate)
+	prevRevenue += archivedRevenue
+	prevJobs += archivedJobs
 
 	// Calculate growth rates
 	revenueGrowth := float64(0)
@@ -707,9 +838,9 @@ func (h *AnalyticsHandler) getRevenueAnalytics(startDate, endDate time.Time) map
 	}
 
 	return map[string]interface{}{
-		"totalRevenue":   totalRevenue,
+		"totalRevenue":   h.currency.Round(totalRevenue),
 		"totalJobs":      totalJobs,
-		"avgJobValue":    avgJobValue,
+		"avgJobValue":    h.currency.Round(avgJobValue),
 		"revenueGrowth":  revenueGrowth,
 		"jobsGrowth":     jobsGrowth,
 	}
@@ -737,24 +868,7 @@ func (h *AnalyticsHandler) getEquipmentAnalytics(startDate, endDate time.Time) m
 	// Revenue per device - calculate individual device revenue with discount applied
 	var totalDeviceRevenue float64
 	h.db.Raw(`
-		SELECT COALESCE(SUM(
-			CASE 
-				WHEN jd.custom_price IS NOT NULL THEN 
-					CASE 
-						WHEN j.discount_type = 'percent' THEN 
-							jd.custom_price * (1 - j.discount/100)
-						ELSE 
-							jd.custom_price * (1 - (j.discount / NULLIF(j.revenue, 0)))
-					END
-				ELSE 
-					CASE 
-						WHEN j.discount_type = 'percent' THEN 
-							p.itemcostperday * (1 - j.discount/100)
-						ELSE 
-							p.itemcostperday * (1 - (j.discount / NULLIF(j.revenue, 0)))
-					END
-			END
-		), 0)
+		SELECT COALESCE(SUM(` + deviceRevenueSQL() + `), 0)
 		FROM jobs j
 		INNER JOIN jobdevices jd ON j.jobID = jd.jobID
 		INNER JOIN devices d ON jd.deviceID = d.deviceID
@@ -772,7 +886,7 @@ func (h *AnalyticsHandler) getEquipmentAnalytics(startDate, endDate time.Time) m
 		"activeDevices":     activeDevices,
 		"maintenanceDevices": maintenanceDevices,
 		"utilizationRate":   utilizationRate,
-		"revenuePerDevice":  revenuePerDevice,
+		"revenuePerDevice":  h.currency.Round(revenuePerDevice),
 		"availableDevices":  totalDevices - activeDevices - maintenanceDevices,
 	}
 }
@@ -812,13 +926,15 @@ func (h *AnalyticsHandler) getCustomerAnalytics(startDate, endDate time.Time) ma
 
 // getJobAnalytics calculates job metrics
 func (h *AnalyticsHandler) getJobAnalytics(startDate, endDate time.Time) map[string]interface{} {
-	var completedJobs, activeJobs, overdueJobs int64
+	var completedJobs, activeJobs int64
 	var avgJobDuration float64
 
 	// Completed jobs
 	h.db.Model(&models.Job{}).
 		Where("endDate BETWEEN ? AND ? AND statusID IN (?)", startDate, endDate, []int{3, 4}).
 		Count(&completedJobs)
+	_, archivedJobs := h.getArchivedTotals(startDate, endDate)
+	completedJobs += archivedJobs
 
 	// Active jobs
 	h.db.Model(&models.Job{}).
@@ -826,10 +942,11 @@ func (h *AnalyticsHandler) getJobAnalytics(startDate, endDate time.Time) map[str
 			endDate, startDate, []int{1, 2}).
 		Count(&activeJobs)
 
-	// Overdue jobs
-	h.db.Model(&models.Job{}).
-		Where("endDate < ? AND statusID NOT IN (?)", time.Now(), []int{3, 4}).
-		Count(&overdueJobs)
+	// Overdue jobs, as listed by GetOverdueJobsAPI
+	overdueJobs, err := h.jobRepo.CountOverdueJobs(time.Now())
+	if err != nil {
+		logger.Errorf("Failed to count overdue jobs: %v", err)
+	}
 
 	// Average job duration
 	h.db.Model(&models.Job{}).
@@ -847,60 +964,68 @@ func (h *AnalyticsHandler) getJobAnalytics(startDate, endDate time.Time) map[str
 }
 
 // getTopEquipment returns top performing equipment
-func (h *AnalyticsHandler) getTopEquipment(startDate, endDate time.Time, limit int) []map[string]interface{} {
-	var results []map[string]interface{}
+func (h *AnalyticsHandler) getTopEquipment(startDate, endDate time.Time, limit int, refresh bool) []map[string]interface{} {
+	metric := fmt.Sprintf("%s:%s:%d", models.MetricTopEquipment, startDate.Format("2006-01-02"), limit)
+
+	var rows []topEquipmentRow
+	if refresh || !h.loadAnalyticsCache(metric, endDate, &rows) {
+		var err error
+		if rows, err = h.queryTopEquipment(startDate, endDate, limit); err != nil {
+			// Not cached, so the next request retries the query
+			logger.Errorf("Failed to load top equipment: %v", err)
+		} else {
+			h.storeAnalyticsCache(metric, endDate, nil, rows)
+		}
+	}
+
+	results := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, map[string]interface{}{
+			"deviceID":     row.DeviceID,
+			"productName":  row.ProductName,
+			"rentalCount":  row.RentalCount,
+			"totalRevenue": row.TotalRevenue,
+			"avgRevenue":   row.AvgRevenue,
+		})
+	}
+	return results
+}
+
+// topEquipmentRow is a device in the top equipment list, as cached
+type topEquipmentRow struct {
+	DeviceID     string  `json:"deviceID"`
+	ProductName  string  `json:"productName"`
+	RentalCount  int     `json:"rentalCount"`
+	TotalRevenue float64 `json:"totalRevenue"`
+	AvgRevenue   float64 `json:"avgRevenue"`
+}
+
+// queryTopEquipment ranks devices by their discounted revenue from jobs ending in the period
+func (h *AnalyticsHandler) queryTopEquipment(startDate, endDate time.Time, limit int) ([]topEquipmentRow, error) {
+	var results []topEquipmentRow
 
 	rows, err := h.db.Raw(`
 		SELECT 
-			d.deviceID,
-			p.name as product_name,
-			COUNT(jd.jobID) as rental_count,
-			COALESCE(SUM(
-				CASE 
-					WHEN jd.custom_price IS NOT NULL THEN 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								jd.custom_price * (1 - j.discount/100)
-							ELSE 
-								jd.custom_price * (1 - (j.discount / NULLIF(j.revenue, 0)))
-						END
-					ELSE 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								p.itemcostperday * (1 - j.discount/100)
-							ELSE 
-								p.itemcostperday * (1 - (j.discount / NULLIF(j.revenue, 0)))
-						END
-				END
-			), 0) as total_revenue,
-			COALESCE(AVG(
-				CASE 
-					WHEN jd.custom_price IS NOT NULL THEN 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								jd.custom_price * (1 - j.discount/100)
-							ELSE 
-								jd.custom_price * (1 - (j.discount / NULLIF(j.revenue, 0)))
-						END
-					ELSE 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								p.itemcostperday * (1 - j.discount/100)
-							ELSE 
-								p.itemcostperday * (1 - (j.discount / NULLIF(j.revenue, 0)))
-						END
-				END
-			), 0) as avg_revenue
-		FROM devices d
-		LEFT JOIN products p ON d.productID = p.productID
-		LEFT JOIN jobdevices jd ON d.deviceID = jd.deviceID
-		LEFT JOIN jobs j ON jd.jobID = j.jobID AND j.endDate BETWEEN ? AND ? 		GROUP BY d.deviceID, p.name
+			deviceID,
+			product_name,
+			COUNT(job_id) as rental_count,
+			COALESCE(SUM(revenue), 0) as total_revenue,
+			COALESCE(AVG(revenue), 0) as avg_revenue
+		FROM (
+			SELECT d.deviceID, COALESCE(p.name, '') as product_name, j.jobID as job_id,
+				` + deviceRevenueSQL() + ` as revenue
+			FROM devices d
+			LEFT JOIN products p ON d.productID = p.productID
+			LEFT JOIN jobdevices jd ON d.deviceID = jd.deviceID
+			LEFT JOIN jobs j ON jd.jobID = j.jobID AND j.endDate BETWEEN ? AND ?
+		) device_revenue
+		GROUP BY deviceID, product_name
 		ORDER BY total_revenue DESC
 		LIMIT ?
 	`, startDate, endDate, limit).Rows()
 
 	if err != nil {
-		return results
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -909,101 +1034,132 @@ func (h *AnalyticsHandler) getTopEquipment(startDate, endDate time.Time, limit i
 		var rentalCount int
 		var totalRevenue, avgRevenue float64
 
-		rows.Scan(&deviceID, &productName, &rentalCount, &totalRevenue, &avgRevenue)
+		if err := rows.Scan(&deviceID, &productName, &rentalCount, &totalRevenue, &avgRevenue); err != nil {
+			return nil, err
+		}
 		
-		results = append(results, map[string]interface{}{
-			"deviceID":     deviceID,
-			"productName":  productName,
-			"rentalCount":  rentalCount,
-			"totalRevenue": totalRevenue,
-			"avgRevenue":   avgRevenue,
+		results = append(results, topEquipmentRow{
+			DeviceID:     deviceID,
+			ProductName:  productName,
+			RentalCount:  rentalCount,
+			TotalRevenue: h.currency.Round(totalRevenue),
+			AvgRevenue:   h.currency.Round(avgRevenue),
 		})
 	}
 
-	return results
+	return results, rows.Err()
 }
 
 // getAllDeviceRevenues returns revenue data for ALL devices (not limited)
 func (h *AnalyticsHandler) getAllDeviceRevenues(startDate, endDate time.Time, sortColumn, order string) []map[string]interface{} {
 	var results []map[string]interface{}
 
-	// Build the query with dynamic sorting
-	query := `
-		SELECT 
-			d.deviceID,
-			p.name as product_name,
-			COUNT(jd.jobID) as rental_count,
-			COALESCE(SUM(
-				CASE 
-					WHEN jd.custom_price IS NOT NULL THEN 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								jd.custom_price * (1 - j.discount/100)
-							ELSE 
-								jd.custom_price * (1 - (j.discount / NULLIF(j.revenue, 0)))
-						END
-					ELSE 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								p.itemcostperday * (1 - j.discount/100)
-							ELSE 
-								p.itemcostperday * (1 - (j.discount / NULLIF(j.revenue, 0)))
-						END
-				END
-			), 0) as total_revenue,
-			COALESCE(AVG(
-				CASE 
-					WHEN jd.custom_price IS NOT NULL THEN 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								jd.custom_price * (1 - j.discount/100)
-							ELSE 
-								jd.custom_price * (1 - (j.discount / NULLIF(j.revenue, 0)))
-						END
-					ELSE 
-						CASE 
-							WHEN j.discount_type = 'percent' THEN 
-								p.itemcostperday * (1 - j.discount/100)
-							ELSE 
-								p.itemcostperday * (1 - (j.discount / NULLIF(j.revenue, 0)))
-						END
-				END
-			), 0) as avg_revenue,
-			p.itemcostperday as product_price,
-			d.status as device_status
-		FROM devices d
-		LEFT JOIN products p ON d.productID = p.productID
-		LEFT JOIN jobdevices jd ON d.deviceID = jd.deviceID
-		LEFT JOIN jobs j ON jd.jobID = j.jobID AND j.endDate BETWEEN ? AND ? 		GROUP BY d.deviceID, p.name, p.itemcostperday, d.status
-		ORDER BY ` + sortColumn + ` ` + order
-
-	rows, err := h.db.Raw(query, startDate, endDate).Rows()
+	rows, err := h.db.Raw(deviceRevenueQuery(sortColumn, order), startDate, endDate).Rows()
 	if err != nil {
 		return results
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var deviceID, productName, deviceStatus string
-		var rentalCount int
-		var totalRevenue, avgRevenue, productPrice float64
+		row, err := scanDeviceRevenueRow(rows)
+		if err != nil {
+			logger.Errorf("getAllDeviceRevenues: failed to scan row: %v", err)
+			continue
+		}
 
-		rows.Scan(&deviceID, &productName, &rentalCount, &totalRevenue, &avgRevenue, &productPrice, &deviceStatus)
-		
 		results = append(results, map[string]interface{}{
-			"deviceID":     deviceID,
-			"productName":  productName,
-			"rentalCount":  rentalCount,
-			"totalRevenue": totalRevenue,
-			"avgRevenue":   avgRevenue,
-			"productPrice": productPrice,
-			"deviceStatus": deviceStatus,
+			"deviceID":     row.DeviceID,
+			"productName":  row.ProductName,
+			"rentalCount":  row.RentalCount,
+			"totalRevenue": h.currency.Round(row.TotalRevenue),
+			"avgRevenue":   h.currency.Round(row.AvgRevenue),
+			"productPrice": h.currency.Round(row.ProductPrice),
+			"deviceStatus": row.DeviceStatus,
 		})
 	}
 
 	return results
 }
 
+// deviceRevenueRow is one device's revenue summary as produced by deviceRevenueQuery
+type deviceRevenueRow struct {
+	DeviceID     string
+	ProductName  string
+	RentalCount  int
+	TotalRevenue float64
+	AvgRevenue   float64
+	ProductPrice float64
+	DeviceStatus string
+}
+
+// deviceRevenueQuery builds the per-device revenue query with dynamic sorting.
+// sortColumn and order must already be validated by parseDeviceRevenueParams.
+func deviceRevenueQuery(sortColumn, order string) string {
+	return `
+		SELECT 
+			deviceID,
+			product_name,
+			COUNT(job_id) as rental_count,
+			COALESCE(SUM(revenue), 0) as total_revenue,
+			COALESCE(AVG(revenue), 0) as avg_revenue,
+			product_price,
+			device_status
+		FROM (
+			SELECT d.deviceID, p.name as product_name, p.itemcostperday as product_price,
+				d.status as device_status, j.jobID as job_id,
+				` + deviceRevenueSQL() + ` as revenue
+			FROM devices d
+			LEFT JOIN products p ON d.productID = p.productID
+			LEFT JOIN jobdevices jd ON d.deviceID = jd.deviceID
+			LEFT JOIN jobs j ON jd.jobID = j.jobID AND j.endDate BETWEEN ? AND ?
+		) device_revenue
+		GROUP BY deviceID, product_name, product_price, device_status
+		ORDER BY ` + sortColumn + ` ` + order
+}
+
+// scanDeviceRevenueRow scans the current row of a deviceRevenueQuery result,
+// treating devices without a product as having an empty name and price
+func scanDeviceRevenueRow(rows *sql.Rows) (deviceRevenueRow, error) {
+	var row deviceRevenueRow
+	var productName, deviceStatus sql.NullString
+	var productPrice sql.NullFloat64
+
+	if err := rows.Scan(&row.DeviceID, &productName, &row.RentalCount, &row.TotalRevenue, &row.AvgRevenue, &productPrice, &deviceStatus); err != nil {
+		return row, err
+	}
+	row.ProductName = productName.String
+	row.ProductPrice = productPrice.Float64
+	row.DeviceStatus = deviceStatus.String
+	return row, nil
+}
+
+// parseDeviceRevenueParams reads the period and sort query parameters shared by
+// the device revenue JSON API and CSV export
+func parseDeviceRevenueParams(c *gin.Context) (period string, startDate, endDate time.Time, sortColumn, order string) {
+	period, startDate, endDate = analyticsDateRange(c, "1year")
+	sortBy := c.DefaultQuery("sort", "revenue") // revenue, device_id, product_name, rental_count
+	order = c.DefaultQuery("order", "desc")     // asc, desc
+
+	// Validate sort and order parameters
+	validSorts := map[string]string{
+		"revenue":      "total_revenue",
+		"device_id":    "deviceID",
+		"product_name": "product_name",
+		"rental_count": "rental_count",
+	}
+	
+	sortColumn, exists := validSorts[sortBy]
+	if !exists {
+		sortColumn = "total_revenue"
+	}
+	
+	if order != "asc" && order != "desc" {
+		order = "desc"
+	}
+
+	return period, startDate, endDate, sortColumn, order
+}
+
 // getTopCustomers returns top customers by revenue
 func (h *AnalyticsHandler) getTopCustomers(startDate, endDate time.Time, limit int) []map[string]interface{} {
 	var results []map[string]interface{}
@@ -1043,8 +1199,8 @@ func (h *AnalyticsHandler) getTopCustomers(startDate, endDate time.Time, limit i
 			"customerID":   customerID,
 			"customerName": customerName,
 			"jobCount":     jobCount,
-			"totalRevenue": totalRevenue,
-			"avgRevenue":   avgRevenue,
+			"totalRevenue": h.currency.Round(totalRevenue),
+			"avgRevenue":   h.currency.Round(avgRevenue),
 		})
 	}
 
@@ -1052,8 +1208,57 @@ func (h *AnalyticsHandler) getTopCustomers(startDate, endDate time.Time, limit i
 }
 
 // getUtilizationMetrics calculates equipment utilization rates
-func (h *AnalyticsHandler) getUtilizationMetrics() map[string]interface{} {
+func (h *AnalyticsHandler) getUtilizationMetrics(refresh bool) map[string]interface{} {
+	// Utilization reflects current device status, so it is cached per day
+	today := time.Now()
+
+	var rows []utilizationRow
+	if refresh || !h.loadAnalyticsCache(models.MetricDeviceUtilization, today, &rows) {
+		var err error
+		if rows, err = h.queryUtilizationMetrics(); err != nil {
+			// Not cached, so the next request retries the query
+			logger.Errorf("Failed to load utilization metrics: %v", err)
+		} else {
+			var totalDevices, activeDevices int
+			for _, row := range rows {
+				totalDevices += row.TotalDevices
+				activeDevices += row.ActiveDevices
+			}
+			var overallRate *float64
+			if totalDevices > 0 {
+				rate := float64(activeDevices) * 100 / float64(totalDevices)
+				overallRate = &rate
+			}
+			h.storeAnalyticsCache(models.MetricDeviceUtilization, today, overallRate, rows)
+		}
+	}
+
 	var results []map[string]interface{}
+	for _, row := range rows {
+		results = append(results, map[string]interface{}{
+			"productName":     row.ProductName,
+			"totalDevices":    row.TotalDevices,
+			"activeDevices":   row.ActiveDevices,
+			"utilizationRate": row.UtilizationRate,
+		})
+	}
+
+	return map[string]interface{}{
+		"categories": results,
+	}
+}
+
+// utilizationRow is a product's share of devices checked out, as cached
+type utilizationRow struct {
+	ProductName     string  `json:"productName"`
+	TotalDevices    int     `json:"totalDevices"`
+	ActiveDevices   int     `json:"activeDevices"`
+	UtilizationRate float64 `json:"utilizationRate"`
+}
+
+// queryUtilizationMetrics returns the share of each product's devices currently checked out
+func (h *AnalyticsHandler) queryUtilizationMetrics() ([]utilizationRow, error) {
+	var results []utilizationRow
 
 	rows, err := h.db.Raw(`
 		SELECT 
@@ -1069,268 +1274,467 @@ func (h *AnalyticsHandler) getUtilizationMetrics() map[string]interface{} {
 	`).Rows()
 
 	if err != nil {
-		return map[string]interface{}{"categories": results}
+		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var productName string
-		var totalDevices, activeDevices int
-		var utilizationRate float64
-
-		rows.Scan(&productName, &totalDevices, &activeDevices, &utilizationRate)
-		
-		results = append(results, map[string]interface{}{
-			"productName":     productName,
-			"totalDevices":    totalDevices,
-			"activeDevices":   activeDevices,
-			"utilizationRate": utilizationRate,
-		})
+		var row utilizationRow
+		if err := rows.Scan(&row.ProductName, &row.TotalDevices, &row.ActiveDevices, &row.UtilizationRate); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
 	}
 
-	return map[string]interface{}{
-		"categories": results,
-	}
+	return results, rows.Err()
 }
 
-// getTrendData returns daily/weekly trend data for charts
-func (h *AnalyticsHandler) getTrendData(startDate, endDate time.Time) map[string]interface{} {
-	// Daily revenue trend
+// getTrendData returns daily/weekly/monthly trend data for charts, with zero
+// entries for buckets without jobs
+func (h *AnalyticsHandler) getTrendData(startDate, endDate time.Time, granularity string) map[string]interface{} {
+	bucket := trendBucketSQL(granularity, "j.endDate")
 	revenueRows, err := h.db.Raw(`
 		SELECT 
-			DATE(j.endDate) as date,
+			`+bucket+` as date,
 			COALESCE(SUM(j.final_revenue), 0) as revenue,
 			COUNT(j.jobID) as jobs
 		FROM jobs j
 		WHERE j.endDate BETWEEN ? AND ?
-		GROUP BY DATE(j.endDate)
+		GROUP BY `+bucket+`
 		ORDER BY date
 	`, startDate, endDate).Rows()
 
-	var revenueTrend []map[string]interface{}
+	points := make(map[string]trendPoint)
 	if err == nil {
 		defer revenueRows.Close()
 		for revenueRows.Next() {
-			var date time.Time
-			var revenue float64
-			var jobs int
-
-			revenueRows.Scan(&date, &revenue, &jobs)
-			revenueTrend = append(revenueTrend, map[string]interface{}{
-				"date":    date.Format("2006-01-02"),
-				"revenue": revenue,
-				"jobs":    jobs,
-			})
+			var date string
+			var point trendPoint
+
+			revenueRows.Scan(&date, &point.Revenue, &point.Jobs)
+			points[date] = point
 		}
+	} else {
+		logger.Errorf("Failed to load trend data: %v", err)
 	}
+	h.addArchivedTrendPoints(points, startDate, endDate, granularity)
 
 	return map[string]interface{}{
-		"revenue": revenueTrend,
+		"revenue":     fillTrendGaps(points, startDate, endDate, granularity, h.currency),
+		"granularity": granularity,
 	}
 }
 
 // GetRevenueAPI returns revenue data as JSON API
 func (h *AnalyticsHandler) GetRevenueAPI(c *gin.Context) {
-	period := c.DefaultQuery("period", "1year")
-	
-	endDate := time.Now()
-	var startDate time.Time
-	
-	switch period {
-	case "7days":
-		startDate = endDate.AddDate(0, 0, -7)
-	case "30days":
-		startDate = endDate.AddDate(0, 0, -30)
-	case "90days":
-		startDate = endDate.AddDate(0, 0, -90)
-	case "1year":
-		startDate = endDate.AddDate(-1, 0, 0)
-	default:
-		startDate = endDate.AddDate(-1, 0, 0)
-	}
+	_, startDate, endDate := analyticsDateRange(c, "1year")
 
 	analytics := h.getRevenueAnalytics(startDate, endDate)
+	analytics["currency"] = h.currency
 	c.JSON(http.StatusOK, analytics)
 }
 
 // GetEquipmentAPI returns equipment analytics as JSON API
 func (h *AnalyticsHandler) GetEquipmentAPI(c *gin.Context) {
-	period := c.DefaultQuery("period", "1year")
-	
-	endDate := time.Now()
-	var startDate time.Time
-	
-	switch period {
-	case "7days":
-		startDate = endDate.AddDate(0, 0, -7)
-	case "30days":
-		startDate = endDate.AddDate(0, 0, -30)
-	case "90days":
-		startDate = endDate.AddDate(0, 0, -90)
-	case "1year":
-		startDate = endDate.AddDate(-1, 0, 0)
-	default:
-		startDate = endDate.AddDate(-1, 0, 0)
-	}
+	_, startDate, endDate := analyticsDateRange(c, "1year")
 
 	analytics := h.getEquipmentAnalytics(startDate, endDate)
+	analytics["currency"] = h.currency
 	c.JSON(http.StatusOK, analytics)
 }
 
+// GetTopAPI returns the top devices and customers by revenue, as many as the
+// limit query parameter asks for
+func (h *AnalyticsHandler) GetTopAPI(c *gin.Context) {
+	period, startDate, endDate := analyticsDateRange(c, "1year")
+	options := analyticsOptionsFromQuery(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"topEquipment": h.getTopEquipment(startDate, endDate, options.TopLimit, options.Refresh),
+		"topCustomers": h.getTopCustomers(startDate, endDate, options.TopLimit),
+		"period":       period,
+		"limit":        options.TopLimit,
+		"currency":     h.currency,
+	})
+}
+
 // GetAllDeviceRevenuesAPI returns revenue data for ALL devices as JSON API
 func (h *AnalyticsHandler) GetAllDeviceRevenuesAPI(c *gin.Context) {
-	period := c.DefaultQuery("period", "1year")
-	sortBy := c.DefaultQuery("sort", "revenue") // revenue, device_id, product_name, rental_count
-	order := c.DefaultQuery("order", "desc")    // asc, desc
-	
-	endDate := time.Now()
-	var startDate time.Time
-	
-	switch period {
-	case "7days":
-		startDate = endDate.AddDate(0, 0, -7)
-	case "30days":
-		startDate = endDate.AddDate(0, 0, -30)
-	case "90days":
-		startDate = endDate.AddDate(0, 0, -90)
-	case "1year":
-		startDate = endDate.AddDate(-1, 0, 0)
-	default:
-		startDate = endDate.AddDate(-1, 0, 0)
+	period, startDate, endDate, sortColumn, order := parseDeviceRevenueParams(c)
+
+	allDevices := h.getAllDeviceRevenues(startDate, endDate, sortColumn, order)
+	c.JSON(http.StatusOK, gin.H{
+		"devices":  allDevices,
+		"period":   period,
+		"count":    len(allDevices),
+		"currency": h.currency,
+	})
+}
+
+// ExportAllDeviceRevenuesCSV streams revenue data for ALL devices as CSV.
+// Rows are written as the database yields them so large fleets are never
+// buffered in memory.
+func (h *AnalyticsHandler) ExportAllDeviceRevenuesCSV(c *gin.Context) {
+	period, startDate, endDate, sortColumn, order := parseDeviceRevenueParams(c)
+
+	rows, err := h.db.Raw(deviceRevenueQuery(sortColumn, order), startDate, endDate).Rows()
+	if err != nil {
+		logger.Errorf("ExportAllDeviceRevenuesCSV: query failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load device revenues"})
+		return
 	}
+	defer rows.Close()
 
-	// Validate sort and order parameters
-	validSorts := map[string]string{
-		"revenue":      "total_revenue",
-		"device_id":    "d.deviceID",
-		"product_name": "p.name",
-		"rental_count": "rental_count",
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", `attachment; filename="device_revenues_`+period+`_`+time.Now().Format("2006-01-02")+`.csv"`)
+	c.Status(http.StatusOK)
+
+	currency := h.currency
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"Device ID", "Product Name", "Status", "Rental Count",
+		"Total Revenue (" + currency.Code + ")", "Average Revenue (" + currency.Code + ")", "Product Price (" + currency.Code + ")"})
+
+	written := 0
+	for rows.Next() {
+		row, err := scanDeviceRevenueRow(rows)
+		if err != nil {
+			logger.Errorf("ExportAllDeviceRevenuesCSV: failed to scan row: %v", err)
+			continue
+		}
+
+		writer.Write([]string{
+			row.DeviceID,
+			row.ProductName,
+			row.DeviceStatus,
+			strconv.Itoa(row.RentalCount),
+			currency.FormatNumber(row.TotalRevenue),
+			currency.FormatNumber(row.AvgRevenue),
+			currency.FormatNumber(row.ProductPrice),
+		})
+
+		// Push rows to the client in batches instead of holding the whole export
+		written++
+		if written%500 == 0 {
+			writer.Flush()
+			c.Writer.Flush()
+		}
 	}
-	
-	sortColumn, exists := validSorts[sortBy]
-	if !exists {
-		sortColumn = "total_revenue"
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		logger.Errorf("ExportAllDeviceRevenuesCSV: failed to write CSV: %v", err)
 	}
-	
-	if order != "asc" && order != "desc" {
-		order = "desc"
+	if err := rows.Err(); err != nil {
+		logger.Errorf("ExportAllDeviceRevenuesCSV: row iteration failed: %v", err)
 	}
-
-	allDevices := h.getAllDeviceRevenues(startDate, endDate, sortColumn, order)
-	c.JSON(http.StatusOK, gin.H{
-		"devices": allDevices,
-		"period":  period,
-		"count":   len(allDevices),
-	})
 }
 
 // ExportAnalytics exports analytics data to CSV/Excel
 func (h *AnalyticsHandler) ExportAnalytics(c *gin.Context) {
 	format := c.DefaultQuery("format", "csv")
-	period := c.DefaultQuery("period", "1year")
-	
-	endDate := time.Now()
-	var startDate time.Time
-	
-	switch period {
-	case "7days":
-		startDate = endDate.AddDate(0, 0, -7)
-	case "30days":
-		startDate = endDate.AddDate(0, 0, -30)
-	case "90days":
-		startDate = endDate.AddDate(0, 0, -90)
-	case "1year":
-		startDate = endDate.AddDate(-1, 0, 0)
-	default:
-		startDate = endDate.AddDate(-1, 0, 0)
-	}
+	period, startDate, endDate := analyticsDateRange(c, "1year")
 
-	if format == "csv" {
-		h.exportToCSV(c, startDate, endDate)
-	} else if format == "pdf" {
-		h.exportToPDF(c, startDate, endDate)
-	} else {
+	if format != "csv" && format != "pdf" && format != "xlsx" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format"})
+		return
+	}
+
+	options := analyticsOptionsFromQuery(c)
+	filename := fmt.Sprintf("analytics_%s_%s.%s", period, endDate.Format("2006-01-02"), format)
+	if period == AnalyticsPeriodCustom {
+		filename = fmt.Sprintf("analytics_%s_to_%s.%s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), format)
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	switch format {
+	case "csv":
+		h.exportToCSV(c, startDate, endDate, options)
+	case "xlsx":
+		h.exportToXLSX(c, startDate, endDate, options)
+	default:
+		h.exportToPDF(c, startDate, endDate, options)
 	}
 }
 
-// exportToCSV exports analytics data to CSV format
-func (h *AnalyticsHandler) exportToCSV(c *gin.Context, startDate, endDate time.Time) {
-	c.Header("Content-Type", "text/csv")
-	c.Header("Content-Disposition", `attachment; filename="analytics_`+time.Now().Format("2006-01-02")+`.csv"`)
+// exportMoney marks an export value as an amount in the report currency
+type exportMoney float64
+
+// exportTable is one section of the analytics export: a sheet in XLSX, a block in CSV
+type exportTable struct {
+	Name   string
+	Title  string
+	Header []string
+	Rows   [][]interface{}
+}
+
+// analyticsExportTables collects the exported analytics into typed tables.
+// Missing or unexpectedly typed values are exported as zero or left out, so a
+// partial analytics map still produces a partial export.
+func analyticsExportTables(analytics map[string]interface{}, currency models.Currency) []exportTable {
+	revenueTable := exportTable{Name: "Revenue", Header: []string{"Metric", "Value"}}
+	revenueTable.Rows = append(revenueTable.Rows, []interface{}{"Currency", currency.Code})
 
-	// Get analytics data
-	analytics := h.getAnalyticsData(startDate, endDate)
-	
-	// Write CSV headers and data
-	csvData := "Metric,Value\n"
-	
 	// Revenue metrics
 	if revenue, ok := analytics["revenue"].(map[string]interface{}); ok {
-		csvData += "Total Revenue," + strconv.FormatFloat(revenue["totalRevenue"].(float64), 'f', 2, 64) + "\n"
-		csvData += "Total Jobs," + strconv.FormatInt(revenue["totalJobs"].(int64), 10) + "\n"
-		csvData += "Average Job Value," + strconv.FormatFloat(revenue["avgJobValue"].(float64), 'f', 2, 64) + "\n"
-		if growth, ok := revenue["revenueGrowth"].(float64); ok {
-			csvData += "Revenue Growth %," + strconv.FormatFloat(growth, 'f', 1, 64) + "\n"
-		}
-	}
-	
-	// Equipment metrics
-	if equipment, ok := analytics["equipment"].(map[string]interface{}); ok {
-		csvData += "Total Devices," + strconv.FormatInt(equipment["totalDevices"].(int64), 10) + "\n"
-		csvData += "Active Devices," + strconv.FormatInt(equipment["activeDevices"].(int64), 10) + "\n"
-		csvData += "Utilization Rate %," + strconv.FormatFloat(equipment["utilizationRate"].(float64), 'f', 1, 64) + "\n"
-		if revenue, ok := equipment["revenuePerDevice"].(float64); ok {
-			csvData += "Revenue per Device," + strconv.FormatFloat(revenue, 'f', 2, 64) + "\n"
+		revenueTable.Rows = append(revenueTable.Rows,
+			[]interface{}{"Total Revenue", exportMoney(exportFloat(revenue, "totalRevenue"))},
+			[]interface{}{"Total Jobs", exportCount(revenue, "totalJobs")},
+			[]interface{}{"Average Job Value", exportMoney(exportFloat(revenue, "avgJobValue"))},
+		)
+		if growth, ok := exportNumber(revenue["revenueGrowth"]); ok {
+			revenueTable.Rows = append(revenueTable.Rows, []interface{}{"Revenue Growth %", roundPercent(growth)})
 		}
 	}
-	
+
 	// Customer metrics
 	if customers, ok := analytics["customers"].(map[string]interface{}); ok {
-		csvData += "Total Customers," + strconv.FormatInt(customers["totalCustomers"].(int64), 10) + "\n"
-		csvData += "Active Customers," + strconv.FormatInt(customers["activeCustomers"].(int64), 10) + "\n"
-		if retention, ok := customers["retentionRate"].(float64); ok {
-			csvData += "Customer Retention %," + strconv.FormatFloat(retention, 'f', 1, 64) + "\n"
+		revenueTable.Rows = append(revenueTable.Rows,
+			[]interface{}{"Total Customers", exportCount(customers, "totalCustomers")},
+			[]interface{}{"Active Customers", exportCount(customers, "activeCustomers")},
+		)
+		if retention, ok := exportNumber(customers["retentionRate"]); ok {
+			revenueTable.Rows = append(revenueTable.Rows, []interface{}{"Customer Retention %", roundPercent(retention)})
 		}
 	}
-	
+
+	// Equipment metrics
+	equipmentTable := exportTable{Name: "Equipment", Header: []string{"Metric", "Value"}}
+	if equipment, ok := analytics["equipment"].(map[string]interface{}); ok {
+		equipmentTable.Rows = append(equipmentTable.Rows,
+			[]interface{}{"Total Devices", exportCount(equipment, "totalDevices")},
+			[]interface{}{"Active Devices", exportCount(equipment, "activeDevices")},
+			[]interface{}{"Utilization Rate %", roundPercent(exportFloat(equipment, "utilizationRate"))},
+		)
+		if revenue, ok := exportNumber(equipment["revenuePerDevice"]); ok {
+			equipmentTable.Rows = append(equipmentTable.Rows, []interface{}{"Revenue per Device", exportMoney(revenue)})
+		}
+	}
+
 	// Top equipment section
-	csvData += "\nTop Equipment by Revenue\n"
-	csvData += "Device ID,Product Name,Rental Count,Total Revenue\n"
+	topEquipmentTable := exportTable{
+		Name:   "Top Equipment",
+		Title:  "Top Equipment by Revenue",
+		Header: []string{"Device ID", "Product Name", "Rental Count", "Total Revenue"},
+	}
 	if topEquipment, ok := analytics["topEquipment"].([]map[string]interface{}); ok {
 		for _, equipment := range topEquipment {
-			csvData += fmt.Sprintf("%s,%s,%v,%.2f\n",
-				equipment["deviceID"].(string),
-				equipment["productName"].(string),
-				equipment["rentalCount"],
-				equipment["totalRevenue"].(float64),
-			)
+			topEquipmentTable.Rows = append(topEquipmentTable.Rows, []interface{}{
+				exportString(equipment, "deviceID"),
+				exportString(equipment, "productName"),
+				exportCount(equipment, "rentalCount"),
+				exportMoney(exportFloat(equipment, "totalRevenue")),
+			})
 		}
 	}
-	
+
 	// Top customers section
-	csvData += "\nTop Customers by Revenue\n"
-	csvData += "Customer Name,Job Count,Total Revenue\n"
+	topCustomersTable := exportTable{
+		Name:   "Top Customers",
+		Title:  "Top Customers by Revenue",
+		Header: []string{"Customer Name", "Job Count", "Total Revenue"},
+	}
 	if topCustomers, ok := analytics["topCustomers"].([]map[string]interface{}); ok {
 		for _, customer := range topCustomers {
-			csvData += fmt.Sprintf("%s,%v,%.2f\n",
-				customer["customerName"].(string),
-				customer["jobCount"],
-				customer["totalRevenue"].(float64),
-			)
+			topCustomersTable.Rows = append(topCustomersTable.Rows, []interface{}{
+				exportString(customer, "customerName"),
+				exportCount(customer, "jobCount"),
+				exportMoney(exportFloat(customer, "totalRevenue")),
+			})
+		}
+	}
+
+	return []exportTable{revenueTable, equipmentTable, topEquipmentTable, topCustomersTable}
+}
+
+// exportNumber reads a numeric analytics value of any of the types the
+// aggregate queries scan into
+func exportNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func exportFloat(data map[string]interface{}, key string) float64 {
+	value, _ := exportNumber(data[key])
+	return value
+}
+
+func exportCount(data map[string]interface{}, key string) int64 {
+	value, _ := exportNumber(data[key])
+	return int64(math.Round(value))
+}
+
+func exportString(data map[string]interface{}, key string) string {
+	value, _ := data[key].(string)
+	return value
+}
+
+func roundPercent(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
+// exportToCSV exports analytics data to CSV format
+func (h *AnalyticsHandler) exportToCSV(c *gin.Context, startDate, endDate time.Time, options analyticsOptions) {
+	analytics := h.getAnalyticsData(startDate, endDate, options)
+	currency := h.currency
+	tables := analyticsExportTables(analytics, currency)
+
+	c.Header("Content-Type", "text/csv")
+	c.Status(http.StatusOK)
+
+	// encoding/csv quotes fields containing commas, quotes or line breaks
+	writer := csv.NewWriter(c.Writer)
+	for i, table := range tables {
+		// Metric tables share the leading Metric,Value block
+		if table.Title == "" {
+			if i == 0 {
+				writer.Write(table.Header)
+			}
+		} else {
+			writer.Write(nil)
+			writer.Write([]string{table.Title})
+			writer.Write(table.Header)
+		}
+		for _, row := range table.Rows {
+			record := make([]string, len(row))
+			for j, value := range row {
+				record[j] = csvExportValue(value, currency)
+			}
+			writer.Write(record)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		logger.Errorf("exportToCSV: %v", err)
+	}
+}
+
+func csvExportValue(value interface{}, currency models.Currency) string {
+	switch v := value.(type) {
+	case exportMoney:
+		return currency.FormatNumber(float64(v))
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// exportToXLSX exports analytics data as an Excel workbook with one sheet per table
+func (h *AnalyticsHandler) exportToXLSX(c *gin.Context, startDate, endDate time.Time, options analyticsOptions) {
+	analytics := h.getAnalyticsData(startDate, endDate, options)
+	currency := h.currency
+	tables := analyticsExportTables(analytics, currency)
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		logger.Errorf("exportToXLSX: header style: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate Excel file"})
+		return
+	}
+	moneyFormat := "#,##0"
+	if currency.Decimals > 0 {
+		moneyFormat += "." + strings.Repeat("0", currency.Decimals)
+	}
+	moneyStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &moneyFormat})
+	if err != nil {
+		logger.Errorf("exportToXLSX: money style: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate Excel file"})
+		return
+	}
+
+	for i, table := range tables {
+		if err := writeXLSXTable(f, i, table, headerStyle, moneyStyle, currency); err != nil {
+			logger.Errorf("exportToXLSX: sheet %s: %v", table.Name, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate Excel file"})
+			return
 		}
 	}
+	f.SetActiveSheet(0)
 
-	c.String(http.StatusOK, csvData)
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Status(http.StatusOK)
+	if err := f.Write(c.Writer); err != nil {
+		logger.Errorf("exportToXLSX: %v", err)
+	}
+}
+
+// writeXLSXTable writes a table to the sheet at index, creating it unless it is
+// the workbook's initial sheet. Numbers are stored as numeric cells.
+func writeXLSXTable(f *excelize.File, index int, table exportTable, headerStyle, moneyStyle int, currency models.Currency) error {
+	if index == 0 {
+		if err := f.SetSheetName(f.GetSheetName(0), table.Name); err != nil {
+			return err
+		}
+	} else if _, err := f.NewSheet(table.Name); err != nil {
+		return err
+	}
+
+	for col, title := range table.Header {
+		cell, err := excelize.CoordinatesToCellName(col+1, 1)
+		if err != nil {
+			return err
+		}
+		if err := f.SetCellValue(table.Name, cell, title); err != nil {
+			return err
+		}
+		if err := f.SetCellStyle(table.Name, cell, cell, headerStyle); err != nil {
+			return err
+		}
+	}
+
+	for r, row := range table.Rows {
+		for col, value := range row {
+			cell, err := excelize.CoordinatesToCellName(col+1, r+2)
+			if err != nil {
+				return err
+			}
+			if money, ok := value.(exportMoney); ok {
+				if err := f.SetCellValue(table.Name, cell, currency.Round(float64(money))); err != nil {
+					return err
+				}
+				if err := f.SetCellStyle(table.Name, cell, cell, moneyStyle); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := f.SetCellValue(table.Name, cell, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	lastCol, err := excelize.ColumnNumberToName(len(table.Header))
+	if err != nil {
+		return err
+	}
+	return f.SetColWidth(table.Name, "A", lastCol, 22)
 }
 
 // exportToPDF exports analytics data to PDF format
-func (h *AnalyticsHandler) exportToPDF(c *gin.Context, startDate, endDate time.Time) {
+func (h *AnalyticsHandler) exportToPDF(c *gin.Context, startDate, endDate time.Time, options analyticsOptions) {
 	c.Header("Content-Type", "application/pdf")
-	c.Header("Content-Disposition", `attachment; filename="analytics_`+time.Now().Format("2006-01-02")+`.pdf"`)
 
 	// Get analytics data
-	analytics := h.getAnalyticsData(startDate, endDate)
+	analytics := h.getAnalyticsData(startDate, endDate, options)
 
 	// Create PDF
 	pdf := gofpdf.New("P", "mm", "A4", "")
@@ -1363,6 +1767,9 @@ func (h *AnalyticsHandler) exportToPDF(c *gin.Context, startDate, endDate time.T
 	// Top Equipment Table
 	h.addTopEquipmentTable(pdf, analytics["topEquipment"])
 
+	// Utilization vs Target Table
+	h.addUtilizationTargetTable(pdf, analytics["utilizationTargets"])
+
 	// Top Customers Table
 	h.addTopCustomersTable(pdf, analytics["topCustomers"])
 
@@ -1411,7 +1818,7 @@ func (h *AnalyticsHandler) addRevenueMetrics(pdf *gofpdf.Fpdf, data map[string]i
 	
 	// Total Revenue
 	if totalRevenue, ok := data["totalRevenue"].(float64); ok {
-		pdf.Cell(90, 6, fmt.Sprintf("Total Revenue: EUR %.2f", totalRevenue))
+		pdf.Cell(90, 6, "Total Revenue: " + h.currency.Format(totalRevenue))
 	}
 	
 	// Total Jobs
@@ -1425,7 +1832,7 @@ func (h *AnalyticsHandler) addRevenueMetrics(pdf *gofpdf.Fpdf, data map[string]i
 	
 	// Average Job Value
 	if avgJobValue, ok := data["avgJobValue"].(float64); ok {
-		pdf.Cell(90, 6, fmt.Sprintf("Average Job Value: EUR %.2f", avgJobValue))
+		pdf.Cell(90, 6, "Average Job Value: " + h.currency.Format(avgJobValue))
 	}
 	
 	// Revenue Growth
@@ -1461,7 +1868,7 @@ func (h *AnalyticsHandler) addEquipmentMetrics(pdf *gofpdf.Fpdf, data map[string
 	// Revenue per Device
 	if revenuePerDevice, ok := data["revenuePerDevice"].(float64); ok {
 		pdf.SetXY(105, y)
-		pdf.Cell(90, 6, fmt.Sprintf("Revenue per Device: EUR %.2f", revenuePerDevice))
+		pdf.Cell(90, 6, "Revenue per Device: " + h.currency.Format(revenuePerDevice))
 	}
 }
 
@@ -1553,10 +1960,6 @@ func (h *AnalyticsHandler) addTopEquipmentTable(pdf *gofpdf.Fpdf, data interface
 
 	if equipmentList, ok := data.([]map[string]interface{}); ok {
 		for i, equipment := range equipmentList {
-			if i >= 10 { // Limit to top 10
-				break
-			}
-			
 			// Alternate row colors
 			if i%2 == 1 {
 				pdf.SetFillColor(248, 250, 252)
@@ -1582,9 +1985,9 @@ func (h *AnalyticsHandler) addTopEquipmentTable(pdf *gofpdf.Fpdf, data interface
 				rentalCount = strconv.Itoa(count)
 			}
 
-			totalRevenue := "EUR 0.00"
+			totalRevenue := h.currency.Format(0)
 			if revenue, ok := equipment["totalRevenue"].(float64); ok {
-				totalRevenue = fmt.Sprintf("EUR %.2f", revenue)
+				totalRevenue = h.currency.Format(revenue)
 			}
 
 			pdf.CellFormat(40, 6, deviceID, "1", 0, "L", true, 0, "")
@@ -1598,6 +2001,75 @@ func (h *AnalyticsHandler) addTopEquipmentTable(pdf *gofpdf.Fpdf, data interface
 	pdf.Ln(10)
 }
 
+// addUtilizationTargetTable adds category utilization against target to PDF
+func (h *AnalyticsHandler) addUtilizationTargetTable(pdf *gofpdf.Fpdf, data interface{}) {
+	if pdf.GetY() > 220 {
+		pdf.AddPage()
+	}
+
+	// Table title
+	pdf.SetFont("Arial", "B", 14)
+	pdf.SetTextColor(51, 51, 51)
+	pdf.Cell(190, 10, "Utilization vs Target by Category")
+	pdf.Ln(12)
+
+	// Table headers
+	pdf.SetFont("Arial", "B", 9)
+	pdf.SetFillColor(230, 230, 230)
+	pdf.CellFormat(55, 8, "Category", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(20, 8, "Devices", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(25, 8, "Actual", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(25, 8, "Target", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(25, 8, "Variance", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(35, 8, "Assessment", "1", 0, "C", true, 0, "")
+	pdf.Ln(8)
+
+	// Table data
+	pdf.SetFont("Arial", "", 8)
+	pdf.SetFillColor(255, 255, 255)
+
+	if categories, ok := data.([]CategoryUtilization); ok {
+		for _, category := range categories {
+			name := category.CategoryName
+			if len(name) > 30 {
+				name = name[:27] + "..."
+			}
+
+			target, variance := "-", "-"
+			if category.HasTarget {
+				target = fmt.Sprintf("%.1f%%", category.TargetPercent)
+				variance = fmt.Sprintf("%+.1f", category.Variance)
+			}
+
+			assessment := "No target"
+			switch category.Status {
+			case UtilizationOnTarget:
+				assessment = "On target"
+				pdf.SetTextColor(22, 163, 74)
+			case UtilizationOverStocked:
+				assessment = "Over-stocked"
+				pdf.SetTextColor(217, 119, 6)
+			case UtilizationUnderStocked:
+				assessment = "Under-stocked"
+				pdf.SetTextColor(220, 38, 38)
+			default:
+				pdf.SetTextColor(51, 51, 51)
+			}
+
+			pdf.CellFormat(55, 6, name, "1", 0, "L", true, 0, "")
+			pdf.CellFormat(20, 6, strconv.Itoa(category.TotalDevices), "1", 0, "C", true, 0, "")
+			pdf.CellFormat(25, 6, fmt.Sprintf("%.1f%%", category.UtilizationRate), "1", 0, "R", true, 0, "")
+			pdf.CellFormat(25, 6, target, "1", 0, "R", true, 0, "")
+			pdf.CellFormat(25, 6, variance, "1", 0, "R", true, 0, "")
+			pdf.CellFormat(35, 6, assessment, "1", 0, "C", true, 0, "")
+			pdf.Ln(6)
+		}
+	}
+
+	pdf.SetTextColor(51, 51, 51)
+	pdf.Ln(10)
+}
+
 // addTopCustomersTable adds top customers table to PDF
 func (h *AnalyticsHandler) addTopCustomersTable(pdf *gofpdf.Fpdf, data interface{}) {
 	if pdf.GetY() > 220 {
@@ -1626,10 +2098,6 @@ func (h *AnalyticsHandler) addTopCustomersTable(pdf *gofpdf.Fpdf, data interface
 
 	if customerList, ok := data.([]map[string]interface{}); ok {
 		for i, customer := range customerList {
-			if i >= 10 { // Limit to top 10
-				break
-			}
-			
 			// Alternate row colors
 			if i%2 == 1 {
 				pdf.SetFillColor(248, 250, 252)
@@ -1650,14 +2118,14 @@ func (h *AnalyticsHandler) addTopCustomersTable(pdf *gofpdf.Fpdf, data interface
 				jobCount = strconv.Itoa(count)
 			}
 
-			avgRevenue := "EUR 0.00"
+			avgRevenue := h.currency.Format(0)
 			if revenue, ok := customer["avgRevenue"].(float64); ok {
-				avgRevenue = fmt.Sprintf("EUR %.2f", revenue)
+				avgRevenue = h.currency.Format(revenue)
 			}
 
-			totalRevenue := "EUR 0.00"
+			totalRevenue := h.currency.Format(0)
 			if revenue, ok := customer["totalRevenue"].(float64); ok {
-				totalRevenue = fmt.Sprintf("EUR %.2f", revenue)
+				totalRevenue = h.currency.Format(revenue)
 			}
 
 			pdf.CellFormat(70, 6, customerName, "1", 0, "L", true, 0, "")
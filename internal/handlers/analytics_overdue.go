@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultOverdueJobsLimit = 50
+	maxOverdueJobsLimit     = 500
+)
+
+// OverdueJob is a job past its end date that hasn't been completed
+type OverdueJob struct {
+	JobID        uint      `json:"jobID"`
+	CustomerID   uint      `json:"customerID"`
+	CustomerName string    `json:"customerName"`
+	EndDate      time.Time `json:"endDate"`
+	DaysOverdue  int       `json:"daysOverdue"`
+	DeviceCount  int       `json:"deviceCount"`
+}
+
+// GetOverdueJobsAPI lists the overdue jobs counted on the dashboard, longest
+// overdue first. limit (default 50, at most 500) caps the list; total is the
+// number of overdue jobs.
+func (h *AnalyticsHandler) GetOverdueJobsAPI(c *gin.Context) {
+	limit := defaultOverdueJobsLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		limit = parsed
+	}
+	if limit > maxOverdueJobsLimit {
+		limit = maxOverdueJobsLimit
+	}
+
+	now := time.Now()
+	jobs, total, err := h.jobRepo.GetOverdueJobs(now, limit)
+	if err != nil {
+		logger.Errorf("GetOverdueJobsAPI: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load overdue jobs"})
+		return
+	}
+
+	jobIDs := make([]uint, len(jobs))
+	for i, job := range jobs {
+		jobIDs[i] = job.JobID
+	}
+	deviceCounts, err := h.jobRepo.GetJobDeviceCounts(jobIDs)
+	if err != nil {
+		logger.Warnf("GetOverdueJobsAPI: %v", err)
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	results := make([]OverdueJob, 0, len(jobs))
+	for _, job := range jobs {
+		if job.EndDate == nil {
+			continue
+		}
+		endDay := time.Date(job.EndDate.Year(), job.EndDate.Month(), job.EndDate.Day(), 0, 0, 0, 0, time.UTC)
+
+		results = append(results, OverdueJob{
+			JobID:        job.JobID,
+			CustomerID:   job.CustomerID,
+			CustomerName: job.Customer.GetDisplayName(),
+			EndDate:      *job.EndDate,
+			DaysOverdue:  int(math.Round(today.Sub(endDay).Hours() / 24)),
+			DeviceCount:  deviceCounts[job.JobID],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":  results,
+		"total": total,
+		"limit": limit,
+	})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+
+	"go-barcode-webapp/internal/models"
+)
+
+func TestDeviceRevenue(t *testing.T) {
+	tests := []struct {
+		name         string
+		price        float64
+		discount     float64
+		discountType string
+		jobRevenue   float64
+		want         float64
+	}{
+		{"no discount", 50, 0, models.DiscountTypeAmount, 200, 50},
+		{"negative discount", 50, -10, models.DiscountTypePercent, 200, 50},
+		{"percent", 50, 10, models.DiscountTypePercent, 200, 45},
+		{"percent over 100", 40, 150, models.DiscountTypePercent, 200, 0},
+		{"amount", 50, 50, models.DiscountTypeAmount, 200, 37.5},
+		{"amount over job revenue", 40, 500, models.DiscountTypeAmount, 200, 0},
+		{"amount without job revenue", 40, 50, models.DiscountTypeAmount, 0, 40},
+		{"unknown discount type", 40, 50, "", 200, 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := deviceRevenue(tt.price, tt.discount, tt.discountType, tt.jobRevenue)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("deviceRevenue(%v, %v, %q, %v) = %v; want %v",
+					tt.price, tt.discount, tt.discountType, tt.jobRevenue, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-barcode-webapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Trend bucket sizes accepted by the granularity parameter
+const (
+	TrendGranularityDay   = "day"
+	TrendGranularityWeek  = "week"
+	TrendGranularityMonth = "month"
+)
+
+// parseTrendGranularity validates the granularity parameter, defaulting to daily buckets
+func parseTrendGranularity(value string) (string, error) {
+	switch value {
+	case "":
+		return TrendGranularityDay, nil
+	case TrendGranularityDay, TrendGranularityWeek, TrendGranularityMonth:
+		return value, nil
+	default:
+		return "", fmt.Errorf("invalid granularity %q (expected day, week or month)", value)
+	}
+}
+
+// trendBucketSQL returns a SQL expression yielding the bucket key (YYYY-MM-DD of
+// the bucket's first day) for a date column. Weeks start on Monday.
+func trendBucketSQL(granularity, column string) string {
+	switch granularity {
+	case TrendGranularityWeek:
+		return fmt.Sprintf("DATE_FORMAT(DATE_SUB(DATE(%s), INTERVAL WEEKDAY(%s) DAY), '%%Y-%%m-%%d')", column, column)
+	case TrendGranularityMonth:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-01')", column)
+	default:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d')", column)
+	}
+}
+
+// trendBucketStart truncates t to the first day of its bucket, matching trendBucketSQL
+func trendBucketStart(t time.Time, granularity string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch granularity {
+	case TrendGranularityWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case TrendGranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return day
+	}
+}
+
+func nextTrendBucket(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case TrendGranularityWeek:
+		return t.AddDate(0, 0, 7)
+	case TrendGranularityMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+type trendPoint struct {
+	Revenue float64
+	Jobs    int
+}
+
+// fillTrendGaps returns one entry per bucket between startDate and endDate in
+// order, using zero revenue and jobs for buckets without activity so charts
+// don't draw lines across missing days
+func fillTrendGaps(points map[string]trendPoint, startDate, endDate time.Time, granularity string, currency models.Currency) []map[string]interface{} {
+	trends := []map[string]interface{}{}
+	for bucket := trendBucketStart(startDate, granularity); !bucket.After(endDate); bucket = nextTrendBucket(bucket, granularity) {
+		key := bucket.Format("2006-01-02")
+		point := points[key]
+		trends = append(trends, map[string]interface{}{
+			"date":    key,
+			"revenue": currency.Round(point.Revenue),
+			"jobs":    point.Jobs,
+		})
+	}
+	return trends
+}
+
+// GetTrendsAPI returns revenue and job counts per day, week or month for the
+// selected period, including empty buckets
+func (h *AnalyticsHandler) GetTrendsAPI(c *gin.Context) {
+	period, startDate, endDate := analyticsDateRange(c, "30days")
+	granularity, err := parseTrendGranularity(c.Query("granularity"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trends := h.getTrendData(startDate, endDate, granularity)
+	trends["period"] = period
+	trends["granularity"] = granularity
+	trends["startDate"] = startDate.Format("2006-01-02")
+	trends["endDate"] = endDate.Format("2006-01-02")
+	trends["currency"] = h.currency
+	c.JSON(http.StatusOK, trends)
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stocking assessment of a category's utilization against its target
+const (
+	UtilizationNoTarget     = "no_target"
+	UtilizationOnTarget     = "on_target"
+	UtilizationOverStocked  = "over_stocked"
+	UtilizationUnderStocked = "under_stocked"
+)
+
+const (
+	// defaultUtilizationTolerance is how many percentage points below target a
+	// category may fall before it counts as over-stocked
+	defaultUtilizationTolerance = 10.0
+	// defaultUtilizationSaturated is the utilization at which a category is
+	// treated as pinned at capacity and therefore under-stocked
+	defaultUtilizationSaturated = 95.0
+)
+
+// CategoryUtilization compares a category's utilization over a period with its target
+type CategoryUtilization struct {
+	CategoryID      uint    `json:"categoryID"`
+	CategoryName    string  `json:"categoryName"`
+	TotalDevices    int     `json:"totalDevices"`
+	RentedDays      int     `json:"rentedDeviceDays"`
+	UtilizationRate float64 `json:"utilizationRate"`
+	HasTarget       bool    `json:"hasTarget"`
+	TargetPercent   float64 `json:"targetPercent"`
+	Variance        float64 `json:"variance"` // Percentage points above (+) or below (-) target
+	Status          string  `json:"status"`
+}
+
+// utilizationThresholds returns the over-stocked tolerance and the saturation
+// level from the analytics settings, the defaults where they aren't set
+func (h *AnalyticsHandler) utilizationThresholds() (tolerance, saturated float64) {
+	tolerance, saturated = defaultUtilizationTolerance, defaultUtilizationSaturated
+	if h.analyticsConfig != nil {
+		if h.analyticsConfig.UtilizationTolerance > 0 {
+			tolerance = h.analyticsConfig.UtilizationTolerance
+		}
+		if h.analyticsConfig.UtilizationSaturated > 0 {
+			saturated = h.analyticsConfig.UtilizationSaturated
+		}
+	}
+	return tolerance, saturated
+}
+
+func utilizationStatus(rate float64, hasTarget bool, target, tolerance, saturated float64) string {
+	if rate >= saturated {
+		return UtilizationUnderStocked
+	}
+	if !hasTarget {
+		return UtilizationNoTarget
+	}
+	if rate <= target-tolerance {
+		return UtilizationOverStocked
+	}
+	return UtilizationOnTarget
+}
+
+// getCategoryUtilization measures how many device-days of each category were
+// booked on jobs within the period, relative to the category's fleet, and
+// compares the result with the configured target
+func (h *AnalyticsHandler) getCategoryUtilization(startDate, endDate time.Time) []CategoryUtilization {
+	results := []CategoryUtilization{}
+
+	var fleet []struct {
+		CategoryID   uint
+		CategoryName string
+		TotalDevices int
+	}
+	if err := h.db.Raw(`
+		SELECT c.categoryID as category_id, c.name as category_name, COUNT(d.deviceID) as total_devices
+		FROM categories c
+		JOIN products p ON p.categoryID = c.categoryID
+		JOIN devices d ON d.productID = p.productID
+		GROUP BY c.categoryID, c.name
+	`).Scan(&fleet).Error; err != nil {
+		logger.Errorf("Failed to load category fleet sizes: %v", err)
+		return results
+	}
+
+	start := startDate.Format("2006-01-02")
+	end := endDate.Format("2006-01-02")
+	var booked []struct {
+		CategoryID uint
+		RentedDays int
+	}
+	if err := h.db.Raw(`
+		SELECT p.categoryID as category_id,
+			COALESCE(SUM(DATEDIFF(LEAST(DATE(j.endDate), ?), GREATEST(DATE(j.startDate), ?)) + 1), 0) as rented_days
+		FROM jobdevices jd
+		JOIN jobs j ON j.jobID = jd.jobID
+		JOIN devices d ON d.deviceID = jd.deviceID
+		JOIN products p ON p.productID = d.productID
+		WHERE p.categoryID IS NOT NULL
+			AND DATE(j.startDate) <= ? AND DATE(j.endDate) >= ?
+		GROUP BY p.categoryID
+	`, end, start, end, start).Scan(&booked).Error; err != nil {
+		logger.Errorf("Failed to load booked device days: %v", err)
+		return results
+	}
+	rentedDays := make(map[uint]int, len(booked))
+	for _, b := range booked {
+		rentedDays[b.CategoryID] = b.RentedDays
+	}
+
+	var targetRows []models.CategoryUtilizationTarget
+	if err := h.db.Find(&targetRows).Error; err != nil {
+		logger.Errorf("Failed to load utilization targets: %v", err)
+	}
+	targets := make(map[uint]float64, len(targetRows))
+	for _, t := range targetRows {
+		targets[t.CategoryID] = t.TargetPercent
+	}
+
+	tolerance, saturated := h.utilizationThresholds()
+	periodDays := int(endDate.Sub(startDate).Hours()/24) + 1
+	for _, f := range fleet {
+		entry := CategoryUtilization{
+			CategoryID:   f.CategoryID,
+			CategoryName: f.CategoryName,
+			TotalDevices: f.TotalDevices,
+			RentedDays:   rentedDays[f.CategoryID],
+		}
+		if capacity := f.TotalDevices * periodDays; capacity > 0 {
+			entry.UtilizationRate = math.Round(float64(entry.RentedDays)*10000/float64(capacity)) / 100
+		}
+		if target, ok := targets[f.CategoryID]; ok {
+			entry.HasTarget = true
+			entry.TargetPercent = target
+			entry.Variance = math.Round((entry.UtilizationRate-target)*100) / 100
+		}
+		entry.Status = utilizationStatus(entry.UtilizationRate, entry.HasTarget, entry.TargetPercent, tolerance, saturated)
+		results = append(results, entry)
+	}
+
+	sortCategoryUtilization(results)
+	return results
+}
+
+// sortCategoryUtilization orders categories needing a stocking decision first,
+// each group by largest deviation from target, then by name
+func sortCategoryUtilization(results []CategoryUtilization) {
+	sort.SliceStable(results, func(i, j int) bool {
+		fi, fj := needsStockingDecision(results[i].Status), needsStockingDecision(results[j].Status)
+		if fi != fj {
+			return fi
+		}
+		di, dj := math.Abs(results[i].Variance), math.Abs(results[j].Variance)
+		if di != dj {
+			return di > dj
+		}
+		return results[i].CategoryName < results[j].CategoryName
+	})
+}
+
+// needsStockingDecision reports whether a category is over- or under-stocked
+func needsStockingDecision(status string) bool {
+	return status == UtilizationOverStocked || status == UtilizationUnderStocked
+}
+
+// ListUtilizationTargets returns the utilization target of every category that has one
+func (h *AnalyticsHandler) ListUtilizationTargets(c *gin.Context) {
+	var targets []models.CategoryUtilizationTarget
+	if err := h.db.Preload("Category").Find(&targets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch utilization targets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"targets": targets})
+}
+
+// SetUtilizationTarget creates or replaces a category's utilization target
+func (h *AnalyticsHandler) SetUtilizationTarget(c *gin.Context) {
+	if !userHasPermission(h.db, c, "analytics.manage_targets") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	categoryID, err := strconv.ParseUint(c.Param("categoryId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	var req models.CategoryUtilizationTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var category models.Category
+	if err := h.db.First(&category, categoryID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	target := models.CategoryUtilizationTarget{
+		CategoryID:    uint(categoryID),
+		TargetPercent: req.TargetPercent,
+	}
+	if user, ok := GetCurrentUser(c); ok {
+		target.UpdatedBy = &user.UserID
+	}
+	var existing models.CategoryUtilizationTarget
+	if err := h.db.First(&existing, categoryID).Error; err == nil {
+		target.CreatedAt = existing.CreatedAt
+	}
+	if err := h.db.Save(&target).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save utilization target"})
+		return
+	}
+
+	target.Category = &category
+	c.JSON(http.StatusOK, gin.H{"target": target})
+}
+
+// DeleteUtilizationTarget removes a category's utilization target
+func (h *AnalyticsHandler) DeleteUtilizationTarget(c *gin.Context) {
+	if !userHasPermission(h.db, c, "analytics.manage_targets") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	categoryID, err := strconv.ParseUint(c.Param("categoryId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	if err := h.db.Delete(&models.CategoryUtilizationTarget{}, categoryID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete utilization target"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Utilization target removed"})
+}
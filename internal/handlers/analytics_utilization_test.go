@@ -0,0 +1,47 @@
+package handlers
+
+import "testing"
+
+func TestSortCategoryUtilization(t *testing.T) {
+	results := []CategoryUtilization{
+		{CategoryName: "Cables", Variance: 4, Status: UtilizationOnTarget},
+		{CategoryName: "Lighting", Status: UtilizationNoTarget},
+		{CategoryName: "Audio", Variance: -12, Status: UtilizationOverStocked},
+		{CategoryName: "Video", Status: UtilizationUnderStocked},
+		{CategoryName: "Rigging", Variance: -25, Status: UtilizationOverStocked},
+		{CategoryName: "Stage", Variance: 4, Status: UtilizationOnTarget},
+	}
+
+	sortCategoryUtilization(results)
+
+	want := []string{"Rigging", "Audio", "Video", "Cables", "Stage", "Lighting"}
+	for i, name := range want {
+		if results[i].CategoryName != name {
+			t.Fatalf("position %d = %s; want order %v", i, results[i].CategoryName, want)
+		}
+	}
+}
+
+func TestUtilizationStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      float64
+		hasTarget bool
+		target    float64
+		want      string
+	}{
+		{"saturated without target", 96, false, 0, UtilizationUnderStocked},
+		{"saturated above target", 97, true, 80, UtilizationUnderStocked},
+		{"no target", 50, false, 0, UtilizationNoTarget},
+		{"within tolerance", 71, true, 80, UtilizationOnTarget},
+		{"at tolerance", 70, true, 80, UtilizationOverStocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := utilizationStatus(tt.rate, tt.hasTarget, tt.target, 10, 95); got != tt.want {
+				t.Errorf("utilizationStatus(%v) = %s; want %s", tt.rate, got, tt.want)
+			}
+		})
+	}
+}
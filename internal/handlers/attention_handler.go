@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Devices whose next maintenance falls within this many days count as due
+const attentionMaintenanceWindowDays = 7
+
+// Period whose utilization decides whether a category is under-stocked
+const attentionUtilizationDays = 30
+
+// AttentionHandler gathers the operational signals dispatchers act on each
+// morning into one response
+type AttentionHandler struct {
+	jobRepo          *repository.JobRepository
+	deviceRepo       *repository.DeviceRepository
+	damageReportRepo *repository.DamageReportRepository
+	invoiceRepo      *repository.InvoiceRepositoryNew
+	analytics        *AnalyticsHandler
+}
+
+func NewAttentionHandler(jobRepo *repository.JobRepository, deviceRepo *repository.DeviceRepository, damageReportRepo *repository.DamageReportRepository, invoiceRepo *repository.InvoiceRepositoryNew, db *gorm.DB, invoiceConfig *config.InvoiceConfig, analyticsConfig *config.AnalyticsConfig) *AttentionHandler {
+	return &AttentionHandler{
+		jobRepo:          jobRepo,
+		deviceRepo:       deviceRepo,
+		damageReportRepo: damageReportRepo,
+		invoiceRepo:      invoiceRepo,
+		analytics:        NewAnalyticsHandler(db, jobRepo, invoiceConfig, analyticsConfig),
+	}
+}
+
+// AttentionGroup is one kind of actionable item with its total count and the
+// most urgent items
+type AttentionGroup struct {
+	Count  int64       `json:"count"`
+	Items  interface{} `json:"items"`
+	Amount *float64    `json:"amount,omitempty"` // Outstanding money, where the group has any
+}
+
+// GetAttentionItems returns overdue jobs, devices due for maintenance,
+// under-stocked categories, open damage reports and overdue invoices, each
+// with a count and the top ?limit= items (default 5). A group that fails to
+// load is reported in "errors" instead of failing the whole response.
+func (h *AttentionHandler) GetAttentionItems(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	if err != nil || limit <= 0 {
+		limit = 5
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	now := time.Now()
+	response := gin.H{"generatedAt": now, "limit": limit}
+	failed := []string{}
+	var total int64
+
+	add := func(key string, group *AttentionGroup, err error) {
+		if err != nil {
+			logger.Errorf("GetAttentionItems: %s: %v", key, err)
+			failed = append(failed, key)
+			group = &AttentionGroup{Items: []interface{}{}}
+		}
+		total += group.Count
+		response[key] = group
+	}
+
+	jobs, count, err := h.jobRepo.GetOverdueJobs(now, limit)
+	add("overdueJobs", &AttentionGroup{Count: count, Items: jobs}, err)
+
+	devices, count, err := h.deviceRepo.GetMaintenanceDue(now.AddDate(0, 0, attentionMaintenanceWindowDays), limit)
+	add("maintenanceDue", &AttentionGroup{Count: count, Items: devices}, err)
+
+	add("lowStock", h.lowStockCategories(now, limit), nil)
+
+	reports, err := h.damageReportRepo.ListOpen(0)
+	openReports := int64(len(reports))
+	if len(reports) > limit {
+		reports = reports[:limit]
+	}
+	add("openDamageReports", &AttentionGroup{Count: openReports, Items: reports}, err)
+
+	group, err := h.overdueInvoices(now, limit)
+	add("overdueInvoices", group, err)
+
+	response["total"] = total
+	response["errors"] = failed
+	response["currency"] = h.analytics.currency
+	c.JSON(http.StatusOK, response)
+}
+
+// lowStockCategories returns the categories booked to capacity over the last
+// month, using the same assessment as the utilization report
+func (h *AttentionHandler) lowStockCategories(now time.Time, limit int) *AttentionGroup {
+	utilization := h.analytics.getCategoryUtilization(now.AddDate(0, 0, -attentionUtilizationDays), now)
+	underStocked := []CategoryUtilization{}
+	for _, category := range utilization {
+		if category.Status == UtilizationUnderStocked {
+			underStocked = append(underStocked, category)
+		}
+	}
+	count := int64(len(underStocked))
+	if len(underStocked) > limit {
+		underStocked = underStocked[:limit]
+	}
+	return &AttentionGroup{Count: count, Items: underStocked}
+}
+
+// overdueInvoices returns unpaid invoices past their due date, longest overdue
+// first, along with the total outstanding amount
+func (h *AttentionHandler) overdueInvoices(now time.Time, limit int) (*AttentionGroup, error) {
+	invoices, err := h.invoiceRepo.GetOverdueInvoices(now)
+	if err != nil {
+		return nil, err
+	}
+	var balance float64
+	for _, invoice := range invoices {
+		balance += invoice.BalanceDue
+	}
+	balance = h.analytics.currency.Round(balance)
+	count := int64(len(invoices))
+	if len(invoices) > limit {
+		invoices = invoices[:limit]
+	}
+	return &AttentionGroup{Count: count, Items: invoices, Amount: &balance}, nil
+}
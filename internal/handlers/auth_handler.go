@@ -3,14 +3,14 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -39,21 +39,24 @@ func (h *AuthHandler) LoginForm(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "login.html", gin.H{
-		"title": "Login",
+		"title":             "Login",
+		"rememberMeEnabled": h.config.Security.RememberMeTimeout > 0,
 	})
 }
 
 // Login handles user login
 func (h *AuthHandler) Login(c *gin.Context) {
 	var loginData struct {
-		Username string `form:"username" binding:"required"`
-		Password string `form:"password" binding:"required"`
+		Username   string `form:"username" binding:"required"`
+		Password   string `form:"password" binding:"required"`
+		RememberMe bool   `form:"remember_me"`
 	}
 
 	if err := c.ShouldBind(&loginData); err != nil {
 		c.HTML(http.StatusBadRequest, "login.html", gin.H{
-			"title": "Login",
-			"error": "Please fill in all fields",
+			"title":             "Login",
+			"error":             "Please fill in all fields",
+			"rememberMeEnabled": h.config.Security.RememberMeTimeout > 0,
 		})
 		return
 	}
@@ -62,8 +65,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	var user models.User
 	if err := h.db.Where("username = ? AND is_active = ?", loginData.Username, true).First(&user).Error; err != nil {
 		c.HTML(http.StatusUnauthorized, "login.html", gin.H{
-			"title": "Login",
-			"error": "Invalid username or password",
+			"title":             "Login",
+			"error":             "Invalid username or password",
+			"rememberMeEnabled": h.config.Security.RememberMeTimeout > 0,
 		})
 		return
 	}
@@ -71,8 +75,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(loginData.Password)); err != nil {
 		c.HTML(http.StatusUnauthorized, "login.html", gin.H{
-			"title": "Login",
-			"error": "Invalid username or password",
+			"title":             "Login",
+			"error":             "Invalid username or password",
+			"rememberMeEnabled": h.config.Security.RememberMeTimeout > 0,
 		})
 		return
 	}
@@ -85,16 +90,21 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		// Store user info in session temporarily for 2FA verification
 		tempSessionID := h.generateSessionID()
 		tempSession := models.Session{
-			SessionID: tempSessionID,
-			UserID:    user.UserID,
-			ExpiresAt: time.Now().Add(5 * time.Minute), // Short-lived for 2FA verification
-			CreatedAt: time.Now(),
+			SessionID:  tempSessionID,
+			UserID:     user.UserID,
+			ExpiresAt:  time.Now().Add(5 * time.Minute), // Short-lived for 2FA verification
+			CreatedAt:  time.Now(),
+			Persistent: loginData.RememberMe, // Carried over to the full session after 2FA
+			Pending2FA: true,
+			UserAgent:  sessionUserAgent(c),
+			IPAddress:  c.ClientIP(),
 		}
 		
 		if err := h.db.Create(&tempSession).Error; err != nil {
 			c.HTML(http.StatusInternalServerError, "login.html", gin.H{
-				"title": "Login",
-				"error": "Login failed. Please try again.",
+				"title":             "Login",
+				"error":             "Login failed. Please try again.",
+				"rememberMeEnabled": h.config.Security.RememberMeTimeout > 0,
 			})
 			return
 		}
@@ -106,21 +116,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// Create full session (no 2FA required)
-	sessionID := h.generateSessionID()
-	sessionTimeout := time.Duration(h.config.Security.SessionTimeout) * time.Second
-	session := models.Session{
-		SessionID: sessionID,
-		UserID:    user.UserID,
-		ExpiresAt: time.Now().Add(sessionTimeout),
-		CreatedAt: time.Now(),
-	}
-
-	fmt.Printf("DEBUG: Creating session for user %s (ID: %d)\n", user.Username, user.UserID)
-	if err := h.db.Create(&session).Error; err != nil {
-		fmt.Printf("DEBUG: Session creation failed: %v\n", err)
+	logger.Debugf("Creating session for user %s (ID: %d)", user.Username, user.UserID)
+	if err := h.startSession(c, user.UserID, loginData.RememberMe); err != nil {
+		logger.Errorf("Session creation failed: %v", err)
 		c.HTML(http.StatusInternalServerError, "login.html", gin.H{
-			"title": "Login",
-			"error": "Login failed. Please try again.",
+			"title":             "Login",
+			"error":             "Login failed. Please try again.",
+			"rememberMeEnabled": h.config.Security.RememberMeTimeout > 0,
 		})
 		return
 	}
@@ -130,9 +132,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	user.LastLogin = &now
 	h.db.Save(&user)
 
-	// Set cookie
-	c.SetCookie("session_id", sessionID, h.config.Security.SessionTimeout, "/", "", false, true)
-	fmt.Printf("DEBUG: Login successful, session created: %s\n", sessionID)
+	logger.Infof("Login successful for user %s", user.Username)
 
 	// Redirect to home
 	c.Redirect(http.StatusSeeOther, "/")
@@ -182,7 +182,7 @@ func (h *AuthHandler) Login2FAVerify(c *gin.Context) {
 
 	// Find temp session
 	var tempSession models.Session
-	if err := h.db.Where("session_id = ? AND expires_at > ?", tempSessionID, time.Now()).First(&tempSession).Error; err != nil {
+	if err := h.db.Where("session_id = ? AND pending_2fa = ? AND expires_at > ?", tempSessionID, true, time.Now()).First(&tempSession).Error; err != nil {
 		c.SetCookie("temp_session_id", "", -1, "/", "", false, true) // Clear cookie
 		c.HTML(http.StatusUnauthorized, "login_2fa.html", gin.H{
 			"title": "Two-Factor Authentication", 
@@ -211,25 +211,12 @@ func (h *AuthHandler) Login2FAVerify(c *gin.Context) {
 	// Verify TOTP code
 	valid := totp.Validate(verifyData.Code, secret)
 	if !valid {
-		// Check backup codes
-		var backupCodesJSON string
-		h.db.Raw("SELECT backup_codes FROM user_2fa WHERE user_id = ?", user.UserID).Scan(&backupCodesJSON)
-		
-		if backupCodesJSON != "" {
-			var backupCodes []string
-			if json.Unmarshal([]byte(backupCodesJSON), &backupCodes) == nil {
-				for i, backupCode := range backupCodes {
-					if backupCode == verifyData.Code {
-						valid = true
-						// Remove used backup code
-						backupCodes = append(backupCodes[:i], backupCodes[i+1:]...)
-						newBackupCodesJSON, _ := json.Marshal(backupCodes)
-						h.db.Exec("UPDATE user_2fa SET backup_codes = ? WHERE user_id = ?", string(newBackupCodesJSON), user.UserID)
-						break
-					}
-				}
-			}
+		// Check backup codes; a used code is removed
+		used, err := consumeBackupCode(h.db, user.UserID, verifyData.Code)
+		if err != nil {
+			logger.Errorf("Failed to check backup codes of user %d: %v", user.UserID, err)
 		}
+		valid = used
 	}
 
 	if !valid {
@@ -245,16 +232,7 @@ func (h *AuthHandler) Login2FAVerify(c *gin.Context) {
 	c.SetCookie("temp_session_id", "", -1, "/", "", false, true)
 
 	// Create full session
-	sessionID := h.generateSessionID()
-	sessionTimeout := time.Duration(h.config.Security.SessionTimeout) * time.Second
-	session := models.Session{
-		SessionID: sessionID,
-		UserID:    user.UserID,
-		ExpiresAt: time.Now().Add(sessionTimeout),
-		CreatedAt: time.Now(),
-	}
-
-	if err := h.db.Create(&session).Error; err != nil {
+	if err := h.startSession(c, user.UserID, tempSession.Persistent); err != nil {
 		c.HTML(http.StatusInternalServerError, "login_2fa.html", gin.H{
 			"title": "Two-Factor Authentication",
 			"error": "Login failed. Please try again.",
@@ -267,32 +245,62 @@ func (h *AuthHandler) Login2FAVerify(c *gin.Context) {
 	user.LastLogin = &now
 	h.db.Save(&user)
 
-	// Set cookie
-	c.SetCookie("session_id", sessionID, h.config.Security.SessionTimeout, "/", "", false, true)
-
 	// Redirect to home
 	c.Redirect(http.StatusSeeOther, "/")
 }
 
+// startSession creates a session for the user and sets its cookie. A persistent
+// ("remember me") session lives for RememberMeTimeout and its cookie survives
+// browser restarts; otherwise the cookie has no Max-Age, so the browser drops it
+// on close, and the server expires the session after SessionTimeout either way.
+func (h *AuthHandler) startSession(c *gin.Context, userID uint, persistent bool) error {
+	persistent = persistent && h.config.Security.RememberMeTimeout > 0
+
+	timeout := h.config.Security.SessionTimeout
+	if persistent {
+		timeout = h.config.Security.RememberMeTimeout
+	}
+
+	session := models.Session{
+		SessionID:  h.generateSessionID(),
+		UserID:     userID,
+		ExpiresAt:  time.Now().Add(time.Duration(timeout) * time.Second),
+		CreatedAt:  time.Now(),
+		Persistent: persistent,
+		UserAgent:  sessionUserAgent(c),
+		IPAddress:  c.ClientIP(),
+	}
+	if err := h.db.Create(&session).Error; err != nil {
+		return err
+	}
+
+	cookieMaxAge := 0 // Browser session cookie
+	if persistent {
+		cookieMaxAge = timeout
+	}
+	c.SetCookie("session_id", session.SessionID, cookieMaxAge, "/", "", false, true)
+	return nil
+}
+
 // AuthMiddleware checks if user is authenticated
 func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log.Printf("DEBUG: AuthMiddleware: Request URL: %s", c.Request.URL.Path)
+		logger.Debugf("AuthMiddleware: Request URL: %s", c.Request.URL.Path)
 		
 		sessionID, err := c.Cookie("session_id")
 		if err != nil || sessionID == "" {
-			log.Printf("DEBUG: AuthMiddleware: No session cookie found for %s, redirecting to /login", c.Request.URL.Path)
+			logger.Debugf("AuthMiddleware: No session cookie found for %s, redirecting to /login", c.Request.URL.Path)
 			c.Redirect(http.StatusSeeOther, "/login")
 			c.Abort()
 			return
 		}
 
-		log.Printf("DEBUG: AuthMiddleware: Found session cookie: %s for %s", sessionID, c.Request.URL.Path)
+		logger.Debugf("AuthMiddleware: Found session cookie for %s", c.Request.URL.Path)
 
 		// Validate session
 		var session models.Session
-		if err := h.db.Where("session_id = ? AND expires_at > ?", sessionID, time.Now()).First(&session).Error; err != nil {
-			log.Printf("DEBUG: AuthMiddleware: Session validation failed for %s: %v", sessionID, err)
+		if err := h.db.Where("session_id = ? AND pending_2fa = ? AND expires_at > ?", sessionID, false, time.Now()).First(&session).Error; err != nil {
+			logger.Debugf("AuthMiddleware: Session validation failed for %s: %v", c.Request.URL.Path, err)
 			// Clean up invalid session cookie
 			c.SetCookie("session_id", "", -1, "/", "", false, true)
 			c.Redirect(http.StatusSeeOther, "/login")
@@ -303,7 +311,7 @@ func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 		// Load the user and verify they are still active
 		var user models.User
 		if err := h.db.Where("userID = ? AND is_active = ?", session.UserID, true).First(&user).Error; err != nil {
-			log.Printf("DEBUG: AuthMiddleware: User not found or inactive for session %s (UserID: %d): %v", sessionID, session.UserID, err)
+			logger.Debugf("AuthMiddleware: User not found or inactive (UserID: %d): %v", session.UserID, err)
 			// Delete the session since user is inactive/deleted
 			h.db.Where("session_id = ?", sessionID).Delete(&models.Session{})
 			c.SetCookie("session_id", "", -1, "/", "", false, true)
@@ -312,13 +320,12 @@ func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		log.Printf("DEBUG: AuthMiddleware: Session valid for user: %s (ID: %d) for URL: %s", user.Username, user.UserID, c.Request.URL.Path)
+		logger.Debugf("AuthMiddleware: Session valid for user: %s (ID: %d) for URL: %s", user.Username, user.UserID, c.Request.URL.Path)
 
-		// Optional: Extend session on activity (sliding expiration)
-		// Uncomment if you want sessions to extend on each request
-		// sessionTimeout := time.Duration(h.config.Security.SessionTimeout) * time.Second
-		// session.ExpiresAt = time.Now().Add(sessionTimeout)
-		// h.db.Save(&session)
+		if h.config.Security.SlidingSessions {
+			h.extendSession(c, &session)
+		}
+		h.touchSession(&session)
 
 		// Store user in context
 		c.Set("user", user)
@@ -327,10 +334,77 @@ func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// slidingSessionInterval is the least time between two extensions of a
+// session, so active users don't cause a database write on every request
+const slidingSessionInterval = time.Minute
+
+// slidingExpiry returns when a session active at now expires under sliding
+// expiration, and whether that is far enough past its current expiry to be
+// worth saving
+func slidingExpiry(expiresAt, now time.Time, timeout time.Duration) (time.Time, bool) {
+	extended := now.Add(timeout)
+	return extended, extended.Sub(expiresAt) >= slidingSessionInterval
+}
+
+// extendSession moves the expiry of an active session to a full timeout from
+// now, at most once per slidingSessionInterval. The cookie of a persistent
+// session is renewed with it.
+func (h *AuthHandler) extendSession(c *gin.Context, session *models.Session) {
+	timeout := h.config.Security.SessionTimeout
+	if session.Persistent && h.config.Security.RememberMeTimeout > 0 {
+		timeout = h.config.Security.RememberMeTimeout
+	}
+
+	expiresAt, due := slidingExpiry(session.ExpiresAt, time.Now(), time.Duration(timeout)*time.Second)
+	if !due {
+		return
+	}
+	if err := h.db.Model(&models.Session{}).
+		Where("session_id = ?", session.SessionID).
+		Update("expires_at", expiresAt).Error; err != nil {
+		logger.Warnf("Failed to extend session of user %d: %v", session.UserID, err)
+		return
+	}
+	session.ExpiresAt = expiresAt
+
+	if session.Persistent {
+		c.SetCookie("session_id", session.SessionID, timeout, "/", "", false, true)
+	}
+}
+
+// touchSession records when a session was last used, at most once per
+// slidingSessionInterval
+func (h *AuthHandler) touchSession(session *models.Session) {
+	now := time.Now()
+	if session.LastSeen != nil && now.Sub(*session.LastSeen) < slidingSessionInterval {
+		return
+	}
+	if err := h.db.Model(&models.Session{}).
+		Where("session_id = ?", session.SessionID).
+		Update("last_seen", now).Error; err != nil {
+		logger.Warnf("Failed to update last use of session of user %d: %v", session.UserID, err)
+		return
+	}
+	session.LastSeen = &now
+}
+
+// sessionUserAgentMaxLength is the size of sessions.user_agent
+const sessionUserAgentMaxLength = 512
+
+// sessionUserAgent returns the User-Agent of the request, cut to fit the
+// sessions table
+func sessionUserAgent(c *gin.Context) string {
+	userAgent := c.GetHeader("User-Agent")
+	if len(userAgent) > sessionUserAgentMaxLength {
+		userAgent = userAgent[:sessionUserAgentMaxLength]
+	}
+	return userAgent
+}
+
 // validateSession checks if a session is valid and the user is active
 func (h *AuthHandler) validateSession(sessionID string) bool {
 	var session models.Session
-	if err := h.db.Where("session_id = ? AND expires_at > ?", sessionID, time.Now()).First(&session).Error; err != nil {
+	if err := h.db.Where("session_id = ? AND pending_2fa = ? AND expires_at > ?", sessionID, false, time.Now()).First(&session).Error; err != nil {
 		return false
 	}
 	
@@ -354,7 +428,7 @@ func (h *AuthHandler) CleanupExpiredSessions() error {
 	}
 	
 	if result.RowsAffected > 0 {
-		fmt.Printf("DEBUG: Cleaned up %d expired sessions\n", result.RowsAffected)
+		logger.Infof("Cleaned up %d expired sessions", result.RowsAffected)
 	}
 	
 	return nil
@@ -370,7 +444,7 @@ func (h *AuthHandler) StartSessionCleanup() {
 			select {
 			case <-ticker.C:
 				if err := h.CleanupExpiredSessions(); err != nil {
-					fmt.Printf("ERROR: Failed to cleanup expired sessions: %v\n", err)
+					logger.Errorf("Failed to cleanup expired sessions: %v", err)
 				}
 			}
 		}
@@ -383,18 +457,20 @@ func HashPassword(password string) (string, error) {
 	return string(bytes), err
 }
 
-// CreateUser creates a new user (helper function for user management)
-func (h *AuthHandler) CreateUser(username, email, password, firstName, lastName string) error {
+// CreateUser creates a new user (helper function for user management) and
+// assigns the configured default role. The returned role assignment is nil
+// when no default role is configured or the role is unavailable.
+func (h *AuthHandler) CreateUser(username, email, password, firstName, lastName string, assignedBy *uint) (*models.User, *models.UserRole, error) {
 	// Check if user already exists
 	var existingUser models.User
 	if err := h.db.Where("username = ? OR email = ?", username, email).First(&existingUser).Error; err == nil {
-		return gorm.ErrDuplicatedKey
+		return nil, nil, gorm.ErrDuplicatedKey
 	}
 
 	// Hash password
 	hashedPassword, err := HashPassword(password)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	// Create user
@@ -409,7 +485,38 @@ func (h *AuthHandler) CreateUser(username, email, password, firstName, lastName
 		UpdatedAt:    time.Now(),
 	}
 
-	return h.db.Create(&user).Error
+	var userRole *models.UserRole
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		roleName := h.config.Security.DefaultUserRole
+		if roleName == "" {
+			return nil
+		}
+		var role models.Role
+		if err := tx.Where("name = ? AND is_active = ?", roleName, true).First(&role).Error; err != nil {
+			logger.Warnf("Default role %q not found or inactive, user %s created without a role", roleName, username)
+			return nil
+		}
+		userRole = &models.UserRole{
+			UserID:     user.UserID,
+			RoleID:     role.RoleID,
+			AssignedAt: time.Now(),
+			AssignedBy: assignedBy,
+			IsActive:   true,
+		}
+		if err := tx.Create(userRole).Error; err != nil {
+			return fmt.Errorf("failed to assign default role %q: %v", roleName, err)
+		}
+		userRole.Role = &role
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &user, userRole, nil
 }
 
 // GetCurrentUser returns the current authenticated user
@@ -424,53 +531,112 @@ func GetCurrentUser(c *gin.Context) (*models.User, bool) {
 
 // User Management Web Interface Handlers
 
-// ListUsers displays all users
+// Page sizes of the user lists
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+// userPage is one page of the user list
+type userPage struct {
+	Users      []models.User
+	Total      int64
+	Page       int
+	PageSize   int
+	TotalPages int
+	Query      string
+}
+
+// findUsers loads the page of users selected by the page, pageSize and q
+// query parameters, newest first. q matches username, email and name.
+func (h *AuthHandler) findUsers(c *gin.Context) (*userPage, error) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(defaultUserPageSize)))
+	if pageSize < 1 {
+		pageSize = defaultUserPageSize
+	}
+	if pageSize > maxUserPageSize {
+		pageSize = maxUserPageSize
+	}
+	result := &userPage{Page: page, PageSize: pageSize, Query: strings.TrimSpace(c.Query("q"))}
+
+	query := h.db.Model(&models.User{})
+	if result.Query != "" {
+		_, pattern := searchPatterns(result.Query)
+		query = query.Where("LOWER(username) LIKE ? OR LOWER(email) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ? OR LOWER(CONCAT_WS(' ', first_name, last_name)) LIKE ?",
+			pattern, pattern, pattern, pattern, pattern)
+	}
+	if err := query.Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&result.Users).Error; err != nil {
+		return nil, err
+	}
+
+	result.TotalPages = int((result.Total + int64(pageSize) - 1) / int64(pageSize))
+	if result.TotalPages == 0 {
+		result.TotalPages = 1
+	}
+	return result, nil
+}
+
+// ListUsers displays a page of users
 func (h *AuthHandler) ListUsers(c *gin.Context) {
-	fmt.Printf("DEBUG: ListUsers called - URL: %s\n", c.Request.URL.Path)
+	logger.Debugf("ListUsers called - URL: %s", c.Request.URL.Path)
 	
-	var users []models.User
-	if err := h.db.Order("created_at DESC").Find(&users).Error; err != nil {
-		fmt.Printf("DEBUG: Database error: %v\n", err)
+	result, err := h.findUsers(c)
+	if err != nil {
+		logger.Errorf("Database error: %v", err)
 		currentUser, _ := GetCurrentUser(c)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error(), "user": currentUser})
 		return
 	}
 
-	fmt.Printf("DEBUG: Found %d users\n", len(users))
+	logger.Debugf("Found %d of %d users", len(result.Users), result.Total)
 	currentUser, exists := GetCurrentUser(c)
-	fmt.Printf("DEBUG: Current user exists: %v, User: %+v\n", exists, currentUser)
+	logger.Debugf("Current user exists: %v", exists)
 	
-	fmt.Printf("DEBUG: Rendering users_list.html with currentPage = 'users'\n")
+	logger.Debugf("Rendering users_list.html with currentPage = 'users'")
 	c.HTML(http.StatusOK, "users_list.html", gin.H{
 		"title":       "User Management",
-		"users":       users,
+		"users":       result.Users,
+		"totalUsers":  result.Total,
+		"pageNumber":  result.Page,
+		"pageSize":    result.PageSize,
+		"totalPages":  result.TotalPages,
+		"query":       result.Query,
 		"user":        currentUser,
 		"currentPage": "users",
 	})
-	fmt.Printf("DEBUG: ListUsers template rendered\n")
+	logger.Debugf("ListUsers template rendered")
 }
 
 // NewUserForm displays the create user form
 func (h *AuthHandler) NewUserForm(c *gin.Context) {
 	// Debug: Let's see what's happening
-	fmt.Printf("DEBUG: NewUserForm called - URL: %s\n", c.Request.URL.Path)
+	logger.Debugf("NewUserForm called - URL: %s", c.Request.URL.Path)
 	
 	currentUser, exists := GetCurrentUser(c)
-	fmt.Printf("DEBUG: User exists: %v, User: %+v\n", exists, currentUser)
+	logger.Debugf("User exists: %v", exists)
 	
 	if !exists || currentUser == nil {
-		fmt.Printf("DEBUG: No user found, redirecting to login\n")
+		logger.Debugf("No user found, redirecting to login")
 		c.Redirect(http.StatusSeeOther, "/login")
 		return
 	}
 	
-	fmt.Printf("DEBUG: Rendering user_form.html template\n")
+	logger.Debugf("Rendering user_form.html template")
 	c.HTML(http.StatusOK, "user_form.html", gin.H{
 		"title":    "Create New User",
 		"formUser": &models.User{},
 		"user":     currentUser,
 	})
-	fmt.Printf("DEBUG: Template rendered successfully\n")
+	logger.Debugf("Template rendered successfully")
 }
 
 // CreateUserWeb handles user creation from web form
@@ -501,7 +667,14 @@ func (h *AuthHandler) CreateUserWeb(c *gin.Context) {
 		return
 	}
 
-	if err := h.CreateUser(username, email, password, firstName, lastName); err != nil {
+	currentUser, _ := GetCurrentUser(c)
+	var assignedBy *uint
+	if currentUser != nil {
+		assignedBy = &currentUser.UserID
+	}
+
+	user, userRole, err := h.CreateUser(username, email, password, firstName, lastName, assignedBy)
+	if err != nil {
 		var errorMsg string
 		if err == gorm.ErrDuplicatedKey {
 			errorMsg = "User with this username or email already exists"
@@ -509,7 +682,6 @@ func (h *AuthHandler) CreateUserWeb(c *gin.Context) {
 			errorMsg = err.Error()
 		}
 		
-		currentUser, _ := GetCurrentUser(c)
 		c.HTML(http.StatusInternalServerError, "user_form.html", gin.H{
 			"title": "Create New User",
 			"formUser": &models.User{
@@ -525,6 +697,10 @@ func (h *AuthHandler) CreateUserWeb(c *gin.Context) {
 		return
 	}
 
+	if userRole != nil {
+		writeAuditLog(h.db, c, "assign_role", "user", strconv.FormatUint(uint64(user.UserID), 10), nil, userRole)
+	}
+
 	c.Redirect(http.StatusFound, "/users")
 }
 
@@ -830,35 +1006,11 @@ func (h *AuthHandler) AdminBlockUser(c *gin.Context) {
 
 // hasAdminPermission checks if user has admin privileges
 func (h *AuthHandler) hasAdminPermission(user *models.User) bool {
-	// System admin always has permission
-	if user.Username == "admin" {
-		return true
-	}
-
-	// Check if user has admin role or specific permissions
-	var userRoles []models.UserRole
-	if err := h.db.Preload("Role").Where("userID = ? AND is_active = ?", user.UserID, true).Find(&userRoles).Error; err != nil {
+	granted, err := effectivePermissions(h.db, user)
+	if err != nil {
 		return false
 	}
-
-	for _, userRole := range userRoles {
-		if userRole.Role == nil || !userRole.Role.IsActive {
-			continue
-		}
-
-		var permissions []string
-		if err := json.Unmarshal(userRole.Role.Permissions, &permissions); err != nil {
-			continue
-		}
-
-		for _, perm := range permissions {
-			if perm == "*" || perm == "users.manage" || perm == "users.admin" {
-				return true
-			}
-		}
-	}
-
-	return false
+	return granted["*"] || granted["users.manage"]
 }
 
 // logAdminAction logs admin actions for auditing
@@ -2,12 +2,12 @@ package handlers
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 
@@ -25,13 +25,13 @@ func NewCableHandler(cableRepo *repository.CableRepository) *CableHandler {
 // Web interface handlers
 func (h *CableHandler) ListCablesWeb(c *gin.Context) {
 	startTime := time.Now()
-	log.Printf("🚀 CableHandler.ListCablesWeb() started")
+	logger.Debugf("CableHandler.ListCablesWeb() started")
 	
 	user, _ := GetCurrentUser(c)
 	
 	params := &models.FilterParams{}
 	if err := c.ShouldBindQuery(params); err != nil {
-		log.Printf("❌ Error binding query parameters: %v", err)
+		logger.Errorf("Error binding query parameters: %v", err)
 		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/error?code=400&message=Bad Request&details=%s", err.Error()))
 		return
 	}
@@ -54,16 +54,16 @@ func (h *CableHandler) ListCablesWeb(c *gin.Context) {
 	params.Page = page
 
 	viewType := c.DefaultQuery("view", "list") // Default to list view
-	log.Printf("🐛 DEBUG: Cable view requested: viewType='%s'", viewType)
+	logger.Debugf("Cable view requested: viewType='%s'", viewType)
 
 	// Get cables from database (grouped by specifications)
 	dbStart := time.Now()
 	cableGroups, err := h.cableRepo.ListGrouped(params)
 	dbTime := time.Since(dbStart)
-	log.Printf("⏱️  Database query took: %v", dbTime)
+	logger.Debugf("Database query took: %v", dbTime)
 	
 	if err != nil {
-		log.Printf("❌ Database error: %v", err)
+		logger.Errorf("Database error: %v", err)
 		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/error?code=500&message=Database Error&details=%s", err.Error()))
 		return
 	}
@@ -71,7 +71,7 @@ func (h *CableHandler) ListCablesWeb(c *gin.Context) {
 	// Get total cable count for pagination
 	totalCables, err := h.cableRepo.GetTotalCount()
 	if err != nil {
-		log.Printf("❌ Error getting total cable count: %v", err)
+		logger.Errorf("Error getting total cable count: %v", err)
 		totalCables = 0
 	}
 	
@@ -96,8 +96,8 @@ func (h *CableHandler) ListCablesWeb(c *gin.Context) {
 	
 	templateTime := time.Since(templateStart)
 	totalTime := time.Since(startTime)
-	log.Printf("⏱️  Template rendering took: %v", templateTime)
-	log.Printf("🏁 CableHandler.ListCablesWeb() completed in %v", totalTime)
+	logger.Debugf("Template rendering took: %v", templateTime)
+	logger.Debugf("CableHandler.ListCablesWeb() completed in %v", totalTime)
 }
 
 func (h *CableHandler) NewCableForm(c *gin.Context) {
@@ -142,7 +142,7 @@ func (h *CableHandler) NewCableForm(c *gin.Context) {
 }
 
 func (h *CableHandler) CreateCable(c *gin.Context) {
-	log.Printf("🔥 CREATE CABLE HANDLER CALLED")
+	logger.Debugf("CREATE CABLE HANDLER CALLED")
 	
 	// Parse form values
 	connector1Str := c.PostForm("connector1")
@@ -152,34 +152,34 @@ func (h *CableHandler) CreateCable(c *gin.Context) {
 	mm2Str := c.PostForm("mm2")
 	amountStr := c.PostForm("amount")
 	
-	log.Printf("📝 Form values: connector1='%s', connector2='%s', type='%s', length='%s', mm2='%s', amount='%s'", 
+	logger.Debugf("Form values: connector1='%s', connector2='%s', type='%s', length='%s', mm2='%s', amount='%s'", 
 		connector1Str, connector2Str, typeStr, lengthStr, mm2Str, amountStr)
 	
 	// Parse required fields
 	connector1, err := strconv.Atoi(connector1Str)
 	if err != nil {
-		log.Printf("❌ Invalid connector1: %v", err)
+		logger.Errorf("Invalid connector1: %v", err)
 		h.renderCableFormWithError(c, "Invalid connector 1 value", nil)
 		return
 	}
 	
 	connector2, err := strconv.Atoi(connector2Str)
 	if err != nil {
-		log.Printf("❌ Invalid connector2: %v", err)
+		logger.Errorf("Invalid connector2: %v", err)
 		h.renderCableFormWithError(c, "Invalid connector 2 value", nil)
 		return
 	}
 	
 	cableType, err := strconv.Atoi(typeStr)
 	if err != nil {
-		log.Printf("❌ Invalid type: %v", err)
+		logger.Errorf("Invalid type: %v", err)
 		h.renderCableFormWithError(c, "Invalid cable type value", nil)
 		return
 	}
 	
 	length, err := strconv.ParseFloat(lengthStr, 64)
 	if err != nil {
-		log.Printf("❌ Invalid length: %v", err)
+		logger.Errorf("Invalid length: %v", err)
 		h.renderCableFormWithError(c, "Invalid length value", nil)
 		return
 	}
@@ -188,7 +188,7 @@ func (h *CableHandler) CreateCable(c *gin.Context) {
 	if mm2Str != "" {
 		parsedMM2, err := strconv.ParseFloat(mm2Str, 64)
 		if err != nil {
-			log.Printf("❌ Invalid mm2: %v", err)
+			logger.Errorf("Invalid mm2: %v", err)
 			h.renderCableFormWithError(c, "Invalid mm² value", nil)
 			return
 		}
@@ -200,7 +200,7 @@ func (h *CableHandler) CreateCable(c *gin.Context) {
 	if amountStr != "" {
 		amount, err = strconv.Atoi(amountStr)
 		if err != nil || amount < 1 {
-			log.Printf("❌ Invalid amount: %v", err)
+			logger.Errorf("Invalid amount: %v", err)
 			h.renderCableFormWithError(c, "Invalid amount value", nil)
 			return
 		}
@@ -220,7 +220,7 @@ func (h *CableHandler) CreateCable(c *gin.Context) {
 		}
 		
 		if err := h.cableRepo.Create(&cable); err != nil {
-			log.Printf("❌ Error creating cable %d of %d: %v", i+1, amount, err)
+			logger.Errorf("Error creating cable %d of %d: %v", i+1, amount, err)
 			h.renderCableFormWithError(c, fmt.Sprintf("Error creating cable %d of %d: %v", i+1, amount, err), &cable)
 			return
 		}
@@ -229,7 +229,7 @@ func (h *CableHandler) CreateCable(c *gin.Context) {
 		createdIDs = append(createdIDs, cable.CableID)
 	}
 	
-	log.Printf("✅ Successfully created %d cables with IDs: %v", amount, createdIDs)
+	logger.Debugf("Successfully created %d cables with IDs: %v", amount, createdIDs)
 	c.Redirect(http.StatusFound, "/cables")
 }
 
@@ -284,10 +284,10 @@ func (h *CableHandler) GetCableAPI(c *gin.Context) {
 		return
 	}
 
-	log.Printf("🐛 DEBUG GetCableAPI: Cable ID=%d, Type=%d, Connector1=%d, Connector2=%d", cable.CableID, cable.Type, cable.Connector1, cable.Connector2)
-	log.Printf("🐛 DEBUG GetCableAPI: TypeInfo=%+v", cable.TypeInfo)
-	log.Printf("🐛 DEBUG GetCableAPI: Connector1Info=%+v", cable.Connector1Info)
-	log.Printf("🐛 DEBUG GetCableAPI: Connector2Info=%+v", cable.Connector2Info)
+	logger.Debugf("GetCableAPI: Cable ID=%d, Type=%d, Connector1=%d, Connector2=%d", cable.CableID, cable.Type, cable.Connector1, cable.Connector2)
+	logger.Debugf("GetCableAPI: TypeInfo=%+v", cable.TypeInfo)
+	logger.Debugf("GetCableAPI: Connector1Info=%+v", cable.Connector1Info)
+	logger.Debugf("GetCableAPI: Connector2Info=%+v", cable.Connector2Info)
 
 	c.JSON(http.StatusOK, gin.H{"cable": cable})
 }
@@ -353,9 +353,9 @@ func (h *CableHandler) GetCableTypesAPI(c *gin.Context) {
 		return
 	}
 
-	log.Printf("🐛 DEBUG GetCableTypesAPI: Found %d types", len(types))
+	logger.Debugf("GetCableTypesAPI: Found %d types", len(types))
 	for i, t := range types {
-		log.Printf("🐛 DEBUG GetCableTypesAPI: Type[%d] ID=%d, Name=%s", i, t.CableTypesID, t.Name)
+		logger.Debugf("GetCableTypesAPI: Type[%d] ID=%d, Name=%s", i, t.CableTypesID, t.Name)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"types": types})
@@ -368,9 +368,9 @@ func (h *CableHandler) GetCableConnectorsAPI(c *gin.Context) {
 		return
 	}
 
-	log.Printf("🐛 DEBUG GetCableConnectorsAPI: Found %d connectors", len(connectors))
+	logger.Debugf("GetCableConnectorsAPI: Found %d connectors", len(connectors))
 	for i, conn := range connectors {
-		log.Printf("🐛 DEBUG GetCableConnectorsAPI: Connector[%d] ID=%d, Name=%s", i, conn.CableConnectorsID, conn.Name)
+		logger.Debugf("GetCableConnectorsAPI: Connector[%d] ID=%d, Name=%s", i, conn.CableConnectorsID, conn.Name)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"connectors": connectors})
@@ -2,11 +2,11 @@ package handlers
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 
@@ -36,18 +36,18 @@ func (h *CaseHandler) ListCases(c *gin.Context) {
 	}
 
 	// DEBUG: Log all query parameters
-	fmt.Printf("DEBUG Case Handler: All query params: %+v\n", c.Request.URL.Query())
+	logger.Debugf("Case Handler: All query params: %+v", c.Request.URL.Query())
 	
 	// Manual parameter extraction to ensure search works
 	searchParam := c.Query("search")
-	fmt.Printf("DEBUG Case Handler: Raw search parameter: '%s'\n", searchParam)
+	logger.Debugf("Case Handler: Raw search parameter: '%s'", searchParam)
 	if searchParam != "" {
 		params.SearchTerm = searchParam
-		fmt.Printf("DEBUG Case Handler: Search parameter SET to: '%s'\n", searchParam)
+		logger.Debugf("Case Handler: Search parameter SET to: '%s'", searchParam)
 	}
 	
 	// DEBUG: Log params after binding
-	fmt.Printf("DEBUG Case Handler: Final params: SearchTerm='%s'\n", params.SearchTerm)
+	logger.Debugf("Case Handler: Final params: SearchTerm='%s'", params.SearchTerm)
 
 	cases, err := h.caseRepo.List(params)
 	if err != nil {
@@ -55,7 +55,7 @@ func (h *CaseHandler) ListCases(c *gin.Context) {
 		return
 	}
 
-	fmt.Printf("DEBUG: Found %d cases with search term '%s'\n", len(cases), params.SearchTerm)
+	logger.Debugf("Found %d cases with search term '%s'", len(cases), params.SearchTerm)
 
 	SafeHTML(c, http.StatusOK, "cases_list.html", gin.H{
 		"title":       "Cases",
@@ -235,14 +235,14 @@ func (h *CaseHandler) EditCaseForm(c *gin.Context) {
 	}
 
 	// Debug: Log the number of available devices
-	log.Printf("EditCaseForm: Found %d available devices for case %d", len(availableDevices), caseID)
+	logger.Debugf("EditCaseForm: Found %d available devices for case %d", len(availableDevices), caseID)
 	for i, device := range availableDevices {
 		if i < 3 { // Only show first 3 for debugging
 			productName := "No Product"
 			if device.Product != nil {
 				productName = device.Product.Name
 			}
-			log.Printf("  Device %d: ID='%s', Status='%s', Product='%s'", i+1, device.DeviceID, device.Status, productName)
+			logger.Debugf("Device %d: ID='%s', Status='%s', Product='%s'", i+1, device.DeviceID, device.Status, productName)
 		}
 	}
 
@@ -625,18 +625,18 @@ func (h *CaseHandler) DeleteCaseAPI(c *gin.Context) {
 // GetCaseDevicesAPI returns devices in a case as JSON
 func (h *CaseHandler) GetCaseDevicesAPI(c *gin.Context) {
 	caseIDStr := c.Param("id")
-	log.Printf("GetCaseDevicesAPI: Getting devices for case ID: %s", caseIDStr)
+	logger.Debugf("GetCaseDevicesAPI: Getting devices for case ID: %s", caseIDStr)
 	
 	caseID, err := strconv.ParseUint(caseIDStr, 10, 32)
 	if err != nil {
-		log.Printf("GetCaseDevicesAPI: Invalid case ID: %s, error: %v", caseIDStr, err)
+		logger.Errorf("GetCaseDevicesAPI: Invalid case ID: %s, error: %v", caseIDStr, err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid case ID"})
 		return
 	}
 
 	deviceCases, err := h.caseRepo.GetDevicesInCase(uint(caseID))
 	if err != nil {
-		log.Printf("GetCaseDevicesAPI: Database error for case %d: %v", caseID, err)
+		logger.Errorf("GetCaseDevicesAPI: Database error for case %d: %v", caseID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -652,7 +652,7 @@ func (h *CaseHandler) GetCaseDevicesAPI(c *gin.Context) {
 		devices[i] = deviceCase.Device
 	}
 
-	log.Printf("GetCaseDevicesAPI: Found %d devices for case %d", len(devices), caseID)
+	logger.Debugf("GetCaseDevicesAPI: Found %d devices for case %d", len(devices), caseID)
 	c.JSON(http.StatusOK, gin.H{"devices": devices})
 }
 
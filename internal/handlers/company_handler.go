@@ -3,7 +3,6 @@ package handlers
 import (
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -11,6 +10,7 @@ import (
 	"time"
 
 	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/services"
 
@@ -39,14 +39,14 @@ func (h *CompanyHandler) CompanySettingsForm(c *gin.Context) {
 	// Get current company settings
 	company, err := h.getCompanySettings()
 	if err != nil {
-		log.Printf("CompanySettingsForm: Error fetching company settings: %v", err)
+		logger.Errorf("CompanySettingsForm: Error fetching company settings: %v", err)
 		// Create default empty company settings
 		company = &models.CompanySettings{
 			CompanyName: "Ihre Firma GmbH",
 		}
 	}
 
-	log.Printf("DEBUG: CompanySettingsForm handler called successfully - rendering company_settings.html")
+	logger.Debugf("CompanySettingsForm handler called successfully - rendering company_settings.html")
 	
 	// Check for success message
 	var successMsg string
@@ -84,7 +84,7 @@ func (h *CompanyHandler) UpdateCompanySettingsForm(c *gin.Context) {
 
 	// Validate required fields
 	if strings.TrimSpace(companyName) == "" {
-		log.Printf("UpdateCompanySettingsForm: Company name is required")
+		logger.Debugf("UpdateCompanySettingsForm: Company name is required")
 		c.HTML(http.StatusBadRequest, "company_settings.html", gin.H{
 			"title":   "Company Settings",
 			"user":    user,
@@ -116,11 +116,11 @@ func (h *CompanyHandler) UpdateCompanySettingsForm(c *gin.Context) {
 	if company.ID != 0 {
 		if company.CreatedAt.IsZero() {
 			company.CreatedAt = time.Now()
-			log.Printf("UpdateCompanySettingsForm: Fixed zero CreatedAt value")
+			logger.Debugf("UpdateCompanySettingsForm: Fixed zero CreatedAt value")
 		}
 		if company.UpdatedAt.IsZero() {
 			company.UpdatedAt = time.Now()
-			log.Printf("UpdateCompanySettingsForm: Fixed zero UpdatedAt value")
+			logger.Debugf("UpdateCompanySettingsForm: Fixed zero UpdatedAt value")
 		}
 	}
 
@@ -133,7 +133,7 @@ func (h *CompanyHandler) UpdateCompanySettingsForm(c *gin.Context) {
 	}
 
 	if result.Error != nil {
-		log.Printf("UpdateCompanySettingsForm: Database error: %v", result.Error)
+		logger.Errorf("UpdateCompanySettingsForm: Database error: %v", result.Error)
 		c.HTML(http.StatusInternalServerError, "company_settings.html", gin.H{
 			"title":   "Company Settings",
 			"user":    user,
@@ -143,7 +143,7 @@ func (h *CompanyHandler) UpdateCompanySettingsForm(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Company settings updated successfully by user %s", user.Username)
+	logger.Debugf("Company settings updated successfully by user %s", user.Username)
 	c.Redirect(http.StatusSeeOther, "/settings/company?success=1")
 }
 
@@ -151,7 +151,7 @@ func (h *CompanyHandler) UpdateCompanySettingsForm(c *gin.Context) {
 func (h *CompanyHandler) GetCompanySettings(c *gin.Context) {
 	company, err := h.getCompanySettings()
 	if err != nil {
-		log.Printf("GetCompanySettings: Error: %v", err)
+		logger.Errorf("GetCompanySettings: Error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load company settings"})
 		return
 	}
@@ -172,7 +172,7 @@ func (h *CompanyHandler) UpdateCompanySettings(c *gin.Context) {
 
 	var request models.CompanySettings
 	if err := c.ShouldBindJSON(&request); err != nil {
-		log.Printf("UpdateCompanySettings: Validation error: %v", err)
+		logger.Errorf("UpdateCompanySettings: Validation error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid input data",
 			"details": err.Error(),
@@ -237,11 +237,11 @@ func (h *CompanyHandler) UpdateCompanySettings(c *gin.Context) {
 	if company.ID != 0 {
 		if company.CreatedAt.IsZero() {
 			company.CreatedAt = time.Now()
-			log.Printf("UpdateCompanySettings: Fixed zero CreatedAt value")
+			logger.Debugf("UpdateCompanySettings: Fixed zero CreatedAt value")
 		}
 		if company.UpdatedAt.IsZero() {
 			company.UpdatedAt = time.Now()
-			log.Printf("UpdateCompanySettings: Fixed zero UpdatedAt value")
+			logger.Debugf("UpdateCompanySettings: Fixed zero UpdatedAt value")
 		}
 	}
 
@@ -259,7 +259,7 @@ func (h *CompanyHandler) UpdateCompanySettings(c *gin.Context) {
 	}
 
 	if result.Error != nil {
-		log.Printf("UpdateCompanySettings: Database error: %v", result.Error)
+		logger.Errorf("UpdateCompanySettings: Database error: %v", result.Error)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to save company settings",
 			"details": result.Error.Error(),
@@ -267,7 +267,7 @@ func (h *CompanyHandler) UpdateCompanySettings(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Company settings updated successfully by user %s", user.Username)
+	logger.Debugf("Company settings updated successfully by user %s", user.Username)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Company settings updated successfully",
@@ -312,7 +312,7 @@ func (h *CompanyHandler) UploadCompanyLogo(c *gin.Context) {
 	// Create uploads directory if it doesn't exist
 	uploadsDir := "uploads/logos"
 	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		log.Printf("UploadCompanyLogo: Failed to create uploads directory: %v", err)
+		logger.Errorf("UploadCompanyLogo: Failed to create uploads directory: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
 		return
 	}
@@ -329,7 +329,7 @@ func (h *CompanyHandler) UploadCompanyLogo(c *gin.Context) {
 	// Create destination file
 	dst, err := os.Create(filePath)
 	if err != nil {
-		log.Printf("UploadCompanyLogo: Failed to create destination file: %v", err)
+		logger.Errorf("UploadCompanyLogo: Failed to create destination file: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
 		return
 	}
@@ -337,7 +337,7 @@ func (h *CompanyHandler) UploadCompanyLogo(c *gin.Context) {
 
 	// Copy file content
 	if _, err := io.Copy(dst, file); err != nil {
-		log.Printf("UploadCompanyLogo: Failed to copy file: %v", err)
+		logger.Errorf("UploadCompanyLogo: Failed to copy file: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
 		return
 	}
@@ -359,7 +359,7 @@ func (h *CompanyHandler) UploadCompanyLogo(c *gin.Context) {
 		oldPath := strings.TrimPrefix(*company.LogoPath, "/")
 		if _, err := os.Stat(oldPath); err == nil {
 			if err := os.Remove(oldPath); err != nil {
-				log.Printf("UploadCompanyLogo: Failed to remove old logo: %v", err)
+				logger.Errorf("UploadCompanyLogo: Failed to remove old logo: %v", err)
 			}
 		}
 	}
@@ -376,7 +376,7 @@ func (h *CompanyHandler) UploadCompanyLogo(c *gin.Context) {
 	}
 
 	if result.Error != nil {
-		log.Printf("UploadCompanyLogo: Database error: %v", result.Error)
+		logger.Errorf("UploadCompanyLogo: Database error: %v", result.Error)
 		// Clean up uploaded file on database error
 		os.Remove(filePath)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -386,7 +386,7 @@ func (h *CompanyHandler) UploadCompanyLogo(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Company logo uploaded successfully by user %s: %s", user.Username, filename)
+	logger.Debugf("Company logo uploaded successfully by user %s: %s", user.Username, filename)
 	c.JSON(http.StatusOK, gin.H{
 		"success":  true,
 		"message":  "Logo uploaded successfully",
@@ -419,7 +419,7 @@ func (h *CompanyHandler) DeleteCompanyLogo(c *gin.Context) {
 	oldPath := strings.TrimPrefix(*company.LogoPath, "/")
 	if _, err := os.Stat(oldPath); err == nil {
 		if err := os.Remove(oldPath); err != nil {
-			log.Printf("DeleteCompanyLogo: Failed to remove logo file: %v", err)
+			logger.Errorf("DeleteCompanyLogo: Failed to remove logo file: %v", err)
 		}
 	}
 
@@ -428,7 +428,7 @@ func (h *CompanyHandler) DeleteCompanyLogo(c *gin.Context) {
 	company.UpdatedAt = time.Now()
 
 	if err := h.db.Save(company).Error; err != nil {
-		log.Printf("DeleteCompanyLogo: Database error: %v", err)
+		logger.Errorf("DeleteCompanyLogo: Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update company settings",
 			"details": err.Error(),
@@ -436,7 +436,7 @@ func (h *CompanyHandler) DeleteCompanyLogo(c *gin.Context) {
 		return
 	}
 
-	log.Printf("Company logo deleted successfully by user %s", user.Username)
+	logger.Debugf("Company logo deleted successfully by user %s", user.Username)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Logo deleted successfully",
@@ -525,16 +525,16 @@ func (h *CompanyHandler) GetSMTPConfig(c *gin.Context) {
 }
 
 func (h *CompanyHandler) UpdateSMTPConfig(c *gin.Context) {
-	log.Printf("UpdateSMTPConfig: Request received")
+	logger.Debugf("UpdateSMTPConfig: Request received")
 
 	user, exists := GetCurrentUser(c)
 	if !exists {
-		log.Printf("UpdateSMTPConfig: Authentication failed")
+		logger.Errorf("UpdateSMTPConfig: Authentication failed")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 		return
 	}
 
-	log.Printf("UpdateSMTPConfig: User authenticated: %s", user.Username)
+	logger.Debugf("UpdateSMTPConfig: User authenticated: %s", user.Username)
 
 	var request struct {
 		SMTPHost      string `json:"smtp_host"`
@@ -547,7 +547,7 @@ func (h *CompanyHandler) UpdateSMTPConfig(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		log.Printf("UpdateSMTPConfig: JSON binding error: %v", err)
+		logger.Errorf("UpdateSMTPConfig: JSON binding error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid input data",
 			"details": err.Error(),
@@ -555,7 +555,7 @@ func (h *CompanyHandler) UpdateSMTPConfig(c *gin.Context) {
 		return
 	}
 
-	log.Printf("UpdateSMTPConfig: Request data - Host: %s, Port: %d, Username: %s, FromEmail: %s",
+	logger.Debugf("UpdateSMTPConfig: Request data - Host: %s, Port: %d, Username: %s, FromEmail: %s",
 		request.SMTPHost, request.SMTPPort, request.SMTPUsername, request.SMTPFromEmail)
 
 	// Validate required fields manually
@@ -588,24 +588,24 @@ func (h *CompanyHandler) UpdateSMTPConfig(c *gin.Context) {
 	}
 
 	// Get existing company settings or create new
-	log.Printf("UpdateSMTPConfig: Getting company settings...")
+	logger.Debugf("UpdateSMTPConfig: Getting company settings...")
 	company, err := h.getCompanySettings()
 	if err != nil {
-		log.Printf("UpdateSMTPConfig: No existing company settings found, creating new: %v", err)
+		logger.Debugf("UpdateSMTPConfig: No existing company settings found, creating new: %v", err)
 		company = &models.CompanySettings{
 			CompanyName: "Ihre Firma GmbH",
 		}
 	} else {
-		log.Printf("UpdateSMTPConfig: Found existing company settings with ID: %d", company.ID)
+		logger.Debugf("UpdateSMTPConfig: Found existing company settings with ID: %d", company.ID)
 
 		// Fix corrupted datetime values if they exist
 		if company.CreatedAt.IsZero() {
 			company.CreatedAt = time.Now()
-			log.Printf("UpdateSMTPConfig: Fixed zero CreatedAt value")
+			logger.Debugf("UpdateSMTPConfig: Fixed zero CreatedAt value")
 		}
 		if company.UpdatedAt.IsZero() {
 			company.UpdatedAt = time.Now()
-			log.Printf("UpdateSMTPConfig: Fixed zero UpdatedAt value")
+			logger.Debugf("UpdateSMTPConfig: Fixed zero UpdatedAt value")
 		}
 	}
 
@@ -626,18 +626,18 @@ func (h *CompanyHandler) UpdateSMTPConfig(c *gin.Context) {
 	}
 
 	// Save to database (GORM will handle UpdatedAt automatically)
-	log.Printf("UpdateSMTPConfig: Saving to database, company ID: %d", company.ID)
+	logger.Debugf("UpdateSMTPConfig: Saving to database, company ID: %d", company.ID)
 	var result *gorm.DB
 	if company.ID == 0 {
-		log.Printf("UpdateSMTPConfig: Creating new company settings record")
+		logger.Debugf("UpdateSMTPConfig: Creating new company settings record")
 		result = h.db.Create(company)
 	} else {
-		log.Printf("UpdateSMTPConfig: Updating existing company settings record")
+		logger.Debugf("UpdateSMTPConfig: Updating existing company settings record")
 		result = h.db.Save(company)
 	}
 
 	if result.Error != nil {
-		log.Printf("UpdateSMTPConfig: Database error: %v", result.Error)
+		logger.Errorf("UpdateSMTPConfig: Database error: %v", result.Error)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to save email configuration",
 			"details": result.Error.Error(),
@@ -645,9 +645,9 @@ func (h *CompanyHandler) UpdateSMTPConfig(c *gin.Context) {
 		return
 	}
 
-	log.Printf("UpdateSMTPConfig: Database save successful, affected rows: %d", result.RowsAffected)
+	logger.Debugf("UpdateSMTPConfig: Database save successful, affected rows: %d", result.RowsAffected)
 
-	log.Printf("SMTP config updated successfully by user %s: %s:%d", user.Username, request.SMTPHost, request.SMTPPort)
+	logger.Debugf("SMTP config updated successfully by user %s: %s:%d", user.Username, request.SMTPHost, request.SMTPPort)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -696,7 +696,7 @@ func (h *CompanyHandler) TestSMTPConnection(c *gin.Context) {
 
 	err = emailService.SendTestEmail(testEmail, testData)
 	if err != nil {
-		log.Printf("TestSMTPConnection: Failed to send test email: %v", err)
+		logger.Errorf("TestSMTPConnection: Failed to send test email: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to send test email",
 			"details": err.Error(),
@@ -704,7 +704,7 @@ func (h *CompanyHandler) TestSMTPConnection(c *gin.Context) {
 		return
 	}
 
-	log.Printf("SMTP connection test successful by user %s, test email sent to %s", user.Username, testEmail)
+	logger.Debugf("SMTP connection test successful by user %s, test email sent to %s", user.Username, testEmail)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
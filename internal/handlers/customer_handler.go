@@ -1,16 +1,24 @@
 package handlers
 
 import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type CustomerHandler struct {
@@ -79,15 +87,17 @@ func (h *CustomerHandler) NewCustomerForm(c *gin.Context) {
 
 func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 	// Debug: Print all form data
-	fmt.Printf("🚨 DEBUG: Customer creation called!\n")
-	fmt.Printf("🚨 DEBUG: HTTP Method: %s\n", c.Request.Method)
-	fmt.Printf("🚨 DEBUG: Content-Type: %s\n", c.ContentType())
-	fmt.Printf("🚨 DEBUG: All form fields:\n")
+	logger.Debugf("Customer creation called!")
+	logger.Debugf("HTTP Method: %s", c.Request.Method)
+	logger.Debugf("Content-Type: %s", c.ContentType())
+	logger.Debugf("All form fields:")
 	
 	// Parse form first
 	c.Request.ParseForm()
-	for key, values := range c.Request.PostForm {
-		fmt.Printf("   %s: %v\n", key, values)
+	if logger.Enabled(logger.DEBUG) {
+		for key, values := range c.Request.PostForm {
+			logger.Debugf("%s: %v", key, values)
+		}
 	}
 	
 	companyName := c.PostForm("company_name")
@@ -105,13 +115,13 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 	notes := c.PostForm("notes")
 	
 	// Debug logging
-	fmt.Printf("🔧 DEBUG: Creating customer with parsed data:\n")
-	fmt.Printf("   CompanyName: '%s'\n", companyName)
-	fmt.Printf("   FirstName: '%s'\n", firstName)
-	fmt.Printf("   LastName: '%s'\n", lastName)
-	fmt.Printf("   Email: '%s'\n", email)
-	fmt.Printf("   PhoneNumber: '%s'\n", phoneNumber)
-	fmt.Printf("   CustomerType: '%s'\n", customerType)
+	logger.Debugf("Creating customer with parsed data:")
+	logger.Debugf("CompanyName: '%s'", companyName)
+	logger.Debugf("FirstName: '%s'", firstName)
+	logger.Debugf("LastName: '%s'", lastName)
+	logger.Debugf("Email: '%s'", email)
+	logger.Debugf("PhoneNumber: '%s'", phoneNumber)
+	logger.Debugf("CustomerType: '%s'", customerType)
 	
 	customer := models.Customer{
 		CompanyName:  &companyName,
@@ -129,9 +139,9 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 		Notes:        &notes,
 	}
 
-	fmt.Printf("🔧 DEBUG: Calling customerRepo.Create()\n")
+	logger.Debugf("Calling customerRepo.Create()")
 	if err := h.customerRepo.Create(&customer); err != nil {
-		fmt.Printf("❌ DEBUG: Customer creation failed: %v\n", err)
+		logger.Errorf("Customer creation failed: %v", err)
 		user, _ := GetCurrentUser(c)
 		c.HTML(http.StatusInternalServerError, "customer_form.html", gin.H{
 			"title":    "New Customer",
@@ -142,7 +152,8 @@ func (h *CustomerHandler) CreateCustomer(c *gin.Context) {
 		return
 	}
 
-	fmt.Printf("✅ DEBUG: Customer creation succeeded, ID: %d\n", customer.CustomerID)
+	logger.Debugf("Customer creation succeeded, ID: %d", customer.CustomerID)
+	writeAuditLog(h.customerRepo.GetDB().DB, c, "create", "customer", strconv.FormatUint(uint64(customer.CustomerID), 10), nil, customer)
 	
 	// Add a simple success page instead of redirect for debugging
 	c.HTML(http.StatusOK, "customers.html", gin.H{
@@ -182,8 +193,14 @@ func (h *CustomerHandler) GetCustomer(c *gin.Context) {
 		return
 	}
 
+	contacts, err := h.customerRepo.ListContacts(customer.CustomerID)
+	if err != nil {
+		logger.Errorf("GetCustomer: failed to load contacts for customer %d: %v", customer.CustomerID, err)
+	}
+
 	c.HTML(http.StatusOK, "customer_detail.html", gin.H{
 		"customer": customer,
+		"contacts": contacts,
 		"user":     user,
 	})
 }
@@ -266,6 +283,8 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 		Notes:        &notes,
 	}
 
+	db := h.customerRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Customer{}, "customerID", id)
 	if err := h.customerRepo.Update(&customer); err != nil {
 		c.HTML(http.StatusInternalServerError, "customer_form.html", gin.H{
 			"title":    "Edit Customer",
@@ -275,6 +294,7 @@ func (h *CustomerHandler) UpdateCustomer(c *gin.Context) {
 		})
 		return
 	}
+	writeAuditLog(db, c, "update", "customer", strconv.FormatUint(id, 10), previous, auditSnapshot(db, &models.Customer{}, "customerID", id))
 
 	c.Redirect(http.StatusFound, "/customers")
 }
@@ -286,10 +306,13 @@ func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
 		return
 	}
 
+	db := h.customerRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Customer{}, "customerID", id)
 	if err := h.customerRepo.Delete(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	writeAuditLog(db, c, "delete", "customer", strconv.FormatUint(id, 10), previous, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Customer deleted successfully"})
 }
@@ -312,32 +335,26 @@ func (h *CustomerHandler) ListCustomersAPI(c *gin.Context) {
 }
 
 func (h *CustomerHandler) CreateCustomerAPI(c *gin.Context) {
-	fmt.Printf("🚨 DEBUG API: CreateCustomerAPI called\n")
-	fmt.Printf("🚨 DEBUG API: Content-Type: %s\n", c.ContentType())
-	
-	// Debug: Print raw request body
-	bodyBytes, _ := c.GetRawData()
-	fmt.Printf("🚨 DEBUG API: Raw request body: %s\n", string(bodyBytes))
-	
-	// Reset the request body so it can be read again
-	c.Request.Body = io.NopCloser(strings.NewReader(string(bodyBytes)))
+	logger.Debugf("API: CreateCustomerAPI called")
+	logger.Debugf("API: Content-Type: %s", c.ContentType())
 	
 	var customer models.Customer
 	if err := c.ShouldBindJSON(&customer); err != nil {
-		fmt.Printf("❌ DEBUG API: JSON binding error: %v\n", err)
+		logger.Errorf("API: JSON binding error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	fmt.Printf("✅ DEBUG API: Parsed customer: %+v\n", customer)
+	logger.Debugf("API: Parsed customer: %+v", customer)
 
 	if err := h.customerRepo.Create(&customer); err != nil {
-		fmt.Printf("❌ DEBUG API: Database error: %v\n", err)
+		logger.Errorf("API: Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	fmt.Printf("🎉 DEBUG API: Customer created successfully with ID: %d\n", customer.CustomerID)
+	logger.Debugf("API: Customer created successfully with ID: %d", customer.CustomerID)
+	writeAuditLog(h.customerRepo.GetDB().DB, c, "create", "customer", strconv.FormatUint(uint64(customer.CustomerID), 10), nil, customer)
 	c.JSON(http.StatusCreated, customer)
 }
 
@@ -370,11 +387,14 @@ func (h *CustomerHandler) UpdateCustomerAPI(c *gin.Context) {
 		return
 	}
 
+	db := h.customerRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Customer{}, "customerID", id)
 	customer.CustomerID = uint(id)
 	if err := h.customerRepo.Update(&customer); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	writeAuditLog(db, c, "update", "customer", strconv.FormatUint(id, 10), previous, auditSnapshot(db, &models.Customer{}, "customerID", id))
 
 	c.JSON(http.StatusOK, customer)
 }
@@ -386,10 +406,251 @@ func (h *CustomerHandler) DeleteCustomerAPI(c *gin.Context) {
 		return
 	}
 
+	db := h.customerRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Customer{}, "customerID", id)
 	if err := h.customerRepo.Delete(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	writeAuditLog(db, c, "delete", "customer", strconv.FormatUint(id, 10), previous, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Customer deleted successfully"})
-}
\ No newline at end of file
+}
+
+// Customer contact API handlers
+
+func (h *CustomerHandler) ListContactsAPI(c *gin.Context) {
+	customerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	contacts, err := h.customerRepo.ListContacts(uint(customerID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"contacts": contacts})
+}
+
+func (h *CustomerHandler) CreateContactAPI(c *gin.Context) {
+	customerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	if _, err := h.customerRepo.GetByID(uint(customerID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
+
+	var request models.CustomerContactRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.IsValidContactRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact role"})
+		return
+	}
+
+	contact := models.CustomerContact{
+		CustomerID: uint(customerID),
+		Name:       strings.TrimSpace(request.Name),
+		Role:       request.Role,
+		Email:      request.Email,
+		Phone:      request.Phone,
+	}
+	if err := h.customerRepo.CreateContact(&contact); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, contact)
+}
+
+func (h *CustomerHandler) UpdateContactAPI(c *gin.Context) {
+	customerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+	contactID, err := strconv.ParseUint(c.Param("contactId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return
+	}
+
+	contact, err := h.customerRepo.GetContact(uint(customerID), uint(contactID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	var request models.CustomerContactRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.IsValidContactRole(request.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact role"})
+		return
+	}
+
+	contact.Name = strings.TrimSpace(request.Name)
+	contact.Role = request.Role
+	contact.Email = request.Email
+	contact.Phone = request.Phone
+	if err := h.customerRepo.UpdateContact(contact); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, contact)
+}
+
+func (h *CustomerHandler) DeleteContactAPI(c *gin.Context) {
+	customerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+	contactID, err := strconv.ParseUint(c.Param("contactId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return
+	}
+
+	if err := h.customerRepo.DeleteContact(uint(customerID), uint(contactID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact deleted successfully"})
+}
+
+// ExportData exports everything stored about a customer as a ZIP of JSON files
+// (plus the uploaded document files) to answer GDPR subject access requests.
+// include=jobs,invoices,... limits the export to some sections. The export
+// itself is recorded in the audit log.
+func (h *CustomerHandler) ExportData(c *gin.Context) {
+	db := h.customerRepo.GetDB().DB
+	if !userHasPermission(db, c, "customers.export_data") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	customerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return
+	}
+
+	include := make(map[string]bool)
+	if param := c.Query("include"); param != "" {
+		for _, section := range strings.Split(param, ",") {
+			include[strings.TrimSpace(section)] = true
+		}
+		for section := range include {
+			if !repository.IsCustomerExportSection(section) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":    fmt.Sprintf("Unknown export section %q", section),
+					"sections": repository.CustomerExportSections,
+				})
+				return
+			}
+		}
+	} else {
+		for _, section := range repository.CustomerExportSections {
+			include[section] = true
+		}
+	}
+
+	export, err := h.customerRepo.CollectExportData(uint(customerID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
+	if err != nil {
+		logger.Errorf("ExportData: customer %d: %v", customerID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect customer data"})
+		return
+	}
+
+	writeAuditLog(db, c, "export", "customer", strconv.FormatUint(customerID, 10), nil, gin.H{
+		"include":      include,
+		"jobs":         len(export.Jobs),
+		"invoices":     len(export.Invoices),
+		"transactions": len(export.Transactions),
+		"documents":    len(export.Documents),
+		"auditLogs":    len(export.AuditLogs),
+	})
+
+	filename := fmt.Sprintf("customer_%d_data_%s.zip", customerID, time.Now().Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	zw := zip.NewWriter(c.Writer)
+	defer zw.Close()
+
+	writeJSON := func(name string, data interface{}) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(data)
+	}
+
+	files := []struct {
+		section string
+		name    string
+		data    interface{}
+	}{
+		{"", "customer.json", export.Customer},
+		{"contacts", "contacts.json", export.Contacts},
+		{"jobs", "jobs.json", export.Jobs},
+		{"invoices", "invoices.json", export.Invoices},
+		{"transactions", "transactions.json", export.Transactions},
+		{"documents", "documents.json", export.Documents},
+		{"audit", "audit_log.json", export.AuditLogs},
+	}
+	for _, file := range files {
+		if file.section != "" && !include[file.section] {
+			continue
+		}
+		if err := writeJSON(file.name, file.data); err != nil {
+			logger.Errorf("ExportData: customer %d: failed to write %s: %v", customerID, file.name, err)
+			return
+		}
+	}
+
+	if include["documents"] {
+		for _, document := range export.Documents {
+			name := fmt.Sprintf("documents/%d_%s", document.DocumentID, filepath.Base(document.OriginalFilename))
+			if err := copyFileToZip(zw, name, document.FilePath); err != nil {
+				logger.Warnf("ExportData: customer %d: skipping document %d: %v", customerID, document.DocumentID, err)
+			}
+		}
+	}
+}
+
+// copyFileToZip adds the file at path to the archive under name
+func copyFileToZip(zw *zip.Writer, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
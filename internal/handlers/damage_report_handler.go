@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/models"
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DamageReportHandler struct {
+	reportRepo *repository.DamageReportRepository
+	jobRepo    *repository.JobRepository
+	db         *gorm.DB
+}
+
+func NewDamageReportHandler(reportRepo *repository.DamageReportRepository, jobRepo *repository.JobRepository, db *gorm.DB) *DamageReportHandler {
+	return &DamageReportHandler{
+		reportRepo: reportRepo,
+		jobRepo:    jobRepo,
+		db:         db,
+	}
+}
+
+// CreateReport records damage on a device, optionally pulling it from service
+func (h *DamageReportHandler) CreateReport(c *gin.Context) {
+	deviceID := c.Param("id")
+
+	var req models.CreateDamageReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Severity == "" {
+		req.Severity = models.DamageSeverityMinor
+	}
+	if !models.IsValidDamageSeverity(req.Severity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid severity (expected minor, moderate, major or critical)"})
+		return
+	}
+	if req.JobID != nil {
+		if _, err := h.jobRepo.GetByID(*req.JobID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Job not found"})
+			return
+		}
+	}
+
+	report := &models.DamageReport{
+		DeviceID:     deviceID,
+		JobID:        req.JobID,
+		Description:  req.Description,
+		Severity:     req.Severity,
+		OutOfService: req.OutOfService,
+	}
+	if user, ok := GetCurrentUser(c); ok {
+		report.ReportedBy = &user.UserID
+	}
+
+	if err := h.reportRepo.Create(report, req.PhotoIDs); err != nil {
+		logger.Errorf("CreateDamageReport: device %s: %v", deviceID, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	writeAuditLog(h.db, c, "create", "damage_report", strconv.FormatUint(uint64(report.ReportID), 10), nil, report)
+
+	created, err := h.reportRepo.GetByID(report.ReportID)
+	if err != nil {
+		created = report
+	}
+	c.JSON(http.StatusCreated, gin.H{"report": created})
+}
+
+// ResolveReport closes a damage report and returns the device to service if
+// no other open report keeps it out
+func (h *DamageReportHandler) ResolveReport(c *gin.Context) {
+	if !userHasPermission(h.db, c, "devices.edit") && !userHasPermission(h.db, c, "devices.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	reportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	var req models.ResolveDamageReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing, err := h.reportRepo.GetByID(uint(reportID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Damage report not found"})
+		return
+	}
+
+	var resolvedBy *uint
+	if user, ok := GetCurrentUser(c); ok {
+		resolvedBy = &user.UserID
+	}
+	report, err := h.reportRepo.Resolve(uint(reportID), resolvedBy, req.ResolutionNotes)
+	if err != nil {
+		logger.Errorf("ResolveDamageReport: report %d: %v", reportID, err)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	writeAuditLog(h.db, c, "resolve", "damage_report", c.Param("id"), existing, report)
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// GetReport returns a single damage report with its photos
+func (h *DamageReportHandler) GetReport(c *gin.Context) {
+	reportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	report, err := h.reportRepo.GetByID(uint(reportID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Damage report not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// ListDeviceReports returns a device's damage reports; ?status=open limits to unresolved ones
+func (h *DamageReportHandler) ListDeviceReports(c *gin.Context) {
+	reports, err := h.reportRepo.ListByDevice(c.Param("id"), c.Query("status") == models.DamageReportOpen)
+	if err != nil {
+		logger.Errorf("ListDeviceDamageReports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch damage reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
+
+// ListOpenReports returns all unresolved damage reports, most severe first
+func (h *DamageReportHandler) ListOpenReports(c *gin.Context) {
+	reports, err := h.reportRepo.ListOpen(0)
+	if err != nil {
+		logger.Errorf("ListOpenDamageReports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch damage reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports, "count": len(reports)})
+}
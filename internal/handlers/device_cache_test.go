@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeDeviceTable answers the device queries of the list and create handlers
+// from an in-memory list of device IDs
+type fakeDeviceTable struct {
+	mu        sync.Mutex
+	deviceIDs []string
+}
+
+func (f *fakeDeviceTable) answer(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO `devices`"):
+		columns := strings.Split(query[strings.Index(query, "(")+1:strings.Index(query, ")")], ",")
+		for i, column := range columns {
+			if strings.Trim(column, "` ") == "deviceID" {
+				f.deviceIDs = append(f.deviceIDs, args[i].(string))
+			}
+		}
+	case strings.HasPrefix(query, "SELECT count(*) FROM `devices`"):
+		return []string{"count(*)"}, [][]driver.Value{{int64(len(f.deviceIDs))}}, nil
+	case strings.HasPrefix(query, "SELECT") && strings.Contains(query, "FROM `devices`"):
+		rows := make([][]driver.Value, len(f.deviceIDs))
+		for i, deviceID := range f.deviceIDs {
+			rows[i] = []driver.Value{deviceID, "free"}
+		}
+		return []string{"deviceID", "status"}, rows, nil
+	}
+	// Assignments, audit entries and anything else: no rows
+	return nil, nil, nil
+}
+
+func TestCreatedDeviceAppearsInCachedList(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	invalidateDeviceCaches()
+	t.Cleanup(invalidateDeviceCaches)
+
+	table := &fakeDeviceTable{deviceIDs: []string{"DEV-1"}}
+	db := newFakeDB(t, table.answer)
+	handler := NewDeviceHandler(repository.NewDeviceRepository(db, nil), nil, nil)
+
+	router := gin.New()
+	router.SetHTMLTemplate(template.Must(template.New("devices_standalone.html").
+		Parse(`{{range .devices}}{{.Device.DeviceID}} {{end}}`)))
+	router.GET("/devices", handler.ListDevices)
+	router.POST("/api/v1/devices", handler.CreateDeviceAPI)
+
+	listDevices := func() string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/devices", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /devices = %d: %s", w.Code, w.Body.String())
+		}
+		return w.Body.String()
+	}
+
+	// The first page of the list is cached
+	if body := listDevices(); !strings.Contains(body, "DEV-1") {
+		t.Fatalf("device list = %q; want DEV-1", body)
+	}
+
+	w := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/devices", strings.NewReader(`{"deviceID": "DEV-2"}`))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, request)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /api/v1/devices = %d: %s", w.Code, w.Body.String())
+	}
+
+	if body := listDevices(); !strings.Contains(body, "DEV-2") {
+		t.Errorf("device list after create = %q; want DEV-2", body)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
@@ -9,11 +10,13 @@ import (
 	"time"
 	"sync"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 	"go-barcode-webapp/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Simple cache for devices
@@ -38,6 +41,18 @@ var treeCache = &TreeCache{
 	timestamp: time.Time{}, // Force cache miss initially - CLEARED FOR HIERARCHY FIX
 }
 
+// invalidateDeviceCaches expires the cached device list and tree so the next
+// request sees device and package changes immediately
+func invalidateDeviceCaches() {
+	deviceCache.mutex.Lock()
+	deviceCache.timestamp = time.Time{}
+	deviceCache.mutex.Unlock()
+
+	treeCache.mutex.Lock()
+	treeCache.timestamp = time.Time{}
+	treeCache.mutex.Unlock()
+}
+
 type DeviceHandler struct {
 	deviceRepo     *repository.DeviceRepository
 	barcodeService *services.BarcodeService
@@ -60,6 +75,10 @@ func (h *DeviceHandler) ListDevices(c *gin.Context) {
 	
 	params := &models.FilterParams{}
 	if err := c.ShouldBindQuery(params); err != nil {
+		if wantsJSON(c) {
+			respondValidationError(c, err)
+			return
+		}
 		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/error?code=400&message=Bad Request&details=%s", err.Error()))
 		return
 	}
@@ -85,6 +104,7 @@ func (h *DeviceHandler) ListDevices(c *gin.Context) {
 
 	// Use cache for basic list view without search (but not for tree or categorized views)
 	var devices []models.DeviceWithJobInfo
+	var treeFallback *TreeFallback
 	var err error
 	
 	if params.SearchTerm == "" && page == 1 && viewType == "list" {
@@ -121,7 +141,7 @@ func (h *DeviceHandler) ListDevices(c *gin.Context) {
 			}
 			
 			if err != nil {
-					c.Redirect(http.StatusSeeOther, fmt.Sprintf("/error?code=500&message=Database Error&details=%s", err.Error()))
+					h.respondListDevicesError(c, err)
 				return
 			}
 			
@@ -153,11 +173,38 @@ func (h *DeviceHandler) ListDevices(c *gin.Context) {
 		}
 		
 		if err != nil {
-			c.Redirect(http.StatusSeeOther, fmt.Sprintf("/error?code=500&message=Database Error&details=%s", err.Error()))
+			h.respondListDevicesError(c, err)
 			return
 		}
 	}
 	
+	if viewType == "tree" {
+		// For tree view, load tree data and render in the main template
+		treeData, err := h.buildTreeData()
+		if err == nil && len(treeData) > 0 {
+			SafeHTML(c, http.StatusOK, "devices_standalone.html", gin.H{
+				"title":       "Device Tree View",
+				"params":      params,
+				"user":        user,
+				"viewType":    "tree",
+				"currentPage": "devices",
+				"treeData":    treeData,
+			})
+			return
+		}
+
+		// Fall back to list view instead of an error page, telling the user why
+		treeFallback = h.treeFallbackFor(err)
+		if err != nil {
+			username := ""
+			if user != nil {
+				username = user.Username
+			}
+			logger.Errorf("ListDevices: tree view for user %q failed, showing list view instead: %v", username, err)
+		}
+		viewType = "list"
+	}
+
 	// Calculate pagination info for all list view requests (both cached and fresh)
 	var totalDevices int
 	var totalPages int
@@ -173,59 +220,66 @@ func (h *DeviceHandler) ListDevices(c *gin.Context) {
 			totalPages = 1
 		}
 	}
-	if viewType == "tree" {
-		// For tree view, load tree data and render in the main template
-		treeData, err := h.buildTreeData()
-		if err != nil {
-			// Fall back to list view instead of error page
-			SafeHTML(c, http.StatusOK, "devices_standalone.html", gin.H{
-				"title":         "Devices (Tree Error - Showing List)",
-				"devices":       devices,
-				"params":        params,
-				"user":          user,
-				"viewType":      "list", // Force list view
-				"currentPage":   "devices",
-				"treeError":     err.Error(),
-			})
-			return
+
+	// Safe template rendering with error handling
+	SafeHTML(c, http.StatusOK, "devices_standalone.html", gin.H{
+		"title":         "Devices",
+		"devices":       devices,
+		"params":        params,
+		"user":          user,
+		"viewType":      "list",
+		"categorized":   false,
+		"currentPage":   "devices", // For navbar highlighting
+		"pageNumber":    page,      // For pagination
+		"hasNextPage":   page < totalPages,
+		"totalPages":    totalPages,
+		"totalDevices":  totalDevices,
+		"treeFallback":  treeFallback,
+	})
+}
+
+// respondListDevicesError reports a failure to load the device list: as JSON
+// to fetch calls, otherwise by redirecting to the error page
+func (h *DeviceHandler) respondListDevicesError(c *gin.Context, err error) {
+	if wantsJSON(c) {
+		logger.Errorf("ListDevices: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load devices")
+		return
+	}
+	c.Redirect(http.StatusSeeOther, fmt.Sprintf("/error?code=500&message=Database Error&details=%s", err.Error()))
+}
+
+// Reasons the device tree view fell back to the list view
+const (
+	TreeFallbackError = "error"
+	TreeFallbackEmpty = "empty"
+)
+
+// TreeFallback explains to the user why the list view is shown instead of the tree view
+type TreeFallback struct {
+	Reason  string
+	Message string
+}
+
+// treeFallbackFor describes why the tree could not be shown. An empty tree is
+// not an error: it means no device belongs to a product with a category.
+func (h *DeviceHandler) treeFallbackFor(treeErr error) *TreeFallback {
+	if treeErr != nil {
+		return &TreeFallback{
+			Reason:  TreeFallbackError,
+			Message: "The device tree could not be loaded, so the list view is shown instead. Please try again in a moment; if the problem persists, contact your administrator.",
 		}
-		
-		if len(treeData) == 0 {
-			SafeHTML(c, http.StatusOK, "devices_standalone.html", gin.H{
-				"title":         "Devices (Empty Tree - Showing List)",
-				"devices":       devices,
-				"params":        params,
-				"user":          user,
-				"viewType":      "list", // Force list view
-				"currentPage":   "devices",
-				"treeError":     "No categories found for tree view",
-			})
-			return
+	}
+
+	if total, err := h.deviceRepo.GetTotalCount(); err == nil && total == 0 {
+		return &TreeFallback{
+			Reason:  TreeFallbackEmpty,
+			Message: "There are no devices yet, so the tree view has nothing to show.",
 		}
-		
-		SafeHTML(c, http.StatusOK, "devices_standalone.html", gin.H{
-			"title":       "Device Tree View",
-			"params":      params,
-			"user":        user,
-			"viewType":    "tree",
-			"currentPage": "devices",
-			"treeData":    treeData,
-		})
-	} else {
-		// Safe template rendering with error handling
-		SafeHTML(c, http.StatusOK, "devices_standalone.html", gin.H{
-			"title":         "Devices",
-			"devices":       devices,
-			"params":        params,
-			"user":          user,
-			"viewType":      "list",
-			"categorized":   false,
-			"currentPage":   "devices", // For navbar highlighting
-			"pageNumber":    page,      // For pagination
-			"hasNextPage":   page < totalPages,
-			"totalPages":    totalPages,
-			"totalDevices":  totalDevices,
-		})
+	}
+	return &TreeFallback{
+		Reason:  TreeFallbackEmpty,
+		Message: "No device belongs to a product with a category yet. Assign categories to your products to browse devices in the tree view.",
 	}
 }
 
@@ -255,10 +309,10 @@ func (h *DeviceHandler) NewDeviceForm(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "device_form.html", gin.H{
-		"title":    "New Device",
-		"device":   &models.Device{},
-		"products": products,
-		"user":     user,
+		"title":     "New Device",
+		"device":    &models.Device{},
+		"products":  products,
+		"user":      user,
 	})
 }
 
@@ -295,11 +349,11 @@ func (h *DeviceHandler) CreateDevice(c *gin.Context) {
 		user, _ := GetCurrentUser(c)
 		products, _ := h.productRepo.List(&models.FilterParams{})
 		c.HTML(http.StatusBadRequest, "device_form.html", gin.H{
-			"title":    "New Device",
-			"device":   &models.Device{},
-			"products": products,
-			"error":    "Please select a product",
-			"user":     user,
+			"title":     "New Device",
+			"device":    &models.Device{},
+			"products":  products,
+			"error":     "Please select a product",
+			"user":      user,
 		})
 		return
 	}
@@ -348,9 +402,13 @@ func (h *DeviceHandler) CreateDevice(c *gin.Context) {
 			lastError = err
 			break
 		}
+		writeAuditLog(h.deviceRepo.GetDB().DB, c, "create", "device", device.DeviceID, nil, device)
 		
 		createdDevices = append(createdDevices, device)
 	}
+	if len(createdDevices) > 0 {
+		invalidateDeviceCaches()
+	}
 	
 	// Handle errors
 	if lastError != nil {
@@ -361,11 +419,11 @@ func (h *DeviceHandler) CreateDevice(c *gin.Context) {
 			errorMsg += fmt.Sprintf(" (%d devices were created successfully before the error)", len(createdDevices))
 		}
 		c.HTML(http.StatusInternalServerError, "device_form.html", gin.H{
-			"title":    "New Device",
-			"device":   &models.Device{},
-			"products": products,
-			"error":    errorMsg,
-			"user":     user,
+			"title":     "New Device",
+			"device":    &models.Device{},
+			"products":  products,
+			"error":     errorMsg,
+			"user":      user,
 		})
 		return
 	}
@@ -400,9 +458,21 @@ func (h *DeviceHandler) GetDevice(c *gin.Context) {
 		return
 	}
 
+	damageReports, err := repository.NewDamageReportRepository(h.deviceRepo.GetDB()).ListByDevice(deviceID, true)
+	if err != nil {
+		logger.Errorf("GetDevice: failed to load damage reports for %s: %v", deviceID, err)
+	}
+
+	labelPrints, err := h.deviceRepo.GetLabelPrintSummary(deviceID)
+	if err != nil {
+		logger.Errorf("GetDevice: failed to load label prints for %s: %v", deviceID, err)
+	}
+
 	c.HTML(http.StatusOK, "device_detail.html", gin.H{
-		"device": device,
-		"user":   user,
+		"device":        device,
+		"user":          user,
+		"damageReports": damageReports,
+		"labelPrints":   labelPrints,
 	})
 }
 
@@ -440,10 +510,10 @@ func (h *DeviceHandler) EditDeviceForm(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "device_form.html", gin.H{
-		"title":    "Edit Device",
-		"device":   device,
-		"products": products,
-		"user":     user,
+		"title":     "Edit Device",
+		"device":    device,
+		"products":  products,
+		"user":      user,
 	})
 }
 
@@ -487,18 +557,22 @@ func (h *DeviceHandler) UpdateDevice(c *gin.Context) {
 		}
 	}
 
+	db := h.deviceRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Device{}, "deviceID", deviceID)
 	if err := h.deviceRepo.Update(&device); err != nil {
 		user, _ := GetCurrentUser(c)
 		products, _ := h.productRepo.List(&models.FilterParams{})
 		c.HTML(http.StatusInternalServerError, "device_form.html", gin.H{
-			"title":    "Edit Device",
-			"device":   &device,
-			"products": products,
-			"error":    err.Error(),
-			"user":     user,
+			"title":     "Edit Device",
+			"device":    &device,
+			"products":  products,
+			"error":     err.Error(),
+			"user":      user,
 		})
 		return
 	}
+	invalidateDeviceCaches()
+	writeAuditLog(db, c, "update", "device", deviceID, previous, auditSnapshot(db, &models.Device{}, "deviceID", deviceID))
 
 	c.Redirect(http.StatusFound, "/devices")
 }
@@ -506,10 +580,17 @@ func (h *DeviceHandler) UpdateDevice(c *gin.Context) {
 func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
 	deviceID := c.Param("id")
 
+	if !h.requireDeviceJSON(c, deviceID) {
+		return
+	}
+	db := h.deviceRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Device{}, "deviceID", deviceID)
 	if err := h.deviceRepo.Delete(deviceID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to delete device")
 		return
 	}
+	invalidateDeviceCaches()
+	writeAuditLog(db, c, "delete", "device", deviceID, previous, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Device deleted successfully"})
 }
@@ -525,9 +606,11 @@ func (h *DeviceHandler) GetDeviceQR(c *gin.Context) {
 		return
 	}
 
-	// Use serial number if available, otherwise use device ID
+	// Prefer the stored QR payload, then the serial number, then the device ID
 	identifier := deviceID
-	if device.SerialNumber != nil && *device.SerialNumber != "" {
+	if device.QRCode != nil && *device.QRCode != "" {
+		identifier = *device.QRCode
+	} else if device.SerialNumber != nil && *device.SerialNumber != "" {
 		identifier = *device.SerialNumber
 	}
 	
@@ -569,7 +652,8 @@ func (h *DeviceHandler) GetDeviceBarcode(c *gin.Context) {
 func (h *DeviceHandler) GetAvailableDevices(c *gin.Context) {
 	devices, err := h.deviceRepo.GetAvailableDevices()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("GetAvailableDevices: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load available devices")
 		return
 	}
 
@@ -582,13 +666,34 @@ func (h *DeviceHandler) GetDevicesByCategory(c *gin.Context) {
 	
 	categoryIDUint, err := strconv.ParseUint(categoryID, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid category ID")
 		return
 	}
 	
 	devices, err := h.productRepo.GetDevicesByCategory(uint(categoryIDUint))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("GetDevicesByCategory: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load category devices")
+		return
+	}
+	
+	c.JSON(http.StatusOK, devices)
+}
+
+// GetDirectCategoryDevices returns the devices of a category whose product has no subcategory
+func (h *DeviceHandler) GetDirectCategoryDevices(c *gin.Context) {
+	categoryID := c.Param("id")
+	
+	categoryIDUint, err := strconv.ParseUint(categoryID, 10, 32)
+	if err != nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid category ID")
+		return
+	}
+	
+	devices, err := h.getDirectCategoryDevices(uint(categoryIDUint))
+	if err != nil {
+		logger.Errorf("GetDirectCategoryDevices: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load category devices")
 		return
 	}
 	
@@ -600,7 +705,8 @@ func (h *DeviceHandler) GetDevicesBySubcategory(c *gin.Context) {
 	
 	devices, err := h.productRepo.GetDevicesBySubcategory(subcategoryID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("GetDevicesBySubcategory: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load subcategory devices")
 		return
 	}
 	
@@ -612,7 +718,8 @@ func (h *DeviceHandler) GetDevicesBySubbiercategory(c *gin.Context) {
 	
 	devices, err := h.productRepo.GetDevicesBySubbiercategory(subbiercategoryID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("GetDevicesBySubbiercategory: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load subbiercategory devices")
 		return
 	}
 	
@@ -623,14 +730,15 @@ func (h *DeviceHandler) GetDevicesBySubbiercategory(c *gin.Context) {
 func (h *DeviceHandler) ListDevicesAPI(c *gin.Context) {
 	params := &models.FilterParams{}
 	if err := c.ShouldBindQuery(params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondValidationError(c, err)
 		return
 	}
 
 	// Use the new method with categories for case management
 	devices, err := h.deviceRepo.ListWithCategories(params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("ListDevicesAPI: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load devices")
 		return
 	}
 
@@ -640,14 +748,17 @@ func (h *DeviceHandler) ListDevicesAPI(c *gin.Context) {
 func (h *DeviceHandler) CreateDeviceAPI(c *gin.Context) {
 	var device models.Device
 	if err := c.ShouldBindJSON(&device); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondValidationError(c, err)
 		return
 	}
 
 	if err := h.deviceRepo.Create(&device); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("CreateDeviceAPI: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to create device")
 		return
 	}
+	invalidateDeviceCaches()
+	writeAuditLog(h.deviceRepo.GetDB().DB, c, "create", "device", device.DeviceID, nil, device)
 
 	c.JSON(http.StatusCreated, device)
 }
@@ -655,16 +766,211 @@ func (h *DeviceHandler) CreateDeviceAPI(c *gin.Context) {
 func (h *DeviceHandler) GetDeviceAPI(c *gin.Context) {
 	deviceID := c.Param("id")
 	device, err := h.deviceRepo.GetByID(deviceID)
-	if err != nil {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		// Try by serial number if not found by ID
 		device, err = h.deviceRepo.GetBySerialNo(deviceID)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Then by a scanned QR payload
+		device, err = h.deviceRepo.GetByQRCode(deviceID)
+	}
+	if err != nil {
+		respondDeviceLookupError(c, "GetDeviceAPI", deviceID, err)
+		return
+	}
+
+	location := gin.H{
+		"location":  device.CurrentLocation,
+		"latitude":  device.GPSLatitude,
+		"longitude": device.GPSLongitude,
+		"lastMove":  nil,
+	}
+	if moves, err := h.deviceRepo.ListLocationHistory(device.DeviceID, 1); err != nil {
+		logger.Errorf("GetDeviceAPI: location history for %s: %v", device.DeviceID, err)
+	} else if len(moves) > 0 {
+		location["lastMove"] = moves[0]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"device": device, "location": location})
+}
+
+// UpdateDeviceLocation records where a device physically is, for field crews
+// moving equipment between warehouses, trucks and venues
+func (h *DeviceHandler) UpdateDeviceLocation(c *gin.Context) {
+	if !userHasPermission(h.deviceRepo.GetDB().DB, c, "devices.location") {
+		respondJSONError(c, http.StatusForbidden, errCodeForbidden, "Insufficient permissions")
+		return
+	}
+
+	deviceID := c.Param("id")
+	var req models.DeviceLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	req.Location = strings.TrimSpace(req.Location)
+	if req.Location == "" {
+		respondFieldErrors(c, []FieldError{{Field: "location", Rule: "required", Message: "location is required"}})
+		return
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		respondFieldErrors(c, []FieldError{{Field: "latitude", Rule: "required_with", Param: "longitude",
+			Message: "latitude and longitude must be given together"}})
+		return
+	}
+
+	var movedBy *uint
+	if user, ok := GetCurrentUser(c); ok {
+		movedBy = &user.UserID
+	}
+	move, err := h.deviceRepo.UpdateLocation(deviceID, req.Location, req.Latitude, req.Longitude, movedBy, req.Notes)
+	if err != nil {
+		respondDeviceLookupError(c, "UpdateDeviceLocation", deviceID, err)
+		return
+	}
+	invalidateDeviceCaches()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device location updated", "move": move})
+}
+
+// GetDeviceLocationHistoryAPI returns where a device was moved, newest first
+func (h *DeviceHandler) GetDeviceLocationHistoryAPI(c *gin.Context) {
+	deviceID := c.Param("id")
+	if !h.requireDeviceJSON(c, deviceID) {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	moves, err := h.deviceRepo.ListLocationHistory(deviceID, limit)
+	if err != nil {
+		logger.Errorf("GetDeviceLocationHistoryAPI: %s: %v", deviceID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to fetch location history")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deviceID": deviceID, "moves": moves})
+}
+
+// respondDeviceLookupError answers a failed device lookup with 404 when the
+// device does not exist and 500 for any other error
+func respondDeviceLookupError(c *gin.Context, handler, deviceID string, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		respondJSONError(c, http.StatusNotFound, errCodeNotFound, "Device not found")
+		return
+	}
+	logger.Errorf("%s: device %s: %v", handler, deviceID, err)
+	respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load device")
+}
+
+// requireDeviceJSON checks that the device exists, responding with a JSON
+// error and returning false if it doesn't
+func (h *DeviceHandler) requireDeviceJSON(c *gin.Context, deviceID string) bool {
+	var count int64
+	if err := h.deviceRepo.GetDB().Model(&models.Device{}).Where("deviceID = ?", deviceID).Count(&count).Error; err != nil {
+		respondDeviceLookupError(c, c.HandlerName(), deviceID, err)
+		return false
+	}
+	if count == 0 {
+		respondJSONError(c, http.StatusNotFound, errCodeNotFound, "Device not found")
+		return false
+	}
+	return true
+}
+
+// maxDeviceSearchResults caps the devices returned by the search API
+const maxDeviceSearchResults = 20
+
+// DeviceSearchResult is a device suggested for a partial device ID or serial
+// number, with whether it is free for the requested dates
+type DeviceSearchResult struct {
+	DeviceID     string  `json:"deviceID"`
+	SerialNumber *string `json:"serialnumber"`
+	ProductName  string  `json:"productName"`
+	Status       string  `json:"status"`
+	Available    bool    `json:"available"`
+}
+
+// SearchDevicesAPI suggests devices whose ID or serial number starts with or
+// contains q, prefix matches first. Availability is for today unless
+// start_date and end_date are given; job_id ignores that job's bookings.
+func (h *DeviceHandler) SearchDevicesAPI(c *gin.Context) {
+	term := strings.TrimSpace(c.Query("q"))
+	if term == "" {
+		c.JSON(http.StatusOK, gin.H{"devices": []DeviceSearchResult{}})
+		return
+	}
+
+	today, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	start, end := today, today
+	if c.Query("start_date") != "" || c.Query("end_date") != "" {
+		var err error
+		if start, err = time.Parse("2006-01-02", c.Query("start_date")); err != nil {
+			respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid start_date format. Use YYYY-MM-DD")
 			return
 		}
+		if end, err = time.Parse("2006-01-02", c.Query("end_date")); err != nil {
+			respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid end_date format. Use YYYY-MM-DD")
+			return
+		}
+		if end.Before(start) {
+			respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "end_date must not be before start_date")
+			return
+		}
+	}
+	excludeJobID := c.Query("job_id")
+	if excludeJobID != "" {
+		if _, err := strconv.ParseUint(excludeJobID, 10, 32); err != nil {
+			respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid job_id")
+			return
+		}
+	}
+
+	devices, err := h.deviceRepo.SearchByIDOrSerial(term, maxDeviceSearchResults)
+	if err != nil {
+		logger.Errorf("SearchDevicesAPI: %q: %v", term, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to search devices")
+		return
+	}
+
+	results := make([]DeviceSearchResult, 0, len(devices))
+	if len(devices) == 0 {
+		c.JSON(http.StatusOK, gin.H{"devices": results})
+		return
+	}
+	deviceIDs := make([]string, len(devices))
+	for i := range devices {
+		deviceIDs[i] = devices[i].DeviceID
 	}
 
-	c.JSON(http.StatusOK, gin.H{"device": device})
+	unavailable, err := h.unavailableDeviceIDs(start, end, excludeJobID, deviceIDs)
+	if err != nil {
+		logger.Errorf("SearchDevicesAPI: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to check device availability")
+		return
+	}
+
+	for _, device := range devices {
+		result := DeviceSearchResult{
+			DeviceID:     device.DeviceID,
+			SerialNumber: device.SerialNumber,
+			Status:       device.Status,
+			Available:    !unavailable[device.DeviceID],
+		}
+		if device.Product != nil {
+			result.ProductName = device.Product.Name
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"devices":   results,
+		"startDate": start.Format("2006-01-02"),
+		"endDate":   end.Format("2006-01-02"),
+	})
 }
 
 func (h *DeviceHandler) UpdateDeviceAPI(c *gin.Context) {
@@ -672,15 +978,23 @@ func (h *DeviceHandler) UpdateDeviceAPI(c *gin.Context) {
 
 	var device models.Device
 	if err := c.ShouldBindJSON(&device); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondValidationError(c, err)
 		return
 	}
 
+	if !h.requireDeviceJSON(c, deviceID) {
+		return
+	}
+	db := h.deviceRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Device{}, "deviceID", deviceID)
 	device.DeviceID = deviceID
 	if err := h.deviceRepo.Update(&device); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("UpdateDeviceAPI: %s: %v", deviceID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to update device")
 		return
 	}
+	invalidateDeviceCaches()
+	writeAuditLog(db, c, "update", "device", deviceID, previous, auditSnapshot(db, &models.Device{}, "deviceID", deviceID))
 
 	c.JSON(http.StatusOK, device)
 }
@@ -688,24 +1002,72 @@ func (h *DeviceHandler) UpdateDeviceAPI(c *gin.Context) {
 func (h *DeviceHandler) DeleteDeviceAPI(c *gin.Context) {
 	deviceID := c.Param("id")
 
+	if !h.requireDeviceJSON(c, deviceID) {
+		return
+	}
+	db := h.deviceRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Device{}, "deviceID", deviceID)
 	if err := h.deviceRepo.Delete(deviceID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to delete device")
 		return
 	}
+	invalidateDeviceCaches()
+	writeAuditLog(db, c, "delete", "device", deviceID, previous, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Device deleted successfully"})
 }
 
+// GetDeviceLabelPrintsAPI returns when and by whom a device's label was printed, newest first
+func (h *DeviceHandler) GetDeviceLabelPrintsAPI(c *gin.Context) {
+	deviceID := c.Param("id")
+	if !h.requireDeviceJSON(c, deviceID) {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	summary, err := h.deviceRepo.GetLabelPrintSummary(deviceID)
+	if err != nil {
+		logger.Errorf("GetDeviceLabelPrintsAPI: %s: %v", deviceID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to fetch label prints")
+		return
+	}
+	prints, err := h.deviceRepo.ListLabelPrints(deviceID, limit)
+	if err != nil {
+		logger.Errorf("GetDeviceLabelPrintsAPI: %s: %v", deviceID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to fetch label prints")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deviceID":    deviceID,
+		"count":       summary.Count,
+		"lastPrinted": summary.LastPrint,
+		"prints":      prints,
+	})
+}
+
 func (h *DeviceHandler) GetDeviceStatsAPI(c *gin.Context) {
 	deviceID := c.Param("id")
 	
 	// Get device details
 	device, err := h.deviceRepo.GetByID(deviceID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		respondDeviceLookupError(c, "GetDeviceStatsAPI", deviceID, err)
 		return
 	}
 
+	// Latest condition rating; devices never rated keep the column default
+	condition := gin.H{"rating": device.ConditionRating, "lastRated": nil}
+	if latest, err := h.deviceRepo.GetLatestCondition(deviceID); err != nil {
+		logger.Errorf("GetDeviceStatsAPI: condition for %s: %v", deviceID, err)
+	} else if latest != nil {
+		condition["lastRated"] = latest
+	}
+
 	// Get device statistics
 	stats, err := h.deviceRepo.GetDeviceStats(deviceID)
 	if err != nil {
@@ -718,6 +1080,7 @@ func (h *DeviceHandler) GetDeviceStatsAPI(c *gin.Context) {
 				"totalDaysRented": 0,
 				"averageRentalDuration": 0.0,
 			},
+			"condition": condition,
 		})
 		return
 	}
@@ -725,17 +1088,180 @@ func (h *DeviceHandler) GetDeviceStatsAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"device": device,
 		"stats": stats,
+		"condition": condition,
+	})
+}
+
+// UpdateDeviceCondition rates a device's physical condition from 0 to 5 and
+// keeps the rating in its condition history
+func (h *DeviceHandler) UpdateDeviceCondition(c *gin.Context) {
+	deviceID := c.Param("id")
+	var req models.DeviceConditionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	var ratedBy *uint
+	if user, ok := GetCurrentUser(c); ok {
+		ratedBy = &user.UserID
+	}
+	rating, err := h.deviceRepo.UpdateCondition(deviceID, *req.Rating, ratedBy, strings.TrimSpace(req.Notes))
+	if err != nil {
+		respondDeviceLookupError(c, "UpdateDeviceCondition", deviceID, err)
+		return
+	}
+	invalidateDeviceCaches()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device condition updated", "condition": rating})
+}
+
+// GetDeviceConditionHistoryAPI returns a device's condition ratings, oldest first
+func (h *DeviceHandler) GetDeviceConditionHistoryAPI(c *gin.Context) {
+	deviceID := c.Param("id")
+	if !h.requireDeviceJSON(c, deviceID) {
+		return
+	}
+
+	ratings, err := h.deviceRepo.ListConditionHistory(deviceID)
+	if err != nil {
+		logger.Errorf("GetDeviceConditionHistoryAPI: %s: %v", deviceID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to fetch condition history")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deviceID": deviceID, "ratings": ratings})
+}
+
+// Service interval for the maintenance-due report, in days
+const (
+	defaultMaintenanceIntervalDays = 180
+	maxMaintenanceIntervalDays     = 3650
+)
+
+// MaintenanceDueDevice is a device overdue for preventive maintenance
+type MaintenanceDueDevice struct {
+	DeviceID        string     `json:"deviceID"`
+	SerialNumber    *string    `json:"serialnumber"`
+	ProductName     string     `json:"productName"`
+	Status          string     `json:"status"`
+	LastMaintenance *time.Time `json:"lastMaintenance"`
+	NextMaintenance *time.Time `json:"nextMaintenance"`
+	// DaysOverdue counts from when the interval ran out; nil if never serviced
+	DaysOverdue *int `json:"daysOverdue"`
+}
+
+// GetMaintenanceDueAPI lists devices whose last maintenance is older than
+// intervalDays (default 180) or that were never serviced, most overdue first
+func (h *DeviceHandler) GetMaintenanceDueAPI(c *gin.Context) {
+	intervalDays := defaultMaintenanceIntervalDays
+	if raw := c.Query("intervalDays"); raw != "" {
+		days, err := strconv.Atoi(raw)
+		if err != nil || days < 1 || days > maxMaintenanceIntervalDays {
+			respondFieldErrors(c, []FieldError{{Field: "intervalDays", Rule: "range", Param: fmt.Sprintf("1-%d", maxMaintenanceIntervalDays),
+				Message: fmt.Sprintf("intervalDays must be a whole number from 1 to %d", maxMaintenanceIntervalDays)}})
+			return
+		}
+		intervalDays = days
+	}
+
+	today, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	cutoff := today.AddDate(0, 0, -intervalDays)
+	devices, err := h.deviceRepo.GetServiceOverdue(cutoff)
+	if err != nil {
+		logger.Errorf("GetMaintenanceDueAPI: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load devices due for maintenance")
+		return
+	}
+
+	results := make([]MaintenanceDueDevice, 0, len(devices))
+	for _, device := range devices {
+		result := MaintenanceDueDevice{
+			DeviceID:        device.DeviceID,
+			SerialNumber:    device.SerialNumber,
+			Status:          device.Status,
+			LastMaintenance: device.LastMaintenance,
+			NextMaintenance: device.NextMaintenance,
+		}
+		if device.Product != nil {
+			result.ProductName = device.Product.Name
+		}
+		if device.LastMaintenance != nil {
+			days := int(cutoff.Sub(*device.LastMaintenance).Hours() / 24)
+			result.DaysOverdue = &days
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"intervalDays":  intervalDays,
+		"serviceBefore": cutoff.Format("2006-01-02"),
+		"count":         len(results),
+		"devices":       results,
 	})
 }
 
+// GetAvailableDevicesAPI lists the devices that are free today. With
+// start_date and end_date it lists the devices free for that window instead,
+// whatever their current status; job_id ignores that job's bookings.
 func (h *DeviceHandler) GetAvailableDevicesAPI(c *gin.Context) {
-	devices, err := h.deviceRepo.GetAvailableDevices()
+	if c.Query("start_date") == "" && c.Query("end_date") == "" {
+		devices, err := h.deviceRepo.GetAvailableDevices()
+		if err != nil {
+			logger.Errorf("GetAvailableDevicesAPI: %v", err)
+			respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load available devices")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"devices": devices})
+		return
+	}
+
+	start, err := time.Parse("2006-01-02", c.Query("start_date"))
+	if err != nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid start_date format. Use YYYY-MM-DD")
+		return
+	}
+	end, err := time.Parse("2006-01-02", c.Query("end_date"))
+	if err != nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid end_date format. Use YYYY-MM-DD")
+		return
+	}
+	if end.Before(start) {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "end_date must not be before start_date")
+		return
+	}
+	excludeJobID := c.Query("job_id")
+	if excludeJobID != "" {
+		if _, err := strconv.ParseUint(excludeJobID, 10, 32); err != nil {
+			respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid job_id")
+			return
+		}
+	}
+
+	unavailable, err := h.unavailableDeviceIDs(start, end, excludeJobID, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("GetAvailableDevicesAPI: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to check device availability")
 		return
 	}
+	excluded := make([]string, 0, len(unavailable))
+	for deviceID := range unavailable {
+		excluded = append(excluded, deviceID)
+	}
 
-	c.JSON(http.StatusOK, gin.H{"devices": devices})
+	devices, err := h.deviceRepo.GetDevicesExcept(excluded)
+	if err != nil {
+		logger.Errorf("GetAvailableDevicesAPI: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load available devices")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"devices":   devices,
+		"startDate": start.Format("2006-01-02"),
+		"endDate":   end.Format("2006-01-02"),
+	})
 }
 
 // GetAvailableDevicesForJobAPI returns devices available for a specific job's date range
@@ -743,7 +1269,7 @@ func (h *DeviceHandler) GetAvailableDevicesForJobAPI(c *gin.Context) {
 	jobIDStr := c.Param("jobId")
 	jobID, err := strconv.ParseUint(jobIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid job ID")
 		return
 	}
 
@@ -755,13 +1281,14 @@ func (h *DeviceHandler) GetAvailableDevicesForJobAPI(c *gin.Context) {
 	db := h.deviceRepo.GetDB() // We need to add this method to device repo
 	err = db.First(&job, uint(jobID)).Error
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		respondJSONError(c, http.StatusNotFound, errCodeNotFound, "Job not found")
 		return
 	}
 
 	devices, err := h.deviceRepo.GetAvailableDevicesForJob(uint(jobID), job.StartDate, job.EndDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("GetAvailableDevicesForJobAPI: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load available devices")
 		return
 	}
 
@@ -775,27 +1302,28 @@ func (h *DeviceHandler) GetDeviceTreeWithAvailability(c *gin.Context) {
 	jobID := c.Query("job_id") // Optional - exclude this job from availability check
 	
 	if startDate == "" || endDate == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date and end_date are required"})
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "start_date and end_date are required")
 		return
 	}
 	
 	// Parse dates
 	start, err := time.Parse("2006-01-02", startDate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format. Use YYYY-MM-DD"})
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid start_date format. Use YYYY-MM-DD")
 		return
 	}
 	
 	end, err := time.Parse("2006-01-02", endDate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format. Use YYYY-MM-DD"})
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid end_date format. Use YYYY-MM-DD")
 		return
 	}
 	
 	// Get tree data with availability information
 	treeData, err := h.buildTreeDataWithAvailability(start, end, jobID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		logger.Errorf("GetDeviceTreeWithAvailability: %v", err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load device tree")
 		return
 	}
 	
@@ -831,8 +1359,43 @@ type TreeDevice struct {
 	ProductName  string `json:"product_name"`
 	SerialNumber string `json:"serial_number"`
 	Status       string `json:"status"`
+	Consumable   bool   `json:"consumable,omitempty"`   // Product is not tracked per unit
 	Available    bool   `json:"available,omitempty"`    // Only included in availability checks
 	ConflictJob  string `json:"conflict_job,omitempty"` // Job ID that conflicts
+	ConflictType string `json:"conflict_type,omitempty"` // job, hold or maintenance
+}
+
+// Conflict types reported by the availability tree. Holds are warnings and
+// leave the device bookable; jobs and maintenance windows block it.
+const (
+	ConflictTypeJob         = "job"
+	ConflictTypeHold        = "hold"
+	ConflictTypeMaintenance = "maintenance"
+)
+
+// DeviceConflict describes why a device is not (or only tentatively) free in a date range
+type DeviceConflict struct {
+	Type  string
+	JobID string
+}
+
+// Blocking reports whether the conflict makes the device unavailable
+func (dc DeviceConflict) Blocking() bool {
+	return dc.Type != ConflictTypeHold
+}
+
+// conflictPriority orders conflicts so the most restrictive one is reported
+var conflictPriority = map[string]int{
+	ConflictTypeHold:        1,
+	ConflictTypeJob:         2,
+	ConflictTypeMaintenance: 3,
+}
+
+func addConflict(conflicts map[string]DeviceConflict, deviceID string, conflict DeviceConflict) {
+	if existing, ok := conflicts[deviceID]; ok && conflictPriority[existing.Type] >= conflictPriority[conflict.Type] {
+		return
+	}
+	conflicts[deviceID] = conflict
 }
 
 // buildTreeData creates a hierarchical tree structure with categories, subcategories, subbiercategories, and devices
@@ -863,37 +1426,189 @@ func (h *DeviceHandler) buildTreeData() ([]TreeCategory, error) {
 	return treeCategories, nil
 }
 
-// buildTreeDataWithAvailability creates tree structure with device availability for date range
-func (h *DeviceHandler) buildTreeDataWithAvailability(startDate, endDate time.Time, excludeJobID string) ([]TreeCategory, error) {
-	// Get conflicting jobs for the date range first (more efficient)
-	var conflictingJobs []struct {
-		JobID    string `json:"job_id" gorm:"column:jobID"`
-		DeviceID string `json:"device_id" gorm:"column:deviceID"`
-	}
-	
+// onHoldStatusSQL is true for jobs with an "on hold" status, which only
+// tentatively reserve their devices. Expects the alias s (status).
+const onHoldStatusSQL = "LOWER(REPLACE(COALESCE(s.status, ''), ' ', '_')) = 'on_hold'"
+
+// conflictingJobsQuery selects the job device assignments overlapping the date
+// range, with the aliases jd (jobdevices), j (jobs), s (status), d (devices)
+// and p (products). Devices of consumable products never conflict, so they are
+// left out.
+func (h *DeviceHandler) conflictingJobsQuery(startDate, endDate time.Time, excludeJobID string) *gorm.DB {
 	query := h.deviceRepo.GetDB().
 		Table("jobdevices jd").
-		Select("j.jobID, jd.deviceID").
 		Joins("JOIN jobs j ON jd.jobID = j.jobID").
-		Where("NOT (COALESCE(j.endDate, j.startDate) < ? OR j.startDate > ?)", startDate, endDate)
-	
+		Joins("LEFT JOIN status s ON j.statusID = s.statusID").
+		Joins("JOIN devices d ON jd.deviceID = d.deviceID").
+		Joins("LEFT JOIN products p ON d.productID = p.productID").
+		Where("NOT (COALESCE(j.endDate, j.startDate) < ? OR j.startDate > ?)", startDate, endDate).
+		Where("COALESCE(p.is_consumable, FALSE) = FALSE")
+
 	// Exclude current job if provided
 	if excludeJobID != "" {
 		query = query.Where("j.jobID != ?", excludeJobID)
 	}
+	return query
+}
+
+// maintenanceConflictQuery selects devices in maintenance, or with maintenance
+// scheduled inside the date range, which blocks them
+func (h *DeviceHandler) maintenanceConflictQuery(startDate, endDate time.Time) *gorm.DB {
+	return h.deviceRepo.GetDB().
+		Model(&models.Device{}).
+		Where("status IN ? OR nextmaintenance BETWEEN ? AND ?", models.DeviceMaintenanceStatuses, startDate, endDate)
+}
+
+// unavailableDeviceIDs returns the devices booked by a job other than a hold,
+// or in maintenance, during the date range. deviceIDs limits the check to
+// those devices; nil checks all of them.
+func (h *DeviceHandler) unavailableDeviceIDs(startDate, endDate time.Time, excludeJobID string, deviceIDs []string) (map[string]bool, error) {
+	bookedQuery := h.conflictingJobsQuery(startDate, endDate, excludeJobID).
+		Where("NOT (" + onHoldStatusSQL + ")")
+	maintenanceQuery := h.maintenanceConflictQuery(startDate, endDate)
+	if deviceIDs != nil {
+		bookedQuery = bookedQuery.Where("jd.deviceID IN ?", deviceIDs)
+		maintenanceQuery = maintenanceQuery.Where("deviceID IN ?", deviceIDs)
+	}
+
+	var booked []string
+	if err := bookedQuery.Distinct().Pluck("jd.deviceID", &booked).Error; err != nil {
+		return nil, fmt.Errorf("failed to check device availability: %v", err)
+	}
+	var inMaintenance []string
+	if err := maintenanceQuery.Pluck("deviceID", &inMaintenance).Error; err != nil {
+		return nil, fmt.Errorf("failed to check device maintenance: %v", err)
+	}
+
+	unavailable := make(map[string]bool, len(booked)+len(inMaintenance))
+	for _, deviceID := range append(booked, inMaintenance...) {
+		unavailable[deviceID] = true
+	}
+	return unavailable, nil
+}
+
+// DeviceJobConflict is a job holding a device during a requested date range
+type DeviceJobConflict struct {
+	JobID        uint       `json:"jobID" gorm:"column:jobID"`
+	Type         string     `json:"type"` // job or hold
+	Status       string     `json:"status" gorm:"column:status"`
+	StartDate    *time.Time `json:"startDate" gorm:"column:startDate"`
+	EndDate      *time.Time `json:"endDate" gorm:"column:endDate"`
+	CustomerID   uint       `json:"customerID" gorm:"column:customerID"`
+	CustomerName string     `json:"customerName" gorm:"column:customer_name"`
+	OnHold       bool       `json:"-" gorm:"column:on_hold"`
+}
+
+// CheckAvailability reports whether a single device is free from start_date
+// to end_date, optionally ignoring job_id, and lists what blocks it. Holds
+// are returned as conflicts but leave the device available, as in the tree.
+func (h *DeviceHandler) CheckAvailability(c *gin.Context) {
+	deviceID := c.Param("id")
+	start, err := time.Parse("2006-01-02", c.Query("start_date"))
+	if err != nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid start_date format. Use YYYY-MM-DD")
+		return
+	}
+	end, err := time.Parse("2006-01-02", c.Query("end_date"))
+	if err != nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid end_date format. Use YYYY-MM-DD")
+		return
+	}
+	if end.Before(start) {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "end_date must not be before start_date")
+		return
+	}
+	excludeJobID := c.Query("job_id")
+	if excludeJobID != "" {
+		if _, err := strconv.ParseUint(excludeJobID, 10, 32); err != nil {
+			respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid job_id")
+			return
+		}
+	}
+
+	device, err := h.deviceRepo.GetByID(deviceID)
+	if err != nil {
+		respondDeviceLookupError(c, "CheckAvailability", deviceID, err)
+		return
+	}
+
+	conflicts := []DeviceJobConflict{}
+	err = h.conflictingJobsQuery(start, end, excludeJobID).
+		Select("j.jobID, COALESCE(s.status, '') AS status, j.startDate, j.endDate, j.customerID, "+
+			"COALESCE(NULLIF(c.companyname, ''), TRIM(CONCAT(COALESCE(c.firstname, ''), ' ', COALESCE(c.lastname, '')))) AS customer_name, "+
+			onHoldStatusSQL+" AS on_hold").
+		Joins("LEFT JOIN customers c ON j.customerID = c.customerID").
+		Where("jd.deviceID = ?", deviceID).
+		Order("j.startDate ASC, j.jobID ASC").
+		Scan(&conflicts).Error
+	if err != nil {
+		logger.Errorf("CheckAvailability: device %s: %v", deviceID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to check device availability")
+		return
+	}
+
+	var inMaintenance int64
+	if err := h.maintenanceConflictQuery(start, end).Where("deviceID = ?", deviceID).Count(&inMaintenance).Error; err != nil {
+		logger.Errorf("CheckAvailability: device %s: %v", deviceID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to check device maintenance")
+		return
+	}
+
+	available := inMaintenance == 0
+	for i := range conflicts {
+		conflicts[i].Type = ConflictTypeJob
+		if conflicts[i].OnHold {
+			conflicts[i].Type = ConflictTypeHold
+		} else {
+			available = false
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deviceID":    device.DeviceID,
+		"startDate":   start.Format("2006-01-02"),
+		"endDate":     end.Format("2006-01-02"),
+		"available":   available,
+		"maintenance": inMaintenance > 0,
+		"conflicts":   conflicts,
+	})
+}
+
+// buildTreeDataWithAvailability creates tree structure with device availability for date range
+func (h *DeviceHandler) buildTreeDataWithAvailability(startDate, endDate time.Time, excludeJobID string) ([]TreeCategory, error) {
+	// Get conflicting jobs for the date range first (more efficient)
+	var conflictingJobs []struct {
+		JobID    string `json:"job_id" gorm:"column:jobID"`
+		DeviceID string `json:"device_id" gorm:"column:deviceID"`
+		OnHold   bool   `json:"on_hold" gorm:"column:on_hold"`
+	}
 	
-	err := query.Scan(&conflictingJobs).Error
+	err := h.conflictingJobsQuery(startDate, endDate, excludeJobID).
+		Select("j.jobID, jd.deviceID, " + onHoldStatusSQL + " AS on_hold").
+		Scan(&conflictingJobs).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to check device availability: %v", err)
 	}
 	
-	
-	
+	// Devices in maintenance, or with maintenance scheduled inside the range, are blocked
+	var maintenanceDevices []string
+	err = h.maintenanceConflictQuery(startDate, endDate).
+		Pluck("deviceID", &maintenanceDevices).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check device maintenance: %v", err)
+	}
 	
 	// Create a map for quick conflict lookup
-	conflicts := make(map[string]string) // deviceID -> jobID
+	conflicts := make(map[string]DeviceConflict) // deviceID -> most restrictive conflict
 	for _, conflict := range conflictingJobs {
-		conflicts[conflict.DeviceID] = conflict.JobID
+		conflictType := ConflictTypeJob
+		if conflict.OnHold {
+			conflictType = ConflictTypeHold
+		}
+		addConflict(conflicts, conflict.DeviceID, DeviceConflict{Type: conflictType, JobID: conflict.JobID})
+	}
+	for _, deviceID := range maintenanceDevices {
+		addConflict(conflicts, deviceID, DeviceConflict{Type: ConflictTypeMaintenance})
 	}
 	
 	// Now get tree data (after we have conflicts for better performance)
@@ -909,22 +1624,11 @@ func (h *DeviceHandler) buildTreeDataWithAvailability(startDate, endDate time.Ti
 }
 
 // updateTreeAvailability recursively updates availability info in tree structure
-func (h *DeviceHandler) updateTreeAvailability(categories []TreeCategory, conflicts map[string]string) {
-	totalDevices := 0
-	unavailableDevices := 0
-	
+func (h *DeviceHandler) updateTreeAvailability(categories []TreeCategory, conflicts map[string]DeviceConflict) {
 	for i := range categories {
 		// Update direct devices in category
 		for j := range categories[i].DirectDevices {
-			device := &categories[i].DirectDevices[j]
-			totalDevices++
-			if conflictJob, hasConflict := conflicts[device.DeviceID]; hasConflict {
-				device.Available = false
-				device.ConflictJob = conflictJob
-				unavailableDevices++
-			} else {
-				device.Available = true
-			}
+			applyDeviceConflict(&categories[i].DirectDevices[j], conflicts)
 		}
 		
 		// Update subcategories
@@ -933,13 +1637,7 @@ func (h *DeviceHandler) updateTreeAvailability(categories []TreeCategory, confli
 			
 			// Update direct devices in subcategory
 			for j := range subcategory.DirectDevices {
-				device := &subcategory.DirectDevices[j]
-				if conflictJob, hasConflict := conflicts[device.DeviceID]; hasConflict {
-					device.Available = false
-					device.ConflictJob = conflictJob
-				} else {
-					device.Available = true
-				}
+				applyDeviceConflict(&subcategory.DirectDevices[j], conflicts)
 			}
 			
 			// Update subbiercategories
@@ -948,15 +1646,7 @@ func (h *DeviceHandler) updateTreeAvailability(categories []TreeCategory, confli
 				
 				// Update devices in subbiercategory
 				for j := range subbiercategory.Devices {
-					device := &subbiercategory.Devices[j]
-					totalDevices++
-					if conflictJob, hasConflict := conflicts[device.DeviceID]; hasConflict {
-						device.Available = false
-						device.ConflictJob = conflictJob
-						unavailableDevices++
-					} else {
-						device.Available = true
-					}
+					applyDeviceConflict(&subbiercategory.Devices[j], conflicts)
 				}
 			}
 		}
@@ -964,6 +1654,18 @@ func (h *DeviceHandler) updateTreeAvailability(categories []TreeCategory, confli
 	
 }
 
+// applyDeviceConflict sets a tree device's availability from the conflict map
+func applyDeviceConflict(device *TreeDevice, conflicts map[string]DeviceConflict) {
+	conflict, hasConflict := conflicts[device.DeviceID]
+	if !hasConflict {
+		device.Available = true
+		return
+	}
+	device.Available = !conflict.Blocking()
+	device.ConflictJob = conflict.JobID
+	device.ConflictType = conflict.Type
+}
+
 // buildOptimizedTreeData performs a single query to get all data and builds the tree structure
 func (h *DeviceHandler) buildOptimizedTreeData() ([]TreeCategory, error) {
 	// Single query to get all devices with their complete hierarchy
@@ -1009,7 +1711,7 @@ func (h *DeviceHandler) buildTreeFromDevices(devices []models.Device) ([]TreeCat
 		
 		// Debug logging for MIX1001 devices
 		if device.Product.Subbiercategory != nil && device.Product.Subbiercategory.SubbiercategoryID == "MIX1001" {
-			fmt.Printf("🔧 DEBUG MIX1001 Device: %s, Product: %s, SerialNumber: %v\n", 
+			logger.Debugf("MIX1001 Device: %s, Product: %s, SerialNumber: %v", 
 				device.DeviceID, device.Product.Name, device.SerialNumber)
 		}
 		
@@ -1128,10 +1830,10 @@ func (h *DeviceHandler) buildTreeFromDevices(devices []models.Device) ([]TreeCat
 						
 						// Debug logging for MIX1001
 						if subbiercategoryID == "MIX1001" {
-							fmt.Printf("🔧 DEBUG Creating MIX1001 TreeSubbiercategory: Name='%s', DeviceCount=%d\n", 
+							logger.Debugf("Creating MIX1001 TreeSubbiercategory: Name='%s', DeviceCount=%d", 
 								subbiercategoryName, len(treeDevices))
 							for i, device := range treeDevices {
-								fmt.Printf("🔧 DEBUG MIX1001 TreeDevice[%d]: %s - %s\n", 
+								logger.Debugf("MIX1001 TreeDevice[%d]: %s - %s", 
 									i, device.DeviceID, device.ProductName)
 							}
 						}
@@ -1188,12 +1890,11 @@ func (h *DeviceHandler) convertToTreeDevice(device models.Device) TreeDevice {
 		ProductName:  productName,
 		SerialNumber: serialNum,
 		Status:       device.Status,
+		Consumable:   device.Product != nil && device.Product.IsConsumable,
 	}
 }
 
 // Helper function to get devices directly in category (without subcategory)
 func (h *DeviceHandler) getDirectCategoryDevices(categoryID uint) ([]models.DeviceWithJobInfo, error) {
-	// For now, return empty slice - we'll focus on the hierarchical structure first
-	// Direct category devices are rare in most setups
-	return []models.DeviceWithJobInfo{}, nil
+	return h.productRepo.GetDirectCategoryDevices(categoryID)
 }
\ No newline at end of file
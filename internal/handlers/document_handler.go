@@ -1,24 +1,49 @@
 package handlers
 
 import (
-	"crypto/md5"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// documentEntityTypes lists the entities documents can be attached to
+var documentEntityTypes = map[string]bool{
+	"job":      true,
+	"device":   true,
+	"customer": true,
+	"user":     true,
+	"system":   true,
+	"product":  true,
+}
+
+// defaultMaxDocumentUploadSize is used unless a positive size is configured
+const defaultMaxDocumentUploadSize = 10 << 20
+
+// documentEntityIDPattern restricts entity IDs to values that are safe to use
+// as a directory name below the upload path
+var documentEntityIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 type DocumentHandler struct {
 	db           *gorm.DB
 	uploadPath   string
@@ -26,7 +51,7 @@ type DocumentHandler struct {
 	allowedTypes map[string]bool
 }
 
-func NewDocumentHandler(db *gorm.DB) *DocumentHandler {
+func NewDocumentHandler(db *gorm.DB, documentsConfig *config.DocumentsConfig) *DocumentHandler {
 	// Create upload directory if it doesn't exist
 	uploadPath := "uploads"
 	if err := os.MkdirAll(uploadPath, 0755); err != nil {
@@ -46,10 +71,15 @@ func NewDocumentHandler(db *gorm.DB) *DocumentHandler {
 		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       true,
 	}
 
+	maxFileSize := int64(defaultMaxDocumentUploadSize)
+	if documentsConfig != nil && documentsConfig.MaxUploadSizeMB > 0 {
+		maxFileSize = int64(documentsConfig.MaxUploadSizeMB) << 20
+	}
+
 	return &DocumentHandler{
 		db:           db,
 		uploadPath:   uploadPath,
-		maxFileSize:  10 * 1024 * 1024, // 10MB
+		maxFileSize:  maxFileSize,
 		allowedTypes: allowedTypes,
 	}
 }
@@ -68,7 +98,7 @@ func (h *DocumentHandler) ListDocuments(c *gin.Context) {
 
 	// If entity parameters are provided, filter by them
 	if entityType != "" && entityID != "" {
-		query = query.Where("entity_type = ? AND entity_id = ?", entityType, entityID)
+		query = filterDocumentsByEntity(query, entityType, entityID)
 	}
 
 	result := query.Find(&documents)
@@ -122,46 +152,113 @@ func (h *DocumentHandler) UploadDocumentForm(c *gin.Context) {
 	})
 }
 
-// UploadDocument handles file uploads
+// UploadDocument stores a multipart file below uploads/<entityType>/<entityID>
+// and records it as a Document owned by the current user.
 func (h *DocumentHandler) UploadDocument(c *gin.Context) {
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		respondJSONError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	if !userHasPermission(h.db, c, "documents.upload") && !userHasPermission(h.db, c, "documents.manage") {
+		respondJSONError(c, http.StatusForbidden, errCodeForbidden, "Missing permission documents.upload")
+		return
+	}
+
+	// Reject oversized bodies before multipart parsing spills them to disk
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxFileSize+1<<20)
+
 	entityType := c.PostForm("entityType")
-	entityID := c.PostForm("entityID")
-	documentType := c.PostForm("documentType")
+	entityID := strings.TrimSpace(c.PostForm("entityID"))
+	documentType := strings.TrimSpace(c.PostForm("documentType"))
 	description := c.PostForm("description")
 	isPublic := c.PostForm("isPublic") == "true"
 
 	if entityType == "" || entityID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Entity type and ID are required"})
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Entity type and ID are required")
 		return
 	}
 
-	currentUser, exists := GetCurrentUser(c)
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	if !documentEntityTypes[entityType] {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid entity type")
+		return
+	}
+
+	if documentType == "" {
+		documentType = "other"
+	}
+	if !models.IsValidDocumentType(documentType) {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid document type")
+		return
+	}
+
+	// Product documents (manuals, spec sheets) are shown on every device of the product
+	if entityType == "product" {
+		var count int64
+		if err := h.db.Model(&models.Product{}).Where("productID = ?", entityID).Count(&count).Error; err != nil || count == 0 {
+			respondJSONError(c, http.StatusNotFound, errCodeNotFound, "Product not found")
+			return
+		}
+	}
+
+	document, ok := h.receiveUpload(c, entityType, entityID)
+	if !ok {
+		return
+	}
+	document.DocumentType = documentType
+	document.Description = description
+	document.UploadedBy = &currentUser.UserID
+	document.IsPublic = isPublic
+	document.Version = 1
+
+	if err := h.db.Create(document).Error; err != nil {
+		// Clean up uploaded file on database error
+		os.Remove(document.FilePath)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to save document record")
 		return
 	}
 
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Document uploaded successfully",
+		"documentID": document.DocumentID,
+		"filename":   document.Filename,
+		"checksum":   document.Checksum,
+		"fileSize":   document.FileSize,
+	})
+}
+
+// receiveUpload validates the multipart file of the request and saves it below
+// uploads/<entityType>/<entityID>. The returned document has the entity, file
+// and checksum fields set; the caller fills in the rest and removes the file
+// if the record can't be created. It writes the error response and returns
+// false on failure.
+func (h *DocumentHandler) receiveUpload(c *gin.Context, entityType, entityID string) (*models.Document, bool) {
+	if !documentEntityIDPattern.MatchString(entityID) {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid entity ID")
+		return nil, false
+	}
+
 	// Get uploaded file
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
-		return
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "No file uploaded")
+		return nil, false
 	}
 	defer file.Close()
 
 	// Validate file size
 	if header.Size > h.maxFileSize {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("File size exceeds maximum limit of %d MB", h.maxFileSize/(1024*1024)),
-		})
-		return
+		respondJSONError(c, http.StatusRequestEntityTooLarge, errCodeFileTooLarge,
+			fmt.Sprintf("File size exceeds maximum limit of %d MB", h.maxFileSize/(1024*1024)))
+		return nil, false
 	}
 
 	// Validate file type
 	contentType := header.Header.Get("Content-Type")
 	if !h.allowedTypes[contentType] {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File type not allowed"})
-		return
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "File type not allowed")
+		return nil, false
 	}
 
 	// Generate unique filename
@@ -170,111 +267,297 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 	// Create directory structure if needed
 	entityDir := filepath.Join(h.uploadPath, entityType, entityID)
 	if err := os.MkdirAll(entityDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create directory"})
-		return
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to create directory")
+		return nil, false
 	}
 
 	finalPath := filepath.Join(entityDir, filename)
 
-	// Save file
-	if err := h.saveUploadedFile(file, finalPath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
-		return
-	}
-
-	// Calculate checksum
-	checksum, err := h.calculateFileChecksum(finalPath)
+	// Save file, hashing it on the way to disk
+	size, checksum, err := h.saveUploadedFile(file, finalPath)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate checksum"})
-		return
+		os.Remove(finalPath)
+		if err == errDocumentTooLarge {
+			respondJSONError(c, http.StatusRequestEntityTooLarge, errCodeFileTooLarge,
+				fmt.Sprintf("File size exceeds maximum limit of %d MB", h.maxFileSize/(1024*1024)))
+			return nil, false
+		}
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to save file")
+		return nil, false
 	}
 
-	// Save document record
-	document := models.Document{
+	return &models.Document{
 		EntityType:       entityType,
 		EntityID:         entityID,
 		Filename:         filename,
 		OriginalFilename: header.Filename,
 		FilePath:         finalPath,
-		FileSize:         header.Size,
+		FileSize:         size,
 		MimeType:         contentType,
-		DocumentType:     documentType,
-		Description:      description,
-		UploadedBy:       &currentUser.UserID,
 		UploadedAt:       time.Now(),
-		IsPublic:         isPublic,
-		Version:          1,
 		Checksum:         checksum,
-	}
-
-	if err := h.db.Create(&document).Error; err != nil {
-		// Clean up uploaded file on database error
-		os.Remove(finalPath)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save document record"})
-		return
-	}
-
-	c.JSON(http.StatusCreated, gin.H{
-		"message":    "Document uploaded successfully",
-		"documentID": document.DocumentID,
-		"filename":   filename,
-	})
+	}, true
 }
 
-// DownloadDocument serves a document for download
-func (h *DocumentHandler) DownloadDocument(c *gin.Context) {
-	documentID := c.Param("id")
+// findReadableDocument loads the document named by the id parameter and checks
+// that the caller may read it: public documents are readable by everyone, the
+// rest require documents.view. It writes the error response and returns false
+// if the document is missing or not readable.
+func (h *DocumentHandler) findReadableDocument(c *gin.Context) (*models.Document, bool) {
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid document ID")
+		return nil, false
+	}
 
 	var document models.Document
 	if err := h.db.First(&document, documentID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			respondJSONError(c, http.StatusNotFound, errCodeNotFound, "Document not found")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load document"})
+			logger.Errorf("Failed to load document %d: %v", documentID, err)
+			respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load document")
 		}
+		return nil, false
+	}
+
+	if !document.IsPublic && !userHasPermission(h.db, c, "documents.view") && !userHasPermission(h.db, c, "documents.manage") {
+		respondJSONError(c, http.StatusForbidden, errCodeForbidden, "Missing permission documents.view")
+		return nil, false
+	}
+
+	return &document, true
+}
+
+// requireDocumentFile answers 404 and returns false if the document's file is
+// gone from disk. The row can outlive its file, e.g. after a restore without
+// the uploads directory.
+func requireDocumentFile(c *gin.Context, document *models.Document) bool {
+	if info, err := os.Stat(document.FilePath); err != nil || info.IsDir() {
+		logger.Warnf("File of document %d missing on disk: %s", document.DocumentID, document.FilePath)
+		respondJSONError(c, http.StatusNotFound, errCodeNotFound, "File not found on disk")
+		return false
+	}
+	return true
+}
+
+// serveDocument streams the document's file with the given disposition
+// (attachment or inline) under its original filename
+func serveDocument(c *gin.Context, document *models.Document, disposition string) {
+	contentType := document.MimeType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{
+		"filename": document.OriginalFilename,
+	}))
+
+	c.File(document.FilePath)
+}
+
+// DownloadDocument serves a document for download. Documents that aren't
+// public require documents.view; every download is audit-logged. With
+// ?latest=true, older versions redirect to the newest one.
+func (h *DocumentHandler) DownloadDocument(c *gin.Context) {
+	document, ok := h.findReadableDocument(c)
+	if !ok {
 		return
 	}
 
-	// Check if file exists
-	if _, err := os.Stat(document.FilePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "File not found on disk"})
+	if c.Query("latest") == "true" {
+		versions, _, err := documentVersionChain(h.db, document)
+		if err != nil {
+			logger.Errorf("Failed to load versions of document %d: %v", document.DocumentID, err)
+			respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load document versions")
+			return
+		}
+		if latest := versions[0]; latest.DocumentID != document.DocumentID {
+			c.Redirect(http.StatusFound, fmt.Sprintf("/documents/%d/download", latest.DocumentID))
+			return
+		}
+	}
+
+	if !requireDocumentFile(c, document) {
 		return
 	}
 
-	// Set headers for download
+	writeAuditLog(h.db, c, "download", "document", strconv.FormatUint(uint64(document.DocumentID), 10), nil, gin.H{
+		"entityType":       document.EntityType,
+		"entityID":         document.EntityID,
+		"originalFilename": document.OriginalFilename,
+	})
+
 	c.Header("Content-Description", "File Transfer")
 	c.Header("Content-Transfer-Encoding", "binary")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", document.OriginalFilename))
-	c.Header("Content-Type", document.MimeType)
-
-	c.File(document.FilePath)
+	serveDocument(c, document, "attachment")
 }
 
-// ViewDocument displays a document inline (for images, PDFs, etc.)
+// ViewDocument displays a document inline (for images, PDFs, etc.) with the
+// same access rules as DownloadDocument
 func (h *DocumentHandler) ViewDocument(c *gin.Context) {
-	documentID := c.Param("id")
+	document, ok := h.findReadableDocument(c)
+	if !ok || !requireDocumentFile(c, document) {
+		return
+	}
 
-	var document models.Document
-	if err := h.db.First(&document, documentID).Error; err != nil {
+	serveDocument(c, document, "inline")
+}
+
+// ReplaceDocument uploads a new version of a document. The new row points at
+// the original document through ParentDocumentID, takes the next version
+// number of the chain and inherits the entity, type and visibility.
+func (h *DocumentHandler) ReplaceDocument(c *gin.Context) {
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		respondJSONError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	if !userHasPermission(h.db, c, "documents.upload") && !userHasPermission(h.db, c, "documents.manage") {
+		respondJSONError(c, http.StatusForbidden, errCodeForbidden, "Missing permission documents.upload")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid document ID")
+		return
+	}
+
+	var existing models.Document
+	if err := h.db.First(&existing, documentID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			respondJSONError(c, http.StatusNotFound, errCodeNotFound, "Document not found")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load document"})
+			logger.Errorf("Failed to load document %d: %v", documentID, err)
+			respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load document")
 		}
 		return
 	}
 
-	// Check if file exists
-	if _, err := os.Stat(document.FilePath); os.IsNotExist(err) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "File not found on disk"})
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxFileSize+1<<20)
+
+	document, ok := h.receiveUpload(c, existing.EntityType, existing.EntityID)
+	if !ok {
 		return
 	}
+	document.DocumentType = existing.DocumentType
+	document.Description = existing.Description
+	if description := c.PostForm("description"); description != "" {
+		document.Description = description
+	}
+	document.UploadedBy = &currentUser.UserID
+	document.IsPublic = existing.IsPublic
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		_, original, err := documentVersionChain(tx, &existing)
+		if err != nil {
+			return err
+		}
 
-	// Set headers for inline display
-	c.Header("Content-Type", document.MimeType)
-	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s", document.OriginalFilename))
+		// Serialize concurrent replacements of the same chain, then read it
+		// again so the version number accounts for them
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&models.Document{}, original.DocumentID).Error; err != nil {
+			return err
+		}
+		versions, _, err := documentVersionChain(tx, &original)
+		if err != nil {
+			return err
+		}
 
-	c.File(document.FilePath)
+		document.Version = versions[0].Version + 1
+		document.ParentDocumentID = &original.DocumentID
+		return tx.Create(document).Error
+	})
+	if err != nil {
+		os.Remove(document.FilePath)
+		logger.Errorf("Failed to replace document %d: %v", documentID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to save document record")
+		return
+	}
+
+	writeAuditLog(h.db, c, "replace", "document", strconv.FormatUint(documentID, 10), existing, document)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "New document version uploaded",
+		"document": document,
+	})
+}
+
+// ListDocumentVersions returns every version of a document, newest first
+func (h *DocumentHandler) ListDocumentVersions(c *gin.Context) {
+	document, ok := h.findReadableDocument(c)
+	if !ok {
+		return
+	}
+
+	versions, original, err := documentVersionChain(h.db, document)
+	if err != nil {
+		logger.Errorf("Failed to load versions of document %d: %v", document.DocumentID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load document versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"documentID":       document.DocumentID,
+		"originalID":       original.DocumentID,
+		"latestDocumentID": versions[0].DocumentID,
+		"versions":         versions,
+	})
+}
+
+// maxDocumentVersionDepth bounds the walk along ParentDocumentID in case of
+// inconsistent data
+const maxDocumentVersionDepth = 1000
+
+// documentVersionChain returns all versions of the document, newest first, and
+// the original document they derive from. It walks ParentDocumentID up to the original and then
+// collects everything derived from it, so both versions pointing at the
+// original and chains of versions pointing at their predecessor (as written
+// by invoice PDF uploads) are found.
+func documentVersionChain(db *gorm.DB, document *models.Document) ([]models.Document, models.Document, error) {
+	original := *document
+	for depth := 0; original.ParentDocumentID != nil && depth < maxDocumentVersionDepth; depth++ {
+		var parent models.Document
+		err := db.First(&parent, *original.ParentDocumentID).Error
+		if err == gorm.ErrRecordNotFound {
+			break
+		}
+		if err != nil {
+			return nil, original, err
+		}
+		original = parent
+	}
+
+	versions := []models.Document{original}
+	seen := map[uint]bool{original.DocumentID: true}
+	parentIDs := []uint{original.DocumentID}
+	for depth := 0; len(parentIDs) > 0 && depth < maxDocumentVersionDepth; depth++ {
+		var children []models.Document
+		if err := db.Where("parent_documentID IN ?", parentIDs).Find(&children).Error; err != nil {
+			return nil, original, err
+		}
+		parentIDs = parentIDs[:0]
+		for _, child := range children {
+			if seen[child.DocumentID] {
+				continue
+			}
+			seen[child.DocumentID] = true
+			versions = append(versions, child)
+			parentIDs = append(parentIDs, child.DocumentID)
+		}
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].Version != versions[j].Version {
+			return versions[i].Version > versions[j].Version
+		}
+		return versions[i].DocumentID > versions[j].DocumentID
+	})
+	return versions, original, nil
 }
 
 // DeleteDocument removes a document
@@ -434,6 +717,17 @@ func (h *DocumentHandler) VerifySignature(c *gin.Context) {
 // UTILITY FUNCTIONS
 // ================================================================
 
+// filterDocumentsByEntity restricts a document query to one entity. Devices also
+// inherit the documents attached to their product.
+func filterDocumentsByEntity(query *gorm.DB, entityType, entityID string) *gorm.DB {
+	if entityType == "device" {
+		return query.Where(`(entity_type = 'device' AND entity_id = ?) OR (entity_type = 'product' AND entity_id = (
+			SELECT CAST(productID AS CHAR) FROM devices WHERE deviceID = ?
+		))`, entityID, entityID)
+	}
+	return query.Where("entity_type = ? AND entity_id = ?", entityType, entityID)
+}
+
 func (h *DocumentHandler) generateUniqueFilename(originalFilename string) string {
 	ext := filepath.Ext(originalFilename)
 	timestamp := time.Now().Unix()
@@ -444,30 +738,30 @@ func (h *DocumentHandler) generateUniqueFilename(originalFilename string) string
 	return fmt.Sprintf("%d_%s%s", timestamp, randomHex, ext)
 }
 
-func (h *DocumentHandler) saveUploadedFile(file multipart.File, dst string) error {
+// errDocumentTooLarge is returned by saveUploadedFile when the stream is
+// longer than the configured upload limit
+var errDocumentTooLarge = errors.New("document exceeds upload size limit")
+
+// saveUploadedFile writes file to dst and returns the number of bytes written
+// and their SHA-256 checksum. The multipart header size is client supplied, so
+// the limit is enforced on the actual stream as well.
+func (h *DocumentHandler) saveUploadedFile(file multipart.File, dst string) (int64, string, error) {
 	out, err := os.Create(dst)
 	if err != nil {
-		return err
+		return 0, "", err
 	}
 	defer out.Close()
 
-	_, err = io.Copy(out, file)
-	return err
-}
-
-func (h *DocumentHandler) calculateFileChecksum(filePath string) (string, error) {
-	file, err := os.Open(filePath)
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(out, hash), io.LimitReader(file, h.maxFileSize+1))
 	if err != nil {
-		return "", err
+		return 0, "", err
 	}
-	defer file.Close()
-
-	hash := md5.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
+	if written > h.maxFileSize {
+		return 0, "", errDocumentTooLarge
 	}
 
-	return hex.EncodeToString(hash.Sum(nil)), nil
+	return written, hex.EncodeToString(hash.Sum(nil)), nil
 }
 
 func (h *DocumentHandler) generateVerificationCode() string {
@@ -489,7 +783,7 @@ func (h *DocumentHandler) ListDocumentsAPI(c *gin.Context) {
 	query := h.db.Preload("Uploader").Preload("Signatures")
 	
 	if entityType != "" && entityID != "" {
-		query = query.Where("entity_type = ? AND entity_id = ?", entityType, entityID)
+		query = filterDocumentsByEntity(query, entityType, entityID)
 	}
 	
 	if err := query.Order("uploaded_at DESC").Find(&documents).Error; err != nil {
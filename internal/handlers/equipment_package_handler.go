@@ -3,17 +3,19 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type EquipmentPackageHandler struct {
@@ -30,13 +32,13 @@ func NewEquipmentPackageHandler(packageRepo *repository.EquipmentPackageReposito
 
 // Equipment Package Templates and Forms
 func (h *EquipmentPackageHandler) ShowPackagesList(c *gin.Context) {
-	log.Printf("🎯 EQUIPMENT PACKAGE HANDLER: ShowPackagesList called")
+	logger.Debugf("EQUIPMENT PACKAGE HANDLER: ShowPackagesList called")
 	// Parse filter parameters
 	params := parseFilterParams(c)
 	
 	packages, err := h.packageRepo.List(params)
 	if err != nil {
-		log.Printf("Error fetching equipment packages: %v", err)
+		logger.Errorf("Error fetching equipment packages: %v", err)
 		c.HTML(http.StatusInternalServerError, "error_page.html", gin.H{
 			"error": "Failed to load equipment packages",
 		})
@@ -45,10 +47,10 @@ func (h *EquipmentPackageHandler) ShowPackagesList(c *gin.Context) {
 
 	// Calculate total values and device counts for display
 	for i := range packages {
-		log.Printf("🎯 BEFORE ENRICH: Package %d ('%s') has %d PackageDevices", 
+		logger.Debugf("BEFORE ENRICH: Package %d ('%s') has %d PackageDevices", 
 			packages[i].PackageID, packages[i].Name, len(packages[i].PackageDevices))
 		h.enrichPackageData(&packages[i])
-		log.Printf("🎯 AFTER ENRICH: Package %d ('%s') has %d PackageDevices and DeviceCount=%d", 
+		logger.Debugf("AFTER ENRICH: Package %d ('%s') has %d PackageDevices and DeviceCount=%d", 
 			packages[i].PackageID, packages[i].Name, len(packages[i].PackageDevices), packages[i].DeviceCount)
 	}
 
@@ -59,9 +61,9 @@ func (h *EquipmentPackageHandler) ShowPackagesList(c *gin.Context) {
 	popularPackages, _ := h.packageRepo.GetPopularPackages(5)
 
 	// Debug template data before rendering
-	log.Printf("🎯 TEMPLATE DEBUG: Rendering with %d packages", len(packages))
+	logger.Debugf("TEMPLATE Rendering with %d packages", len(packages))
 	for i, pkg := range packages {
-		log.Printf("🎯 TEMPLATE DEBUG: Package %d: ID=%d, Name='%s', PackageDevices=%d, DeviceCount=%d", 
+		logger.Debugf("TEMPLATE Package %d: ID=%d, Name='%s', PackageDevices=%d, DeviceCount=%d", 
 			i, pkg.PackageID, pkg.Name, len(pkg.PackageDevices), pkg.DeviceCount)
 	}
 	
@@ -99,7 +101,7 @@ func (h *EquipmentPackageHandler) ShowPackageForm(c *gin.Context) {
 	// Get available devices
 	availableDevices, err := h.packageRepo.GetAvailableDevices()
 	if err != nil {
-		log.Printf("Error fetching available devices: %v", err)
+		logger.Errorf("Error fetching available devices: %v", err)
 		c.HTML(http.StatusInternalServerError, "error_page.html", gin.H{
 			"error": "Failed to load available devices",
 		})
@@ -161,17 +163,17 @@ func (h *EquipmentPackageHandler) ShowPackageDetail(c *gin.Context) {
 
 // API Endpoints
 func (h *EquipmentPackageHandler) GetPackages(c *gin.Context) {
-	log.Printf("GetPackages called")
+	logger.Debugf("GetPackages called")
 	params := parseFilterParams(c)
 	
 	packages, err := h.packageRepo.List(params)
 	if err != nil {
-		log.Printf("Error fetching packages: %v", err)
+		logger.Errorf("Error fetching packages: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	log.Printf("Found %d packages", len(packages))
+	logger.Debugf("Found %d packages", len(packages))
 	// Enrich packages with calculated data
 	for i := range packages {
 		h.enrichPackageData(&packages[i])
@@ -189,18 +191,18 @@ func (h *EquipmentPackageHandler) GetPackages(c *gin.Context) {
 
 func (h *EquipmentPackageHandler) GetPackage(c *gin.Context) {
 	packageID := c.Param("id")
-	log.Printf("GetPackage called with packageID: %s", packageID)
+	logger.Debugf("GetPackage called with packageID: %s", packageID)
 	
 	id, err := strconv.ParseUint(packageID, 10, 32)
 	if err != nil {
-		log.Printf("Invalid package ID: %v", err)
+		logger.Debugf("Invalid package ID: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid package ID"})
 		return
 	}
 
 	pkg, err := h.packageRepo.GetByIDWithDeviceDetails(uint(id))
 	if err != nil {
-		log.Printf("Package not found: %v", err)
+		logger.Debugf("Package not found: %v", err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Package not found"})
 		return
 	}
@@ -214,7 +216,7 @@ func (h *EquipmentPackageHandler) GetPackage(c *gin.Context) {
 	// Validate package devices
 	isValid, invalidDevices, _ := h.packageRepo.ValidatePackageDevices(uint(id))
 
-	log.Printf("Successfully returning package data for ID: %d", id)
+	logger.Debugf("Successfully returning package data for ID: %d", id)
 	c.JSON(http.StatusOK, gin.H{
 		"package":        pkg,
 		"stats":          stats,
@@ -226,7 +228,7 @@ func (h *EquipmentPackageHandler) GetPackage(c *gin.Context) {
 func (h *EquipmentPackageHandler) CreatePackage(c *gin.Context) {
 	var req models.CreateEquipmentPackageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondValidationError(c, err)
 		return
 	}
 
@@ -267,6 +269,7 @@ func (h *EquipmentPackageHandler) CreatePackage(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	invalidateDeviceCaches()
 
 	// Enrich the created package
 	h.enrichPackageData(pkg)
@@ -276,31 +279,31 @@ func (h *EquipmentPackageHandler) CreatePackage(c *gin.Context) {
 
 func (h *EquipmentPackageHandler) UpdatePackage(c *gin.Context) {
 	packageID := c.Param("id")
-	log.Printf("🔄 UpdatePackage called with packageID: %s", packageID)
+	logger.Debugf("UpdatePackage called with packageID: %s", packageID)
 	
 	id, err := strconv.ParseUint(packageID, 10, 32)
 	if err != nil {
-		log.Printf("Invalid package ID: %v", err)
+		logger.Debugf("Invalid package ID: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid package ID"})
 		return
 	}
 
 	// Log the raw request body
 	bodyBytes, _ := c.GetRawData()
-	log.Printf("Raw request body: %s", string(bodyBytes))
+	logger.Debugf("Raw request body: %s", string(bodyBytes))
 	
 	// Reset the request body for binding
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 	
 	var req models.UpdateEquipmentPackageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("Failed to bind JSON: %v", err)
-		log.Printf("Raw JSON was: %s", string(bodyBytes))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		logger.Errorf("Failed to bind JSON: %v", err)
+		logger.Debugf("Raw JSON was: %s", string(bodyBytes))
+		respondValidationError(c, err)
 		return
 	}
 	
-	log.Printf("Update request data: %+v", req)
+	logger.Debugf("Update request data: %+v", req)
 
 	// Get existing package
 	pkg, err := h.packageRepo.GetByID(uint(id))
@@ -312,7 +315,7 @@ func (h *EquipmentPackageHandler) UpdatePackage(c *gin.Context) {
 	// Skip validation for updates - devices are being managed through associations
 	// Validation is only needed for new packages, not for updates
 	// if err := h.validatePackageDevices(convertUpdateToCreateDevices(req.Devices)); err != nil {
-	// 	log.Printf("Device validation failed: %v", err)
+	// 	logger.Errorf("Device validation failed: %v", err)
 	// 	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	// 	return
 	// }
@@ -328,23 +331,16 @@ func (h *EquipmentPackageHandler) UpdatePackage(c *gin.Context) {
 	pkg.Category = req.Category
 	pkg.Tags = req.Tags
 
-	// Update package
-	if err := h.packageRepo.Update(pkg); err != nil {
-		log.Printf("Package update failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
 	// Update device associations
 	var deviceMappings []models.PackageDevice
-	log.Printf("🔄 Building device mappings for %d devices", len(req.Devices))
+	logger.Debugf("Building device mappings for %d devices", len(req.Devices))
 	for _, deviceReq := range req.Devices {
-		log.Printf("🔄 Adding device mapping: %s (quantity: %d)", deviceReq.DeviceID, deviceReq.Quantity)
+		logger.Debugf("Adding device mapping: %s (quantity: %d)", deviceReq.DeviceID, deviceReq.Quantity)
 		
 		// Validate device exists before adding to mappings
 		_, err := h.deviceRepo.GetByID(deviceReq.DeviceID)
 		if err != nil {
-			log.Printf("❌ Device %s does not exist or is not accessible - skipping", deviceReq.DeviceID)
+			logger.Warnf("Device %s does not exist or is not accessible - skipping", deviceReq.DeviceID)
 			continue
 		}
 		
@@ -358,11 +354,29 @@ func (h *EquipmentPackageHandler) UpdatePackage(c *gin.Context) {
 		})
 	}
 
-	if err := h.packageRepo.UpdateDeviceAssociations(uint(id), deviceMappings); err != nil {
-		log.Printf("Device association update failed: %v", err)
+	// Update package fields and device associations in one transaction
+	err = h.packageRepo.GetDB().WithTransaction(func(tx *repository.Database) error {
+		packageRepo := h.packageRepo.WithTx(tx)
+		if err := packageRepo.Update(pkg); err != nil {
+			logger.Errorf("Package update failed: %v", err)
+			return err
+		}
+		if err := packageRepo.UpdateDeviceAssociations(uint(id), deviceMappings); err != nil {
+			logger.Errorf("Device association update failed: %v", err)
+			return err
+		}
+		return nil
+	})
+	var duplicateErr *repository.DuplicatePackageDeviceError
+	if errors.As(err, &duplicateErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "duplicateDevices": duplicateErr.DeviceIDs})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	invalidateDeviceCaches()
 
 	// Reload package with updated data (without device preload to prevent auto-creation)
 	pkg, _ = h.packageRepo.GetByIDWithoutDevicePreload(uint(id))
@@ -373,23 +387,30 @@ func (h *EquipmentPackageHandler) UpdatePackage(c *gin.Context) {
 
 func (h *EquipmentPackageHandler) DeletePackage(c *gin.Context) {
 	packageID := c.Param("id")
-	log.Printf("DeletePackage called with packageID: %s", packageID)
+	logger.Debugf("DeletePackage called with packageID: %s", packageID)
 	
 	id, err := strconv.ParseUint(packageID, 10, 32)
 	if err != nil {
-		log.Printf("Invalid package ID: %v", err)
+		logger.Debugf("Invalid package ID: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid package ID"})
 		return
 	}
 
-	if err := h.packageRepo.Delete(uint(id)); err != nil {
-		log.Printf("Failed to delete package: %v", err)
+	// Archive instead of deleting so jobs that used the package keep their history
+	if err := h.packageRepo.Archive(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Package not found"})
+			return
+		}
+		logger.Errorf("Failed to archive package: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	log.Printf("Package deleted successfully: %d", id)
-	c.JSON(http.StatusOK, gin.H{"message": "Package deleted successfully"})
+	invalidateDeviceCaches()
+
+	logger.Debugf("Package archived successfully: %d", id)
+	c.JSON(http.StatusOK, gin.H{"message": "Package archived successfully", "archived": true})
 }
 
 // Advanced Features
@@ -438,6 +459,7 @@ func (h *EquipmentPackageHandler) ClonePackage(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	invalidateDeviceCaches()
 
 	h.enrichPackageData(clonedPkg)
 
@@ -555,7 +577,7 @@ func (h *EquipmentPackageHandler) BulkUpdatePackages(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondValidationError(c, err)
 		return
 	}
 
@@ -635,23 +657,23 @@ func (h *EquipmentPackageHandler) enrichPackageData(pkg *models.EquipmentPackage
 }
 
 func (h *EquipmentPackageHandler) validatePackageDevices(devices []models.CreatePackageDeviceRequest) error {
-	log.Printf("🔍 VALIDATION: Starting validation for %d devices", len(devices))
+	logger.Debugf("VALIDATION: Starting validation for %d devices", len(devices))
 	for _, device := range devices {
-		log.Printf("🔍 VALIDATION: Validating device %s", device.DeviceID)
+		logger.Debugf("VALIDATION: Validating device %s", device.DeviceID)
 		// Check if device exists and is available
 		existingDevice, err := h.deviceRepo.GetByID(device.DeviceID)
 		if err != nil {
-			log.Printf("❌ VALIDATION: Device %s not found: %v", device.DeviceID, err)
+			logger.Errorf("VALIDATION: Device %s not found: %v", device.DeviceID, err)
 			return fmt.Errorf("device %s not found", device.DeviceID)
 		}
 
-		log.Printf("✅ VALIDATION: Device %s exists with status: %s", device.DeviceID, existingDevice.Status)
+		logger.Debugf("VALIDATION: Device %s exists with status: %s", device.DeviceID, existingDevice.Status)
 		if existingDevice.Status != "free" && existingDevice.Status != "available" && existingDevice.Status != "ready" {
-			log.Printf("❌ VALIDATION: Device %s is not available (status: %s)", device.DeviceID, existingDevice.Status)
+			logger.Errorf("VALIDATION: Device %s is not available (status: %s)", device.DeviceID, existingDevice.Status)
 			return fmt.Errorf("device %s is not available (status: %s)", device.DeviceID, existingDevice.Status)
 		}
 	}
-	log.Printf("✅ VALIDATION: All devices validated successfully")
+	logger.Debugf("VALIDATION: All devices validated successfully")
 	return nil
 }
 
@@ -690,6 +712,8 @@ func parseFilterParams(c *gin.Context) *models.FilterParams {
 		}
 	}
 
+	params.IncludeArchived = c.Query("includeArchived") == "true"
+
 	if page := c.Query("page"); page != "" {
 		if p, err := strconv.Atoi(page); err == nil && p > 0 {
 			params.Page = p
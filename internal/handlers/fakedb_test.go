@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"go-barcode-webapp/internal/repository"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// fakeQueryFunc answers one SQL statement of a handler test. Queries return
+// their columns and rows; for statements that don't return rows only the
+// error is used. Transactions show up as the statements BEGIN, COMMIT and
+// ROLLBACK.
+type fakeQueryFunc func(query string, args []driver.Value) (columns []string, rows [][]driver.Value, err error)
+
+// newFakeDB opens a GORM MySQL connection whose statements are answered by
+// answer instead of a server
+func newFakeDB(t *testing.T, answer fakeQueryFunc) *repository.Database {
+	t.Helper()
+	sqlDB := sql.OpenDB(fakeConnector{answer: answer})
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		Logger:               gormlogger.Discard,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open fake database: %v", err)
+	}
+	return &repository.Database{DB: db}
+}
+
+type fakeConnector struct {
+	answer fakeQueryFunc
+}
+
+func (f fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{answer: f.answer}, nil
+}
+
+func (f fakeConnector) Driver() driver.Driver { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fake driver connections are opened through fakeConnector")
+}
+
+type fakeConn struct {
+	answer fakeQueryFunc
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	if _, _, err := c.answer("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return fakeTx{conn: c}, nil
+}
+
+// CheckNamedValue passes every argument through unconverted
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	columns, rows, err := c.answer(query, fakeValues(args))
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, _, err := c.answer(query, fakeValues(args)); err != nil {
+		return nil, err
+	}
+	return fakeResult{}, nil
+}
+
+func fakeValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, _, err := s.conn.answer(s.query, args); err != nil {
+		return nil, err
+	}
+	return fakeResult{}, nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	columns, rows, err := s.conn.answer(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+// fakeResult reports one affected row; inserted rows get no generated ID
+type fakeResult struct{}
+
+func (fakeResult) LastInsertId() (int64, error) { return 0, nil }
+func (fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx fakeTx) Commit() error {
+	_, _, err := tx.conn.answer("COMMIT", nil)
+	return err
+}
+
+func (tx fakeTx) Rollback() error {
+	_, _, err := tx.conn.answer("ROLLBACK", nil)
+	return err
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
@@ -3,8 +3,12 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -12,11 +16,12 @@ import (
 )
 
 type FinancialHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	currency models.Currency
 }
 
-func NewFinancialHandler(db *gorm.DB) *FinancialHandler {
-	return &FinancialHandler{db: db}
+func NewFinancialHandler(db *gorm.DB, invoiceConfig *config.InvoiceConfig) *FinancialHandler {
+	return &FinancialHandler{db: db, currency: invoiceConfig.ReportCurrency()}
 }
 
 // ================================================================
@@ -103,6 +108,21 @@ func (h *FinancialHandler) NewTransactionForm(c *gin.Context) {
 	h.db.Find(&jobs)
 	h.db.Find(&customers)
 
+	// Links such as "Record Deposit" on the job page preselect the form
+	prefill := gin.H{
+		"type":   c.Query("type"),
+		"jobID":  c.Query("jobID"),
+		"amount": c.Query("amount"),
+	}
+	if jobID, err := strconv.ParseUint(c.Query("jobID"), 10, 32); err == nil {
+		for _, job := range jobs {
+			if job.JobID == uint(jobID) {
+				prefill["customerID"] = strconv.FormatUint(uint64(job.CustomerID), 10)
+				break
+			}
+		}
+	}
+
 	user, _ := GetCurrentUser(c)
 	c.HTML(http.StatusOK, "transaction_form.html", gin.H{
 		"title":     "New Transaction",
@@ -110,6 +130,7 @@ func (h *FinancialHandler) NewTransactionForm(c *gin.Context) {
 		"jobs":      jobs,
 		"customers": customers,
 		"isEdit":    false,
+		"prefill":   prefill,
 	})
 }
 
@@ -131,6 +152,10 @@ func (h *FinancialHandler) CreateTransaction(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !models.IsValidTransactionType(request.Type) {
+		respondFieldErrors(c, []FieldError{transactionTypeFieldError()})
+		return
+	}
 
 	currentUser, exists := GetCurrentUser(c)
 	if !exists {
@@ -164,7 +189,7 @@ func (h *FinancialHandler) CreateTransaction(c *gin.Context) {
 	}
 
 	if request.Currency == "" {
-		transaction.Currency = "EUR"
+		transaction.Currency = models.DefaultTransactionCurrency
 	}
 
 	if err := h.db.Create(&transaction).Error; err != nil {
@@ -227,14 +252,7 @@ func (h *FinancialHandler) UpdateTransactionStatus(c *gin.Context) {
 	}
 
 	// Validate status
-	validStatuses := map[string]bool{
-		"pending":   true,
-		"completed": true,
-		"failed":    true,
-		"cancelled": true,
-	}
-
-	if !validStatuses[request.Status] {
+	if !models.IsValidTransactionStatus(request.Status) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
 		return
 	}
@@ -260,6 +278,178 @@ func (h *FinancialHandler) UpdateTransactionStatus(c *gin.Context) {
 	})
 }
 
+// transactionTypeFieldError describes an unknown transaction type
+func transactionTypeFieldError() FieldError {
+	return FieldError{Field: "type", Rule: "oneof", Param: "rental deposit payment refund fee discount",
+		Message: "type must be one of rental, deposit, payment, refund, fee, discount"}
+}
+
+// transactionStatusFieldError describes an unknown transaction status
+func transactionStatusFieldError() FieldError {
+	return FieldError{Field: "status", Rule: "oneof", Param: "pending completed failed cancelled",
+		Message: "status must be one of pending, completed, failed, cancelled"}
+}
+
+// JobTransactionRequest records a deposit, payment or other transaction for a job
+type JobTransactionRequest struct {
+	Type            string  `json:"type" binding:"required"`
+	Amount          float64 `json:"amount" binding:"required,gt=0"`
+	Status          string  `json:"status"`
+	Currency        string  `json:"currency" binding:"omitempty,len=3,alpha"`
+	PaymentMethod   string  `json:"paymentMethod" binding:"max=50"`
+	ReferenceNumber string  `json:"referenceNumber" binding:"max=100"`
+	Notes           string  `json:"notes"`
+	TransactionDate string  `json:"transactionDate"`
+	DueDate         string  `json:"dueDate"`
+}
+
+// loadTransactionJob looks up the job of a job-scoped transaction request,
+// answering with 400 or 404 and returning nil if it can't
+func (h *FinancialHandler) loadTransactionJob(c *gin.Context) *models.Job {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid job ID")
+		return nil
+	}
+	var job models.Job
+	if err := h.db.First(&job, jobID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			respondJSONError(c, http.StatusNotFound, errCodeNotFound, "Job not found")
+		} else {
+			respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load job")
+		}
+		return nil
+	}
+	return &job
+}
+
+// CreateJobTransaction records a financial transaction for a job and its
+// customer. Status defaults to pending and currency to EUR.
+func (h *FinancialHandler) CreateJobTransaction(c *gin.Context) {
+	job := h.loadTransactionJob(c)
+	if job == nil {
+		return
+	}
+
+	var request JobTransactionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	var fieldErrors []FieldError
+	if !models.IsValidTransactionType(request.Type) {
+		fieldErrors = append(fieldErrors, transactionTypeFieldError())
+	}
+	if request.Status == "" {
+		request.Status = models.TransactionStatusPending
+	} else if !models.IsValidTransactionStatus(request.Status) {
+		fieldErrors = append(fieldErrors, transactionStatusFieldError())
+	}
+	transactionDate := time.Now()
+	if request.TransactionDate != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", request.TransactionDate, time.Local)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: "transactionDate", Rule: "datetime", Param: "2006-01-02",
+				Message: "transactionDate must be a date in YYYY-MM-DD format"})
+		}
+		transactionDate = parsed
+	}
+	var dueDate *time.Time
+	if request.DueDate != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", request.DueDate, time.Local)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: "dueDate", Rule: "datetime", Param: "2006-01-02",
+				Message: "dueDate must be a date in YYYY-MM-DD format"})
+		}
+		dueDate = &parsed
+	}
+	if len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
+
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		respondJSONError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	currency := strings.ToUpper(request.Currency)
+	if currency == "" {
+		currency = models.DefaultTransactionCurrency
+	}
+	customerID := job.CustomerID
+	now := time.Now()
+	transaction := models.FinancialTransaction{
+		JobID:           &job.JobID,
+		CustomerID:      &customerID,
+		Type:            request.Type,
+		Amount:          request.Amount,
+		Currency:        currency,
+		Status:          request.Status,
+		PaymentMethod:   request.PaymentMethod,
+		TransactionDate: transactionDate,
+		DueDate:         dueDate,
+		ReferenceNumber: request.ReferenceNumber,
+		Notes:           request.Notes,
+		CreatedBy:       &currentUser.UserID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := h.db.Create(&transaction).Error; err != nil {
+		logger.Errorf("CreateJobTransaction: job %d: %v", job.JobID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to create transaction")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Transaction created successfully",
+		"transaction": transaction,
+	})
+}
+
+// ListJobTransactions returns a job's financial transactions, newest first,
+// optionally filtered by type and status
+func (h *FinancialHandler) ListJobTransactions(c *gin.Context) {
+	job := h.loadTransactionJob(c)
+	if job == nil {
+		return
+	}
+
+	query := h.db.Preload("Creator").Where("jobID = ?", job.JobID)
+	var fieldErrors []FieldError
+	if transactionType := c.Query("type"); transactionType != "" {
+		if !models.IsValidTransactionType(transactionType) {
+			fieldErrors = append(fieldErrors, transactionTypeFieldError())
+		}
+		query = query.Where("type = ?", transactionType)
+	}
+	if status := c.Query("status"); status != "" {
+		if !models.IsValidTransactionStatus(status) {
+			fieldErrors = append(fieldErrors, transactionStatusFieldError())
+		}
+		query = query.Where("status = ?", status)
+	}
+	if len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
+
+	transactions := []models.FinancialTransaction{}
+	if err := query.Order("transaction_date DESC, transactionID DESC").Find(&transactions).Error; err != nil {
+		logger.Errorf("ListJobTransactions: job %d: %v", job.JobID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load transactions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobID":        job.JobID,
+		"transactions": transactions,
+		"count":        len(transactions),
+	})
+}
+
 // ================================================================
 // INVOICING
 // ================================================================
@@ -493,6 +683,136 @@ func (h *FinancialHandler) getFinancialStats() (map[string]interface{}, error) {
 	return stats, nil
 }
 
+// JobFinancialSummary is what a customer has been charged and has paid for a job
+type JobFinancialSummary struct {
+	TotalRevenue       float64         `json:"totalRevenue"`
+	Fees               float64         `json:"fees"`
+	Discounts          float64         `json:"discounts"`
+	DepositHeld        float64         `json:"depositHeld"`
+	PaymentsReceived   float64         `json:"paymentsReceived"`
+	PendingPayments    float64         `json:"pendingPayments"`
+	OutstandingBalance float64         `json:"outstandingBalance"` // Negative when the customer has overpaid
+	SuggestedDeposit   float64         `json:"suggestedDeposit"`   // Sum of the deposits its devices' products require
+	DepositOutstanding float64         `json:"depositOutstanding"` // Suggested deposit not yet held
+	Currency           models.Currency `json:"currency"`
+}
+
+// Settled reports whether nothing is left to collect for the job
+func (s JobFinancialSummary) Settled() bool {
+	return s.OutstandingBalance <= 0
+}
+
+// getJobFinancialSummary aggregates a job's transactions. Only completed
+// transactions count towards deposits and payments; refunds are taken from the
+// deposit first and from payments beyond that. Rental transactions charge the
+// job's revenue, which TotalRevenue already holds, so they aren't counted again.
+func getJobFinancialSummary(db *gorm.DB, job *models.Job, currency models.Currency) (*JobFinancialSummary, error) {
+	var totals []struct {
+		Type   string
+		Status string
+		Total  float64
+	}
+	// The table predates the model's naming, so address it explicitly
+	if err := db.Table("financial_transactions").
+		Select("type, status, COALESCE(SUM(amount), 0) as total").
+		Where("jobID = ? AND status IN (?)", job.JobID, []string{"completed", "pending"}).
+		Group("type, status").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &JobFinancialSummary{
+		TotalRevenue: job.Revenue,
+		Currency:     currency,
+	}
+	if job.FinalRevenue != nil {
+		summary.TotalRevenue = *job.FinalRevenue
+	}
+
+	var deposits, refunds float64
+	for _, t := range totals {
+		if t.Status == models.TransactionStatusPending {
+			if t.Type == models.TransactionTypePayment {
+				summary.PendingPayments += t.Total
+			}
+			continue
+		}
+		switch t.Type {
+		case models.TransactionTypePayment:
+			summary.PaymentsReceived += t.Total
+		case models.TransactionTypeDeposit:
+			deposits += t.Total
+		case models.TransactionTypeRefund:
+			refunds += t.Total
+		case models.TransactionTypeFee:
+			summary.Fees += t.Total
+		case models.TransactionTypeDiscount:
+			summary.Discounts += t.Total
+		}
+	}
+
+	summary.DepositHeld = deposits - refunds
+	if summary.DepositHeld < 0 {
+		summary.PaymentsReceived += summary.DepositHeld
+		summary.DepositHeld = 0
+	}
+	summary.OutstandingBalance = summary.TotalRevenue + summary.Fees - summary.Discounts - summary.PaymentsReceived
+
+	suggested, err := getSuggestedDeposit(db, job.JobID)
+	if err != nil {
+		return nil, err
+	}
+	summary.SuggestedDeposit = suggested
+	if suggested > summary.DepositHeld {
+		summary.DepositOutstanding = suggested - summary.DepositHeld
+	}
+
+	summary.TotalRevenue = currency.Round(summary.TotalRevenue)
+	summary.Fees = currency.Round(summary.Fees)
+	summary.Discounts = currency.Round(summary.Discounts)
+	summary.DepositHeld = currency.Round(summary.DepositHeld)
+	summary.PaymentsReceived = currency.Round(summary.PaymentsReceived)
+	summary.PendingPayments = currency.Round(summary.PendingPayments)
+	summary.OutstandingBalance = currency.Round(summary.OutstandingBalance)
+	summary.SuggestedDeposit = currency.Round(summary.SuggestedDeposit)
+	summary.DepositOutstanding = currency.Round(summary.DepositOutstanding)
+	return summary, nil
+}
+
+// getSuggestedDeposit sums the deposits required by the products of a job's
+// devices. Percentage deposits apply to the device's price on the job: its
+// custom price, or its product's day rate for the job's start date.
+func getSuggestedDeposit(db *gorm.DB, jobID uint) (float64, error) {
+	var rows []struct {
+		CustomPrice    *float64
+		DayRate        float64
+		DepositAmount  *float64
+		DepositPercent *float64
+	}
+	if err := db.Raw(`
+		SELECT jd.custom_price, COALESCE(`+seasonalItemCostSQL+`, 0) as day_rate,
+			p.deposit_amount, p.deposit_percent
+		FROM jobdevices jd
+		JOIN jobs j ON j.jobID = jd.jobID
+		JOIN devices d ON d.deviceID = jd.deviceID
+		JOIN products p ON p.productID = d.productID
+		WHERE jd.jobID = ? AND (p.deposit_amount IS NOT NULL OR p.deposit_percent IS NOT NULL)
+	`, jobID).Scan(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for _, row := range rows {
+		price := row.DayRate
+		if row.CustomPrice != nil && *row.CustomPrice > 0 {
+			price = *row.CustomPrice
+		}
+		product := models.Product{DepositAmount: row.DepositAmount, DepositPercent: row.DepositPercent}
+		total += product.DepositFor(price)
+	}
+	return total, nil
+}
+
 func (h *FinancialHandler) generateInvoiceNumber() string {
 	// Simple invoice number generation
 	timestamp := time.Now().Format("200601")
@@ -565,6 +885,202 @@ func (h *FinancialHandler) GetFinancialStatsAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetJobFinancialSummaryAPI returns the deposit, payment and balance summary of a job
+func (h *FinancialHandler) GetJobFinancialSummaryAPI(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	var job models.Job
+	if err := h.db.First(&job, jobID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	summary, err := getJobFinancialSummary(h.db, &job, h.currency)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load job financial summary"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"summary": summary})
+}
+
+// CustomerOpenInvoice is an issued invoice of a customer that still has a balance
+type CustomerOpenInvoice struct {
+	InvoiceID     uint64    `json:"invoiceId"`
+	InvoiceNumber string    `json:"invoiceNumber"`
+	JobID         *uint     `json:"jobId"`
+	Status        string    `json:"status"`
+	IssueDate     time.Time `json:"issueDate"`
+	DueDate       time.Time `json:"dueDate"`
+	TotalAmount   float64   `json:"totalAmount"`
+	BalanceDue    float64   `json:"balanceDue"`
+	Overdue       bool      `json:"overdue"`
+}
+
+// CustomerBalance is what a customer owes from their financial transactions
+type CustomerBalance struct {
+	CustomerID         uint                  `json:"customerId"`
+	Charges            float64               `json:"charges"`            // Pending and completed rental and fee transactions
+	Discounts          float64               `json:"discounts"`          // Pending and completed discounts
+	Payments           float64               `json:"payments"`           // Completed payments
+	Deposits           float64               `json:"deposits"`           // Completed deposits
+	Refunds            float64               `json:"refunds"`            // Completed refunds
+	OutstandingBalance float64               `json:"outstandingBalance"` // Negative when the customer is in credit
+	OpenInvoices       []CustomerOpenInvoice `json:"openInvoices"`
+	OpenInvoiceTotal   float64               `json:"openInvoiceTotal"`
+	Currency           models.Currency       `json:"currency"`
+}
+
+// getCustomerBalance sums a customer's transactions, including those of their
+// jobs, with transaction dates in [from, to] when given. Charges are rental
+// and fee transactions less discounts; completed payments and deposits less
+// completed refunds are set against them.
+func getCustomerBalance(db *gorm.DB, customerID uint, from, to *time.Time, currency models.Currency) (*CustomerBalance, error) {
+	var totals []struct {
+		Type   string
+		Status string
+		Total  float64
+	}
+	query := db.Table("financial_transactions").
+		Select("type, status, COALESCE(SUM(amount), 0) as total").
+		Where("customerID = ? OR jobID IN (SELECT jobID FROM jobs WHERE customerID = ?)", customerID, customerID).
+		Where("status IN ?", []string{models.TransactionStatusPending, models.TransactionStatusCompleted})
+	if from != nil {
+		query = query.Where("transaction_date >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("transaction_date < ?", to.AddDate(0, 0, 1))
+	}
+	if err := query.Group("type, status").Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to sum transactions: %v", err)
+	}
+
+	balance := &CustomerBalance{
+		CustomerID:   customerID,
+		OpenInvoices: []CustomerOpenInvoice{},
+		Currency:     currency,
+	}
+	for _, t := range totals {
+		switch t.Type {
+		case models.TransactionTypeRental, models.TransactionTypeFee:
+			balance.Charges += t.Total
+		case models.TransactionTypeDiscount:
+			balance.Discounts += t.Total
+		}
+		if t.Status != models.TransactionStatusCompleted {
+			continue
+		}
+		switch t.Type {
+		case models.TransactionTypePayment:
+			balance.Payments += t.Total
+		case models.TransactionTypeDeposit:
+			balance.Deposits += t.Total
+		case models.TransactionTypeRefund:
+			balance.Refunds += t.Total
+		}
+	}
+	balance.OutstandingBalance = balance.Charges - balance.Discounts - (balance.Payments + balance.Deposits - balance.Refunds)
+
+	var invoices []models.Invoice
+	invoiceQuery := db.Where("customer_id = ? AND status IN ? AND balance_due > 0", customerID, []string{"sent", "overdue"})
+	if from != nil {
+		invoiceQuery = invoiceQuery.Where("issue_date >= ?", from.Format("2006-01-02"))
+	}
+	if to != nil {
+		invoiceQuery = invoiceQuery.Where("issue_date <= ?", to.Format("2006-01-02"))
+	}
+	if err := invoiceQuery.Order("due_date ASC, invoice_id ASC").Find(&invoices).Error; err != nil {
+		return nil, fmt.Errorf("failed to load open invoices: %v", err)
+	}
+	today, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	for _, invoice := range invoices {
+		balance.OpenInvoices = append(balance.OpenInvoices, CustomerOpenInvoice{
+			InvoiceID:     invoice.InvoiceID,
+			InvoiceNumber: invoice.InvoiceNumber,
+			JobID:         invoice.JobID,
+			Status:        invoice.Status,
+			IssueDate:     invoice.IssueDate,
+			DueDate:       invoice.DueDate,
+			TotalAmount:   invoice.TotalAmount,
+			BalanceDue:    invoice.BalanceDue,
+			Overdue:       invoice.DueDate.Before(today),
+		})
+		balance.OpenInvoiceTotal += invoice.BalanceDue
+	}
+
+	balance.Charges = currency.Round(balance.Charges)
+	balance.Discounts = currency.Round(balance.Discounts)
+	balance.Payments = currency.Round(balance.Payments)
+	balance.Deposits = currency.Round(balance.Deposits)
+	balance.Refunds = currency.Round(balance.Refunds)
+	balance.OutstandingBalance = currency.Round(balance.OutstandingBalance)
+	balance.OpenInvoiceTotal = currency.Round(balance.OpenInvoiceTotal)
+	return balance, nil
+}
+
+// GetCustomerBalanceAPI returns a customer's outstanding balance and open
+// invoices. from and to (YYYY-MM-DD) limit it to transactions and invoices
+// dated in that range. Customers without transactions have a zero balance.
+func (h *FinancialHandler) GetCustomerBalanceAPI(c *gin.Context) {
+	if !userHasPermission(h.db, c, "financial.view") {
+		respondJSONError(c, http.StatusForbidden, errCodeForbidden, "Insufficient permissions")
+		return
+	}
+
+	customerID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid customer ID")
+		return
+	}
+
+	var fieldErrors []FieldError
+	parseDate := func(field string) *time.Time {
+		raw := c.Query(field)
+		if raw == "" {
+			return nil
+		}
+		parsed, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: field, Rule: "datetime", Param: "2006-01-02",
+				Message: field + " must be a date in YYYY-MM-DD format"})
+			return nil
+		}
+		return &parsed
+	}
+	from, to := parseDate("from"), parseDate("to")
+	if from != nil && to != nil && to.Before(*from) {
+		fieldErrors = append(fieldErrors, FieldError{Field: "to", Rule: "gtefield", Param: "from",
+			Message: "to must not be before from"})
+	}
+	if len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
+
+	var customer models.Customer
+	if err := h.db.First(&customer, customerID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			respondJSONError(c, http.StatusNotFound, errCodeNotFound, "Customer not found")
+		} else {
+			respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load customer")
+		}
+		return
+	}
+
+	balance, err := getCustomerBalance(h.db, uint(customerID), from, to, h.currency)
+	if err != nil {
+		logger.Errorf("GetCustomerBalanceAPI: customer %d: %v", customerID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load customer balance")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"balance": balance})
+}
+
 // ================================================================
 // EXPORT FUNCTIONS
 // ================================================================
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"go-barcode-webapp/internal/models"
+)
+
+// billedJobTransactions are the transaction totals of a job billed with a
+// rental transaction over its revenue of 100 and paid in part
+func billedJobTransactions(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	if strings.Contains(query, "FROM `financial_transactions`") {
+		return []string{"type", "status", "total"}, [][]driver.Value{
+			{models.TransactionTypeRental, models.TransactionStatusPending, 100.0},
+			{models.TransactionTypePayment, models.TransactionStatusCompleted, 60.0},
+			{models.TransactionTypePayment, models.TransactionStatusPending, 10.0},
+		}, nil
+	}
+	return nil, nil, nil
+}
+
+func TestRentalTransactionsAreCharges(t *testing.T) {
+	db := newFakeDB(t, billedJobTransactions)
+	currency := models.NewCurrency("EUR", "€", 2)
+
+	summary, err := getJobFinancialSummary(db.DB, &models.Job{JobID: 1, CustomerID: 2, Revenue: 100}, currency)
+	if err != nil {
+		t.Fatalf("getJobFinancialSummary: %v", err)
+	}
+	if summary.PaymentsReceived != 60 || summary.PendingPayments != 10 || summary.OutstandingBalance != 40 {
+		t.Errorf("job summary = received %v, pending %v, outstanding %v; want 60, 10, 40",
+			summary.PaymentsReceived, summary.PendingPayments, summary.OutstandingBalance)
+	}
+
+	balance, err := getCustomerBalance(db.DB, 2, nil, nil, currency)
+	if err != nil {
+		t.Fatalf("getCustomerBalance: %v", err)
+	}
+	if balance.Charges != 100 || balance.OutstandingBalance != summary.OutstandingBalance {
+		t.Errorf("customer balance = charges %v, outstanding %v; want 100, %v",
+			balance.Charges, balance.OutstandingBalance, summary.OutstandingBalance)
+	}
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/models"
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type HolidayHandler struct {
+	holidayRepo *repository.HolidayRepository
+	db          *gorm.DB
+}
+
+func NewHolidayHandler(holidayRepo *repository.HolidayRepository, db *gorm.DB) *HolidayHandler {
+	return &HolidayHandler{
+		holidayRepo: holidayRepo,
+		db:          db,
+	}
+}
+
+// ListHolidays returns the company holidays, optionally limited to ?year=
+func (h *HolidayHandler) ListHolidays(c *gin.Context) {
+	year := 0
+	if value := c.Query("year"); value != "" {
+		var err error
+		if year, err = strconv.Atoi(value); err != nil || year < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year"})
+			return
+		}
+	}
+
+	holidays, err := h.holidayRepo.List(year)
+	if err != nil {
+		logger.Errorf("ListHolidays: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holidays"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"holidays":          holidays,
+		"rentalDayCounting": h.holidayRepo.RentalDayCounting(),
+	})
+}
+
+// CreateHoliday adds a company holiday
+func (h *HolidayHandler) CreateHoliday(c *gin.Context) {
+	if !userHasPermission(h.db, c, "settings.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	holiday := models.CompanyHoliday{}
+	if !h.bindHoliday(c, &holiday) {
+		return
+	}
+
+	if err := h.holidayRepo.Create(&holiday); err != nil {
+		logger.Errorf("CreateHoliday: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create holiday"})
+		return
+	}
+
+	writeAuditLog(h.db, c, "create", "holiday", strconv.FormatUint(uint64(holiday.HolidayID), 10), nil, holiday)
+
+	c.JSON(http.StatusCreated, gin.H{"holiday": holiday})
+}
+
+// UpdateHoliday changes the date or name of a company holiday
+func (h *HolidayHandler) UpdateHoliday(c *gin.Context) {
+	if !userHasPermission(h.db, c, "settings.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	holiday, ok := h.loadHoliday(c)
+	if !ok {
+		return
+	}
+	previous := *holiday
+	if !h.bindHoliday(c, holiday) {
+		return
+	}
+
+	if err := h.holidayRepo.Update(holiday); err != nil {
+		logger.Errorf("UpdateHoliday: holiday %d: %v", holiday.HolidayID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update holiday"})
+		return
+	}
+
+	writeAuditLog(h.db, c, "update", "holiday", c.Param("id"), previous, holiday)
+
+	c.JSON(http.StatusOK, gin.H{"holiday": holiday})
+}
+
+// DeleteHoliday removes a company holiday
+func (h *HolidayHandler) DeleteHoliday(c *gin.Context) {
+	if !userHasPermission(h.db, c, "settings.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	holiday, ok := h.loadHoliday(c)
+	if !ok {
+		return
+	}
+
+	if err := h.holidayRepo.Delete(holiday.HolidayID); err != nil {
+		logger.Errorf("DeleteHoliday: holiday %d: %v", holiday.HolidayID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete holiday"})
+		return
+	}
+
+	writeAuditLog(h.db, c, "delete", "holiday", c.Param("id"), holiday, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Holiday deleted successfully"})
+}
+
+// CountRentalDays returns the rental days from ?start= to ?end= in the
+// configured counting mode, along with the holidays skipped
+func (h *HolidayHandler) CountRentalDays(c *gin.Context) {
+	start, err := time.Parse("2006-01-02", c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format, expected YYYY-MM-DD"})
+		return
+	}
+	end, err := time.Parse("2006-01-02", c.Query("end"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format, expected YYYY-MM-DD"})
+		return
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End date must not be before start date"})
+		return
+	}
+
+	days, err := h.holidayRepo.RentalDays(start, end)
+	if err != nil {
+		logger.Errorf("CountRentalDays: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count rental days"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"start":             start.Format("2006-01-02"),
+		"end":               end.Format("2006-01-02"),
+		"rentalDays":        days,
+		"rentalDayCounting": h.holidayRepo.RentalDayCounting(),
+	})
+}
+
+// bindHoliday validates the request and copies it onto the holiday, writing
+// the error response itself when the request is invalid
+func (h *HolidayHandler) bindHoliday(c *gin.Context, holiday *models.CompanyHoliday) bool {
+	var req models.CompanyHolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondValidationError(c, err)
+		return false
+	}
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		respondFieldErrors(c, []FieldError{{Field: "date", Rule: "date", Param: "YYYY-MM-DD", Message: "date must be a date in YYYY-MM-DD format"}})
+		return false
+	}
+
+	exists, err := h.holidayRepo.DateExists(date, holiday.HolidayID)
+	if err != nil {
+		logger.Errorf("bindHoliday: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check holiday date"})
+		return false
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "A holiday already exists on " + req.Date})
+		return false
+	}
+
+	holiday.HolidayDate = date
+	holiday.Name = req.Name
+	return true
+}
+
+// loadHoliday fetches the holiday named by the :id route parameter and
+// writes the error response itself when it can't
+func (h *HolidayHandler) loadHoliday(c *gin.Context) (*models.CompanyHoliday, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid holiday ID"})
+		return nil, false
+	}
+
+	holiday, err := h.holidayRepo.GetByID(uint(id))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Holiday not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch holiday"})
+		return nil, false
+	}
+
+	return holiday, true
+}
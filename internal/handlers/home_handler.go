@@ -62,11 +62,23 @@ func (h *HomeHandler) Dashboard(c *gin.Context) {
 		Limit: 5,
 	})
 	
+	// Open damage reports, most severe first
+	var openDamageReports int64
+	var damageReports []models.DamageReport
+	h.db.Model(&models.DamageReport{}).Where("status = ?", models.DamageReportOpen).Count(&openDamageReports)
+	h.db.Where("status = ?", models.DamageReportOpen).
+		Preload("Device.Product").
+		Order("FIELD(severity, 'critical', 'major', 'moderate', 'minor'), created_at ASC").
+		Limit(5).
+		Find(&damageReports)
+	stats["OpenDamageReports"] = openDamageReports
+	
 	c.HTML(http.StatusOK, "home.html", gin.H{
 		"title":       "Home",
 		"user":        user,
 		"stats":       stats,
 		"recentJobs":  recentJobs,
+		"damageReports": damageReports,
 		"currentPage": "home",
 	})
 }
\ No newline at end of file
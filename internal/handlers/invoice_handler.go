@@ -1,14 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 	"go-barcode-webapp/internal/services"
@@ -17,13 +18,14 @@ import (
 )
 
 type InvoiceHandlerNew struct {
-	invoiceRepo  *repository.InvoiceRepositoryNew
-	customerRepo *repository.CustomerRepository
-	jobRepo      *repository.JobRepository
-	deviceRepo   *repository.DeviceRepository
-	packageRepo  *repository.EquipmentPackageRepository
-	productRepo  *repository.ProductRepository
-	pdfService   *services.PDFServiceNew
+	invoiceRepo   *repository.InvoiceRepositoryNew
+	customerRepo  *repository.CustomerRepository
+	jobRepo       *repository.JobRepository
+	deviceRepo    *repository.DeviceRepository
+	packageRepo   *repository.EquipmentPackageRepository
+	productRepo   *repository.ProductRepository
+	pdfService    *services.PDFServiceNew
+	recipientRole string // Customer contact role invoice emails go to, e.g. accounting
 }
 
 func NewInvoiceHandlerNew(
@@ -34,15 +36,17 @@ func NewInvoiceHandlerNew(
 	packageRepo *repository.EquipmentPackageRepository,
 	productRepo *repository.ProductRepository,
 	pdfConfig *config.PDFConfig,
+	invoiceConfig *config.InvoiceConfig,
 ) *InvoiceHandlerNew {
 	return &InvoiceHandlerNew{
-		invoiceRepo:  invoiceRepo,
-		customerRepo: customerRepo,
-		jobRepo:      jobRepo,
-		deviceRepo:   deviceRepo,
-		packageRepo:  packageRepo,
-		productRepo:  productRepo,
-		pdfService:   services.NewPDFServiceNew(pdfConfig),
+		invoiceRepo:   invoiceRepo,
+		customerRepo:  customerRepo,
+		jobRepo:       jobRepo,
+		deviceRepo:    deviceRepo,
+		packageRepo:   packageRepo,
+		productRepo:   productRepo,
+		pdfService:    services.NewPDFServiceNew(pdfConfig),
+		recipientRole: invoiceConfig.RecipientContactRole,
 	}
 }
 
@@ -56,7 +60,7 @@ func (h *InvoiceHandlerNew) CreateInvoice(c *gin.Context) {
 
 	var request models.InvoiceCreateRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		log.Printf("CreateInvoice: Validation error: %v", err)
+		logger.Errorf("CreateInvoice: Validation error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid input data",
 			"details": err.Error(),
@@ -66,7 +70,7 @@ func (h *InvoiceHandlerNew) CreateInvoice(c *gin.Context) {
 
 	// Additional validation
 	if err := request.Validate(); err != nil {
-		log.Printf("CreateInvoice: Business validation error: %v", err)
+		logger.Errorf("CreateInvoice: Business validation error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Validation failed",
 			"details": err.Error(),
@@ -77,7 +81,7 @@ func (h *InvoiceHandlerNew) CreateInvoice(c *gin.Context) {
 	// Create invoice
 	invoice, err := h.invoiceRepo.CreateInvoice(&request)
 	if err != nil {
-		log.Printf("CreateInvoice: Database error: %v", err)
+		logger.Errorf("CreateInvoice: Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create invoice",
 			"details": err.Error(),
@@ -93,6 +97,147 @@ func (h *InvoiceHandlerNew) CreateInvoice(c *gin.Context) {
 	})
 }
 
+// CreateInvoiceFromJob creates a draft invoice from a job's assigned devices
+func (h *InvoiceHandlerNew) CreateInvoiceFromJob(c *gin.Context) {
+	_, exists := GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	jobID, err := strconv.ParseUint(c.Param("jobId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	// grouping overrides the line_item_grouping setting for this invoice
+	grouping := c.Query("grouping")
+	if grouping != "" && !models.IsValidLineItemGrouping(grouping) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "grouping must be 'product' or 'device'"})
+		return
+	}
+
+	invoice, err := h.invoiceRepo.CreateFromJob(uint(jobID), time.Now(), grouping)
+	if err != nil {
+		respondJobInvoiceError(c, "CreateInvoiceFromJob", uint(jobID), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":       true,
+		"message":       "Invoice created successfully",
+		"invoiceId":     invoice.InvoiceID,
+		"invoiceNumber": invoice.InvoiceNumber,
+	})
+}
+
+// CreatePartialInvoiceFromJob creates a draft invoice for selected devices of a
+// job over part of its rental period
+func (h *InvoiceHandlerNew) CreatePartialInvoiceFromJob(c *gin.Context) {
+	_, exists := GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	jobID, err := strconv.ParseUint(c.Param("jobId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	var request models.PartialInvoiceRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	var fieldErrors []FieldError
+	periodStart, ok := parseOptionalDate(request.PeriodStart)
+	if !ok {
+		fieldErrors = append(fieldErrors, FieldError{Field: "periodStart", Rule: "date", Param: "2006-01-02", Message: "periodStart must be a date in YYYY-MM-DD format"})
+	}
+	periodEnd, ok := parseOptionalDate(request.PeriodEnd)
+	if !ok {
+		fieldErrors = append(fieldErrors, FieldError{Field: "periodEnd", Rule: "date", Param: "2006-01-02", Message: "periodEnd must be a date in YYYY-MM-DD format"})
+	}
+	if len(fieldErrors) > 0 {
+		respondFieldErrors(c, fieldErrors)
+		return
+	}
+
+	invoice, err := h.invoiceRepo.CreateFromJobDevices(uint(jobID), request.DeviceIDs, periodStart, periodEnd, time.Now(), request.Grouping)
+	if err != nil {
+		respondJobInvoiceError(c, "CreatePartialInvoiceFromJob", uint(jobID), err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success":       true,
+		"message":       "Invoice created successfully",
+		"invoiceId":     invoice.InvoiceID,
+		"invoiceNumber": invoice.InvoiceNumber,
+	})
+}
+
+// GetJobBilledPeriods lists which devices of a job have been invoiced for which days
+func (h *InvoiceHandlerNew) GetJobBilledPeriods(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("jobId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	periods, err := h.invoiceRepo.GetBilledPeriods(uint(jobID))
+	if err != nil {
+		logger.Errorf("GetJobBilledPeriods: job %d: %v", jobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load billed periods"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobId":         jobID,
+		"billedPeriods": periods,
+	})
+}
+
+// respondJobInvoiceError maps errors from creating an invoice from a job to a response
+func respondJobInvoiceError(c *gin.Context, handler string, jobID uint, err error) {
+	var conflictErr *repository.BilledPeriodConflictError
+	if errors.As(err, &conflictErr) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     err.Error(),
+			"conflicts": conflictErr.Conflicts,
+		})
+		return
+	}
+
+	var selectionErr *repository.InvoiceSelectionError
+	if errors.As(err, &selectionErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": selectionErr.Message})
+		return
+	}
+
+	logger.Errorf("%s: job %d: %v", handler, jobID, err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "Failed to create invoice from job",
+		"details": err.Error(),
+	})
+}
+
+// parseOptionalDate parses a YYYY-MM-DD date, treating an empty string as no date
+func parseOptionalDate(value string) (*time.Time, bool) {
+	if strings.TrimSpace(value) == "" {
+		return nil, true
+	}
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(value))
+	if err != nil {
+		return nil, false
+	}
+	return &date, true
+}
+
 // GenerateInvoicePDF generates and downloads a PDF for an invoice
 func (h *InvoiceHandlerNew) GenerateInvoicePDF(c *gin.Context) {
 	invoiceIDStr := c.Param("id")
@@ -105,32 +250,79 @@ func (h *InvoiceHandlerNew) GenerateInvoicePDF(c *gin.Context) {
 	// Get invoice
 	invoice, err := h.invoiceRepo.GetInvoiceByID(invoiceID)
 	if err != nil {
-		log.Printf("GenerateInvoicePDF: Error fetching invoice: %v", err)
+		logger.Errorf("GenerateInvoicePDF: Error fetching invoice: %v", err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
 		return
 	}
 
+	// A final PDF uploaded by finance takes precedence; ?source=generated bypasses it
+	if c.Query("format") != "html" && c.Query("source") != InvoicePDFSourceGenerated {
+		if h.serveUploadedPDF(c, invoice) {
+			return
+		}
+	}
+
 	// Get company settings
 	company, err := h.invoiceRepo.GetCompanySettings()
 	if err != nil {
-		log.Printf("GenerateInvoicePDF: Error fetching company settings: %v", err)
+		logger.Errorf("GenerateInvoicePDF: Error fetching company settings: %v", err)
 		company = &models.CompanySettings{CompanyName: "RentalCore Company"}
 	}
 
 	// Get invoice settings
 	settings, err := h.invoiceRepo.GetAllInvoiceSettings()
 	if err != nil {
-		log.Printf("GenerateInvoicePDF: Error fetching settings: %v", err)
+		logger.Errorf("GenerateInvoicePDF: Error fetching settings: %v", err)
 		settings = &models.InvoiceSettings{CurrencySymbol: "€"}
 	}
 
+	// Raw HTML download, offered when no PDF engine is available
+	if c.Query("format") == "html" {
+		html, err := h.pdfService.GenerateInvoiceHTML(invoice, company, settings)
+		if err != nil {
+			logger.Errorf("GenerateInvoicePDF: Error generating HTML: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invoice HTML"})
+			return
+		}
+		filename := fmt.Sprintf("Invoice_%s.html", strings.ReplaceAll(invoice.InvoiceNumber, "/", "_"))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+		return
+	}
+
 	// Generate PDF
 	pdfBytes, err := h.pdfService.GenerateInvoicePDF(invoice, company, settings)
 	if err != nil {
-		log.Printf("GenerateInvoicePDF: Error generating PDF: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to generate PDF",
-			"details": err.Error(),
+		logger.Errorf("GenerateInvoicePDF: Error generating PDF: %v", err)
+
+		var genErr *services.PDFGenerationError
+		if !errors.As(err, &genErr) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to generate PDF",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		htmlURL := fmt.Sprintf("/invoices/%d/pdf?format=html", invoice.InvoiceID)
+		if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEHTML {
+			c.HTML(http.StatusServiceUnavailable, "error.html", gin.H{
+				"title":         "PDF Unavailable",
+				"error":         "PDF engine unavailable, contact admin. You can download the invoice as HTML and print it from your browser instead.",
+				"fallbackURL":   htmlURL,
+				"fallbackLabel": "Download HTML",
+			})
+			return
+		}
+
+		methods := make([]gin.H, len(genErr.Failures))
+		for i, failure := range genErr.Failures {
+			methods[i] = gin.H{"method": failure.Method, "reason": failure.Err.Error()}
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":           "PDF engine unavailable, contact admin",
+			"methods":         methods,
+			"htmlFallbackUrl": htmlURL,
 		})
 		return
 	}
@@ -143,7 +335,7 @@ func (h *InvoiceHandlerNew) GenerateInvoicePDF(c *gin.Context) {
 
 	// Validate PDF content - ensure it's actually a PDF, not HTML
 	if len(pdfBytes) < 4 || string(pdfBytes[:4]) != "%PDF" {
-		log.Printf("GenerateInvoicePDF: Invalid PDF content returned (not starting with %%PDF)")
+		logger.Debugf("GenerateInvoicePDF: Invalid PDF content returned (not starting with %%PDF)")
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "PDF generation failed - invalid PDF format",
 			"details": "The generated content is not a valid PDF file",
@@ -156,11 +348,86 @@ func (h *InvoiceHandlerNew) GenerateInvoicePDF(c *gin.Context) {
 	c.Header("Content-Type", "application/pdf")
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
 	c.Header("Content-Length", strconv.Itoa(len(pdfBytes)))
+	c.Header("X-Invoice-PDF-Source", InvoicePDFSourceGenerated)
 
 	// Send PDF
 	c.Data(http.StatusOK, "application/pdf", pdfBytes)
 }
 
+// SendInvoiceEmail emails the invoice PDF to the customer's contact with the
+// configured recipient role, falling back to the customer's own address
+func (h *InvoiceHandlerNew) SendInvoiceEmail(c *gin.Context) {
+	db := h.invoiceRepo.GetDB()
+	if !userHasPermission(db, c, "invoices.generate") && !userHasPermission(db, c, "financial.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	invoiceID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+	invoice, err := h.invoiceRepo.GetInvoiceByID(invoiceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	}
+	if invoice.Customer == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invoice has no customer"})
+		return
+	}
+	recipient := h.customerRepo.GetContactEmail(invoice.Customer, h.recipientRole)
+	if recipient == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer has no email address"})
+		return
+	}
+
+	company, err := h.invoiceRepo.GetCompanySettings()
+	if err != nil {
+		logger.Errorf("SendInvoiceEmail: Error fetching company settings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load company settings"})
+		return
+	}
+	settings, err := h.invoiceRepo.GetAllInvoiceSettings()
+	if err != nil {
+		logger.Errorf("SendInvoiceEmail: Error fetching settings: %v", err)
+		settings = &models.InvoiceSettings{CurrencySymbol: "€"}
+	}
+
+	pdfBytes, err := h.invoicePDF(invoice, company, settings)
+	if err != nil {
+		logger.Errorf("SendInvoiceEmail: Error generating PDF for invoice %d: %v", invoice.InvoiceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate PDF"})
+		return
+	}
+
+	emailData := &services.EmailData{
+		Invoice:        invoice,
+		Company:        company,
+		Customer:       invoice.Customer,
+		Settings:       settings,
+		RecipientEmail: recipient,
+	}
+	if err := services.NewEmailServiceFromCompany(company).SendInvoiceEmail(emailData, pdfBytes); err != nil {
+		logger.Errorf("SendInvoiceEmail: Error sending invoice %d to %s: %v", invoice.InvoiceID, recipient, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send invoice email", "details": err.Error()})
+		return
+	}
+
+	if invoice.Status == "draft" {
+		if err := h.invoiceRepo.UpdateInvoiceStatus(invoice.InvoiceID, "sent"); err != nil {
+			logger.Errorf("SendInvoiceEmail: Error marking invoice %d as sent: %v", invoice.InvoiceID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"message":   "Invoice sent successfully",
+		"recipient": recipient,
+	})
+}
+
 // GetInvoicesAPI returns invoices as JSON
 func (h *InvoiceHandlerNew) GetInvoicesAPI(c *gin.Context) {
 	var filter models.InvoiceFilter
@@ -171,7 +438,7 @@ func (h *InvoiceHandlerNew) GetInvoicesAPI(c *gin.Context) {
 
 	invoices, totalCount, err := h.invoiceRepo.GetInvoices(&filter)
 	if err != nil {
-		log.Printf("GetInvoicesAPI: Error: %v", err)
+		logger.Errorf("GetInvoicesAPI: Error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load invoices"})
 		return
 	}
@@ -192,7 +459,7 @@ func (h *InvoiceHandlerNew) GetInvoicesAPI(c *gin.Context) {
 func (h *InvoiceHandlerNew) GetInvoiceStatsAPI(c *gin.Context) {
 	stats, err := h.invoiceRepo.GetInvoiceStats()
 	if err != nil {
-		log.Printf("GetInvoiceStatsAPI: Error: %v", err)
+		logger.Errorf("GetInvoiceStatsAPI: Error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get invoice statistics"})
 		return
 	}
@@ -214,7 +481,7 @@ func (h *InvoiceHandlerNew) ListInvoices(c *gin.Context) {
 	// Parse filter parameters
 	var filter models.InvoiceFilter
 	if err := c.ShouldBindQuery(&filter); err != nil {
-		log.Printf("ListInvoices: Filter binding error: %v", err)
+		logger.Errorf("ListInvoices: Filter binding error: %v", err)
 	}
 
 	// Set default pagination
@@ -228,7 +495,7 @@ func (h *InvoiceHandlerNew) ListInvoices(c *gin.Context) {
 	// Get invoices using new repository
 	invoices, _, err := h.invoiceRepo.GetInvoices(&filter)
 	if err != nil {
-		log.Printf("ListInvoices: Error fetching invoices: %v", err)
+		logger.Errorf("ListInvoices: Error fetching invoices: %v", err)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"error": "Failed to load invoices",
 			"user":  user,
@@ -255,7 +522,7 @@ func (h *InvoiceHandlerNew) NewInvoiceForm(c *gin.Context) {
 	// Get customers for dropdown
 	customers, err := h.customerRepo.List(&models.FilterParams{Limit: 1000})
 	if err != nil {
-		log.Printf("NewInvoiceForm: Error fetching customers: %v", err)
+		logger.Errorf("NewInvoiceForm: Error fetching customers: %v", err)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"error": "Failed to load customers",
 			"user":  user,
@@ -266,21 +533,21 @@ func (h *InvoiceHandlerNew) NewInvoiceForm(c *gin.Context) {
 	// Get jobs for dropdown
 	jobs, err := h.jobRepo.List(&models.FilterParams{Limit: 1000})
 	if err != nil {
-		log.Printf("NewInvoiceForm: Error fetching jobs: %v", err)
+		logger.Errorf("NewInvoiceForm: Error fetching jobs: %v", err)
 		jobs = []models.JobWithDetails{} // Continue with empty jobs list
 	}
 
 	// Get products for dropdown
 	products, err := h.productRepo.List(&models.FilterParams{Limit: 1000})
 	if err != nil {
-		log.Printf("NewInvoiceForm: Error fetching products: %v", err)
+		logger.Errorf("NewInvoiceForm: Error fetching products: %v", err)
 		products = []models.Product{} // Continue with empty products list
 	}
 
 	// Generate a preview invoice number
 	previewInvoiceNumber, err := h.invoiceRepo.GeneratePreviewInvoiceNumber()
 	if err != nil {
-		log.Printf("NewInvoiceForm: Error generating preview invoice number: %v", err)
+		logger.Errorf("NewInvoiceForm: Error generating preview invoice number: %v", err)
 		previewInvoiceNumber = "INV-PREVIEW" // Fallback
 	}
 
@@ -314,7 +581,7 @@ func (h *InvoiceHandlerNew) GetInvoice(c *gin.Context) {
 	// Get invoice using new repository
 	invoice, err := h.invoiceRepo.GetInvoiceByID(invoiceID)
 	if err != nil {
-		log.Printf("GetInvoice: Error fetching invoice: %v", err)
+		logger.Errorf("GetInvoice: Error fetching invoice: %v", err)
 		c.HTML(http.StatusNotFound, "error.html", gin.H{
 			"error": "Invoice not found",
 			"user":  user,
@@ -322,10 +589,16 @@ func (h *InvoiceHandlerNew) GetInvoice(c *gin.Context) {
 		return
 	}
 
+	uploadedPDF, err := h.invoiceRepo.GetUploadedPDF(invoiceID)
+	if err != nil {
+		logger.Errorf("GetInvoice: Error looking up uploaded PDF: %v", err)
+	}
+
 	c.HTML(http.StatusOK, "invoice_detail.html", gin.H{
-		"title":   fmt.Sprintf("Invoice %s", invoice.InvoiceNumber),
-		"invoice": invoice,
-		"user":    user,
+		"title":       fmt.Sprintf("Invoice %s", invoice.InvoiceNumber),
+		"invoice":     invoice,
+		"uploadedPDF": uploadedPDF,
+		"user":        user,
 	})
 }
 
@@ -350,7 +623,7 @@ func (h *InvoiceHandlerNew) EditInvoiceForm(c *gin.Context) {
 	// Get invoice
 	invoice, err := h.invoiceRepo.GetInvoiceByID(invoiceID)
 	if err != nil {
-		log.Printf("EditInvoiceForm: Error fetching invoice: %v", err)
+		logger.Errorf("EditInvoiceForm: Error fetching invoice: %v", err)
 		c.HTML(http.StatusNotFound, "error.html", gin.H{
 			"error": "Invoice not found",
 			"user":  user,
@@ -361,14 +634,14 @@ func (h *InvoiceHandlerNew) EditInvoiceForm(c *gin.Context) {
 	// Get customers for dropdown
 	customers, err := h.customerRepo.List(&models.FilterParams{Limit: 1000})
 	if err != nil {
-		log.Printf("EditInvoiceForm: Error fetching customers: %v", err)
+		logger.Errorf("EditInvoiceForm: Error fetching customers: %v", err)
 		customers = []models.Customer{}
 	}
 
 	// Get jobs for dropdown
 	jobs, err := h.jobRepo.List(&models.FilterParams{Limit: 1000})
 	if err != nil {
-		log.Printf("EditInvoiceForm: Error fetching jobs: %v", err)
+		logger.Errorf("EditInvoiceForm: Error fetching jobs: %v", err)
 		jobs = []models.JobWithDetails{}
 	}
 
@@ -399,7 +672,7 @@ func (h *InvoiceHandlerNew) PreviewInvoice(c *gin.Context) {
 	// Get invoice
 	invoice, err := h.invoiceRepo.GetInvoiceByID(invoiceID)
 	if err != nil {
-		log.Printf("PreviewInvoice: Error fetching invoice: %v", err)
+		logger.Errorf("PreviewInvoice: Error fetching invoice: %v", err)
 		c.HTML(http.StatusNotFound, "error.html", gin.H{
 			"error": "Invoice not found",
 			"user":  user,
@@ -410,14 +683,14 @@ func (h *InvoiceHandlerNew) PreviewInvoice(c *gin.Context) {
 	// Get company settings
 	company, err := h.invoiceRepo.GetCompanySettings()
 	if err != nil {
-		log.Printf("PreviewInvoice: Error fetching company settings: %v", err)
+		logger.Errorf("PreviewInvoice: Error fetching company settings: %v", err)
 		company = &models.CompanySettings{CompanyName: "RentalCore Company"}
 	}
 
 	// Get invoice settings
 	settings, err := h.invoiceRepo.GetAllInvoiceSettings()
 	if err != nil {
-		log.Printf("PreviewInvoice: Error fetching settings: %v", err)
+		logger.Errorf("PreviewInvoice: Error fetching settings: %v", err)
 		settings = &models.InvoiceSettings{CurrencySymbol: "€"}
 	}
 
@@ -441,7 +714,7 @@ func (h *InvoiceHandlerNew) UpdateInvoice(c *gin.Context) {
 
 	var request models.InvoiceCreateRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		log.Printf("UpdateInvoice: Validation error: %v", err)
+		logger.Errorf("UpdateInvoice: Validation error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid input data",
 			"details": err.Error(),
@@ -451,7 +724,7 @@ func (h *InvoiceHandlerNew) UpdateInvoice(c *gin.Context) {
 
 	// Additional validation
 	if err := request.Validate(); err != nil {
-		log.Printf("UpdateInvoice: Business validation error: %v", err)
+		logger.Errorf("UpdateInvoice: Business validation error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Validation failed",
 			"details": err.Error(),
@@ -462,7 +735,7 @@ func (h *InvoiceHandlerNew) UpdateInvoice(c *gin.Context) {
 	// Update invoice using new repository
 	invoice, err := h.invoiceRepo.UpdateInvoice(invoiceID, &request)
 	if err != nil {
-		log.Printf("UpdateInvoice: Database error: %v", err)
+		logger.Errorf("UpdateInvoice: Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update invoice",
 			"details": err.Error(),
@@ -490,7 +763,7 @@ func (h *InvoiceHandlerNew) DeleteInvoice(c *gin.Context) {
 	// Delete invoice using new repository
 	err = h.invoiceRepo.DeleteInvoice(invoiceID)
 	if err != nil {
-		log.Printf("DeleteInvoice: Database error: %v", err)
+		logger.Errorf("DeleteInvoice: Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to delete invoice",
 			"details": err.Error(),
@@ -522,7 +795,7 @@ func (h *InvoiceHandlerNew) GetProductDetails(c *gin.Context) {
 	// Get devices for this product
 	devices, err := h.deviceRepo.GetByProductID(uint(productID))
 	if err != nil {
-		log.Printf("GetProductDetails: Error fetching devices: %v", err)
+		logger.Errorf("GetProductDetails: Error fetching devices: %v", err)
 		devices = []models.Device{} // Continue with empty devices list
 	}
 
@@ -575,7 +848,7 @@ func (h *InvoiceHandlerNew) UpdateInvoiceStatus(c *gin.Context) {
 	// Update status using new repository
 	err = h.invoiceRepo.UpdateInvoiceStatus(invoiceID, request.Status)
 	if err != nil {
-		log.Printf("UpdateInvoiceStatus: Database error: %v", err)
+		logger.Errorf("UpdateInvoiceStatus: Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update invoice status",
 			"details": err.Error(),
@@ -3,11 +3,11 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 
@@ -26,16 +26,16 @@ func NewInvoiceTemplateHandler(invoiceRepo *repository.InvoiceRepositoryNew) *In
 
 // ListTemplates displays all invoice templates
 func (h *InvoiceTemplateHandler) ListTemplates(c *gin.Context) {
-	log.Printf("=== INVOICE TEMPLATE HANDLER CALLED ===")
-	log.Printf("ListTemplates: Handler called for path: %s", c.Request.URL.Path)
-	log.Printf("ListTemplates: Request method: %s", c.Request.Method)
-	log.Printf("ListTemplates: User-Agent: %s", c.Request.Header.Get("User-Agent"))
+	logger.Debugf("=== INVOICE TEMPLATE HANDLER CALLED ===")
+	logger.Debugf("ListTemplates: Handler called for path: %s", c.Request.URL.Path)
+	logger.Debugf("ListTemplates: Request method: %s", c.Request.Method)
+	logger.Debugf("ListTemplates: User-Agent: %s", c.Request.Header.Get("User-Agent"))
 	user, _ := GetCurrentUser(c)
-	log.Printf("ListTemplates: Current user: %+v", user)
+	logger.Debugf("ListTemplates: Current user: %+v", user)
 
 	templates, err := h.invoiceRepo.GetAllTemplates()
 	if err != nil {
-		log.Printf("ListTemplates: Error fetching templates: %v", err)
+		logger.Errorf("ListTemplates: Error fetching templates: %v", err)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"error": "Failed to load templates",
 			"user":  user,
@@ -43,19 +43,19 @@ func (h *InvoiceTemplateHandler) ListTemplates(c *gin.Context) {
 		return
 	}
 
-	log.Printf("ListTemplates: Found %d templates", len(templates))
+	logger.Debugf("ListTemplates: Found %d templates", len(templates))
 	for i, template := range templates {
-		log.Printf("ListTemplates: Template %d: ID=%d, Name=%s, IsActive=%t", i+1, template.TemplateID, template.Name, template.IsActive)
+		logger.Debugf("ListTemplates: Template %d: ID=%d, Name=%s, IsActive=%t", i+1, template.TemplateID, template.Name, template.IsActive)
 	}
 
-	log.Printf("ListTemplates: Rendering template 'invoice_templates_list.html' with %d templates", len(templates))
-	log.Printf("=== ABOUT TO RENDER INVOICE_TEMPLATES_LIST.HTML ===")
+	logger.Debugf("ListTemplates: Rendering template 'invoice_templates_list.html' with %d templates", len(templates))
+	logger.Debugf("=== ABOUT TO RENDER INVOICE_TEMPLATES_LIST.HTML ===")
 	c.HTML(http.StatusOK, "invoice_templates_list.html", gin.H{
 		"title":     "Invoice Templates",
 		"templates": templates,
 		"user":      user,
 	})
-	log.Printf("=== FINISHED RENDERING INVOICE_TEMPLATES_LIST.HTML ===")
+	logger.Debugf("=== FINISHED RENDERING INVOICE_TEMPLATES_LIST.HTML ===")
 }
 
 // NewTemplateForm displays the template designer for creating a new template
@@ -96,7 +96,7 @@ func (h *InvoiceTemplateHandler) EditTemplateForm(c *gin.Context) {
 
 	template, err := h.invoiceRepo.GetTemplateByID(templateID)
 	if err != nil {
-		log.Printf("EditTemplateForm: Error fetching template: %v", err)
+		logger.Errorf("EditTemplateForm: Error fetching template: %v", err)
 		c.HTML(http.StatusNotFound, "error.html", gin.H{
 			"error": "Template not found",
 			"user":  user,
@@ -114,14 +114,14 @@ func (h *InvoiceTemplateHandler) EditTemplateForm(c *gin.Context) {
 
 // CreateTemplate creates a new invoice template
 func (h *InvoiceTemplateHandler) CreateTemplate(c *gin.Context) {
-	log.Printf("CreateTemplate: Handler called")
+	logger.Debugf("CreateTemplate: Handler called")
 	user, exists := GetCurrentUser(c)
 	if !exists {
-		log.Printf("CreateTemplate: User not authenticated")
+		logger.Debugf("CreateTemplate: User not authenticated")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 		return
 	}
-	log.Printf("CreateTemplate: User authenticated: %s", user.Username)
+	logger.Debugf("CreateTemplate: User authenticated: %s", user.Username)
 
 	var request struct {
 		Name         string `json:"name" binding:"required"`
@@ -133,7 +133,7 @@ func (h *InvoiceTemplateHandler) CreateTemplate(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		log.Printf("CreateTemplate: Validation error: %v", err)
+		logger.Errorf("CreateTemplate: Validation error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid input data",
 			"details": err.Error(),
@@ -153,10 +153,10 @@ func (h *InvoiceTemplateHandler) CreateTemplate(c *gin.Context) {
 		UpdatedAt:    time.Now(),
 	}
 
-	log.Printf("CreateTemplate: Attempting to save template: %s", template.Name)
+	logger.Debugf("CreateTemplate: Attempting to save template: %s", template.Name)
 	err := h.invoiceRepo.CreateTemplate(template)
 	if err != nil {
-		log.Printf("CreateTemplate: Database error: %v", err)
+		logger.Errorf("CreateTemplate: Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create template",
 			"details": err.Error(),
@@ -164,7 +164,7 @@ func (h *InvoiceTemplateHandler) CreateTemplate(c *gin.Context) {
 		return
 	}
 
-	log.Printf("CreateTemplate: Template created successfully with ID: %d", template.TemplateID)
+	logger.Debugf("CreateTemplate: Template created successfully with ID: %d", template.TemplateID)
 	c.JSON(http.StatusCreated, gin.H{
 		"success":    true,
 		"message":    "Template created successfully",
@@ -198,7 +198,7 @@ func (h *InvoiceTemplateHandler) UpdateTemplate(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		log.Printf("UpdateTemplate: Validation error: %v", err)
+		logger.Errorf("UpdateTemplate: Validation error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Invalid input data",
 			"details": err.Error(),
@@ -219,7 +219,7 @@ func (h *InvoiceTemplateHandler) UpdateTemplate(c *gin.Context) {
 
 	err = h.invoiceRepo.UpdateTemplate(template)
 	if err != nil {
-		log.Printf("UpdateTemplate: Database error: %v", err)
+		logger.Errorf("UpdateTemplate: Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update template",
 			"details": err.Error(),
@@ -257,7 +257,7 @@ func (h *InvoiceTemplateHandler) DeleteTemplate(c *gin.Context) {
 
 	err = h.invoiceRepo.DeleteTemplate(templateID)
 	if err != nil {
-		log.Printf("DeleteTemplate: Database error: %v", err)
+		logger.Errorf("DeleteTemplate: Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to delete template",
 			"details": err.Error(),
@@ -288,7 +288,7 @@ func (h *InvoiceTemplateHandler) PreviewTemplate(c *gin.Context) {
 
 	template, err := h.invoiceRepo.GetTemplateByID(templateID)
 	if err != nil {
-		log.Printf("PreviewTemplate: Error fetching template: %v", err)
+		logger.Errorf("PreviewTemplate: Error fetching template: %v", err)
 		c.HTML(http.StatusNotFound, "error.html", gin.H{
 			"error": "Template not found",
 			"user":  user,
@@ -299,7 +299,7 @@ func (h *InvoiceTemplateHandler) PreviewTemplate(c *gin.Context) {
 	// Get company settings or use placeholder for preview
 	company, err := h.invoiceRepo.GetCompanySettings()
 	if err != nil {
-		log.Printf("PreviewTemplate: Error fetching company settings: %v", err)
+		logger.Errorf("PreviewTemplate: Error fetching company settings: %v", err)
 		addressLine1 := "[Company Address]"
 		postalCode := "[ZIP]"
 		city := "[City]"
@@ -363,7 +363,7 @@ func (h *InvoiceTemplateHandler) PreviewTemplate(c *gin.Context) {
 	var designSettings map[string]interface{}
 	if template.CSSStyles != nil && *template.CSSStyles != "" {
 		if err := json.Unmarshal([]byte(*template.CSSStyles), &designSettings); err != nil {
-			log.Printf("PreviewTemplate: Error parsing CSS styles: %v", err)
+			logger.Errorf("PreviewTemplate: Error parsing CSS styles: %v", err)
 			designSettings = make(map[string]interface{})
 		}
 	}
@@ -383,7 +383,7 @@ func (h *InvoiceTemplateHandler) PreviewTemplate(c *gin.Context) {
 func (h *InvoiceTemplateHandler) GetTemplatesAPI(c *gin.Context) {
 	templates, err := h.invoiceRepo.GetAllTemplates()
 	if err != nil {
-		log.Printf("GetTemplatesAPI: Error: %v", err)
+		logger.Errorf("GetTemplatesAPI: Error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load templates"})
 		return
 	}
@@ -406,7 +406,7 @@ func (h *InvoiceTemplateHandler) SetDefaultTemplate(c *gin.Context) {
 
 	err = h.invoiceRepo.SetDefaultTemplate(templateID)
 	if err != nil {
-		log.Printf("SetDefaultTemplate: Database error: %v", err)
+		logger.Errorf("SetDefaultTemplate: Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to set default template",
 			"details": err.Error(),
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Where a downloaded invoice PDF came from, reported in the X-Invoice-PDF-Source header
+const (
+	InvoicePDFSourceGenerated = "generated"
+	InvoicePDFSourceUploaded  = "uploaded"
+)
+
+const maxInvoicePDFSize = 10 * 1024 * 1024 // 10MB, same as other documents
+
+// serveUploadedPDF sends the invoice's uploaded final PDF, if it has one. It
+// reports false when the PDF has to be generated instead.
+func (h *InvoiceHandlerNew) serveUploadedPDF(c *gin.Context, invoice *models.Invoice) bool {
+	document, err := h.invoiceRepo.GetUploadedPDF(invoice.InvoiceID)
+	if err != nil {
+		logger.Errorf("GenerateInvoicePDF: Error looking up uploaded PDF of invoice %d: %v", invoice.InvoiceID, err)
+		return false
+	}
+	if document == nil {
+		return false
+	}
+	if _, err := os.Stat(document.FilePath); err != nil {
+		logger.Warnf("GenerateInvoicePDF: Uploaded PDF %d of invoice %d missing on disk, generating instead", document.DocumentID, invoice.InvoiceID)
+		return false
+	}
+
+	filename := fmt.Sprintf("Invoice_%s.pdf", strings.ReplaceAll(invoice.InvoiceNumber, "/", "_"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	c.Header("X-Invoice-PDF-Source", InvoicePDFSourceUploaded)
+	c.Header("Content-Type", "application/pdf")
+	c.File(document.FilePath)
+	return true
+}
+
+// invoicePDF returns the invoice's uploaded final PDF if it has one, otherwise
+// a generated one
+func (h *InvoiceHandlerNew) invoicePDF(invoice *models.Invoice, company *models.CompanySettings, settings *models.InvoiceSettings) ([]byte, error) {
+	document, err := h.invoiceRepo.GetUploadedPDF(invoice.InvoiceID)
+	if err != nil {
+		logger.Errorf("Error looking up uploaded PDF of invoice %d: %v", invoice.InvoiceID, err)
+	} else if document != nil {
+		data, err := os.ReadFile(document.FilePath)
+		if err == nil {
+			return data, nil
+		}
+		logger.Warnf("Uploaded PDF %d of invoice %d unreadable, generating instead: %v", document.DocumentID, invoice.InvoiceID, err)
+	}
+	return h.pdfService.GenerateInvoicePDF(invoice, company, settings)
+}
+
+// UploadInvoicePDF stores a final invoice PDF edited outside RentalCore. From
+// then on the download endpoint serves it instead of generating one.
+func (h *InvoiceHandlerNew) UploadInvoicePDF(c *gin.Context) {
+	db := h.invoiceRepo.GetDB()
+	if !userHasPermission(db, c, "invoices.generate") && !userHasPermission(db, c, "financial.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	invoiceID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+	invoice, err := h.invoiceRepo.GetInvoiceByID(invoiceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxInvoicePDFSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("File size exceeds maximum limit of %d MB", maxInvoicePDFSize/(1024*1024)),
+		})
+		return
+	}
+	content, err := io.ReadAll(io.LimitReader(file, maxInvoicePDFSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	if !bytes.HasPrefix(content, []byte("%PDF")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is not a PDF"})
+		return
+	}
+
+	dir := filepath.Join("uploads", "invoice", strconv.FormatUint(invoiceID, 10))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create directory"})
+		return
+	}
+	filename := fmt.Sprintf("%d_%s.pdf", time.Now().Unix(), strings.ReplaceAll(invoice.InvoiceNumber, "/", "_"))
+	path := filepath.Join(dir, filename)
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return
+	}
+
+	checksum := md5.Sum(content)
+	document := &models.Document{
+		Filename:         filename,
+		OriginalFilename: header.Filename,
+		FilePath:         path,
+		FileSize:         int64(len(content)),
+		MimeType:         "application/pdf",
+		Description:      c.PostForm("description"),
+		UploadedAt:       time.Now(),
+		Checksum:         hex.EncodeToString(checksum[:]),
+	}
+	if user, ok := GetCurrentUser(c); ok {
+		document.UploadedBy = &user.UserID
+	}
+	if err := h.invoiceRepo.AttachUploadedPDF(invoiceID, document); err != nil {
+		os.Remove(path)
+		logger.Errorf("UploadInvoicePDF: invoice %d: %v", invoiceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save document record"})
+		return
+	}
+
+	writeAuditLog(db, c, "upload_pdf", "invoice", c.Param("id"), nil, document)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Invoice PDF uploaded",
+		"document": document,
+		"source":   InvoicePDFSourceUploaded,
+	})
+}
+
+// RemoveUploadedInvoicePDF discards the uploaded PDFs of an invoice so the
+// download endpoint generates the PDF again
+func (h *InvoiceHandlerNew) RemoveUploadedInvoicePDF(c *gin.Context) {
+	db := h.invoiceRepo.GetDB()
+	if !userHasPermission(db, c, "invoices.generate") && !userHasPermission(db, c, "financial.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	invoiceID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	documents, err := h.invoiceRepo.RemoveUploadedPDFs(invoiceID)
+	if err != nil {
+		logger.Errorf("RemoveUploadedInvoicePDF: invoice %d: %v", invoiceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove uploaded PDF"})
+		return
+	}
+	if len(documents) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice has no uploaded PDF"})
+		return
+	}
+	for _, document := range documents {
+		if err := os.Remove(document.FilePath); err != nil && !os.IsNotExist(err) {
+			logger.Warnf("RemoveUploadedInvoicePDF: failed to delete %s: %v", document.FilePath, err)
+		}
+	}
+
+	writeAuditLog(db, c, "remove_uploaded_pdf", "invoice", c.Param("id"), documents, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Uploaded PDF removed, the invoice PDF will be generated again",
+		"source":  InvoicePDFSourceGenerated,
+	})
+}
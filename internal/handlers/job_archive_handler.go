@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type JobArchiveHandler struct {
+	archiveRepo *repository.JobArchiveRepository
+	db          *gorm.DB
+	config      *config.JobsConfig
+}
+
+func NewJobArchiveHandler(archiveRepo *repository.JobArchiveRepository, db *gorm.DB, cfg *config.JobsConfig) *JobArchiveHandler {
+	return &JobArchiveHandler{
+		archiveRepo: archiveRepo,
+		db:          db,
+		config:      cfg,
+	}
+}
+
+// ArchiveJobsRequest optionally overrides the configured retention period
+type ArchiveJobsRequest struct {
+	OlderThanYears int `json:"olderThanYears"`
+}
+
+// ArchiveJobs moves completed jobs older than the retention period into the archive
+func (h *JobArchiveHandler) ArchiveJobs(c *gin.Context) {
+	if !userHasPermission(h.db, c, "settings.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	var request ArchiveJobsRequest
+	if err := c.ShouldBindJSON(&request); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	years := h.config.ArchiveAfterYears
+	if request.OlderThanYears != 0 {
+		years = request.OlderThanYears
+	}
+	if years <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Job archiving is disabled; set archive_after_years or pass olderThanYears"})
+		return
+	}
+
+	var archivedBy *uint
+	if user, ok := GetCurrentUser(c); ok {
+		archivedBy = &user.UserID
+	}
+	result, err := h.archiveRepo.ArchiveCompletedJobs(time.Now().AddDate(-years, 0, 0), archivedBy)
+	if err != nil {
+		logger.Errorf("ArchiveJobs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to archive jobs"})
+		return
+	}
+
+	writeAuditLog(h.db, c, "archive", "job", "", nil, result)
+
+	c.JSON(http.StatusOK, gin.H{
+		"olderThanYears": years,
+		"result":         result,
+	})
+}
+
+// ListArchivedJobs returns archived jobs, most recently ended first
+func (h *JobArchiveHandler) ListArchivedJobs(c *gin.Context) {
+	if !userHasPermission(h.db, c, "settings.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	archives, total, err := h.archiveRepo.List(limit, offset)
+	if err != nil {
+		logger.Errorf("ListArchivedJobs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch archived jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":   archives,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// RestoreArchivedJob moves an archived job back into the operational tables
+func (h *JobArchiveHandler) RestoreArchivedJob(c *gin.Context) {
+	if !userHasPermission(h.db, c, "settings.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, missingDevices, err := h.archiveRepo.Restore(uint(jobID))
+	if err == gorm.ErrRecordNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Archived job not found"})
+		return
+	}
+	if err != nil {
+		logger.Errorf("RestoreArchivedJob: job %d: %v", jobID, err)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if len(missingDevices) > 0 {
+		logger.Warnf("RestoreArchivedJob: job %d restored without deleted devices %v", jobID, missingDevices)
+	}
+
+	writeAuditLog(h.db, c, "restore", "job", c.Param("id"), nil, job)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Job restored",
+		"job":            job,
+		"missingDevices": missingDevices,
+	})
+}
+
+// StartArchiveSchedule archives jobs past the retention period once a day
+// while archiving is enabled
+func (h *JobArchiveHandler) StartArchiveSchedule() {
+	if h.config.ArchiveAfterYears <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			result, err := h.archiveRepo.ArchiveCompletedJobs(time.Now().AddDate(-h.config.ArchiveAfterYears, 0, 0), nil)
+			if err != nil {
+				logger.Errorf("Scheduled job archiving failed: %v", err)
+				continue
+			}
+			if len(result.Archived) > 0 || len(result.Skipped) > 0 {
+				logger.Infof("Archived %d completed jobs, skipped %d", len(result.Archived), len(result.Skipped))
+			}
+		}
+	}()
+}
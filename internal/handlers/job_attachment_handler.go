@@ -3,10 +3,10 @@ package handlers
 import (
 	"crypto/md5"
 	"fmt"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 	"io"
-	"log"
 	"mime"
 	"net/http"
 	"os"
@@ -32,7 +32,7 @@ func NewJobAttachmentHandler(repo *repository.JobAttachmentRepository, jobRepo *
 
 	// Create upload directory if it doesn't exist
 	if err := os.MkdirAll(uploadPath, 0755); err != nil {
-		log.Printf("Error creating upload directory: %v", err)
+		logger.Errorf("Error creating upload directory: %v", err)
 	}
 
 	return &JobAttachmentHandler{
@@ -60,7 +60,7 @@ func (h *JobAttachmentHandler) UploadAttachment(c *gin.Context) {
 	// Verify job exists
 	_, err = h.jobRepo.GetByID(uint(jobID))
 	if err != nil {
-		log.Printf("Job not found for ID %d: %v", jobID, err)
+		logger.Debugf("Job not found for ID %d: %v", jobID, err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
 		return
 	}
@@ -68,7 +68,7 @@ func (h *JobAttachmentHandler) UploadAttachment(c *gin.Context) {
 	// Get uploaded file
 	file, fileHeader, err := c.Request.FormFile("file")
 	if err != nil {
-		log.Printf("Error getting uploaded file: %v", err)
+		logger.Errorf("Error getting uploaded file: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
 		return
 	}
@@ -98,7 +98,7 @@ func (h *JobAttachmentHandler) UploadAttachment(c *gin.Context) {
 	// Create destination file
 	dst, err := os.Create(fullPath)
 	if err != nil {
-		log.Printf("Error creating destination file: %v", err)
+		logger.Errorf("Error creating destination file: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
 		return
 	}
@@ -107,7 +107,7 @@ func (h *JobAttachmentHandler) UploadAttachment(c *gin.Context) {
 	// Copy file content
 	fileSize, err := io.Copy(dst, file)
 	if err != nil {
-		log.Printf("Error copying file content: %v", err)
+		logger.Errorf("Error copying file content: %v", err)
 		// Clean up created file
 		os.Remove(fullPath)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
@@ -139,14 +139,14 @@ func (h *JobAttachmentHandler) UploadAttachment(c *gin.Context) {
 
 	err = h.repo.Create(attachment)
 	if err != nil {
-		log.Printf("Error saving attachment to database: %v", err)
+		logger.Errorf("Error saving attachment to database: %v", err)
 		// Clean up created file
 		os.Remove(fullPath)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save attachment"})
 		return
 	}
 
-	log.Printf("✅ Successfully uploaded attachment %s for job %d", originalFilename, jobID)
+	logger.Debugf("Successfully uploaded attachment %s for job %d", originalFilename, jobID)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message":      "File uploaded successfully",
@@ -166,7 +166,7 @@ func (h *JobAttachmentHandler) GetJobAttachments(c *gin.Context) {
 
 	attachments, err := h.repo.GetByJobID(uint(jobID))
 	if err != nil {
-		log.Printf("Error getting attachments for job %d: %v", jobID, err)
+		logger.Errorf("Error getting attachments for job %d: %v", jobID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get attachments"})
 		return
 	}
@@ -206,14 +206,14 @@ func (h *JobAttachmentHandler) ViewAttachment(c *gin.Context) {
 
 	attachment, err := h.repo.GetByID(uint(attachmentID))
 	if err != nil {
-		log.Printf("Attachment not found for ID %d: %v", attachmentID, err)
+		logger.Debugf("Attachment not found for ID %d: %v", attachmentID, err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
 		return
 	}
 
 	// Check if file exists
 	if _, err := os.Stat(attachment.FilePath); os.IsNotExist(err) {
-		log.Printf("File not found on disk: %s", attachment.FilePath)
+		logger.Debugf("File not found on disk: %s", attachment.FilePath)
 		c.JSON(http.StatusNotFound, gin.H{"error": "File not found on disk"})
 		return
 	}
@@ -246,14 +246,14 @@ func (h *JobAttachmentHandler) DownloadAttachment(c *gin.Context) {
 
 	attachment, err := h.repo.GetByID(uint(attachmentID))
 	if err != nil {
-		log.Printf("Attachment not found for ID %d: %v", attachmentID, err)
+		logger.Debugf("Attachment not found for ID %d: %v", attachmentID, err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
 		return
 	}
 
 	// Check if file exists
 	if _, err := os.Stat(attachment.FilePath); os.IsNotExist(err) {
-		log.Printf("File not found on disk: %s", attachment.FilePath)
+		logger.Debugf("File not found on disk: %s", attachment.FilePath)
 		c.JSON(http.StatusNotFound, gin.H{"error": "File not found on disk"})
 		return
 	}
@@ -281,7 +281,7 @@ func (h *JobAttachmentHandler) DeleteAttachment(c *gin.Context) {
 	// Get attachment to verify it exists
 	attachment, err := h.repo.GetByID(uint(attachmentID))
 	if err != nil {
-		log.Printf("Attachment not found for ID %d: %v", attachmentID, err)
+		logger.Debugf("Attachment not found for ID %d: %v", attachmentID, err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
 		return
 	}
@@ -289,12 +289,12 @@ func (h *JobAttachmentHandler) DeleteAttachment(c *gin.Context) {
 	// Soft delete (set is_active to false)
 	err = h.repo.Delete(uint(attachmentID))
 	if err != nil {
-		log.Printf("Error deleting attachment %d: %v", attachmentID, err)
+		logger.Errorf("Error deleting attachment %d: %v", attachmentID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete attachment"})
 		return
 	}
 
-	log.Printf("✅ Successfully deleted attachment %s (ID: %d)", attachment.OriginalFilename, attachmentID)
+	logger.Debugf("Successfully deleted attachment %s (ID: %d)", attachment.OriginalFilename, attachmentID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
 }
@@ -319,7 +319,7 @@ func (h *JobAttachmentHandler) UpdateAttachmentDescription(c *gin.Context) {
 
 	attachment, err := h.repo.GetByID(uint(attachmentID))
 	if err != nil {
-		log.Printf("Attachment not found for ID %d: %v", attachmentID, err)
+		logger.Debugf("Attachment not found for ID %d: %v", attachmentID, err)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
 		return
 	}
@@ -327,12 +327,12 @@ func (h *JobAttachmentHandler) UpdateAttachmentDescription(c *gin.Context) {
 	attachment.Description = req.Description
 	err = h.repo.Update(attachment)
 	if err != nil {
-		log.Printf("Error updating attachment description for ID %d: %v", attachmentID, err)
+		logger.Errorf("Error updating attachment description for ID %d: %v", attachmentID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update attachment"})
 		return
 	}
 
-	log.Printf("✅ Successfully updated description for attachment %d", attachmentID)
+	logger.Debugf("Successfully updated description for attachment %d", attachmentID)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Description updated successfully"})
 }
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/models"
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRentalDurationErrorReportsCategoryMaximum(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The job's category allows 14 days, less than the configured 30
+	db := newFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		if strings.Contains(query, "FROM `jobCategory`") {
+			return []string{"jobcategoryID", "max_rental_days"}, [][]driver.Value{{int64(2), int64(14)}}, nil
+		}
+		return nil, nil, nil
+	})
+	jobRepo := repository.NewJobRepository(db, &config.JobsConfig{MaxRentalDays: 30})
+
+	categoryID := uint(2)
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 19)
+	job := &models.Job{JobCategoryID: &categoryID, StartDate: &start, EndDate: &end}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/jobs", nil)
+
+	err := checkRentalDuration(c, jobRepo, job, 0)
+	if err == nil {
+		t.Fatal("checkRentalDuration accepted a 20-day job in a 14-day category")
+	}
+	respondRentalDurationError(c, err)
+
+	var response struct {
+		Fields []FieldError `json:"fields"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+	}
+	if w.Code != http.StatusBadRequest || len(response.Fields) != 1 {
+		t.Fatalf("response = %d %s; want one field error", w.Code, w.Body.String())
+	}
+	if field := response.Fields[0]; field.Field != "endDate" || field.Rule != "max_duration" || field.Param != "14" {
+		t.Errorf("field error = %+v; want endDate max_duration 14", field)
+	}
+}
@@ -1,17 +1,21 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type JobHandler struct {
@@ -20,15 +24,17 @@ type JobHandler struct {
 	customerRepo    *repository.CustomerRepository
 	statusRepo      *repository.StatusRepository
 	jobCategoryRepo *repository.JobCategoryRepository
+	currency        models.Currency
 }
 
-func NewJobHandler(jobRepo *repository.JobRepository, deviceRepo *repository.DeviceRepository, customerRepo *repository.CustomerRepository, statusRepo *repository.StatusRepository, jobCategoryRepo *repository.JobCategoryRepository) *JobHandler {
+func NewJobHandler(jobRepo *repository.JobRepository, deviceRepo *repository.DeviceRepository, customerRepo *repository.CustomerRepository, statusRepo *repository.StatusRepository, jobCategoryRepo *repository.JobCategoryRepository, invoiceConfig *config.InvoiceConfig) *JobHandler {
 	return &JobHandler{
 		jobRepo:         jobRepo,
 		deviceRepo:      deviceRepo,
 		customerRepo:    customerRepo,
 		statusRepo:      statusRepo,
 		jobCategoryRepo: jobCategoryRepo,
+		currency:        invoiceConfig.ReportCurrency(),
 	}
 }
 
@@ -43,18 +49,18 @@ func (h *JobHandler) ListJobs(c *gin.Context) {
 	}
 
 	// DEBUG: Log all query parameters
-	fmt.Printf("DEBUG Job Handler: All query params: %+v\n", c.Request.URL.Query())
+	logger.Debugf("Job Handler: All query params: %+v", c.Request.URL.Query())
 	
 	// Manual parameter extraction to ensure search works
 	searchParam := c.Query("search")
-	fmt.Printf("DEBUG Job Handler: Raw search parameter: '%s'\n", searchParam)
+	logger.Debugf("Job Handler: Raw search parameter: '%s'", searchParam)
 	if searchParam != "" {
 		params.SearchTerm = searchParam
-		fmt.Printf("DEBUG Job Handler: Search parameter SET to: '%s'\n", searchParam)
+		logger.Debugf("Job Handler: Search parameter SET to: '%s'", searchParam)
 	}
 	
 	// DEBUG: Log params after binding
-	fmt.Printf("DEBUG Job Handler: Final params: SearchTerm='%s', StartDate=%v, EndDate=%v\n", params.SearchTerm, params.StartDate, params.EndDate)
+	logger.Debugf("Job Handler: Final params: SearchTerm='%s', StartDate=%v, EndDate=%v", params.SearchTerm, params.StartDate, params.EndDate)
 
 	// For /scan page, only show open jobs - for /jobs page, show all
 	// Check if this is called from scan page
@@ -65,15 +71,15 @@ func (h *JobHandler) ListJobs(c *gin.Context) {
 	jobs, err := h.jobRepo.List(params)
 	if err != nil {
 		// Log the error for debugging
-		fmt.Printf("DEBUG: Error loading jobs: %v\n", err)
+		logger.Errorf("Error loading jobs: %v", err)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": err.Error(), "user": user})
 		return
 	}
 
 	// Debug: Log how many jobs were found
-	fmt.Printf("DEBUG: Found %d jobs with search term '%s'\n", len(jobs), params.SearchTerm)
+	logger.Debugf("Found %d jobs with search term '%s'", len(jobs), params.SearchTerm)
 	if len(jobs) > 0 {
-		fmt.Printf("DEBUG: First job: %+v\n", jobs[0])
+		logger.Debugf("First job: %+v", jobs[0])
 	}
 
 	c.HTML(http.StatusOK, "jobs.html", gin.H{
@@ -208,9 +214,21 @@ func (h *JobHandler) CreateJob(c *gin.Context) {
 	}
 
 	description := c.PostForm("description")
-	discountType := c.PostForm("discount_type")
-	if discountType == "" {
-		discountType = "amount" // default
+	discountType, err := h.jobRepo.NormalizeDiscountType(c.PostForm("discount_type"))
+	if err != nil {
+		user, _ := GetCurrentUser(c)
+		customers, _ := h.customerRepo.List(&models.FilterParams{})
+		statuses, _ := h.statusRepo.List()
+		jobCategories, _ := h.jobCategoryRepo.List()
+		c.HTML(http.StatusBadRequest, "job_form.html", gin.H{
+			"title":        "New Job",
+			"customers":    customers,
+			"statuses":     statuses,
+			"jobCategories": jobCategories,
+			"error":        err.Error(),
+			"user":         user,
+		})
+		return
 	}
 	
 	job := models.Job{
@@ -241,6 +259,23 @@ func (h *JobHandler) CreateJob(c *gin.Context) {
 		}
 	}
 
+	if err := checkRentalDuration(c, h.jobRepo, &job, 0); err != nil {
+		user, _ := GetCurrentUser(c)
+		customers, _ := h.customerRepo.List(&models.FilterParams{})
+		statuses, _ := h.statusRepo.List()
+		jobCategories, _ := h.jobCategoryRepo.List()
+		c.HTML(http.StatusBadRequest, "job_form.html", gin.H{
+			"title":        "New Job",
+			"job":          &job,
+			"customers":    customers,
+			"statuses":     statuses,
+			"jobCategories": jobCategories,
+			"error":        err.Error(),
+			"user":         user,
+		})
+		return
+	}
+
 	if err := h.jobRepo.Create(&job); err != nil {
 		user, _ := GetCurrentUser(c)
 		customers, _ := h.customerRepo.List(&models.FilterParams{})
@@ -257,6 +292,7 @@ func (h *JobHandler) CreateJob(c *gin.Context) {
 		})
 		return
 	}
+	writeAuditLog(h.jobRepo.GetDB().DB, c, "create", "job", strconv.FormatUint(uint64(job.JobID), 10), nil, job)
 
 	c.Redirect(http.StatusFound, "/jobs")
 }
@@ -318,14 +354,20 @@ func (h *JobHandler) GetJob(c *gin.Context) {
 		totalValue += effectivePrice
 	}
 
+	financialSummary, err := getJobFinancialSummary(h.jobRepo.GetDB().DB, job, h.currency)
+	if err != nil {
+		logger.Warnf("GetJob: failed to load financial summary for job %d: %v", job.JobID, err)
+	}
+
 	c.HTML(http.StatusOK, "job_detail.html", gin.H{
-		"title":         "Job Details",
-		"job":           job,
-		"jobDevices":    jobDevices,
-		"productGroups": productGroups,
-		"totalDevices":  totalDevices,
-		"totalValue":    totalValue,
-		"user":          user,
+		"title":            "Job Details",
+		"job":              job,
+		"jobDevices":       jobDevices,
+		"productGroups":    productGroups,
+		"totalDevices":     totalDevices,
+		"totalValue":       totalValue,
+		"financialSummary": financialSummary,
+		"user":             user,
 	})
 }
 
@@ -392,6 +434,9 @@ func (h *JobHandler) UpdateJob(c *gin.Context) {
 	}
 
 
+	previousDays := job.RentalDays()
+	previousStatusID := job.StatusID
+
 	// Update fields from form
 	customerID, _ := strconv.ParseUint(c.PostForm("customer_id"), 10, 32)
 	statusID, _ := strconv.ParseUint(c.PostForm("status_id"), 10, 32)
@@ -476,9 +521,22 @@ func (h *JobHandler) UpdateJob(c *gin.Context) {
 	description := c.PostForm("description")
 	job.Description = &description
 	
-	discountType := c.PostForm("discount_type")
-	if discountType == "" {
-		discountType = "amount" // default
+	discountType, err := h.jobRepo.NormalizeDiscountType(c.PostForm("discount_type"))
+	if err != nil {
+		user, _ := GetCurrentUser(c)
+		customers, _ := h.customerRepo.List(&models.FilterParams{})
+		statuses, _ := h.statusRepo.List()
+		jobCategories, _ := h.jobCategoryRepo.List()
+		c.HTML(http.StatusBadRequest, "job_form.html", gin.H{
+			"title":        "Edit Job",
+			"job":          job,
+			"customers":    customers,
+			"statuses":     statuses,
+			"jobCategories": jobCategories,
+			"error":        err.Error(),
+			"user":         user,
+		})
+		return
 	}
 	job.DiscountType = discountType
 
@@ -501,11 +559,11 @@ func (h *JobHandler) UpdateJob(c *gin.Context) {
 		}
 	}
 
-	if err := h.jobRepo.Update(job); err != nil {
+	if err := checkRentalDuration(c, h.jobRepo, job, previousDays); err != nil {
 		customers, _ := h.customerRepo.List(&models.FilterParams{})
 		statuses, _ := h.statusRepo.List()
 		jobCategories, _ := h.jobCategoryRepo.List()
-		c.HTML(http.StatusInternalServerError, "job_form.html", gin.H{
+		c.HTML(http.StatusBadRequest, "job_form.html", gin.H{
 			"title":        "Edit Job",
 			"job":          job,
 			"customers":    customers,
@@ -517,18 +575,115 @@ func (h *JobHandler) UpdateJob(c *gin.Context) {
 		return
 	}
 
-	// Only recalculate revenue automatically if no manual revenue was provided
-	// This preserves manual revenue entries while still updating when dates change
-	if c.PostForm("revenue") == "" {
-		h.jobRepo.CalculateAndUpdateRevenue(uint(id))
-	} else {
-		// If manual revenue was provided, still calculate final_revenue based on discount
-		h.jobRepo.UpdateFinalRevenue(uint(id))
+	db := h.jobRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Job{}, "jobID", id)
+
+	// Save the job and recalculate its revenue atomically
+	err = h.jobRepo.GetDB().WithTransaction(func(tx *repository.Database) error {
+		jobRepo := h.jobRepo.WithTx(tx)
+		if err := jobRepo.Update(job); err != nil {
+			return err
+		}
+
+		// Only recalculate revenue automatically if no manual revenue was provided
+		// This preserves manual revenue entries while still updating when dates change
+		if c.PostForm("revenue") == "" {
+			if err := jobRepo.CalculateAndUpdateRevenue(uint(id)); err != nil {
+				return err
+			}
+		} else if err := jobRepo.UpdateFinalRevenue(uint(id)); err != nil {
+			// If manual revenue was provided, still calculate final_revenue based on discount
+			return err
+		}
+		return recordCompletionRevenue(c, jobRepo, uint(id), previousStatusID, job.StatusID)
+	})
+	if err != nil {
+		customers, _ := h.customerRepo.List(&models.FilterParams{})
+		statuses, _ := h.statusRepo.List()
+		jobCategories, _ := h.jobCategoryRepo.List()
+		c.HTML(http.StatusInternalServerError, "job_form.html", gin.H{
+			"title":        "Edit Job",
+			"job":          job,
+			"customers":    customers,
+			"statuses":     statuses,
+			"jobCategories": jobCategories,
+			"error":        err.Error(),
+			"user":         user,
+		})
+		return
 	}
+	writeAuditLog(db, c, "update", "job", strconv.FormatUint(id, 10), previous, auditSnapshot(db, &models.Job{}, "jobID", id))
 
 	c.Redirect(http.StatusFound, fmt.Sprintf("/jobs/%d", id))
 }
 
+// recordCompletionRevenue books the job's rental revenue in the financial
+// ledger when an update moves it into a completed status
+func recordCompletionRevenue(c *gin.Context, jobRepo *repository.JobRepository, jobID, previousStatusID, statusID uint) error {
+	if repository.IsCompletedJobStatus(previousStatusID) || !repository.IsCompletedJobStatus(statusID) {
+		return nil
+	}
+	var createdBy *uint
+	if user, ok := GetCurrentUser(c); ok {
+		createdBy = &user.UserID
+	}
+	created, err := jobRepo.RecordCompletionRevenue(jobID, createdBy)
+	if err != nil {
+		return err
+	}
+	if created {
+		logger.Infof("Job %d completed: rental transaction recorded", jobID)
+	}
+	return nil
+}
+
+// checkRentalDuration rejects a rental period longer than the maximum for the
+// job's category unless the user may override the limit. Jobs that already
+// ran longer than the limit are only checked when their period grows beyond
+// previousDays, so unrelated edits to them still go through.
+func checkRentalDuration(c *gin.Context, jobRepo *repository.JobRepository, job *models.Job, previousDays int) error {
+	err := jobRepo.CheckRentalDuration(job)
+	var durationErr *repository.RentalDurationError
+	if !errors.As(err, &durationErr) {
+		return err
+	}
+	if previousDays > 0 && durationErr.Days <= previousDays {
+		return nil
+	}
+	if userHasPermission(jobRepo.GetDB().DB, c, "jobs.override_duration") {
+		logger.Warnf("Job %d: %v (overridden)", job.JobID, durationErr)
+		return nil
+	}
+	return fmt.Errorf("%w; the jobs.override_duration permission is required to exceed it", durationErr)
+}
+
+// respondRentalDurationError reports a rental period longer than its maximum
+// as a field error on endDate carrying the maximum that applied
+func respondRentalDurationError(c *gin.Context, err error) {
+	var durationErr *repository.RentalDurationError
+	if !errors.As(err, &durationErr) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondFieldErrors(c, []FieldError{{Field: "endDate", Rule: "max_duration", Param: strconv.Itoa(durationErr.MaxDays), Message: err.Error()}})
+}
+
+// GetDurationViolationsAPI reports existing jobs whose rental period exceeds
+// the maximum rental duration, e.g. from a mistyped year
+func (h *JobHandler) GetDurationViolationsAPI(c *gin.Context) {
+	violations, err := h.jobRepo.GetJobsExceedingMaxDuration()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":          violations,
+		"count":         len(violations),
+		"maxRentalDays": h.jobRepo.MaxRentalDays(),
+	})
+}
+
 func (h *JobHandler) DeleteJob(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -536,10 +691,13 @@ func (h *JobHandler) DeleteJob(c *gin.Context) {
 		return
 	}
 
+	db := h.jobRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Job{}, "jobID", id)
 	if err := h.jobRepo.Delete(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	writeAuditLog(db, c, "delete", "job", strconv.FormatUint(id, 10), previous, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Job deleted successfully"})
 }
@@ -558,7 +716,7 @@ func (h *JobHandler) GetJobDevices(c *gin.Context) {
 	}
 
 	// Debug logging for device pricing
-	fmt.Printf("🔧 DEBUG GetJobDevices: Job %d has %d devices\n", id, len(jobDevices))
+	logger.Debugf("GetJobDevices: Job %d has %d devices", id, len(jobDevices))
 	for i, device := range jobDevices {
 		customPriceVal := "nil"
 		if device.CustomPrice != nil {
@@ -570,7 +728,7 @@ func (h *JobHandler) GetJobDevices(c *gin.Context) {
 			productPriceVal = fmt.Sprintf("%.2f", *device.Device.Product.ItemCostPerDay)
 		}
 		
-		fmt.Printf("🔧 DEBUG GetJobDevices[%d]: DeviceID=%s, CustomPrice=%s, ProductPrice=%s\n", 
+		logger.Debugf("GetJobDevices[%d]: DeviceID=%s, CustomPrice=%s, ProductPrice=%s", 
 			i, device.DeviceID, customPriceVal, productPriceVal)
 	}
 
@@ -646,11 +804,73 @@ func (h *JobHandler) ListJobsAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
 }
 
+// validateJobRequest checks the raw job API payload field by field. IDs are
+// required when creating a job; on update only the fields sent are checked.
+func validateJobRequest(requestData map[string]interface{}, creating bool) []FieldError {
+	var fields []FieldError
+	for _, key := range []string{"customerID", "statusID"} {
+		value, ok := requestData[key]
+		if !ok || value == nil {
+			if creating {
+				fields = append(fields, FieldError{Field: key, Rule: "required", Message: key + " is required"})
+			}
+			continue
+		}
+		if id, ok := value.(float64); !ok || id < 1 || id != float64(uint(id)) {
+			fields = append(fields, FieldError{Field: key, Rule: "min", Param: "1", Message: key + " must be a positive integer"})
+		}
+	}
+	for _, key := range []string{"discount", "revenue", "final_revenue"} {
+		value, ok := requestData[key]
+		if !ok || value == nil {
+			continue
+		}
+		if number, ok := value.(float64); !ok {
+			fields = append(fields, FieldError{Field: key, Rule: "type", Param: "number", Message: key + " must be a number"})
+		} else if number < 0 {
+			fields = append(fields, FieldError{Field: key, Rule: "min", Param: "0", Message: key + " must be at least 0"})
+		}
+	}
+	if value, ok := requestData["discount_type"]; ok && value != nil {
+		discountType, isString := value.(string)
+		if _, err := models.NormalizeDiscountType(discountType, ""); !isString || err != nil {
+			fields = append(fields, FieldError{
+				Field:   "discount_type",
+				Rule:    "oneof",
+				Param:   models.DiscountTypeAmount + " " + models.DiscountTypePercent,
+				Message: fmt.Sprintf("discount_type must be one of: %s, %s", models.DiscountTypeAmount, models.DiscountTypePercent),
+			})
+		}
+	}
+	var dates [2]*time.Time
+	for i, key := range []string{"startDate", "endDate"} {
+		value, ok := requestData[key]
+		if !ok || value == nil || value == "" {
+			continue
+		}
+		dateStr, _ := value.(string)
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			fields = append(fields, FieldError{Field: key, Rule: "date", Param: "YYYY-MM-DD", Message: key + " must be a date in YYYY-MM-DD format"})
+			continue
+		}
+		dates[i] = &parsed
+	}
+	if dates[0] != nil && dates[1] != nil && dates[1].Before(*dates[0]) {
+		fields = append(fields, FieldError{Field: "endDate", Rule: "gtefield", Param: "startDate", Message: "endDate must not be before startDate"})
+	}
+	return fields
+}
+
 func (h *JobHandler) CreateJobAPI(c *gin.Context) {
 	// Use a map to capture raw JSON data
 	var requestData map[string]interface{}
 	if err := c.ShouldBindJSON(&requestData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondValidationError(c, err)
+		return
+	}
+	if fields := validateJobRequest(requestData, true); len(fields) > 0 {
+		respondFieldErrors(c, fields)
 		return
 	}
 
@@ -681,6 +901,12 @@ func (h *JobHandler) CreateJobAPI(c *gin.Context) {
 			job.DiscountType = dt
 		}
 	}
+	normalizedDiscountType, err := h.jobRepo.NormalizeDiscountType(job.DiscountType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	job.DiscountType = normalizedDiscountType
 	if revenue, ok := requestData["revenue"]; ok {
 		if r, ok := revenue.(float64); ok {
 			job.Revenue = r
@@ -708,10 +934,16 @@ func (h *JobHandler) CreateJobAPI(c *gin.Context) {
 		}
 	}
 
+	if err := checkRentalDuration(c, h.jobRepo, &job, 0); err != nil {
+		respondRentalDurationError(c, err)
+		return
+	}
+
 	if err := h.jobRepo.Create(&job); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	writeAuditLog(h.jobRepo.GetDB().DB, c, "create", "job", strconv.FormatUint(uint64(job.JobID), 10), nil, job)
 
 	c.JSON(http.StatusCreated, job)
 }
@@ -729,15 +961,7 @@ func (h *JobHandler) GetJobAPI(c *gin.Context) {
 		return
 	}
 
-	// Debug logging to check customer and status data
-	fmt.Printf("🔧 DEBUG GetJobAPI: Job %d - CustomerID: %d, StatusID: %d\n", job.JobID, job.CustomerID, job.StatusID)
-	fmt.Printf("🔧 DEBUG GetJobAPI: Customer loaded - ID: %d, CompanyName: %v, FirstName: %v, LastName: %v\n", 
-		job.Customer.CustomerID, job.Customer.CompanyName, job.Customer.FirstName, job.Customer.LastName)
-	fmt.Printf("🔧 DEBUG GetJobAPI: Status loaded - ID: %d, Status: %s\n", job.Status.StatusID, job.Status.Status)
-	
-	// Debug: Print full JSON being returned
-	jsonData, _ := json.MarshalIndent(job, "", "  ")
-	fmt.Printf("🔧 DEBUG GetJobAPI: Full JSON response:\n%s\n", string(jsonData))
+	logger.Debugf("GetJobAPI: Job %d - CustomerID: %d, StatusID: %d", job.JobID, job.CustomerID, job.StatusID)
 
 	c.JSON(http.StatusOK, job)
 }
@@ -752,7 +976,11 @@ func (h *JobHandler) UpdateJobAPI(c *gin.Context) {
 	// Use a map to capture raw JSON data
 	var requestData map[string]interface{}
 	if err := c.ShouldBindJSON(&requestData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondValidationError(c, err)
+		return
+	}
+	if fields := validateJobRequest(requestData, false); len(fields) > 0 {
+		respondFieldErrors(c, fields)
 		return
 	}
 
@@ -802,6 +1030,12 @@ func (h *JobHandler) UpdateJobAPI(c *gin.Context) {
 			job.DiscountType = dt
 		}
 	}
+	normalizedDiscountType, err := h.jobRepo.NormalizeDiscountType(job.DiscountType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	job.DiscountType = normalizedDiscountType
 	if revenue, ok := requestData["revenue"]; ok {
 		if r, ok := revenue.(float64); ok {
 			job.Revenue = r
@@ -829,58 +1063,74 @@ func (h *JobHandler) UpdateJobAPI(c *gin.Context) {
 		}
 	}
 
-	if err := h.jobRepo.Update(&job); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := checkRentalDuration(c, h.jobRepo, &job, existingJob.RentalDays()); err != nil {
+		respondRentalDurationError(c, err)
 		return
 	}
 
-	// Handle device assignments if selected_devices is provided
-	if selectedDevicesStr, ok := requestData["selected_devices"]; ok {
-		if deviceStr, ok := selectedDevicesStr.(string); ok && deviceStr != "" {
+	db := h.jobRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Job{}, "jobID", id)
+
+	// Update the job and sync its devices in one transaction so a failure
+	// mid-way does not leave the job updated but its devices half-synced
+	err = h.jobRepo.GetDB().WithTransaction(func(tx *repository.Database) error {
+		jobRepo := h.jobRepo.WithTx(tx)
+
+		if err := jobRepo.Update(&job); err != nil {
+			return err
+		}
+
+		// Handle device assignments if selected_devices is provided
+		if deviceStr, ok := requestData["selected_devices"].(string); ok && deviceStr != "" {
 			// Parse selected devices
 			selectedDevices := strings.Split(deviceStr, ",")
-			
+
 			// Get current job devices
-			currentDevices, err := h.jobRepo.GetJobDevices(uint(id))
+			currentDevices, err := jobRepo.GetJobDevices(uint(id))
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get current devices"})
-				return
+				return fmt.Errorf("failed to get current devices: %w", err)
 			}
-			
+
 			// Create sets for comparison
 			currentDeviceIDs := make(map[string]bool)
 			for _, device := range currentDevices {
 				currentDeviceIDs[device.DeviceID] = true
 			}
-			
+
 			newDeviceIDs := make(map[string]bool)
 			for _, deviceID := range selectedDevices {
 				if deviceID != "" {
 					newDeviceIDs[deviceID] = true
 				}
 			}
-			
+
 			// Remove devices that are no longer selected
 			for deviceID := range currentDeviceIDs {
 				if !newDeviceIDs[deviceID] {
-					if err := h.jobRepo.UnassignDevice(uint(id), deviceID); err != nil {
-						c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unassign device " + deviceID})
-						return
+					if err := jobRepo.UnassignDevice(uint(id), deviceID); err != nil {
+						return fmt.Errorf("failed to unassign device %s: %w", deviceID, err)
 					}
 				}
 			}
-			
+
 			// Add new devices
 			for deviceID := range newDeviceIDs {
 				if !currentDeviceIDs[deviceID] {
-					if err := h.jobRepo.AssignDevice(uint(id), deviceID, 0.0); err != nil {
-						c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign device " + deviceID})
-						return
+					if err := jobRepo.AssignDevice(uint(id), deviceID, 0.0); err != nil {
+						return fmt.Errorf("failed to assign device %s: %w", deviceID, err)
 					}
 				}
 			}
 		}
+
+		// Book the completion revenue once the job's devices are final
+		return recordCompletionRevenue(c, jobRepo, job.JobID, existingJob.StatusID, job.StatusID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	writeAuditLog(db, c, "update", "job", strconv.FormatUint(id, 10), previous, auditSnapshot(db, &models.Job{}, "jobID", id))
 
 	c.JSON(http.StatusOK, job)
 }
@@ -892,10 +1142,13 @@ func (h *JobHandler) DeleteJobAPI(c *gin.Context) {
 		return
 	}
 
+	db := h.jobRepo.GetDB().DB
+	previous := auditSnapshot(db, &models.Job{}, "jobID", id)
 	if err := h.jobRepo.Delete(uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	writeAuditLog(db, c, "delete", "job", strconv.FormatUint(id, 10), previous, nil)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Job deleted successfully"})
 }
@@ -925,6 +1178,176 @@ func (h *JobHandler) AssignDeviceAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Device assigned successfully"})
 }
 
+// ValidateAssignmentsRequest lists the devices to check for a job, by ID or
+// by dates. Dates override the stored job's dates, e.g. while they are edited.
+type ValidateAssignmentsRequest struct {
+	JobID     uint     `json:"jobId"`
+	StartDate string   `json:"startDate"`
+	EndDate   string   `json:"endDate"`
+	DeviceIDs []string `json:"deviceIds" binding:"required"`
+}
+
+// ValidateAssignments reports whether every device in the list is bookable for
+// the job's dates, so the edit form can show conflicts before saving. Nothing
+// is assigned.
+func (h *JobHandler) ValidateAssignments(c *gin.Context) {
+	var request ValidateAssignmentsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job := &models.Job{}
+	if request.JobID != 0 {
+		existing, err := h.jobRepo.GetByID(request.JobID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		job.JobID = existing.JobID
+		job.StartDate = existing.StartDate
+		job.EndDate = existing.EndDate
+	}
+	if request.StartDate != "" {
+		parsed, err := time.Parse("2006-01-02", request.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format"})
+			return
+		}
+		job.StartDate = &parsed
+	}
+	if request.EndDate != "" {
+		parsed, err := time.Parse("2006-01-02", request.EndDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format"})
+			return
+		}
+		job.EndDate = &parsed
+	}
+
+	results, err := h.jobRepo.ValidateAssignments(job, request.DeviceIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conflicts := 0
+	for _, result := range results {
+		if !result.Available {
+			conflicts++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":     conflicts == 0,
+		"conflicts": conflicts,
+		"devices":   results,
+	})
+}
+
+// PreviewRevenueRequest describes a job's devices and discount to price, by
+// job ID, device IDs or both. Device IDs replace the job's current devices.
+type PreviewRevenueRequest struct {
+	JobID        uint     `json:"jobId"`
+	DeviceIDs    []string `json:"deviceIds"`
+	StartDate    string   `json:"startDate"`
+	Discount     float64  `json:"discount"`
+	DiscountType string   `json:"discountType"`
+}
+
+// PreviewRevenue returns the gross revenue, discount applied and net revenue a
+// job would be saved with, so the job form can show totals while the discount
+// is typed. Nothing is stored.
+func (h *JobHandler) PreviewRevenue(c *gin.Context) {
+	var request PreviewRevenueRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.JobID == 0 && len(request.DeviceIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "jobId or deviceIds is required"})
+		return
+	}
+
+	var startDate *time.Time
+	if request.StartDate != "" {
+		parsed, err := time.Parse("2006-01-02", request.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format"})
+			return
+		}
+		startDate = &parsed
+	}
+
+	if request.JobID != 0 {
+		if _, err := h.jobRepo.GetByID(request.JobID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+	}
+
+	preview, err := h.jobRepo.PreviewRevenue(request.JobID, request.DeviceIDs, startDate, request.Discount, request.DiscountType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"gross":           h.currency.Round(preview.Gross),
+		"discountApplied": h.currency.Round(preview.DiscountApplied),
+		"net":             h.currency.Round(preview.Net),
+		"discountType":    preview.DiscountType,
+		"devices":         preview.Devices,
+		"currency":        h.currency,
+	})
+}
+
+// TransferDevice moves a device from the job in the URL straight to another
+// job, without a window in which it is unassigned
+func (h *JobHandler) TransferDevice(c *gin.Context) {
+	fromJobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	deviceID := c.Param("deviceId")
+
+	var request struct {
+		ToJobID uint `json:"toJobId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.ToJobID == uint(fromJobID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target job must differ from the current job"})
+		return
+	}
+
+	if err := h.jobRepo.TransferDevice(uint(fromJobID), request.ToJobID, deviceID); err != nil {
+		var conflict *repository.AssignmentConflictError
+		switch {
+		case errors.As(err, &conflict):
+			logger.Warnf("TransferDevice: device %s from job %d to job %d: %v", deviceID, fromJobID, request.ToJobID, err)
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			logger.Errorf("TransferDevice: device %s from job %d to job %d: %v", deviceID, fromJobID, request.ToJobID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer device"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Device transferred successfully",
+		"deviceId":  deviceID,
+		"fromJobId": fromJobID,
+		"toJobId":   request.ToJobID,
+	})
+}
+
 func (h *JobHandler) RemoveDeviceAPI(c *gin.Context) {
 	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -961,34 +1384,34 @@ func (h *JobHandler) BulkScanDevicesAPI(c *gin.Context) {
 func (h *JobHandler) UpdateDevicePriceAPI(c *gin.Context) {
 	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		fmt.Printf("🔧 DEBUG UpdateDevicePriceAPI: Invalid job ID: %v\n", err)
+		logger.Debugf("UpdateDevicePriceAPI: Invalid job ID: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
 		return
 	}
 
 	deviceID := c.Param("deviceId")
-	fmt.Printf("🔧 DEBUG UpdateDevicePriceAPI: JobID=%d, DeviceID=%s\n", jobID, deviceID)
+	logger.Debugf("UpdateDevicePriceAPI: JobID=%d, DeviceID=%s", jobID, deviceID)
 	
 	var request struct {
 		Price float64 `json:"price"`
 	}
 	
 	if err := c.ShouldBindJSON(&request); err != nil {
-		fmt.Printf("🔧 DEBUG UpdateDevicePriceAPI: JSON binding error: %v\n", err)
+		logger.Errorf("UpdateDevicePriceAPI: JSON binding error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	fmt.Printf("🔧 DEBUG UpdateDevicePriceAPI: Updating price to %.2f\n", request.Price)
+	logger.Debugf("UpdateDevicePriceAPI: Updating price to %.2f", request.Price)
 
 	// Update the device price in the job
 	if err := h.jobRepo.UpdateDevicePrice(uint(jobID), deviceID, request.Price); err != nil {
-		fmt.Printf("🔧 DEBUG UpdateDevicePriceAPI: Repository error: %v\n", err)
+		logger.Errorf("UpdateDevicePriceAPI: Repository error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	fmt.Printf("🔧 DEBUG UpdateDevicePriceAPI: Success!\n")
+	logger.Debugf("UpdateDevicePriceAPI: Success!")
 	c.JSON(http.StatusOK, gin.H{"message": "Device price updated successfully"})
 }
 
@@ -1026,7 +1449,7 @@ func (h *JobHandler) GetScanBoardData(c *gin.Context) {
 
 	rows, err := h.jobRepo.GetDB().Raw(query, jobID).Rows()
 	if err != nil {
-		fmt.Printf("Error getting scan board devices: %v\n", err)
+		logger.Errorf("Error getting scan board devices: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load devices"})
 		return
 	}
@@ -1096,7 +1519,7 @@ func (h *JobHandler) ScanDeviceForPack(c *gin.Context) {
 		Where("jobID = ? AND deviceID = ?", jobID, deviceID).
 		Count(&count).Error
 	if err != nil {
-		fmt.Printf("Error checking device job membership: %v\n", err)
+		logger.Errorf("Error checking device job membership: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
@@ -1115,7 +1538,7 @@ func (h *JobHandler) ScanDeviceForPack(c *gin.Context) {
 			"pack_ts":     now,
 		}).Error
 	if err != nil {
-		fmt.Printf("Error updating pack status: %v\n", err)
+		logger.Errorf("Error updating pack status: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pack status"})
 		return
 	}
@@ -1166,7 +1589,7 @@ func (h *JobHandler) UpdateDevicePackStatus(c *gin.Context) {
 		Where("jobID = ? AND deviceID = ?", jobID, deviceID).
 		Count(&count).Error
 	if err != nil {
-		fmt.Printf("Error checking device assignment: %v\n", err)
+		logger.Errorf("Error checking device assignment: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
@@ -1191,7 +1614,7 @@ func (h *JobHandler) UpdateDevicePackStatus(c *gin.Context) {
 		Where("jobID = ? AND deviceID = ?", jobID, deviceID).
 		Updates(updateData).Error
 	if err != nil {
-		fmt.Printf("Error updating pack status: %v\n", err)
+		logger.Errorf("Error updating pack status: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pack status"})
 		return
 	}
@@ -1236,7 +1659,7 @@ func (h *JobHandler) FinishPack(c *gin.Context) {
 
 	rows, err := h.jobRepo.GetDB().Raw(query, jobID).Rows()
 	if err != nil {
-		fmt.Printf("Error getting missing items: %v\n", err)
+		logger.Errorf("Error getting missing items: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check missing items"})
 		return
 	}
@@ -1273,7 +1696,7 @@ func (h *JobHandler) FinishPack(c *gin.Context) {
 				"pack_ts":     now,
 			}).Error
 		if err != nil {
-			fmt.Printf("Error marking all as packed: %v\n", err)
+			logger.Errorf("Error marking all as packed: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finish packing"})
 			return
 		}
@@ -1283,4 +1706,101 @@ func (h *JobHandler) FinishPack(c *gin.Context) {
 		"success": true,
 		"message": "Pack process completed successfully",
 	})
-}
\ No newline at end of file
+}
+
+// revenueRecalculation tracks the background job that recomputes all job revenues
+type revenueRecalculation struct {
+	mu         sync.Mutex
+	running    bool
+	processed  int
+	total      int
+	startedAt  *time.Time
+	finishedAt *time.Time
+	result     *repository.RevenueRecalculationResult
+	err        string
+}
+
+var revenueRecalc = &revenueRecalculation{}
+
+func (rr *revenueRecalculation) status() gin.H {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	return gin.H{
+		"running":    rr.running,
+		"processed":  rr.processed,
+		"total":      rr.total,
+		"startedAt":  rr.startedAt,
+		"finishedAt": rr.finishedAt,
+		"result":     rr.result,
+		"error":      rr.err,
+	}
+}
+
+// RecalculateAllRevenue starts recomputing the revenue and final revenue of every
+// job in the background, e.g. after pricing or discount rules changed.
+// Progress is reported by RecalculateAllRevenueStatus.
+func (h *JobHandler) RecalculateAllRevenue(c *gin.Context) {
+	if !userHasPermission(h.jobRepo.GetDB().DB, c, "financial.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	revenueRecalc.mu.Lock()
+	if revenueRecalc.running {
+		revenueRecalc.mu.Unlock()
+		c.JSON(http.StatusConflict, gin.H{"error": "A revenue recalculation is already running"})
+		return
+	}
+	now := time.Now()
+	revenueRecalc.running = true
+	revenueRecalc.processed = 0
+	revenueRecalc.total = 0
+	revenueRecalc.startedAt = &now
+	revenueRecalc.finishedAt = nil
+	revenueRecalc.result = nil
+	revenueRecalc.err = ""
+	revenueRecalc.mu.Unlock()
+
+	if user, exists := GetCurrentUser(c); exists {
+		logger.Infof("Revenue recalculation started by %s", user.Username)
+	}
+
+	go func() {
+		result, err := h.jobRepo.RecalculateAllRevenue(100, func(processed, total int) {
+			revenueRecalc.mu.Lock()
+			revenueRecalc.processed = processed
+			revenueRecalc.total = total
+			revenueRecalc.mu.Unlock()
+		})
+
+		finished := time.Now()
+		revenueRecalc.mu.Lock()
+		revenueRecalc.running = false
+		revenueRecalc.finishedAt = &finished
+		revenueRecalc.result = result
+		if err != nil {
+			revenueRecalc.err = err.Error()
+		}
+		revenueRecalc.mu.Unlock()
+
+		if err != nil {
+			logger.Errorf("Revenue recalculation failed: %v", err)
+		} else {
+			logger.Infof("Revenue recalculation finished: %d jobs, %d changed, %d failed, net difference %.2f",
+				result.Processed, result.Changed, result.Failed, result.NetDifference)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, revenueRecalc.status())
+}
+
+// RecalculateAllRevenueStatus reports the progress of the revenue recalculation
+func (h *JobHandler) RecalculateAllRevenueStatus(c *gin.Context) {
+	if !userHasPermission(h.jobRepo.GetDB().DB, c, "financial.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, revenueRecalc.status())
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeTransferTables answers the queries of a device transfer. The device is
+// on job 1 unless notAssigned, job 2 exists unless targetMissing, and the
+// device is already on job 2 or booked on job 3 when asked to be.
+type fakeTransferTables struct {
+	notAssigned    bool
+	targetMissing  bool
+	onTarget       bool
+	bookedOnJob3   bool
+	assignmentsErr error
+}
+
+func (f fakeTransferTables) answer(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 5, 3, 0, 0, 0, 0, time.UTC)
+
+	switch {
+	case strings.HasPrefix(query, "SELECT * FROM `jobdevices`"):
+		if f.assignmentsErr != nil {
+			return nil, nil, f.assignmentsErr
+		}
+		if f.notAssigned {
+			return nil, nil, nil
+		}
+		return []string{"jobID", "deviceID"}, [][]driver.Value{{int64(1), "DEV-1"}}, nil
+	case strings.HasPrefix(query, "SELECT * FROM `jobs`"):
+		if f.targetMissing {
+			return nil, nil, nil
+		}
+		return []string{"jobID", "startDate", "endDate"}, [][]driver.Value{{args[0], start, end}}, nil
+	case strings.HasPrefix(query, "SELECT count(*) FROM `jobdevices`"):
+		count := int64(0)
+		if f.onTarget {
+			count = 1
+		}
+		return []string{"count(*)"}, [][]driver.Value{{count}}, nil
+	case strings.Contains(query, "FROM `jobdevices` JOIN jobs"):
+		if !f.bookedOnJob3 {
+			return nil, nil, nil
+		}
+		return []string{"jobID", "deviceID"}, [][]driver.Value{{int64(3), "DEV-1"}}, nil
+	}
+	// Consumable checks, deletes, inserts and revenue updates: no rows
+	return nil, nil, nil
+}
+
+func TestTransferDeviceStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		toJob  string
+		tables fakeTransferTables
+		want   int
+	}{
+		{"transferred", "2", fakeTransferTables{}, http.StatusOK},
+		{"same job", "1", fakeTransferTables{}, http.StatusBadRequest},
+		{"device not on job", "2", fakeTransferTables{notAssigned: true}, http.StatusNotFound},
+		{"target job missing", "2", fakeTransferTables{targetMissing: true}, http.StatusNotFound},
+		{"already on target", "2", fakeTransferTables{onTarget: true}, http.StatusConflict},
+		{"booked elsewhere", "2", fakeTransferTables{bookedOnJob3: true}, http.StatusConflict},
+		{"database error", "2", fakeTransferTables{assignmentsErr: errors.New("connection lost")}, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t, tt.tables.answer)
+			handler := NewJobHandler(repository.NewJobRepository(db, nil), nil, nil, nil, nil, nil)
+
+			router := gin.New()
+			router.POST("/api/v1/jobs/:id/devices/:deviceId/transfer", handler.TransferDevice)
+
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/api/v1/jobs/1/devices/DEV-1/transfer",
+				strings.NewReader(`{"toJobId": `+tt.toJob+`}`))
+			request.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, request)
+
+			if w.Code != tt.want {
+				t.Fatalf("transfer = %d: %s; want %d", w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
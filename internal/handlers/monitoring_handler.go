@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"runtime"
 	"strconv"
@@ -10,6 +9,7 @@ import (
 
 	"go-barcode-webapp/internal/cache"
 	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/middleware"
 	"go-barcode-webapp/internal/monitoring"
 
@@ -42,7 +42,7 @@ func NewMonitoringHandler(
 
 // Dashboard displays the monitoring dashboard
 func (h *MonitoringHandler) Dashboard(c *gin.Context) {
-	log.Printf("⚠️ MONITORING DASHBOARD HANDLER CALLED - URL: %s", c.Request.URL.Path)
+	logger.Debugf("MONITORING DASHBOARD HANDLER CALLED - URL: %s", c.Request.URL.Path)
 	user, exists := GetCurrentUser(c)
 	if !exists {
 		c.Redirect(http.StatusSeeOther, "/login")
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// permissionCheckFuncs are the functions and methods whose string arguments
+// are permission codes
+var permissionCheckFuncs = map[string]bool{
+	"userHasPermission": true,
+	"hasPermission":     true,
+	"requirePermission": true,
+}
+
+// checkedPermissions returns the permission codes passed as literals to the
+// permission checks in the package's source files, with where they are used
+func checkedPermissions(t *testing.T) map[string]string {
+	t.Helper()
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	checked := make(map[string]string)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		parsed, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", file, err)
+		}
+		ast.Inspect(parsed, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			var name string
+			switch fun := call.Fun.(type) {
+			case *ast.Ident:
+				name = fun.Name
+			case *ast.SelectorExpr:
+				name = fun.Sel.Name
+			}
+			if !permissionCheckFuncs[name] {
+				return true
+			}
+			for _, arg := range call.Args {
+				literal, ok := arg.(*ast.BasicLit)
+				if !ok || literal.Kind != token.STRING {
+					continue
+				}
+				if code, err := strconv.Unquote(literal.Value); err == nil {
+					checked[code] = fset.Position(literal.Pos()).String()
+				}
+			}
+			return true
+		})
+	}
+	return checked
+}
+
+func TestCheckedPermissionsAreDefined(t *testing.T) {
+	checked := checkedPermissions(t)
+	if len(checked) == 0 {
+		t.Fatal("found no permission checks")
+	}
+
+	// Permissions looked up from tables instead of literals
+	for _, byAction := range syncPermissions {
+		for _, permission := range byAction {
+			checked[permission] = "syncPermissions"
+			checked[strings.SplitN(permission, ".", 2)[0]+".manage"] = "syncPermissions (manage)"
+		}
+	}
+	for _, permissions := range globalSearchPermissions {
+		for _, permission := range permissions {
+			checked[permission] = "globalSearchPermissions"
+		}
+	}
+
+	var undefined []string
+	for permission, usedAt := range checked {
+		if !isKnownPermission(permission) {
+			undefined = append(undefined, permission+" ("+usedAt+")")
+		}
+	}
+	sort.Strings(undefined)
+	for _, permission := range undefined {
+		t.Errorf("permission %s is checked but not in permissionDefinitions", permission)
+	}
+}
+
+func TestLegacyPermissionsMapToDefinedOnes(t *testing.T) {
+	for legacy, canonical := range legacyPermissionNames {
+		if !isKnownPermission(canonical) {
+			t.Errorf("legacy permission %s maps to undefined %s", legacy, canonical)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-barcode-webapp/internal/models"
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PricingCalendarHandler struct {
+	calendarRepo *repository.PricingCalendarRepository
+	db           *gorm.DB
+}
+
+func NewPricingCalendarHandler(calendarRepo *repository.PricingCalendarRepository, db *gorm.DB) *PricingCalendarHandler {
+	return &PricingCalendarHandler{
+		calendarRepo: calendarRepo,
+		db:           db,
+	}
+}
+
+// ListCalendars returns all pricing calendar entries
+func (h *PricingCalendarHandler) ListCalendars(c *gin.Context) {
+	if !userHasPermission(h.db, c, "pricing.view") && !userHasPermission(h.db, c, "pricing.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	calendars, err := h.calendarRepo.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pricing calendars"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"calendars": calendars})
+}
+
+// GetCalendar returns a single pricing calendar entry
+func (h *PricingCalendarHandler) GetCalendar(c *gin.Context) {
+	if !userHasPermission(h.db, c, "pricing.view") && !userHasPermission(h.db, c, "pricing.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	calendar, ok := h.loadCalendar(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"calendar": calendar})
+}
+
+// CreateCalendar creates a new pricing calendar entry
+func (h *PricingCalendarHandler) CreateCalendar(c *gin.Context) {
+	if !userHasPermission(h.db, c, "pricing.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	var req models.PricingCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	calendar := models.PricingCalendar{IsActive: true}
+	if err := applyPricingCalendarRequest(&calendar, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if currentUser, exists := GetCurrentUser(c); exists {
+		calendar.CreatedBy = &currentUser.UserID
+	}
+
+	if err := h.calendarRepo.Create(&calendar); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pricing calendar"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"calendar": calendar})
+}
+
+// UpdateCalendar updates an existing pricing calendar entry
+func (h *PricingCalendarHandler) UpdateCalendar(c *gin.Context) {
+	if !userHasPermission(h.db, c, "pricing.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	calendar, ok := h.loadCalendar(c)
+	if !ok {
+		return
+	}
+
+	var req models.PricingCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := applyPricingCalendarRequest(calendar, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Drop preloaded relations so Save doesn't touch products or categories
+	calendar.Product = nil
+	calendar.Category = nil
+
+	if err := h.calendarRepo.Update(calendar); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pricing calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"calendar": calendar})
+}
+
+// DeleteCalendar removes a pricing calendar entry
+func (h *PricingCalendarHandler) DeleteCalendar(c *gin.Context) {
+	if !userHasPermission(h.db, c, "pricing.manage") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	calendar, ok := h.loadCalendar(c)
+	if !ok {
+		return
+	}
+
+	if err := h.calendarRepo.Delete(calendar.CalendarID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete pricing calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pricing calendar deleted successfully"})
+}
+
+// loadCalendar fetches the calendar named by the :id route parameter and
+// writes the error response itself when it can't
+func (h *PricingCalendarHandler) loadCalendar(c *gin.Context) (*models.PricingCalendar, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pricing calendar ID"})
+		return nil, false
+	}
+
+	calendar, err := h.calendarRepo.GetByID(uint(id))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pricing calendar not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pricing calendar"})
+		return nil, false
+	}
+
+	return calendar, true
+}
+
+// applyPricingCalendarRequest validates the request and copies it onto the calendar
+func applyPricingCalendarRequest(calendar *models.PricingCalendar, req *models.PricingCalendarRequest) error {
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return fmt.Errorf("invalid start date format, expected YYYY-MM-DD")
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		return fmt.Errorf("invalid end date format, expected YYYY-MM-DD")
+	}
+	if endDate.Before(startDate) {
+		return fmt.Errorf("end date must not be before start date")
+	}
+	if req.ProductID != nil && req.CategoryID != nil {
+		return fmt.Errorf("a pricing calendar applies to either a product or a category, not both")
+	}
+	if (req.Multiplier == nil) == (req.OverrideRate == nil) {
+		return fmt.Errorf("exactly one of multiplier or overrideRate is required")
+	}
+
+	calendar.Name = req.Name
+	calendar.StartDate = startDate
+	calendar.EndDate = endDate
+	calendar.ProductID = req.ProductID
+	calendar.CategoryID = req.CategoryID
+	calendar.Multiplier = req.Multiplier
+	calendar.OverrideRate = req.OverrideRate
+	if req.IsActive != nil {
+		calendar.IsActive = *req.IsActive
+	}
+	return nil
+}
@@ -2,12 +2,12 @@ package handlers
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 
@@ -25,13 +25,13 @@ func NewProductHandler(productRepo *repository.ProductRepository) *ProductHandle
 // Web interface handlers
 func (h *ProductHandler) ListProductsWeb(c *gin.Context) {
 	startTime := time.Now()
-	log.Printf("🚀 ProductHandler.ListProductsWeb() started")
+	logger.Debugf("ProductHandler.ListProductsWeb() started")
 	
 	user, _ := GetCurrentUser(c)
 	
 	params := &models.FilterParams{}
 	if err := c.ShouldBindQuery(params); err != nil {
-		log.Printf("❌ Error binding query parameters: %v", err)
+		logger.Errorf("Error binding query parameters: %v", err)
 		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/error?code=400&message=Bad Request&details=%s", err.Error()))
 		return
 	}
@@ -54,7 +54,7 @@ func (h *ProductHandler) ListProductsWeb(c *gin.Context) {
 	params.Page = page
 
 	viewType := c.DefaultQuery("view", "list") // Default to list view
-	log.Printf("🐛 DEBUG: Product view requested: viewType='%s'", viewType)
+	logger.Debugf("Product view requested: viewType='%s'", viewType)
 
 	// Get total product count first (without pagination) for proper pagination calculation
 	var totalProducts int64
@@ -67,7 +67,7 @@ func (h *ProductHandler) ListProductsWeb(c *gin.Context) {
 		countQuery = countQuery.Where("category = ?", params.Category)
 	}
 	if err := countQuery.Count(&totalProducts).Error; err != nil {
-		log.Printf("❌ Count query error: %v", err)
+		logger.Errorf("Count query error: %v", err)
 		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/error?code=500&message=Database Error&details=%s", err.Error()))
 		return
 	}
@@ -81,10 +81,10 @@ func (h *ProductHandler) ListProductsWeb(c *gin.Context) {
 	dbStart := time.Now()
 	products, err := h.productRepo.List(params)
 	dbTime := time.Since(dbStart)
-	log.Printf("⏱️  Database query took: %v", dbTime)
+	logger.Debugf("Database query took: %v", dbTime)
 	
 	if err != nil {
-		log.Printf("❌ Database error: %v", err)
+		logger.Errorf("Database error: %v", err)
 		c.Redirect(http.StatusSeeOther, fmt.Sprintf("/error?code=500&message=Database Error&details=%s", err.Error()))
 		return
 	}
@@ -105,8 +105,8 @@ func (h *ProductHandler) ListProductsWeb(c *gin.Context) {
 	
 	templateTime := time.Since(templateStart)
 	totalTime := time.Since(startTime)
-	log.Printf("⏱️  Template rendering took: %v", templateTime)
-	log.Printf("🏁 ProductHandler.ListProductsWeb() completed in %v", totalTime)
+	logger.Debugf("Template rendering took: %v", templateTime)
+	logger.Debugf("ProductHandler.ListProductsWeb() completed in %v", totalTime)
 }
 
 func (h *ProductHandler) NewProductForm(c *gin.Context) {
@@ -177,18 +177,44 @@ func (h *ProductHandler) GetProductAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"product": product})
 }
 
+// GetDocuments returns the documents attached to a product; these are shown on every device of the product
+func (h *ProductHandler) GetDocuments(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID"})
+		return
+	}
+
+	if _, err := h.productRepo.GetByID(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	documents, err := h.productRepo.GetDocuments(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load documents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"documents": documents,
+		"count":     len(documents),
+	})
+}
+
 func (h *ProductHandler) CreateProductAPI(c *gin.Context) {
 	var product models.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
-		log.Printf("❌ Error binding product JSON: %v", err)
+		logger.Errorf("Error binding product JSON: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid product data: %v", err)})
 		return
 	}
 
-	log.Printf("📦 Creating product: %+v", product)
+	logger.Debugf("Creating product: %+v", product)
 
 	if err := h.productRepo.Create(&product); err != nil {
-		log.Printf("❌ Error creating product: %v", err)
+		logger.Errorf("Error creating product: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
 		return
 	}
@@ -206,16 +232,16 @@ func (h *ProductHandler) UpdateProductAPI(c *gin.Context) {
 
 	var product models.Product
 	if err := c.ShouldBindJSON(&product); err != nil {
-		log.Printf("❌ Error binding product JSON for update: %v", err)
+		logger.Errorf("Error binding product JSON for update: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid product data: %v", err)})
 		return
 	}
 
-	log.Printf("📦 Updating product %d: %+v", id, product)
+	logger.Debugf("Updating product %d: %+v", id, product)
 
 	product.ProductID = uint(id)
 	if err := h.productRepo.Update(&product); err != nil {
-		log.Printf("❌ Error updating product: %v", err)
+		logger.Errorf("Error updating product: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
 		return
 	}
@@ -227,7 +253,7 @@ func (h *ProductHandler) UpdateProductAPI(c *gin.Context) {
 func (h *ProductHandler) GetSubcategoriesAPI(c *gin.Context) {
 	var subcategories []models.Subcategory
 	if err := h.productRepo.GetAllSubcategories(&subcategories); err != nil {
-		log.Printf("❌ Error fetching subcategories: %v", err)
+		logger.Errorf("Error fetching subcategories: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subcategories"})
 		return
 	}
@@ -239,7 +265,7 @@ func (h *ProductHandler) GetSubcategoriesAPI(c *gin.Context) {
 func (h *ProductHandler) GetSubbiercategoriesAPI(c *gin.Context) {
 	var subbiercategories []models.Subbiercategory
 	if err := h.productRepo.GetAllSubbiercategories(&subbiercategories); err != nil {
-		log.Printf("❌ Error fetching subbiercategories: %v", err)
+		logger.Errorf("Error fetching subbiercategories: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subbiercategories"})
 		return
 	}
@@ -263,7 +289,7 @@ func (h *ProductHandler) GetSubcategoriesByCategoryAPI(c *gin.Context) {
 
 	var subcategories []models.Subcategory
 	if err := h.productRepo.GetSubcategoriesByCategory(uint(categoryID), &subcategories); err != nil {
-		log.Printf("❌ Error fetching subcategories for category %d: %v", categoryID, err)
+		logger.Errorf("Error fetching subcategories for category %d: %v", categoryID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subcategories"})
 		return
 	}
@@ -281,7 +307,7 @@ func (h *ProductHandler) GetSubbiercategoriesBySubcategoryAPI(c *gin.Context) {
 
 	var subbiercategories []models.Subbiercategory
 	if err := h.productRepo.GetSubbiercategoriesBySubcategory(subcategoryIDStr, &subbiercategories); err != nil {
-		log.Printf("❌ Error fetching subbiercategories for subcategory %s: %v", subcategoryIDStr, err)
+		logger.Errorf("Error fetching subbiercategories for subcategory %s: %v", subcategoryIDStr, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subbiercategories"})
 		return
 	}
@@ -293,7 +319,7 @@ func (h *ProductHandler) GetSubbiercategoriesBySubcategoryAPI(c *gin.Context) {
 func (h *ProductHandler) GetBrandsAPI(c *gin.Context) {
 	var brands []models.Brand
 	if err := h.productRepo.GetAllBrands(&brands); err != nil {
-		log.Printf("❌ Error fetching brands: %v", err)
+		logger.Errorf("Error fetching brands: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch brands"})
 		return
 	}
@@ -305,7 +331,7 @@ func (h *ProductHandler) GetBrandsAPI(c *gin.Context) {
 func (h *ProductHandler) GetManufacturersAPI(c *gin.Context) {
 	var manufacturers []models.Manufacturer
 	if err := h.productRepo.GetAllManufacturers(&manufacturers); err != nil {
-		log.Printf("❌ Error fetching manufacturers: %v", err)
+		logger.Errorf("Error fetching manufacturers: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch manufacturers"})
 		return
 	}
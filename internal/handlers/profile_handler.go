@@ -92,14 +92,24 @@ func (h *ProfileHandler) ProfileSettingsForm(c *gin.Context) {
 		Limit(5).
 		Find(&recentAttempts)
 
+	// Get active sessions with the client they were started from, leaving out
+	// logins still waiting for their second factor
+	var sessions []models.Session
+	h.db.Where("user_id = ? AND pending_2fa = ? AND expires_at > ?", currentUser.UserID, false, time.Now()).
+		Order("created_at DESC").
+		Find(&sessions)
+	currentSessionID, _ := c.Cookie("session_id")
+
 	c.HTML(http.StatusOK, "profile_settings_standalone.html", gin.H{
-		"title":           "Profile Settings",
-		"user":            currentUser,
-		"preferences":     preferences,
-		"twoFAEnabled":    twoFAEnabled,
-		"passkeys":        passkeys,
-		"recentAttempts":  recentAttempts,
-		"currentPage":     "profile",
+		"title":            "Profile Settings",
+		"user":             currentUser,
+		"preferences":      preferences,
+		"twoFAEnabled":     twoFAEnabled,
+		"passkeys":         passkeys,
+		"recentAttempts":   recentAttempts,
+		"sessions":         sessions,
+		"currentSessionID": currentSessionID,
+		"currentPage":      "profile",
 	})
 }
 
@@ -216,6 +226,10 @@ func (h *ProfileHandler) Get2FAStatus(c *gin.Context) {
 	h.webauthnHandler.Get2FAStatus(c)
 }
 
+func (h *ProfileHandler) RegenerateBackupCodes(c *gin.Context) {
+	h.webauthnHandler.RegenerateBackupCodes(c)
+}
+
 // ================================================================
 // PASSKEY ENDPOINTS (delegating to WebAuthnHandler)
 // ================================================================
@@ -2,101 +2,153 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
+	"go-barcode-webapp/internal/repository"
+	"go-barcode-webapp/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PWAHandler struct {
-	db *gorm.DB
+	jobRepo      *repository.JobRepository
+	deviceRepo   *repository.DeviceRepository
+	customerRepo *repository.CustomerRepository
+	db           *gorm.DB
+	push         *services.PushService // nil when no VAPID keys are configured
 }
 
-func NewPWAHandler(db *gorm.DB) *PWAHandler {
-	return &PWAHandler{db: db}
+func NewPWAHandler(jobRepo *repository.JobRepository, deviceRepo *repository.DeviceRepository, customerRepo *repository.CustomerRepository, db *gorm.DB, push *services.PushService) *PWAHandler {
+	return &PWAHandler{
+		jobRepo:      jobRepo,
+		deviceRepo:   deviceRepo,
+		customerRepo: customerRepo,
+		db:           db,
+		push:         push,
+	}
 }
 
-// Push notification subscription structure
+// Push notification subscription structure, as serialized by the browser's
+// PushSubscription.toJSON() plus the optional device type
 type PushSubscription struct {
-	Endpoint       string `json:"endpoint"`
+	Endpoint       string `json:"endpoint" binding:"required,url,max=2048"`
 	ExpirationTime *int64 `json:"expirationTime"`
 	Keys           struct {
-		P256dh string `json:"p256dh"`
-		Auth   string `json:"auth"`
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
 	} `json:"keys"`
+	DeviceType string `json:"deviceType" binding:"omitempty,oneof=mobile tablet desktop"`
 }
 
-// SubscribePush handles push notification subscription
-func (h *PWAHandler) SubscribePush(c *gin.Context) {
-	var subscription PushSubscription
-	if err := c.ShouldBindJSON(&subscription); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// GetPushPublicKey returns the VAPID public key the browser subscribes with
+func (h *PWAHandler) GetPushPublicKey(c *gin.Context) {
+	if h.push == nil {
+		respondJSONError(c, http.StatusServiceUnavailable, errCodePushNotConfigured, "Push notifications are not configured")
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"publicKey": h.push.PublicKey()})
+}
 
+// SubscribePush stores the browser's push subscription for the current user.
+// An endpoint identifies one browser profile, so subscribing an endpoint that
+// is already known updates that row, reactivates it and moves it to the
+// current user instead of adding a duplicate.
+func (h *PWAHandler) SubscribePush(c *gin.Context) {
 	currentUser, exists := GetCurrentUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondJSONError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
 		return
 	}
 
-	// Convert subscription to JSON (for logging if needed)
-	_, _ = json.Marshal(subscription)
+	var subscription PushSubscription
+	if err := c.ShouldBindJSON(&subscription); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	if !strings.HasPrefix(subscription.Endpoint, "https://") {
+		respondFieldErrors(c, []FieldError{{Field: "endpoint", Rule: "https", Message: "endpoint must be an https URL"}})
+		return
+	}
+	if err := services.ValidatePushKeys(subscription.Keys.P256dh, subscription.Keys.Auth); err != nil {
+		respondFieldErrors(c, []FieldError{{Field: "keys", Rule: "push_keys", Message: err.Error()}})
+		return
+	}
 
-	// Save or update push subscription
-	pushSub := models.PushSubscription{
-		UserID:     currentUser.UserID,
-		Endpoint:   subscription.Endpoint,
-		KeysP256dh: subscription.Keys.P256dh,
-		KeysAuth:   subscription.Keys.Auth,
-		IsActive:   true,
-		CreatedAt:  time.Now(),
-		LastUsed:   time.Now(),
+	userAgent := c.GetHeader("User-Agent")
+	deviceType := subscription.DeviceType
+	if deviceType == "" {
+		deviceType = pushDeviceType(userAgent)
 	}
 
-	// Try to find existing subscription first
-	var existing models.PushSubscription
-	result := h.db.Where("userID = ? AND endpoint = ?", currentUser.UserID, subscription.Endpoint).First(&existing)
-	
-	if result.Error == gorm.ErrRecordNotFound {
-		// Create new subscription
-		if err := h.db.Create(&pushSub).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save subscription"})
-			return
+	now := time.Now()
+	created := false
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.PushSubscription
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("endpoint = ?", subscription.Endpoint).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			created = true
+			return tx.Create(&models.PushSubscription{
+				UserID:     currentUser.UserID,
+				Endpoint:   subscription.Endpoint,
+				KeysP256dh: subscription.Keys.P256dh,
+				KeysAuth:   subscription.Keys.Auth,
+				UserAgent:  userAgent,
+				DeviceType: deviceType,
+				IsActive:   true,
+				CreatedAt:  now,
+				LastUsed:   now,
+			}).Error
 		}
-	} else {
-		// Update existing subscription
-		existing.KeysP256dh = subscription.Keys.P256dh
-		existing.KeysAuth = subscription.Keys.Auth
-		existing.IsActive = true
-		existing.LastUsed = time.Now()
-		
-		if err := h.db.Save(&existing).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subscription"})
-			return
+		if err != nil {
+			return err
 		}
+
+		return tx.Model(&existing).Updates(map[string]interface{}{
+			"userID":      currentUser.UserID,
+			"keys_p256dh": subscription.Keys.P256dh,
+			"keys_auth":   subscription.Keys.Auth,
+			"user_agent":  userAgent,
+			"device_type": deviceType,
+			"is_active":   true,
+			"last_used":   now,
+		}).Error
+	})
+	if err != nil {
+		logger.Errorf("Failed to save push subscription of user %d: %v", currentUser.UserID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to save subscription")
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Push subscription saved successfully"})
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{"message": "Push subscription saved successfully"})
 }
 
-// UnsubscribePush handles push notification unsubscription
+// UnsubscribePush deactivates one of the current user's push subscriptions
 func (h *PWAHandler) UnsubscribePush(c *gin.Context) {
 	var request struct {
-		Endpoint string `json:"endpoint"`
+		Endpoint string `json:"endpoint" binding:"required"`
 	}
-	
+
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondValidationError(c, err)
 		return
 	}
 
 	currentUser, exists := GetCurrentUser(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		respondJSONError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
 		return
 	}
 
@@ -106,13 +158,177 @@ func (h *PWAHandler) UnsubscribePush(c *gin.Context) {
 		Update("is_active", false)
 
 	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		logger.Errorf("Failed to remove push subscription of user %d: %v", currentUser.UserID, result.Error)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to unsubscribe")
+		return
+	}
+	if result.RowsAffected == 0 {
+		respondJSONError(c, http.StatusNotFound, errCodeNotFound, "Push subscription not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Push subscription removed successfully"})
 }
 
+// pushDeviceType guesses the device type from the user agent for
+// subscriptions that don't state it
+func pushDeviceType(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "Tablet"):
+		return "tablet"
+	case strings.Contains(userAgent, "Mobi") || strings.Contains(userAgent, "Android") || strings.Contains(userAgent, "iPhone"):
+		return "mobile"
+	default:
+		return "desktop"
+	}
+}
+
+// maxJobsPerReminder caps the jobs listed in one reminder notification
+const maxJobsPerReminder = 3
+
+// StartJobDueReminders notifies subscribed users once a day at reminderTime
+// (HH:MM, local time) about jobs ending tomorrow, in a background goroutine
+func (h *PWAHandler) StartJobDueReminders(reminderTime string) error {
+	if h.push == nil {
+		return errors.New("push notifications are not configured")
+	}
+	at, err := time.Parse("15:04", reminderTime)
+	if err != nil {
+		return fmt.Errorf("invalid job reminder time %q, expected HH:MM", reminderTime)
+	}
+
+	go func() {
+		for {
+			time.Sleep(time.Until(nextDailyRun(time.Now(), at.Hour(), at.Minute())))
+			if sent, err := h.SendJobDueReminders(time.Now()); err != nil {
+				logger.Errorf("Job reminders: %v", err)
+			} else if sent > 0 {
+				logger.Infof("Job reminders: notified %d user(s) about jobs ending tomorrow", sent)
+			}
+		}
+	}()
+	return nil
+}
+
+// nextDailyRun returns the next time after now at hour:minute
+func nextDailyRun(now time.Time, hour, minute int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// SendJobDueReminders sends a push notification about the open jobs ending
+// tomorrow to every user with an active subscription who can view jobs and
+// hasn't turned off job notifications. It returns how many users received it.
+func (h *PWAHandler) SendJobDueReminders(now time.Time) (int, error) {
+	if h.push == nil {
+		return 0, errors.New("push notifications are not configured")
+	}
+
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+	var candidates []models.Job
+	if err := h.db.Preload("Customer").
+		Where("endDate = ?", tomorrow.Format("2006-01-02")).
+		Order("jobID ASC").
+		Find(&candidates).Error; err != nil {
+		return 0, fmt.Errorf("failed to load jobs ending tomorrow: %v", err)
+	}
+	var jobs []models.Job
+	for _, job := range candidates {
+		if !repository.IsCompletedJobStatus(job.StatusID) {
+			jobs = append(jobs, job)
+		}
+	}
+	if len(jobs) == 0 {
+		return 0, nil
+	}
+
+	var userIDs []uint
+	if err := h.db.Model(&models.PushSubscription{}).
+		Where("is_active = ?", true).
+		Distinct().Pluck("userID", &userIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to load push subscribers: %v", err)
+	}
+
+	notification := jobDueNotification(jobs)
+	notified := 0
+	for _, userID := range userIDs {
+		if !h.wantsJobReminders(userID) {
+			continue
+		}
+		delivered, err := h.push.SendToUser(userID, notification)
+		if err != nil {
+			logger.Errorf("Job reminders: user %d: %v", userID, err)
+			continue
+		}
+		if delivered > 0 {
+			notified++
+		}
+	}
+	return notified, nil
+}
+
+// wantsJobReminders reports whether the user may view jobs and hasn't turned
+// off job notifications in their preferences
+func (h *PWAHandler) wantsJobReminders(userID uint) bool {
+	var user models.User
+	if err := h.db.Where("userID = ? AND is_active = ?", userID, true).First(&user).Error; err != nil {
+		return false
+	}
+	granted, err := effectivePermissions(h.db, &user)
+	if err != nil || !(granted["*"] || granted["jobs.view"] || granted["jobs.manage"]) {
+		return false
+	}
+
+	var preferences models.UserPreferences
+	err = h.db.Where("user_id = ?", userID).First(&preferences).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true
+	}
+	return err == nil && preferences.JobStatusNotifications
+}
+
+// jobDueNotification summarizes the jobs ending tomorrow in one notification
+func jobDueNotification(jobs []models.Job) services.PushNotification {
+	if len(jobs) == 1 {
+		return services.PushNotification{
+			Title: fmt.Sprintf("Job #%d ends tomorrow", jobs[0].JobID),
+			Body:  jobReminderLabel(&jobs[0]),
+			URL:   fmt.Sprintf("/jobs/%d", jobs[0].JobID),
+			Tag:   "jobs-due",
+		}
+	}
+
+	lines := make([]string, 0, maxJobsPerReminder+1)
+	for i := range jobs {
+		if i == maxJobsPerReminder {
+			lines = append(lines, fmt.Sprintf("and %d more", len(jobs)-maxJobsPerReminder))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("#%d %s", jobs[i].JobID, jobReminderLabel(&jobs[i])))
+	}
+	return services.PushNotification{
+		Title: fmt.Sprintf("%d jobs end tomorrow", len(jobs)),
+		Body:  strings.Join(lines, "\n"),
+		URL:   "/jobs",
+		Tag:   "jobs-due",
+	}
+}
+
+// jobReminderLabel names a job by its customer and description
+func jobReminderLabel(job *models.Job) string {
+	parts := make([]string, 0, 2)
+	if name := job.Customer.GetDisplayName(); name != "" {
+		parts = append(parts, name)
+	}
+	if job.Description != nil && *job.Description != "" {
+		parts = append(parts, *job.Description)
+	}
+	return strings.Join(parts, " - ")
+}
+
 // SyncOfflineData handles offline data synchronization
 func (h *PWAHandler) SyncOfflineData(c *gin.Context) {
 	var request struct {
@@ -297,8 +513,10 @@ func (h *PWAHandler) GetOfflineManifest(c *gin.Context) {
 		},
 		"sync_endpoints": map[string]string{
 			"sync_offline": "/pwa/sync",
+			"sync_queue":   "/pwa/sync/queue",
 			"subscribe":    "/pwa/subscribe",
 			"unsubscribe":  "/pwa/unsubscribe",
+			"push_key":     "/pwa/push-key",
 		},
 	}
 
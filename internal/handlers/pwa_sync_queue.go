@@ -0,0 +1,681 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/models"
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sync queue item outcomes reported to the client
+const (
+	syncStatusSynced   = "synced"
+	syncStatusFailed   = "failed"
+	syncStatusConflict = "conflict"
+)
+
+// maxSyncQueueBatch bounds the queued actions processed in one request
+const maxSyncQueueBatch = 500
+
+// maxSyncRetries is how often a queued action is replayed before it is given
+// up and leaves the queue as failed
+const maxSyncRetries = 5
+
+// syncPermissions maps entity type and action to the permission needed to
+// replay it; the entity's .manage permission is accepted as well
+var syncPermissions = map[string]map[string]string{
+	"job": {
+		models.SyncActionCreate: "jobs.create",
+		models.SyncActionUpdate: "jobs.edit",
+		models.SyncActionDelete: "jobs.delete",
+	},
+	"job_device": {
+		models.SyncActionCreate: "jobs.edit",
+		models.SyncActionUpdate: "jobs.edit",
+		models.SyncActionDelete: "jobs.edit",
+	},
+	"device": {
+		models.SyncActionCreate: "devices.create",
+		models.SyncActionUpdate: "devices.edit",
+		models.SyncActionDelete: "devices.delete",
+	},
+	"customer": {
+		models.SyncActionCreate: "customers.create",
+		models.SyncActionUpdate: "customers.edit",
+		models.SyncActionDelete: "customers.delete",
+	},
+}
+
+// syncEntityKeys are the ID fields of the entities whose updated_at guards
+// offline updates and deletes against changes made on the server meanwhile
+var syncEntityKeys = map[string]string{
+	"job":      "jobID",
+	"device":   "deviceID",
+	"customer": "customerID",
+}
+
+// syncConflictError reports that the server copy of an entity changed after
+// the app loaded the version its queued action was based on
+type syncConflictError struct {
+	serverVersion interface{}
+}
+
+func (e *syncConflictError) Error() string {
+	return "changed on the server since the app loaded it"
+}
+
+// SyncQueueAction is an action the app recorded while offline
+type SyncQueueAction struct {
+	Action     string          `json:"action" binding:"required,oneof=create update delete"`
+	EntityType string          `json:"entityType" binding:"required"`
+	EntityData json.RawMessage `json:"entityData" binding:"required"`
+	Timestamp  time.Time       `json:"timestamp"`
+}
+
+// SyncQueueResult is the outcome of replaying one queued action
+type SyncQueueResult struct {
+	QueueID    uint        `json:"queueID"`
+	Action     string      `json:"action"`
+	EntityType string      `json:"entityType"`
+	EntityID   interface{} `json:"entityID,omitempty"`
+	Status     string      `json:"status"`
+	Error      string      `json:"error,omitempty"`
+	RetryCount int         `json:"retryCount"`
+
+	// Both versions of a conflicting entity, for the app to merge
+	ServerVersion interface{}     `json:"serverVersion,omitempty"`
+	ClientVersion json.RawMessage `json:"clientVersion,omitempty"`
+}
+
+// ProcessSyncQueue is called by the app on reconnect. Actions in the body are
+// added to the user's offline sync queue first; then every pending action of
+// the user is replayed in timestamp order through the repositories. Replayed
+// actions are marked synced, failed ones keep their place with an increased
+// retry count and the error, so the app can show and resolve them. Actions
+// that keep failing leave the queue with the status failed instead.
+//
+// Updates and deletes that carry the updated_at of the entity version they
+// were made on are rejected with a conflict when the server row is newer.
+// Conflicts leave the queue with the status conflict; the result holds the server and the queued
+// version so the app can merge them and queue the merged change.
+func (h *PWAHandler) ProcessSyncQueue(c *gin.Context) {
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		respondJSONError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	var request struct {
+		Actions []SyncQueueAction `json:"actions" binding:"dive"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondValidationError(c, err)
+			return
+		}
+	}
+
+	if len(request.Actions) > 0 {
+		now := time.Now()
+		queued := make([]models.OfflineSyncQueue, len(request.Actions))
+		for i, action := range request.Actions {
+			timestamp := action.Timestamp
+			if timestamp.IsZero() || timestamp.After(now) {
+				timestamp = now
+			}
+			queued[i] = models.OfflineSyncQueue{
+				UserID:     currentUser.UserID,
+				Action:     action.Action,
+				EntityType: action.EntityType,
+				EntityData: action.EntityData,
+				Timestamp:  timestamp,
+				Status:     models.SyncQueueStatusPending,
+			}
+		}
+		if err := h.db.Create(&queued).Error; err != nil {
+			logger.Errorf("Failed to queue offline actions of user %d: %v", currentUser.UserID, err)
+			respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to queue offline actions")
+			return
+		}
+	}
+
+	var pending []models.OfflineSyncQueue
+	if err := h.db.Where("userID = ? AND status = ?", currentUser.UserID, models.SyncQueueStatusPending).
+		Order("timestamp ASC, queueID ASC").
+		Limit(maxSyncQueueBatch).
+		Find(&pending).Error; err != nil {
+		logger.Errorf("Failed to load offline sync queue of user %d: %v", currentUser.UserID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load sync queue")
+		return
+	}
+
+	results := make([]SyncQueueResult, 0, len(pending))
+	synced, failed, conflicts := 0, 0, 0
+	// updated_at of the entities this run changed, so several offline edits of
+	// one entity don't conflict with each other
+	written := make(map[string]time.Time)
+	for i := range pending {
+		item := &pending[i]
+		result := SyncQueueResult{
+			QueueID:    item.QueueID,
+			Action:     item.Action,
+			EntityType: item.EntityType,
+			RetryCount: item.RetryCount,
+		}
+
+		entityID, err := h.applySyncAction(c, item, written)
+		result.EntityID = entityID
+		var conflict *syncConflictError
+		if errors.As(err, &conflict) {
+			result.Status = syncStatusConflict
+			result.Error = err.Error()
+			result.ServerVersion = conflict.serverVersion
+			result.ClientVersion = item.EntityData
+			if err := h.db.Model(item).Updates(map[string]interface{}{
+				"status":        models.SyncQueueStatusConflict,
+				"error_message": "conflict: " + result.Error,
+			}).Error; err != nil {
+				logger.Errorf("Sync queue: failed to settle conflicting item %d: %v", item.QueueID, err)
+			}
+			conflicts++
+		} else if err == nil {
+			now := time.Now()
+			err = h.db.Model(item).Updates(map[string]interface{}{
+				"synced":        true,
+				"synced_at":     now,
+				"status":        models.SyncQueueStatusSynced,
+				"error_message": "",
+			}).Error
+			if err != nil {
+				// The action was applied; report it so the app doesn't replay it
+				logger.Errorf("Sync queue: failed to mark item %d synced: %v", item.QueueID, err)
+			}
+			result.Status = syncStatusSynced
+			synced++
+		} else {
+			result.Status = syncStatusFailed
+			result.Error = err.Error()
+			result.RetryCount = item.RetryCount + 1
+			updates := map[string]interface{}{
+				"retry_count":   gorm.Expr("retry_count + 1"),
+				"error_message": result.Error,
+			}
+			if result.RetryCount >= maxSyncRetries {
+				// Give up on actions that keep failing instead of replaying them forever
+				result.Error = fmt.Sprintf("failed after %d attempts: %s", result.RetryCount, result.Error)
+				updates["status"] = models.SyncQueueStatusFailed
+				updates["error_message"] = result.Error
+			}
+			if err := h.db.Model(item).Updates(updates).Error; err != nil {
+				logger.Errorf("Sync queue: failed to record error of item %d: %v", item.QueueID, err)
+			}
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	var remaining int64
+	h.db.Model(&models.OfflineSyncQueue{}).
+		Where("userID = ? AND status = ?", currentUser.UserID, models.SyncQueueStatusPending).
+		Count(&remaining)
+
+	c.JSON(http.StatusOK, gin.H{
+		"synced":    synced,
+		"failed":    failed,
+		"conflicts": conflicts,
+		"remaining": remaining,
+		"results":   results,
+	})
+}
+
+// applySyncAction replays one queued action and returns the ID of the entity
+// it affected. written holds the updated_at of entities changed earlier in the
+// same run and receives the ones this action changes.
+func (h *PWAHandler) applySyncAction(c *gin.Context, item *models.OfflineSyncQueue, written map[string]time.Time) (interface{}, error) {
+	permission, ok := syncPermissions[item.EntityType][item.Action]
+	if !ok {
+		return nil, fmt.Errorf("unsupported action %s on %s", item.Action, item.EntityType)
+	}
+	managePermission := strings.SplitN(permission, ".", 2)[0] + ".manage"
+	if !userHasPermission(h.db, c, permission) && !userHasPermission(h.db, c, managePermission) {
+		return nil, fmt.Errorf("missing permission %s", permission)
+	}
+
+	data, err := normalizeSyncDates(item.EntityData)
+	if err != nil {
+		return nil, err
+	}
+
+	// The conflict check locks the entity's row until the action is applied,
+	// so no other change can land in between
+	var entityID interface{}
+	err = h.jobRepo.GetDB().WithTransaction(func(tx *repository.Database) error {
+		var err error
+		entityID, err = h.withTx(tx).replaySyncAction(c, item, data, written)
+		return err
+	})
+	return entityID, err
+}
+
+// withTx returns a copy of the handler whose database and repositories are
+// bound to the given transaction
+func (h *PWAHandler) withTx(tx *repository.Database) *PWAHandler {
+	txHandler := &PWAHandler{jobRepo: h.jobRepo.WithTx(tx), db: tx.DB, push: h.push}
+	if h.deviceRepo != nil {
+		txHandler.deviceRepo = h.deviceRepo.WithTx(tx)
+	}
+	if h.customerRepo != nil {
+		txHandler.customerRepo = h.customerRepo.WithTx(tx)
+	}
+	return txHandler
+}
+
+// replaySyncAction checks a queued action for conflicts and applies it. It
+// expects to run in a transaction.
+func (h *PWAHandler) replaySyncAction(c *gin.Context, item *models.OfflineSyncQueue, data []byte, written map[string]time.Time) (interface{}, error) {
+	if entityID, err := h.checkSyncConflict(item.EntityType, item.Action, data, written); err != nil {
+		return entityID, err
+	}
+
+	previous := h.syncAuditSnapshot(item.EntityType, item.Action, data)
+
+	var entityID interface{}
+	var err error
+	switch item.EntityType {
+	case "job":
+		entityID, err = h.applyJobSync(c, item.Action, data)
+	case "job_device":
+		entityID, err = h.applyJobDeviceSync(item.Action, data)
+	case "device":
+		entityID, err = h.applyDeviceSync(item.Action, data)
+	case "customer":
+		entityID, err = h.applyCustomerSync(item.Action, data)
+	default:
+		return nil, fmt.Errorf("unsupported entity type %s", item.EntityType)
+	}
+
+	if err == nil && item.Action == models.SyncActionUpdate {
+		if _, ok := syncEntityKeys[item.EntityType]; ok {
+			if _, updatedAt, err := h.loadSyncEntity(item.EntityType, entityID, false); err == nil {
+				written[fmt.Sprintf("%s:%v", item.EntityType, entityID)] = updatedAt
+			}
+		}
+	}
+	if err == nil {
+		if _, ok := syncEntityKeys[item.EntityType]; ok {
+			var current interface{}
+			if item.Action != models.SyncActionDelete {
+				if entity, _, err := h.loadSyncEntity(item.EntityType, entityID, false); err == nil {
+					current = entity
+				}
+			}
+			writeAuditLog(h.db, c, item.Action, item.EntityType, fmt.Sprint(entityID), previous, current)
+		}
+	}
+	return entityID, err
+}
+
+// syncAuditSnapshot returns the server copy of the job, device or customer a
+// queued update or delete is about to change, for the old values of its audit
+// entry. It returns nil for creates and entities that can't be loaded.
+func (h *PWAHandler) syncAuditSnapshot(entityType, action string, data []byte) interface{} {
+	key, ok := syncEntityKeys[entityType]
+	if !ok || action == models.SyncActionCreate {
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil || fields[key] == nil {
+		return nil
+	}
+	entity, _, err := h.loadSyncEntity(entityType, fields[key], false)
+	if err != nil {
+		return nil
+	}
+	return entity
+}
+
+// checkSyncConflict compares the updated_at the queued data was based on
+// with the server row, which it locks for the rest of the transaction.
+// Actions without updated_at are applied unchecked, as older app versions
+// don't send it.
+func (h *PWAHandler) checkSyncConflict(entityType, action string, data []byte, written map[string]time.Time) (interface{}, error) {
+	key, ok := syncEntityKeys[entityType]
+	if !ok || action == models.SyncActionCreate {
+		return nil, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid %s data: %v", entityType, err)
+	}
+	entityID, ok := fields[key]
+	if !ok || entityID == nil {
+		return nil, nil
+	}
+	baseValue, ok := fields["updated_at"].(string)
+	if !ok || baseValue == "" {
+		return nil, nil
+	}
+	base, err := time.Parse(time.RFC3339, baseValue)
+	if err != nil {
+		return entityID, fmt.Errorf("invalid updated_at: %v", err)
+	}
+
+	server, serverUpdatedAt, err := h.loadSyncEntity(entityType, entityID, true)
+	if err != nil {
+		// Missing entities are reported by the apply step
+		return nil, nil
+	}
+	if own, ok := written[fmt.Sprintf("%s:%v", entityType, entityID)]; ok && own.After(base) {
+		base = own
+	}
+	// The column has second precision
+	if serverUpdatedAt.Truncate(time.Second).After(base.Truncate(time.Second)) {
+		return entityID, &syncConflictError{serverVersion: server}
+	}
+	return nil, nil
+}
+
+// loadSyncEntity returns the server copy of a job, device or customer and
+// its updated_at, locking its row with SELECT ... FOR UPDATE when forUpdate
+// is set
+func (h *PWAHandler) loadSyncEntity(entityType string, entityID interface{}, forUpdate bool) (interface{}, time.Time, error) {
+	if number, ok := entityID.(float64); ok {
+		entityID = uint(number)
+	}
+	where := syncEntityKeys[entityType] + " = ?"
+	query := h.db
+	if forUpdate {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	switch entityType {
+	case "job":
+		var job models.Job
+		err := query.Where(where, entityID).First(&job).Error
+		return &job, job.UpdatedAt, err
+	case "device":
+		var device models.Device
+		err := query.Where(where, entityID).First(&device).Error
+		return &device, device.UpdatedAt, err
+	case "customer":
+		var customer models.Customer
+		err := query.Where(where, entityID).First(&customer).Error
+		return &customer, customer.UpdatedAt, err
+	}
+	return nil, time.Time{}, fmt.Errorf("unsupported entity type %s", entityType)
+}
+
+func (h *PWAHandler) applyJobSync(c *gin.Context, action string, data []byte) (interface{}, error) {
+	var ref struct {
+		JobID uint `json:"jobID"`
+	}
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return nil, fmt.Errorf("invalid job data: %v", err)
+	}
+
+	if action != models.SyncActionDelete {
+		if err := validateSyncJob(data, action == models.SyncActionCreate); err != nil {
+			return nil, err
+		}
+	}
+
+	switch action {
+	case models.SyncActionCreate:
+		var job models.Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("invalid job data: %v", err)
+		}
+		job.JobID = 0
+		clearJobAssociations(&job)
+		if err := checkRentalDuration(c, h.jobRepo, &job, 0); err != nil {
+			return nil, err
+		}
+		if err := h.jobRepo.Create(&job); err != nil {
+			return nil, err
+		}
+		return job.JobID, nil
+
+	case models.SyncActionUpdate:
+		if ref.JobID == 0 {
+			return nil, errors.New("jobID is required")
+		}
+		var job models.Job
+		if err := h.db.First(&job, ref.JobID).Error; err != nil {
+			return ref.JobID, syncLookupError("job", err)
+		}
+		previousStatusID := job.StatusID
+		previousDays := job.RentalDays()
+		// Fields missing from the queued data keep their current values
+		if err := json.Unmarshal(data, &job); err != nil {
+			return ref.JobID, fmt.Errorf("invalid job data: %v", err)
+		}
+		job.JobID = ref.JobID
+		clearJobAssociations(&job)
+		if err := checkRentalDuration(c, h.jobRepo, &job, previousDays); err != nil {
+			return ref.JobID, err
+		}
+		// Completing a job offline books its revenue like an online update
+		return ref.JobID, h.jobRepo.GetDB().WithTransaction(func(tx *repository.Database) error {
+			jobRepo := h.jobRepo.WithTx(tx)
+			if err := jobRepo.Update(&job); err != nil {
+				return err
+			}
+			return recordCompletionRevenue(c, jobRepo, job.JobID, previousStatusID, job.StatusID)
+		})
+
+	default:
+		if ref.JobID == 0 {
+			return nil, errors.New("jobID is required")
+		}
+		if err := h.db.First(&models.Job{}, ref.JobID).Error; err != nil {
+			return ref.JobID, syncLookupError("job", err)
+		}
+		return ref.JobID, h.jobRepo.Delete(ref.JobID)
+	}
+}
+
+// clearJobAssociations drops the customer, status and devices queued job data
+// may embed, so saving the job doesn't write them as well
+func clearJobAssociations(job *models.Job) {
+	job.Customer = models.Customer{}
+	job.Status = models.Status{}
+	job.JobDevices = nil
+}
+
+// validateSyncJob applies the checks of the job API to queued job data
+func validateSyncJob(data []byte, creating bool) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("invalid job data: %v", err)
+	}
+	// The API checks plain dates; normalizeSyncDates made them timestamps
+	for _, key := range []string{"startDate", "endDate"} {
+		if value, ok := fields[key].(string); ok {
+			if date, err := time.Parse(time.RFC3339, value); err == nil {
+				fields[key] = date.Format("2006-01-02")
+			}
+		}
+	}
+
+	invalid := validateJobRequest(fields, creating)
+	if len(invalid) == 0 {
+		return nil
+	}
+	messages := make([]string, len(invalid))
+	for i, field := range invalid {
+		messages[i] = field.Message
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+func (h *PWAHandler) applyJobDeviceSync(action string, data []byte) (interface{}, error) {
+	var assignment struct {
+		JobID    uint     `json:"jobID"`
+		DeviceID string   `json:"deviceID"`
+		Price    *float64 `json:"price"`
+	}
+	if err := json.Unmarshal(data, &assignment); err != nil {
+		return nil, fmt.Errorf("invalid assignment data: %v", err)
+	}
+	if assignment.JobID == 0 || assignment.DeviceID == "" {
+		return nil, errors.New("jobID and deviceID are required")
+	}
+	entityID := fmt.Sprintf("%d/%s", assignment.JobID, assignment.DeviceID)
+
+	var err error
+	switch action {
+	case models.SyncActionCreate:
+		price := 0.0
+		if assignment.Price != nil {
+			price = *assignment.Price
+		}
+		err = h.jobRepo.AssignDevice(assignment.JobID, assignment.DeviceID, price)
+	case models.SyncActionUpdate:
+		if assignment.Price == nil {
+			return entityID, errors.New("price is required")
+		}
+		err = h.jobRepo.UpdateDevicePrice(assignment.JobID, assignment.DeviceID, *assignment.Price)
+	default:
+		err = h.jobRepo.RemoveDevice(assignment.JobID, assignment.DeviceID)
+	}
+	if err == nil {
+		invalidateDeviceCaches()
+	}
+	return entityID, err
+}
+
+func (h *PWAHandler) applyDeviceSync(action string, data []byte) (interface{}, error) {
+	var ref struct {
+		DeviceID string `json:"deviceID"`
+	}
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return nil, fmt.Errorf("invalid device data: %v", err)
+	}
+	if ref.DeviceID == "" && action != models.SyncActionCreate {
+		return nil, errors.New("deviceID is required")
+	}
+
+	var err error
+	switch action {
+	case models.SyncActionCreate:
+		var device models.Device
+		if err := json.Unmarshal(data, &device); err != nil {
+			return nil, fmt.Errorf("invalid device data: %v", err)
+		}
+		device.Product = nil
+		if err := h.deviceRepo.Create(&device); err != nil {
+			return nil, err
+		}
+		ref.DeviceID = device.DeviceID
+
+	case models.SyncActionUpdate:
+		var device models.Device
+		if err := h.db.Where("deviceID = ?", ref.DeviceID).First(&device).Error; err != nil {
+			return ref.DeviceID, syncLookupError("device", err)
+		}
+		if err := json.Unmarshal(data, &device); err != nil {
+			return ref.DeviceID, fmt.Errorf("invalid device data: %v", err)
+		}
+		device.DeviceID = ref.DeviceID
+		device.Product = nil
+		err = h.deviceRepo.Update(&device)
+
+	default:
+		if err := h.db.Where("deviceID = ?", ref.DeviceID).First(&models.Device{}).Error; err != nil {
+			return ref.DeviceID, syncLookupError("device", err)
+		}
+		err = h.deviceRepo.Delete(ref.DeviceID)
+	}
+	if err == nil {
+		invalidateDeviceCaches()
+	}
+	return ref.DeviceID, err
+}
+
+func (h *PWAHandler) applyCustomerSync(action string, data []byte) (interface{}, error) {
+	var ref struct {
+		CustomerID uint `json:"customerID"`
+	}
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return nil, fmt.Errorf("invalid customer data: %v", err)
+	}
+
+	switch action {
+	case models.SyncActionCreate:
+		var customer models.Customer
+		if err := json.Unmarshal(data, &customer); err != nil {
+			return nil, fmt.Errorf("invalid customer data: %v", err)
+		}
+		customer.CustomerID = 0
+		if err := h.customerRepo.Create(&customer); err != nil {
+			return nil, err
+		}
+		return customer.CustomerID, nil
+
+	case models.SyncActionUpdate:
+		if ref.CustomerID == 0 {
+			return nil, errors.New("customerID is required")
+		}
+		customer, err := h.customerRepo.GetByID(ref.CustomerID)
+		if err != nil {
+			return ref.CustomerID, syncLookupError("customer", err)
+		}
+		if err := json.Unmarshal(data, customer); err != nil {
+			return ref.CustomerID, fmt.Errorf("invalid customer data: %v", err)
+		}
+		customer.CustomerID = ref.CustomerID
+		return ref.CustomerID, h.customerRepo.Update(customer)
+
+	default:
+		if ref.CustomerID == 0 {
+			return nil, errors.New("customerID is required")
+		}
+		if _, err := h.customerRepo.GetByID(ref.CustomerID); err != nil {
+			return ref.CustomerID, syncLookupError("customer", err)
+		}
+		return ref.CustomerID, h.customerRepo.Delete(ref.CustomerID)
+	}
+}
+
+// syncLookupError names the missing entity, e.g. when it was deleted on the
+// server while the app was offline
+func syncLookupError(entity string, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s not found", entity)
+	}
+	return err
+}
+
+// syncDateFields are entity fields the app sends as plain YYYY-MM-DD dates
+var syncDateFields = []string{"startDate", "endDate", "purchaseDate", "lastmaintenance", "nextmaintenance"}
+
+// normalizeSyncDates turns plain dates in the queued entity data into the
+// RFC 3339 timestamps the models decode
+func normalizeSyncDates(data json.RawMessage) ([]byte, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("entityData must be a JSON object: %v", err)
+	}
+	for _, key := range syncDateFields {
+		value, ok := fields[key].(string)
+		if !ok {
+			continue
+		}
+		if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+			fields[key] = date.Format(time.RFC3339)
+		}
+	}
+	return json.Marshal(fields)
+}
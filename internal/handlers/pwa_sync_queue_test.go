@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-barcode-webapp/internal/models"
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeSyncQueue answers the queries of replaying one queued action and
+// records every statement. Job 9 exists when jobUpdatedAt is set.
+type fakeSyncQueue struct {
+	action       string
+	entityType   string
+	entityData   string
+	retryCount   int
+	jobUpdatedAt time.Time
+
+	mu         sync.Mutex
+	statements []string
+}
+
+func (f *fakeSyncQueue) answer(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	f.mu.Lock()
+	f.statements = append(f.statements, query)
+	f.mu.Unlock()
+
+	if strings.HasPrefix(query, "SELECT * FROM `offline_sync_queue`") {
+		return []string{"queueID", "userID", "action", "entity_type", "entity_data", "timestamp", "retry_count", "status"},
+			[][]driver.Value{{int64(1), int64(1), f.action, f.entityType, []byte(f.entityData),
+				time.Now().Add(-time.Hour), int64(f.retryCount), models.SyncQueueStatusPending}}, nil
+	}
+	if strings.HasPrefix(query, "SELECT * FROM `jobs`") && !f.jobUpdatedAt.IsZero() {
+		return []string{"jobID", "customerID", "statusID", "discount_type", "updated_at"},
+			[][]driver.Value{{int64(9), int64(3), int64(1), models.DiscountTypeAmount, f.jobUpdatedAt}}, nil
+	}
+	// Other jobs, customers and statuses don't exist; writes succeed
+	return nil, nil, nil
+}
+
+// statement returns the first recorded statement starting with prefix
+func (f *fakeSyncQueue) statement(prefix string) string {
+	if i := f.index(prefix, 0); i >= 0 {
+		return f.statements[i]
+	}
+	return ""
+}
+
+// transactionEnd returns the position and statement of the first COMMIT or
+// ROLLBACK after position from
+func (f *fakeSyncQueue) transactionEnd(from int) (int, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := from; i < len(f.statements); i++ {
+		if f.statements[i] == "COMMIT" || f.statements[i] == "ROLLBACK" {
+			return i, f.statements[i]
+		}
+	}
+	return -1, ""
+}
+
+// index returns the position of the first statement from position from on
+// that starts with prefix and contains all of parts, or -1
+func (f *fakeSyncQueue) index(prefix string, from int, parts ...string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+next:
+	for i := from; i < len(f.statements); i++ {
+		if !strings.HasPrefix(f.statements[i], prefix) {
+			continue
+		}
+		for _, part := range parts {
+			if !strings.Contains(f.statements[i], part) {
+				continue next
+			}
+		}
+		return i
+	}
+	return -1
+}
+
+func replaySyncQueue(t *testing.T, queue *fakeSyncQueue) *httptest.ResponseRecorder {
+	t.Helper()
+	db := newFakeDB(t, queue.answer)
+	handler := NewPWAHandler(repository.NewJobRepository(db, nil), nil, nil, db.DB, nil)
+
+	router := gin.New()
+	router.POST("/pwa/sync/queue", func(c *gin.Context) {
+		c.Set("user", models.User{UserID: 1, Username: "admin"})
+		handler.ProcessSyncQueue(c)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pwa/sync/queue", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("sync = %d: %s; want 200", w.Code, w.Body.String())
+	}
+	return w
+}
+
+func TestSyncQueueGivesUpAsFailed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	queue := &fakeSyncQueue{
+		action:     models.SyncActionUpdate,
+		entityType: "job",
+		entityData: `{"jobID": 9, "description": "Stage"}`,
+		retryCount: maxSyncRetries - 1,
+	}
+
+	w := replaySyncQueue(t, queue)
+	if !strings.Contains(w.Body.String(), `"failed":1`) || !strings.Contains(w.Body.String(), `"synced":0`) {
+		t.Errorf("sync = %s; want one failed and none synced", w.Body.String())
+	}
+
+	update := queue.statement("UPDATE `offline_sync_queue`")
+	if !strings.Contains(update, "`status`=") || strings.Contains(update, "`synced`=") {
+		t.Errorf("queue update = %q; want the status set and synced left alone", update)
+	}
+}
+
+func TestSyncQueueJobCreateSkipsAssociations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	queue := &fakeSyncQueue{
+		action:     models.SyncActionCreate,
+		entityType: "job",
+		entityData: `{"customerID": 3, "statusID": 1, "startDate": "2026-05-01", "endDate": "2026-05-03",
+			"customer": {"customerID": 3, "companyname": "Acme"}, "status": {"statusID": 1, "status": "open"}}`,
+	}
+
+	w := replaySyncQueue(t, queue)
+	if !strings.Contains(w.Body.String(), `"synced":1`) {
+		t.Fatalf("sync = %s; want the job created", w.Body.String())
+	}
+	if queue.statement("INSERT INTO `jobs`") == "" {
+		t.Error("job was not inserted")
+	}
+	for _, table := range []string{"`customers`", "`status`"} {
+		if statement := queue.statement("INSERT INTO " + table); statement != "" {
+			t.Errorf("queued job data wrote %s: %q", table, statement)
+		}
+	}
+}
+
+func TestSyncQueueConflictCheckLocksUntilApplied(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		serverChange time.Time
+		wantApplied  bool
+	}{
+		{"unchanged on the server", base, true},
+		{"changed on the server", base.Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &fakeSyncQueue{
+				action:       models.SyncActionUpdate,
+				entityType:   "job",
+				entityData:   `{"jobID": 9, "description": "Stage", "updated_at": "` + base.Format(time.RFC3339) + `"}`,
+				jobUpdatedAt: tt.serverChange,
+			}
+			replaySyncQueue(t, queue)
+
+			begin := queue.index("BEGIN", 0)
+			lock := queue.index("SELECT * FROM `jobs`", 0, "FOR UPDATE")
+			if begin < 0 || lock < begin {
+				t.Fatalf("conflict check = %d, BEGIN = %d; want a locking read in a transaction", lock, begin)
+			}
+			update := queue.index("UPDATE `jobs`", lock)
+			end, outcome := queue.transactionEnd(lock)
+
+			if tt.wantApplied && (update < 0 || end < update || outcome != "COMMIT") {
+				t.Errorf("update = %d, %s = %d; want the update committed with the lock", update, outcome, end)
+			}
+			if !tt.wantApplied && (update >= 0 || outcome != "ROLLBACK") {
+				t.Errorf("update = %d, %s = %d; want no update and a rollback", update, outcome, end)
+			}
+		})
+	}
+}
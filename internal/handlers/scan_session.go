@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Scan session event types pushed to connected scanners
+const (
+	ScanEventReady    = "ready"
+	ScanEventAssigned = "assigned"
+	ScanEventConflict = "conflict"
+	ScanEventError    = "error"
+)
+
+// ScanEvent is the result of one scan in a scanner session
+type ScanEvent struct {
+	Type        string    `json:"type"`
+	JobID       uint      `json:"job_id"`
+	Code        string    `json:"code,omitempty"`
+	DeviceID    string    `json:"device_id,omitempty"`
+	ProductName string    `json:"product_name,omitempty"`
+	Message     string    `json:"message"`
+	ConflictJob uint      `json:"conflict_job,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// scanSessionHub fans scan results out to every scanner connected to a job
+type scanSessionHub struct {
+	mu          sync.Mutex
+	subscribers map[uint]map[chan ScanEvent]struct{}
+}
+
+var scanSessions = &scanSessionHub{subscribers: make(map[uint]map[chan ScanEvent]struct{})}
+
+func (hub *scanSessionHub) subscribe(jobID uint) chan ScanEvent {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	ch := make(chan ScanEvent, 16)
+	if hub.subscribers[jobID] == nil {
+		hub.subscribers[jobID] = make(map[chan ScanEvent]struct{})
+	}
+	hub.subscribers[jobID][ch] = struct{}{}
+	return ch
+}
+
+func (hub *scanSessionHub) unsubscribe(jobID uint, ch chan ScanEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	delete(hub.subscribers[jobID], ch)
+	if len(hub.subscribers[jobID]) == 0 {
+		delete(hub.subscribers, jobID)
+	}
+}
+
+// publish sends the event to all of the job's subscribers. Slow subscribers
+// miss events rather than blocking the scan.
+func (hub *scanSessionHub) publish(event ScanEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for ch := range hub.subscribers[event.JobID] {
+		select {
+		case ch <- event:
+		default:
+			logger.Warnf("Scan session: dropping event for slow subscriber on job %d", event.JobID)
+		}
+	}
+}
+
+// ScanSessionStream streams scan results for a job as server-sent events so
+// operators get immediate feedback while scanning continuously
+func (h *ScannerHandler) ScanSessionStream(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+	if _, err := h.jobRepo.GetByID(uint(jobID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	events := scanSessions.subscribe(uint(jobID))
+	defer scanSessions.unsubscribe(uint(jobID), events)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent(ScanEventReady, ScanEvent{Type: ScanEventReady, JobID: uint(jobID), Message: "Scan session started", Time: time.Now()})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(25 * time.Second)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-events:
+			c.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			// Comment line keeps proxies from closing an idle connection
+			io.WriteString(w, ": ping\n\n")
+			return true
+		}
+	})
+}
+
+type ScanSessionRequest struct {
+	Code  string   `json:"code" binding:"required"`
+	Price *float64 `json:"price"`
+}
+
+// ScanSessionScan assigns a scanned code to the job and pushes the outcome to
+// the job's scan session stream. The outcome is also returned directly.
+func (h *ScannerHandler) ScanSessionScan(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	var req ScanSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := h.assignScannedDevice(uint(jobID), req.Code, req.Price)
+
+	event := ScanEvent{
+		JobID:       uint(jobID),
+		Code:        req.Code,
+		ConflictJob: result.ConflictJobID,
+		Time:        time.Now(),
+	}
+	if result.Device != nil {
+		event.DeviceID = result.Device.DeviceID
+		if result.Device.Product != nil {
+			event.ProductName = result.Device.Product.Name
+		}
+	}
+	switch result.Status {
+	case http.StatusOK:
+		event.Type = ScanEventAssigned
+		event.Message = "Device successfully assigned to job"
+	case http.StatusConflict:
+		event.Type = ScanEventConflict
+		event.Message = result.Error
+	default:
+		event.Type = ScanEventError
+		event.Message = result.Error
+	}
+
+	scanSessions.publish(event)
+	c.JSON(result.Status, event)
+}
@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 
@@ -45,7 +45,7 @@ func (h *ScanBoardHandler) GetScanBoardData(c *gin.Context) {
 	// Get devices for this job with pack status
 	devices, err := h.getScanBoardDevices(uint(jobID))
 	if err != nil {
-		fmt.Printf("Error getting scan board devices: %v\n", err)
+		logger.Errorf("Error getting scan board devices: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load devices"})
 		return
 	}
@@ -88,7 +88,7 @@ func (h *ScanBoardHandler) ScanDevice(c *gin.Context) {
 	// Validate that device belongs to this job
 	exists, err := h.deviceBelongsToJob(deviceID, uint(jobID))
 	if err != nil {
-		fmt.Printf("Error checking device job membership: %v\n", err)
+		logger.Errorf("Error checking device job membership: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
@@ -101,7 +101,7 @@ func (h *ScanBoardHandler) ScanDevice(c *gin.Context) {
 	// Update pack status to 'packed'
 	err = h.updatePackStatus(uint(jobID), deviceID, "packed")
 	if err != nil {
-		fmt.Printf("Error updating pack status: %v\n", err)
+		logger.Errorf("Error updating pack status: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pack status"})
 		return
 	}
@@ -109,7 +109,7 @@ func (h *ScanBoardHandler) ScanDevice(c *gin.Context) {
 	// Log the event
 	err = h.logDeviceEvent(uint(jobID), deviceID, "scanned", "system")
 	if err != nil {
-		fmt.Printf("Error logging device event: %v\n", err)
+		logger.Errorf("Error logging device event: %v", err)
 		// Don't fail the request for logging errors
 	}
 
@@ -138,7 +138,7 @@ func (h *ScanBoardHandler) FinishPack(c *gin.Context) {
 	// Check for missing items
 	missingItems, err := h.getMissingItems(uint(jobID))
 	if err != nil {
-		fmt.Printf("Error getting missing items: %v\n", err)
+		logger.Errorf("Error getting missing items: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check missing items"})
 		return
 	}
@@ -157,7 +157,7 @@ func (h *ScanBoardHandler) FinishPack(c *gin.Context) {
 	if finishReq.Force && len(missingItems) > 0 {
 		err = h.markAllAsPacked(uint(jobID))
 		if err != nil {
-			fmt.Printf("Error marking all as packed: %v\n", err)
+			logger.Errorf("Error marking all as packed: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to finish packing"})
 			return
 		}
@@ -166,7 +166,7 @@ func (h *ScanBoardHandler) FinishPack(c *gin.Context) {
 	// Log completion event
 	err = h.logJobEvent(uint(jobID), "pack_completed")
 	if err != nil {
-		fmt.Printf("Error logging job completion: %v\n", err)
+		logger.Errorf("Error logging job completion: %v", err)
 	}
 
 	c.JSON(http.StatusOK, models.FinishPackResponse{
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 
@@ -19,15 +20,17 @@ type ScannerHandler struct {
 	customerRepo      *repository.CustomerRepository
 	caseRepo          *repository.CaseRepository
 	rentalEquipmentRepo *repository.RentalEquipmentRepository
+	packageRepo       *repository.EquipmentPackageRepository
 }
 
-func NewScannerHandler(jobRepo *repository.JobRepository, deviceRepo *repository.DeviceRepository, customerRepo *repository.CustomerRepository, caseRepo *repository.CaseRepository, rentalEquipmentRepo *repository.RentalEquipmentRepository) *ScannerHandler {
+func NewScannerHandler(jobRepo *repository.JobRepository, deviceRepo *repository.DeviceRepository, customerRepo *repository.CustomerRepository, caseRepo *repository.CaseRepository, rentalEquipmentRepo *repository.RentalEquipmentRepository, packageRepo *repository.EquipmentPackageRepository) *ScannerHandler {
 	return &ScannerHandler{
 		deviceRepo:        deviceRepo,
 		jobRepo:           jobRepo,
 		customerRepo:      customerRepo,
 		caseRepo:          caseRepo,
 		rentalEquipmentRepo: rentalEquipmentRepo,
+		packageRepo:       packageRepo,
 	}
 }
 
@@ -38,7 +41,7 @@ func (h *ScannerHandler) ScanJobSelection(c *gin.Context) {
 	err := h.jobRepo.FreeDevicesFromCompletedJobs()
 	if err != nil {
 		// Log error but don't fail the request
-		fmt.Printf("Warning: Failed to free devices from completed jobs: %v\n", err)
+		logger.Warnf("Failed to free devices from completed jobs: %v", err)
 	}
 	
 	// Get all jobs first
@@ -110,19 +113,19 @@ func (h *ScannerHandler) ScanJob(c *gin.Context) {
 	}
 
 	// Debug logging for customer
-	fmt.Printf("🔧 DEBUG ScanJob: Job %d has CustomerID: %d\n", jobID, job.CustomerID)
-	fmt.Printf("🔧 DEBUG ScanJob: Customer loaded - ID: %d, Company: %v, FirstName: %v, LastName: %v\n", 
+	logger.Debugf("ScanJob: Job %d has CustomerID: %d", jobID, job.CustomerID)
+	logger.Debugf("ScanJob: Customer loaded - ID: %d, Company: %v, FirstName: %v, LastName: %v", 
 		job.Customer.CustomerID, job.Customer.CompanyName, job.Customer.FirstName, job.Customer.LastName)
-	fmt.Printf("🔧 DEBUG ScanJob: GetDisplayName returns: '%s'\n", job.Customer.GetDisplayName())
+	logger.Debugf("ScanJob: GetDisplayName returns: '%s'", job.Customer.GetDisplayName())
 	
 	// Try to manually load customer if the preloaded one is empty
 	if job.Customer.CustomerID == 0 && job.CustomerID > 0 {
-		fmt.Printf("🔧 DEBUG ScanJob: Customer not preloaded, trying manual load for CustomerID: %d\n", job.CustomerID)
+		logger.Debugf("ScanJob: Customer not preloaded, trying manual load for CustomerID: %d", job.CustomerID)
 		customer, err := h.customerRepo.GetByID(job.CustomerID)
 		if err != nil {
-			fmt.Printf("🔧 DEBUG ScanJob: Failed to manually load customer: %v\n", err)
+			logger.Errorf("ScanJob: Failed to manually load customer: %v", err)
 		} else {
-			fmt.Printf("🔧 DEBUG ScanJob: Manually loaded customer - ID: %d, Company: %v, FirstName: %v, LastName: %v\n", 
+			logger.Debugf("ScanJob: Manually loaded customer - ID: %d, Company: %v, FirstName: %v, LastName: %v", 
 				customer.CustomerID, customer.CompanyName, customer.FirstName, customer.LastName)
 			job.Customer = *customer
 		}
@@ -186,110 +189,138 @@ type ScanCaseRequest struct {
 	CaseID uint `json:"case_id" binding:"required"`
 }
 
+// ScanPackageRequest is the body of POST /api/v1/jobs/:id/assign-package. The
+// job comes from the URL; job_id is optional and must match it when given.
+type ScanPackageRequest struct {
+	JobID       uint   `json:"job_id"`
+	PackageCode string `json:"package_code" binding:"required"`
+}
+
 func (h *ScannerHandler) ScanDevice(c *gin.Context) {
-	fmt.Printf("🚨 DEBUG SCANNER: ScanDevice called!\n")
+	logger.Debugf("SCANNER: ScanDevice called!")
 	
 	var req ScanDeviceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("❌ DEBUG SCANNER: JSON binding error: %v\n", err)
+		logger.Errorf("SCANNER: JSON binding error: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	fmt.Printf("🚨 DEBUG SCANNER: Request - JobID: %d, DeviceID: %s\n", req.JobID, req.DeviceID)
+	result := h.assignScannedDevice(req.JobID, req.DeviceID, req.Price)
+	if result.Status != http.StatusOK {
+		body := gin.H{"error": result.Error}
+		if result.Details != "" {
+			body["details"] = result.Details
+			body["device_id"] = result.Device.DeviceID
+		} else if result.Status == http.StatusConflict && result.Device != nil && result.ConflictJobID == 0 {
+			body["device"] = result.Device
+		}
+		c.JSON(result.Status, body)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Device successfully assigned to job",
+		"device":  result.Device,
+		"price":   result.Price,
+	})
+}
+
+// scanAssignResult is the outcome of assigning a scanned device to a job
+type scanAssignResult struct {
+	Status        int // HTTP status describing the outcome
+	Device        *models.Device
+	Price         float64
+	ConflictJobID uint
+	Error         string
+	Details       string
+}
+
+// assignScannedDevice resolves a scanned code (device ID, serial number or QR payload), checks
+// the device's availability for the job's dates and assigns it
+func (h *ScannerHandler) assignScannedDevice(jobID uint, code string, customPrice *float64) scanAssignResult {
+	logger.Debugf("SCANNER: Request - JobID: %d, DeviceID: %s", jobID, code)
 
-	// Try to get device by ID first, then by serial number
+	// Try to get device by ID first, then by serial number, then by QR code
 	var device *models.Device
 	var err error
 
-	device, err = h.deviceRepo.GetByID(req.DeviceID)
+	device, err = h.deviceRepo.GetByID(code)
 	if err != nil {
 		// Try by serial number
-		device, err = h.deviceRepo.GetBySerialNo(req.DeviceID)
+		device, err = h.deviceRepo.GetBySerialNo(code)
+	}
+	if err != nil {
+		// Try by QR payload
+		device, err = h.deviceRepo.GetByQRCode(code)
 		if err != nil {
-			fmt.Printf("❌ DEBUG SCANNER: Device not found: %v\n", err)
-			c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
-			return
+			logger.Errorf("SCANNER: Device not found: %v", err)
+			return scanAssignResult{Status: http.StatusNotFound, Error: "Device not found"}
 		}
 	}
 
-	fmt.Printf("✅ DEBUG SCANNER: Device found: %s\n", device.DeviceID)
+	logger.Debugf("SCANNER: Device found: %s", device.DeviceID)
 
 	// Get job details to check date range
-	job, err := h.jobRepo.GetByID(req.JobID)
+	job, err := h.jobRepo.GetByID(jobID)
 	if err != nil {
-		fmt.Printf("❌ DEBUG SCANNER: Job not found: %v\n", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
-		return
+		logger.Errorf("SCANNER: Job not found: %v", err)
+		return scanAssignResult{Status: http.StatusNotFound, Device: device, Error: "Job not found"}
 	}
 
-	fmt.Printf("🚨 DEBUG SCANNER: Job %d dates: %v to %v\n", req.JobID, job.StartDate, job.EndDate)
+	logger.Debugf("SCANNER: Job %d dates: %v to %v", jobID, job.StartDate, job.EndDate)
 
 	// Check if device is available for this job's date range
-	fmt.Printf("🔍 DEBUG SCANNER: Checking availability for device %s, job %d, dates: %v to %v\n",
-		device.DeviceID, req.JobID, job.StartDate, job.EndDate)
+	logger.Debugf("SCANNER: Checking availability for device %s, job %d, dates: %v to %v",
+		device.DeviceID, jobID, job.StartDate, job.EndDate)
 
-	isAvailable, conflictingAssignment, err := h.deviceRepo.IsDeviceAvailableForJob(device.DeviceID, req.JobID, job.StartDate, job.EndDate)
+	isAvailable, conflictingAssignment, err := h.deviceRepo.IsDeviceAvailableForJob(device.DeviceID, jobID, job.StartDate, job.EndDate)
 	if err != nil {
-		fmt.Printf("❌ DEBUG SCANNER: Availability check error: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to check device availability",
-			"details": err.Error(),
-			"device_id": device.DeviceID,
-		})
-		return
+		logger.Errorf("SCANNER: Availability check error: %v", err)
+		return scanAssignResult{
+			Status:  http.StatusInternalServerError,
+			Device:  device,
+			Error:   "Failed to check device availability",
+			Details: err.Error(),
+		}
 	}
 
-	fmt.Printf("🚨 DEBUG SCANNER: Device available: %t\n", isAvailable)
+	logger.Debugf("SCANNER: Device available: %t", isAvailable)
 
 	if !isAvailable {
+		result := scanAssignResult{Status: http.StatusConflict, Device: device, Error: "Device is not available"}
 		if conflictingAssignment != nil {
+			result.ConflictJobID = conflictingAssignment.JobID
 			// Get conflicting job details for error message
 			conflictingJob, _ := h.jobRepo.GetByID(conflictingAssignment.JobID)
-			if conflictingJob != nil && conflictingAssignment.JobID == req.JobID {
-				c.JSON(http.StatusConflict, gin.H{
-					"error": fmt.Sprintf("Device is already assigned to this job #%d", req.JobID),
-				})
+			if conflictingJob != nil && conflictingAssignment.JobID == jobID {
+				result.Error = fmt.Sprintf("Device is already assigned to this job #%d", jobID)
 			} else if conflictingJob != nil {
-				c.JSON(http.StatusConflict, gin.H{
-					"error": fmt.Sprintf("Device is already assigned to job #%d from %s to %s", 
-						conflictingAssignment.JobID,
-						conflictingJob.StartDate.Format("2006-01-02"),
-						conflictingJob.EndDate.Format("2006-01-02")),
-				})
+				result.Error = fmt.Sprintf("Device is already assigned to job #%d from %s to %s", 
+					conflictingAssignment.JobID,
+					conflictingJob.StartDate.Format("2006-01-02"),
+					conflictingJob.EndDate.Format("2006-01-02"))
 			} else {
-				c.JSON(http.StatusConflict, gin.H{
-					"error": fmt.Sprintf("Device is already assigned to job #%d", conflictingAssignment.JobID),
-				})
+				result.Error = fmt.Sprintf("Device is already assigned to job #%d", conflictingAssignment.JobID)
 			}
-		} else {
-			c.JSON(http.StatusConflict, gin.H{
-				"error":  "Device is not available",
-				"device": device,
-			})
 		}
-		return
+		return result
 	}
 
 	// Assign device to job
 	var price float64
 	// Only use custom price if explicitly provided, otherwise pass 0 (which means NULL in DB)
-	if req.Price != nil {
-		price = *req.Price
+	if customPrice != nil {
+		price = *customPrice
 	} else {
 		price = 0.0 // This will result in NULL custom_price in database
 	}
 
-	if err := h.jobRepo.AssignDevice(req.JobID, device.DeviceID, price); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	if err := h.jobRepo.AssignDevice(jobID, device.DeviceID, price); err != nil {
+		return scanAssignResult{Status: http.StatusInternalServerError, Device: device, Error: err.Error()}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Device successfully assigned to job",
-		"device":  device,
-		"price":   price,
-	})
+	return scanAssignResult{Status: http.StatusOK, Device: device, Price: price}
 }
 
 func (h *ScannerHandler) RemoveDevice(c *gin.Context) {
@@ -435,6 +466,126 @@ func (h *ScannerHandler) ScanCase(c *gin.Context) {
 	})
 }
 
+// ScanPackage expands a scanned kit code into its package devices and assigns them
+// all to the job. Devices already on the job are skipped; if a required device is
+// unavailable nothing is assigned.
+func (h *ScannerHandler) ScanPackage(c *gin.Context) {
+	jobID64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+	jobID := uint(jobID64)
+
+	var req ScanPackageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.JobID != 0 && req.JobID != jobID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "job_id does not match the job in the URL"})
+		return
+	}
+
+	pkg, err := h.packageRepo.GetByKitCode(req.PackageCode)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kit not found", "details": err.Error()})
+		return
+	}
+
+	if len(pkg.PackageDevices) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Kit is empty - no devices to assign"})
+		return
+	}
+
+	job, err := h.jobRepo.GetByID(jobID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	var results []map[string]interface{}
+	var toAssign []models.JobDevice
+	var blocking []string
+	skippedCount := 0
+
+	for _, pd := range pkg.PackageDevices {
+		isAvailable, conflictingAssignment, err := h.deviceRepo.IsDeviceAvailableForJob(pd.DeviceID, jobID, job.StartDate, job.EndDate)
+
+		switch {
+		case err == nil && isAvailable:
+			toAssign = append(toAssign, models.JobDevice{DeviceID: pd.DeviceID, CustomPrice: pd.CustomPrice})
+			continue
+		case err == nil && conflictingAssignment != nil && conflictingAssignment.JobID == jobID:
+			results = append(results, map[string]interface{}{
+				"device_id": pd.DeviceID,
+				"success":   true,
+				"message":   "Device is already assigned to this job",
+			})
+			skippedCount++
+			continue
+		}
+
+		message := "Device is not available"
+		if err != nil {
+			message = err.Error()
+		} else if conflictingAssignment != nil {
+			message = fmt.Sprintf("Device is already assigned to job #%d", conflictingAssignment.JobID)
+		}
+
+		results = append(results, map[string]interface{}{
+			"device_id": pd.DeviceID,
+			"success":   false,
+			"required":  pd.IsRequired,
+			"message":   message,
+		})
+		if pd.IsRequired {
+			blocking = append(blocking, pd.DeviceID)
+		}
+	}
+
+	if len(blocking) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":            fmt.Sprintf("Kit cannot be assigned: required devices unavailable (%s)", strings.Join(blocking, ", ")),
+			"package_id":       pkg.PackageID,
+			"package_name":     pkg.Name,
+			"blocking_devices": blocking,
+			"results":          results,
+		})
+		return
+	}
+
+	if len(toAssign) > 0 {
+		if err := h.jobRepo.AssignDevices(jobID, toAssign); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := h.packageRepo.IncrementUsageCount(pkg.PackageID); err != nil {
+			logger.Warnf("ScanPackage: failed to update usage count for package %d: %v", pkg.PackageID, err)
+		}
+	}
+
+	for _, jd := range toAssign {
+		results = append(results, map[string]interface{}{
+			"device_id": jd.DeviceID,
+			"success":   true,
+			"message":   "Device assigned successfully",
+		})
+	}
+
+	errorCount := len(pkg.PackageDevices) - len(toAssign) - skippedCount
+	c.JSON(http.StatusOK, gin.H{
+		"message":        fmt.Sprintf("Kit scan complete: %d devices assigned, %d already on job, %d unavailable", len(toAssign), skippedCount, errorCount),
+		"package_id":     pkg.PackageID,
+		"package_name":   pkg.Name,
+		"total_devices":  len(pkg.PackageDevices),
+		"assigned_count": len(toAssign),
+		"skipped_count":  skippedCount,
+		"error_count":    errorCount,
+		"results":        results,
+	})
+}
+
 // AddRentalToJob adds rental equipment to a job from the scan page
 func (h *ScannerHandler) AddRentalToJob(c *gin.Context) {
 	var request models.AddRentalToJobRequest
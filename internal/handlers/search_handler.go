@@ -2,15 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SearchHandler struct {
@@ -21,12 +24,42 @@ func NewSearchHandler(db *gorm.DB) *SearchHandler {
 	return &SearchHandler{db: db}
 }
 
-// GlobalSearch performs search across all entities
+// globalSearchPermissions lists the permissions of which the caller needs one
+// to see a result group of the global search
+var globalSearchPermissions = map[string][]string{
+	"jobs":      {"jobs.view", "jobs.manage"},
+	"devices":   {"devices.view", "devices.manage"},
+	"customers": {"customers.view", "customers.manage"},
+	"cases":     {"devices.view", "devices.manage"},
+}
+
+// canSearch reports whether the caller may see search results of the group
+func (h *SearchHandler) canSearch(c *gin.Context, group string) bool {
+	for _, permission := range globalSearchPermissions[group] {
+		if userHasPermission(h.db, c, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// GlobalSearch searches jobs, devices, customers and cases at once (or one of
+// them with type=). Each group is ranked best match first and has its own
+// total; groups the caller has no view permission for are left out.
 func (h *SearchHandler) GlobalSearch(c *gin.Context) {
-	query := c.Query("q")
+	query := strings.TrimSpace(c.Query("q"))
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "20"))
 	searchType := c.DefaultQuery("type", "global")
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
 
 	// For GET requests without query, always show search page (browser navigation)
 	if query == "" && c.Request.Method == "GET" {
@@ -47,28 +80,35 @@ func (h *SearchHandler) GlobalSearch(c *gin.Context) {
 		return
 	}
 
-	// Log search for analytics
-	currentUser, _ := GetCurrentUser(c)
-	h.logSearch(currentUser.UserID, query, searchType, page, pageSize)
-
+	started := time.Now()
 	results := make(map[string]interface{})
 	
-	if searchType == "global" || searchType == "jobs" {
+	if (searchType == "global" || searchType == "jobs") && h.canSearch(c, "jobs") {
 		results["jobs"] = h.searchJobs(query, page, pageSize)
 	}
-	
-	if searchType == "global" || searchType == "devices" {
+
+	if (searchType == "global" || searchType == "devices") && h.canSearch(c, "devices") {
 		results["devices"] = h.searchDevices(query, page, pageSize)
 	}
-	
-	if searchType == "global" || searchType == "customers" {
+
+	if (searchType == "global" || searchType == "customers") && h.canSearch(c, "customers") {
 		results["customers"] = h.searchCustomers(query, page, pageSize)
 	}
-	
-	if searchType == "global" || searchType == "cases" {
+
+	if (searchType == "global" || searchType == "cases") && h.canSearch(c, "cases") {
 		results["cases"] = h.searchCases(query, page, pageSize)
 	}
 
+	var resultsCount int64
+	for _, result := range results {
+		if section, ok := result.(map[string]interface{}); ok {
+			if total, ok := section["total"].(int64); ok {
+				resultsCount += total
+			}
+		}
+	}
+	h.recordSearch(c, query, searchType, nil, resultsCount, started)
+
 	// For GET requests, always return HTML (browser navigation)
 	// For other methods, return JSON (API calls)
 	if c.Request.Method == "GET" {
@@ -79,35 +119,60 @@ func (h *SearchHandler) GlobalSearch(c *gin.Context) {
 			"query":      query,
 			"searchType": searchType,
 			"results":    results,
+			"total":      resultsCount,
 		})
 	} else {
 		c.JSON(http.StatusOK, gin.H{
-			"query":   query,
-			"type":    searchType,
-			"page":    page,
-			"results": results,
+			"query":    query,
+			"type":     searchType,
+			"page":     page,
+			"pageSize": pageSize,
+			"results":  results,
+			"total":    resultsCount,
 		})
 	}
 }
 
-// searchJobs searches in jobs table
+// searchLikeEscaper escapes the LIKE wildcards in search terms
+var searchLikeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// searchPatterns returns the lower-cased LIKE patterns matching values that
+// start with and that contain the query
+func searchPatterns(query string) (prefix, contains string) {
+	escaped := searchLikeEscaper.Replace(strings.ToLower(query))
+	return escaped + "%", "%" + escaped + "%"
+}
+
+// rankedOrder orders search results by a CASE expression so the best matches
+// (exact, then prefix, then anywhere) come first
+func rankedOrder(sql string, vars ...interface{}) clause.OrderBy {
+	return clause.OrderBy{Expression: clause.Expr{SQL: sql, Vars: vars, WithoutParentheses: true}}
+}
+
+// searchJobs searches jobs by ID and description. An exact job ID ranks
+// first, then descriptions starting with the query, newest jobs first.
 func (h *SearchHandler) searchJobs(query string, page, pageSize int) map[string]interface{} {
 	var jobs []models.Job
 	var total int64
 
 	offset := (page - 1) * pageSize
-	searchTerm := "%" + strings.ToLower(query) + "%"
+	prefix, contains := searchPatterns(query)
+	where := "LOWER(description) LIKE ? OR jobID = ?"
 
 	// Count total
-	h.db.Model(&models.Job{}).
-		Where("LOWER(description) LIKE ? OR jobID = ?", searchTerm, query).
-		Count(&total)
+	if err := h.db.Model(&models.Job{}).Where(where, contains, query).Count(&total).Error; err != nil {
+		logger.Errorf("Global search: counting jobs failed: %v", err)
+	}
 
 	// Get results with pagination
-	h.db.Preload("Customer").Preload("Status").
-		Where("LOWER(description) LIKE ? OR jobID = ?", searchTerm, query).
+	if err := h.db.Preload("Customer").Preload("Status").
+		Where(where, contains, query).
+		Order(rankedOrder("CASE WHEN jobID = ? THEN 0 WHEN LOWER(description) LIKE ? THEN 1 ELSE 2 END", query, prefix)).
+		Order("jobID DESC").
 		Offset(offset).Limit(pageSize).
-		Find(&jobs)
+		Find(&jobs).Error; err != nil {
+		logger.Errorf("Global search: loading jobs failed: %v", err)
+	}
 
 	return map[string]interface{}{
 		"items": jobs,
@@ -116,28 +181,40 @@ func (h *SearchHandler) searchJobs(query string, page, pageSize int) map[string]
 	}
 }
 
-// searchDevices searches in devices table
+// searchDevices searches devices by ID, serial number and product name.
+// Exact IDs and serial numbers rank first, then prefix and substring matches
+// on them, then devices only found through their product name.
 func (h *SearchHandler) searchDevices(query string, page, pageSize int) map[string]interface{} {
 	var devices []models.Device
 	var total int64
 
 	offset := (page - 1) * pageSize
-	searchTerm := "%" + strings.ToLower(query) + "%"
+	prefix, contains := searchPatterns(query)
+	where := "LOWER(devices.deviceID) LIKE ? OR LOWER(devices.serialnumber) LIKE ? OR LOWER(products.name) LIKE ?"
 
 	// Count total
-	h.db.Model(&models.Device{}).
+	if err := h.db.Model(&models.Device{}).
 		Joins("LEFT JOIN products ON devices.productID = products.productID").
-		Where("LOWER(devices.deviceID) LIKE ? OR LOWER(devices.serialnumber) LIKE ? OR LOWER(products.name) LIKE ?", 
-			searchTerm, searchTerm, searchTerm).
-		Count(&total)
+		Where(where, contains, contains, contains).
+		Count(&total).Error; err != nil {
+		logger.Errorf("Global search: counting devices failed: %v", err)
+	}
 
 	// Get results with pagination
-	h.db.Preload("Product").
+	if err := h.db.Preload("Product").
 		Joins("LEFT JOIN products ON devices.productID = products.productID").
-		Where("LOWER(devices.deviceID) LIKE ? OR LOWER(devices.serialnumber) LIKE ? OR LOWER(products.name) LIKE ?", 
-			searchTerm, searchTerm, searchTerm).
+		Where(where, contains, contains, contains).
+		Order(rankedOrder(`CASE
+			WHEN LOWER(devices.deviceID) = ? OR LOWER(devices.serialnumber) = ? THEN 0
+			WHEN LOWER(devices.deviceID) LIKE ? OR LOWER(devices.serialnumber) LIKE ? THEN 1
+			WHEN LOWER(devices.deviceID) LIKE ? OR LOWER(devices.serialnumber) LIKE ? THEN 2
+			ELSE 3 END`,
+			strings.ToLower(query), strings.ToLower(query), prefix, prefix, contains, contains)).
+		Order("devices.deviceID ASC").
 		Offset(offset).Limit(pageSize).
-		Find(&devices)
+		Find(&devices).Error; err != nil {
+		logger.Errorf("Global search: loading devices failed: %v", err)
+	}
 
 	return map[string]interface{}{
 		"items": devices,
@@ -146,25 +223,35 @@ func (h *SearchHandler) searchDevices(query string, page, pageSize int) map[stri
 	}
 }
 
-// searchCustomers searches in customers table
+// searchCustomers searches customers by ID, company, first and last name and
+// email. An exact customer ID ranks first, then names starting with the query.
 func (h *SearchHandler) searchCustomers(query string, page, pageSize int) map[string]interface{} {
 	var customers []models.Customer
 	var total int64
 
 	offset := (page - 1) * pageSize
-	searchTerm := "%" + strings.ToLower(query) + "%"
+	prefix, contains := searchPatterns(query)
+	where := "LOWER(companyname) LIKE ? OR LOWER(firstname) LIKE ? OR LOWER(lastname) LIKE ? OR LOWER(email) LIKE ? OR customerID = ?"
 
 	// Count total
-	h.db.Model(&models.Customer{}).
-		Where("LOWER(companyname) LIKE ? OR LOWER(firstname) LIKE ? OR LOWER(lastname) LIKE ? OR LOWER(email) LIKE ? OR customerID = ?", 
-			searchTerm, searchTerm, searchTerm, searchTerm, query).
-		Count(&total)
+	if err := h.db.Model(&models.Customer{}).
+		Where(where, contains, contains, contains, contains, query).
+		Count(&total).Error; err != nil {
+		logger.Errorf("Global search: counting customers failed: %v", err)
+	}
 
 	// Get results with pagination
-	h.db.Where("LOWER(companyname) LIKE ? OR LOWER(firstname) LIKE ? OR LOWER(lastname) LIKE ? OR LOWER(email) LIKE ? OR customerID = ?", 
-		searchTerm, searchTerm, searchTerm, searchTerm, query).
+	if err := h.db.Where(where, contains, contains, contains, contains, query).
+		Order(rankedOrder(`CASE
+			WHEN customerID = ? THEN 0
+			WHEN LOWER(companyname) LIKE ? OR LOWER(firstname) LIKE ? OR LOWER(lastname) LIKE ? THEN 1
+			ELSE 2 END`,
+			query, prefix, prefix, prefix)).
+		Order("companyname ASC, lastname ASC").
 		Offset(offset).Limit(pageSize).
-		Find(&customers)
+		Find(&customers).Error; err != nil {
+		logger.Errorf("Global search: loading customers failed: %v", err)
+	}
 
 	return map[string]interface{}{
 		"items": customers,
@@ -179,7 +266,7 @@ func (h *SearchHandler) searchCases(query string, page, pageSize int) map[string
 	var total int64
 
 	offset := (page - 1) * pageSize
-	searchTerm := "%" + strings.ToLower(query) + "%"
+	_, searchTerm := searchPatterns(query)
 
 	// Count total
 	h.db.Model(&models.Case{}).
@@ -226,6 +313,7 @@ func (h *SearchHandler) AdvancedSearch(c *gin.Context) {
 		request.PageSize = 20
 	}
 
+	started := time.Now()
 	var results interface{}
 	var total int64
 
@@ -241,6 +329,8 @@ func (h *SearchHandler) AdvancedSearch(c *gin.Context) {
 		return
 	}
 
+	h.recordSearch(c, request.Query, request.Type, request.Filters, total, started)
+
 	// Save search if requested
 	if request.SaveSearch && request.SearchName != "" {
 		currentUser, _ := GetCurrentUser(c)
@@ -424,44 +514,178 @@ func (h *SearchHandler) advancedSearchCustomers(query string, filters map[string
 	return customers, total
 }
 
-// SavedSearches returns user's saved searches
-func (h *SearchHandler) SavedSearches(c *gin.Context) {
-	currentUser, _ := GetCurrentUser(c)
-	searchType := c.Query("type")
+// ListSavedSearches returns the current user's saved searches and those other
+// users made public, optionally only of one search type
+func (h *SearchHandler) ListSavedSearches(c *gin.Context) {
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		respondJSONError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
+		return
+	}
 
 	var savedSearches []models.SavedSearch
-	query := h.db.Where("userID = ?", currentUser.UserID)
-	
-	if searchType != "" {
+	query := h.db.Where("(userID = ? OR is_public = ?)", currentUser.UserID, true)
+
+	if searchType := c.Query("type"); searchType != "" {
 		query = query.Where("search_type = ?", searchType)
 	}
-	
-	query.Order("usage_count DESC, updated_at DESC").Find(&savedSearches)
+
+	if err := query.Order("is_default DESC, usage_count DESC, updated_at DESC").Find(&savedSearches).Error; err != nil {
+		logger.Errorf("Failed to list saved searches of user %d: %v", currentUser.UserID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load saved searches")
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"savedSearches": savedSearches})
 }
 
-// DeleteSavedSearch deletes a saved search
+// CreateSavedSearch saves a search of the current user
+func (h *SearchHandler) CreateSavedSearch(c *gin.Context) {
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		respondJSONError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req models.SavedSearchRequest
+	if !bindSavedSearchRequest(c, &req) {
+		return
+	}
+
+	savedSearch := models.SavedSearch{UserID: currentUser.UserID}
+	applySavedSearchRequest(&savedSearch, &req)
+
+	if err := h.storeSavedSearch(&savedSearch); err != nil {
+		logger.Errorf("Failed to create saved search for user %d: %v", currentUser.UserID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to save search")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"savedSearch": savedSearch})
+}
+
+// UpdateSavedSearch replaces one of the current user's saved searches
+func (h *SearchHandler) UpdateSavedSearch(c *gin.Context) {
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		respondJSONError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	searchID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid saved search ID")
+		return
+	}
+
+	var req models.SavedSearchRequest
+	if !bindSavedSearchRequest(c, &req) {
+		return
+	}
+
+	// Public searches of other users are readable but only their owner may change them
+	var savedSearch models.SavedSearch
+	if err := h.db.Where("searchID = ? AND userID = ?", searchID, currentUser.UserID).First(&savedSearch).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondJSONError(c, http.StatusNotFound, errCodeNotFound, "Saved search not found")
+			return
+		}
+		logger.Errorf("Failed to load saved search %d: %v", searchID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load saved search")
+		return
+	}
+
+	applySavedSearchRequest(&savedSearch, &req)
+
+	if err := h.storeSavedSearch(&savedSearch); err != nil {
+		logger.Errorf("Failed to update saved search %d: %v", searchID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to save search")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"savedSearch": savedSearch})
+}
+
+// DeleteSavedSearch deletes one of the current user's saved searches
 func (h *SearchHandler) DeleteSavedSearch(c *gin.Context) {
-	searchID := c.Param("id")
-	currentUser, _ := GetCurrentUser(c)
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		respondJSONError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	searchID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "Invalid saved search ID")
+		return
+	}
 
 	result := h.db.Where("searchID = ? AND userID = ?", searchID, currentUser.UserID).
 		Delete(&models.SavedSearch{})
 
 	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		logger.Errorf("Failed to delete saved search %d: %v", searchID, result.Error)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to delete saved search")
 		return
 	}
 
 	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		respondJSONError(c, http.StatusNotFound, errCodeNotFound, "Saved search not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted"})
 }
 
+// bindSavedSearchRequest binds and validates a saved search body, writing the
+// error response and returning false if it is invalid
+func bindSavedSearchRequest(c *gin.Context, req *models.SavedSearchRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		respondValidationError(c, err)
+		return false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		respondFieldErrors(c, []FieldError{{Field: "name", Rule: "required", Message: "name is required"}})
+		return false
+	}
+
+	if len(req.Filters) == 0 || string(req.Filters) == "null" {
+		req.Filters = json.RawMessage("{}")
+	} else if !json.Valid(req.Filters) {
+		respondFieldErrors(c, []FieldError{{Field: "filters", Rule: "json", Message: "filters must be well-formed JSON"}})
+		return false
+	}
+
+	return true
+}
+
+func applySavedSearchRequest(savedSearch *models.SavedSearch, req *models.SavedSearchRequest) {
+	savedSearch.Name = req.Name
+	savedSearch.SearchType = req.SearchType
+	savedSearch.Filters = req.Filters
+	savedSearch.IsDefault = req.IsDefault
+	savedSearch.IsPublic = req.IsPublic
+}
+
+// storeSavedSearch creates or updates a saved search. A user has at most one
+// default search per search type, so making this one the default clears the
+// flag on the user's other searches of that type in the same transaction.
+func (h *SearchHandler) storeSavedSearch(savedSearch *models.SavedSearch) error {
+	return h.db.Transaction(func(tx *gorm.DB) error {
+		if savedSearch.IsDefault {
+			if err := tx.Model(&models.SavedSearch{}).
+				Where("userID = ? AND search_type = ? AND is_default = ? AND searchID <> ?",
+					savedSearch.UserID, savedSearch.SearchType, true, savedSearch.SearchID).
+				Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(savedSearch).Error
+	})
+}
+
 // SearchSuggestions provides autocomplete suggestions
 func (h *SearchHandler) SearchSuggestions(c *gin.Context) {
 	query := c.Query("q")
@@ -508,15 +732,77 @@ func (h *SearchHandler) SearchSuggestions(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
 }
 
-// logSearch logs search for analytics
-func (h *SearchHandler) logSearch(userID uint, query, searchType string, page, pageSize int) {
-	searchHistory := models.SearchHistory{
-		UserID:     &userID,
-		SearchTerm: query,
-		SearchType: searchType,
-		SearchedAt: time.Now(),
+// maxSearchTermLength is the size of search_history.search_term
+const maxSearchTermLength = 500
+
+// recordSearch stores an executed search in the search history for the
+// recent searches list and search analytics. Failures are only logged so
+// they never affect the search itself.
+func (h *SearchHandler) recordSearch(c *gin.Context, term, searchType string, filters interface{}, resultsCount int64, started time.Time) {
+	entry := models.SearchHistory{
+		SearchTerm:      strings.TrimSpace(term),
+		SearchType:      searchType,
+		ResultsCount:    int(resultsCount),
+		ExecutionTimeMS: int(time.Since(started).Milliseconds()),
+		SearchedAt:      time.Now(),
+	}
+	if runes := []rune(entry.SearchTerm); len(runes) > maxSearchTermLength {
+		entry.SearchTerm = string(runes[:maxSearchTermLength])
 	}
-	h.db.Create(&searchHistory)
+	if currentUser, exists := GetCurrentUser(c); exists {
+		entry.UserID = &currentUser.UserID
+	}
+	if filters != nil {
+		if data, err := json.Marshal(filters); err == nil && string(data) != "null" {
+			entry.Filters = data
+		}
+	}
+
+	if err := h.db.Create(&entry).Error; err != nil {
+		logger.Warnf("Failed to record search history: %v", err)
+	}
+}
+
+// RecentSearch is a distinct search term with when it was last searched
+type RecentSearch struct {
+	SearchTerm     string    `json:"searchTerm"`
+	LastSearchedAt time.Time `json:"lastSearchedAt"`
+}
+
+// GetRecentSearches returns the current user's most recent distinct search
+// terms, newest first (?limit= up to 50, default 10; ?type= filters by search type)
+func (h *SearchHandler) GetRecentSearches(c *gin.Context) {
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		respondJSONError(c, http.StatusUnauthorized, errCodeUnauthorized, "User not authenticated")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, "limit must be a positive number")
+		return
+	}
+	if limit > 50 {
+		limit = 50
+	}
+
+	query := h.db.Model(&models.SearchHistory{}).
+		Select("search_term, MAX(searched_at) AS last_searched_at").
+		Where("userID = ? AND search_term <> ''", currentUser.UserID)
+	if searchType := c.Query("type"); searchType != "" {
+		query = query.Where("search_type = ?", searchType)
+	}
+
+	recent := []RecentSearch{}
+	if err := query.Group("search_term").Order("last_searched_at DESC").Limit(limit).
+		Scan(&recent).Error; err != nil {
+		logger.Errorf("Failed to load recent searches of user %d: %v", currentUser.UserID, err)
+		respondJSONError(c, http.StatusInternalServerError, errCodeInternal, "Failed to load recent searches")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recentSearches": recent})
 }
 
 // saveSearch saves a search for later use
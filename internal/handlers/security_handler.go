@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -14,11 +21,12 @@ import (
 )
 
 type SecurityHandler struct {
-	db *gorm.DB
+	db     *gorm.DB
+	config *config.Config
 }
 
-func NewSecurityHandler(db *gorm.DB) *SecurityHandler {
-	return &SecurityHandler{db: db}
+func NewSecurityHandler(db *gorm.DB, cfg *config.Config) *SecurityHandler {
+	return &SecurityHandler{db: db, config: cfg}
 }
 
 // Permission represents a permission with friendly name and description
@@ -31,69 +39,181 @@ type Permission struct {
 
 // GetPermissionDefinitions returns all available permissions with descriptions
 func (h *SecurityHandler) GetPermissionDefinitions() []Permission {
-	return []Permission{
-		// User Management
-		{Code: "users.manage", Name: "Manage Users", Description: "Create, edit, and delete user accounts", Category: "User Management"},
-		{Code: "users.view", Name: "View Users", Description: "View user lists and basic information", Category: "User Management"},
-		{Code: "users.create", Name: "Create Users", Description: "Create new user accounts", Category: "User Management"},
-		{Code: "users.edit", Name: "Edit Users", Description: "Modify existing user information", Category: "User Management"},
-		{Code: "users.delete", Name: "Delete Users", Description: "Remove user accounts", Category: "User Management"},
-		
-		// Job Management
-		{Code: "jobs.manage", Name: "Manage Jobs", Description: "Full access to job creation and management", Category: "Job Management"},
-		{Code: "jobs.view", Name: "View Jobs", Description: "View job listings and details", Category: "Job Management"},
-		{Code: "jobs.create", Name: "Create Jobs", Description: "Create new jobs and projects", Category: "Job Management"},
-		{Code: "jobs.edit", Name: "Edit Jobs", Description: "Modify existing job information", Category: "Job Management"},
-		{Code: "jobs.delete", Name: "Delete Jobs", Description: "Remove jobs from the system", Category: "Job Management"},
-		
-		// Device Management
-		{Code: "devices.manage", Name: "Manage Equipment", Description: "Add, edit, and track equipment inventory", Category: "Equipment"},
-		{Code: "devices.view", Name: "View Equipment", Description: "View equipment lists and availability", Category: "Equipment"},
-		{Code: "devices.create", Name: "Add Equipment", Description: "Add new equipment to inventory", Category: "Equipment"},
-		{Code: "devices.edit", Name: "Edit Equipment", Description: "Modify equipment information", Category: "Equipment"},
-		{Code: "devices.delete", Name: "Remove Equipment", Description: "Remove equipment from inventory", Category: "Equipment"},
-		
-		// Customer Management
-		{Code: "customers.manage", Name: "Manage Customers", Description: "Create and edit customer information", Category: "Customer Management"},
-		{Code: "customers.view", Name: "View Customers", Description: "View customer listings and details", Category: "Customer Management"},
-		{Code: "customers.create", Name: "Create Customers", Description: "Add new customers to database", Category: "Customer Management"},
-		{Code: "customers.edit", Name: "Edit Customers", Description: "Modify customer information", Category: "Customer Management"},
-		{Code: "customers.delete", Name: "Delete Customers", Description: "Remove customers from database", Category: "Customer Management"},
-		
-		// Reports & Analytics
-		{Code: "reports.view", Name: "View Reports", Description: "Access analytics and generate reports", Category: "Reports & Analytics"},
-		{Code: "analytics.view", Name: "View Analytics", Description: "Access dashboard analytics and insights", Category: "Reports & Analytics"},
-		{Code: "analytics.export", Name: "Export Data", Description: "Export analytics data and reports", Category: "Reports & Analytics"},
-		
-		// System Settings
-		{Code: "settings.manage", Name: "System Settings", Description: "Configure application settings", Category: "System"},
-		{Code: "roles.manage", Name: "Manage Roles", Description: "Create and modify user roles and permissions", Category: "System"},
-		{Code: "audit.view", Name: "View Audit Logs", Description: "Access system audit trail and logs", Category: "System"},
-		
-		// Scanner & Mobile
-		{Code: "scan.use", Name: "Use Scanner", Description: "Access mobile barcode scanning features", Category: "Scanner & Mobile"},
-		{Code: "mobile.access", Name: "Mobile Access", Description: "Access mobile app features", Category: "Scanner & Mobile"},
-		
-		// Documents
-		{Code: "documents.manage", Name: "Manage Documents", Description: "Upload, view, and organize documents", Category: "Documents"},
-		{Code: "documents.view", Name: "View Documents", Description: "View and download documents", Category: "Documents"},
-		{Code: "documents.upload", Name: "Upload Documents", Description: "Upload new documents and files", Category: "Documents"},
-		{Code: "documents.sign", Name: "Digital Signatures", Description: "Create and verify digital signatures", Category: "Documents"},
-		
-		// Financial
-		{Code: "financial.view", Name: "View Financial Data", Description: "Access financial reports and transactions", Category: "Financial"},
-		{Code: "financial.manage", Name: "Manage Finances", Description: "Create invoices and manage transactions", Category: "Financial"},
-		{Code: "invoices.generate", Name: "Generate Invoices", Description: "Create and send customer invoices", Category: "Financial"},
-		
-		// Super Admin
-		{Code: "*", Name: "Full System Access", Description: "Complete administrative access to all features", Category: "Super Admin"},
+	return permissionDefinitions
+}
+
+// permissionDefinitions is the one permission vocabulary: role permissions,
+// the default roles and every handler check use these codes
+var permissionDefinitions = []Permission{
+	// User Management
+	{Code: "users.manage", Name: "Manage Users", Description: "Create, edit, and delete user accounts", Category: "User Management"},
+	{Code: "users.view", Name: "View Users", Description: "View user lists and basic information", Category: "User Management"},
+	{Code: "users.create", Name: "Create Users", Description: "Create new user accounts", Category: "User Management"},
+	{Code: "users.edit", Name: "Edit Users", Description: "Modify existing user information", Category: "User Management"},
+	{Code: "users.delete", Name: "Delete Users", Description: "Remove user accounts", Category: "User Management"},
+	
+	// Job Management
+	{Code: "jobs.manage", Name: "Manage Jobs", Description: "Full access to job creation and management", Category: "Job Management"},
+	{Code: "jobs.view", Name: "View Jobs", Description: "View job listings and details", Category: "Job Management"},
+	{Code: "jobs.create", Name: "Create Jobs", Description: "Create new jobs and projects", Category: "Job Management"},
+	{Code: "jobs.edit", Name: "Edit Jobs", Description: "Modify existing job information", Category: "Job Management"},
+	{Code: "jobs.delete", Name: "Delete Jobs", Description: "Remove jobs from the system", Category: "Job Management"},
+	{Code: "jobs.override_duration", Name: "Override Rental Duration", Description: "Save jobs longer than the maximum rental duration", Category: "Job Management"},
+	
+	// Device Management
+	{Code: "devices.manage", Name: "Manage Equipment", Description: "Add, edit, and track equipment inventory", Category: "Equipment"},
+	{Code: "devices.view", Name: "View Equipment", Description: "View equipment lists and availability", Category: "Equipment"},
+	{Code: "devices.create", Name: "Add Equipment", Description: "Add new equipment to inventory", Category: "Equipment"},
+	{Code: "devices.edit", Name: "Edit Equipment", Description: "Modify equipment information", Category: "Equipment"},
+	{Code: "devices.delete", Name: "Remove Equipment", Description: "Remove equipment from inventory", Category: "Equipment"},
+	{Code: "devices.location", Name: "Update Equipment Location", Description: "Record where equipment physically is", Category: "Equipment"},
+	
+	// Customer Management
+	{Code: "customers.manage", Name: "Manage Customers", Description: "Create and edit customer information", Category: "Customer Management"},
+	{Code: "customers.view", Name: "View Customers", Description: "View customer listings and details", Category: "Customer Management"},
+	{Code: "customers.create", Name: "Create Customers", Description: "Add new customers to database", Category: "Customer Management"},
+	{Code: "customers.edit", Name: "Edit Customers", Description: "Modify customer information", Category: "Customer Management"},
+	{Code: "customers.delete", Name: "Delete Customers", Description: "Remove customers from database", Category: "Customer Management"},
+	{Code: "customers.export_data", Name: "Export Customer Data", Description: "Export all data stored about a customer (GDPR subject access)", Category: "Customer Management"},
+	
+	// Reports & Analytics
+	{Code: "reports.view", Name: "View Reports", Description: "Access analytics and generate reports", Category: "Reports & Analytics"},
+	{Code: "analytics.view", Name: "View Analytics", Description: "Access dashboard analytics and insights", Category: "Reports & Analytics"},
+	{Code: "analytics.export", Name: "Export Data", Description: "Export analytics data and reports", Category: "Reports & Analytics"},
+	{Code: "analytics.manage_targets", Name: "Manage Utilization Targets", Description: "Set target utilization per equipment category", Category: "Reports & Analytics"},
+	
+	// System Settings
+	{Code: "settings.manage", Name: "System Settings", Description: "Configure application settings", Category: "System"},
+	{Code: "roles.view", Name: "View Roles", Description: "View roles and the available permissions", Category: "System"},
+	{Code: "roles.manage", Name: "Manage Roles", Description: "Create and modify user roles and permissions", Category: "System"},
+	{Code: "roles.assign", Name: "Assign Roles", Description: "Grant roles to users and revoke them", Category: "System"},
+	{Code: "audit.view", Name: "View Audit Logs", Description: "Access system audit trail and logs", Category: "System"},
+	{Code: "audit.manage", Name: "Manage Audit Logs", Description: "Purge audit log entries past their retention period", Category: "System"},
+	
+	// Scanner & Mobile
+	{Code: "scan.use", Name: "Use Scanner", Description: "Access mobile barcode scanning features", Category: "Scanner & Mobile"},
+	{Code: "mobile.access", Name: "Mobile Access", Description: "Access mobile app features", Category: "Scanner & Mobile"},
+	
+	// Documents
+	{Code: "documents.manage", Name: "Manage Documents", Description: "Upload, view, and organize documents", Category: "Documents"},
+	{Code: "documents.view", Name: "View Documents", Description: "View and download documents", Category: "Documents"},
+	{Code: "documents.upload", Name: "Upload Documents", Description: "Upload new documents and files", Category: "Documents"},
+	{Code: "documents.sign", Name: "Digital Signatures", Description: "Create and verify digital signatures", Category: "Documents"},
+	
+	// Financial
+	{Code: "financial.view", Name: "View Financial Data", Description: "Access financial reports and transactions", Category: "Financial"},
+	{Code: "financial.manage", Name: "Manage Finances", Description: "Create invoices and manage transactions", Category: "Financial"},
+	{Code: "invoices.generate", Name: "Generate Invoices", Description: "Create and send customer invoices", Category: "Financial"},
+	{Code: "pricing.view", Name: "View Pricing Calendars", Description: "View seasonal pricing calendars", Category: "Financial"},
+	{Code: "pricing.manage", Name: "Manage Pricing Calendars", Description: "Create, edit and delete seasonal pricing calendars", Category: "Financial"},
+	
+	// Super Admin
+	{Code: "*", Name: "Full System Access", Description: "Complete administrative access to all features", Category: "Super Admin"},
+}
+
+// legacyPermissionNames maps the singular codes earlier releases seeded and
+// advertised to the canonical ones, so roles stored with them keep working
+var legacyPermissionNames = map[string]string{
+	"role.read":            "roles.view",
+	"role.create":          "roles.manage",
+	"role.update":          "roles.manage",
+	"role.delete":          "roles.manage",
+	"permission.read":      "roles.view",
+	"user.read":            "users.view",
+	"user.create":          "users.create",
+	"user.update":          "users.edit",
+	"user.delete":          "users.delete",
+	"user.assign_role":     "roles.assign",
+	"user.revoke_role":     "roles.assign",
+	"users.admin":          "users.manage",
+	"job.read":             "jobs.view",
+	"job.create":           "jobs.create",
+	"job.update":           "jobs.edit",
+	"job.delete":           "jobs.delete",
+	"job.assign_device":    "jobs.edit",
+	"job.manage_templates": "jobs.manage",
+	"device.read":          "devices.view",
+	"device.create":        "devices.create",
+	"device.update":        "devices.edit",
+	"device.delete":        "devices.delete",
+	"device.maintenance":   "devices.edit",
+	"device.location":      "devices.location",
+	"customer.read":        "customers.view",
+	"customer.create":      "customers.create",
+	"customer.update":      "customers.edit",
+	"customer.delete":      "customers.delete",
+	"financial.read":       "financial.view",
+	"financial.create":     "financial.manage",
+	"financial.update":     "financial.manage",
+	"financial.delete":     "financial.manage",
+	"financial.reports":    "financial.view",
+	"financial.invoices":   "invoices.generate",
+	"document.read":        "documents.view",
+	"document.create":      "documents.upload",
+	"document.update":      "documents.manage",
+	"document.delete":      "documents.manage",
+	"document.sign":        "documents.sign",
+	"analytics.read":       "analytics.view",
+	"audit.read":           "audit.view",
+	"system.admin":         "settings.manage",
+}
+
+// canonicalPermission returns the current code for a possibly legacy one
+func canonicalPermission(permission string) string {
+	if canonical, ok := legacyPermissionNames[permission]; ok {
+		return canonical
+	}
+	return permission
+}
+
+// isKnownPermission reports whether the code is defined in permissionDefinitions
+func isKnownPermission(permission string) bool {
+	for _, definition := range permissionDefinitions {
+		if definition.Code == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownPermissions parses a role's permission list and returns the codes
+// that aren't defined. The wildcard "*" is allowed.
+func unknownPermissions(raw json.RawMessage) ([]string, error) {
+	var permissions []string
+	if err := json.Unmarshal(raw, &permissions); err != nil || permissions == nil {
+		return nil, fmt.Errorf("permissions must be a list of permission codes")
+	}
+
+	unknown := []string{}
+	for _, permission := range permissions {
+		if !isKnownPermission(permission) {
+			unknown = append(unknown, permission)
+		}
 	}
+	return unknown, nil
+}
+
+// validateRolePermissions rejects a role whose permissions aren't all
+// defined with 400, naming the unknown codes
+func validateRolePermissions(c *gin.Context, raw json.RawMessage) bool {
+	unknown, err := unknownPermissions(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":              fmt.Sprintf("Unknown permissions: %s", strings.Join(unknown, ", ")),
+			"unknownPermissions": unknown,
+		})
+		return false
+	}
+	return true
 }
 
 // GetPermissionDefinitionsAPI returns permission definitions for API calls
 func (h *SecurityHandler) GetPermissionDefinitionsAPI(c *gin.Context) {
-	if !h.hasPermission(c, "roles.manage") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	if !h.requirePermission(c, "roles.view", "roles.manage") {
 		return
 	}
 
@@ -107,8 +227,7 @@ func (h *SecurityHandler) GetPermissionDefinitionsAPI(c *gin.Context) {
 
 // GetRoles returns all available roles
 func (h *SecurityHandler) GetRoles(c *gin.Context) {
-	if !h.hasPermission(c, "role.read") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	if !h.requirePermission(c, "roles.view", "roles.manage") {
 		return
 	}
 
@@ -124,8 +243,7 @@ func (h *SecurityHandler) GetRoles(c *gin.Context) {
 
 // GetRole returns a specific role by ID
 func (h *SecurityHandler) GetRole(c *gin.Context) {
-	if !h.hasPermission(c, "role.read") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	if !h.requirePermission(c, "roles.view", "roles.manage") {
 		return
 	}
 
@@ -146,8 +264,7 @@ func (h *SecurityHandler) GetRole(c *gin.Context) {
 
 // CreateRole creates a new role
 func (h *SecurityHandler) CreateRole(c *gin.Context) {
-	if !h.hasPermission(c, "role.create") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	if !h.requirePermission(c, "roles.manage") {
 		return
 	}
 
@@ -156,6 +273,9 @@ func (h *SecurityHandler) CreateRole(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !validateRolePermissions(c, role.Permissions) {
+		return
+	}
 
 	// Set defaults
 	role.IsActive = true
@@ -176,8 +296,7 @@ func (h *SecurityHandler) CreateRole(c *gin.Context) {
 
 // UpdateRole updates an existing role
 func (h *SecurityHandler) UpdateRole(c *gin.Context) {
-	if !h.hasPermission(c, "role.update") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	if !h.requirePermission(c, "roles.manage") {
 		return
 	}
 
@@ -206,11 +325,17 @@ func (h *SecurityHandler) UpdateRole(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	// Permissions are only replaced when the request sends them
+	if updateData.Permissions != nil {
+		if !validateRolePermissions(c, updateData.Permissions) {
+			return
+		}
+		role.Permissions = updateData.Permissions
+	}
 
 	// Update allowed fields
 	role.DisplayName = updateData.DisplayName
 	role.Description = updateData.Description
-	role.Permissions = updateData.Permissions
 	role.IsActive = updateData.IsActive
 	role.UpdatedAt = time.Now()
 
@@ -222,14 +347,14 @@ func (h *SecurityHandler) UpdateRole(c *gin.Context) {
 
 	// Log the action
 	h.logAction(c, "update", "role", fmt.Sprintf("%d", role.RoleID), oldRole, role)
+	myPermissionsCache.clear()
 
 	c.JSON(http.StatusOK, gin.H{"role": role})
 }
 
 // DeleteRole deactivates a role
 func (h *SecurityHandler) DeleteRole(c *gin.Context) {
-	if !h.hasPermission(c, "role.delete") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	if !h.requirePermission(c, "roles.manage") {
 		return
 	}
 
@@ -265,6 +390,7 @@ func (h *SecurityHandler) DeleteRole(c *gin.Context) {
 
 	// Log the action
 	h.logAction(c, "delete", "role", fmt.Sprintf("%d", role.RoleID), oldRole, role)
+	myPermissionsCache.clear()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Role deactivated successfully"})
 }
@@ -284,7 +410,7 @@ func (h *SecurityHandler) GetUserRoles(c *gin.Context) {
 		return
 	}
 
-	if fmt.Sprintf("%d", currentUser.UserID) != userID && !h.hasPermission(c, "user.read") {
+	if fmt.Sprintf("%d", currentUser.UserID) != userID && !h.hasPermission(c, "users.view") {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
@@ -299,10 +425,57 @@ func (h *SecurityHandler) GetUserRoles(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"userRoles": userRoles})
 }
 
+// GetUserEffectivePermissions returns the distinct, sorted permissions a user
+// holds through all of their active, unexpired roles, to explain why a user
+// can or can't do something. A wildcard role collapses the list to ["*"].
+func (h *SecurityHandler) GetUserEffectivePermissions(c *gin.Context) {
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userID := c.Param("userId")
+	if fmt.Sprintf("%d", currentUser.UserID) != userID && !h.hasPermission(c, "users.view") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.Where("userID = ?", userID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	granted, err := effectivePermissions(h.db, &user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+		return
+	}
+
+	permissions := []string{"*"}
+	if !granted["*"] {
+		permissions = make([]string, 0, len(granted))
+		for permission := range granted {
+			permissions = append(permissions, permission)
+		}
+		sort.Strings(permissions)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"userID":      user.UserID,
+		"username":    user.Username,
+		"permissions": permissions,
+	})
+}
+
 // AssignUserRole assigns a role to a user
 func (h *SecurityHandler) AssignUserRole(c *gin.Context) {
-	if !h.hasPermission(c, "user.assign_role") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	if !h.requirePermission(c, "roles.assign") {
 		return
 	}
 
@@ -364,7 +537,7 @@ func (h *SecurityHandler) AssignUserRole(c *gin.Context) {
 			
 			result = h.db.Save(&existing)
 			if result.Error != nil {
-				fmt.Printf("ERROR reactivating user role: %v\n", result.Error)
+				logger.Errorf("reactivating user role: %v", result.Error)
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign role", "details": result.Error.Error()})
 				return
 			}
@@ -401,14 +574,14 @@ func (h *SecurityHandler) AssignUserRole(c *gin.Context) {
 
 	// Log the action
 	h.logAction(c, "assign_role", "user", userID, nil, userRole)
+	myPermissionsCache.clear()
 
 	c.JSON(http.StatusCreated, gin.H{"userRole": userRole})
 }
 
 // RevokeUserRole revokes a role from a user
 func (h *SecurityHandler) RevokeUserRole(c *gin.Context) {
-	if !h.hasPermission(c, "user.revoke_role") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	if !h.requirePermission(c, "roles.assign") {
 		return
 	}
 
@@ -438,6 +611,7 @@ func (h *SecurityHandler) RevokeUserRole(c *gin.Context) {
 
 	// Log the action
 	h.logAction(c, "revoke_role", "user", userID, oldUserRole, userRole)
+	myPermissionsCache.clear()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Role revoked successfully"})
 }
@@ -448,8 +622,7 @@ func (h *SecurityHandler) RevokeUserRole(c *gin.Context) {
 
 // GetAuditLogs returns audit logs with filtering and pagination
 func (h *SecurityHandler) GetAuditLogs(c *gin.Context) {
-	if !h.hasPermission(c, "audit.read") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	if !h.requirePermission(c, "audit.view") {
 		return
 	}
 
@@ -527,8 +700,7 @@ func (h *SecurityHandler) GetAuditLogs(c *gin.Context) {
 
 // GetAuditLog returns a specific audit log entry
 func (h *SecurityHandler) GetAuditLog(c *gin.Context) {
-	if !h.hasPermission(c, "audit.read") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	if !h.requirePermission(c, "audit.view") {
 		return
 	}
 
@@ -547,8 +719,130 @@ func (h *SecurityHandler) GetAuditLog(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"auditLog": auditLog})
 }
 
-// ExportAuditLogs exports audit logs to CSV format
+// AuditFieldChange is a field whose value differs between the old and new
+// values of an audit log entry. Nested fields are named by their path, e.g.
+// "customer.city".
+type AuditFieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// GetAuditLogDiff returns only the fields an audited action changed, with
+// their values before and after. Entries without old values (creates) list
+// every new field with a null before, entries without new values (deletes)
+// every old field with a null after.
+func (h *SecurityHandler) GetAuditLogDiff(c *gin.Context) {
+	if !h.requirePermission(c, "audit.view") {
+		return
+	}
+
+	var auditLog models.AuditLog
+	if err := h.db.First(&auditLog, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Audit log not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	before, err := auditValueFields(auditLog.OldValues)
+	if err != nil {
+		logger.Warnf("Audit log %d has unreadable old values: %v", auditLog.AuditID, err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Old values of the audit log are not valid JSON"})
+		return
+	}
+	after, err := auditValueFields(auditLog.NewValues)
+	if err != nil {
+		logger.Warnf("Audit log %d has unreadable new values: %v", auditLog.AuditID, err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "New values of the audit log are not valid JSON"})
+		return
+	}
+
+	changeType := "update"
+	switch {
+	case before == nil && after == nil:
+		changeType = "none"
+	case before == nil:
+		changeType = "create"
+	case after == nil:
+		changeType = "delete"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"auditID":    auditLog.AuditID,
+		"action":     auditLog.Action,
+		"entityType": auditLog.EntityType,
+		"entityID":   auditLog.EntityID,
+		"changeType": changeType,
+		"changes":    diffAuditFields(before, after),
+	})
+}
+
+// auditValueFields flattens stored audit values into their fields by path.
+// Values that aren't JSON objects, like lists, become a single field named
+// "value". It returns nil when nothing was stored.
+func auditValueFields(raw json.RawMessage) (map[string]interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	fields := make(map[string]interface{})
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		fields["value"] = value
+		return fields, nil
+	}
+	flattenAuditFields(fields, "", object)
+	return fields, nil
+}
+
+func flattenAuditFields(fields map[string]interface{}, prefix string, object map[string]interface{}) {
+	for key, value := range object {
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenAuditFields(fields, prefix+key+".", nested)
+			continue
+		}
+		fields[prefix+key] = value
+	}
+}
+
+// diffAuditFields returns the fields whose values differ, sorted by name. A
+// field missing on one side counts as null there.
+func diffAuditFields(before, after map[string]interface{}) []AuditFieldChange {
+	names := make(map[string]bool, len(before)+len(after))
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+
+	changes := make([]AuditFieldChange, 0)
+	for name := range names {
+		if !reflect.DeepEqual(before[name], after[name]) {
+			changes = append(changes, AuditFieldChange{Field: name, Before: before[name], After: after[name]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// ExportAuditLogs exports the filtered audit logs as CSV (format=csv, the
+// default) or as a JSON array (format=json)
 func (h *SecurityHandler) ExportAuditLogs(c *gin.Context) {
+	if !h.requirePermission(c, "audit.view") {
+		return
+	}
+
 	format := c.DefaultQuery("format", "csv")
 	userID := c.Query("userId")
 	action := c.Query("action")
@@ -556,14 +850,11 @@ func (h *SecurityHandler) ExportAuditLogs(c *gin.Context) {
 	startDate := c.Query("startDate")
 	endDate := c.Query("endDate")
 
-	if format != "csv" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only CSV format is supported"})
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Supported formats are csv and json"})
 		return
 	}
 
-	c.Header("Content-Type", "text/csv")
-	c.Header("Content-Disposition", `attachment; filename="audit_logs_`+time.Now().Format("2006-01-02")+`.csv"`)
-
 	// Build query
 	query := h.db.Model(&models.AuditLog{}).Preload("User")
 
@@ -589,8 +880,23 @@ func (h *SecurityHandler) ExportAuditLogs(c *gin.Context) {
 		return
 	}
 
-	// Generate CSV
-	csvContent := "Timestamp,User,Action,Entity Type,Entity ID,IP Address,User Agent,Description\n"
+	filename := "audit_logs_" + time.Now().Format("2006-01-02") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	if format == "json" {
+		if auditLogs == nil {
+			auditLogs = []models.AuditLog{}
+		}
+		c.JSON(http.StatusOK, auditLogs)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Status(http.StatusOK)
+
+	// encoding/csv quotes fields containing commas, quotes or line breaks
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"Timestamp", "User", "Action", "Entity Type", "Entity ID", "IP Address", "User Agent", "Description"})
 
 	for _, log := range auditLogs {
 		username := ""
@@ -613,7 +919,7 @@ func (h *SecurityHandler) ExportAuditLogs(c *gin.Context) {
 			userAgent = "N/A"
 		}
 
-		csvContent += fmt.Sprintf("%s,\"%s\",%s,%s,%s,\"%s\",\"%s\",\"%s\"\n",
+		writer.Write([]string{
 			log.Timestamp.Format("2006-01-02 15:04:05"),
 			username,
 			log.Action,
@@ -622,84 +928,137 @@ func (h *SecurityHandler) ExportAuditLogs(c *gin.Context) {
 			ipAddress,
 			userAgent,
 			description,
-		)
+		})
 	}
 
-	c.String(http.StatusOK, csvContent)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		logger.Errorf("ExportAuditLogs: failed to write CSV: %v", err)
+	}
+}
+
+// accessChangeEntityTypes and accessChangeActions identify the audit entries
+// recording who was given which access, and purges of the log itself. They
+// are kept by purges while AuditKeepAccessChanges is set.
+var (
+	accessChangeEntityTypes = []string{"role", "audit_log"}
+	accessChangeActions     = []string{"assign_role", "revoke_role"}
+)
+
+// PurgeAuditLogs deletes audit log entries older than olderThanDays, by
+// default the configured retention period, and returns how many were removed
+func (h *SecurityHandler) PurgeAuditLogs(c *gin.Context) {
+	if !h.requirePermission(c, "audit.manage") {
+		return
+	}
+
+	var request struct {
+		OlderThanDays     int   `json:"olderThanDays"`
+		KeepAccessChanges *bool `json:"keepAccessChanges"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+			return
+		}
+	}
+
+	days := request.OlderThanDays
+	if days == 0 {
+		days = h.config.Security.AuditRetentionDays
+	}
+	if days <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "olderThanDays is required when no audit retention period is configured"})
+		return
+	}
+
+	keepAccessChanges := h.config.Security.AuditKeepAccessChanges
+	if request.KeepAccessChanges != nil {
+		keepAccessChanges = *request.KeepAccessChanges
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	purged, err := purgeAuditLogs(h.db, cutoff, keepAccessChanges)
+	if err != nil {
+		logger.Errorf("Failed to purge audit logs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge audit logs"})
+		return
+	}
+
+	h.logAction(c, "purge", "audit_log", "", nil, gin.H{
+		"olderThan":         cutoff,
+		"keepAccessChanges": keepAccessChanges,
+		"purged":            purged,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"purged":            purged,
+		"olderThan":         cutoff,
+		"keepAccessChanges": keepAccessChanges,
+	})
+}
+
+// purgeAuditLogs deletes audit log entries from before cutoff and returns how
+// many were removed
+func purgeAuditLogs(db *gorm.DB, cutoff time.Time, keepAccessChanges bool) (int64, error) {
+	query := db.Where("timestamp < ?", cutoff)
+	if keepAccessChanges {
+		query = query.Where("entity_type NOT IN ? AND action NOT IN ?", accessChangeEntityTypes, accessChangeActions)
+	}
+	result := query.Delete(&models.AuditLog{})
+	return result.RowsAffected, result.Error
+}
+
+// StartAuditLogPurge starts a background goroutine purging audit log entries
+// past the configured retention period once a day. It does nothing when no
+// retention period is configured.
+func (h *SecurityHandler) StartAuditLogPurge() {
+	days := h.config.Security.AuditRetentionDays
+	if days <= 0 {
+		return
+	}
+
+	purge := func() {
+		cutoff := time.Now().AddDate(0, 0, -days)
+		purged, err := purgeAuditLogs(h.db, cutoff, h.config.Security.AuditKeepAccessChanges)
+		if err != nil {
+			logger.Errorf("Failed to purge audit logs: %v", err)
+			return
+		}
+		if purged > 0 {
+			logger.Infof("Purged %d audit log entries older than %d days", purged, days)
+		}
+	}
+
+	go func() {
+		purge()
+
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			purge()
+		}
+	}()
 }
 
 // ================================================================
 // PERMISSION MANAGEMENT
 // ================================================================
 
-// GetPermissions returns all available permissions
+// GetPermissions returns the codes of all available permissions by area
 func (h *SecurityHandler) GetPermissions(c *gin.Context) {
-	if !h.hasPermission(c, "permission.read") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	if !h.requirePermission(c, "roles.view", "roles.manage") {
 		return
 	}
 
-	permissions := map[string][]string{
-		"roles": {
-			"role.read",
-			"role.create",
-			"role.update",
-			"role.delete",
-		},
-		"users": {
-			"user.read",
-			"user.create",
-			"user.update",
-			"user.delete",
-			"user.assign_role",
-			"user.revoke_role",
-		},
-		"jobs": {
-			"job.read",
-			"job.create",
-			"job.update",
-			"job.delete",
-			"job.assign_device",
-			"job.manage_templates",
-		},
-		"devices": {
-			"device.read",
-			"device.create",
-			"device.update",
-			"device.delete",
-			"device.maintenance",
-			"device.location",
-		},
-		"customers": {
-			"customer.read",
-			"customer.create",
-			"customer.update",
-			"customer.delete",
-		},
-		"financial": {
-			"financial.read",
-			"financial.create",
-			"financial.update",
-			"financial.delete",
-			"financial.reports",
-			"financial.invoices",
-		},
-		"documents": {
-			"document.read",
-			"document.create",
-			"document.update",
-			"document.delete",
-			"document.sign",
-		},
-		"analytics": {
-			"analytics.read",
-			"analytics.export",
-		},
-		"system": {
-			"audit.read",
-			"permission.read",
-			"system.admin",
-		},
+	// Grouped by the part before the dot, e.g. "jobs"
+	permissions := make(map[string][]string)
+	for _, definition := range permissionDefinitions {
+		if definition.Code == "*" {
+			continue
+		}
+		group := strings.SplitN(definition.Code, ".", 2)[0]
+		permissions[group] = append(permissions[group], definition.Code)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"permissions": permissions})
@@ -717,32 +1076,165 @@ func (h *SecurityHandler) CheckPermission(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"hasPermission": hasPermission})
 }
 
+// myPermissionsTTL bounds how long a session's cached permission set is served
+const myPermissionsTTL = 5 * time.Minute
+
+type myPermissionsEntry struct {
+	permissions []string
+	all         bool
+	expiresAt   time.Time
+}
+
+// myPermissionsCache holds each session's effective permissions. It is cleared
+// whenever roles or role assignments change.
+var myPermissionsCache = &permissionSetCache{entries: make(map[string]myPermissionsEntry)}
+
+type permissionSetCache struct {
+	mutex   sync.RWMutex
+	entries map[string]myPermissionsEntry
+}
+
+func (pc *permissionSetCache) get(sessionID string) (myPermissionsEntry, bool) {
+	pc.mutex.RLock()
+	defer pc.mutex.RUnlock()
+	entry, ok := pc.entries[sessionID]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return myPermissionsEntry{}, false
+	}
+	return entry, true
+}
+
+func (pc *permissionSetCache) set(sessionID string, entry myPermissionsEntry) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	entry.expiresAt = time.Now().Add(myPermissionsTTL)
+	pc.entries[sessionID] = entry
+}
+
+func (pc *permissionSetCache) clear() {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	pc.entries = make(map[string]myPermissionsEntry)
+}
+
+// GetMyPermissions returns the current user's effective permissions across all
+// active roles, so the UI can decide which menus and features to show in one
+// request. A wildcard role is expanded to every defined permission.
+func (h *SecurityHandler) GetMyPermissions(c *gin.Context) {
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	sessionID, _ := c.Cookie("session_id")
+	if sessionID != "" {
+		if entry, ok := myPermissionsCache.get(sessionID); ok {
+			c.JSON(http.StatusOK, gin.H{"permissions": entry.permissions, "all": entry.all})
+			return
+		}
+	}
+
+	granted, err := effectivePermissions(h.db, currentUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+		return
+	}
+
+	entry := myPermissionsEntry{all: granted["*"]}
+	if entry.all {
+		for _, permission := range h.GetPermissionDefinitions() {
+			granted[permission.Code] = true
+		}
+	}
+	delete(granted, "*")
+	entry.permissions = make([]string, 0, len(granted))
+	for permission := range granted {
+		entry.permissions = append(entry.permissions, permission)
+	}
+	sort.Strings(entry.permissions)
+
+	if sessionID != "" {
+		myPermissionsCache.set(sessionID, entry)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"permissions": entry.permissions, "all": entry.all})
+}
+
 // ================================================================
 // HELPER FUNCTIONS
 // ================================================================
 
 // hasPermission checks if the current user has the specified permission
 func (h *SecurityHandler) hasPermission(c *gin.Context, permission string) bool {
+	return userHasPermission(h.db, c, permission)
+}
+
+// RequirePermission returns middleware that lets a request through only if
+// the current user has one of the permissions, so route groups can be guarded
+// once instead of in every handler. Requests without a user get 401, users
+// without the permissions 403.
+func (h *SecurityHandler) RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := GetCurrentUser(c); !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		if !h.requirePermission(c, permissions...) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// requirePermission reports whether the current user has one of the
+// permissions. Otherwise it aborts the request with 403.
+func (h *SecurityHandler) requirePermission(c *gin.Context, permissions ...string) bool {
+	for _, permission := range permissions {
+		if h.hasPermission(c, permission) {
+			return true
+		}
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	return false
+}
+
+// userHasPermission checks the current user's active roles for a permission.
+// Shared by handlers that guard endpoints outside the security module.
+func userHasPermission(db *gorm.DB, c *gin.Context, permission string) bool {
 	currentUser, exists := GetCurrentUser(c)
 	if !exists {
 		return false
 	}
 
+	granted, err := effectivePermissions(db, currentUser)
+	if err != nil {
+		return false
+	}
+	return granted[permission] || granted["*"]
+}
+
+// effectivePermissions collects the permissions of all of the user's active,
+// unexpired roles, with legacy codes translated to the canonical ones. The
+// wildcard "*" is kept as is for callers to resolve.
+func effectivePermissions(db *gorm.DB, user *models.User) (map[string]bool, error) {
+	granted := make(map[string]bool)
+
 	// System admin has all permissions
-	if currentUser.Username == "admin" {
-		return true
+	if user.Username == "admin" {
+		granted["*"] = true
+		return granted, nil
 	}
 
 	// Get user's active roles
 	var userRoles []models.UserRole
-	result := h.db.Preload("Role").Where("userID = ? AND is_active = ? AND (expires_at IS NULL OR expires_at > ?)", 
-		currentUser.UserID, true, time.Now()).Find(&userRoles)
+	result := db.Preload("Role").Where("userID = ? AND is_active = ? AND (expires_at IS NULL OR expires_at > ?)", 
+		user.UserID, true, time.Now()).Find(&userRoles)
 	
 	if result.Error != nil {
-		return false
+		return nil, result.Error
 	}
 
-	// Check if any role has the required permission
 	for _, userRole := range userRoles {
 		if userRole.Role == nil || !userRole.Role.IsActive {
 			continue
@@ -754,17 +1246,21 @@ func (h *SecurityHandler) hasPermission(c *gin.Context, permission string) bool
 		}
 
 		for _, perm := range permissions {
-			if perm == permission || perm == "*" {
-				return true
-			}
+			granted[canonicalPermission(perm)] = true
 		}
 	}
 
-	return false
+	return granted, nil
 }
 
 // logAction logs an action to the audit trail
 func (h *SecurityHandler) logAction(c *gin.Context, action, entityType, entityID string, oldValues, newValues interface{}) {
+	writeAuditLog(h.db, c, action, entityType, entityID, oldValues, newValues)
+}
+
+// writeAuditLog records an action by the current user in the audit trail.
+// Shared by handlers that audit actions outside the security module.
+func writeAuditLog(db *gorm.DB, c *gin.Context, action, entityType, entityID string, oldValues, newValues interface{}) {
 	currentUser, exists := GetCurrentUser(c)
 	
 	auditLog := models.AuditLog{
@@ -799,7 +1295,17 @@ func (h *SecurityHandler) logAction(c *gin.Context, action, entityType, entityID
 	}
 
 	// Save audit log (ignore errors to not break the main operation)
-	h.db.Create(&auditLog)
+	db.Create(&auditLog)
+}
+
+// auditSnapshot loads the stored row whose key column equals id into dest, as
+// the old or new values of an audit entry. It returns nil when the row can't
+// be loaded, so the entry is still written without them.
+func auditSnapshot(db *gorm.DB, dest interface{}, keyColumn string, id interface{}) interface{} {
+	if err := db.Where(keyColumn+" = ?", id).First(dest).Error; err != nil {
+		return nil
+	}
+	return dest
 }
 
 // InitializeDefaultRoles creates default system roles
@@ -821,13 +1327,13 @@ func (h *SecurityHandler) InitializeDefaultRoles() error {
 
 	// Manager role
 	managerPermissions := []string{
-		"job.read", "job.create", "job.update", "job.delete", "job.assign_device", "job.manage_templates",
-		"device.read", "device.create", "device.update", "device.maintenance", "device.location",
-		"customer.read", "customer.create", "customer.update",
-		"financial.read", "financial.create", "financial.update", "financial.reports", "financial.invoices",
-		"document.read", "document.create", "document.update", "document.sign",
-		"analytics.read", "analytics.export",
-		"user.read",
+		"jobs.view", "jobs.create", "jobs.edit", "jobs.delete", "jobs.manage",
+		"devices.view", "devices.create", "devices.edit", "devices.location",
+		"customers.view", "customers.create", "customers.edit",
+		"financial.view", "financial.manage", "invoices.generate",
+		"documents.view", "documents.upload", "documents.manage", "documents.sign",
+		"analytics.view", "analytics.export", "reports.view",
+		"users.view",
 	}
 	managerPermsJSON, _ := json.Marshal(managerPermissions)
 	
@@ -844,11 +1350,11 @@ func (h *SecurityHandler) InitializeDefaultRoles() error {
 
 	// Employee role
 	employeePermissions := []string{
-		"job.read", "job.update", "job.assign_device",
-		"device.read", "device.update", "device.location",
-		"customer.read",
-		"document.read", "document.create",
-		"analytics.read",
+		"jobs.view", "jobs.edit",
+		"devices.view", "devices.edit", "devices.location",
+		"customers.view",
+		"documents.view", "documents.upload",
+		"analytics.view",
 	}
 	employeePermsJSON, _ := json.Marshal(employeePermissions)
 	
@@ -865,11 +1371,11 @@ func (h *SecurityHandler) InitializeDefaultRoles() error {
 
 	// Viewer role
 	viewerPermissions := []string{
-		"job.read",
-		"device.read",
-		"customer.read",
-		"document.read",
-		"analytics.read",
+		"jobs.view",
+		"devices.view",
+		"customers.view",
+		"documents.view",
+		"analytics.view",
 	}
 	viewerPermsJSON, _ := json.Marshal(viewerPermissions)
 	
@@ -886,6 +1392,11 @@ func (h *SecurityHandler) InitializeDefaultRoles() error {
 
 	// Create roles if they don't exist
 	roles := []models.Role{adminRole, managerRole, employeeRole, viewerRole}
+	for _, role := range roles {
+		if unknown, err := unknownPermissions(role.Permissions); err != nil || len(unknown) > 0 {
+			return fmt.Errorf("default role %s has unknown permissions %v", role.Name, unknown)
+		}
+	}
 	for _, role := range roles {
 		var existing models.Role
 		result := h.db.Where("name = ?", role.Name).First(&existing)
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-barcode-webapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeRoleAssignment is one role of the test user with the role's permissions
+type fakeRoleAssignment struct {
+	roleID      uint
+	permissions []string
+	expiresAt   *time.Time
+}
+
+// fakeRoleTables answers the user role and role queries of effectivePermissions
+func fakeRoleTables(assignments []fakeRoleAssignment) fakeQueryFunc {
+	return func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		switch {
+		case strings.Contains(query, "FROM `user_roles`"):
+			// userID, is_active and the time roles must not have expired by
+			now := args[2].(time.Time)
+			var rows [][]driver.Value
+			for _, assignment := range assignments {
+				if assignment.expiresAt != nil && !assignment.expiresAt.After(now) {
+					continue
+				}
+				var expiresAt driver.Value
+				if assignment.expiresAt != nil {
+					expiresAt = *assignment.expiresAt
+				}
+				rows = append(rows, []driver.Value{args[0], int64(assignment.roleID), expiresAt, true})
+			}
+			return []string{"userID", "roleID", "expires_at", "is_active"}, rows, nil
+
+		case strings.Contains(query, "FROM `roles`"):
+			var rows [][]driver.Value
+			for _, assignment := range assignments {
+				permissions, _ := json.Marshal(assignment.permissions)
+				rows = append(rows, []driver.Value{int64(assignment.roleID), permissions, true})
+			}
+			return []string{"roleID", "permissions", "is_active"}, rows, nil
+		}
+		return nil, nil, nil
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	nextWeek := time.Now().Add(7 * 24 * time.Hour)
+
+	tests := []struct {
+		name        string
+		username    string
+		assignments []fakeRoleAssignment
+		want        bool
+	}{
+		{
+			name:        "allowed by role",
+			username:    "alice",
+			assignments: []fakeRoleAssignment{{roleID: 1, permissions: []string{"jobs.view", "roles.view"}}},
+			want:        true,
+		},
+		{
+			name:        "allowed by unexpired role",
+			username:    "alice",
+			assignments: []fakeRoleAssignment{{roleID: 1, permissions: []string{"roles.manage"}, expiresAt: &nextWeek}},
+			want:        true,
+		},
+		{
+			name:        "allowed by legacy code",
+			username:    "alice",
+			assignments: []fakeRoleAssignment{{roleID: 1, permissions: []string{"role.read"}}},
+			want:        true,
+		},
+		{
+			name:     "allowed for admin",
+			username: "admin",
+			want:     true,
+		},
+		{
+			name:        "denied without permission",
+			username:    "alice",
+			assignments: []fakeRoleAssignment{{roleID: 1, permissions: []string{"jobs.view"}}},
+			want:        false,
+		},
+		{
+			name:        "denied by expired role",
+			username:    "alice",
+			assignments: []fakeRoleAssignment{{roleID: 1, permissions: []string{"roles.manage"}, expiresAt: &yesterday}},
+			want:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t, fakeRoleTables(tt.assignments))
+			handler := NewSecurityHandler(db.DB, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/security/roles", nil)
+			c.Set("user", models.User{UserID: 7, Username: tt.username})
+
+			got := handler.requirePermission(c, "roles.view", "roles.manage")
+			if got != tt.want {
+				t.Fatalf("requirePermission = %v; want %v", got, tt.want)
+			}
+			if got && c.IsAborted() {
+				t.Error("allowed request was aborted")
+			}
+			if !got && (!c.IsAborted() || w.Code != http.StatusForbidden) {
+				t.Errorf("denied request: aborted %v, status %d; want aborted with 403", c.IsAborted(), w.Code)
+			}
+		})
+	}
+}
+
+func TestRequirePermissionWithoutUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newFakeDB(t, fakeRoleTables(nil))
+	handler := NewSecurityHandler(db.DB, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/security/roles", nil)
+
+	if handler.requirePermission(c, "roles.view") {
+		t.Fatal("requirePermission allowed a request without a user")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d; want 403", w.Code)
+	}
+}
+
+func TestRequirePermissionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		user        *models.User
+		assignments []fakeRoleAssignment
+		want        int
+	}{
+		{
+			name:        "allowed",
+			user:        &models.User{UserID: 7, Username: "alice"},
+			assignments: []fakeRoleAssignment{{roleID: 1, permissions: []string{"roles.view"}}},
+			want:        http.StatusOK,
+		},
+		{
+			name:        "forbidden",
+			user:        &models.User{UserID: 7, Username: "alice"},
+			assignments: []fakeRoleAssignment{{roleID: 1, permissions: []string{"jobs.view"}}},
+			want:        http.StatusForbidden,
+		},
+		{
+			name: "unauthenticated",
+			want: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t, fakeRoleTables(tt.assignments))
+			handler := NewSecurityHandler(db.DB, nil)
+
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.user != nil {
+					c.Set("user", *tt.user)
+				}
+			})
+			reached := false
+			roles := router.Group("/api/v1/security", handler.RequirePermission("roles.view", "roles.manage"))
+			roles.GET("/roles", func(c *gin.Context) {
+				reached = true
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/security/roles", nil))
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d: %s; want %d", w.Code, w.Body.String(), tt.want)
+			}
+			if reached != (tt.want == http.StatusOK) {
+				t.Errorf("handler reached = %v with status %d", reached, w.Code)
+			}
+		})
+	}
+}
+
+func TestUpdateRolePermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stored := `["jobs.view"]`
+
+	tests := []struct {
+		name            string
+		body            string
+		want            int
+		wantPermissions string
+	}{
+		{"permissions left out", `{"displayName": "Crew", "isActive": true}`, http.StatusOK, stored},
+		{"permissions replaced", `{"displayName": "Crew", "permissions": ["jobs.view", "devices.view"]}`, http.StatusOK, `["jobs.view", "devices.view"]`},
+		{"unknown permission", `{"displayName": "Crew", "permissions": ["jobs.fly"]}`, http.StatusBadRequest, ""},
+		{"null permissions", `{"displayName": "Crew", "permissions": null}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved string
+			db := newFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+				switch {
+				case strings.HasPrefix(query, "SELECT * FROM `roles`"):
+					return []string{"roleID", "name", "displayName", "permissions", "is_active"},
+						[][]driver.Value{{int64(4), "crew", "Crew", []byte(stored), true}}, nil
+				case strings.HasPrefix(query, "UPDATE `roles`"):
+					for _, arg := range args {
+						if raw, ok := arg.(json.RawMessage); ok {
+							saved = string(raw)
+						}
+					}
+				}
+				return nil, nil, nil
+			})
+			handler := NewSecurityHandler(db.DB, nil)
+
+			router := gin.New()
+			router.PUT("/api/v1/security/roles/:id", func(c *gin.Context) {
+				c.Set("user", models.User{UserID: 1, Username: "admin"})
+				handler.UpdateRole(c)
+			})
+
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPut, "/api/v1/security/roles/4", strings.NewReader(tt.body))
+			request.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, request)
+
+			if w.Code != tt.want {
+				t.Fatalf("update role = %d: %s; want %d", w.Code, w.Body.String(), tt.want)
+			}
+			if saved != tt.wantPermissions {
+				t.Errorf("saved permissions = %s; want %s", saved, tt.wantPermissions)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	timeout := 30 * time.Minute
+	extended := now.Add(timeout)
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      bool
+	}{
+		{"extended a moment ago", extended.Add(-time.Second), false},
+		{"just below the interval", extended.Add(-slidingSessionInterval + time.Nanosecond), false},
+		{"exactly the interval", extended.Add(-slidingSessionInterval), true},
+		{"past the interval", extended.Add(-slidingSessionInterval - time.Second), true},
+		{"close to expiring", now.Add(time.Second), true},
+		{"already expired", now.Add(-time.Hour), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, due := slidingExpiry(tt.expiresAt, now, timeout)
+			if !got.Equal(extended) {
+				t.Errorf("expiry = %v; want %v", got, extended)
+			}
+			if due != tt.want {
+				t.Errorf("due = %v; want %v", due, tt.want)
+			}
+		})
+	}
+}
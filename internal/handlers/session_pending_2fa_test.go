@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeSessionTables holds a full session and a login waiting for its second
+// factor, both of user 1. Session queries that filter on pending_2fa only
+// see the matching one.
+func fakeSessionTables(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	expires := time.Now().Add(time.Hour)
+	sessions := [][]driver.Value{
+		{"full-1", int64(1), expires, false},
+		{"temp-1", int64(1), expires, true},
+	}
+
+	switch {
+	case strings.HasPrefix(query, "SELECT * FROM `sessions`"):
+		// The lookup by ID and the list by user both take the key first
+		var rows [][]driver.Value
+		for _, session := range sessions {
+			if strings.Contains(query, "session_id = ?") && session[0] != args[0] {
+				continue
+			}
+			if strings.Contains(query, "pending_2fa = ?") && session[3] != args[1] {
+				continue
+			}
+			rows = append(rows, session)
+		}
+		return []string{"session_id", "user_id", "expires_at", "pending_2fa"}, rows, nil
+	case strings.HasPrefix(query, "SELECT * FROM `users`"):
+		return []string{"userID", "username", "is_active"}, [][]driver.Value{{int64(1), "alice", true}}, nil
+	case strings.HasPrefix(query, "SELECT * FROM `user_preferences`"):
+		return []string{"user_id", "language"}, [][]driver.Value{{int64(1), "de"}}, nil
+	}
+	// 2FA status, passkeys, login attempts and session touches: no rows
+	return nil, nil, nil
+}
+
+func TestProfileSessionsLeaveOutPending2FA(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newFakeDB(t, fakeSessionTables)
+	handler := NewProfileHandler(db.DB, &config.Config{})
+
+	router := gin.New()
+	router.SetHTMLTemplate(template.Must(template.New("profile_settings_standalone.html").
+		Parse(`{{range .sessions}}{{.SessionID}} {{end}}`)))
+	router.GET("/profile/settings", func(c *gin.Context) {
+		c.Set("user", models.User{UserID: 1, Username: "alice"})
+		handler.ProfileSettingsForm(c)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/profile/settings", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("profile settings = %d: %s", w.Code, w.Body.String())
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != "full-1" {
+		t.Errorf("listed sessions = %q; want only full-1", got)
+	}
+}
+
+func TestAuthMiddlewareRejectsPending2FASession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		sessionID string
+		want      int
+	}{
+		{"full session", "full-1", http.StatusOK},
+		{"waiting for second factor", "temp-1", http.StatusSeeOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t, fakeSessionTables)
+			handler := NewAuthHandler(db.DB, &config.Config{})
+
+			router := gin.New()
+			router.GET("/dashboard", handler.AuthMiddleware(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+			request.AddCookie(&http.Cookie{Name: "session_id", Value: tt.sessionID})
+			router.ServeHTTP(w, request)
+
+			if w.Code != tt.want {
+				t.Fatalf("dashboard = %d; want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one request field that failed binding or validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// Report fields by their JSON names, as clients send them
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// bindingFieldErrors converts a binding error into one FieldError per failed
+// field. Errors not tied to a field, such as malformed JSON, yield nil.
+func bindingFieldErrors(err error) []FieldError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: validationMessage(fe),
+			})
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   typeErr.Type.String(),
+			Message: fmt.Sprintf("%s must be of type %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value),
+		}}
+	}
+	return nil
+}
+
+// respondValidationError writes a 400 response listing the failed fields. The
+// combined message stays in "error" for clients that only show that.
+func respondValidationError(c *gin.Context, err error) {
+	fields := bindingFieldErrors(err)
+	if fields == nil {
+		respondJSONError(c, http.StatusBadRequest, errCodeInvalidRequest, err.Error())
+		return
+	}
+	respondFieldErrors(c, fields)
+}
+
+// respondFieldErrors writes a 400 response for field errors found by handler checks
+func respondFieldErrors(c *gin.Context, fields []FieldError) {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":  strings.Join(messages, "; "),
+		"code":   "VALIDATION_ERROR",
+		"fields": fields,
+	})
+}
+
+// fieldPath returns the field's JSON path without the top-level struct name,
+// e.g. "devices[0].quantity"
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fe.Field()
+}
+
+func validationMessage(fe validator.FieldError) string {
+	field := fieldPath(fe)
+	isString := fe.Kind() == reflect.String
+	isList := fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map || fe.Kind() == reflect.Array
+
+	switch fe.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "min", "gte":
+		if isString {
+			return fmt.Sprintf("%s must be at least %s characters", field, fe.Param())
+		}
+		if isList {
+			return fmt.Sprintf("%s must contain at least %s items", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", field, fe.Param())
+	case "max", "lte":
+		if isString {
+			return fmt.Sprintf("%s must be at most %s characters", field, fe.Param())
+		}
+		if isList {
+			return fmt.Sprintf("%s must contain at most %s items", field, fe.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", field, fe.Param())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", field, fe.Param())
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s", field, fe.Param())
+	case "len":
+		return fmt.Sprintf("%s must have length %s", field, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.ReplaceAll(fe.Param(), " ", ", "))
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", field)
+	}
+	return fmt.Sprintf("%s failed the %s validation", field, fe.Tag())
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
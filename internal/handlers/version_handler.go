@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/repository"
+	"go-barcode-webapp/internal/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VersionHandler reports the deployed build and database schema version
+type VersionHandler struct {
+	db *repository.Database
+}
+
+func NewVersionHandler(db *repository.Database) *VersionHandler {
+	return &VersionHandler{db: db}
+}
+
+// GetVersion returns the running version. Commit, build and schema details
+// are only included for signed-in users.
+func (h *VersionHandler) GetVersion(c *gin.Context) {
+	info := version.Get()
+
+	if _, exists := GetCurrentUser(c); !exists {
+		c.JSON(http.StatusOK, gin.H{"version": info.Version})
+		return
+	}
+
+	applied, err := h.db.SchemaVersion()
+	if err != nil {
+		logger.Errorf("GetVersion: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read schema version"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version":   info.Version,
+		"commit":    info.Commit,
+		"buildDate": info.BuildDate,
+		"goVersion": info.GoVersion,
+		"schema": gin.H{
+			"applied":  applied,
+			"expected": version.SchemaVersion,
+			"current":  applied >= version.SchemaVersion,
+		},
+	})
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -14,6 +17,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
@@ -363,6 +367,8 @@ func (h *WebAuthnHandler) CompletePasskeyAuthentication(c *gin.Context) {
 		UserID:    user.UserID,
 		ExpiresAt: time.Now().Add(24 * time.Hour), // 24 hour session
 		CreatedAt: time.Now(),
+		UserAgent: sessionUserAgent(c),
+		IPAddress: c.ClientIP(),
 	}
 	
 	if err := h.db.Create(&userSession).Error; err != nil {
@@ -495,17 +501,16 @@ func (h *WebAuthnHandler) Setup2FA(c *gin.Context) {
 	}
 
 	// Generate backup codes
-	backupCodes := make([]string, 10)
-	for i := range backupCodes {
-		code := make([]byte, 6)
-		rand.Read(code)
-		backupCodes[i] = fmt.Sprintf("%x", code)[:8]
+	backupCodes, err := generateBackupCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate backup codes"})
+		return
 	}
 
 	// Create 2FA record with manual JSON serialization
 	
-	// Convert backup codes to JSON manually
-	backupCodesJSON, err := json.Marshal(backupCodes)
+	// Only hashes of the backup codes are stored
+	backupCodesJSON, err := hashBackupCodes(backupCodes)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to setup 2FA"})
 		return
@@ -623,12 +628,7 @@ func (h *WebAuthnHandler) Disable2FA(c *gin.Context) {
 		var backupCodeList []string
 		if backupCodes != "" && backupCodes != "[]" {
 			json.Unmarshal([]byte(backupCodes), &backupCodeList)
-			for _, backupCode := range backupCodeList {
-				if backupCode == request.Code {
-					valid = true
-					break
-				}
-			}
+			valid = matchBackupCode(backupCodeList, request.Code) >= 0
 		}
 		
 		if !valid {
@@ -646,6 +646,65 @@ func (h *WebAuthnHandler) Disable2FA(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "2FA disabled successfully"})
 }
 
+// RegenerateBackupCodes replaces the user's backup codes with a fresh set
+// after checking a current TOTP code. The new codes are only shown in this
+// response; the old ones stop working.
+func (h *WebAuthnHandler) RegenerateBackupCodes(c *gin.Context) {
+	currentUser, exists := GetCurrentUser(c)
+	if !exists || currentUser == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var request struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var secret string
+	var isEnabled bool
+	row := h.db.Raw("SELECT secret, is_enabled FROM user_2fa WHERE user_id = ? LIMIT 1", currentUser.UserID).Row()
+	if err := row.Scan(&secret, &isEnabled); err != nil || !isEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "2FA not enabled"})
+		return
+	}
+
+	// Only the authenticator app proves possession; a backup code can't be
+	// used to mint new ones
+	if !totp.Validate(request.Code, secret) {
+		h.logAuthAttempt(currentUser.UserID, "2fa_backup_codes", c.ClientIP(), c.GetHeader("User-Agent"), false, stringPtr("Invalid TOTP code"), nil)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+		return
+	}
+
+	backupCodes, err := generateBackupCodes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate backup codes"})
+		return
+	}
+	backupCodesJSON, err := hashBackupCodes(backupCodes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate backup codes"})
+		return
+	}
+
+	if err := h.db.Exec("UPDATE user_2fa SET backup_codes = ?, updated_at = ? WHERE user_id = ?",
+		string(backupCodesJSON), time.Now(), currentUser.UserID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save backup codes"})
+		return
+	}
+
+	h.logAuthAttempt(currentUser.UserID, "2fa_backup_codes", c.ClientIP(), c.GetHeader("User-Agent"), true, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"backupCodes": backupCodes,
+		"message":     "New backup codes generated. Store them safely; they won't be shown again.",
+	})
+}
+
 // Get2FAStatus returns the current 2FA status for the user
 func (h *WebAuthnHandler) Get2FAStatus(c *gin.Context) {
 	currentUser, exists := GetCurrentUser(c)
@@ -671,6 +730,7 @@ func (h *WebAuthnHandler) Get2FAStatus(c *gin.Context) {
 			"verified":   false,
 			"setupDate":  nil,
 			"lastUsed":   nil,
+			"backupCodesCount": 0,
 		})
 		return
 	}
@@ -714,6 +774,116 @@ func (h *WebAuthnHandler) logAuthAttempt(userID uint, method, ipAddress, userAge
 	h.db.Create(&attempt)
 }
 
+// backupCodeCount is how many backup codes a user gets
+const backupCodeCount = 10
+
+// generateBackupCodes returns a set of random single-use backup codes of 8
+// hex characters
+func generateBackupCodes() ([]string, error) {
+	codes := make([]string, backupCodeCount)
+	for i := range codes {
+		code := make([]byte, 4)
+		if _, err := rand.Read(code); err != nil {
+			return nil, err
+		}
+		codes[i] = fmt.Sprintf("%x", code)
+	}
+	return codes, nil
+}
+
+// hashBackupCodes returns the JSON list of bcrypt hashes stored for the codes
+func hashBackupCodes(codes []string) ([]byte, error) {
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = string(hash)
+	}
+	return json.Marshal(hashes)
+}
+
+// isHashedBackupCode tells bcrypt hashes from the plaintext codes stored by
+// earlier releases
+func isHashedBackupCode(stored string) bool {
+	return strings.HasPrefix(stored, "$2")
+}
+
+// matchBackupCode returns the index of the stored backup code, hashed or
+// plaintext, that the submitted code matches, or -1
+func matchBackupCode(stored []string, code string) int {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return -1
+	}
+	for i, entry := range stored {
+		if isHashedBackupCode(entry) {
+			if bcrypt.CompareHashAndPassword([]byte(entry), []byte(code)) == nil {
+				return i
+			}
+		} else if subtle.ConstantTimeCompare([]byte(entry), []byte(code)) == 1 {
+			return i
+		}
+	}
+	return -1
+}
+
+// consumeBackupCode checks a backup code of the user and removes it, so each
+// code works once. Remaining plaintext codes from earlier releases are
+// replaced by their hashes on the way.
+func consumeBackupCode(db *gorm.DB, userID uint, code string) (bool, error) {
+	used := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var backupCodesJSON sql.NullString
+		row := tx.Raw("SELECT backup_codes FROM user_2fa WHERE user_id = ? FOR UPDATE", userID).Row()
+		if err := row.Scan(&backupCodesJSON); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		if backupCodesJSON.String == "" {
+			return nil
+		}
+
+		var stored []string
+		if err := json.Unmarshal([]byte(backupCodesJSON.String), &stored); err != nil {
+			return err
+		}
+		index := matchBackupCode(stored, code)
+		if index < 0 {
+			return nil
+		}
+		used = true
+
+		remaining := make([]string, 0, len(stored)-1)
+		for i, entry := range stored {
+			if i == index {
+				continue
+			}
+			if !isHashedBackupCode(entry) {
+				hash, err := bcrypt.GenerateFromPassword([]byte(entry), bcrypt.DefaultCost)
+				if err != nil {
+					return err
+				}
+				entry = string(hash)
+			}
+			remaining = append(remaining, entry)
+		}
+		remainingJSON, err := json.Marshal(remaining)
+		if err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE user_2fa SET backup_codes = ?, updated_at = ? WHERE user_id = ?",
+			string(remainingJSON), time.Now(), userID).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return used, nil
+}
+
 // generateSessionID creates a new session ID
 func generateSessionID() string {
 	bytes := make([]byte, 32)
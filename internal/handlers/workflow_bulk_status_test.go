@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupDeviceStatusColumn is the devices.status column of the setup schema
+const setupDeviceStatusColumn = "enum('available','checked out','maintenance','retired')"
+
+func TestBulkUpdateDeviceStatusValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		status    string
+		schemaErr error
+		want      int
+	}{
+		{"status of the schema", "retired", nil, http.StatusOK},
+		{"status of the other schema", "free", nil, http.StatusBadRequest},
+		{"unknown status", "lost", nil, http.StatusBadRequest},
+		{"schema unreadable", "retired", errors.New("connection lost"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t, func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+				if strings.Contains(query, "INFORMATION_SCHEMA.COLUMNS") {
+					if tt.schemaErr != nil {
+						return nil, nil, tt.schemaErr
+					}
+					return []string{"COLUMN_TYPE"}, [][]driver.Value{{setupDeviceStatusColumn}}, nil
+				}
+				// No devices: each one is reported as not found
+				return nil, nil, nil
+			})
+			handler := NewWorkflowHandler(nil, nil, nil, repository.NewDeviceRepository(db, nil), db.DB, nil)
+
+			router := gin.New()
+			router.POST("/workflow/bulk/update-status", handler.BulkUpdateDeviceStatus)
+
+			w := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/workflow/bulk/update-status",
+				strings.NewReader(`{"deviceIds": ["DEV-1"], "status": "`+tt.status+`"}`))
+			request.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, request)
+
+			if w.Code != tt.want {
+				t.Fatalf("bulk status %q = %d: %s; want %d", tt.status, w.Code, w.Body.String(), tt.want)
+			}
+			if tt.want == http.StatusBadRequest && !strings.Contains(w.Body.String(), `"field":"status"`) {
+				t.Errorf("bulk status %q error = %s; want a status field error", tt.status, w.Body.String())
+			}
+		})
+	}
+}
@@ -3,18 +3,22 @@ package handlers
 import (
 	"archive/zip"
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	"image/png"
-	"log"
+	"math"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 	"go-barcode-webapp/internal/repository"
 	"go-barcode-webapp/internal/services"
@@ -64,9 +68,15 @@ func getDefaultStatusID() *uint {
 // EQUIPMENT PACKAGES - PLACEHOLDER METHODS
 // ================================================================
 
-// ListEquipmentPackages displays all equipment packages
+// Page sizes of the equipment package list
+const (
+	equipmentPackagesPerPage    = 25
+	maxEquipmentPackagesPerPage = 100
+)
+
+// ListEquipmentPackages displays one page of equipment packages
 func (h *WorkflowHandler) ListEquipmentPackages(c *gin.Context) {
-	log.Printf("🎯 WORKFLOW HANDLER: ListEquipmentPackages called")
+	logger.Debugf("WORKFLOW HANDLER: ListEquipmentPackages called")
 	user, _ := GetCurrentUser(c)
 	
 	params := &models.FilterParams{}
@@ -75,18 +85,29 @@ func (h *WorkflowHandler) ListEquipmentPackages(c *gin.Context) {
 		return
 	}
 
-	packages, err := h.packageRepo.List(params)
+	// Handle pagination
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.Limit <= 0 {
+		params.Limit = equipmentPackagesPerPage
+	} else if params.Limit > maxEquipmentPackagesPerPage {
+		params.Limit = maxEquipmentPackagesPerPage
+	}
+	params.Offset = (params.Page - 1) * params.Limit
+
+	packages, totalCount, err := h.packageRepo.ListPage(params)
 	if err != nil {
-		log.Printf("ListEquipmentPackages: Error fetching packages: %v", err)
+		logger.Errorf("ListEquipmentPackages: Error fetching packages: %v", err)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{"error": "Failed to load equipment packages", "user": user})
 		return
 	}
 
-	log.Printf("🎯 WORKFLOW HANDLER: Got %d packages from repository", len(packages))
+	logger.Debugf("WORKFLOW HANDLER: Got %d packages from repository", len(packages))
 
 	// Use the same enrichment logic as equipment package handler
 	for i := range packages {
-		log.Printf("🎯 WORKFLOW: Package %d ('%s') has %d PackageDevices BEFORE enrichment", 
+		logger.Debugf("WORKFLOW: Package %d ('%s') has %d PackageDevices BEFORE enrichment", 
 			packages[i].PackageID, packages[i].Name, len(packages[i].PackageDevices))
 		// Calculate total value and price
 		totalValue := 0.0
@@ -115,21 +136,29 @@ func (h *WorkflowHandler) ListEquipmentPackages(c *gin.Context) {
 		packages[i].TotalValue = totalValue
 		packages[i].CalculatedPrice = calculatedPrice
 		packages[i].DeviceCount = len(packages[i].PackageDevices)
-		log.Printf("🎯 WORKFLOW: Package %d ('%s') has %d PackageDevices AFTER enrichment, DeviceCount=%d", 
+		logger.Debugf("WORKFLOW: Package %d ('%s') has %d PackageDevices AFTER enrichment, DeviceCount=%d", 
 			packages[i].PackageID, packages[i].Name, len(packages[i].PackageDevices), packages[i].DeviceCount)
 	}
 
+	totalPages := int((totalCount + int64(params.Limit) - 1) / int64(params.Limit))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+
 	// Get additional data that the standalone template expects
-	totalCount, _ := h.packageRepo.GetTotalCount(params)
 	popularPackages, _ := h.packageRepo.GetPopularPackages(5)
 
-	log.Printf("DEBUG: ListEquipmentPackages: Attempting to render equipment_packages_standalone.html")
+	logger.Debugf("ListEquipmentPackages: Attempting to render equipment_packages_standalone.html")
 	c.HTML(http.StatusOK, "equipment_packages_standalone.html", gin.H{
 		"packages":        packages,
 		"popularPackages": popularPackages,
 		"totalCount":      totalCount,
 		"filters":         params,
 		"user":            user,
+		"pageNumber":      params.Page,
+		"hasPrevPage":     params.Page > 1,
+		"hasNextPage":     params.Page < totalPages,
+		"totalPages":      totalPages,
 	})
 }
 
@@ -138,7 +167,7 @@ func (h *WorkflowHandler) NewEquipmentPackageForm(c *gin.Context) {
 	// Get current user for base template
 	currentUser, exists := GetCurrentUser(c)
 	if !exists {
-		log.Printf("NewEquipmentPackageForm: User not authenticated")
+		logger.Debugf("NewEquipmentPackageForm: User not authenticated")
 		c.Redirect(http.StatusSeeOther, "/login")
 		return
 	}
@@ -146,13 +175,13 @@ func (h *WorkflowHandler) NewEquipmentPackageForm(c *gin.Context) {
 	// Get available devices for the dropdown
 	availableDevices, err := h.packageRepo.GetAvailableDevices()
 	if err != nil {
-		log.Printf("NewEquipmentPackageForm: Error fetching available devices: %v", err)
+		logger.Errorf("NewEquipmentPackageForm: Error fetching available devices: %v", err)
 		availableDevices = []models.Device{} // Use empty slice if error
 	}
 	
-	log.Printf("NewEquipmentPackageForm: Found %d available devices", len(availableDevices))
+	logger.Debugf("NewEquipmentPackageForm: Found %d available devices", len(availableDevices))
 	if len(availableDevices) > 0 {
-		log.Printf("NewEquipmentPackageForm: Sample device: ID=%s, Product=%v", 
+		logger.Debugf("NewEquipmentPackageForm: Sample device: ID=%s, Product=%v", 
 			availableDevices[0].DeviceID, 
 			func() string { if availableDevices[0].Product != nil { return availableDevices[0].Product.Name } else { return "nil" } }())
 	}
@@ -276,12 +305,44 @@ func (h *WorkflowHandler) CreateEquipmentPackage(c *gin.Context) {
 		return
 	}
 
+	// Every selected device must exist, otherwise the package would reference nothing
+	deviceIDs := make([]string, 0, len(deviceMappings))
+	for _, mapping := range deviceMappings {
+		deviceIDs = append(deviceIDs, mapping.DeviceID)
+	}
+	missing, err := h.packageRepo.FindMissingDevices(deviceIDs)
+	if err != nil {
+		logger.Errorf("CreateEquipmentPackage: Failed to validate devices: %v", err)
+	}
+	if err != nil || len(missing) > 0 {
+		message := "Failed to validate the selected devices"
+		if len(missing) == 1 {
+			message = fmt.Sprintf("Device %s does not exist", missing[0])
+		} else if len(missing) > 1 {
+			message = fmt.Sprintf("Devices %s do not exist", strings.Join(missing, ", "))
+		}
+		status := http.StatusBadRequest
+		if err != nil {
+			status = http.StatusInternalServerError
+		}
+		availableDevices, _ := h.packageRepo.GetAvailableDevices()
+		c.HTML(status, "equipment_package_form.html", gin.H{
+			"title":            "New Equipment Package",
+			"package":          &pkg,
+			"isEdit":           false,
+			"error":            message,
+			"user":             currentUser,
+			"availableDevices": availableDevices,
+		})
+		return
+	}
+
 	// Set creator
 	pkg.CreatedBy = &currentUser.UserID
 	
 	// Save to database with device associations
 	if err := h.packageRepo.CreateWithDevices(&pkg, deviceMappings); err != nil {
-		log.Printf("CreateEquipmentPackage: Database error: %v", err)
+		logger.Errorf("CreateEquipmentPackage: Database error: %v", err)
 		availableDevices, _ := h.packageRepo.GetAvailableDevices()
 		c.HTML(http.StatusInternalServerError, "equipment_package_form.html", gin.H{
 			"title":            "New Equipment Package",
@@ -294,7 +355,9 @@ func (h *WorkflowHandler) CreateEquipmentPackage(c *gin.Context) {
 		return
 	}
 	
-	log.Printf("CreateEquipmentPackage: Successfully created package '%s' (ID: %d) with %d devices by user %s", 
+	invalidateDeviceCaches()
+	
+	logger.Debugf("CreateEquipmentPackage: Successfully created package '%s' (ID: %d) with %d devices by user %s", 
 		pkg.Name, pkg.PackageID, len(deviceMappings), currentUser.Username)
 	
 	// Redirect to packages list on success
@@ -347,7 +410,7 @@ func (h *WorkflowHandler) GetEquipmentPackageForm(c *gin.Context) {
 	if packageIDStr == "new" {
 		availableDevices, err := h.packageRepo.GetAvailableDevices()
 		if err != nil {
-			log.Printf("GetEquipmentPackageForm: Error fetching available devices: %v", err)
+			logger.Errorf("GetEquipmentPackageForm: Error fetching available devices: %v", err)
 		}
 		c.HTML(http.StatusOK, "equipment_package_form.html", gin.H{
 			"title":            "New Equipment Package",
@@ -373,7 +436,7 @@ func (h *WorkflowHandler) GetEquipmentPackageForm(c *gin.Context) {
 
 	availableDevices, err := h.packageRepo.GetAvailableDevices()
 	if err != nil {
-		log.Printf("GetEquipmentPackageForm: Error fetching available devices: %v", err)
+		logger.Errorf("GetEquipmentPackageForm: Error fetching available devices: %v", err)
 	}
 
 	c.HTML(http.StatusOK, "equipment_package_form.html", gin.H{
@@ -494,19 +557,25 @@ func (h *WorkflowHandler) UpdateEquipmentPackage(c *gin.Context) {
 
 	// Update device associations
 	if err := h.packageRepo.UpdateDeviceAssociations(uint(packageID), deviceMappings); err != nil {
-		log.Printf("UpdateEquipmentPackage: Database error: %v", err)
+		var duplicateErr *repository.DuplicatePackageDeviceError
+		if errors.As(err, &duplicateErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "duplicateDevices": duplicateErr.DeviceIDs})
+			return
+		}
+		logger.Errorf("UpdateEquipmentPackage: Database error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update device associations: " + err.Error()})
 		return
 	}
 
 	// Save changes to the package
 	if err := h.packageRepo.Update(&pkg); err != nil {
-		log.Printf("UpdateEquipmentPackage: Error updating package %d: %v", packageID, err)
+		logger.Errorf("UpdateEquipmentPackage: Error updating package %d: %v", packageID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update package"})
 		return
 	}
+	invalidateDeviceCaches()
 
-	log.Printf("UpdateEquipmentPackage: Package %d updated successfully by user %s", packageID, currentUser.Username)
+	logger.Debugf("UpdateEquipmentPackage: Package %d updated successfully by user %s", packageID, currentUser.Username)
 	c.Redirect(http.StatusSeeOther, "/workflow/packages")
 }
 
@@ -537,26 +606,52 @@ func (h *WorkflowHandler) DeleteEquipmentPackage(c *gin.Context) {
 		return
 	}
 
-	// Delete associated package devices first
-	if err := h.db.Where("packageID = ?", packageID).Delete(&models.PackageDevice{}).Error; err != nil {
-		log.Printf("DeleteEquipmentPackage: Error deleting package devices for package %d: %v", packageID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete package devices"})
-		return
-	}
-
-	// Delete the package
-	if err := h.db.Delete(&pkg).Error; err != nil {
-		log.Printf("DeleteEquipmentPackage: Error deleting package %d: %v", packageID, err)
+	// Archive instead of deleting so jobs that used the package keep their history
+	if err := h.packageRepo.Archive(pkg.PackageID); err != nil {
+		logger.Errorf("DeleteEquipmentPackage: Error archiving package %d: %v", packageID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete package"})
 		return
 	}
 
-	log.Printf("DeleteEquipmentPackage: Package %d deleted successfully by user %s", packageID, currentUser.Username)
+	invalidateDeviceCaches()
+
+	logger.Debugf("DeleteEquipmentPackage: Package %d archived by user %s", packageID, currentUser.Username)
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Package deleted successfully",
+		"message":  "Package archived successfully",
+		"archived": true,
 	})
 }
 
+// RestoreEquipmentPackage brings an archived package back into the package list
+func (h *WorkflowHandler) RestoreEquipmentPackage(c *gin.Context) {
+	packageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid package ID"})
+		return
+	}
+
+	currentUser, exists := GetCurrentUser(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	if err := h.packageRepo.Restore(uint(packageID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Archived package not found"})
+			return
+		}
+		logger.Errorf("RestoreEquipmentPackage: Error restoring package %d: %v", packageID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore package"})
+		return
+	}
+
+	invalidateDeviceCaches()
+
+	logger.Debugf("RestoreEquipmentPackage: Package %d restored by user %s", packageID, currentUser.Username)
+	c.JSON(http.StatusOK, gin.H{"message": "Package restored; activate it to use it again"})
+}
+
 // DebugPackageForm shows debug info for package form
 func (h *WorkflowHandler) DebugPackageForm(c *gin.Context) {
 	currentUser, exists := GetCurrentUser(c)
@@ -568,7 +663,7 @@ func (h *WorkflowHandler) DebugPackageForm(c *gin.Context) {
 	// Get available devices for debugging
 	availableDevices, err := h.packageRepo.GetAvailableDevices()
 	if err != nil {
-		log.Printf("DebugPackageForm: Error fetching available devices: %v", err)
+		logger.Errorf("PackageForm: Error fetching available devices: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -580,6 +675,74 @@ func (h *WorkflowHandler) DebugPackageForm(c *gin.Context) {
 	})
 }
 
+// ApplyPackageRequest selects the equipment package to apply to a job
+type ApplyPackageRequest struct {
+	JobID     uint `json:"jobId" binding:"required"`
+	PackageID uint `json:"packageId" binding:"required"`
+}
+
+// ApplyPackageToJob assigns the devices of an equipment package to a job in one
+// transaction and counts the package as used. Unavailable devices are skipped
+// and reported instead of failing the whole package.
+func (h *WorkflowHandler) ApplyPackageToJob(c *gin.Context) {
+	var request ApplyPackageRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	pkg, err := h.packageRepo.GetWithDevices(request.PackageID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Equipment package not found"})
+		return
+	}
+	if pkg.IsArchived {
+		c.JSON(http.StatusConflict, gin.H{"error": "Equipment package is archived; restore and activate it to apply it"})
+		return
+	}
+	if !pkg.IsActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Equipment package is inactive; activate it to apply it"})
+		return
+	}
+	if len(pkg.PackageDevices) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Equipment package has no devices"})
+		return
+	}
+	if _, err := h.jobRepo.GetByID(request.JobID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	var result *repository.PackageApplication
+	err = h.jobRepo.GetDB().WithTransaction(func(tx *repository.Database) error {
+		var err error
+		result, err = h.jobRepo.WithTx(tx).ApplyPackage(request.JobID, pkg.PackageDevices)
+		if err != nil {
+			return err
+		}
+		if len(result.Assigned) == 0 {
+			return nil
+		}
+		return h.packageRepo.WithTx(tx).IncrementUsageCount(pkg.PackageID)
+	})
+	if err != nil {
+		logger.Errorf("ApplyPackageToJob: failed to apply package %d to job %d: %v", request.PackageID, request.JobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply package to job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       fmt.Sprintf("%d devices assigned, %d skipped", len(result.Assigned), len(result.Skipped)),
+		"jobId":         request.JobID,
+		"packageId":     pkg.PackageID,
+		"packageName":   pkg.Name,
+		"assigned":      result.Assigned,
+		"skipped":       result.Skipped,
+		"assignedCount": len(result.Assigned),
+		"skippedCount":  len(result.Skipped),
+	})
+}
+
 // ================================================================
 // BULK OPERATIONS - PLACEHOLDER METHODS
 // ================================================================
@@ -595,19 +758,60 @@ func (h *WorkflowHandler) BulkOperationsForm(c *gin.Context) {
 	})
 }
 
-// BulkUpdateDeviceStatus updates multiple device statuses
+// BulkUpdateDeviceStatus updates multiple device statuses and reports the
+// outcome per device, so unknown IDs don't hide which devices were updated
 func (h *WorkflowHandler) BulkUpdateDeviceStatus(c *gin.Context) {
-	// TODO: Implement bulk device status update
-	log.Printf("BulkUpdateDeviceStatus: Not yet implemented")
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"error": "Bulk device status update not yet implemented",
+	var request models.BulkDeviceStatusRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	supported, err := h.deviceRepo.SupportedStatuses()
+	if err != nil {
+		logger.Errorf("Bulk status update: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update device statuses"})
+		return
+	}
+	if !models.IsValidDeviceStatus(request.Status, supported) {
+		respondFieldErrors(c, []FieldError{{
+			Field:   "status",
+			Rule:    "oneof",
+			Param:   strings.Join(supported, ","),
+			Message: fmt.Sprintf("status %q is not a known device status", request.Status),
+		}})
+		return
+	}
+
+	results, err := h.deviceRepo.BulkUpdateStatus(request.DeviceIDs, request.Status, request.Notes)
+	if err != nil {
+		logger.Errorf("Bulk status update to %q failed: %v", request.Status, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update device statuses"})
+		return
+	}
+	invalidateDeviceCaches()
+
+	updated := 0
+	for _, result := range results {
+		if result.Success {
+			updated++
+		}
+	}
+	logger.Infof("Bulk status update to %q: %d of %d devices updated", request.Status, updated, len(results))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        fmt.Sprintf("Updated %d of %d devices to %q", updated, len(results), request.Status),
+		"status":         request.Status,
+		"updated":        updated,
+		"devicesUpdated": updated,
+		"failed":         len(results) - updated,
+		"results":        results,
 	})
 }
 
 // BulkAssignToJob assigns multiple devices to a job
 func (h *WorkflowHandler) BulkAssignToJob(c *gin.Context) {
 	// TODO: Implement bulk device assignment
-	log.Printf("BulkAssignToJob: Not yet implemented")
+	logger.Debugf("BulkAssignToJob: Not yet implemented")
 	c.JSON(http.StatusNotImplemented, gin.H{
 		"error": "Bulk device assignment not yet implemented",
 	})
@@ -620,6 +824,8 @@ func (h *WorkflowHandler) BulkGenerateQRCodes(c *gin.Context) {
 		DeviceIDs    []string `json:"deviceIds" form:"deviceIds"`
 		Format       string   `json:"format" form:"format"`       // "pdf" or "zip"
 		LabelFormat  string   `json:"labelFormat" form:"labelFormat"` // "simple" or "detailed"
+		LabelSheet   string   `json:"labelSheet" form:"labelSheet"`   // "3x7", "4x10" or "5x13"
+		CodeType     string   `json:"codeType" form:"codeType"`       // "qr" or "barcode"
 		PrintReady   bool     `json:"printReady" form:"printReady"`
 	}
 
@@ -641,34 +847,40 @@ func (h *WorkflowHandler) BulkGenerateQRCodes(c *gin.Context) {
 	if request.LabelFormat == "" {
 		request.LabelFormat = "simple"
 	}
+	request.CodeType = labelCodeType(request.CodeType)
 
-	log.Printf("Generating QR codes for %d devices, format: %s", len(request.DeviceIDs), request.Format)
+	logger.Debugf("Generating QR codes for %d devices, format: %s", len(request.DeviceIDs), request.Format)
 
 	// Fetch device information
 	devices := make([]models.Device, 0, len(request.DeviceIDs))
+	knownDeviceIDs := make([]string, 0, len(request.DeviceIDs))
 	for _, deviceID := range request.DeviceIDs {
 		var device models.Device
 		if err := h.db.Preload("Product").Preload("Product.Brand").Where("deviceID = ?", deviceID).First(&device).Error; err != nil {
-			log.Printf("Warning: Device %s not found in database, will generate QR anyway", deviceID)
+			logger.Warnf("Device %s not found in database, will generate QR anyway", deviceID)
 			// Create a minimal device record for QR generation
 			device = models.Device{
 				DeviceID: deviceID,
 				Status:   "unknown",
 				Product:  nil, // Will be handled in template
 			}
+		} else {
+			knownDeviceIDs = append(knownDeviceIDs, deviceID)
 		}
 		devices = append(devices, device)
 	}
 
 	if request.Format == "zip" {
 		// Generate PNG files and create ZIP
-		zipBytes, err := h.generateDeviceLabelsZIP(devices, request.LabelFormat, request.PrintReady)
+		zipBytes, err := h.generateDeviceLabelsZIP(devices, request.LabelFormat, request.CodeType, request.PrintReady)
 		if err != nil {
-			log.Printf("Error generating device labels ZIP: %v", err)
+			logger.Errorf("Error generating device labels ZIP: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate device labels ZIP"})
 			return
 		}
 
+		h.recordLabelPrints(c, knownDeviceIDs, request.Format, request.LabelFormat)
+
 		// Set headers for ZIP download
 		filename := fmt.Sprintf("device_labels_%s.zip", time.Now().Format("20060102_150405"))
 		c.Header("Content-Type", "application/zip")
@@ -679,13 +891,15 @@ func (h *WorkflowHandler) BulkGenerateQRCodes(c *gin.Context) {
 		c.Data(http.StatusOK, "application/zip", zipBytes)
 	} else {
 		// Generate PDF with multiple labels per page
-		pdfBytes, err := h.generateDeviceLabelsPDF(devices, request.LabelFormat, request.PrintReady)
+		pdfBytes, err := h.generateDeviceLabelsPDF(devices, request.LabelFormat, request.LabelSheet, request.CodeType, request.PrintReady)
 		if err != nil {
-			log.Printf("Error generating device labels PDF: %v", err)
+			logger.Errorf("Error generating device labels PDF: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate device labels PDF"})
 			return
 		}
 
+		h.recordLabelPrints(c, knownDeviceIDs, "pdf", request.LabelFormat)
+
 		// Set headers for PDF download
 		filename := fmt.Sprintf("device_labels_%s.pdf", time.Now().Format("20060102_150405"))
 		c.Header("Content-Type", "application/pdf")
@@ -697,11 +911,74 @@ func (h *WorkflowHandler) BulkGenerateQRCodes(c *gin.Context) {
 	}
 }
 
+// recordLabelPrints logs the generated labels per device so worn or
+// duplicated labels can be traced. Failing to log doesn't fail the download.
+func (h *WorkflowHandler) recordLabelPrints(c *gin.Context, deviceIDs []string, outputFormat, labelFormat string) {
+	var printedBy *uint
+	if user, ok := GetCurrentUser(c); ok {
+		printedBy = &user.UserID
+	}
+	if err := h.deviceRepo.RecordLabelPrints(deviceIDs, printedBy, outputFormat, labelFormat); err != nil {
+		logger.Errorf("Failed to record label prints for %d devices: %v", len(deviceIDs), err)
+	}
+}
+
+// Codes printed on device labels
+const (
+	labelCodeQR      = "qr"
+	labelCodeBarcode = "barcode"
+)
+
+// labelCodeType normalizes the requested label code, defaulting to QR codes
+func labelCodeType(codeType string) string {
+	if strings.EqualFold(strings.TrimSpace(codeType), labelCodeBarcode) {
+		return labelCodeBarcode
+	}
+	return labelCodeQR
+}
+
+// labelSheetLayout describes an A4 label sheet in mm. Gaps are the space
+// between neighbouring labels.
+type labelSheetLayout struct {
+	Columns    int
+	Rows       int
+	Width      float64
+	Height     float64
+	MarginLeft float64
+	MarginTop  float64
+	GapX       float64
+	GapY       float64
+}
+
+// labelSheets are the supported label stocks by "<columns>x<rows>"
+var labelSheets = map[string]labelSheetLayout{
+	// Original layout, fits Avery L7160 style 3x7 sheets
+	"3x7": {Columns: 3, Rows: 7, Width: 60, Height: 35, MarginLeft: 10, MarginTop: 10},
+	// Avery L7654
+	"4x10": {Columns: 4, Rows: 10, Width: 45.7, Height: 25.4, MarginLeft: 9.7, MarginTop: 21.5, GapX: 2.5},
+	// Avery L7651
+	"5x13": {Columns: 5, Rows: 13, Width: 38.1, Height: 21.2, MarginLeft: 4.75, MarginTop: 10.7, GapX: 2.5},
+}
+
+// getLabelSheet returns the layout for a label sheet name, falling back to
+// the 3x7 sheet for empty or unknown names
+func getLabelSheet(name string) labelSheetLayout {
+	if sheet, ok := labelSheets[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return sheet
+	}
+	if name != "" {
+		logger.Warnf("Unknown label sheet %q, using 3x7", name)
+	}
+	return labelSheets["3x7"]
+}
+
 // generateDeviceLabelsPDF creates a PDF with multiple device labels per page
-func (h *WorkflowHandler) generateDeviceLabelsPDF(devices []models.Device, labelFormat string, printReady bool) ([]byte, error) {
+func (h *WorkflowHandler) generateDeviceLabelsPDF(devices []models.Device, labelFormat, labelSheet, codeType string, printReady bool) ([]byte, error) {
 	// Create PDF document - A4 Portrait for multiple labels
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.SetMargins(10, 10, 10)
+	// Labels are positioned explicitly; dense sheets reach into the bottom margin
+	pdf.SetAutoPageBreak(false, 0)
 	
 	// Load logo if exists
 	logoPath := "logo.png"
@@ -710,11 +987,12 @@ func (h *WorkflowHandler) generateDeviceLabelsPDF(devices []models.Device, label
 		logoExists = true
 	}
 	
-	// Label dimensions - 3x7 grid on A4 (21 labels per page)
-	labelWidth := 60.0
-	labelHeight := 35.0
-	labelsPerRow := 3
-	labelsPerCol := 7
+	// Label dimensions from the selected sheet
+	sheet := getLabelSheet(labelSheet)
+	labelWidth := sheet.Width
+	labelHeight := sheet.Height
+	labelsPerRow := sheet.Columns
+	labelsPerCol := sheet.Rows
 	labelsPerPage := labelsPerRow * labelsPerCol
 	
 	// Process devices in batches per page
@@ -729,10 +1007,10 @@ func (h *WorkflowHandler) generateDeviceLabelsPDF(devices []models.Device, label
 			row := i / labelsPerRow
 			col := i % labelsPerRow
 			
-			offsetX := 10.0 + float64(col)*labelWidth
-			offsetY := 10.0 + float64(row)*labelHeight
+			offsetX := sheet.MarginLeft + float64(col)*(labelWidth+sheet.GapX)
+			offsetY := sheet.MarginTop + float64(row)*(labelHeight+sheet.GapY)
 			
-			h.drawSingleLabel(pdf, device, offsetX, offsetY, labelWidth, labelHeight, logoExists, logoPath)
+			h.drawSingleLabel(pdf, device, offsetX, offsetY, labelWidth, labelHeight, logoExists, logoPath, codeType)
 		}
 	}
 	
@@ -746,89 +1024,131 @@ func (h *WorkflowHandler) generateDeviceLabelsPDF(devices []models.Device, label
 	return buf.Bytes(), nil
 }
 
-// drawSingleLabel draws a single device label at the specified position
-func (h *WorkflowHandler) drawSingleLabel(pdf *gofpdf.Fpdf, device models.Device, offsetX, offsetY, width, height float64, logoExists bool, logoPath string) {
+// drawSingleLabel draws a single device label at the specified position. The
+// layout is designed for a 60x35mm label and scaled to the given size. QR
+// labels put a square code on the left with the text beside it; barcode labels
+// put a Code128 across the top.
+func (h *WorkflowHandler) drawSingleLabel(pdf *gofpdf.Fpdf, device models.Device, offsetX, offsetY, width, height float64, logoExists bool, logoPath, codeType string) {
 	// Get product name
 	productName := "Unknown Product"
 	if device.Product != nil {
 		productName = device.Product.Name
 	}
 	
+	// Scale factors relative to the 60x35mm base layout
+	scaleX := width / 60
+	scaleY := height / 35
+	scale := math.Min(scaleX, scaleY)
+	
 	// Draw border around label (optional)
 	pdf.SetDrawColor(200, 200, 200)
 	pdf.Rect(offsetX, offsetY, width, height, "D")
 	
 	// 1. Logo at right side, vertically centered (if exists)
 	if logoExists {
-		logoX := offsetX + width - 20
-		logoY := offsetY + (height-8)/2
-		pdf.Image(logoPath, logoX, logoY, 15, 8, false, "", 0, "")
+		logoWidth := 15 * scale
+		logoHeight := 8 * scale
+		logoX := offsetX + width - 20*scaleX
+		logoY := offsetY + (height-logoHeight)/2
+		pdf.Image(logoPath, logoX, logoY, logoWidth, logoHeight, false, "", 0, "")
 	}
 	
-	// Remove the title - start barcode higher up
+	textX := offsetX + 2*scaleX
+	qrSize := height - 4*scaleY
+	if codeType == labelCodeQR && h.drawLabelQRCode(pdf, device.DeviceID, offsetX+2*scaleX, offsetY+2*scaleY, qrSize) {
+		textX += qrSize + 2*scaleX
+	} else {
+		// Remove the title - start barcode higher up
 	
-	// 3. Main barcode in center area (moved up since no title)
-	barcodeX := offsetX + 2
-	barcodeY := offsetY + 4
-	barcodeWidth := width - 25 // Leave space for logo
-	barcodeHeight := 8.0
+		// 3. Main barcode in center area (moved up since no title)
+		barcodeX := offsetX + 2*scaleX
+		barcodeY := offsetY + 4*scaleY
+		barcodeWidth := width - 25*scaleX // Leave space for logo
+		barcodeHeight := 8 * scaleY
 	
-	// Generate realistic Code128 barcode pattern
-	pdf.SetDrawColor(0, 0, 0)
-	pdf.SetFillColor(0, 0, 0)
+		// Generate realistic Code128 barcode pattern
+		pdf.SetDrawColor(0, 0, 0)
+		pdf.SetFillColor(0, 0, 0)
 	
-	// Use device ID for barcode data
-	deviceData := device.DeviceID
-	totalBars := len(deviceData) * 8 + 20
-	barWidth := barcodeWidth / float64(totalBars)
+		// Use device ID for barcode data
+		deviceData := device.DeviceID
+		totalBars := len(deviceData) * 8 + 20
+		barWidth := barcodeWidth / float64(totalBars)
 	
-	x := barcodeX
+		x := barcodeX
 	
-	// Start pattern
-	for i := 0; i < 3; i++ {
-		pdf.Rect(x, barcodeY, barWidth, barcodeHeight, "F")
-		x += barWidth * 2
-	}
+		// Start pattern
+		for i := 0; i < 3; i++ {
+			pdf.Rect(x, barcodeY, barWidth, barcodeHeight, "F")
+			x += barWidth * 2
+		}
 	
-	// Data encoding
-	for i, char := range deviceData {
-		charVal := int(char) + i
-		for j := 0; j < 6; j++ {
-			if (charVal+j)%3 != 0 {
-				pdf.Rect(x, barcodeY, barWidth, barcodeHeight, "F")
+		// Data encoding
+		for i, char := range deviceData {
+			charVal := int(char) + i
+			for j := 0; j < 6; j++ {
+				if (charVal+j)%3 != 0 {
+					pdf.Rect(x, barcodeY, barWidth, barcodeHeight, "F")
+				}
+				x += barWidth
 			}
 			x += barWidth
 		}
-		x += barWidth
-	}
 	
-	// End pattern
-	for i := 0; i < 3; i++ {
-		pdf.Rect(x, barcodeY, barWidth, barcodeHeight, "F")
-		x += barWidth * 2
-	}
+		// End pattern
+		for i := 0; i < 3; i++ {
+			pdf.Rect(x, barcodeY, barWidth, barcodeHeight, "F")
+			x += barWidth * 2
+		}
 	
-	// 4. Human readable text under barcode
-	pdf.SetXY(barcodeX, barcodeY + barcodeHeight + 1)
-	pdf.SetFont("Arial", "", 5)
-	pdf.CellFormat(barcodeWidth, 2, device.DeviceID, "", 0, "C", false, 0, "")
+		// 4. Human readable text under barcode
+		pdf.SetXY(barcodeX, barcodeY + barcodeHeight + scaleY)
+		pdf.SetFont("Arial", "", 5*scale)
+		pdf.CellFormat(barcodeWidth, 2*scaleY, device.DeviceID, "", 0, "C", false, 0, "")
+	
+	}
 	
 	// 5. Device information at bottom
-	pdf.SetXY(offsetX+2, offsetY+height-10)
-	pdf.SetFont("Arial", "B", 7)
-	pdf.Cell(0, 3, device.DeviceID)
+	pdf.SetXY(textX, offsetY+height-10*scaleY)
+	pdf.SetFont("Arial", "B", 7*scale)
+	pdf.Cell(0, 3*scaleY, device.DeviceID)
 	
-	pdf.SetXY(offsetX+2, offsetY+height-7)
-	pdf.SetFont("Arial", "", 6)
-	// Truncate product name if too long
-	if len(productName) > 25 {
-		productName = productName[:22] + "..."
+	pdf.SetXY(textX, offsetY+height-7*scaleY)
+	pdf.SetFont("Arial", "", 6*scale)
+	// Truncate product name if too long for the label width
+	maxNameLength := int(25 * (offsetX + width - textX) / 56)
+	if maxNameLength < 8 {
+		maxNameLength = 8
 	}
-	pdf.Cell(0, 3, productName)
+	if len(productName) > maxNameLength {
+		productName = productName[:maxNameLength-3] + "..."
+	}
+	pdf.Cell(0, 3*scaleY, productName)
+}
+
+// drawLabelQRCode places a square QR code of the device ID on a PDF label. It
+// reports false when the code couldn't be generated so the caller can fall
+// back to a barcode.
+func (h *WorkflowHandler) drawLabelQRCode(pdf *gofpdf.Fpdf, deviceID string, x, y, size float64) bool {
+	qrBytes, err := h.barcodeService.GenerateQRCode(deviceID, 256)
+	if err != nil {
+		logger.Warnf("Failed to generate QR code for device %s: %v", deviceID, err)
+		return false
+	}
+	imageName := "label-qr-" + deviceID
+	options := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader(imageName, options, bytes.NewReader(qrBytes))
+	if !pdf.Ok() {
+		logger.Warnf("Failed to embed QR code for device %s: %v", deviceID, pdf.Error())
+		pdf.ClearError()
+		return false
+	}
+	pdf.ImageOptions(imageName, x, y, size, size, false, options, 0, "")
+	return true
 }
 
 // generateDeviceLabelsZIP creates complete label PNG files for each device and packages them in a ZIP
-func (h *WorkflowHandler) generateDeviceLabelsZIP(devices []models.Device, labelFormat string, printReady bool) ([]byte, error) {
+func (h *WorkflowHandler) generateDeviceLabelsZIP(devices []models.Device, labelFormat, codeType string, printReady bool) ([]byte, error) {
 	// Create ZIP file in memory
 	var buf bytes.Buffer
 	zipWriter := zip.NewWriter(&buf)
@@ -842,12 +1162,17 @@ func (h *WorkflowHandler) generateDeviceLabelsZIP(devices []models.Device, label
 		logoFile.Close()
 	}
 	
+	// Index of the written labels, added to the archive as manifest.csv
+	var manifest bytes.Buffer
+	manifestWriter := csv.NewWriter(&manifest)
+	manifestWriter.Write([]string{"Device ID", "Product", "Serial Number", "Status", "Filename"})
+	
 	// Create complete label PNG for each device
 	for _, device := range devices {
 		// Create PNG image for this device
-		pngBytes, err := h.createLabelPNG(device, logoImg)
+		pngBytes, err := h.createLabelPNG(device, logoImg, codeType)
 		if err != nil {
-			log.Printf("Error generating PNG for device %s: %v", device.DeviceID, err)
+			logger.Errorf("Error generating PNG for device %s: %v", device.DeviceID, err)
 			continue
 		}
 		
@@ -856,18 +1181,40 @@ func (h *WorkflowHandler) generateDeviceLabelsZIP(devices []models.Device, label
 		
 		zipFile, err := zipWriter.Create(filename)
 		if err != nil {
-			log.Printf("Error creating zip file for device %s: %v", device.DeviceID, err)
+			logger.Errorf("Error creating zip file for device %s: %v", device.DeviceID, err)
 			continue
 		}
 		
 		_, err = zipFile.Write(pngBytes)
 		if err != nil {
-			log.Printf("Error writing to zip file for device %s: %v", device.DeviceID, err)
+			logger.Errorf("Error writing to zip file for device %s: %v", device.DeviceID, err)
 			continue
 		}
+		
+		productName := ""
+		if device.Product != nil {
+			productName = device.Product.Name
+		}
+		serialNumber := ""
+		if device.SerialNumber != nil {
+			serialNumber = *device.SerialNumber
+		}
+		manifestWriter.Write([]string{device.DeviceID, productName, serialNumber, device.Status, filename})
 	}
 	
-	err := zipWriter.Close()
+	manifestWriter.Flush()
+	if err := manifestWriter.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write label manifest: %v", err)
+	}
+	manifestFile, err := zipWriter.Create("manifest.csv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to add label manifest: %v", err)
+	}
+	if _, err := manifestFile.Write(manifest.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to add label manifest: %v", err)
+	}
+	
+	err = zipWriter.Close()
 	if err != nil {
 		return nil, fmt.Errorf("failed to close ZIP writer: %v", err)
 	}
@@ -875,8 +1222,9 @@ func (h *WorkflowHandler) generateDeviceLabelsZIP(devices []models.Device, label
 	return buf.Bytes(), nil
 }
 
-// createLabelPNG creates a complete label as PNG image
-func (h *WorkflowHandler) createLabelPNG(device models.Device, logoImg image.Image) ([]byte, error) {
+// createLabelPNG creates a complete label as PNG image with either a QR code
+// or a Code128 barcode of the device ID
+func (h *WorkflowHandler) createLabelPNG(device models.Device, logoImg image.Image, codeType string) ([]byte, error) {
 	// Label dimensions in pixels (300 DPI equivalent for 100x60mm)
 	width := 1200
 	height := 700
@@ -891,19 +1239,41 @@ func (h *WorkflowHandler) createLabelPNG(device models.Device, logoImg image.Ima
 	borderColor := color.RGBA{200, 200, 200, 255}
 	h.drawRect(img, 10, 10, width-20, height-20, borderColor)
 	
-	// Generate barcode image
-	barcodeBytes, err := h.barcodeService.GenerateDeviceBarcode(device.DeviceID)
-	if err == nil {
-		if barcodeImg, _, err := image.Decode(bytes.NewReader(barcodeBytes)); err == nil {
-			// Scale and position barcode
-			barcodeRect := image.Rect(50, 150, 800, 350)
-			xdraw.BiLinear.Scale(img, barcodeRect, barcodeImg, barcodeImg.Bounds(), draw.Over, nil)
+	// Generate the QR code, falling back to the barcode layout if it fails
+	var qrImg image.Image
+	if codeType == labelCodeQR {
+		qrBytes, err := h.barcodeService.GenerateQRCode(device.DeviceID, 512)
+		if err == nil {
+			qrImg, _, err = image.Decode(bytes.NewReader(qrBytes))
+		}
+		if err != nil {
+			logger.Warnf("Failed to generate QR code for device %s: %v", device.DeviceID, err)
+			qrImg = nil
+		}
+	}
+	
+	if qrImg != nil {
+		// Square QR code on the left, nearest neighbour keeps the modules sharp
+		qrRect := image.Rect(50, 100, 550, 600)
+		xdraw.NearestNeighbor.Scale(img, qrRect, qrImg, qrImg.Bounds(), draw.Over, nil)
+	} else {
+		// Generate barcode image
+		barcodeBytes, err := h.barcodeService.GenerateDeviceBarcode(device.DeviceID)
+		if err == nil {
+			if barcodeImg, _, err := image.Decode(bytes.NewReader(barcodeBytes)); err == nil {
+				// Scale and position barcode
+				barcodeRect := image.Rect(50, 150, 800, 350)
+				xdraw.BiLinear.Scale(img, barcodeRect, barcodeImg, barcodeImg.Bounds(), draw.Over, nil)
+			}
 		}
 	}
 	
-	// Draw logo if available
+	// Draw logo if available, below the text next to a QR code
 	if logoImg != nil {
 		logoRect := image.Rect(900, 200, 1100, 300)
+		if qrImg != nil {
+			logoRect = image.Rect(900, 450, 1100, 550)
+		}
 		xdraw.BiLinear.Scale(img, logoRect, logoImg, logoImg.Bounds(), draw.Over, nil)
 	}
 	
@@ -919,18 +1289,24 @@ func (h *WorkflowHandler) createLabelPNG(device models.Device, logoImg image.Ima
 	// Draw text
 	textColor := color.RGBA{0, 0, 0, 255}
 	
-	// Device ID (large, bold)
-	h.drawText(img, device.DeviceID, 50, 450, 48, textColor)
-	
-	// Product name (smaller)
-	h.drawText(img, productName, 50, 520, 32, textColor)
-	
-	// Device ID under barcode (small)
-	h.drawText(img, device.DeviceID, 350, 380, 24, textColor)
+	if qrImg != nil {
+		// Device ID and product name beside the QR code
+		h.drawText(img, device.DeviceID, 600, 250, 48, textColor)
+		h.drawText(img, productName, 600, 320, 32, textColor)
+	} else {
+		// Device ID (large, bold)
+		h.drawText(img, device.DeviceID, 50, 450, 48, textColor)
+		
+		// Product name (smaller)
+		h.drawText(img, productName, 50, 520, 32, textColor)
+		
+		// Device ID under barcode (small)
+		h.drawText(img, device.DeviceID, 350, 380, 24, textColor)
+	}
 	
 	// Convert to PNG bytes
 	var buf bytes.Buffer
-	err = png.Encode(&buf, img)
+	err := png.Encode(&buf, img)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode PNG: %v", err)
 	}
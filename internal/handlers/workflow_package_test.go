@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-barcode-webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakePackageTables answers the queries of applying package 7 to job 42. The
+// package is active unless told otherwise and holds two units of product 5,
+// device DEV-1 and a top-up from DEV-2. Job 42 exists when asked to be; DEV-2
+// is booked on job 3 for its dates, or the booking lookup fails, when asked to.
+type fakePackageTables struct {
+	inactive    bool
+	archived    bool
+	jobExists   bool
+	dev2Booked  bool
+	bookingsErr error
+}
+
+func (f fakePackageTables) answer(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	start := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 5, 3, 0, 0, 0, 0, time.UTC)
+
+	switch {
+	case strings.HasPrefix(query, "SELECT * FROM `equipment_packages`"):
+		return []string{"packageID", "name", "is_active", "is_archived"},
+			[][]driver.Value{{int64(7), "Stage kit", !f.inactive, f.archived}}, nil
+	case strings.HasPrefix(query, "SELECT * FROM `package_devices`"):
+		return []string{"packageID", "deviceID", "quantity"}, [][]driver.Value{{int64(7), "DEV-1", int64(2)}}, nil
+	case strings.HasPrefix(query, "SELECT * FROM `jobs`"):
+		if !f.jobExists {
+			return nil, nil, nil
+		}
+		return []string{"jobID", "startDate", "endDate"}, [][]driver.Value{{int64(42), start, end}}, nil
+	case strings.HasPrefix(query, "SELECT `deviceID`,`productID`,`status` FROM `devices`"):
+		return []string{"deviceID", "productID", "status"}, [][]driver.Value{{args[0], int64(5), "free"}}, nil
+	case strings.HasPrefix(query, "SELECT `deviceID` FROM `devices`"):
+		return []string{"deviceID"}, [][]driver.Value{{"DEV-1"}, {"DEV-2"}}, nil
+	case strings.Contains(query, "FROM `jobdevices` JOIN jobs"):
+		if args[0] != "DEV-2" {
+			return nil, nil, nil
+		}
+		if f.bookingsErr != nil {
+			return nil, nil, f.bookingsErr
+		}
+		if !f.dev2Booked {
+			return nil, nil, nil
+		}
+		return []string{"jobID", "deviceID"}, [][]driver.Value{{int64(3), "DEV-2"}}, nil
+	}
+	// Counts, consumable checks, inserts and revenue updates: no rows
+	return nil, nil, nil
+}
+
+func applyPackage(t *testing.T, answer fakeQueryFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	db := newFakeDB(t, answer)
+	handler := NewWorkflowHandler(repository.NewJobRepository(db, nil), nil,
+		repository.NewEquipmentPackageRepository(db), nil, db.DB, nil)
+
+	router := gin.New()
+	router.POST("/api/v1/workflow/packages/apply", handler.ApplyPackageToJob)
+
+	w := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/workflow/packages/apply",
+		strings.NewReader(`{"jobId": 42, "packageId": 7}`))
+	request.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, request)
+	return w
+}
+
+func TestApplyPackageToJobPackageState(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		tables fakePackageTables
+		want   int
+	}{
+		{"active package, job missing", fakePackageTables{}, http.StatusNotFound},
+		{"inactive package", fakePackageTables{inactive: true}, http.StatusConflict},
+		{"archived package", fakePackageTables{archived: true, inactive: true}, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := applyPackage(t, tt.tables.answer)
+			if w.Code != tt.want {
+				t.Fatalf("apply package = %d: %s; want %d", w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPackageToJobTopUp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		tables      fakePackageTables
+		want        int
+		wantSkipped string
+	}{
+		{"top-up assigned", fakePackageTables{jobExists: true}, http.StatusOK, `"skippedCount":0`},
+		{"top-up booked elsewhere", fakePackageTables{jobExists: true, dev2Booked: true}, http.StatusOK, `"skippedCount":1`},
+		{"booking lookup fails", fakePackageTables{jobExists: true, bookingsErr: errors.New("connection lost")}, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := applyPackage(t, tt.tables.answer)
+			if w.Code != tt.want {
+				t.Fatalf("apply package = %d: %s; want %d", w.Code, w.Body.String(), tt.want)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantSkipped) {
+				t.Errorf("apply package = %s; want %s", w.Body.String(), tt.wantSkipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// currentLevel gates the printf-style helpers below. Debug output is off
+// until the configured level says otherwise.
+var currentLevel int32 = int32(INFO)
+
+// ParseLogLevel converts a config or environment value ("debug", "info",
+// "warn", "error") to a LogLevel. Unknown values fall back to INFO.
+func ParseLogLevel(level string) LogLevel {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return DEBUG
+	case "warn", "warning":
+		return WARN
+	case "error":
+		return ERROR
+	case "fatal":
+		return FATAL
+	default:
+		return INFO
+	}
+}
+
+// SetLevel sets the minimum level printed by Debugf, Infof, Warnf and Errorf
+func SetLevel(level LogLevel) {
+	atomic.StoreInt32(&currentLevel, int32(level))
+}
+
+// Enabled reports whether messages at the given level are printed. Use it to
+// skip building expensive debug output.
+func Enabled(level LogLevel) bool {
+	return level >= LogLevel(atomic.LoadInt32(&currentLevel))
+}
+
+// Debugf logs developer diagnostics; off by default
+func Debugf(format string, args ...interface{}) {
+	logf(DEBUG, format, args...)
+}
+
+// Infof logs normal operational events
+func Infof(format string, args ...interface{}) {
+	logf(INFO, format, args...)
+}
+
+// Warnf logs recoverable problems
+func Warnf(format string, args ...interface{}) {
+	logf(WARN, format, args...)
+}
+
+// Errorf logs failures
+func Errorf(format string, args ...interface{}) {
+	logf(ERROR, format, args...)
+}
+
+func logf(level LogLevel, format string, args ...interface{}) {
+	if !Enabled(level) {
+		return
+	}
+	log.Output(3, level.String()+" "+fmt.Sprintf(format, args...))
+}
@@ -480,5 +480,6 @@ var GlobalLogger *StructuredLogger
 func InitializeLogger(config LoggerConfig) error {
 	var err error
 	GlobalLogger, err = NewStructuredLogger(config)
+	SetLevel(config.Level)
 	return err
 }
\ No newline at end of file
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 	"encoding/json"
+	"strconv"
 )
 
 // ================================================================
@@ -10,9 +11,9 @@ import (
 // ================================================================
 
 type EquipmentUsageLog struct {
-	LogID            uint      `gorm:"primaryKey;autoIncrement" json:"logID"`
-	DeviceID         string    `gorm:"not null" json:"deviceID"`
-	JobID            *uint     `json:"jobID"`
+	LogID            uint      `gorm:"primaryKey;autoIncrement;column:logID" json:"logID"`
+	DeviceID         string    `gorm:"not null;column:deviceID" json:"deviceID"`
+	JobID            *uint     `gorm:"column:jobID" json:"jobID"`
 	Action           string    `gorm:"type:enum('assigned','returned','maintenance','available');not null" json:"action"`
 	Timestamp        time.Time `gorm:"not null" json:"timestamp"`
 	DurationHours    *float64  `gorm:"type:decimal(10,2)" json:"durationHours"`
@@ -25,10 +26,14 @@ type EquipmentUsageLog struct {
 	Job    *Job    `gorm:"foreignKey:JobID" json:"job,omitempty"`
 }
 
+func (EquipmentUsageLog) TableName() string {
+	return "equipment_usage_logs"
+}
+
 type FinancialTransaction struct {
-	TransactionID     uint      `gorm:"primaryKey;autoIncrement" json:"transactionID"`
-	JobID             *uint     `json:"jobID"`
-	CustomerID        *uint     `json:"customerID"`
+	TransactionID     uint      `gorm:"primaryKey;autoIncrement;column:transactionID" json:"transactionID"`
+	JobID             *uint     `gorm:"column:jobID" json:"jobID"`
+	CustomerID        *uint     `gorm:"column:customerID" json:"customerID"`
 	Type              string    `gorm:"type:enum('rental','deposit','payment','refund','fee','discount');not null" json:"type"`
 	Amount            float64   `gorm:"type:decimal(12,2);not null" json:"amount"`
 	Currency          string    `gorm:"default:'EUR'" json:"currency"`
@@ -48,6 +53,47 @@ type FinancialTransaction struct {
 	Creator  *User     `gorm:"foreignKey:CreatedBy" json:"creator,omitempty"`
 }
 
+func (FinancialTransaction) TableName() string {
+	return "financial_transactions"
+}
+
+// Financial transaction types and statuses, as allowed by the table's enums.
+// A rental transaction charges the customer for a job's rental; payments,
+// deposits and refunds are money received or returned.
+const (
+	TransactionTypeRental   = "rental"
+	TransactionTypeDeposit  = "deposit"
+	TransactionTypePayment  = "payment"
+	TransactionTypeRefund   = "refund"
+	TransactionTypeFee      = "fee"
+	TransactionTypeDiscount = "discount"
+
+	TransactionStatusPending   = "pending"
+	TransactionStatusCompleted = "completed"
+	TransactionStatusFailed    = "failed"
+	TransactionStatusCancelled = "cancelled"
+)
+
+// DefaultTransactionCurrency is used for transactions recorded without a currency
+const DefaultTransactionCurrency = "EUR"
+
+func IsValidTransactionType(transactionType string) bool {
+	switch transactionType {
+	case TransactionTypeRental, TransactionTypeDeposit, TransactionTypePayment,
+		TransactionTypeRefund, TransactionTypeFee, TransactionTypeDiscount:
+		return true
+	}
+	return false
+}
+
+func IsValidTransactionStatus(status string) bool {
+	switch status {
+	case TransactionStatusPending, TransactionStatusCompleted, TransactionStatusFailed, TransactionStatusCancelled:
+		return true
+	}
+	return false
+}
+
 type AnalyticsCache struct {
 	CacheID    uint            `gorm:"primaryKey;autoIncrement" json:"cacheID"`
 	MetricName string          `gorm:"not null" json:"metricName"`
@@ -63,8 +109,8 @@ type AnalyticsCache struct {
 // ================================================================
 
 type Document struct {
-	DocumentID       uint      `gorm:"primaryKey;autoIncrement" json:"documentID"`
-	EntityType       string    `gorm:"type:enum('job','device','customer','user','system');not null" json:"entityType"`
+	DocumentID       uint      `gorm:"primaryKey;autoIncrement;column:documentID" json:"documentID"`
+	EntityType       string    `gorm:"type:enum('job','device','customer','user','system','product','invoice');not null" json:"entityType"`
 	EntityID         string    `gorm:"not null" json:"entityID"`
 	Filename         string    `gorm:"not null" json:"filename"`
 	OriginalFilename string    `gorm:"not null" json:"originalFilename"`
@@ -77,7 +123,7 @@ type Document struct {
 	UploadedAt       time.Time `json:"uploadedAt"`
 	IsPublic         bool      `gorm:"default:false" json:"isPublic"`
 	Version          int       `gorm:"default:1" json:"version"`
-	ParentDocumentID *uint     `json:"parentDocumentID"`
+	ParentDocumentID *uint     `gorm:"column:parent_documentID" json:"parentDocumentID"`
 	Checksum         string    `json:"checksum"`
 
 	// Relationships
@@ -86,6 +132,26 @@ type Document struct {
 	Signatures     []DigitalSignature  `gorm:"foreignKey:DocumentID" json:"signatures,omitempty"`
 }
 
+func (Document) TableName() string {
+	return "documents"
+}
+
+// Document types, as allowed by the documents table's enum
+var documentTypes = map[string]bool{
+	"contract":  true,
+	"manual":    true,
+	"photo":     true,
+	"invoice":   true,
+	"receipt":   true,
+	"signature": true,
+	"other":     true,
+}
+
+// IsValidDocumentType reports whether documents can be stored with the type
+func IsValidDocumentType(documentType string) bool {
+	return documentTypes[documentType]
+}
+
 type DigitalSignature struct {
 	SignatureID      uint      `gorm:"primaryKey;autoIncrement" json:"signatureID"`
 	DocumentID       uint      `gorm:"not null" json:"documentID"`
@@ -107,8 +173,8 @@ type DigitalSignature struct {
 // ================================================================
 
 type SavedSearch struct {
-	SearchID   uint            `gorm:"primaryKey;autoIncrement" json:"searchID"`
-	UserID     uint            `gorm:"not null" json:"userID"`
+	SearchID   uint            `gorm:"primaryKey;autoIncrement;column:searchID" json:"searchID"`
+	UserID     uint            `gorm:"not null;column:userID" json:"userID"`
 	Name       string          `gorm:"not null" json:"name"`
 	SearchType string          `gorm:"type:enum('global','jobs','devices','customers','cases');not null" json:"searchType"`
 	Filters    json.RawMessage `gorm:"type:json;not null" json:"filters"`
@@ -123,20 +189,28 @@ type SavedSearch struct {
 	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
+func (SavedSearch) TableName() string {
+	return "saved_searches"
+}
+
 type SearchHistory struct {
-	HistoryID       uint            `gorm:"primaryKey;autoIncrement" json:"historyID"`
-	UserID          *uint           `json:"userID"`
+	HistoryID       uint            `gorm:"primaryKey;autoIncrement;column:historyID" json:"historyID"`
+	UserID          *uint           `gorm:"column:userID" json:"userID"`
 	SearchTerm      string          `json:"searchTerm"`
 	SearchType      string          `json:"searchType"`
 	Filters         json.RawMessage `gorm:"type:json" json:"filters"`
 	ResultsCount    int             `json:"resultsCount"`
-	ExecutionTimeMS int             `json:"executionTimeMS"`
+	ExecutionTimeMS int             `gorm:"column:execution_time_ms" json:"executionTimeMS"`
 	SearchedAt      time.Time       `json:"searchedAt"`
 
 	// Relationships
 	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
+func (SearchHistory) TableName() string {
+	return "search_history"
+}
+
 // ================================================================
 // WORKFLOW & TEMPLATES MODELS
 // ================================================================
@@ -152,6 +226,8 @@ type EquipmentPackage struct {
 	MinRentalDays    int             `gorm:"default:1;column:min_rental_days" json:"minRentalDays" binding:"min=1,max=365"`
 	MaxRentalDays    *int            `gorm:"column:max_rental_days" json:"maxRentalDays" binding:"omitempty,min=1,max=3650"`
 	IsActive         bool            `gorm:"default:true;column:is_active" json:"isActive"`
+	IsArchived       bool            `gorm:"not null;default:false;column:is_archived" json:"isArchived"`
+	ArchivedAt       *time.Time      `gorm:"column:archived_at" json:"archivedAt"`
 	Category         string          `gorm:"size:50;column:category" json:"category" binding:"max=50"`
 	Tags             string          `gorm:"size:500;column:tags" json:"tags" binding:"max=500"`
 	CreatedBy        *uint           `gorm:"column:created_by" json:"createdBy"`
@@ -173,6 +249,14 @@ func (EquipmentPackage) TableName() string {
 	return "equipment_packages"
 }
 
+// KitCodePrefix prefixes the barcode printed on a package so a single scan assigns the whole kit
+const KitCodePrefix = "PKG"
+
+// KitCode returns the scannable master code for the package
+func (p *EquipmentPackage) KitCode() string {
+	return KitCodePrefix + "-" + strconv.FormatUint(uint64(p.PackageID), 10)
+}
+
 type PackageDevice struct {
 	PackageID   uint     `gorm:"primaryKey;column:packageID" json:"packageID"`
 	DeviceID    string   `gorm:"primaryKey;column:deviceID;size:50" json:"deviceID" binding:"required,max=50"`
@@ -258,8 +342,8 @@ type AuditLog struct {
 // ================================================================
 
 type PushSubscription struct {
-	SubscriptionID uint      `gorm:"primaryKey;autoIncrement" json:"subscriptionID"`
-	UserID         uint      `gorm:"not null" json:"userID"`
+	SubscriptionID uint      `gorm:"primaryKey;autoIncrement;column:subscriptionID" json:"subscriptionID"`
+	UserID         uint      `gorm:"not null;column:userID" json:"userID"`
 	Endpoint       string    `gorm:"type:text;not null" json:"endpoint"`
 	KeysP256dh     string    `gorm:"type:text;not null" json:"keysP256dh"`
 	KeysAuth       string    `gorm:"type:text;not null" json:"keysAuth"`
@@ -273,14 +357,19 @@ type PushSubscription struct {
 	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
+func (PushSubscription) TableName() string {
+	return "push_subscriptions"
+}
+
 type OfflineSyncQueue struct {
-	QueueID      uint            `gorm:"primaryKey;autoIncrement" json:"queueID"`
-	UserID       uint            `gorm:"not null" json:"userID"`
+	QueueID      uint            `gorm:"primaryKey;autoIncrement;column:queueID" json:"queueID"`
+	UserID       uint            `gorm:"not null;column:userID" json:"userID"`
 	Action       string          `gorm:"type:enum('create','update','delete');not null" json:"action"`
 	EntityType   string          `gorm:"not null" json:"entityType"`
 	EntityData   json.RawMessage `gorm:"type:json;not null" json:"entityData"`
 	Timestamp    time.Time       `json:"timestamp"`
 	Synced       bool            `gorm:"default:false" json:"synced"`
+	Status       string          `gorm:"type:enum('pending','synced','failed','conflict');not null;default:pending" json:"status"`
 	SyncedAt     *time.Time      `json:"syncedAt"`
 	RetryCount   int             `gorm:"default:0" json:"retryCount"`
 	ErrorMessage string          `json:"errorMessage"`
@@ -289,6 +378,26 @@ type OfflineSyncQueue struct {
 	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
+func (OfflineSyncQueue) TableName() string {
+	return "offline_sync_queue"
+}
+
+// Offline sync queue actions, as allowed by the offline_sync_queue table's enum
+const (
+	SyncActionCreate = "create"
+	SyncActionUpdate = "update"
+	SyncActionDelete = "delete"
+)
+
+// Offline sync queue item states. Pending items are replayed; the others have
+// left the queue, and only synced ones were applied.
+const (
+	SyncQueueStatusPending  = "pending"
+	SyncQueueStatusSynced   = "synced"
+	SyncQueueStatusFailed   = "failed"
+	SyncQueueStatusConflict = "conflict"
+)
+
 // ================================================================
 // ENHANCED EXISTING MODELS (EXTENSIONS)
 // ================================================================
@@ -430,6 +539,16 @@ type SearchRequest struct {
 	SearchName string                 `json:"searchName"`
 }
 
+// SavedSearchRequest creates or replaces a saved search. Filters may be any
+// JSON value and default to an empty object.
+type SavedSearchRequest struct {
+	Name       string          `json:"name" binding:"required,max=100"`
+	SearchType string          `json:"searchType" binding:"required,oneof=global jobs devices customers cases"`
+	Filters    json.RawMessage `json:"filters"`
+	IsDefault  bool            `json:"isDefault"`
+	IsPublic   bool            `json:"isPublic"`
+}
+
 type BulkActionRequest struct {
 	Action   string   `json:"action"`
 	EntityIDs []string `json:"entityIds"`
@@ -716,4 +835,372 @@ type JobAttachmentResponse struct {
 	Uploader         *User     `json:"uploader,omitempty"`
 	FileSizeFormatted string   `json:"fileSizeFormatted"`
 	IsImage          bool      `json:"isImage"`
-}
\ No newline at end of file
+}
+
+// ================================================================
+// PRICING CALENDAR MODELS
+// ================================================================
+
+// PricingCalendar adjusts product day rates for a date range. An entry scoped
+// to a product wins over one scoped to a category, which wins over a global one.
+type PricingCalendar struct {
+	CalendarID   uint      `gorm:"primaryKey;autoIncrement;column:calendar_id" json:"calendarID"`
+	Name         string    `gorm:"not null;size:100;column:name" json:"name"`
+	StartDate    time.Time `gorm:"not null;type:date;column:start_date" json:"startDate"`
+	EndDate      time.Time `gorm:"not null;type:date;column:end_date" json:"endDate"`
+	ProductID    *uint     `gorm:"column:product_id" json:"productID"`
+	CategoryID   *uint     `gorm:"column:category_id" json:"categoryID"`
+	Multiplier   *float64  `gorm:"type:decimal(6,3);column:multiplier" json:"multiplier"`
+	OverrideRate *float64  `gorm:"type:decimal(12,2);column:override_rate" json:"overrideRate"`
+	IsActive     bool      `gorm:"default:true;column:is_active" json:"isActive"`
+	CreatedBy    *uint     `gorm:"column:created_by" json:"createdBy"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt    time.Time `gorm:"column:updated_at" json:"updatedAt"`
+
+	// Relationships
+	Product  *Product  `gorm:"foreignKey:ProductID;references:ProductID" json:"product,omitempty"`
+	Category *Category `gorm:"foreignKey:CategoryID;references:CategoryID" json:"category,omitempty"`
+}
+
+func (PricingCalendar) TableName() string {
+	return "pricing_calendars"
+}
+
+// Matches reports whether the entry is scoped to the given product. Entries
+// only adjust product rates, so an item without a product never matches, not
+// even a global entry.
+func (pc *PricingCalendar) Matches(product *Product) bool {
+	if product == nil {
+		return false
+	}
+	if pc.ProductID != nil {
+		return *pc.ProductID == product.ProductID
+	}
+	if pc.CategoryID != nil {
+		return product.CategoryID != nil && *pc.CategoryID == *product.CategoryID
+	}
+	return true
+}
+
+// Apply returns the adjusted day rate; an override rate takes precedence over a multiplier
+func (pc *PricingCalendar) Apply(rate float64) float64 {
+	if pc.OverrideRate != nil {
+		return *pc.OverrideRate
+	}
+	if pc.Multiplier != nil {
+		return rate * *pc.Multiplier
+	}
+	return rate
+}
+
+func (pc *PricingCalendar) specificity() int {
+	switch {
+	case pc.ProductID != nil:
+		return 2
+	case pc.CategoryID != nil:
+		return 1
+	}
+	return 0
+}
+
+// ResolveSeasonalRate applies the most specific matching calendar entry to the
+// product's rate. Entries are expected to already cover the relevant date.
+func ResolveSeasonalRate(entries []PricingCalendar, product *Product, rate float64) float64 {
+	var best *PricingCalendar
+	for i := range entries {
+		entry := &entries[i]
+		if !entry.Matches(product) {
+			continue
+		}
+		if best == nil || entry.specificity() > best.specificity() ||
+			(entry.specificity() == best.specificity() && entry.CalendarID > best.CalendarID) {
+			best = entry
+		}
+	}
+	if best == nil {
+		return rate
+	}
+	return best.Apply(rate)
+}
+
+// ================================================================
+// PRICING CALENDAR DTOs
+// ================================================================
+
+type PricingCalendarRequest struct {
+	Name         string   `json:"name" binding:"required,min=1,max=100"`
+	StartDate    string   `json:"startDate" binding:"required"`
+	EndDate      string   `json:"endDate" binding:"required"`
+	ProductID    *uint    `json:"productID"`
+	CategoryID   *uint    `json:"categoryID"`
+	Multiplier   *float64 `json:"multiplier" binding:"omitempty,gt=0"`
+	OverrideRate *float64 `json:"overrideRate" binding:"omitempty,min=0"`
+	IsActive     *bool    `json:"isActive"`
+}
+
+// ================================================================
+// CUSTOMER CONTACTS
+// ================================================================
+
+// Contact roles a customer's contact persons can have
+const (
+	ContactRoleBooking    = "booking"
+	ContactRoleAccounting = "accounting"
+	ContactRoleOnSite     = "on_site"
+	ContactRoleOther      = "other"
+)
+
+// IsValidContactRole reports whether role is one of the known contact roles
+func IsValidContactRole(role string) bool {
+	switch role {
+	case ContactRoleBooking, ContactRoleAccounting, ContactRoleOnSite, ContactRoleOther:
+		return true
+	}
+	return false
+}
+
+// CustomerContact is a contact person at a customer, e.g. the booker or the accountant
+type CustomerContact struct {
+	ContactID  uint      `gorm:"primaryKey;autoIncrement;column:contact_id" json:"contactID"`
+	CustomerID uint      `gorm:"not null;column:customer_id" json:"customerID"`
+	Name       string    `gorm:"not null;column:name" json:"name"`
+	Role       string    `gorm:"not null;default:other;column:role" json:"role"`
+	Email      *string   `gorm:"column:email" json:"email"`
+	Phone      *string   `gorm:"column:phone" json:"phone"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt  time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (CustomerContact) TableName() string {
+	return "customer_contacts"
+}
+
+type CustomerContactRequest struct {
+	Name  string  `json:"name" binding:"required,min=1,max=100"`
+	Role  string  `json:"role" binding:"required"`
+	Email *string `json:"email" binding:"omitempty,email"`
+	Phone *string `json:"phone"`
+}
+
+// CategoryUtilizationTarget is the utilization percentage managers aim for in
+// an equipment category
+type CategoryUtilizationTarget struct {
+	CategoryID    uint      `gorm:"primaryKey;autoIncrement:false;column:category_id" json:"categoryID"`
+	TargetPercent float64   `gorm:"type:decimal(5,2);not null;column:target_percent" json:"targetPercent"`
+	UpdatedBy     *uint     `gorm:"column:updated_by" json:"updatedBy"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updatedAt"`
+
+	Category *Category `gorm:"foreignKey:CategoryID;references:CategoryID" json:"category,omitempty"`
+}
+
+func (CategoryUtilizationTarget) TableName() string {
+	return "category_utilization_targets"
+}
+
+type CategoryUtilizationTargetRequest struct {
+	TargetPercent float64 `json:"targetPercent" binding:"gte=0,lte=100"`
+}
+
+// Damage report severities
+const (
+	DamageSeverityMinor    = "minor"
+	DamageSeverityModerate = "moderate"
+	DamageSeverityMajor    = "major"
+	DamageSeverityCritical = "critical"
+)
+
+// Damage report states
+const (
+	DamageReportOpen     = "open"
+	DamageReportResolved = "resolved"
+)
+
+func IsValidDamageSeverity(severity string) bool {
+	switch severity {
+	case DamageSeverityMinor, DamageSeverityModerate, DamageSeverityMajor, DamageSeverityCritical:
+		return true
+	}
+	return false
+}
+
+// DamageReport records damage found on a device. Reports that take the device
+// out of service keep it in maintenance until they are resolved.
+type DamageReport struct {
+	ReportID             uint       `gorm:"primaryKey;autoIncrement;column:report_id" json:"reportID"`
+	DeviceID             string     `gorm:"not null;column:device_id" json:"deviceID"`
+	JobID                *uint      `gorm:"column:job_id" json:"jobID"`
+	Description          string     `gorm:"type:text;not null;column:description" json:"description"`
+	Severity             string     `gorm:"type:enum('minor','moderate','major','critical');default:minor;column:severity" json:"severity"`
+	OutOfService         bool       `gorm:"not null;default:false;column:out_of_service" json:"outOfService"`
+	PreviousDeviceStatus *string    `gorm:"column:previous_device_status" json:"previousDeviceStatus,omitempty"`
+	Status               string     `gorm:"type:enum('open','resolved');default:open;column:status" json:"status"`
+	ResolutionNotes      *string    `gorm:"type:text;column:resolution_notes" json:"resolutionNotes"`
+	ReportedBy           *uint      `gorm:"column:reported_by" json:"reportedBy"`
+	ResolvedBy           *uint      `gorm:"column:resolved_by" json:"resolvedBy"`
+	ResolvedAt           *time.Time `gorm:"column:resolved_at" json:"resolvedAt"`
+	CreatedAt            time.Time  `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt            time.Time  `gorm:"column:updated_at" json:"updatedAt"`
+
+	// Relationships
+	Device   *Device    `gorm:"foreignKey:DeviceID;references:DeviceID" json:"device,omitempty"`
+	Job      *Job       `gorm:"foreignKey:JobID;references:JobID" json:"job,omitempty"`
+	Reporter *User      `gorm:"foreignKey:ReportedBy;references:UserID" json:"reporter,omitempty"`
+	Photos   []Document `gorm:"-" json:"photos,omitempty"`
+}
+
+func (DamageReport) TableName() string {
+	return "damage_reports"
+}
+
+// DamageReportPhoto links a photo document to a damage report
+type DamageReportPhoto struct {
+	ReportID   uint `gorm:"primaryKey;column:report_id"`
+	DocumentID uint `gorm:"primaryKey;column:document_id"`
+}
+
+func (DamageReportPhoto) TableName() string {
+	return "damage_report_photos"
+}
+
+type CreateDamageReportRequest struct {
+	JobID        *uint  `json:"jobId"`
+	Description  string `json:"description" binding:"required"`
+	Severity     string `json:"severity"`
+	OutOfService bool   `json:"outOfService"`
+	PhotoIDs     []uint `json:"photoIds"`
+}
+
+type ResolveDamageReportRequest struct {
+	ResolutionNotes string `json:"resolutionNotes"`
+}
+
+// ================================================================
+// JOB ARCHIVE MODELS
+// ================================================================
+
+// Analytics cache metrics holding the monthly totals of archived jobs, keyed
+// by the month of the job's end date
+const (
+	MetricArchivedJobRevenue = "archived_job_revenue"
+	MetricArchivedJobs       = "archived_jobs"
+)
+
+// Analytics cache metrics of the dashboard, refreshed after an hour. Top
+// equipment is cached per period start and limit, as "top_equipment:<start>:<limit>".
+const (
+	MetricDeviceUtilization = "device_utilization"
+	MetricTopEquipment      = "top_equipment"
+)
+
+// JobArchive is a completed job moved out of the operational tables by the
+// retention policy
+type JobArchive struct {
+	JobID       uint            `gorm:"primaryKey;autoIncrement:false;column:job_id" json:"jobID"`
+	CustomerID  uint            `gorm:"not null;column:customer_id" json:"customerID"`
+	StatusID    uint            `gorm:"not null;column:status_id" json:"statusID"`
+	StartDate   *time.Time      `gorm:"type:date;column:start_date" json:"startDate"`
+	EndDate     *time.Time      `gorm:"type:date;column:end_date" json:"endDate"`
+	Revenue     float64         `gorm:"type:decimal(12,2);not null;default:0.00;column:revenue" json:"revenue"`
+	DeviceCount int             `gorm:"not null;default:0;column:device_count" json:"deviceCount"`
+	Snapshot    json.RawMessage `gorm:"type:json;not null;column:snapshot" json:"-"`
+	ArchivedBy  *uint           `gorm:"column:archived_by" json:"archivedBy"`
+	ArchivedAt  time.Time       `gorm:"column:archived_at" json:"archivedAt"`
+
+	Customer *Customer `gorm:"foreignKey:CustomerID;references:CustomerID" json:"customer,omitempty"`
+}
+
+func (JobArchive) TableName() string {
+	return "job_archive"
+}
+
+// ArchivedJobSnapshot is everything removed from the operational tables when
+// a job is archived. Records that only reference the job are kept in place and
+// listed by ID so the reference can be restored.
+type ArchivedJobSnapshot struct {
+	Job             Job                  `json:"job"`
+	Devices         []JobDevice          `json:"devices"`
+	DeviceEvents    []JobDeviceEvent     `json:"deviceEvents"`
+	RentalEquipment []JobRentalEquipment `json:"rentalEquipment"`
+	Attachments     []JobAttachment      `json:"attachments"`
+	InvoiceIDs      []uint64             `json:"invoiceIDs"`
+	UsageLogIDs     []uint               `json:"usageLogIDs"`
+	DamageReportIDs []uint               `json:"damageReportIDs"`
+}
+
+// DeviceLabelPrint records one generation of a device's barcode/QR label
+type DeviceLabelPrint struct {
+	PrintID      uint      `gorm:"primaryKey;autoIncrement;column:print_id" json:"printID"`
+	DeviceID     string    `gorm:"not null;column:device_id" json:"deviceID"`
+	PrintedBy    *uint     `gorm:"column:printed_by" json:"printedBy"`
+	OutputFormat string    `gorm:"not null;column:output_format" json:"outputFormat"`
+	LabelFormat  string    `gorm:"not null;column:label_format" json:"labelFormat"`
+	PrintedAt    time.Time `gorm:"column:printed_at" json:"printedAt"`
+
+	Printer *User `gorm:"foreignKey:PrintedBy;references:UserID" json:"printer,omitempty"`
+}
+
+func (DeviceLabelPrint) TableName() string {
+	return "device_label_prints"
+}
+
+// DeviceLabelPrintSummary is how often and when a device's label was last printed
+type DeviceLabelPrintSummary struct {
+	Count     int64             `json:"count"`
+	LastPrint *DeviceLabelPrint `json:"lastPrint"`
+}
+
+// DeviceLocationLog records one move of a device to a new location
+type DeviceLocationLog struct {
+	LogID            uint      `gorm:"primaryKey;autoIncrement;column:log_id" json:"logID"`
+	DeviceID         string    `gorm:"not null;column:device_id" json:"deviceID"`
+	Location         string    `gorm:"not null;column:location" json:"location"`
+	PreviousLocation *string   `gorm:"column:previous_location" json:"previousLocation"`
+	GPSLatitude      *float64  `gorm:"type:decimal(10,8);column:gps_latitude" json:"gpsLatitude"`
+	GPSLongitude     *float64  `gorm:"type:decimal(11,8);column:gps_longitude" json:"gpsLongitude"`
+	MovedBy          *uint     `gorm:"column:moved_by" json:"movedBy"`
+	Notes            string    `gorm:"column:notes" json:"notes"`
+	MovedAt          time.Time `gorm:"column:moved_at" json:"movedAt"`
+
+	Mover *User `gorm:"foreignKey:MovedBy;references:UserID" json:"mover,omitempty"`
+}
+
+func (DeviceLocationLog) TableName() string {
+	return "device_location_logs"
+}
+
+// DeviceConditionLog records one condition rating given to a device
+type DeviceConditionLog struct {
+	LogID          uint      `gorm:"primaryKey;autoIncrement;column:log_id" json:"logID"`
+	DeviceID       string    `gorm:"not null;column:device_id" json:"deviceID"`
+	Rating         float64   `gorm:"type:decimal(3,1);not null;column:rating" json:"rating"`
+	PreviousRating *float64  `gorm:"type:decimal(3,1);column:previous_rating" json:"previousRating"`
+	Notes          string    `gorm:"column:notes" json:"notes"`
+	RatedBy        *uint     `gorm:"column:rated_by" json:"ratedBy"`
+	RatedAt        time.Time `gorm:"column:rated_at" json:"ratedAt"`
+
+	Rater *User `gorm:"foreignKey:RatedBy;references:UserID" json:"rater,omitempty"`
+}
+
+func (DeviceConditionLog) TableName() string {
+	return "device_condition_logs"
+}
+
+// CompanyHoliday is a day not charged when rental days are counted as business days
+type CompanyHoliday struct {
+	HolidayID   uint      `gorm:"primaryKey;autoIncrement;column:holiday_id" json:"holidayID"`
+	HolidayDate time.Time `gorm:"type:date;not null;uniqueIndex;column:holiday_date" json:"date"`
+	Name        string    `gorm:"not null;column:name" json:"name"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"updatedAt"`
+}
+
+func (CompanyHoliday) TableName() string {
+	return "company_holidays"
+}
+
+// CompanyHolidayRequest creates or updates a company holiday
+type CompanyHolidayRequest struct {
+	Date string `json:"date" binding:"required"`
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -242,6 +244,61 @@ func (InvoicePayment) TableName() string {
 	return "invoice_payments"
 }
 
+// Payment reminder levels, sent in order for an overdue invoice
+const (
+	ReminderLevelFirst  = 1
+	ReminderLevelSecond = 2
+	ReminderLevelFinal  = 3
+)
+
+// InvoiceReminder records a payment reminder sent for an overdue invoice
+type InvoiceReminder struct {
+	ReminderID    uint64    `gorm:"primaryKey;autoIncrement;column:reminder_id" json:"reminderId"`
+	InvoiceID     uint64    `gorm:"not null;column:invoice_id" json:"invoiceId"`
+	ReminderLevel int       `gorm:"not null;column:reminder_level" json:"reminderLevel"`
+	SentTo        string    `gorm:"not null;column:sent_to" json:"sentTo"`
+	AmountDue     float64   `gorm:"type:decimal(12,2);not null;default:0.00;column:amount_due" json:"amountDue"`
+	SentAt        time.Time `gorm:"not null;column:sent_at" json:"sentAt"`
+}
+
+func (InvoiceReminder) TableName() string {
+	return "invoice_reminders"
+}
+
+// LevelName returns a human readable name for the reminder level
+func (ir *InvoiceReminder) LevelName() string {
+	return ReminderLevelName(ir.ReminderLevel)
+}
+
+// ReminderLevelName returns a human readable name for a reminder level
+func ReminderLevelName(level int) string {
+	switch level {
+	case ReminderLevelFirst:
+		return "First Reminder"
+	case ReminderLevelSecond:
+		return "Second Reminder"
+	case ReminderLevelFinal:
+		return "Final Notice"
+	default:
+		return "Payment Reminder"
+	}
+}
+
+// InvoiceBilledPeriod records the days of a job device an invoice has billed
+type InvoiceBilledPeriod struct {
+	BilledPeriodID uint64    `gorm:"primaryKey;autoIncrement;column:billed_period_id" json:"billedPeriodId"`
+	InvoiceID      uint64    `gorm:"not null;column:invoice_id" json:"invoiceId"`
+	JobID          uint      `gorm:"not null;column:job_id" json:"jobId"`
+	DeviceID       string    `gorm:"not null;column:device_id" json:"deviceId"`
+	PeriodStart    time.Time `gorm:"type:date;not null;column:period_start" json:"periodStart"`
+	PeriodEnd      time.Time `gorm:"type:date;not null;column:period_end" json:"periodEnd"`
+	CreatedAt      time.Time `gorm:"column:created_at" json:"createdAt"`
+}
+
+func (InvoiceBilledPeriod) TableName() string {
+	return "invoice_billed_periods"
+}
+
 // ================================================================
 // DTOs and Request/Response Models
 // ================================================================
@@ -350,6 +407,73 @@ type InvoiceSettings struct {
 	CurrencySymbol          string  `json:"currencySymbol"`
 	CurrencyCode            string  `json:"currencyCode"`
 	DateFormat              string  `json:"dateFormat"`
+
+	// Boilerplate applied to invoices that don't specify their own
+	DefaultTermsConditions string `json:"defaultTermsConditions"`
+	PaymentInstructions    string `json:"paymentInstructions"`
+
+	// How job devices become line items on invoices created from a job
+	LineItemGrouping string `json:"lineItemGrouping"`
+}
+
+// Line item grouping modes for invoices created from a job
+const (
+	LineItemGroupingProduct = "product" // One line per product and rate, quantity = number of devices
+	LineItemGroupingDevice  = "device"  // One line per device, itemized with its serial number
+)
+
+// IsValidLineItemGrouping reports whether grouping is a known line item grouping mode
+func IsValidLineItemGrouping(grouping string) bool {
+	return grouping == LineItemGroupingProduct || grouping == LineItemGroupingDevice
+}
+
+// Currency describes how money amounts are rounded and labelled in reports
+type Currency struct {
+	Code     string `json:"code"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"` // Minor units, e.g. 2 for cents
+}
+
+// currencyMinorUnits lists ISO 4217 currencies that don't use two decimals
+var currencyMinorUnits = map[string]int{
+	"JPY": 0, "KRW": 0, "ISK": 0, "CLP": 0, "VND": 0, "HUF": 0,
+	"BHD": 3, "KWD": 3, "OMR": 3, "JOD": 3, "TND": 3, "IQD": 3, "LYD": 3,
+}
+
+// NewCurrency returns the currency with the given ISO 4217 code, EUR if
+// empty. A negative decimals value uses the currency's minor units and an
+// empty symbol the code.
+func NewCurrency(code, symbol string, decimals int) Currency {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		code = "EUR"
+	}
+	if decimals < 0 {
+		decimals = 2
+		if units, ok := currencyMinorUnits[code]; ok {
+			decimals = units
+		}
+	}
+	if symbol == "" {
+		symbol = code
+	}
+	return Currency{Code: code, Symbol: symbol, Decimals: decimals}
+}
+
+// Round rounds an amount to the currency's minor units
+func (c Currency) Round(amount float64) float64 {
+	factor := math.Pow(10, float64(c.Decimals))
+	return math.Round(amount*factor) / factor
+}
+
+// Format renders an amount with the currency code, e.g. "EUR 1234.50"
+func (c Currency) Format(amount float64) string {
+	return fmt.Sprintf("%s %.*f", c.Code, c.Decimals, c.Round(amount))
+}
+
+// FormatNumber renders an amount rounded to the minor units without a label, for CSV cells
+func (c Currency) FormatNumber(amount float64) string {
+	return strconv.FormatFloat(c.Round(amount), 'f', c.Decimals, 64)
 }
 
 // InvoiceTemplateVariables represents variables available in templates
@@ -363,6 +487,22 @@ type InvoiceTemplateVariables struct {
 	LogoURL   string           `json:"logoUrl"`
 }
 
+// PartialInvoiceRequest selects the devices and sub-period of a job to invoice.
+// Dates are YYYY-MM-DD and default to the job's start and end date.
+type PartialInvoiceRequest struct {
+	DeviceIDs   []string `json:"deviceIds" binding:"required,min=1"`
+	PeriodStart string   `json:"periodStart"`
+	PeriodEnd   string   `json:"periodEnd"`
+	Grouping    string   `json:"grouping" binding:"omitempty,oneof=product device"`
+}
+
+// JobBilledPeriod is a billed period with the invoice it was billed on
+type JobBilledPeriod struct {
+	InvoiceBilledPeriod
+	InvoiceNumber string `json:"invoiceNumber"`
+	InvoiceStatus string `json:"invoiceStatus"`
+}
+
 // InvoiceFilter represents filters for listing invoices
 type InvoiceFilter struct {
 	Status        string     `form:"status" json:"status"`
@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -20,6 +21,7 @@ type Customer struct {
 	Email        *string   `json:"email" gorm:"column:email"`
 	CustomerType *string   `json:"customertype" gorm:"column:customertype"`
 	Notes        *string   `json:"notes" gorm:"column:notes"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"column:updated_at"`
 	Jobs         []Job     `json:"jobs,omitempty" gorm:"-"`
 }
 
@@ -67,6 +69,7 @@ type Job struct {
 	FinalRevenue    *float64    `json:"final_revenue" gorm:"column:final_revenue"`
 	StartDate       *time.Time  `json:"startDate" gorm:"column:startDate;type:date"`
 	EndDate         *time.Time  `json:"endDate" gorm:"column:endDate;type:date"`
+	UpdatedAt       time.Time   `json:"updated_at" gorm:"column:updated_at"`
 	JobDevices      []JobDevice `json:"job_devices,omitempty" gorm:"foreignKey:JobID"`
 	DeviceCount     int         `json:"device_count" gorm:"-:all"`
 }
@@ -75,6 +78,101 @@ func (Job) TableName() string {
 	return "jobs"
 }
 
+// Discount types understood by revenue and analytics calculations
+const (
+	DiscountTypeAmount  = "amount"
+	DiscountTypePercent = "percent"
+)
+
+// NormalizeDiscountType returns the canonical discount type, substituting
+// defaultType for an empty value and rejecting anything else unknown. An empty
+// defaultType means DiscountTypeAmount.
+func NormalizeDiscountType(discountType, defaultType string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(discountType)) {
+	case "":
+		if defaultType == "" {
+			return DiscountTypeAmount, nil
+		}
+		return defaultType, nil
+	case DiscountTypeAmount:
+		return DiscountTypeAmount, nil
+	case DiscountTypePercent:
+		return DiscountTypePercent, nil
+	}
+	return "", fmt.Errorf("invalid discount type %q (must be %q or %q)", discountType, DiscountTypeAmount, DiscountTypePercent)
+}
+
+// ApplyDiscount returns the net revenue after a job discount. Amount discounts
+// never take the revenue below zero.
+func ApplyDiscount(gross, discount float64, discountType string) float64 {
+	if discountType == DiscountTypePercent {
+		return gross * (1 - discount/100)
+	}
+	net := gross - discount
+	if net < 0 {
+		return 0
+	}
+	return net
+}
+
+// RentalDays returns the number of rental days of the job, counting both the
+// start and end day, or 0 if either date is missing
+func (j *Job) RentalDays() int {
+	if j.StartDate == nil || j.EndDate == nil {
+		return 0
+	}
+	return int(j.EndDate.Sub(*j.StartDate).Hours()/24) + 1
+}
+
+// How rental days are counted for invoicing
+const (
+	RentalDayCountingCalendar     = "calendar"
+	RentalDayCountingBusinessDays = "business_days"
+)
+
+// NormalizeRentalDayCounting returns the rental day counting mode, calendar
+// days for an empty value
+func NormalizeRentalDayCounting(mode string) (string, error) {
+	switch mode {
+	case "":
+		return RentalDayCountingCalendar, nil
+	case RentalDayCountingCalendar, RentalDayCountingBusinessDays:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid rental day counting %q (must be %q or %q)", mode, RentalDayCountingCalendar, RentalDayCountingBusinessDays)
+	}
+}
+
+// CountRentalDays returns the rental days from start to end, counting both
+// days. When counting business days, weekends and the given holidays (keyed
+// YYYY-MM-DD) are skipped.
+func CountRentalDays(start, end time.Time, mode string, holidays map[string]bool) int {
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	if last.Before(first) {
+		return 0
+	}
+	if mode != RentalDayCountingBusinessDays {
+		return int(last.Sub(first).Hours()/24) + 1
+	}
+	days := 0
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday || holidays[day.Format("2006-01-02")] {
+			continue
+		}
+		days++
+	}
+	return days
+}
+
+// DeviceQRCodePrefix prefixes the QR payload stored for each device
+const DeviceQRCodePrefix = "QR-"
+
+// DeviceQRCode returns the stable QR payload for a device ID
+func DeviceQRCode(deviceID string) string {
+	return DeviceQRCodePrefix + deviceID
+}
+
 type Device struct {
 	DeviceID             string      `json:"deviceID" gorm:"primaryKey;column:deviceID"`
 	ProductID            *uint       `json:"productID" gorm:"column:productID"`
@@ -96,6 +194,7 @@ type Device struct {
 	LastMaintenanceCost  *float64    `json:"lastMaintenanceCost" gorm:"column:last_maintenance_cost"`
 	Notes                *string     `json:"notes" gorm:"column:notes"`
 	Barcode              *string     `json:"barcode" gorm:"column:barcode"`
+	UpdatedAt            time.Time   `json:"updated_at" gorm:"column:updated_at"`
 	JobDevices           []JobDevice `json:"job_devices,omitempty" gorm:"-"`
 }
 
@@ -103,6 +202,93 @@ func (Device) TableName() string {
 	return "devices"
 }
 
+// Device statuses. "maintance" is the legacy spelling older records and the
+// case forms still use; newer code writes "maintenance". The setup schema
+// uses "available" instead of "free" and adds "retired".
+const (
+	DeviceStatusFree        = "free"
+	DeviceStatusRented      = "rented"
+	DeviceStatusCheckedOut  = "checked out"
+	DeviceStatusMaintance   = "maintance"
+	DeviceStatusMaintenance = "maintenance"
+	DeviceStatusAvailable   = "available"
+	DeviceStatusRetired     = "retired"
+)
+
+// DeviceStatuses are the device statuses of both schemas, for databases whose
+// status column doesn't restrict them
+var DeviceStatuses = []string{
+	DeviceStatusFree, DeviceStatusAvailable, DeviceStatusRented, DeviceStatusCheckedOut,
+	DeviceStatusMaintance, DeviceStatusMaintenance, DeviceStatusRetired,
+}
+
+// DeviceMaintenanceStatuses are both spellings of the maintenance status, for
+// queries that must match devices in maintenance on either schema
+var DeviceMaintenanceStatuses = []string{DeviceStatusMaintance, DeviceStatusMaintenance}
+
+// IsMaintenanceStatus reports whether a device status means the device is in
+// maintenance, in either spelling
+func IsMaintenanceStatus(status string) bool {
+	return status == DeviceStatusMaintance || status == DeviceStatusMaintenance
+}
+
+// IsValidDeviceStatus reports whether status is one of the supported
+// statuses, or of DeviceStatuses when supported is empty
+func IsValidDeviceStatus(status string, supported []string) bool {
+	if len(supported) == 0 {
+		supported = DeviceStatuses
+	}
+	for _, value := range supported {
+		if value == status {
+			return true
+		}
+	}
+	return false
+}
+
+// DeviceStatusUsageAction returns the equipment usage log action recorded when
+// a device is set to the given status
+func DeviceStatusUsageAction(status string) string {
+	switch status {
+	case DeviceStatusRented, DeviceStatusCheckedOut:
+		return "assigned"
+	case DeviceStatusMaintance, DeviceStatusMaintenance:
+		return "maintenance"
+	}
+	return "available"
+}
+
+// BulkDeviceStatusRequest sets the status of several devices at once
+type BulkDeviceStatusRequest struct {
+	DeviceIDs []string `json:"deviceIds" binding:"required,min=1"`
+	Status    string   `json:"status" binding:"required"`
+	Notes     string   `json:"notes"`
+}
+
+// BulkDeviceStatusResult is the outcome of a bulk status update for one device
+type BulkDeviceStatusResult struct {
+	DeviceID       string `json:"deviceID"`
+	Success        bool   `json:"success"`
+	PreviousStatus string `json:"previousStatus,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// DeviceLocationRequest reports where a device physically is. Latitude and
+// longitude are optional but must be given together.
+type DeviceLocationRequest struct {
+	Location  string   `json:"location" binding:"required,max=100"`
+	Latitude  *float64 `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	Notes     string   `json:"notes"`
+}
+
+// DeviceConditionRequest rates a device's physical condition from 0 (unusable)
+// to 5 (as new)
+type DeviceConditionRequest struct {
+	Rating *float64 `json:"rating" binding:"required,min=0,max=5"`
+	Notes  string   `json:"notes"`
+}
+
 type Product struct {
 	ProductID             uint     `json:"productID" gorm:"primaryKey;column:productID"`
 	Name                  string   `json:"name" gorm:"not null;column:name"`
@@ -120,6 +306,9 @@ type Product struct {
 	Depth                 *float64 `json:"depth" gorm:"column:depth"`
 	PowerConsumption      *float64     `json:"powerconsumption" gorm:"column:powerconsumption"`
 	PosInCategory         *uint        `json:"pos_in_category" gorm:"column:pos_in_category"`
+	IsConsumable          bool         `json:"is_consumable" gorm:"column:is_consumable;default:false"` // Not tracked per unit, never blocks availability
+	DepositAmount         *float64     `json:"deposit_amount" gorm:"column:deposit_amount" binding:"omitempty,min=0"`          // Deposit per device
+	DepositPercent        *float64     `json:"deposit_percent" gorm:"column:deposit_percent" binding:"omitempty,min=0,max=100"` // Deposit per device as % of its rental price, if no amount is set
 	Category              *Category       `json:"category,omitempty" gorm:"foreignKey:CategoryID;references:CategoryID"`
 	Subcategory           *Subcategory    `json:"subcategory,omitempty" gorm:"foreignKey:SubcategoryID;references:SubcategoryID"`
 	Subbiercategory       *Subbiercategory `json:"subbiercategory,omitempty" gorm:"foreignKey:SubbiercategoryID;references:SubbiercategoryID"`
@@ -131,6 +320,18 @@ func (Product) TableName() string {
 	return "products"
 }
 
+// DepositFor returns the deposit required for one device of the product
+// rented at the given price. A fixed amount takes precedence over a percentage.
+func (p *Product) DepositFor(price float64) float64 {
+	if p.DepositAmount != nil {
+		return *p.DepositAmount
+	}
+	if p.DepositPercent != nil {
+		return price * *p.DepositPercent / 100
+	}
+	return 0
+}
+
 
 type Subcategory struct {
 	SubcategoryID string  `json:"subcategoryID" gorm:"primaryKey;column:subcategoryID"`
@@ -215,6 +416,7 @@ type JobCategory struct {
 	JobCategoryID uint    `json:"jobcategoryID" gorm:"primaryKey;column:jobcategoryID"`
 	Name          string  `json:"name" gorm:"column:name"`
 	Abbreviation  *string `json:"abbreviation" gorm:"column:abbreviation"`
+	MaxRentalDays *int    `json:"max_rental_days" gorm:"column:max_rental_days"` // Overrides the global maximum rental duration
 }
 
 func (JobCategory) TableName() string {
@@ -272,6 +474,7 @@ type FilterParams struct {
 	ProductID          *uint  `form:"product_id"`
 	AssignmentStatus   string `form:"assignment_status"`
 	JobID              *uint  `form:"job_id"`
+	IncludeArchived    bool   `form:"includeArchived"`
 }
 
 // DeviceAssignmentHistory represents the history of device assignments
@@ -317,6 +520,14 @@ type Session struct {
 	UserID    uint      `json:"userID" gorm:"not null;column:user_id"`
 	ExpiresAt time.Time `json:"expiresAt" gorm:"not null;column:expires_at"`
 	CreatedAt time.Time `json:"createdAt" gorm:"column:created_at"`
+	// Persistent sessions ("remember me") outlive the browser session
+	Persistent bool `json:"persistent" gorm:"not null;default:false;column:persistent"`
+	// Pending2FA marks the short-lived session of a login awaiting its second factor
+	Pending2FA bool `json:"pending2FA" gorm:"not null;default:false;column:pending_2fa"`
+	// Client the session was started from
+	UserAgent string     `json:"userAgent" gorm:"column:user_agent"`
+	IPAddress string     `json:"ipAddress" gorm:"column:ip_address"`
+	LastSeen  *time.Time `json:"lastSeen,omitempty" gorm:"column:last_seen"`
 }
 
 func (Session) TableName() string {
@@ -1,7 +1,7 @@
 package repository
 
 import (
-	"log"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 )
 
@@ -146,7 +146,7 @@ func (r *CableRepository) GetAllCableTypes() ([]models.CableType, error) {
 	var types []models.CableType
 	err := r.db.Order("name ASC").Find(&types).Error
 	if err != nil {
-		log.Printf("❌ GetAllCableTypes error: %v", err)
+		logger.Errorf("GetAllCableTypes error: %v", err)
 		return nil, err
 	}
 	return types, nil
@@ -157,7 +157,7 @@ func (r *CableRepository) GetAllCableConnectors() ([]models.CableConnector, erro
 	var connectors []models.CableConnector
 	err := r.db.Order("name ASC").Find(&connectors).Error
 	if err != nil {
-		log.Printf("❌ GetAllCableConnectors error: %v", err)
+		logger.Errorf("GetAllCableConnectors error: %v", err)
 		return nil, err
 	}
 	return connectors, nil
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"fmt"
+	"strconv"
+
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 )
 
@@ -13,11 +16,16 @@ func NewCustomerRepository(db *Database) *CustomerRepository {
 	return &CustomerRepository{db: db}
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *CustomerRepository) WithTx(tx *Database) *CustomerRepository {
+	return &CustomerRepository{db: tx}
+}
+
 func (r *CustomerRepository) Create(customer *models.Customer) error {
-	fmt.Printf("🔧 DEBUG CustomerRepo.Create: Before DB operation, customer ID: %d\n", customer.CustomerID)
+	logger.Debugf("CustomerRepo.Create: Before DB operation, customer ID: %d", customer.CustomerID)
 	result := r.db.Create(customer)
-	fmt.Printf("🔧 DEBUG CustomerRepo.Create: After DB operation, customer ID: %d, Error: %v\n", customer.CustomerID, result.Error)
-	fmt.Printf("🔧 DEBUG CustomerRepo.Create: Rows affected: %d\n", result.RowsAffected)
+	logger.Debugf("CustomerRepo.Create: After DB operation, customer ID: %d, Error: %v", customer.CustomerID, result.Error)
+	logger.Debugf("CustomerRepo.Create: Rows affected: %d", result.RowsAffected)
 	return result.Error
 }
 
@@ -59,4 +67,157 @@ func (r *CustomerRepository) List(params *models.FilterParams) ([]models.Custome
 
 	err := query.Find(&customers).Error
 	return customers, err
-}
\ No newline at end of file
+}
+
+// ListContacts returns a customer's contact persons ordered by role and name
+func (r *CustomerRepository) ListContacts(customerID uint) ([]models.CustomerContact, error) {
+	var contacts []models.CustomerContact
+	err := r.db.Where("customer_id = ?", customerID).
+		Order("role ASC, name ASC").
+		Find(&contacts).Error
+	return contacts, err
+}
+
+// GetContact returns a contact person belonging to the given customer
+func (r *CustomerRepository) GetContact(customerID, contactID uint) (*models.CustomerContact, error) {
+	var contact models.CustomerContact
+	err := r.db.Where("customer_id = ? AND contact_id = ?", customerID, contactID).First(&contact).Error
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+func (r *CustomerRepository) CreateContact(contact *models.CustomerContact) error {
+	return r.db.Create(contact).Error
+}
+
+func (r *CustomerRepository) UpdateContact(contact *models.CustomerContact) error {
+	return r.db.Save(contact).Error
+}
+
+func (r *CustomerRepository) DeleteContact(customerID, contactID uint) error {
+	return r.db.Where("customer_id = ? AND contact_id = ?", customerID, contactID).
+		Delete(&models.CustomerContact{}).Error
+}
+
+// GetContactEmail returns the email of the customer's first contact with the given
+// role, falling back to the customer's own email when there is none
+func (r *CustomerRepository) GetContactEmail(customer *models.Customer, role string) string {
+	if role != "" {
+		var contact models.CustomerContact
+		err := r.db.Where("customer_id = ? AND role = ? AND email IS NOT NULL AND email <> ''", customer.CustomerID, role).
+			Order("contact_id ASC").
+			First(&contact).Error
+		if err == nil {
+			return *contact.Email
+		}
+	}
+
+	if customer.Email != nil {
+		return *customer.Email
+	}
+	return ""
+}
+
+// GetDB returns the underlying database connection
+func (r *CustomerRepository) GetDB() *Database {
+	return r.db
+}
+
+// CustomerExportSections are the parts of a customer data export that can be
+// selected with the include parameter; the customer record is always exported
+var CustomerExportSections = []string{"contacts", "jobs", "invoices", "transactions", "documents", "audit"}
+
+// IsCustomerExportSection reports whether section is one of CustomerExportSections
+func IsCustomerExportSection(section string) bool {
+	return containsDeviceID(CustomerExportSections, section)
+}
+
+// CustomerDataExport is everything stored about one customer, for subject access requests
+type CustomerDataExport struct {
+	Customer     *models.Customer              `json:"customer"`
+	Contacts     []models.CustomerContact      `json:"contacts"`
+	Jobs         []models.Job                  `json:"jobs"`
+	Invoices     []models.Invoice              `json:"invoices"`
+	Transactions []models.FinancialTransaction `json:"transactions"`
+	Documents    []models.Document             `json:"documents"`
+	AuditLogs    []models.AuditLog             `json:"auditLogs"`
+}
+
+// CollectExportData gathers the customer record with their contacts, jobs,
+// invoices (with line items and payments), financial transactions, the
+// documents attached to the customer, their jobs or invoices, and the audit
+// entries referencing any of them
+func (r *CustomerRepository) CollectExportData(customerID uint) (*CustomerDataExport, error) {
+	customer, err := r.GetByID(customerID)
+	if err != nil {
+		return nil, err
+	}
+	export := &CustomerDataExport{Customer: customer}
+
+	if export.Contacts, err = r.ListContacts(customerID); err != nil {
+		return nil, fmt.Errorf("failed to load contacts: %v", err)
+	}
+
+	if err := r.db.Preload("Status").Preload("JobDevices").
+		Where("customerID = ?", customerID).
+		Order("jobID ASC").
+		Find(&export.Jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load jobs: %v", err)
+	}
+	jobIDs := make([]string, len(export.Jobs))
+	for i, job := range export.Jobs {
+		jobIDs[i] = strconv.FormatUint(uint64(job.JobID), 10)
+	}
+
+	if err := r.db.Where("customer_id = ?", customerID).
+		Order("invoice_id ASC").
+		Find(&export.Invoices).Error; err != nil {
+		return nil, fmt.Errorf("failed to load invoices: %v", err)
+	}
+	invoiceIDs := make([]string, len(export.Invoices))
+	for i := range export.Invoices {
+		invoice := &export.Invoices[i]
+		invoiceIDs[i] = strconv.FormatUint(invoice.InvoiceID, 10)
+		if err := r.db.Where("invoice_id = ?", invoice.InvoiceID).Order("sort_order ASC").Find(&invoice.LineItems).Error; err != nil {
+			return nil, fmt.Errorf("failed to load line items of invoice %s: %v", invoice.InvoiceNumber, err)
+		}
+		if err := r.db.Where("invoice_id = ?", invoice.InvoiceID).Find(&invoice.Payments).Error; err != nil {
+			return nil, fmt.Errorf("failed to load payments of invoice %s: %v", invoice.InvoiceNumber, err)
+		}
+	}
+
+	transactions := r.db.Where("customerID = ?", customerID)
+	if len(jobIDs) > 0 {
+		transactions = transactions.Or("jobID IN ?", jobIDs)
+	}
+	if err := transactions.Order("transaction_date ASC").Find(&export.Transactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load transactions: %v", err)
+	}
+
+	customerKey := strconv.FormatUint(uint64(customerID), 10)
+	documents := r.db.Where("entity_type = ? AND entity_id = ?", "customer", customerKey)
+	if len(jobIDs) > 0 {
+		documents = documents.Or("entity_type = ? AND entity_id IN ?", "job", jobIDs)
+	}
+	if len(invoiceIDs) > 0 {
+		documents = documents.Or("entity_type = ? AND entity_id IN ?", "invoice", invoiceIDs)
+	}
+	if err := documents.Order("uploaded_at ASC").Find(&export.Documents).Error; err != nil {
+		return nil, fmt.Errorf("failed to load documents: %v", err)
+	}
+
+	audit := r.db.Where("entity_type = ? AND entity_id = ?", "customer", customerKey)
+	if len(jobIDs) > 0 {
+		audit = audit.Or("entity_type = ? AND entity_id IN ?", "job", jobIDs)
+	}
+	if len(invoiceIDs) > 0 {
+		audit = audit.Or("entity_type = ? AND entity_id IN ?", "invoice", invoiceIDs)
+	}
+	if err := audit.Order("timestamp ASC").Find(&export.AuditLogs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load audit log: %v", err)
+	}
+
+	return export, nil
+}
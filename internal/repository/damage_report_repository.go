@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"go-barcode-webapp/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+type DamageReportRepository struct {
+	db *Database
+}
+
+func NewDamageReportRepository(db *Database) *DamageReportRepository {
+	return &DamageReportRepository{db: db}
+}
+
+// Create stores a damage report with its photos. A report that takes the
+// device out of service moves it to maintenance, which removes it from all
+// availability checks until the report is resolved.
+func (r *DamageReportRepository) Create(report *models.DamageReport, photoIDs []uint) error {
+	return r.db.WithTransaction(func(tx *Database) error {
+		var device models.Device
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deviceID = ?", report.DeviceID).First(&device).Error; err != nil {
+			return fmt.Errorf("device %s not found", report.DeviceID)
+		}
+
+		// Remember the status to return to. If another open report already took
+		// the device out of service, inherit its status; a device put into
+		// maintenance by hand is left there when the report is resolved.
+		takeOutOfService := false
+		if report.OutOfService {
+			if !models.IsMaintenanceStatus(device.Status) {
+				previous := device.Status
+				report.PreviousDeviceStatus = &previous
+				takeOutOfService = true
+			} else {
+				var open models.DamageReport
+				if err := tx.Where("device_id = ? AND status = ? AND out_of_service = ? AND previous_device_status IS NOT NULL",
+					report.DeviceID, models.DamageReportOpen, true).
+					Order("report_id ASC").First(&open).Error; err == nil {
+					report.PreviousDeviceStatus = open.PreviousDeviceStatus
+				}
+			}
+		}
+		report.Status = models.DamageReportOpen
+		if err := tx.Create(report).Error; err != nil {
+			return fmt.Errorf("failed to create damage report: %v", err)
+		}
+
+		for _, documentID := range photoIDs {
+			photo := models.DamageReportPhoto{ReportID: report.ReportID, DocumentID: documentID}
+			if err := tx.Create(&photo).Error; err != nil {
+				return fmt.Errorf("failed to attach photo %d: %v", documentID, err)
+			}
+		}
+
+		if !takeOutOfService {
+			return nil
+		}
+		maintenance, err := maintenanceDeviceStatus(tx)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Device{}).Where("deviceID = ?", report.DeviceID).
+			Update("status", maintenance).Error; err != nil {
+			return fmt.Errorf("failed to take device out of service: %v", err)
+		}
+		log := models.EquipmentUsageLog{
+			DeviceID:  report.DeviceID,
+			JobID:     report.JobID,
+			Action:    "maintenance",
+			Timestamp: time.Now(),
+			Notes:     fmt.Sprintf("Out of service: damage report %d", report.ReportID),
+		}
+		if err := tx.Create(&log).Error; err != nil {
+			return fmt.Errorf("failed to write usage log: %v", err)
+		}
+		return nil
+	})
+}
+
+// Resolve closes a damage report. The device returns to the status it had
+// before being taken out of service once no other open report keeps it there.
+func (r *DamageReportRepository) Resolve(reportID uint, resolvedBy *uint, notes string) (*models.DamageReport, error) {
+	var report models.DamageReport
+	err := r.db.WithTransaction(func(tx *Database) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, reportID).Error; err != nil {
+			return err
+		}
+		if report.Status == models.DamageReportResolved {
+			return fmt.Errorf("damage report %d is already resolved", reportID)
+		}
+
+		now := time.Now()
+		report.Status = models.DamageReportResolved
+		report.ResolvedBy = resolvedBy
+		report.ResolvedAt = &now
+		if notes != "" {
+			report.ResolutionNotes = &notes
+		}
+		if err := tx.Save(&report).Error; err != nil {
+			return fmt.Errorf("failed to resolve damage report: %v", err)
+		}
+
+		if !report.OutOfService || report.PreviousDeviceStatus == nil {
+			return nil
+		}
+
+		var stillOpen int64
+		if err := tx.Model(&models.DamageReport{}).
+			Where("device_id = ? AND status = ? AND out_of_service = ?", report.DeviceID, models.DamageReportOpen, true).
+			Count(&stillOpen).Error; err != nil {
+			return err
+		}
+		if stillOpen > 0 {
+			return nil
+		}
+
+		restore := *report.PreviousDeviceStatus
+		if err := tx.Model(&models.Device{}).
+			Where("deviceID = ? AND status IN ?", report.DeviceID, models.DeviceMaintenanceStatuses).
+			Update("status", restore).Error; err != nil {
+			return fmt.Errorf("failed to return device to service: %v", err)
+		}
+		log := models.EquipmentUsageLog{
+			DeviceID:  report.DeviceID,
+			Action:    "available",
+			Timestamp: now,
+			Notes:     fmt.Sprintf("Back in service: damage report %d resolved", report.ReportID),
+		}
+		if err := tx.Create(&log).Error; err != nil {
+			return fmt.Errorf("failed to write usage log: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *DamageReportRepository) GetByID(reportID uint) (*models.DamageReport, error) {
+	var report models.DamageReport
+	err := r.db.Preload("Device").Preload("Device.Product").Preload("Job").Preload("Reporter").
+		First(&report, reportID).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadPhotos([]*models.DamageReport{&report}); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ListByDevice returns a device's damage reports, newest first
+func (r *DamageReportRepository) ListByDevice(deviceID string, openOnly bool) ([]models.DamageReport, error) {
+	var reports []models.DamageReport
+	query := r.db.Where("device_id = ?", deviceID)
+	if openOnly {
+		query = query.Where("status = ?", models.DamageReportOpen)
+	}
+	if err := query.Preload("Job").Preload("Reporter").Order("created_at DESC").Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, r.loadPhotos(reportPointers(reports))
+}
+
+// ListOpen returns all unresolved damage reports, most severe and oldest first
+func (r *DamageReportRepository) ListOpen(limit int) ([]models.DamageReport, error) {
+	var reports []models.DamageReport
+	query := r.db.Where("status = ?", models.DamageReportOpen).
+		Preload("Device").Preload("Device.Product").Preload("Job").
+		Order("FIELD(severity, 'critical', 'major', 'moderate', 'minor'), created_at ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (r *DamageReportRepository) CountOpen() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.DamageReport{}).Where("status = ?", models.DamageReportOpen).Count(&count).Error
+	return count, err
+}
+
+func (r *DamageReportRepository) loadPhotos(reports []*models.DamageReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(reports))
+	byID := make(map[uint]*models.DamageReport, len(reports))
+	for i, report := range reports {
+		ids[i] = report.ReportID
+		byID[report.ReportID] = report
+	}
+
+	var links []models.DamageReportPhoto
+	if err := r.db.Where("report_id IN ?", ids).Find(&links).Error; err != nil {
+		return fmt.Errorf("failed to load damage report photos: %v", err)
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	documentIDs := make([]uint, len(links))
+	for i, link := range links {
+		documentIDs[i] = link.DocumentID
+	}
+	var documents []models.Document
+	if err := r.db.Find(&documents, documentIDs).Error; err != nil {
+		return fmt.Errorf("failed to load damage report photos: %v", err)
+	}
+	documentsByID := make(map[uint]models.Document, len(documents))
+	for _, document := range documents {
+		documentsByID[document.DocumentID] = document
+	}
+	for _, link := range links {
+		if document, ok := documentsByID[link.DocumentID]; ok {
+			byID[link.ReportID].Photos = append(byID[link.ReportID].Photos, document)
+		}
+	}
+	return nil
+}
+
+func reportPointers(reports []models.DamageReport) []*models.DamageReport {
+	pointers := make([]*models.DamageReport, len(reports))
+	for i := range reports {
+		pointers[i] = &reports[i]
+	}
+	return pointers
+}
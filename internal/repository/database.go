@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"go-barcode-webapp/internal/config"
+	applog "go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/version"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -53,7 +55,47 @@ func NewDatabase(cfg *config.DatabaseConfig) (*Database, error) {
 	// Basic database connection setup only - no schema operations
 	
 	log.Println("Database connection established successfully")
-	return &Database{db}, nil
+
+	database := &Database{db}
+	if err := database.CheckSchemaVersion(cfg.RequireCurrentSchema); err != nil {
+		return nil, err
+	}
+	return database, nil
+}
+
+// SchemaVersion returns the highest migration recorded in schema_migrations,
+// or 0 if the table doesn't exist yet
+func (db *Database) SchemaVersion() (uint, error) {
+	if !db.Migrator().HasTable("schema_migrations") {
+		return 0, nil
+	}
+
+	var applied uint
+	if err := db.Raw("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&applied).Error; err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return applied, nil
+}
+
+// CheckSchemaVersion logs the build and schema versions and compares the applied
+// migrations with the ones this binary expects. A schema that is behind is an
+// error when required is set and a loud warning otherwise.
+func (db *Database) CheckSchemaVersion(required bool) error {
+	applied, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	applog.Infof("RentalCore %s, database schema version %d (expected %d)", version.Get(), applied, version.SchemaVersion)
+
+	if applied >= version.SchemaVersion {
+		return nil
+	}
+	if required {
+		return fmt.Errorf("database schema version %d is behind the expected version %d; apply the pending migrations first", applied, version.SchemaVersion)
+	}
+	applog.Warnf("DATABASE SCHEMA IS OUT OF DATE: applied version %d, expected %d. Apply the pending migrations in migrations/ or features may be missing.", applied, version.SchemaVersion)
+	return nil
 }
 
 func (db *Database) Close() error {
@@ -70,4 +112,13 @@ func (db *Database) Ping() error {
 		return err
 	}
 	return sqlDB.Ping()
-}
\ No newline at end of file
+}
+
+// WithTransaction runs fn inside a single database transaction. The transaction
+// is committed when fn returns nil and rolled back when it returns an error or panics.
+// Repositories can be bound to the transaction via their WithTx methods.
+func (db *Database) WithTransaction(fn func(tx *Database) error) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(&Database{tx})
+	})
+}
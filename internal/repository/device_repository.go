@@ -1,20 +1,32 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"runtime/debug"
 	"strings"
 	"time"
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DeviceRepository struct {
-	db *Database
+	db            *Database
+	devicesConfig *config.DevicesConfig
+}
+
+func NewDeviceRepository(db *Database, devicesConfig *config.DevicesConfig) *DeviceRepository {
+	return &DeviceRepository{db: db, devicesConfig: devicesConfig}
 }
 
-func NewDeviceRepository(db *Database) *DeviceRepository {
-	return &DeviceRepository{db: db}
+// autoAssignQRCodes reports whether new devices get a QR code stored on
+// creation; on unless the devices settings turn it off
+func (r *DeviceRepository) autoAssignQRCodes() bool {
+	return r.devicesConfig == nil || r.devicesConfig.AutoAssignQRCode
 }
 
 // GetDB returns the underlying database connection for advanced queries
@@ -22,14 +34,92 @@ func (r *DeviceRepository) GetDB() *Database {
 	return r.db
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *DeviceRepository) WithTx(tx *Database) *DeviceRepository {
+	return &DeviceRepository{db: tx, devicesConfig: r.devicesConfig}
+}
+
+// isConsumableDevice reports whether the device belongs to a product flagged as consumable.
+// Consumables are not tracked per unit, so their assignments never block availability.
+func isConsumableDevice(db *Database, deviceID string) (bool, error) {
+	var count int64
+	err := db.Table("devices d").
+		Joins("JOIN products p ON d.productID = p.productID").
+		Where("d.deviceID = ? AND p.is_consumable = TRUE", deviceID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// deviceStatusValues returns the values the devices.status enum accepts, or
+// nil when the column isn't an enum. RentalCore.sql and the setup schema
+// define different enums, e.g. "maintance" versus "maintenance".
+func deviceStatusValues(db *Database) ([]string, error) {
+	var columnType string
+	err := db.Raw(`SELECT COLUMN_TYPE FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'devices' AND COLUMN_NAME = 'status'`).
+		Scan(&columnType).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device status column: %v", err)
+	}
+	return parseEnumValues(columnType), nil
+}
+
+// parseEnumValues returns the values of a MySQL enum column type such as
+// enum('free','rented'), leaving out the empty value
+func parseEnumValues(columnType string) []string {
+	if !strings.HasPrefix(strings.ToLower(columnType), "enum(") || !strings.HasSuffix(columnType, ")") {
+		return nil
+	}
+	var values []string
+	for _, value := range strings.Split(columnType[len("enum("):len(columnType)-1], ",") {
+		if value = strings.Trim(value, "'"); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// SupportedStatuses returns the device statuses the deployed schema accepts,
+// or models.DeviceStatuses when the status column isn't an enum
+func (r *DeviceRepository) SupportedStatuses() ([]string, error) {
+	values, err := deviceStatusValues(r.db)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return models.DeviceStatuses, nil
+	}
+	return values, nil
+}
+
+// maintenanceDeviceStatus returns the spelling of the maintenance status the
+// devices.status column accepts
+func maintenanceDeviceStatus(db *Database) (string, error) {
+	values, err := deviceStatusValues(db)
+	if err != nil {
+		return "", err
+	}
+	if values == nil {
+		return models.DeviceStatusMaintenance, nil
+	}
+	for _, spelling := range models.DeviceMaintenanceStatuses {
+		for _, value := range values {
+			if value == spelling {
+				return value, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("device status column has no maintenance status")
+}
+
 func (r *DeviceRepository) Create(device *models.Device) error {
-	log.Printf("🚨 DEVICE CREATION: Creating device %s with productID %v", device.DeviceID, device.ProductID)
-	log.Printf("🚨 DEVICE CREATION: Stack trace: %s", string(debug.Stack()))
+	logger.Debugf("DEVICE CREATION: Creating device %s with productID %v", device.DeviceID, device.ProductID)
+	logger.Debugf("DEVICE CREATION: Stack trace: %s", string(debug.Stack()))
 	
 	// Check if this is being called during package operations
 	stackTrace := string(debug.Stack())
 	if strings.Contains(stackTrace, "equipment_package") || strings.Contains(stackTrace, "UpdateDeviceAssociations") || strings.Contains(stackTrace, "package") {
-		log.Printf("❌ DEVICE CREATION: Blocked device creation during package operations")
+		logger.Warnf("DEVICE CREATION: Blocked device creation during package operations")
 		return fmt.Errorf("device creation blocked during package operations - device %s does not exist", device.DeviceID)
 	}
 	
@@ -37,16 +127,34 @@ func (r *DeviceRepository) Create(device *models.Device) error {
 	if device.DeviceID == "" {
 		generatedID, err := r.generateDeviceID(device)
 		if err != nil {
-			log.Printf("❌ DEVICE CREATION: Failed to generate device ID: %v", err)
+			logger.Errorf("DEVICE CREATION: Failed to generate device ID: %v", err)
 			return fmt.Errorf("failed to generate device ID: %v", err)
 		}
 		device.DeviceID = generatedID
-		log.Printf("✅ DEVICE CREATION: Generated device ID: %s", device.DeviceID)
+		logger.Debugf("DEVICE CREATION: Generated device ID: %s", device.DeviceID)
+	}
+
+	// Store a stable QR payload unless one was supplied (e.g. from a pre-printed label)
+	if (device.QRCode == nil || *device.QRCode == "") && r.autoAssignQRCodes() {
+		qrCode := models.DeviceQRCode(device.DeviceID)
+		device.QRCode = &qrCode
 	}
 	
 	return r.db.Create(device).Error
 }
 
+// BackfillQRCodes stores the default QR payload for every device that has none
+// and returns how many devices were updated
+func (r *DeviceRepository) BackfillQRCodes() (int64, error) {
+	result := r.db.Model(&models.Device{}).
+		Where("qr_code IS NULL OR qr_code = ''").
+		Update("qr_code", gorm.Expr("CONCAT(?, deviceID)", models.DeviceQRCodePrefix))
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to backfill QR codes: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
 func (r *DeviceRepository) GetByID(deviceID string) (*models.Device, error) {
 	var device models.Device
 	err := r.db.Where("deviceID = ?", deviceID).
@@ -79,24 +187,48 @@ func (r *DeviceRepository) GetBySerialNo(serialNo string) (*models.Device, error
 	return &device, nil
 }
 
+// GetByQRCode returns the device a scanned QR payload belongs to: the device
+// storing that code, otherwise for a default QR-<deviceID> payload the device
+// with that ID
+func (r *DeviceRepository) GetByQRCode(code string) (*models.Device, error) {
+	var device models.Device
+	err := r.db.Where("qr_code = ?", code).
+		Preload("Product").
+		Preload("Product.Category").
+		Preload("Product.Subcategory").
+		Preload("Product.Subbiercategory").
+		Preload("Product.Brand").
+		Preload("Product.Manufacturer").
+		First(&device).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if deviceID, ok := strings.CutPrefix(code, models.DeviceQRCodePrefix); ok {
+			return r.GetByID(deviceID)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
 func (r *DeviceRepository) Update(device *models.Device) error {
 	return r.db.Save(device).Error
 }
 
 func (r *DeviceRepository) Delete(deviceID string) error {
-	log.Printf("🗑️ DEVICE DELETION: Deleting device %s", deviceID)
+	logger.Debugf("DEVICE DELETION: Deleting device %s", deviceID)
 	err := r.db.Where("deviceID = ?", deviceID).Delete(&models.Device{}).Error
 	if err != nil {
-		log.Printf("❌ DEVICE DELETION: Failed to delete device %s: %v", deviceID, err)
+		logger.Errorf("DEVICE DELETION: Failed to delete device %s: %v", deviceID, err)
 	} else {
-		log.Printf("✅ DEVICE DELETION: Successfully deleted device %s", deviceID)
+		logger.Debugf("DEVICE DELETION: Successfully deleted device %s", deviceID)
 	}
 	return err
 }
 
 func (r *DeviceRepository) List(params *models.FilterParams) ([]models.DeviceWithJobInfo, error) {
 	startTime := time.Now()
-	log.Printf("⏱️  DeviceRepository.List() started")
+	logger.Debugf("DeviceRepository.List() started")
 
 	var devices []models.Device
 
@@ -130,10 +262,10 @@ func (r *DeviceRepository) List(params *models.FilterParams) ([]models.DeviceWit
 	queryStart := time.Now()
 	err := query.Find(&devices).Error
 	queryTime := time.Since(queryStart)
-	log.Printf("⏱️  Device query took: %v", queryTime)
+	logger.Debugf("Device query took: %v", queryTime)
 	
 	if err != nil {
-		log.Printf("❌ Device query error: %v", err)
+		logger.Errorf("Device query error: %v", err)
 		return nil, err
 	}
 	
@@ -148,7 +280,7 @@ func (r *DeviceRepository) List(params *models.FilterParams) ([]models.DeviceWit
 	}
 
 	totalTime := time.Since(startTime)
-	log.Printf("⏱️  DeviceRepository.List() completed in %v (found %d devices)", totalTime, len(result))
+	logger.Debugf("DeviceRepository.List() completed in %v (found %d devices)", totalTime, len(result))
 
 	return result, nil
 }
@@ -219,18 +351,31 @@ func (r *DeviceRepository) GetAvailableDevices() ([]models.Device, error) {
 	
 	// Get devices that are available and not currently assigned to any active job (considering dates)
 	currentDate := time.Now().Format("2006-01-02")
-	err := r.db.Where(`status = 'free' AND deviceID NOT IN (
+	// Devices of consumable products stay available regardless of assignments
+	err := r.db.Where(`status = 'free' AND (deviceID NOT IN (
 		SELECT DISTINCT jd.deviceID 
 		FROM jobdevices jd
 		JOIN jobs j ON jd.jobID = j.jobID 
 		WHERE j.startDate <= ? AND j.endDate >= ? AND j.statusID IN (
 			SELECT statusID FROM status WHERE status IN ('open', 'in_progress')
 		)
-	)`, currentDate, currentDate).Find(&devices).Error
+	) OR productID IN (SELECT productID FROM products WHERE is_consumable = TRUE))`, currentDate, currentDate).Find(&devices).Error
 	
 	return devices, err
 }
 
+// GetDevicesExcept returns all devices except the given ones, used for
+// availability over a date range where the caller has found the conflicts
+func (r *DeviceRepository) GetDevicesExcept(deviceIDs []string) ([]models.Device, error) {
+	var devices []models.Device
+	query := r.db.Model(&models.Device{})
+	if len(deviceIDs) > 0 {
+		query = query.Where("deviceID NOT IN ?", deviceIDs)
+	}
+	err := query.Order("deviceID ASC").Find(&devices).Error
+	return devices, err
+}
+
 func (r *DeviceRepository) GetDevicesByCategory(category string) ([]models.Device, error) {
 	var devices []models.Device
 	err := r.db.Where("category = ? AND available = true", category).
@@ -282,7 +427,7 @@ func (r *DeviceRepository) GetDeviceStats(deviceID string) (map[string]interface
 		Where("deviceID = ?", deviceID).
 		Count(&totalJobs).Error
 	if err != nil {
-		log.Printf("Error counting jobs for device %s: %v", deviceID, err)
+		logger.Errorf("Error counting jobs for device %s: %v", deviceID, err)
 		totalJobs = 0
 	}
 	
@@ -297,7 +442,7 @@ func (r *DeviceRepository) GetDeviceStats(deviceID string) (map[string]interface
 		WHERE jd.deviceID = ?
 	`, deviceID).Scan(&totalEarnings).Error
 	if err != nil {
-		log.Printf("Error calculating earnings for device %s: %v", deviceID, err)
+		logger.Errorf("Error calculating earnings for device %s: %v", deviceID, err)
 		totalEarnings = 0.0
 	}
 	
@@ -310,7 +455,7 @@ func (r *DeviceRepository) GetDeviceStats(deviceID string) (map[string]interface
 		WHERE jd.deviceID = ?
 	`, deviceID).Scan(&totalDaysRented).Error
 	if err != nil {
-		log.Printf("Error calculating days rented for device %s: %v", deviceID, err)
+		logger.Errorf("Error calculating days rented for device %s: %v", deviceID, err)
 		totalDaysRented = 0
 	}
 	
@@ -324,7 +469,7 @@ func (r *DeviceRepository) GetDeviceStats(deviceID string) (map[string]interface
 	var device models.Device
 	err = r.db.Where("deviceID = ?", deviceID).Preload("Product").First(&device).Error
 	if err != nil {
-		log.Printf("Error getting device details for %s: %v", deviceID, err)
+		logger.Errorf("Error getting device details for %s: %v", deviceID, err)
 	}
 	
 	var pricePerDay float64
@@ -371,7 +516,7 @@ func (r *DeviceRepository) generateDeviceID(device *models.Device) (string, erro
 	`, len(prefix)+1, prefix+"%").Scan(&maxNum).Error
 	
 	if err != nil {
-		log.Printf("❌ Error finding max device number for prefix %s: %v", prefix, err)
+		logger.Errorf("Error finding max device number for prefix %s: %v", prefix, err)
 		return "", fmt.Errorf("failed to find max device number: %v", err)
 	}
 	
@@ -379,7 +524,7 @@ func (r *DeviceRepository) generateDeviceID(device *models.Device) (string, erro
 	newNum := maxNum + 1
 	deviceID := fmt.Sprintf("%s%04d", prefix, newNum)
 	
-	log.Printf("✅ Generated device ID: %s (prefix: %s, next number: %d)", deviceID, prefix, newNum)
+	logger.Debugf("Generated device ID: %s (prefix: %s, next number: %d)", deviceID, prefix, newNum)
 	return deviceID, nil
 }
 
@@ -496,7 +641,7 @@ func (r *DeviceRepository) CountDevicesByStatus(status string) (int64, error) {
 func (r *DeviceRepository) CountDevicesAssignedToJobs(targetDate time.Time) (int64, error) {
 	var count int64
 	
-	fmt.Printf("🔍 DEBUG: CountDevicesAssignedToJobs called with targetDate: %s\n", targetDate.Format("2006-01-02"))
+	logger.Debugf("CountDevicesAssignedToJobs called with targetDate: %s", targetDate.Format("2006-01-02"))
 	
 	// CORRECTED: Use >= for endDate comparison
 	// This ensures devices are unavailable ON the end date and become available the day AFTER
@@ -505,37 +650,42 @@ func (r *DeviceRepository) CountDevicesAssignedToJobs(targetDate time.Time) (int
 		Where("j.startDate <= ? AND j.endDate >= ? AND j.statusID IN (SELECT statusID FROM status WHERE status IN ('open', 'in_progress'))", targetDate, targetDate).
 		Count(&count).Error
 	
-	fmt.Printf("🔍 DEBUG: Total devices assigned to jobs on %s: %d\n", targetDate.Format("2006-01-02"), count)
+	logger.Debugf("Total devices assigned to jobs on %s: %d", targetDate.Format("2006-01-02"), count)
 	
 	return count, err
 }
 
 // IsDeviceAvailableForJob checks if a device is available for a specific job's date range
 func (r *DeviceRepository) IsDeviceAvailableForJob(deviceID string, jobID uint, startDate, endDate *time.Time) (bool, *models.JobDevice, error) {
-	log.Printf("🔍 IsDeviceAvailableForJob: Checking device %s for job %d", deviceID, jobID)
+	logger.Debugf("IsDeviceAvailableForJob: Checking device %s for job %d", deviceID, jobID)
 
 	if startDate != nil && endDate != nil {
-		log.Printf("🔍 IsDeviceAvailableForJob: Date range: %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+		logger.Debugf("IsDeviceAvailableForJob: Date range: %s to %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 	} else {
-		log.Printf("🔍 IsDeviceAvailableForJob: No dates specified")
+		logger.Debugf("IsDeviceAvailableForJob: No dates specified")
 	}
 
 	// First, check if device exists at all
 	var deviceExists models.Device
 	err := r.db.Where("deviceID = ?", deviceID).First(&deviceExists).Error
 	if err != nil {
-		log.Printf("❌ IsDeviceAvailableForJob: Device %s does not exist: %v", deviceID, err)
+		logger.Errorf("IsDeviceAvailableForJob: Device %s does not exist: %v", deviceID, err)
 		return false, nil, fmt.Errorf("device %s not found: %v", deviceID, err)
 	}
-	log.Printf("✅ IsDeviceAvailableForJob: Device %s exists with status: %s, productID: %v", deviceExists.DeviceID, deviceExists.Status, deviceExists.ProductID)
+	logger.Debugf("IsDeviceAvailableForJob: Device %s exists with status: %s, productID: %v", deviceExists.DeviceID, deviceExists.Status, deviceExists.ProductID)
+
+	consumable, err := isConsumableDevice(r.db, deviceID)
+	if err != nil {
+		return false, nil, fmt.Errorf("database error checking device availability: %v", err)
+	}
 
 	// If no dates specified, use basic availability check
 	if startDate == nil || endDate == nil {
-		log.Printf("🔍 IsDeviceAvailableForJob: Using basic availability check (no dates)")
+		logger.Debugf("IsDeviceAvailableForJob: Using basic availability check (no dates)")
 
 		// Check if device has 'free' status
 		if deviceExists.Status != "free" {
-			log.Printf("❌ IsDeviceAvailableForJob: Device %s status is %s (not free)", deviceID, deviceExists.Status)
+			logger.Debugf("IsDeviceAvailableForJob: Device %s status is %s (not free)", deviceID, deviceExists.Status)
 			return false, nil, fmt.Errorf("device %s is not available (status: %s)", deviceID, deviceExists.Status)
 		}
 
@@ -543,10 +693,16 @@ func (r *DeviceRepository) IsDeviceAvailableForJob(deviceID string, jobID uint,
 		var existingAssignment models.JobDevice
 		err = r.db.Where("deviceID = ? AND jobID = ?", deviceID, jobID).First(&existingAssignment).Error
 		if err == nil {
-			log.Printf("⚠️ IsDeviceAvailableForJob: Device %s already assigned to job %d", deviceID, jobID)
+			logger.Debugf("IsDeviceAvailableForJob: Device %s already assigned to job %d", deviceID, jobID)
 			return false, &existingAssignment, nil // Already assigned to this job
 		}
 
+		// Consumables are not tracked per unit and never conflict
+		if consumable {
+			logger.Debugf("IsDeviceAvailableForJob: Device %s is consumable, skipping conflict check", deviceID)
+			return true, nil, nil
+		}
+
 		// Check if assigned to any other active job
 		var anyActiveAssignment models.JobDevice
 		err = r.db.Joins("JOIN jobs ON jobdevices.jobID = jobs.jobID").
@@ -554,22 +710,28 @@ func (r *DeviceRepository) IsDeviceAvailableForJob(deviceID string, jobID uint,
 				SELECT statusID FROM status WHERE status IN ('open', 'in_progress')
 			)`, deviceID).First(&anyActiveAssignment).Error
 		if err == nil {
-			log.Printf("❌ IsDeviceAvailableForJob: Device %s assigned to active job %d", deviceID, anyActiveAssignment.JobID)
+			logger.Debugf("IsDeviceAvailableForJob: Device %s assigned to active job %d", deviceID, anyActiveAssignment.JobID)
 			return false, &anyActiveAssignment, nil // Assigned to another active job
 		}
 
-		log.Printf("✅ IsDeviceAvailableForJob: Device %s is available (basic check)", deviceID)
+		logger.Debugf("IsDeviceAvailableForJob: Device %s is available (basic check)", deviceID)
 		return true, nil, nil
 	}
 
 	// Check if device has 'free' status for date-specific check
 	if deviceExists.Status != "free" {
-		log.Printf("❌ IsDeviceAvailableForJob: Device %s status is %s (not free) for date range check", deviceID, deviceExists.Status)
+		logger.Debugf("IsDeviceAvailableForJob: Device %s status is %s (not free) for date range check", deviceID, deviceExists.Status)
 		return false, nil, fmt.Errorf("device %s is not available (status: %s)", deviceID, deviceExists.Status)
 	}
 
+	// Consumables are not tracked per unit and never conflict
+	if consumable {
+		logger.Debugf("IsDeviceAvailableForJob: Device %s is consumable, skipping conflict check", deviceID)
+		return true, nil, nil
+	}
+
 	// Check for overlapping job assignments
-	log.Printf("🔍 IsDeviceAvailableForJob: Checking for overlapping assignments...")
+	logger.Debugf("IsDeviceAvailableForJob: Checking for overlapping assignments...")
 	var conflictingJob models.JobDevice
 	err = r.db.Joins("JOIN jobs ON jobdevices.jobID = jobs.jobID").
 		Where(`jobdevices.deviceID = ?
@@ -586,20 +748,67 @@ func (r *DeviceRepository) IsDeviceAvailableForJob(deviceID string, jobID uint,
 		var job models.Job
 		r.db.Where("jobID = ?", conflictingJob.JobID).First(&job)
 		conflictingJob.Job = job
-		log.Printf("❌ IsDeviceAvailableForJob: Device %s has conflicting assignment to job %d (%s to %s)",
+		logger.Debugf("IsDeviceAvailableForJob: Device %s has conflicting assignment to job %d (%s to %s)",
 			deviceID, conflictingJob.JobID, job.StartDate, job.EndDate)
 		return false, &conflictingJob, nil
 	}
 
 	if err.Error() != "record not found" {
-		log.Printf("❌ IsDeviceAvailableForJob: Database error checking conflicts: %v", err)
+		logger.Errorf("IsDeviceAvailableForJob: Database error checking conflicts: %v", err)
 		return false, nil, fmt.Errorf("database error checking device availability: %v", err)
 	}
 
-	log.Printf("✅ IsDeviceAvailableForJob: Device %s is available for job %d", deviceID, jobID)
+	logger.Debugf("IsDeviceAvailableForJob: Device %s is available for job %d", deviceID, jobID)
 	return true, nil, nil
 }
 
+// likeEscaper escapes the LIKE wildcards in user input
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SearchByIDOrSerial returns up to limit devices whose device ID or serial
+// number starts with term, followed by those only containing it. Prefix
+// matches are looked up first so they can use the indexes; matching relies on
+// the columns' case-insensitive collation. A scanned QR payload is listed with
+// the prefix matches: the device storing that QR code, or for a default
+// QR-<deviceID> payload the device with that ID.
+func (r *DeviceRepository) SearchByIDOrSerial(term string, limit int) ([]models.Device, error) {
+	escaped := likeEscaper.Replace(term)
+
+	prefixMatch := "deviceID LIKE ? OR serialnumber LIKE ? OR qr_code = ?"
+	prefixArgs := []interface{}{escaped + "%", escaped + "%", term}
+	if deviceID, ok := strings.CutPrefix(term, models.DeviceQRCodePrefix); ok {
+		prefixMatch += " OR deviceID = ?"
+		prefixArgs = append(prefixArgs, deviceID)
+	}
+
+	var devices []models.Device
+	if err := r.db.Preload("Product").
+		Where(prefixMatch, prefixArgs...).
+		Order("deviceID ASC").
+		Limit(limit).
+		Find(&devices).Error; err != nil {
+		return nil, fmt.Errorf("failed to search devices: %v", err)
+	}
+	if len(devices) >= limit {
+		return devices, nil
+	}
+
+	query := r.db.Preload("Product").
+		Where("deviceID LIKE ? OR serialnumber LIKE ?", "%"+escaped+"%", "%"+escaped+"%")
+	if len(devices) > 0 {
+		found := make([]string, len(devices))
+		for i := range devices {
+			found[i] = devices[i].DeviceID
+		}
+		query = query.Where("deviceID NOT IN ?", found)
+	}
+	var contains []models.Device
+	if err := query.Order("deviceID ASC").Limit(limit - len(devices)).Find(&contains).Error; err != nil {
+		return nil, fmt.Errorf("failed to search devices: %v", err)
+	}
+	return append(devices, contains...), nil
+}
+
 // GetTotalCount returns the total number of devices
 func (r *DeviceRepository) GetTotalCount() (int, error) {
 	var count int64
@@ -616,8 +825,8 @@ func (r *DeviceRepository) GetAvailableDevicesForJob(jobID uint, startDate, endD
 		return r.GetAvailableDevices()
 	}
 	
-	// Get devices that are not assigned to overlapping jobs
-	err := r.db.Where(`status = 'free' AND deviceID NOT IN (
+	// Get devices that are not assigned to overlapping jobs (consumables are always available)
+	err := r.db.Where(`status = 'free' AND (deviceID NOT IN (
 		SELECT DISTINCT jd.deviceID 
 		FROM jobdevices jd
 		JOIN jobs j ON jd.jobID = j.jobID 
@@ -627,7 +836,7 @@ func (r *DeviceRepository) GetAvailableDevicesForJob(jobID uint, startDate, endD
 			AND j.statusID IN (
 				SELECT statusID FROM status WHERE status IN ('open', 'in_progress')
 			)
-	)`, jobID, endDate, startDate).Find(&devices).Error
+	) OR productID IN (SELECT productID FROM products WHERE is_consumable = TRUE))`, jobID, endDate, startDate).Find(&devices).Error
 	
 	return devices, err
 }
@@ -655,4 +864,271 @@ func (r *DeviceRepository) IsDeviceCurrentlyAssigned(deviceID string) (bool, *ui
 	}
 	
 	return true, &assignment.JobID, nil
-}
\ No newline at end of file
+}
+
+// RecordLabelPrints logs that labels were generated for the given devices
+func (r *DeviceRepository) RecordLabelPrints(deviceIDs []string, printedBy *uint, outputFormat, labelFormat string) error {
+	if len(deviceIDs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	prints := make([]models.DeviceLabelPrint, 0, len(deviceIDs))
+	for _, deviceID := range deviceIDs {
+		prints = append(prints, models.DeviceLabelPrint{
+			DeviceID:     deviceID,
+			PrintedBy:    printedBy,
+			OutputFormat: outputFormat,
+			LabelFormat:  labelFormat,
+			PrintedAt:    now,
+		})
+	}
+	return r.db.Create(&prints).Error
+}
+
+// GetLabelPrintSummary returns how often a device's label was printed and the latest print
+func (r *DeviceRepository) GetLabelPrintSummary(deviceID string) (*models.DeviceLabelPrintSummary, error) {
+	summary := &models.DeviceLabelPrintSummary{}
+	if err := r.db.Model(&models.DeviceLabelPrint{}).Where("device_id = ?", deviceID).Count(&summary.Count).Error; err != nil {
+		return nil, err
+	}
+	if summary.Count == 0 {
+		return summary, nil
+	}
+	var last models.DeviceLabelPrint
+	if err := r.db.Preload("Printer").Where("device_id = ?", deviceID).
+		Order("printed_at DESC, print_id DESC").First(&last).Error; err != nil {
+		return nil, err
+	}
+	summary.LastPrint = &last
+	return summary, nil
+}
+
+// ListLabelPrints returns a device's label prints, newest first
+func (r *DeviceRepository) ListLabelPrints(deviceID string, limit int) ([]models.DeviceLabelPrint, error) {
+	var prints []models.DeviceLabelPrint
+	query := r.db.Preload("Printer").Where("device_id = ?", deviceID).Order("printed_at DESC, print_id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&prints).Error
+	return prints, err
+}
+
+// UpdateLocation moves a device to a new location and records the move. The
+// coordinates are replaced too, so a move without GPS clears the old fix.
+func (r *DeviceRepository) UpdateLocation(deviceID, location string, latitude, longitude *float64, movedBy *uint, notes string) (*models.DeviceLocationLog, error) {
+	var entry *models.DeviceLocationLog
+	err := r.db.WithTransaction(func(tx *Database) error {
+		var device models.Device
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deviceID = ?", deviceID).First(&device).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Device{}).Where("deviceID = ?", deviceID).Updates(map[string]interface{}{
+			"current_location": location,
+			"gps_latitude":     latitude,
+			"gps_longitude":    longitude,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update device location: %v", err)
+		}
+
+		entry = &models.DeviceLocationLog{
+			DeviceID:         deviceID,
+			Location:         location,
+			PreviousLocation: device.CurrentLocation,
+			GPSLatitude:      latitude,
+			GPSLongitude:     longitude,
+			MovedBy:          movedBy,
+			Notes:            notes,
+			MovedAt:          time.Now(),
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			return fmt.Errorf("failed to record device location: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// UpdateCondition stores a new condition rating on the device and records it
+func (r *DeviceRepository) UpdateCondition(deviceID string, rating float64, ratedBy *uint, notes string) (*models.DeviceConditionLog, error) {
+	var entry *models.DeviceConditionLog
+	err := r.db.WithTransaction(func(tx *Database) error {
+		var device models.Device
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deviceID = ?", deviceID).First(&device).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Device{}).Where("deviceID = ?", deviceID).
+			Update("condition_rating", rating).Error; err != nil {
+			return fmt.Errorf("failed to update device condition: %v", err)
+		}
+
+		entry = &models.DeviceConditionLog{
+			DeviceID:       deviceID,
+			Rating:         rating,
+			PreviousRating: device.ConditionRating,
+			Notes:          notes,
+			RatedBy:        ratedBy,
+			RatedAt:        time.Now(),
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			return fmt.Errorf("failed to record device condition: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ListConditionHistory returns a device's condition ratings, oldest first so
+// they can be charted as given
+func (r *DeviceRepository) ListConditionHistory(deviceID string) ([]models.DeviceConditionLog, error) {
+	var ratings []models.DeviceConditionLog
+	err := r.db.Preload("Rater").Where("device_id = ?", deviceID).
+		Order("rated_at ASC, log_id ASC").Find(&ratings).Error
+	return ratings, err
+}
+
+// GetLatestCondition returns a device's most recent condition rating, or nil
+// if it was never rated
+func (r *DeviceRepository) GetLatestCondition(deviceID string) (*models.DeviceConditionLog, error) {
+	var latest models.DeviceConditionLog
+	err := r.db.Preload("Rater").Where("device_id = ?", deviceID).
+		Order("rated_at DESC, log_id DESC").First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &latest, nil
+}
+
+// ListLocationHistory returns a device's moves, newest first
+func (r *DeviceRepository) ListLocationHistory(deviceID string, limit int) ([]models.DeviceLocationLog, error) {
+	var moves []models.DeviceLocationLog
+	query := r.db.Preload("Mover").Where("device_id = ?", deviceID).Order("moved_at DESC, log_id DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&moves).Error
+	return moves, err
+}
+
+// GetMaintenanceDue returns devices in maintenance or whose next maintenance
+// falls on or before the given day, most overdue first, and how many there
+// are in total. A limit of 0 returns all of them.
+func (r *DeviceRepository) GetMaintenanceDue(dueBy time.Time, limit int) ([]models.Device, int64, error) {
+	query := r.db.Model(&models.Device{}).
+		Where("status IN ? OR nextmaintenance <= ?", models.DeviceMaintenanceStatuses, dueBy.Format("2006-01-02"))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count devices due for maintenance: %v", err)
+	}
+
+	var devices []models.Device
+	query = query.Preload("Product").Order("nextmaintenance IS NULL, nextmaintenance ASC, deviceID ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&devices).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get devices due for maintenance: %v", err)
+	}
+	return devices, total, nil
+}
+
+// GetServiceOverdue returns devices never serviced or last serviced before the
+// given day, never-serviced devices first and then the longest unserviced
+func (r *DeviceRepository) GetServiceOverdue(serviceBefore time.Time) ([]models.Device, error) {
+	var devices []models.Device
+	err := r.db.Preload("Product").
+		Where("lastmaintenance IS NULL OR lastmaintenance < ?", serviceBefore.Format("2006-01-02")).
+		Order("lastmaintenance IS NOT NULL, lastmaintenance ASC, deviceID ASC").
+		Find(&devices).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devices overdue for service: %v", err)
+	}
+	return devices, nil
+}
+
+// BulkUpdateStatus sets the status of the given devices in one transaction and
+// logs an equipment usage entry for each device that changed. Unknown devices
+// are reported as failed without affecting the others; an error is only
+// returned when the transaction itself fails.
+func (r *DeviceRepository) BulkUpdateStatus(deviceIDs []string, status, notes string) ([]models.BulkDeviceStatusResult, error) {
+	results := make([]models.BulkDeviceStatusResult, 0, len(deviceIDs))
+	err := r.db.WithTransaction(func(tx *Database) error {
+		results = results[:0]
+
+		var devices []models.Device
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("deviceID", "status").
+			Where("deviceID IN ?", deviceIDs).
+			Find(&devices).Error; err != nil {
+			return fmt.Errorf("failed to load devices: %v", err)
+		}
+		current := make(map[string]string, len(devices))
+		for _, device := range devices {
+			current[device.DeviceID] = device.Status
+		}
+
+		seen := make(map[string]bool, len(deviceIDs))
+		var changed []string
+		for _, deviceID := range deviceIDs {
+			if seen[deviceID] {
+				continue
+			}
+			seen[deviceID] = true
+
+			previous, ok := current[deviceID]
+			if !ok {
+				results = append(results, models.BulkDeviceStatusResult{DeviceID: deviceID, Error: "device not found"})
+				continue
+			}
+			results = append(results, models.BulkDeviceStatusResult{DeviceID: deviceID, Success: true, PreviousStatus: previous})
+			if previous != status {
+				changed = append(changed, deviceID)
+			}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.Device{}).Where("deviceID IN ?", changed).
+			Update("status", status).Error; err != nil {
+			return fmt.Errorf("failed to update device status: %v", err)
+		}
+
+		now := time.Now()
+		logs := make([]models.EquipmentUsageLog, 0, len(changed))
+		for _, deviceID := range changed {
+			note := fmt.Sprintf("Bulk status change: %s -> %s", current[deviceID], status)
+			if notes != "" {
+				note += ": " + notes
+			}
+			logs = append(logs, models.EquipmentUsageLog{
+				DeviceID:  deviceID,
+				Action:    models.DeviceStatusUsageAction(status),
+				Timestamp: now,
+				Notes:     note,
+			})
+		}
+		if err := tx.Create(&logs).Error; err != nil {
+			return fmt.Errorf("failed to write usage logs: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
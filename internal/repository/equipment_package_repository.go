@@ -3,13 +3,16 @@ package repository
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type EquipmentPackageRepository struct {
@@ -20,19 +23,32 @@ func NewEquipmentPackageRepository(db *Database) *EquipmentPackageRepository {
 	return &EquipmentPackageRepository{db: db}
 }
 
+// GetDB returns the underlying database connection
+func (r *EquipmentPackageRepository) GetDB() *Database {
+	return r.db
+}
+
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *EquipmentPackageRepository) WithTx(tx *Database) *EquipmentPackageRepository {
+	return &EquipmentPackageRepository{db: tx}
+}
+
 // List returns all equipment packages with optional filtering
 func (r *EquipmentPackageRepository) List(params *models.FilterParams) ([]models.EquipmentPackage, error) {
-	log.Printf("📊 PACKAGE LIST: Starting List method with params: %+v", params)
+	logger.Debugf("PACKAGE LIST: Starting List method with params: %+v", params)
 	
 	// Log call stack to identify which handler is calling this method
 	if pc, file, line, ok := runtime.Caller(1); ok {
 		funcName := runtime.FuncForPC(pc).Name()
-		log.Printf("🔍 CALL STACK: List method called from: %s:%d (%s)", file, line, funcName)
+		logger.Debugf("CALL STACK: List method called from: %s:%d (%s)", file, line, funcName)
 	}
 	
 	var packages []models.EquipmentPackage
 	
 	query := r.db.DB.Model(&models.EquipmentPackage{})
+	if params == nil || !params.IncludeArchived {
+		query = query.Where("is_archived = ?", false)
+	}
 	
 	// Apply filters
 	if params != nil {
@@ -69,11 +85,11 @@ func (r *EquipmentPackageRepository) List(params *models.FilterParams) ([]models
 		var deviceCount int64
 		
 		if err := r.db.DB.Table("package_devices").Where("packageID = ?", packages[i].PackageID).Count(&deviceCount).Error; err != nil {
-			log.Printf("Failed to count devices for package %d: %v", packages[i].PackageID, err)
+			logger.Errorf("Failed to count devices for package %d: %v", packages[i].PackageID, err)
 			deviceCount = 0
 		}
 		
-		log.Printf("📊 PACKAGE COUNT: Package %d ('%s') has %d devices", 
+		logger.Debugf("PACKAGE COUNT: Package %d ('%s') has %d devices", 
 			packages[i].PackageID, packages[i].Name, deviceCount)
 		
 		packages[i].DeviceCount = int(deviceCount)
@@ -84,6 +100,71 @@ func (r *EquipmentPackageRepository) List(params *models.FilterParams) ([]models
 	return packages, nil
 }
 
+// ListPage returns one page of the packages matching the filters, selected by
+// Limit and Offset, and the total number of matches. The devices of the page's
+// packages are loaded with their products so list prices can be computed.
+func (r *EquipmentPackageRepository) ListPage(params *models.FilterParams) ([]models.EquipmentPackage, int64, error) {
+	total, err := r.GetTotalCount(params)
+	if err != nil {
+		return nil, 0, err
+	}
+	packages, err := r.List(params)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := r.loadPackageDevices(packages); err != nil {
+		return nil, 0, err
+	}
+	return packages, total, nil
+}
+
+// loadPackageDevices fills PackageDevices of the given packages, with device
+// and product, using one query per table
+func (r *EquipmentPackageRepository) loadPackageDevices(packages []models.EquipmentPackage) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	packageIDs := make([]uint, len(packages))
+	for i := range packages {
+		packageIDs[i] = packages[i].PackageID
+	}
+
+	var packageDevices []models.PackageDevice
+	if err := r.db.DB.Where("packageID IN ?", packageIDs).
+		Order("sort_order IS NULL, sort_order ASC, deviceID ASC").
+		Find(&packageDevices).Error; err != nil {
+		return fmt.Errorf("failed to load package devices: %v", err)
+	}
+
+	deviceIDs := make([]string, 0, len(packageDevices))
+	for _, mapping := range packageDevices {
+		deviceIDs = append(deviceIDs, mapping.DeviceID)
+	}
+	devices := make(map[string]*models.Device, len(deviceIDs))
+	if len(deviceIDs) > 0 {
+		var found []models.Device
+		if err := r.db.DB.Preload("Product").Where("deviceID IN ?", deviceIDs).Find(&found).Error; err != nil {
+			return fmt.Errorf("failed to load package device details: %v", err)
+		}
+		for i := range found {
+			devices[found[i].DeviceID] = &found[i]
+		}
+	}
+
+	byPackage := make(map[uint][]models.PackageDevice, len(packages))
+	for _, mapping := range packageDevices {
+		mapping.Device = devices[mapping.DeviceID]
+		byPackage[mapping.PackageID] = append(byPackage[mapping.PackageID], mapping)
+	}
+	for i := range packages {
+		packages[i].PackageDevices = byPackage[packages[i].PackageID]
+		if packages[i].PackageDevices == nil {
+			packages[i].PackageDevices = []models.PackageDevice{}
+		}
+	}
+	return nil
+}
+
 // GetByID returns a specific equipment package by ID
 func (r *EquipmentPackageRepository) GetByID(id uint) (*models.EquipmentPackage, error) {
 	var pkg models.EquipmentPackage
@@ -98,7 +179,7 @@ func (r *EquipmentPackageRepository) GetByID(id uint) (*models.EquipmentPackage,
 	// Manually load package devices without preloading device details
 	var packageDevices []models.PackageDevice
 	if err := r.db.DB.Where("packageID = ?", id).Find(&packageDevices).Error; err != nil {
-		log.Printf("Warning: Failed to load package devices for package %d: %v", id, err)
+		logger.Warnf("Failed to load package devices for package %d: %v", id, err)
 	}
 	
 	pkg.PackageDevices = packageDevices
@@ -151,11 +232,51 @@ func (r *EquipmentPackageRepository) Delete(id uint) error {
 	return nil
 }
 
+// Archive deactivates a package and hides it from package lists while keeping
+// it and its devices for the jobs that used it
+func (r *EquipmentPackageRepository) Archive(id uint) error {
+	result := r.db.DB.Model(&models.EquipmentPackage{}).
+		Where("packageID = ?", id).
+		Updates(map[string]interface{}{
+			"is_active":   false,
+			"is_archived": true,
+			"archived_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to archive equipment package: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Restore brings an archived package back into the package lists. It stays
+// inactive until it is activated again.
+func (r *EquipmentPackageRepository) Restore(id uint) error {
+	result := r.db.DB.Model(&models.EquipmentPackage{}).
+		Where("packageID = ? AND is_archived = ?", id, true).
+		Updates(map[string]interface{}{
+			"is_archived": false,
+			"archived_at": nil,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to restore equipment package: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 // GetTotalCount returns the total count of equipment packages
 func (r *EquipmentPackageRepository) GetTotalCount(params *models.FilterParams) (int64, error) {
 	var count int64
 	
 	query := r.db.DB.Model(&models.EquipmentPackage{})
+	if params == nil || !params.IncludeArchived {
+		query = query.Where("is_archived = ?", false)
+	}
 	
 	// Apply same filters as List for consistent counting
 	if params != nil {
@@ -204,7 +325,7 @@ func (r *EquipmentPackageRepository) GetWithDevices(id uint) (*models.EquipmentP
 	// Manually load package devices without preloading device details
 	var packageDevices []models.PackageDevice
 	if err := r.db.DB.Where("packageID = ?", id).Find(&packageDevices).Error; err != nil {
-		log.Printf("Warning: Failed to load package devices for package %d: %v", id, err)
+		logger.Warnf("Failed to load package devices for package %d: %v", id, err)
 	}
 	
 	pkg.PackageDevices = packageDevices
@@ -212,6 +333,28 @@ func (r *EquipmentPackageRepository) GetWithDevices(id uint) (*models.EquipmentP
 	return &pkg, nil
 }
 
+// GetByKitCode resolves a scanned kit code ("PKG-12", "PKG12" or "12") to an
+// active package with its devices
+func (r *EquipmentPackageRepository) GetByKitCode(code string) (*models.EquipmentPackage, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(code))
+	normalized = strings.TrimPrefix(strings.TrimPrefix(normalized, models.KitCodePrefix), "-")
+
+	id, err := strconv.ParseUint(normalized, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kit code %q", code)
+	}
+
+	pkg, err := r.GetWithDevices(uint(id))
+	if err != nil {
+		return nil, err
+	}
+	if !pkg.IsActive {
+		return nil, fmt.Errorf("equipment package %s is inactive", pkg.Name)
+	}
+
+	return pkg, nil
+}
+
 // CreateWithDevices creates a package and associates devices with it
 func (r *EquipmentPackageRepository) CreateWithDevices(pkg *models.EquipmentPackage, deviceMappings []models.PackageDevice) error {
 	return r.db.DB.Transaction(func(tx *gorm.DB) error {
@@ -251,16 +394,50 @@ func (r *EquipmentPackageRepository) CreateWithDevices(pkg *models.EquipmentPack
 	})
 }
 
-// UpdateDeviceAssociations updates the devices associated with a package
+// DuplicatePackageDeviceError reports devices submitted more than once for a package
+type DuplicatePackageDeviceError struct {
+	DeviceIDs []string
+}
+
+func (e *DuplicatePackageDeviceError) Error() string {
+	return fmt.Sprintf("device(s) listed more than once: %s", strings.Join(e.DeviceIDs, ", "))
+}
+
+// UpdateDeviceAssociations replaces the devices associated with a package. The
+// package row is locked for the duration of the transaction so concurrent edits
+// of the same package are applied one after the other instead of interleaving.
+// Submitting the current device set again leaves the package untouched.
 func (r *EquipmentPackageRepository) UpdateDeviceAssociations(packageID uint, deviceMappings []models.PackageDevice) error {
+	seen := make(map[string]bool, len(deviceMappings))
+	var duplicates []string
+	for _, mapping := range deviceMappings {
+		if seen[mapping.DeviceID] && !containsDeviceID(duplicates, mapping.DeviceID) {
+			duplicates = append(duplicates, mapping.DeviceID)
+		}
+		seen[mapping.DeviceID] = true
+	}
+	if len(duplicates) > 0 {
+		return &DuplicatePackageDeviceError{DeviceIDs: duplicates}
+	}
+
 	return r.db.DB.Transaction(func(tx *gorm.DB) error {
-		// Delete existing associations using raw SQL to prevent cascading deletes
-		log.Printf("🔄 PACKAGE UPDATE: Deleting existing device associations for package %d", packageID)
-		if err := tx.Exec("DELETE FROM package_devices WHERE packageID = ?", packageID).Error; err != nil {
-			return fmt.Errorf("failed to delete existing device associations: %v", err)
+		// Serialize concurrent updates of this package
+		var pkg models.EquipmentPackage
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("packageID").
+			First(&pkg, packageID).Error; err != nil {
+			return fmt.Errorf("failed to lock package %d: %v", packageID, err)
 		}
-		log.Printf("✅ PACKAGE UPDATE: Successfully deleted existing device associations for package %d", packageID)
-		
+
+		var existing []models.PackageDevice
+		if err := tx.Raw("SELECT * FROM package_devices WHERE packageID = ?", packageID).Scan(&existing).Error; err != nil {
+			return fmt.Errorf("failed to load existing device associations: %v", err)
+		}
+		existingByDevice := make(map[string]models.PackageDevice, len(existing))
+		for _, mapping := range existing {
+			existingByDevice[mapping.DeviceID] = mapping
+		}
+
 		// Validate and filter device mappings to only include existing devices
 		var validMappings []models.PackageDevice
 		now := time.Now()
@@ -269,47 +446,113 @@ func (r *EquipmentPackageRepository) UpdateDeviceAssociations(packageID uint, de
 			// Check if device exists
 			var deviceExists bool
 			if err := tx.Raw("SELECT EXISTS(SELECT 1 FROM devices WHERE deviceID = ?)", mapping.DeviceID).Scan(&deviceExists).Error; err != nil {
-				log.Printf("❌ PACKAGE UPDATE: Failed to check device %s existence: %v", mapping.DeviceID, err)
+				logger.Errorf("PACKAGE UPDATE: Failed to check device %s existence: %v", mapping.DeviceID, err)
 				continue
 			}
 			
 			if !deviceExists {
-				log.Printf("❌ PACKAGE UPDATE: Device %s does not exist - skipping association", mapping.DeviceID)
+				logger.Warnf("PACKAGE UPDATE: Device %s does not exist - skipping association", mapping.DeviceID)
 				continue
 			}
 			
-			// Device exists, add to valid mappings
+			// Device exists, add to valid mappings keeping when it joined the package
 			mapping.PackageID = packageID
 			mapping.CreatedAt = now
+			if previous, ok := existingByDevice[mapping.DeviceID]; ok {
+				mapping.CreatedAt = previous.CreatedAt
+			}
 			mapping.UpdatedAt = now
 			validMappings = append(validMappings, mapping)
-			log.Printf("✅ PACKAGE UPDATE: Device %s validated and added to mappings", mapping.DeviceID)
 		}
-		
-		// Create new associations only for valid devices
-		if len(validMappings) > 0 {
-			log.Printf("🔄 PACKAGE UPDATE: Creating %d validated device associations for package %d", len(validMappings), packageID)
-			
-			// Use raw SQL to prevent GORM from auto-creating devices
-			for _, mapping := range validMappings {
-				log.Printf("🔄 PACKAGE UPDATE: Creating association for device %s", mapping.DeviceID)
-				if err := tx.Exec(`
-					INSERT INTO package_devices (packageID, deviceID, quantity, custom_price, is_required, notes, sort_order, created_at, updated_at)
-					VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
-				`, mapping.PackageID, mapping.DeviceID, mapping.Quantity, mapping.CustomPrice, mapping.IsRequired, mapping.Notes, mapping.SortOrder, mapping.CreatedAt, mapping.UpdatedAt).Error; err != nil {
-					log.Printf("❌ PACKAGE UPDATE: Failed to create association for device %s: %v", mapping.DeviceID, err)
-					return fmt.Errorf("failed to create new device association for device %s: %v", mapping.DeviceID, err)
-				}
+
+		if samePackageDevices(existingByDevice, validMappings) {
+			logger.Debugf("PACKAGE UPDATE: Device associations of package %d unchanged", packageID)
+			return nil
+		}
+
+		// Delete existing associations using raw SQL to prevent cascading deletes
+		if err := tx.Exec("DELETE FROM package_devices WHERE packageID = ?", packageID).Error; err != nil {
+			return fmt.Errorf("failed to delete existing device associations: %v", err)
+		}
+
+		// Use raw SQL to prevent GORM from auto-creating devices
+		for _, mapping := range validMappings {
+			if err := tx.Exec(`
+				INSERT INTO package_devices (packageID, deviceID, quantity, custom_price, is_required, notes, sort_order, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, mapping.PackageID, mapping.DeviceID, mapping.Quantity, mapping.CustomPrice, mapping.IsRequired, mapping.Notes, mapping.SortOrder, mapping.CreatedAt, mapping.UpdatedAt).Error; err != nil {
+				logger.Errorf("PACKAGE UPDATE: Failed to create association for device %s: %v", mapping.DeviceID, err)
+				return fmt.Errorf("failed to create new device association for device %s: %v", mapping.DeviceID, err)
 			}
-			log.Printf("✅ PACKAGE UPDATE: Successfully created %d device associations for package %d", len(validMappings), packageID)
-		} else {
-			log.Printf("✅ PACKAGE UPDATE: No valid device associations to create for package %d", packageID)
 		}
+		logger.Debugf("PACKAGE UPDATE: Replaced device associations of package %d with %d device(s)", packageID, len(validMappings))
 		
 		return nil
 	})
 }
 
+// samePackageDevices reports whether the submitted mappings match the stored ones exactly
+func samePackageDevices(existing map[string]models.PackageDevice, mappings []models.PackageDevice) bool {
+	if len(existing) != len(mappings) {
+		return false
+	}
+	for _, mapping := range mappings {
+		previous, ok := existing[mapping.DeviceID]
+		if !ok ||
+			previous.Quantity != mapping.Quantity ||
+			previous.IsRequired != mapping.IsRequired ||
+			previous.Notes != mapping.Notes ||
+			!equalFloatPtr(previous.CustomPrice, mapping.CustomPrice) ||
+			!equalUintPtr(previous.SortOrder, mapping.SortOrder) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalFloatPtr(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalUintPtr(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func containsDeviceID(deviceIDs []string, deviceID string) bool {
+	for _, id := range deviceIDs {
+		if id == deviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// FindMissingDevices returns the given device IDs that don't exist, in the
+// order they were passed
+func (r *EquipmentPackageRepository) FindMissingDevices(deviceIDs []string) ([]string, error) {
+	if len(deviceIDs) == 0 {
+		return nil, nil
+	}
+	var existing []string
+	if err := r.db.DB.Model(&models.Device{}).Where("deviceID IN ?", deviceIDs).
+		Pluck("deviceID", &existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up devices: %v", err)
+	}
+	var missing []string
+	for _, deviceID := range deviceIDs {
+		if !containsDeviceID(existing, deviceID) && !containsDeviceID(missing, deviceID) {
+			missing = append(missing, deviceID)
+		}
+	}
+	return missing, nil
+}
+
 // GetAvailableDevices returns devices that can be added to packages
 func (r *EquipmentPackageRepository) GetAvailableDevices() ([]models.Device, error) {
 	var devices []models.Device
@@ -469,6 +712,9 @@ func (r *EquipmentPackageRepository) Search(query string, params *models.FilterP
 	dbQuery := r.db.DB.Model(&models.EquipmentPackage{}).
 		Where("name LIKE ? OR description LIKE ? OR category LIKE ? OR tags LIKE ?",
 			"%"+query+"%", "%"+query+"%", "%"+query+"%", "%"+query+"%")
+	if params == nil || !params.IncludeArchived {
+		dbQuery = dbQuery.Where("is_archived = ?", false)
+	}
 	
 	// Apply additional filters
 	if params != nil {
@@ -512,7 +758,7 @@ func (r *EquipmentPackageRepository) GetByIDWithoutDevicePreload(id uint) (*mode
 	// Manually load package devices without preloading the actual device records
 	var packageDevices []models.PackageDevice
 	if err := r.db.DB.Where("packageID = ?", id).Find(&packageDevices).Error; err != nil {
-		log.Printf("Warning: Failed to load package devices for package %d: %v", id, err)
+		logger.Warnf("Failed to load package devices for package %d: %v", id, err)
 	}
 	
 	// Only attach the package devices without device preloading
@@ -535,14 +781,14 @@ func (r *EquipmentPackageRepository) GetByIDWithDeviceDetails(id uint) (*models.
 	// Manually load package devices
 	var packageDevices []models.PackageDevice
 	if err := r.db.DB.Where("packageID = ?", id).Find(&packageDevices).Error; err != nil {
-		log.Printf("Warning: Failed to load package devices for package %d: %v", id, err)
+		logger.Warnf("Failed to load package devices for package %d: %v", id, err)
 	}
 	
 	// Manually load device details for each package device
 	for i := range packageDevices {
 		var device models.Device
 		if err := r.db.DB.Preload("Product").Where("deviceID = ?", packageDevices[i].DeviceID).First(&device).Error; err != nil {
-			log.Printf("Warning: Failed to load device %s for package %d: %v", packageDevices[i].DeviceID, id, err)
+			logger.Warnf("Failed to load device %s for package %d: %v", packageDevices[i].DeviceID, id, err)
 			continue
 		}
 		packageDevices[i].Device = &device
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"time"
+
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/models"
+)
+
+type HolidayRepository struct {
+	db         *Database
+	jobsConfig *config.JobsConfig
+}
+
+func NewHolidayRepository(db *Database, jobsConfig *config.JobsConfig) *HolidayRepository {
+	return &HolidayRepository{db: db, jobsConfig: jobsConfig}
+}
+
+// RentalDayCounting returns how rental days are counted, calendar days unless
+// the jobs settings say otherwise
+func (r *HolidayRepository) RentalDayCounting() string {
+	if r.jobsConfig == nil || r.jobsConfig.RentalDayCounting == "" {
+		return models.RentalDayCountingCalendar
+	}
+	return r.jobsConfig.RentalDayCounting
+}
+
+func (r *HolidayRepository) Create(holiday *models.CompanyHoliday) error {
+	return r.db.Create(holiday).Error
+}
+
+func (r *HolidayRepository) GetByID(id uint) (*models.CompanyHoliday, error) {
+	var holiday models.CompanyHoliday
+	if err := r.db.First(&holiday, id).Error; err != nil {
+		return nil, err
+	}
+	return &holiday, nil
+}
+
+// List returns the holidays of a year in date order, or all holidays for year 0
+func (r *HolidayRepository) List(year int) ([]models.CompanyHoliday, error) {
+	var holidays []models.CompanyHoliday
+	query := r.db.Order("holiday_date ASC")
+	if year > 0 {
+		query = query.Where("YEAR(holiday_date) = ?", year)
+	}
+	err := query.Find(&holidays).Error
+	return holidays, err
+}
+
+func (r *HolidayRepository) Update(holiday *models.CompanyHoliday) error {
+	return r.db.Save(holiday).Error
+}
+
+func (r *HolidayRepository) Delete(id uint) error {
+	return r.db.Delete(&models.CompanyHoliday{}, id).Error
+}
+
+// DateExists reports whether another holiday already falls on the date
+func (r *HolidayRepository) DateExists(date time.Time, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.CompanyHoliday{}).
+		Where("holiday_date = ? AND holiday_id <> ?", date.Format("2006-01-02"), excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// HolidaysBetween returns the holiday dates from start to end, keyed YYYY-MM-DD
+func (r *HolidayRepository) HolidaysBetween(start, end time.Time) (map[string]bool, error) {
+	var dates []time.Time
+	if err := r.db.Model(&models.CompanyHoliday{}).
+		Where("holiday_date BETWEEN ? AND ?", start.Format("2006-01-02"), end.Format("2006-01-02")).
+		Pluck("holiday_date", &dates).Error; err != nil {
+		return nil, err
+	}
+	holidays := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		holidays[date.Format("2006-01-02")] = true
+	}
+	return holidays, nil
+}
+
+// RentalDays counts the rental days from start to end in the configured
+// counting mode. Holidays are only looked up when counting business days.
+func (r *HolidayRepository) RentalDays(start, end time.Time) (int, error) {
+	mode := r.RentalDayCounting()
+	var holidays map[string]bool
+	if mode == models.RentalDayCountingBusinessDays {
+		var err error
+		if holidays, err = r.HolidaysBetween(start, end); err != nil {
+			return 0, err
+		}
+	}
+	return models.CountRentalDays(start, end, mode, holidays), nil
+}
@@ -2,22 +2,26 @@ package repository
 
 import (
 	"fmt"
-	"log"
+	"math"
 	"strconv"
 	"strings"
 	"time"
 
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type InvoiceRepositoryNew struct {
-	db *Database
+	db         *Database
+	jobsConfig *config.JobsConfig
 }
 
-func NewInvoiceRepositoryNew(db *Database) *InvoiceRepositoryNew {
-	return &InvoiceRepositoryNew{db: db}
+func NewInvoiceRepositoryNew(db *Database, jobsConfig *config.JobsConfig) *InvoiceRepositoryNew {
+	return &InvoiceRepositoryNew{db: db, jobsConfig: jobsConfig}
 }
 
 // GetDB returns the database instance for direct queries
@@ -31,72 +35,96 @@ func (r *InvoiceRepositoryNew) GetDB() *gorm.DB {
 
 // CreateInvoice creates a new invoice with proper validation
 func (r *InvoiceRepositoryNew) CreateInvoice(request *models.InvoiceCreateRequest) (*models.Invoice, error) {
-	// Validate request
-	if err := request.Validate(); err != nil {
-		return nil, fmt.Errorf("validation failed: %v", err)
+	if err := r.prepareInvoiceRequest(request); err != nil {
+		return nil, err
 	}
 
 	var invoice *models.Invoice
 	err := r.db.DB.Transaction(func(tx *gorm.DB) error {
-		// Generate invoice number
-		invoiceNumber, err := r.generateInvoiceNumber(tx)
-		if err != nil {
-			return fmt.Errorf("failed to generate invoice number: %v", err)
-		}
-
-		// Create invoice
-		invoice = &models.Invoice{
-			InvoiceNumber:   invoiceNumber,
-			CustomerID:      request.CustomerID,
-			JobID:           request.JobID,
-			TemplateID:      request.TemplateID,
-			Status:          "draft",
-			IssueDate:       request.IssueDate,
-			DueDate:         request.DueDate,
-			PaymentTerms:    request.PaymentTerms,
-			TaxRate:         request.TaxRate,
-			DiscountAmount:  request.DiscountAmount,
-			Notes:           request.Notes,
-			TermsConditions: request.TermsConditions,
-			CreatedAt:       time.Now(),
-			UpdatedAt:       time.Now(),
-		}
+		var err error
+		invoice, err = r.insertInvoice(tx, request)
+		return err
+	})
 
-		// Create line items
-		for i, itemRequest := range request.LineItems {
-			lineItem := models.InvoiceLineItem{
-				ItemType:        itemRequest.ItemType,
-				DeviceID:        itemRequest.DeviceID,
-				PackageID:       itemRequest.PackageID,
-				Description:     itemRequest.Description,
-				Quantity:        itemRequest.Quantity,
-				UnitPrice:       itemRequest.UnitPrice,
-				RentalStartDate: itemRequest.RentalStartDate,
-				RentalEndDate:   itemRequest.RentalEndDate,
-				RentalDays:      itemRequest.RentalDays,
-				SortOrder:       func() *uint { order := uint(i); return &order }(),
-				CreatedAt:       time.Now(),
-				UpdatedAt:       time.Now(),
-			}
-			lineItem.CalculateTotal()
-			invoice.LineItems = append(invoice.LineItems, lineItem)
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		// Calculate totals
-		invoice.CalculateTotals()
+	return r.loadCreatedInvoice(invoice)
+}
 
-		// Save to database
-		if err := tx.Create(invoice).Error; err != nil {
-			return fmt.Errorf("failed to create invoice: %v", err)
-		}
+// prepareInvoiceRequest validates a create request and fills in defaults and rental days
+func (r *InvoiceRepositoryNew) prepareInvoiceRequest(request *models.InvoiceCreateRequest) error {
+	// Validate request
+	if err := request.Validate(); err != nil {
+		return fmt.Errorf("validation failed: %v", err)
+	}
 
-		return nil
-	})
+	if err := r.applyInvoiceDefaults(request); err != nil {
+		return err
+	}
+	return r.fillRentalDays(request)
+}
 
+// insertInvoice numbers and stores a draft invoice within the given transaction
+func (r *InvoiceRepositoryNew) insertInvoice(tx *gorm.DB, request *models.InvoiceCreateRequest) (*models.Invoice, error) {
+	// Generate invoice number
+	invoiceNumber, err := r.generateInvoiceNumber(tx)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to generate invoice number: %v", err)
+	}
+
+	// Create invoice
+	invoice := &models.Invoice{
+		InvoiceNumber:   invoiceNumber,
+		CustomerID:      request.CustomerID,
+		JobID:           request.JobID,
+		TemplateID:      request.TemplateID,
+		Status:          "draft",
+		IssueDate:       request.IssueDate,
+		DueDate:         request.DueDate,
+		PaymentTerms:    request.PaymentTerms,
+		TaxRate:         request.TaxRate,
+		DiscountAmount:  request.DiscountAmount,
+		Notes:           request.Notes,
+		TermsConditions: request.TermsConditions,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	// Create line items
+	for i, itemRequest := range request.LineItems {
+		lineItem := models.InvoiceLineItem{
+			ItemType:        itemRequest.ItemType,
+			DeviceID:        itemRequest.DeviceID,
+			PackageID:       itemRequest.PackageID,
+			Description:     itemRequest.Description,
+			Quantity:        itemRequest.Quantity,
+			UnitPrice:       itemRequest.UnitPrice,
+			RentalStartDate: itemRequest.RentalStartDate,
+			RentalEndDate:   itemRequest.RentalEndDate,
+			RentalDays:      itemRequest.RentalDays,
+			SortOrder:       func() *uint { order := uint(i); return &order }(),
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+		}
+		lineItem.CalculateTotal()
+		invoice.LineItems = append(invoice.LineItems, lineItem)
+	}
+
+	// Calculate totals
+	invoice.CalculateTotals()
+
+	// Save to database
+	if err := tx.Create(invoice).Error; err != nil {
+		return nil, fmt.Errorf("failed to create invoice: %v", err)
 	}
 
+	return invoice, nil
+}
+
+// loadCreatedInvoice reloads a newly created invoice with its relationships
+func (r *InvoiceRepositoryNew) loadCreatedInvoice(invoice *models.Invoice) (*models.Invoice, error) {
 	// Load relationships for return
 	if err := r.db.DB.Preload("Customer").
 		Preload("Job").
@@ -106,15 +134,428 @@ func (r *InvoiceRepositoryNew) CreateInvoice(request *models.InvoiceCreateReques
 		return nil, fmt.Errorf("failed to load created invoice: %v", err)
 	}
 
-	log.Printf("Successfully created invoice %s with ID %d, CustomerID: %d", invoice.InvoiceNumber, invoice.InvoiceID, invoice.CustomerID)
+	logger.Debugf("Successfully created invoice %s with ID %d, CustomerID: %d", invoice.InvoiceNumber, invoice.InvoiceID, invoice.CustomerID)
 	if invoice.Customer != nil {
-		log.Printf("Loaded customer: ID=%d, Name=%s", invoice.Customer.CustomerID, invoice.Customer.GetDisplayName())
+		logger.Debugf("Loaded customer: ID=%d, Name=%s", invoice.Customer.CustomerID, invoice.Customer.GetDisplayName())
 	} else {
-		log.Printf("WARNING: Customer not loaded for CustomerID %d", invoice.CustomerID)
+		logger.Warnf("Customer not loaded for CustomerID %d", invoice.CustomerID)
 	}
 	return invoice, nil
 }
 
+// CreateFromJob creates a draft invoice for the devices assigned to the job. Devices of the
+// same product at the same rate share a line item when grouping by product, otherwise each
+// device gets its own; an empty grouping uses the line_item_grouping setting.
+// Terms, payment instructions and due date are taken from the invoice and company settings.
+func (r *InvoiceRepositoryNew) CreateFromJob(jobID uint, issueDate time.Time, grouping string) (*models.Invoice, error) {
+	return r.CreateFromJobDevices(jobID, nil, nil, nil, issueDate, grouping)
+}
+
+// InvoiceSelectionError reports devices or a period that cannot be invoiced for a job
+type InvoiceSelectionError struct {
+	Message string
+}
+
+func (e *InvoiceSelectionError) Error() string {
+	return e.Message
+}
+
+// BilledPeriodConflictError reports device-days that an earlier invoice of the job already billed
+type BilledPeriodConflictError struct {
+	Conflicts []models.JobBilledPeriod
+}
+
+func (e *BilledPeriodConflictError) Error() string {
+	first := e.Conflicts[0]
+	message := fmt.Sprintf("device %s was already billed from %s to %s on invoice %s",
+		first.DeviceID, first.PeriodStart.Format("2006-01-02"), first.PeriodEnd.Format("2006-01-02"), first.InvoiceNumber)
+	if len(e.Conflicts) > 1 {
+		message += fmt.Sprintf(" (and %d more)", len(e.Conflicts)-1)
+	}
+	return message
+}
+
+// CreateFromJobDevices creates a draft invoice for some of a job's devices over
+// part of its rental period. A nil deviceIDs selects every device and nil dates
+// default to the job's start and end. Device prices are rental prices for the
+// whole job, so a shorter period bills the share of its rental days. The billed
+// device-days are recorded, and device-days already on another invoice of the
+// job that isn't cancelled are rejected with a BilledPeriodConflictError.
+func (r *InvoiceRepositoryNew) CreateFromJobDevices(jobID uint, deviceIDs []string, periodStart, periodEnd *time.Time, issueDate time.Time, grouping string) (*models.Invoice, error) {
+	jobRepo := NewJobRepository(r.db, r.jobsConfig)
+
+	job, err := jobRepo.GetByID(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("job not found: %v", err)
+	}
+
+	jobDevices, err := jobRepo.GetJobDevices(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job devices: %v", err)
+	}
+	if len(jobDevices) == 0 {
+		return nil, fmt.Errorf("job %d has no devices assigned", jobID)
+	}
+
+	totalDevices := len(jobDevices)
+	if deviceIDs != nil {
+		jobDevices, err = selectJobDevices(jobDevices, deviceIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Billed periods can only be tracked for jobs with a rental period
+	tracked := job.StartDate != nil && job.EndDate != nil
+	if !tracked && (periodStart != nil || periodEnd != nil) {
+		return nil, &InvoiceSelectionError{Message: fmt.Sprintf("job %d has no rental period to invoice part of", jobID)}
+	}
+
+	rentalStart, rentalEnd := job.StartDate, job.EndDate
+	priceShare := 1.0
+	if tracked {
+		rentalStart, rentalEnd, priceShare, err = r.invoicePeriodShare(job, periodStart, periodEnd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	settings, err := r.GetAllInvoiceSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	// Seasonal pricing is keyed on the job's start date, as in job revenue
+	var seasonalRates []models.PricingCalendar
+	if job.StartDate != nil {
+		seasonalRates, err = NewPricingCalendarRepository(r.db).FindActiveForDate(*job.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load pricing calendar: %v", err)
+		}
+	}
+
+	request := &models.InvoiceCreateRequest{
+		CustomerID: job.CustomerID,
+		JobID:      &job.JobID,
+		IssueDate:  issueDate,
+		DueDate:    issueDate.AddDate(0, 0, settings.DefaultPaymentTerms),
+		TaxRate:    settings.DefaultTaxRate,
+	}
+
+	if grouping == "" {
+		grouping = settings.LineItemGrouping
+	}
+	if !models.IsValidLineItemGrouping(grouping) {
+		return nil, fmt.Errorf("invalid line item grouping %q (must be %q or %q)", grouping, models.LineItemGroupingProduct, models.LineItemGroupingDevice)
+	}
+
+	// Devices of the same product at the same rate share a line when grouping by product
+	type productLine struct {
+		productID uint
+		price     float64
+	}
+	productLines := make(map[productLine]int) // -> index in request.LineItems
+
+	var subtotal float64
+	for _, jd := range jobDevices {
+		deviceID := jd.DeviceID
+		var price float64
+		if jd.Device.Product != nil {
+			if jd.Device.Product.ItemCostPerDay != nil {
+				price = *jd.Device.Product.ItemCostPerDay
+			}
+			price = models.ResolveSeasonalRate(seasonalRates, jd.Device.Product, price)
+		}
+		if jd.CustomPrice != nil && *jd.CustomPrice > 0 {
+			price = *jd.CustomPrice
+		}
+		if priceShare != 1 {
+			price = math.Round(price*priceShare*100) / 100
+		}
+		subtotal += price
+
+		if grouping == models.LineItemGroupingProduct && jd.Device.Product != nil {
+			key := productLine{productID: jd.Device.Product.ProductID, price: price}
+			if index, ok := productLines[key]; ok {
+				request.LineItems[index].Quantity++
+				continue
+			}
+			productLines[key] = len(request.LineItems)
+			request.LineItems = append(request.LineItems, models.InvoiceLineItemCreateRequest{
+				ItemType:        "device",
+				Description:     jd.Device.Product.Name,
+				Quantity:        1,
+				UnitPrice:       price,
+				RentalStartDate: rentalStart,
+				RentalEndDate:   rentalEnd,
+			})
+			continue
+		}
+
+		// Itemized lines carry the serial number, e.g. for insurance purposes
+		description := jd.DeviceID
+		if jd.Device.SerialNumber != nil && *jd.Device.SerialNumber != "" {
+			description = fmt.Sprintf("%s, S/N %s", jd.DeviceID, *jd.Device.SerialNumber)
+		}
+		if jd.Device.Product != nil {
+			description = fmt.Sprintf("%s (%s)", jd.Device.Product.Name, description)
+		}
+		request.LineItems = append(request.LineItems, models.InvoiceLineItemCreateRequest{
+			ItemType:        "device",
+			DeviceID:        &deviceID,
+			Description:     description,
+			Quantity:        1,
+			UnitPrice:       price,
+			RentalStartDate: rentalStart,
+			RentalEndDate:   rentalEnd,
+		})
+	}
+
+	if job.Discount > 0 {
+		if job.DiscountType == "percent" {
+			request.DiscountAmount = subtotal * job.Discount / 100
+		} else {
+			// A fixed job discount is spread over the job's devices and days
+			request.DiscountAmount = math.Round(job.Discount*priceShare*float64(len(jobDevices))/float64(totalDevices)*100) / 100
+		}
+	}
+
+	if !tracked {
+		return r.CreateInvoice(request)
+	}
+
+	if err := r.prepareInvoiceRequest(request); err != nil {
+		return nil, err
+	}
+
+	var invoice *models.Invoice
+	err = r.db.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the job so two invoices for it can't claim the same device-days
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("jobID = ?", jobID).
+			First(&models.Job{}).Error; err != nil {
+			return fmt.Errorf("failed to lock job: %v", err)
+		}
+
+		billedDeviceIDs := make([]string, 0, len(jobDevices))
+		for _, jd := range jobDevices {
+			billedDeviceIDs = append(billedDeviceIDs, jd.DeviceID)
+		}
+
+		var conflicts []models.JobBilledPeriod
+		if err := billedPeriodsQuery(tx, jobID).
+			Where("bp.device_id IN ?", billedDeviceIDs).
+			Where("bp.period_start <= ? AND bp.period_end >= ?", rentalEnd.Format("2006-01-02"), rentalStart.Format("2006-01-02")).
+			Where("i.status <> ?", "cancelled").
+			Find(&conflicts).Error; err != nil {
+			return fmt.Errorf("failed to check billed periods: %v", err)
+		}
+		if len(conflicts) > 0 {
+			return &BilledPeriodConflictError{Conflicts: conflicts}
+		}
+
+		var err error
+		invoice, err = r.insertInvoice(tx, request)
+		if err != nil {
+			return err
+		}
+
+		periods := make([]models.InvoiceBilledPeriod, 0, len(billedDeviceIDs))
+		for _, deviceID := range billedDeviceIDs {
+			periods = append(periods, models.InvoiceBilledPeriod{
+				InvoiceID:   invoice.InvoiceID,
+				JobID:       jobID,
+				DeviceID:    deviceID,
+				PeriodStart: *rentalStart,
+				PeriodEnd:   *rentalEnd,
+				CreatedAt:   time.Now(),
+			})
+		}
+		if err := tx.Create(&periods).Error; err != nil {
+			return fmt.Errorf("failed to record billed periods: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.loadCreatedInvoice(invoice)
+}
+
+// GetBilledPeriods lists the device-days billed for a job, including those of
+// cancelled invoices, which no longer block billing them again
+func (r *InvoiceRepositoryNew) GetBilledPeriods(jobID uint) ([]models.JobBilledPeriod, error) {
+	var periods []models.JobBilledPeriod
+	err := billedPeriodsQuery(r.db.DB, jobID).
+		Order("bp.device_id, bp.period_start").
+		Find(&periods).Error
+	return periods, err
+}
+
+func billedPeriodsQuery(db *gorm.DB, jobID uint) *gorm.DB {
+	return db.Table("invoice_billed_periods bp").
+		Select("bp.*, i.invoice_number, i.status AS invoice_status").
+		Joins("JOIN invoices i ON i.invoice_id = bp.invoice_id").
+		Where("bp.job_id = ?", jobID)
+}
+
+// selectJobDevices keeps the job devices listed in deviceIDs, all of which must
+// be assigned to the job
+func selectJobDevices(jobDevices []models.JobDevice, deviceIDs []string) ([]models.JobDevice, error) {
+	byID := make(map[string]models.JobDevice, len(jobDevices))
+	for _, jd := range jobDevices {
+		byID[jd.DeviceID] = jd
+	}
+
+	selected := make([]models.JobDevice, 0, len(deviceIDs))
+	seen := make(map[string]bool, len(deviceIDs))
+	var missing []string
+	for _, deviceID := range deviceIDs {
+		if seen[deviceID] {
+			continue
+		}
+		seen[deviceID] = true
+		jd, ok := byID[deviceID]
+		if !ok {
+			missing = append(missing, deviceID)
+			continue
+		}
+		selected = append(selected, jd)
+	}
+
+	if len(missing) > 0 {
+		return nil, &InvoiceSelectionError{Message: fmt.Sprintf("device(s) not assigned to the job: %s", strings.Join(missing, ", "))}
+	}
+	if len(selected) == 0 {
+		return nil, &InvoiceSelectionError{Message: "no devices selected"}
+	}
+	return selected, nil
+}
+
+// invoicePeriodShare resolves the period to invoice within the job's rental
+// period and the share of the job's rental days it covers
+func (r *InvoiceRepositoryNew) invoicePeriodShare(job *models.Job, periodStart, periodEnd *time.Time) (*time.Time, *time.Time, float64, error) {
+	jobStart := invoiceDate(*job.StartDate)
+	jobEnd := invoiceDate(*job.EndDate)
+
+	start, end := jobStart, jobEnd
+	if periodStart != nil {
+		start = invoiceDate(*periodStart)
+	}
+	if periodEnd != nil {
+		end = invoiceDate(*periodEnd)
+	}
+
+	if end.Before(start) {
+		return nil, nil, 0, &InvoiceSelectionError{Message: "period end must not be before period start"}
+	}
+	if start.Before(jobStart) || end.After(jobEnd) {
+		return nil, nil, 0, &InvoiceSelectionError{Message: fmt.Sprintf("period must lie within the job's rental period %s to %s",
+			jobStart.Format("2006-01-02"), jobEnd.Format("2006-01-02"))}
+	}
+	if start.Equal(jobStart) && end.Equal(jobEnd) {
+		return &start, &end, 1, nil
+	}
+
+	holidayRepo := NewHolidayRepository(r.db, r.jobsConfig)
+	jobDays, err := holidayRepo.RentalDays(jobStart, jobEnd)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to count rental days: %v", err)
+	}
+	periodDays, err := holidayRepo.RentalDays(start, end)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to count rental days: %v", err)
+	}
+	if jobDays <= 0 {
+		return &start, &end, 1, nil
+	}
+	return &start, &end, float64(periodDays) / float64(jobDays), nil
+}
+
+// invoiceDate drops the time of day so periods compare by calendar date
+func invoiceDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// applyInvoiceDefaults fills terms and payment instructions the request leaves empty
+// from the invoice settings, falling back to the company's payment terms text
+func (r *InvoiceRepositoryNew) applyInvoiceDefaults(request *models.InvoiceCreateRequest) error {
+	if !isBlank(request.TermsConditions) && !isBlank(request.PaymentTerms) {
+		return nil
+	}
+
+	settings, err := r.GetAllInvoiceSettings()
+	if err != nil {
+		return err
+	}
+
+	if isBlank(request.TermsConditions) && settings.DefaultTermsConditions != "" {
+		terms := settings.DefaultTermsConditions
+		request.TermsConditions = &terms
+	}
+
+	if isBlank(request.PaymentTerms) {
+		instructions := settings.PaymentInstructions
+		if instructions == "" {
+			company, err := r.GetCompanySettings()
+			if err != nil {
+				return err
+			}
+			if company.PaymentTermsText != nil {
+				instructions = *company.PaymentTermsText
+			}
+		}
+		if instructions != "" {
+			request.PaymentTerms = &instructions
+		}
+	}
+
+	return nil
+}
+
+// fillRentalDays counts the rental days of line items that have a rental
+// period but no day count, unless automatic counting is turned off. Business
+// day counting skips weekends and company holidays.
+func (r *InvoiceRepositoryNew) fillRentalDays(request *models.InvoiceCreateRequest) error {
+	pending := false
+	for _, item := range request.LineItems {
+		if item.RentalDays == nil && item.RentalStartDate != nil && item.RentalEndDate != nil {
+			pending = true
+			break
+		}
+	}
+	if !pending {
+		return nil
+	}
+
+	settings, err := r.GetAllInvoiceSettings()
+	if err != nil {
+		return err
+	}
+	if !settings.AutoCalculateRentalDays {
+		return nil
+	}
+
+	holidayRepo := NewHolidayRepository(r.db, r.jobsConfig)
+	for i := range request.LineItems {
+		item := &request.LineItems[i]
+		if item.RentalDays != nil || item.RentalStartDate == nil || item.RentalEndDate == nil {
+			continue
+		}
+		days, err := holidayRepo.RentalDays(*item.RentalStartDate, *item.RentalEndDate)
+		if err != nil {
+			return fmt.Errorf("failed to count rental days: %v", err)
+		}
+		item.RentalDays = &days
+	}
+	return nil
+}
+
+func isBlank(s *string) bool {
+	return s == nil || strings.TrimSpace(*s) == ""
+}
+
 // GetInvoiceByID retrieves an invoice by ID with all relationships
 func (r *InvoiceRepositoryNew) GetInvoiceByID(id uint64) (*models.Invoice, error) {
 	var invoice models.Invoice
@@ -172,6 +613,9 @@ func (r *InvoiceRepositoryNew) UpdateInvoice(id uint64, request *models.InvoiceC
 	if err := request.Validate(); err != nil {
 		return nil, fmt.Errorf("validation failed: %v", err)
 	}
+	if err := r.fillRentalDays(request); err != nil {
+		return nil, err
+	}
 
 	var invoice models.Invoice
 	err := r.db.DB.Transaction(func(tx *gorm.DB) error {
@@ -252,7 +696,7 @@ func (r *InvoiceRepositoryNew) UpdateInvoice(id uint64, request *models.InvoiceC
 		return nil, fmt.Errorf("failed to load updated invoice: %v", err)
 	}
 
-	log.Printf("Successfully updated invoice %s", invoice.InvoiceNumber)
+	logger.Debugf("Successfully updated invoice %s", invoice.InvoiceNumber)
 	return &invoice, nil
 }
 
@@ -297,7 +741,7 @@ func (r *InvoiceRepositoryNew) UpdateInvoiceStatus(id uint64, status string) err
 		return fmt.Errorf("invoice with ID %d not found", id)
 	}
 
-	log.Printf("Successfully updated invoice %d status to %s", id, status)
+	logger.Debugf("Successfully updated invoice %d status to %s", id, status)
 	return nil
 }
 
@@ -318,7 +762,7 @@ func (r *InvoiceRepositoryNew) DeleteInvoice(id uint64) error {
 		return fmt.Errorf("invoice with ID %d not found", id)
 	}
 
-	log.Printf("Successfully deleted invoice %d", id)
+	logger.Debugf("Successfully deleted invoice %d", id)
 	return nil
 }
 
@@ -352,7 +796,7 @@ func (r *InvoiceRepositoryNew) generateInvoiceNumber(tx *gorm.DB) (string, error
 	if err != nil {
 		// Fallback: use timestamp-based number
 		maxNumber = int(time.Now().Unix()) % 100000
-		log.Printf("Warning: Could not get max invoice number, using fallback: %d", maxNumber)
+		logger.Warnf("Could not get max invoice number, using fallback: %d", maxNumber)
 	}
 
 	nextNumber := maxNumber + 1
@@ -414,7 +858,7 @@ func (r *InvoiceRepositoryNew) GeneratePreviewInvoiceNumber() (string, error) {
 	if err != nil {
 		// Fallback: use 1 as the next number
 		maxNumber = 0
-		log.Printf("Warning: Could not get max invoice number for preview, using fallback")
+		logger.Warnf("Could not get max invoice number for preview, using fallback")
 	}
 	
 	nextNumber := maxNumber + 1
@@ -733,6 +1177,7 @@ func (r *InvoiceRepositoryNew) GetAllInvoiceSettings() (*models.InvoiceSettings,
 		CurrencySymbol:          "€",
 		CurrencyCode:            "EUR",
 		DateFormat:              "DD.MM.YYYY",
+		LineItemGrouping:        models.LineItemGroupingProduct,
 	}
 
 	// Override with database values
@@ -764,6 +1209,14 @@ func (r *InvoiceRepositoryNew) GetAllInvoiceSettings() (*models.InvoiceSettings,
 			settings.CurrencyCode = *setting.SettingValue
 		case "date_format":
 			settings.DateFormat = *setting.SettingValue
+		case "default_terms_conditions":
+			settings.DefaultTermsConditions = *setting.SettingValue
+		case "payment_instructions":
+			settings.PaymentInstructions = *setting.SettingValue
+		case "line_item_grouping":
+			if models.IsValidLineItemGrouping(*setting.SettingValue) {
+				settings.LineItemGrouping = *setting.SettingValue
+			}
 		}
 	}
 
@@ -786,6 +1239,135 @@ func (r *InvoiceRepositoryNew) UpdateInvoiceSetting(key, value string, updatedBy
 	return nil
 }
 
+// ================================================================
+// PAYMENT REMINDERS
+// ================================================================
+
+// GetOverdueInvoices returns sent invoices past their due date that still have a balance
+func (r *InvoiceRepositoryNew) GetOverdueInvoices(asOf time.Time) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+
+	if err := r.db.DB.
+		Where("status IN ?", []string{"sent", "overdue"}).
+		Where("balance_due > 0 AND due_date < ?", asOf.Format("2006-01-02")).
+		Order("due_date ASC").
+		Find(&invoices).Error; err != nil {
+		return nil, fmt.Errorf("failed to get overdue invoices: %v", err)
+	}
+
+	// Customers are not a GORM relation on invoices, load them manually
+	for i := range invoices {
+		var customer models.Customer
+		if err := r.db.DB.Where("customerID = ?", invoices[i].CustomerID).First(&customer).Error; err == nil {
+			invoices[i].Customer = &customer
+		}
+	}
+
+	return invoices, nil
+}
+
+// GetLatestReminder returns the most recent reminder sent for an invoice, or nil if none was sent
+func (r *InvoiceRepositoryNew) GetLatestReminder(invoiceID uint64) (*models.InvoiceReminder, error) {
+	var reminder models.InvoiceReminder
+
+	if err := r.db.DB.Where("invoice_id = ?", invoiceID).
+		Order("reminder_level DESC").
+		First(&reminder).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get latest reminder: %v", err)
+	}
+
+	return &reminder, nil
+}
+
+// GetInvoiceReminders returns all reminders sent for an invoice
+func (r *InvoiceRepositoryNew) GetInvoiceReminders(invoiceID uint64) ([]models.InvoiceReminder, error) {
+	var reminders []models.InvoiceReminder
+
+	if err := r.db.DB.Where("invoice_id = ?", invoiceID).
+		Order("reminder_level ASC").
+		Find(&reminders).Error; err != nil {
+		return nil, fmt.Errorf("failed to get invoice reminders: %v", err)
+	}
+
+	return reminders, nil
+}
+
+// RecordReminder stores a sent reminder and flags the invoice as overdue
+func (r *InvoiceRepositoryNew) RecordReminder(reminder *models.InvoiceReminder) error {
+	return r.db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(reminder).Error; err != nil {
+			return fmt.Errorf("failed to record reminder: %v", err)
+		}
+		if err := tx.Model(&models.Invoice{}).
+			Where("invoice_id = ? AND status = ?", reminder.InvoiceID, "sent").
+			Update("status", "overdue").Error; err != nil {
+			return fmt.Errorf("failed to mark invoice overdue: %v", err)
+		}
+		return nil
+	})
+}
+
+// ================================================================
+// UPLOADED INVOICE PDFS
+// ================================================================
+
+// GetUploadedPDF returns the latest final PDF uploaded for an invoice, or nil
+// if the PDF is generated
+func (r *InvoiceRepositoryNew) GetUploadedPDF(invoiceID uint64) (*models.Document, error) {
+	var document models.Document
+	err := r.db.Where("entity_type = ? AND entity_id = ? AND document_type = ?",
+		"invoice", strconv.FormatUint(invoiceID, 10), "invoice").
+		Order("version DESC, documentID DESC").
+		First(&document).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// AttachUploadedPDF stores an uploaded PDF as the invoice's current version,
+// keeping earlier uploads as its history
+func (r *InvoiceRepositoryNew) AttachUploadedPDF(invoiceID uint64, document *models.Document) error {
+	previous, err := r.GetUploadedPDF(invoiceID)
+	if err != nil {
+		return err
+	}
+
+	document.EntityType = "invoice"
+	document.EntityID = strconv.FormatUint(invoiceID, 10)
+	document.DocumentType = "invoice"
+	document.Version = 1
+	if previous != nil {
+		document.Version = previous.Version + 1
+		document.ParentDocumentID = &previous.DocumentID
+	}
+	return r.db.Create(document).Error
+}
+
+// RemoveUploadedPDFs deletes every uploaded PDF of an invoice so downloads are
+// generated again, returning the removed documents so their files can be deleted
+func (r *InvoiceRepositoryNew) RemoveUploadedPDFs(invoiceID uint64) ([]models.Document, error) {
+	var documents []models.Document
+	query := r.db.Where("entity_type = ? AND entity_id = ? AND document_type = ?",
+		"invoice", strconv.FormatUint(invoiceID, 10), "invoice")
+	if err := query.Find(&documents).Error; err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return documents, nil
+	}
+	if err := r.db.Delete(&documents).Error; err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
 // ================================================================
 // STATISTICS
 // ================================================================
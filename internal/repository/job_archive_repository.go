@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// completedJobStatusIDs are the job statuses counted as completed by analytics
+var completedJobStatusIDs = []uint{3, 4}
+
+type JobArchiveRepository struct {
+	db *Database
+}
+
+func NewJobArchiveRepository(db *Database) *JobArchiveRepository {
+	return &JobArchiveRepository{db: db}
+}
+
+// ArchiveSkip is a job that matched the retention policy but was left in place
+type ArchiveSkip struct {
+	JobID  uint   `json:"jobID"`
+	Reason string `json:"reason"`
+}
+
+// ArchiveResult summarizes one archiving run
+type ArchiveResult struct {
+	Cutoff   time.Time     `json:"cutoff"`
+	Archived []uint        `json:"archived"`
+	Skipped  []ArchiveSkip `json:"skipped"`
+}
+
+// ArchiveCompletedJobs moves every completed job that ended before the cutoff
+// into the archive. Each job is archived in its own transaction, so a failure
+// only skips that job.
+func (r *JobArchiveRepository) ArchiveCompletedJobs(cutoff time.Time, archivedBy *uint) (*ArchiveResult, error) {
+	var jobIDs []uint
+	if err := r.db.Model(&models.Job{}).
+		Where("statusID IN ? AND endDate < ?", completedJobStatusIDs, cutoff).
+		Order("endDate ASC").
+		Pluck("jobID", &jobIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to find jobs to archive: %v", err)
+	}
+
+	result := &ArchiveResult{Cutoff: cutoff, Archived: []uint{}, Skipped: []ArchiveSkip{}}
+	for _, jobID := range jobIDs {
+		if reason, err := r.archiveBlocker(jobID); err != nil {
+			return result, err
+		} else if reason != "" {
+			result.Skipped = append(result.Skipped, ArchiveSkip{JobID: jobID, Reason: reason})
+			continue
+		}
+		if err := r.archiveJob(jobID, archivedBy); err != nil {
+			logger.Errorf("Failed to archive job %d: %v", jobID, err)
+			result.Skipped = append(result.Skipped, ArchiveSkip{JobID: jobID, Reason: err.Error()})
+			continue
+		}
+		result.Archived = append(result.Archived, jobID)
+	}
+	return result, nil
+}
+
+// archiveBlocker explains why a job must stay in the operational tables.
+// Financial transactions would be deleted with the job and employee
+// assignments prevent deleting it.
+func (r *JobArchiveRepository) archiveBlocker(jobID uint) (string, error) {
+	var transactions int64
+	if err := r.db.Table("financial_transactions").Where("jobID = ?", jobID).Count(&transactions).Error; err != nil {
+		return "", err
+	}
+	if transactions > 0 {
+		return "job has financial transactions", nil
+	}
+	var employees int64
+	if err := r.db.Table("employeejob").Where("jobID = ?", jobID).Count(&employees).Error; err != nil {
+		return "", err
+	}
+	if employees > 0 {
+		return "job has employee assignments", nil
+	}
+	return "", nil
+}
+
+func (r *JobArchiveRepository) archiveJob(jobID uint, archivedBy *uint) error {
+	return r.db.WithTransaction(func(tx *Database) error {
+		var snapshot models.ArchivedJobSnapshot
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&snapshot.Job, jobID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("jobID = ?", jobID).Find(&snapshot.Devices).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("jobID = ?", jobID).Find(&snapshot.DeviceEvents).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("job_id = ?", jobID).Find(&snapshot.RentalEquipment).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("job_id = ?", jobID).Find(&snapshot.Attachments).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Invoice{}).Where("job_id = ?", jobID).Pluck("invoice_id", &snapshot.InvoiceIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.EquipmentUsageLog{}).Where("jobID = ?", jobID).Pluck("logID", &snapshot.UsageLogIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.DamageReport{}).Where("job_id = ?", jobID).Pluck("report_id", &snapshot.DamageReportIDs).Error; err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return fmt.Errorf("failed to encode job snapshot: %v", err)
+		}
+		job := snapshot.Job
+		archive := models.JobArchive{
+			JobID:       job.JobID,
+			CustomerID:  job.CustomerID,
+			StatusID:    job.StatusID,
+			StartDate:   job.StartDate,
+			EndDate:     job.EndDate,
+			Revenue:     archivedJobRevenue(&job),
+			DeviceCount: len(snapshot.Devices),
+			Snapshot:    data,
+			ArchivedBy:  archivedBy,
+			ArchivedAt:  time.Now(),
+		}
+		if err := tx.Create(&archive).Error; err != nil {
+			return fmt.Errorf("failed to write archive: %v", err)
+		}
+
+		// Children first; references in invoices, usage logs and damage
+		// reports are cleared by their foreign keys
+		for _, child := range []interface{}{
+			&models.JobDeviceEvent{}, &models.JobDevice{},
+		} {
+			if err := tx.Where("jobID = ?", jobID).Delete(child).Error; err != nil {
+				return err
+			}
+		}
+		for _, child := range []interface{}{
+			&models.JobRentalEquipment{}, &models.JobAttachment{},
+		} {
+			if err := tx.Where("job_id = ?", jobID).Delete(child).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&models.Job{}, jobID).Error; err != nil {
+			return err
+		}
+
+		return adjustArchivedTotals(tx, archive.EndDate, archive.Revenue, 1)
+	})
+}
+
+// Restore moves an archived job back into the operational tables. Devices
+// that no longer exist are left off the job and returned.
+func (r *JobArchiveRepository) Restore(jobID uint) (*models.Job, []string, error) {
+	var job models.Job
+	var missingDevices []string
+	err := r.db.WithTransaction(func(tx *Database) error {
+		var archive models.JobArchive
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&archive, jobID).Error; err != nil {
+			return err
+		}
+		var snapshot models.ArchivedJobSnapshot
+		if err := json.Unmarshal(archive.Snapshot, &snapshot); err != nil {
+			return fmt.Errorf("failed to decode job snapshot: %v", err)
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Job{}).Where("jobID = ?", jobID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("job %d already exists", jobID)
+		}
+
+		job = snapshot.Job
+		job.JobDevices = nil
+		if err := tx.Omit(clause.Associations).Create(&job).Error; err != nil {
+			return fmt.Errorf("failed to restore job: %v", err)
+		}
+
+		deviceExists := make(map[string]bool)
+		for _, jd := range snapshot.Devices {
+			var count int64
+			if err := tx.Model(&models.Device{}).Where("deviceID = ?", jd.DeviceID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				missingDevices = append(missingDevices, jd.DeviceID)
+				continue
+			}
+			deviceExists[jd.DeviceID] = true
+			if err := tx.Omit(clause.Associations).Create(&jd).Error; err != nil {
+				return fmt.Errorf("failed to restore device %s: %v", jd.DeviceID, err)
+			}
+		}
+		for _, event := range snapshot.DeviceEvents {
+			if !deviceExists[event.DeviceID] {
+				continue
+			}
+			if err := tx.Create(&event).Error; err != nil {
+				return fmt.Errorf("failed to restore pack events: %v", err)
+			}
+		}
+		for _, equipment := range snapshot.RentalEquipment {
+			if err := tx.Omit(clause.Associations).Create(&equipment).Error; err != nil {
+				return fmt.Errorf("failed to restore rental equipment: %v", err)
+			}
+		}
+		for _, attachment := range snapshot.Attachments {
+			if err := tx.Omit(clause.Associations).Create(&attachment).Error; err != nil {
+				return fmt.Errorf("failed to restore attachments: %v", err)
+			}
+		}
+
+		// Relink records that lost their reference when the job was archived,
+		// unless they have since been assigned elsewhere
+		if len(snapshot.InvoiceIDs) > 0 {
+			if err := tx.Model(&models.Invoice{}).Where("invoice_id IN ? AND job_id IS NULL", snapshot.InvoiceIDs).
+				Update("job_id", jobID).Error; err != nil {
+				return err
+			}
+		}
+		if len(snapshot.UsageLogIDs) > 0 {
+			if err := tx.Model(&models.EquipmentUsageLog{}).Where("logID IN ? AND jobID IS NULL", snapshot.UsageLogIDs).
+				Update("jobID", jobID).Error; err != nil {
+				return err
+			}
+		}
+		if len(snapshot.DamageReportIDs) > 0 {
+			if err := tx.Model(&models.DamageReport{}).Where("report_id IN ? AND job_id IS NULL", snapshot.DamageReportIDs).
+				Update("job_id", jobID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&archive).Error; err != nil {
+			return err
+		}
+		return adjustArchivedTotals(tx, archive.EndDate, -archive.Revenue, -1)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &job, missingDevices, nil
+}
+
+// List returns archived jobs, most recently ended first
+func (r *JobArchiveRepository) List(limit, offset int) ([]models.JobArchive, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.JobArchive{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var archives []models.JobArchive
+	err := r.db.Preload("Customer").
+		Order("end_date DESC, job_id DESC").
+		Limit(limit).Offset(offset).
+		Find(&archives).Error
+	return archives, total, err
+}
+
+func archivedJobRevenue(job *models.Job) float64 {
+	if job.FinalRevenue != nil {
+		return *job.FinalRevenue
+	}
+	return job.Revenue
+}
+
+// adjustArchivedTotals adds a job's revenue and count to (or, restoring,
+// subtracts them from) the monthly archive totals analytics adds to live data
+func adjustArchivedTotals(tx *Database, endDate *time.Time, revenue float64, jobs int) error {
+	if endDate == nil {
+		return nil
+	}
+	month := time.Date(endDate.Year(), endDate.Month(), 1, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+	for metric, delta := range map[string]float64{
+		models.MetricArchivedJobRevenue: revenue,
+		models.MetricArchivedJobs:       float64(jobs),
+	} {
+		if err := tx.Exec(`
+			INSERT INTO analytics_cache (metric_name, period_type, period_date, value)
+			VALUES (?, 'monthly', ?, ?)
+			ON DUPLICATE KEY UPDATE value = COALESCE(value, 0) + VALUES(value)
+		`, metric, month, delta).Error; err != nil {
+			return fmt.Errorf("failed to update archived analytics totals: %v", err)
+		}
+	}
+	return nil
+}
@@ -1,19 +1,26 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strings"
+	"time"
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type JobRepository struct {
-	db *Database
+	db         *Database
+	jobsConfig *config.JobsConfig
 }
 
-func NewJobRepository(db *Database) *JobRepository {
-	return &JobRepository{db: db}
+func NewJobRepository(db *Database, jobsConfig *config.JobsConfig) *JobRepository {
+	return &JobRepository{db: db, jobsConfig: jobsConfig}
 }
 
 // GetDB returns the underlying database connection
@@ -21,6 +28,21 @@ func (r *JobRepository) GetDB() *Database {
 	return r.db
 }
 
+// WithTx returns a copy of the repository bound to the given transaction
+func (r *JobRepository) WithTx(tx *Database) *JobRepository {
+	return &JobRepository{db: tx, jobsConfig: r.jobsConfig}
+}
+
+// NormalizeDiscountType returns the canonical discount type, substituting the
+// configured default for an empty value
+func (r *JobRepository) NormalizeDiscountType(discountType string) (string, error) {
+	var defaultType string
+	if r.jobsConfig != nil {
+		defaultType = r.jobsConfig.DefaultDiscountType
+	}
+	return models.NormalizeDiscountType(discountType, defaultType)
+}
+
 // loadProductsForJobDevices manually loads products for job devices
 // This is a workaround for GORM nested preloading issues
 func (r *JobRepository) loadProductsForJobDevices(jobDevices []models.JobDevice) {
@@ -37,6 +59,12 @@ func (r *JobRepository) loadProductsForJobDevices(jobDevices []models.JobDevice)
 }
 
 func (r *JobRepository) Create(job *models.Job) error {
+	discountType, err := r.NormalizeDiscountType(job.DiscountType)
+	if err != nil {
+		return err
+	}
+	job.DiscountType = discountType
+
 	return r.db.Create(job).Error
 }
 
@@ -44,7 +72,7 @@ func (r *JobRepository) GetByID(id uint) (*models.Job, error) {
 	var job models.Job
 	err := r.db.Preload("JobDevices.Device").First(&job, id).Error
 	if err != nil {
-		fmt.Printf("🔧 DEBUG JobRepo.GetByID: Error loading job %d: %v\n", id, err)
+		logger.Errorf("JobRepo.GetByID: Error loading job %d: %v", id, err)
 		return nil, err
 	}
 	
@@ -52,10 +80,10 @@ func (r *JobRepository) GetByID(id uint) (*models.Job, error) {
 	if job.CustomerID > 0 {
 		var customer models.Customer
 		if err := r.db.Where("customerID = ?", job.CustomerID).First(&customer).Error; err != nil {
-			fmt.Printf("🔧 DEBUG JobRepo.GetByID: Failed to load customer %d: %v\n", job.CustomerID, err)
+			logger.Errorf("JobRepo.GetByID: Failed to load customer %d: %v", job.CustomerID, err)
 		} else {
 			job.Customer = customer
-			fmt.Printf("🔧 DEBUG JobRepo.GetByID: Loaded customer %d: %s\n", customer.CustomerID, 
+			logger.Debugf("JobRepo.GetByID: Loaded customer %d: %s", customer.CustomerID, 
 				func() string {
 					if customer.CompanyName != nil && *customer.CompanyName != "" {
 						return *customer.CompanyName
@@ -72,10 +100,10 @@ func (r *JobRepository) GetByID(id uint) (*models.Job, error) {
 	if job.StatusID > 0 {
 		var status models.Status
 		if err := r.db.Where("statusID = ?", job.StatusID).First(&status).Error; err != nil {
-			fmt.Printf("🔧 DEBUG JobRepo.GetByID: Failed to load status %d: %v\n", job.StatusID, err)
+			logger.Errorf("JobRepo.GetByID: Failed to load status %d: %v", job.StatusID, err)
 		} else {
 			job.Status = status
-			fmt.Printf("🔧 DEBUG JobRepo.GetByID: Loaded status %d: %s\n", status.StatusID, status.Status)
+			logger.Debugf("JobRepo.GetByID: Loaded status %d: %s", status.StatusID, status.Status)
 		}
 	}
 	
@@ -89,7 +117,7 @@ func (r *JobRepository) GetByID(id uint) (*models.Job, error) {
 	// Manually load products for each device
 	r.loadProductsForJobDevices(job.JobDevices)
 	
-	fmt.Printf("🔧 DEBUG JobRepo.GetByID: Loaded job %d with description: '%s'\n", id, func() string {
+	logger.Debugf("JobRepo.GetByID: Loaded job %d with description: '%s'", id, func() string {
 		if job.Description == nil {
 			return "<nil>"
 		}
@@ -100,7 +128,13 @@ func (r *JobRepository) GetByID(id uint) (*models.Job, error) {
 }
 
 func (r *JobRepository) Update(job *models.Job) error {
-	fmt.Printf("🔧 DEBUG JobRepo.Update: Saving job ID %d with description: '%s'\n", job.JobID, func() string {
+	discountType, err := r.NormalizeDiscountType(job.DiscountType)
+	if err != nil {
+		return err
+	}
+	job.DiscountType = discountType
+
+	logger.Debugf("JobRepo.Update: Saving job ID %d with description: '%s'", job.JobID, func() string {
 		if job.Description == nil {
 			return "<nil>"
 		}
@@ -122,24 +156,24 @@ func (r *JobRepository) Update(job *models.Job) error {
 	})
 	
 	if result.Error != nil {
-		fmt.Printf("🔧 DEBUG JobRepo.Update: Error: %v\n", result.Error)
+		logger.Errorf("JobRepo.Update: Error: %v", result.Error)
 		return result.Error
 	}
 	
-	fmt.Printf("🔧 DEBUG JobRepo.Update: Success! Rows affected: %d\n", result.RowsAffected)
+	logger.Debugf("JobRepo.Update: Success! Rows affected: %d", result.RowsAffected)
 	
 	// Verify the update by reading the job back from DB
 	var verifyJob models.Job
 	verifyResult := r.db.Where("jobID = ?", job.JobID).First(&verifyJob)
 	if verifyResult.Error == nil {
-		fmt.Printf("🔧 DEBUG JobRepo.Update: Verification - DB now has description: '%s'\n", func() string {
+		logger.Debugf("JobRepo.Update: Verification - DB now has description: '%s'", func() string {
 			if verifyJob.Description == nil {
 				return "<nil>"
 			}
 			return *verifyJob.Description
 		}())
 	} else {
-		fmt.Printf("🔧 DEBUG JobRepo.Update: Verification failed: %v\n", verifyResult.Error)
+		logger.Errorf("JobRepo.Update: Verification failed: %v", verifyResult.Error)
 	}
 	
 	return nil
@@ -271,7 +305,7 @@ func (r *JobRepository) GetJobDevices(jobID uint) ([]models.JobDevice, error) {
 }
 
 func (r *JobRepository) AssignDevice(jobID uint, deviceID string, price float64) error {
-	fmt.Printf("🚨 DEBUG: NEW AssignDevice called! jobID=%d, deviceID=%s\n", jobID, deviceID)
+	logger.Debugf("NEW AssignDevice called! jobID=%d, deviceID=%s", jobID, deviceID)
 	
 	// Get the job to check its date range
 	var job models.Job
@@ -280,7 +314,7 @@ func (r *JobRepository) AssignDevice(jobID uint, deviceID string, price float64)
 		return fmt.Errorf("job not found: %v", err)
 	}
 
-	fmt.Printf("🚨 DEBUG: Job %d dates: %v to %v\n", jobID, job.StartDate, job.EndDate)
+	logger.Debugf("Job %d dates: %v to %v", jobID, job.StartDate, job.EndDate)
 
 	// Check if device is available for this job's date range
 	// Implement the date-based availability check directly
@@ -293,39 +327,8 @@ func (r *JobRepository) AssignDevice(jobID uint, deviceID string, price float64)
 	}
 
 	// Check for conflicting assignments based on date overlap
-	if job.StartDate != nil && job.EndDate != nil {
-		var conflictingJob models.JobDevice
-		err = r.db.Joins("JOIN jobs ON jobdevices.jobID = jobs.jobID").
-			Where(`jobdevices.deviceID = ? 
-				AND jobs.jobID != ? 
-				AND jobs.startDate <= ? 
-				AND jobs.endDate >= ? 
-				AND jobs.statusID IN (
-					SELECT statusID FROM status WHERE status IN ('open', 'in_progress')
-				)`, deviceID, jobID, job.EndDate, job.StartDate).
-			First(&conflictingJob).Error
-		
-		if err == nil {
-			// Get conflicting job details for error message
-			var conflictJob models.Job
-			r.db.Where("jobID = ?", conflictingJob.JobID).First(&conflictJob)
-			return fmt.Errorf("device is already assigned to job %d (dates: %s to %s)", 
-				conflictJob.JobID, 
-				conflictJob.StartDate.Format("2006-01-02"), 
-				conflictJob.EndDate.Format("2006-01-02"))
-		}
-		if err != gorm.ErrRecordNotFound {
-			return fmt.Errorf("error checking device availability: %v", err)
-		}
-	} else {
-		// If no dates specified, fall back to simple assignment check
-		err = r.db.Where("deviceID = ?", deviceID).First(&existingAssignment).Error
-		if err == nil {
-			return fmt.Errorf("device is already assigned to job %d", existingAssignment.JobID)
-		}
-		if err != gorm.ErrRecordNotFound {
-			return err
-		}
+	if err := r.checkAssignmentConflict(&job, deviceID); err != nil {
+		return err
 	}
 
 	// Create new assignment
@@ -418,6 +421,280 @@ func (r *JobRepository) BulkAssignDevices(jobID uint, deviceIDs []string, price
 	return results, nil
 }
 
+// AssignDevices assigns several devices in one transaction and recalculates revenue
+// once. A failure for any device rolls back the whole assignment.
+func (r *JobRepository) AssignDevices(jobID uint, devices []models.JobDevice) error {
+	return r.db.WithTransaction(func(tx *Database) error {
+		txRepo := r.WithTx(tx)
+		for _, jd := range devices {
+			var price float64
+			if jd.CustomPrice != nil {
+				price = *jd.CustomPrice
+			}
+			if err := txRepo.assignDeviceWithoutRevenue(jobID, jd.DeviceID, price); err != nil {
+				return fmt.Errorf("failed to assign device %s: %v", jd.DeviceID, err)
+			}
+		}
+		return txRepo.CalculateAndUpdateRevenue(jobID)
+	})
+}
+
+// PackageDeviceAssignment is one device unit considered while applying an
+// equipment package to a job
+type PackageDeviceAssignment struct {
+	DeviceID        string   `json:"deviceId,omitempty"`
+	PackageDeviceID string   `json:"packageDeviceId"`
+	CustomPrice     *float64 `json:"customPrice,omitempty"`
+	Reason          string   `json:"reason,omitempty"`
+}
+
+// PackageApplication lists the devices assigned and skipped when applying an
+// equipment package to a job
+type PackageApplication struct {
+	Assigned []PackageDeviceAssignment `json:"assigned"`
+	Skipped  []PackageDeviceAssignment `json:"skipped"`
+}
+
+// ApplyPackage assigns the devices of an equipment package to a job with their
+// custom prices. A package device with a quantity above one is topped up with
+// other devices of the same product. Devices that are already on the job, in
+// maintenance or booked elsewhere for the job's dates are skipped. The job row
+// is locked, so callers should run this inside a transaction.
+func (r *JobRepository) ApplyPackage(jobID uint, packageDevices []models.PackageDevice) (*PackageApplication, error) {
+	var job models.Job
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job, jobID).Error; err != nil {
+		return nil, fmt.Errorf("job not found: %v", err)
+	}
+
+	result := &PackageApplication{
+		Assigned: []PackageDeviceAssignment{},
+		Skipped:  []PackageDeviceAssignment{},
+	}
+	used := make(map[string]bool)
+	unavailable := []string{models.DeviceStatusMaintance, models.DeviceStatusMaintenance}
+
+	for _, pd := range packageDevices {
+		entry := PackageDeviceAssignment{DeviceID: pd.DeviceID, PackageDeviceID: pd.DeviceID, CustomPrice: pd.CustomPrice}
+		used[pd.DeviceID] = true
+
+		var device models.Device
+		if err := r.db.Select("deviceID", "productID", "status").Where("deviceID = ?", pd.DeviceID).First(&device).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return nil, fmt.Errorf("failed to load device %s: %v", pd.DeviceID, err)
+			}
+			entry.Reason = "device not found"
+			result.Skipped = append(result.Skipped, entry)
+			continue
+		}
+
+		assigned := 0
+		if reason, err := r.packageDeviceUnavailable(&job, device.DeviceID, device.Status); err != nil {
+			return nil, err
+		} else if reason != "" {
+			entry.Reason = reason
+			result.Skipped = append(result.Skipped, entry)
+		} else {
+			if err := r.assignDeviceWithoutRevenue(jobID, device.DeviceID, packagePrice(pd.CustomPrice)); err != nil {
+				return nil, fmt.Errorf("failed to assign device %s: %v", device.DeviceID, err)
+			}
+			result.Assigned = append(result.Assigned, entry)
+			assigned++
+		}
+
+		if pd.Quantity <= 1 || device.ProductID == nil {
+			continue
+		}
+
+		// Top up the remaining quantity with other devices of the same product
+		var candidates []string
+		if err := r.db.Model(&models.Device{}).
+			Where("productID = ? AND status NOT IN ? AND deviceID NOT IN (SELECT deviceID FROM jobdevices WHERE jobID = ?)",
+				*device.ProductID, unavailable, jobID).
+			Order("deviceID ASC").
+			Pluck("deviceID", &candidates).Error; err != nil {
+			return nil, fmt.Errorf("failed to find devices for package device %s: %v", pd.DeviceID, err)
+		}
+		for _, candidate := range candidates {
+			if assigned >= int(pd.Quantity) {
+				break
+			}
+			if used[candidate] {
+				continue
+			}
+			if err := r.checkAssignmentConflict(&job, candidate); err != nil {
+				var conflict *AssignmentConflictError
+				if errors.As(err, &conflict) {
+					continue
+				}
+				return nil, err
+			}
+			if err := r.assignDeviceWithoutRevenue(jobID, candidate, packagePrice(pd.CustomPrice)); err != nil {
+				return nil, fmt.Errorf("failed to assign device %s: %v", candidate, err)
+			}
+			used[candidate] = true
+			result.Assigned = append(result.Assigned, PackageDeviceAssignment{DeviceID: candidate, PackageDeviceID: pd.DeviceID, CustomPrice: pd.CustomPrice})
+			assigned++
+		}
+		for ; assigned < int(pd.Quantity); assigned++ {
+			result.Skipped = append(result.Skipped, PackageDeviceAssignment{
+				PackageDeviceID: pd.DeviceID,
+				CustomPrice:     pd.CustomPrice,
+				Reason:          "no further device of this product is available",
+			})
+		}
+	}
+
+	if len(result.Assigned) > 0 {
+		if err := r.CalculateAndUpdateRevenue(jobID); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// packageDeviceUnavailable returns why a device can't be added to the job, or
+// an empty string if it can. Failed lookups are returned as errors, not as
+// reasons.
+func (r *JobRepository) packageDeviceUnavailable(job *models.Job, deviceID, status string) (string, error) {
+	var count int64
+	if err := r.db.Model(&models.JobDevice{}).Where("jobID = ? AND deviceID = ?", job.JobID, deviceID).Count(&count).Error; err != nil {
+		return "", fmt.Errorf("failed to check device %s: %v", deviceID, err)
+	}
+	if count > 0 {
+		return "device is already assigned to this job", nil
+	}
+	if status == models.DeviceStatusMaintance || status == models.DeviceStatusMaintenance {
+		return "device is in maintenance", nil
+	}
+	if err := r.checkAssignmentConflict(job, deviceID); err != nil {
+		var conflict *AssignmentConflictError
+		if errors.As(err, &conflict) {
+			return conflict.Error(), nil
+		}
+		return "", err
+	}
+	return "", nil
+}
+
+// packagePrice returns a package device's custom price for assignment; zero
+// keeps the product's regular price
+func packagePrice(customPrice *float64) float64 {
+	if customPrice == nil {
+		return 0
+	}
+	return *customPrice
+}
+
+// TransferDevice moves a device from one job to another in a single transaction,
+// keeping its custom price. The target job's dates are checked against the
+// device's other bookings, and the move is recorded in the usage log of both jobs.
+func (r *JobRepository) TransferDevice(fromJobID, toJobID uint, deviceID string) error {
+	if fromJobID == toJobID {
+		return fmt.Errorf("device is already assigned to job %d", toJobID)
+	}
+
+	return r.db.WithTransaction(func(tx *Database) error {
+		txRepo := r.WithTx(tx)
+
+		var assignment models.JobDevice
+		err := tx.Where("jobID = ? AND deviceID = ?", fromJobID, deviceID).First(&assignment).Error
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("device %s is not assigned to job %d: %w", deviceID, fromJobID, err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get assignment: %v", err)
+		}
+
+		var toJob models.Job
+		if err := tx.First(&toJob, toJobID).Error; err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("job %d not found: %w", toJobID, err)
+		} else if err != nil {
+			return fmt.Errorf("failed to get job %d: %v", toJobID, err)
+		}
+
+		var existing int64
+		if err := tx.Model(&models.JobDevice{}).Where("jobID = ? AND deviceID = ?", toJobID, deviceID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return &AssignmentConflictError{JobID: toJobID, Message: fmt.Sprintf("device is already assigned to job %d", toJobID)}
+		}
+
+		// Release the source booking first so it doesn't count as a conflict
+		if err := tx.Where("jobID = ? AND deviceID = ?", fromJobID, deviceID).Delete(&models.JobDevice{}).Error; err != nil {
+			return fmt.Errorf("failed to remove device from job %d: %v", fromJobID, err)
+		}
+		if err := txRepo.checkAssignmentConflict(&toJob, deviceID); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&models.JobDevice{
+			JobID:       toJobID,
+			DeviceID:    deviceID,
+			CustomPrice: assignment.CustomPrice,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to assign device to job %d: %v", toJobID, err)
+		}
+
+		now := time.Now()
+		logs := []models.EquipmentUsageLog{
+			{DeviceID: deviceID, JobID: &fromJobID, Action: "returned", Timestamp: now, Notes: fmt.Sprintf("Transferred to job %d", toJobID)},
+			{DeviceID: deviceID, JobID: &toJobID, Action: "assigned", Timestamp: now, Notes: fmt.Sprintf("Transferred from job %d", fromJobID)},
+		}
+		if err := tx.Create(&logs).Error; err != nil {
+			return fmt.Errorf("failed to write usage log: %v", err)
+		}
+
+		if err := txRepo.CalculateAndUpdateRevenue(fromJobID); err != nil {
+			return err
+		}
+		return txRepo.CalculateAndUpdateRevenue(toJobID)
+	})
+}
+
+// DeviceAvailability tells whether a device could be booked for a job
+type DeviceAvailability struct {
+	DeviceID  string `json:"deviceId"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// ValidateAssignments checks whether each device could be assigned to the job
+// for its dates, ignoring the job's own bookings, without changing anything
+func (r *JobRepository) ValidateAssignments(job *models.Job, deviceIDs []string) ([]DeviceAvailability, error) {
+	if job.StartDate == nil || job.EndDate == nil {
+		return nil, fmt.Errorf("start and end date are required")
+	}
+	if job.EndDate.Before(*job.StartDate) {
+		return nil, fmt.Errorf("end date cannot be before start date")
+	}
+
+	results := make([]DeviceAvailability, 0, len(deviceIDs))
+	checked := make(map[string]bool, len(deviceIDs))
+	for _, deviceID := range deviceIDs {
+		if deviceID == "" || checked[deviceID] {
+			continue
+		}
+		checked[deviceID] = true
+
+		result := DeviceAvailability{DeviceID: deviceID, Available: true}
+		var count int64
+		if err := r.db.Model(&models.Device{}).Where("deviceID = ?", deviceID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			result.Available = false
+			result.Reason = "device not found"
+		} else if err := r.checkAssignmentConflict(job, deviceID); err != nil {
+			result.Available = false
+			result.Reason = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
 // Helper method to assign device without triggering revenue calculation
 func (r *JobRepository) assignDeviceWithoutRevenue(jobID uint, deviceID string, price float64) error {
 	// Get the job to check its date range
@@ -435,6 +712,47 @@ func (r *JobRepository) assignDeviceWithoutRevenue(jobID uint, deviceID string,
 	}
 
 	// Check for conflicting assignments based on date overlap
+	if err := r.checkAssignmentConflict(&job, deviceID); err != nil {
+		return err
+	}
+
+	// Create new assignment
+	jobDevice := &models.JobDevice{
+		JobID:    jobID,
+		DeviceID: deviceID,
+	}
+
+	// Only set custom price if it's greater than 0
+	if price > 0 {
+		jobDevice.CustomPrice = &price
+	}
+
+	return r.db.Create(jobDevice).Error
+}
+
+// AssignmentConflictError reports a device that is already booked on another job
+type AssignmentConflictError struct {
+	JobID   uint // The job holding the booking
+	Message string
+}
+
+func (e *AssignmentConflictError) Error() string {
+	return e.Message
+}
+
+// checkAssignmentConflict returns an *AssignmentConflictError if the device is already
+// booked on another active job overlapping the given job's dates, or the error of a
+// failed lookup. Devices of consumable products are not tracked per unit and never
+// conflict.
+func (r *JobRepository) checkAssignmentConflict(job *models.Job, deviceID string) error {
+	consumable, err := isConsumableDevice(r.db, deviceID)
+	if err != nil {
+		return fmt.Errorf("error checking device availability: %v", err)
+	}
+	if consumable {
+		return nil
+	}
+
 	if job.StartDate != nil && job.EndDate != nil {
 		var conflictingJob models.JobDevice
 		err = r.db.Joins("JOIN jobs ON jobdevices.jobID = jobs.jobID").
@@ -444,43 +762,34 @@ func (r *JobRepository) assignDeviceWithoutRevenue(jobID uint, deviceID string,
 				AND jobs.endDate >= ? 
 				AND jobs.statusID IN (
 					SELECT statusID FROM status WHERE status IN ('open', 'in_progress')
-				)`, deviceID, jobID, job.EndDate, job.StartDate).
+				)`, deviceID, job.JobID, job.EndDate, job.StartDate).
 			First(&conflictingJob).Error
 		
 		if err == nil {
+			// Get conflicting job details for error message
 			var conflictJob models.Job
 			r.db.Where("jobID = ?", conflictingJob.JobID).First(&conflictJob)
-			return fmt.Errorf("device is already assigned to job %d (dates: %s to %s)", 
+			return &AssignmentConflictError{JobID: conflictingJob.JobID, Message: fmt.Sprintf("device is already assigned to job %d (dates: %s to %s)", 
 				conflictJob.JobID, 
 				conflictJob.StartDate.Format("2006-01-02"), 
-				conflictJob.EndDate.Format("2006-01-02"))
+				conflictJob.EndDate.Format("2006-01-02"))}
 		}
 		if err != gorm.ErrRecordNotFound {
 			return fmt.Errorf("error checking device availability: %v", err)
 		}
-	} else {
-		// If no dates specified, fall back to simple assignment check
-		err = r.db.Where("deviceID = ?", deviceID).First(&existingAssignment).Error
-		if err == nil {
-			return fmt.Errorf("device is already assigned to job %d", existingAssignment.JobID)
-		}
-		if err != gorm.ErrRecordNotFound {
-			return err
-		}
+		return nil
 	}
 
-	// Create new assignment
-	jobDevice := &models.JobDevice{
-		JobID:    jobID,
-		DeviceID: deviceID,
+	// If no dates specified, fall back to simple assignment check
+	var existingAssignment models.JobDevice
+	err = r.db.Where("deviceID = ?", deviceID).First(&existingAssignment).Error
+	if err == nil {
+		return &AssignmentConflictError{JobID: existingAssignment.JobID, Message: fmt.Sprintf("device is already assigned to job %d", existingAssignment.JobID)}
 	}
-
-	// Only set custom price if it's greater than 0
-	if price > 0 {
-		jobDevice.CustomPrice = &price
+	if err != gorm.ErrRecordNotFound {
+		return err
 	}
-
-	return r.db.Create(jobDevice).Error
+	return nil
 }
 
 func (r *JobRepository) GetJobStats(jobID uint) (*models.JobWithDetails, error) {
@@ -507,10 +816,6 @@ func (r *JobRepository) CalculateAndUpdateRevenue(jobID uint) error {
 		return err
 	}
 
-	// Revenue is calculated as flat rates, not per day
-
-	// Calculate total revenue from job devices
-	var totalRevenue float64
 	var jobDevices []models.JobDevice
 	err = r.db.Where("jobID = ?", jobID).
 		Preload("Device").
@@ -518,38 +823,131 @@ func (r *JobRepository) CalculateAndUpdateRevenue(jobID uint) error {
 	if err != nil {
 		return err
 	}
-	
+
+	// Update the job revenue
+	totalRevenue, err := r.grossRevenue(jobDevices, job.StartDate)
+	if err != nil {
+		return err
+	}
+	job.Revenue = totalRevenue
+
+	// Calculate final revenue after discount
+	finalRevenue := models.ApplyDiscount(totalRevenue, job.Discount, job.DiscountType)
+	job.FinalRevenue = &finalRevenue
+
+	return r.db.Save(&job).Error
+}
+
+// grossRevenue sums the flat rates of the job devices before discount. Revenue
+// is calculated as flat rates, not per day: custom prices are used as-is,
+// otherwise the product rate adjusted by the seasonal rate for the start date.
+func (r *JobRepository) grossRevenue(jobDevices []models.JobDevice, startDate *time.Time) (float64, error) {
 	// Manually load products for each device
 	r.loadProductsForJobDevices(jobDevices)
 
+	// Seasonal pricing is keyed on the job's start date
+	var seasonalRates []models.PricingCalendar
+	if startDate != nil {
+		var err error
+		seasonalRates, err = NewPricingCalendarRepository(r.db).FindActiveForDate(*startDate)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load pricing calendar: %v", err)
+		}
+	}
+
+	var totalRevenue float64
 	for _, jd := range jobDevices {
 		if jd.CustomPrice != nil && *jd.CustomPrice > 0 {
-			// Use custom price as-is (flat rate, not per day)
 			totalRevenue += *jd.CustomPrice
-		} else if jd.Device.Product != nil && jd.Device.Product.ItemCostPerDay != nil {
-			// Use product price as flat rate (not per day)
-			totalRevenue += *jd.Device.Product.ItemCostPerDay
+		} else if jd.Device.Product != nil {
+			var rate float64
+			if jd.Device.Product.ItemCostPerDay != nil {
+				rate = *jd.Device.Product.ItemCostPerDay
+			}
+			totalRevenue += models.ResolveSeasonalRate(seasonalRates, jd.Device.Product, rate)
 		}
 	}
+	return totalRevenue, nil
+}
 
-	// Update the job revenue
-	job.Revenue = totalRevenue
-	
-	// Calculate final revenue after discount
-	var finalRevenue float64
-	if job.DiscountType == "percent" {
-		// Percentage discount
-		finalRevenue = totalRevenue * (1 - job.Discount/100)
-	} else {
-		// Fixed amount discount
-		finalRevenue = totalRevenue - job.Discount
-		if finalRevenue < 0 {
-			finalRevenue = 0 // Cannot be negative
+// RevenuePreview is the revenue a job would be stored with
+type RevenuePreview struct {
+	Gross           float64 `json:"gross"`
+	DiscountApplied float64 `json:"discountApplied"`
+	Net             float64 `json:"net"`
+	DiscountType    string  `json:"discountType"`
+	Devices         int     `json:"devices"`
+}
+
+// PreviewRevenue calculates revenue the same way as CalculateAndUpdateRevenue
+// without saving anything. Without device IDs the job's current devices are
+// priced; otherwise the given devices are, keeping the custom prices of those
+// already on the job. startDate selects the seasonal rates and falls back to
+// the job's start date.
+func (r *JobRepository) PreviewRevenue(jobID uint, deviceIDs []string, startDate *time.Time, discount float64, discountType string) (*RevenuePreview, error) {
+	discountType, err := r.NormalizeDiscountType(discountType)
+	if err != nil {
+		return nil, err
+	}
+	if discount < 0 {
+		return nil, fmt.Errorf("discount cannot be negative")
+	}
+	if discountType == models.DiscountTypePercent && discount > 100 {
+		return nil, fmt.Errorf("percent discount cannot exceed 100")
+	}
+
+	var assigned []models.JobDevice
+	if jobID != 0 {
+		var job models.Job
+		if err := r.db.First(&job, jobID).Error; err != nil {
+			return nil, err
+		}
+		if startDate == nil {
+			startDate = job.StartDate
+		}
+		if err := r.db.Where("jobID = ?", jobID).Preload("Device").Find(&assigned).Error; err != nil {
+			return nil, err
 		}
 	}
-	job.FinalRevenue = &finalRevenue
-	
-	return r.db.Save(&job).Error
+
+	jobDevices := assigned
+	if len(deviceIDs) > 0 {
+		byDevice := make(map[string]models.JobDevice, len(assigned))
+		for _, jd := range assigned {
+			byDevice[jd.DeviceID] = jd
+		}
+
+		jobDevices = make([]models.JobDevice, 0, len(deviceIDs))
+		seen := make(map[string]bool, len(deviceIDs))
+		for _, deviceID := range deviceIDs {
+			if deviceID == "" || seen[deviceID] {
+				continue
+			}
+			seen[deviceID] = true
+			if jd, ok := byDevice[deviceID]; ok {
+				jobDevices = append(jobDevices, jd)
+				continue
+			}
+			var device models.Device
+			if err := r.db.Where("deviceID = ?", deviceID).First(&device).Error; err != nil {
+				return nil, fmt.Errorf("device %s not found", deviceID)
+			}
+			jobDevices = append(jobDevices, models.JobDevice{DeviceID: deviceID, Device: device})
+		}
+	}
+
+	gross, err := r.grossRevenue(jobDevices, startDate)
+	if err != nil {
+		return nil, err
+	}
+	net := models.ApplyDiscount(gross, discount, discountType)
+	return &RevenuePreview{
+		Gross:           gross,
+		DiscountApplied: gross - net,
+		Net:             net,
+		DiscountType:    discountType,
+		Devices:         len(jobDevices),
+	}, nil
 }
 
 func (r *JobRepository) UpdateFinalRevenue(jobID uint) error {
@@ -561,24 +959,233 @@ func (r *JobRepository) UpdateFinalRevenue(jobID uint) error {
 	}
 
 	// Calculate final revenue after discount using existing revenue
-	var finalRevenue float64
-	if job.DiscountType == "percent" {
-		// Percentage discount
-		finalRevenue = job.Revenue * (1 - job.Discount/100)
-	} else {
-		// Fixed amount discount
-		finalRevenue = job.Revenue - job.Discount
-		if finalRevenue < 0 {
-			finalRevenue = 0 // Cannot be negative
-		}
-	}
+	finalRevenue := models.ApplyDiscount(job.Revenue, job.Discount, job.DiscountType)
 	job.FinalRevenue = &finalRevenue
 	
 	return r.db.Save(&job).Error
 }
 
+// IsCompletedJobStatus reports whether jobs with the status count as completed
+func IsCompletedJobStatus(statusID uint) bool {
+	for _, completed := range completedJobStatusIDs {
+		if statusID == completed {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordCompletionRevenue books a completed job's rental revenue as a pending
+// rental transaction over its final revenue, or its revenue if no final
+// revenue is set. Nothing is recorded if the job already has a rental
+// transaction or nothing to charge. Reports whether a transaction was created.
+func (r *JobRepository) RecordCompletionRevenue(jobID uint, createdBy *uint) (bool, error) {
+	var job models.Job
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job, jobID).Error; err != nil {
+		return false, err
+	}
+
+	var existing int64
+	if err := r.db.Model(&models.FinancialTransaction{}).
+		Where("jobID = ? AND type = ?", jobID, models.TransactionTypeRental).
+		Count(&existing).Error; err != nil {
+		return false, fmt.Errorf("failed to check rental transactions: %v", err)
+	}
+	if existing > 0 {
+		return false, nil
+	}
+
+	amount := job.Revenue
+	if job.FinalRevenue != nil {
+		amount = *job.FinalRevenue
+	}
+	if amount <= 0 {
+		return false, nil
+	}
+
+	customerID := job.CustomerID
+	now := time.Now()
+	transaction := models.FinancialTransaction{
+		JobID:           &job.JobID,
+		CustomerID:      &customerID,
+		Type:            models.TransactionTypeRental,
+		Amount:          amount,
+		Currency:        models.DefaultTransactionCurrency,
+		Status:          models.TransactionStatusPending,
+		TransactionDate: now,
+		Notes:           fmt.Sprintf("Rental revenue of completed job #%d", job.JobID),
+		CreatedBy:       createdBy,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if err := r.db.Create(&transaction).Error; err != nil {
+		return false, fmt.Errorf("failed to record rental transaction: %v", err)
+	}
+	return true, nil
+}
+
+// RentalDurationError reports a job whose rental period exceeds the allowed maximum
+type RentalDurationError struct {
+	Days    int
+	MaxDays int
+}
+
+func (e *RentalDurationError) Error() string {
+	return fmt.Sprintf("rental duration of %d days exceeds the maximum of %d days", e.Days, e.MaxDays)
+}
+
+// MaxRentalDays returns the configured maximum rental duration in days, 0 if
+// unlimited
+func (r *JobRepository) MaxRentalDays() int {
+	if r.jobsConfig == nil || r.jobsConfig.MaxRentalDays < 0 {
+		return 0
+	}
+	return r.jobsConfig.MaxRentalDays
+}
+
+// MaxRentalDaysFor returns the maximum rental duration for jobs of the given
+// category, falling back to the configured limit. 0 means unlimited.
+func (r *JobRepository) MaxRentalDaysFor(jobCategoryID *uint) int {
+	if jobCategoryID != nil {
+		var category models.JobCategory
+		if err := r.db.Select("jobcategoryID, max_rental_days").First(&category, *jobCategoryID).Error; err == nil && category.MaxRentalDays != nil {
+			return *category.MaxRentalDays
+		}
+	}
+	return r.MaxRentalDays()
+}
+
+// CheckRentalDuration returns a *RentalDurationError if the job's rental
+// period is longer than the maximum for its category
+func (r *JobRepository) CheckRentalDuration(job *models.Job) error {
+	maxDays := r.MaxRentalDaysFor(job.JobCategoryID)
+	if maxDays <= 0 {
+		return nil
+	}
+	if days := job.RentalDays(); days > maxDays {
+		return &RentalDurationError{Days: days, MaxDays: maxDays}
+	}
+	return nil
+}
+
+// JobDurationViolation is an existing job whose rental period exceeds the maximum
+type JobDurationViolation struct {
+	JobID         uint      `json:"jobID" gorm:"column:jobID"`
+	CustomerID    uint      `json:"customerID" gorm:"column:customerID"`
+	JobCategoryID *uint     `json:"jobcategoryID" gorm:"column:jobcategoryID"`
+	Description   *string   `json:"description" gorm:"column:description"`
+	StartDate     time.Time `json:"startDate" gorm:"column:startDate"`
+	EndDate       time.Time `json:"endDate" gorm:"column:endDate"`
+	Days          int       `json:"days" gorm:"column:days"`
+	MaxDays       int       `json:"maxDays" gorm:"column:max_days"`
+}
+
+// GetJobsExceedingMaxDuration lists all jobs whose rental period is longer than
+// the maximum for their category, longest first
+func (r *JobRepository) GetJobsExceedingMaxDuration() ([]JobDurationViolation, error) {
+	var violations []JobDurationViolation
+	maxDays := r.MaxRentalDays()
+	err := r.db.Table("jobs j").
+		Select(`j.jobID, j.customerID, j.jobcategoryID, j.description, j.startDate, j.endDate,
+			DATEDIFF(j.endDate, j.startDate) + 1 AS days,
+			COALESCE(jc.max_rental_days, ?) AS max_days`, maxDays).
+		Joins("LEFT JOIN jobCategory jc ON jc.jobcategoryID = j.jobcategoryID").
+		Where("j.startDate IS NOT NULL AND j.endDate IS NOT NULL").
+		Where("COALESCE(jc.max_rental_days, ?) > 0", maxDays).
+		Where("DATEDIFF(j.endDate, j.startDate) + 1 > COALESCE(jc.max_rental_days, ?)", maxDays).
+		Order("days DESC").
+		Scan(&violations).Error
+	return violations, err
+}
+
+// RevenueRecalculationResult summarizes a bulk revenue recalculation
+type RevenueRecalculationResult struct {
+	Processed     int     `json:"processed"`
+	Changed       int     `json:"changed"`
+	Failed        int     `json:"failed"`
+	NetDifference float64 `json:"netDifference"` // Sum of new minus old final revenue
+}
+
+// RecalculateAllRevenue re-runs the revenue calculation for every job in batches of
+// batchSize. Jobs with devices are recalculated from their devices; jobs without
+// devices keep their (manual) revenue and only get the discount reapplied.
+// progress, if set, is called after each batch.
+func (r *JobRepository) RecalculateAllRevenue(batchSize int, progress func(processed, total int)) (*RevenueRecalculationResult, error) {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	var total int64
+	if err := r.db.Model(&models.Job{}).Count(&total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %v", err)
+	}
+
+	result := &RevenueRecalculationResult{}
+	var lastJobID uint
+	for {
+		var jobs []struct {
+			JobID        uint     `gorm:"column:jobID"`
+			FinalRevenue *float64 `gorm:"column:final_revenue"`
+			DeviceCount  int      `gorm:"column:device_count"`
+		}
+		err := r.db.Table("jobs j").
+			Select("j.jobID, j.final_revenue, (SELECT COUNT(*) FROM jobdevices jd WHERE jd.jobID = j.jobID) AS device_count").
+			Where("j.jobID > ?", lastJobID).
+			Order("j.jobID ASC").
+			Limit(batchSize).
+			Scan(&jobs).Error
+		if err != nil {
+			return result, fmt.Errorf("failed to load jobs: %v", err)
+		}
+		if len(jobs) == 0 {
+			break
+		}
+
+		for _, job := range jobs {
+			lastJobID = job.JobID
+			result.Processed++
+
+			var err error
+			if job.DeviceCount > 0 {
+				err = r.CalculateAndUpdateRevenue(job.JobID)
+			} else {
+				err = r.UpdateFinalRevenue(job.JobID)
+			}
+			if err != nil {
+				logger.Errorf("RecalculateAllRevenue: job %d: %v", job.JobID, err)
+				result.Failed++
+				continue
+			}
+
+			var updated models.Job
+			if err := r.db.Select("jobID, final_revenue").First(&updated, job.JobID).Error; err != nil {
+				result.Failed++
+				continue
+			}
+
+			var before, after float64
+			if job.FinalRevenue != nil {
+				before = *job.FinalRevenue
+			}
+			if updated.FinalRevenue != nil {
+				after = *updated.FinalRevenue
+			}
+			if math.Abs(after-before) >= 0.005 {
+				result.Changed++
+				result.NetDifference += after - before
+			}
+		}
+
+		if progress != nil {
+			progress(result.Processed, int(total))
+		}
+	}
+
+	return result, nil
+}
+
 func (r *JobRepository) UpdateDevicePrice(jobID uint, deviceID string, price float64) error {
-	fmt.Printf("🔧 DEBUG UpdateDevicePrice: JobID=%d, DeviceID=%s, Price=%.2f\n", jobID, deviceID, price)
+	logger.Debugf("UpdateDevicePrice: JobID=%d, DeviceID=%s, Price=%.2f", jobID, deviceID, price)
 	
 	// Update the custom_price for the specific job-device relationship
 	// Fix: column name is 'deviceID' not 'device_id'
@@ -586,27 +1193,27 @@ func (r *JobRepository) UpdateDevicePrice(jobID uint, deviceID string, price flo
 		Where("jobID = ? AND deviceID = ?", jobID, deviceID).
 		Update("custom_price", price)
 	
-	fmt.Printf("🔧 DEBUG UpdateDevicePrice: SQL result - Error=%v, RowsAffected=%d\n", result.Error, result.RowsAffected)
+	logger.Debugf("UpdateDevicePrice: SQL result - Error=%v, RowsAffected=%d", result.Error, result.RowsAffected)
 	
 	if result.Error != nil {
-		fmt.Printf("🔧 DEBUG UpdateDevicePrice: Database error: %v\n", result.Error)
+		logger.Errorf("UpdateDevicePrice: Database error: %v", result.Error)
 		return result.Error
 	}
 	
 	if result.RowsAffected == 0 {
-		fmt.Printf("🔧 DEBUG UpdateDevicePrice: No rows affected - device not found\n")
+		logger.Debugf("UpdateDevicePrice: No rows affected - device not found")
 		return fmt.Errorf("device %s not found in job %d", deviceID, jobID)
 	}
 	
 	// Recalculate job revenue after price update
-	fmt.Printf("🔧 DEBUG UpdateDevicePrice: Recalculating revenue for job %d\n", jobID)
+	logger.Debugf("UpdateDevicePrice: Recalculating revenue for job %d", jobID)
 	err := r.CalculateAndUpdateRevenue(jobID)
 	if err != nil {
-		fmt.Printf("🔧 DEBUG UpdateDevicePrice: Revenue calculation error: %v\n", err)
+		logger.Errorf("UpdateDevicePrice: Revenue calculation error: %v", err)
 		return err
 	}
 	
-	fmt.Printf("🔧 DEBUG UpdateDevicePrice: Success!\n")
+	logger.Debugf("UpdateDevicePrice: Success!")
 	return nil
 }
 
@@ -617,6 +1224,31 @@ func (r *JobRepository) GetJobDeviceCount(jobID uint) (int, error) {
 	return int(count), err
 }
 
+// GetJobDeviceCounts returns the number of devices assigned to each of the
+// jobs in one query; jobs without devices are left out
+func (r *JobRepository) GetJobDeviceCounts(jobIDs []uint) (map[uint]int, error) {
+	counts := make(map[uint]int, len(jobIDs))
+	if len(jobIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		JobID   uint `gorm:"column:jobID"`
+		Devices int  `gorm:"column:devices"`
+	}
+	if err := r.db.Model(&models.JobDevice{}).
+		Select("jobID, COUNT(*) AS devices").
+		Where("jobID IN ?", jobIDs).
+		Group("jobID").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count job devices: %v", err)
+	}
+	for _, row := range rows {
+		counts[row.JobID] = row.Devices
+	}
+	return counts, nil
+}
+
 // ProductSummary represents device count summary by product
 type ProductSummary struct {
 	ProductName string
@@ -2,7 +2,11 @@ package repository
 
 import (
 	"fmt"
+	"time"
+
 	"go-barcode-webapp/internal/models"
+
+	"gorm.io/gorm"
 )
 
 // FreeDevicesFromCompletedJobs removes device assignments from jobs with "cancelled" status
@@ -75,4 +79,41 @@ func (r *JobRepository) FreeDevicesFromCompletedJobs() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// overdueJobs selects the jobs that ended before the day of asOf but are not
+// completed yet; a job ending today isn't overdue
+func (r *JobRepository) overdueJobs(asOf time.Time) *gorm.DB {
+	return r.db.Model(&models.Job{}).
+		Where("endDate < ? AND statusID NOT IN ?", asOf.Format("2006-01-02"), completedJobStatusIDs)
+}
+
+// CountOverdueJobs returns how many jobs GetOverdueJobs would list in total
+func (r *JobRepository) CountOverdueJobs(asOf time.Time) (int64, error) {
+	var total int64
+	if err := r.overdueJobs(asOf).Count(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to count overdue jobs: %v", err)
+	}
+	return total, nil
+}
+
+// GetOverdueJobs returns jobs whose end date has passed but that are not
+// completed yet, longest overdue first, and how many there are in total.
+// A limit of 0 returns all of them.
+func (r *JobRepository) GetOverdueJobs(asOf time.Time, limit int) ([]models.Job, int64, error) {
+	total, err := r.CountOverdueJobs(asOf)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := r.overdueJobs(asOf)
+
+	var jobs []models.Job
+	query = query.Preload("Customer").Preload("Status").Order("endDate ASC, jobID ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&jobs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get overdue jobs: %v", err)
+	}
+	return jobs, total, nil
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"time"
+
+	"go-barcode-webapp/internal/models"
+)
+
+type PricingCalendarRepository struct {
+	db *Database
+}
+
+func NewPricingCalendarRepository(db *Database) *PricingCalendarRepository {
+	return &PricingCalendarRepository{db: db}
+}
+
+func (r *PricingCalendarRepository) Create(calendar *models.PricingCalendar) error {
+	return r.db.Create(calendar).Error
+}
+
+func (r *PricingCalendarRepository) GetByID(id uint) (*models.PricingCalendar, error) {
+	var calendar models.PricingCalendar
+	err := r.db.Preload("Product").Preload("Category").First(&calendar, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+func (r *PricingCalendarRepository) List() ([]models.PricingCalendar, error) {
+	var calendars []models.PricingCalendar
+	err := r.db.Preload("Product").Preload("Category").
+		Order("start_date DESC, calendar_id DESC").
+		Find(&calendars).Error
+	return calendars, err
+}
+
+func (r *PricingCalendarRepository) Update(calendar *models.PricingCalendar) error {
+	return r.db.Save(calendar).Error
+}
+
+func (r *PricingCalendarRepository) Delete(id uint) error {
+	return r.db.Delete(&models.PricingCalendar{}, id).Error
+}
+
+// FindActiveForDate returns all active calendar entries covering the given date
+func (r *PricingCalendarRepository) FindActiveForDate(date time.Time) ([]models.PricingCalendar, error) {
+	var calendars []models.PricingCalendar
+	day := date.Format("2006-01-02")
+	err := r.db.Where("is_active = ? AND start_date <= ? AND end_date >= ?", true, day, day).
+		Find(&calendars).Error
+	return calendars, err
+}
@@ -1,7 +1,8 @@
 package repository
 
 import (
-	"log"
+	"strconv"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 )
 
@@ -78,12 +79,12 @@ func (r *ProductRepository) GetAllCategories() ([]models.Category, error) {
 	var categories []models.Category
 	err := r.db.Order("name ASC").Find(&categories).Error
 	if err != nil {
-		log.Printf("❌ GetAllCategories error: %v", err)
+		logger.Errorf("GetAllCategories error: %v", err)
 		return nil, err
 	}
-	log.Printf("🔧 GetAllCategories: Found %d categories in database", len(categories))
+	logger.Debugf("GetAllCategories: Found %d categories in database", len(categories))
 	for _, cat := range categories {
-		log.Printf("🔧 Category from DB: %s (ID: %d)", cat.Name, cat.CategoryID)
+		logger.Debugf("Category from DB: %s (ID: %d)", cat.Name, cat.CategoryID)
 	}
 	return categories, err
 }
@@ -170,7 +171,7 @@ func (r *ProductRepository) GetDevicesBySubcategory(subcategoryID string) ([]mod
 }
 
 func (r *ProductRepository) GetDevicesByCategory(categoryID uint) ([]models.DeviceWithJobInfo, error) {
-	log.Printf("🔍 GetDevicesByCategory: Searching for devices in category %d", categoryID)
+	logger.Debugf("GetDevicesByCategory: Searching for devices in category %d", categoryID)
 	var devices []models.Device
 	
 	err := r.db.Model(&models.Device{}).
@@ -184,11 +185,11 @@ func (r *ProductRepository) GetDevicesByCategory(categoryID uint) ([]models.Devi
 		Find(&devices).Error
 	
 	if err != nil {
-		log.Printf("❌ GetDevicesByCategory: Database error for category %d: %v", categoryID, err)
+		logger.Errorf("GetDevicesByCategory: Database error for category %d: %v", categoryID, err)
 		return nil, err
 	}
 	
-	log.Printf("🔍 GetDevicesByCategory: Found %d devices for category %d", len(devices), categoryID)
+	logger.Debugf("GetDevicesByCategory: Found %d devices for category %d", len(devices), categoryID)
 	
 	// Convert to DeviceWithJobInfo format
 	var result []models.DeviceWithJobInfo
@@ -213,6 +214,48 @@ func (r *ProductRepository) GetDevicesByCategory(categoryID uint) ([]models.Devi
 	return result, nil
 }
 
+// GetDirectCategoryDevices returns devices whose product has the category but no subcategory
+func (r *ProductRepository) GetDirectCategoryDevices(categoryID uint) ([]models.DeviceWithJobInfo, error) {
+	var devices []models.Device
+	
+	err := r.db.Model(&models.Device{}).
+		Preload("Product").
+		Preload("Product.Category").
+		Preload("Product.Subcategory").
+		Preload("Product.Subbiercategory").
+		Joins("LEFT JOIN products ON products.productID = devices.productID").
+		Where("products.categoryID = ? AND (products.subcategoryID IS NULL OR products.subcategoryID = '' OR products.subcategoryID = '0')", categoryID).
+		Order("devices.serialnumber ASC").
+		Find(&devices).Error
+	
+	if err != nil {
+		logger.Errorf("GetDirectCategoryDevices: Database error for category %d: %v", categoryID, err)
+		return nil, err
+	}
+	
+	// Convert to DeviceWithJobInfo format
+	result := make([]models.DeviceWithJobInfo, 0, len(devices))
+	for _, device := range devices {
+		// Check if device is assigned to any job
+		var jobDevice models.JobDevice
+		err := r.db.Where("deviceID = ?", device.DeviceID).First(&jobDevice).Error
+		var jobID *uint
+		isAssigned := false
+		if err == nil {
+			jobID = &jobDevice.JobID
+			isAssigned = true
+		}
+		
+		result = append(result, models.DeviceWithJobInfo{
+			Device:     device,
+			JobID:      jobID,
+			IsAssigned: isAssigned,
+		})
+	}
+	
+	return result, nil
+}
+
 // GetSubcategoriesByCategory gets all subcategories for a given category
 func (r *ProductRepository) GetSubcategoriesByCategory(categoryID uint, subcategories *[]models.Subcategory) error {
 	return r.db.Where("categoryID = ?", categoryID).Order("name ASC").Find(subcategories).Error
@@ -242,4 +285,14 @@ func (r *ProductRepository) GetAllBrands(brands *[]models.Brand) error {
 // GetAllManufacturers gets all manufacturers
 func (r *ProductRepository) GetAllManufacturers(manufacturers *[]models.Manufacturer) error {
 	return r.db.Order("name ASC").Find(manufacturers).Error
-}
\ No newline at end of file
+}
+
+// GetDocuments returns the manuals and spec sheets attached to a product
+func (r *ProductRepository) GetDocuments(productID uint) ([]models.Document, error) {
+	var documents []models.Document
+	err := r.db.Preload("Uploader").
+		Where("entity_type = ? AND entity_id = ?", "product", strconv.FormatUint(uint64(productID), 10)).
+		Order("uploaded_at DESC").
+		Find(&documents).Error
+	return documents, err
+}
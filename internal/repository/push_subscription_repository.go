@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"time"
+
+	"go-barcode-webapp/internal/models"
+)
+
+type PushSubscriptionRepository struct {
+	db *Database
+}
+
+func NewPushSubscriptionRepository(db *Database) *PushSubscriptionRepository {
+	return &PushSubscriptionRepository{db: db}
+}
+
+// GetActiveByUser returns the push subscriptions of a user that haven't been
+// unsubscribed or expired
+func (r *PushSubscriptionRepository) GetActiveByUser(userID uint) ([]models.PushSubscription, error) {
+	var subscriptions []models.PushSubscription
+	err := r.db.Where("userID = ? AND is_active = ?", userID, true).
+		Order("last_used DESC").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// MarkUsed records a successful delivery to the subscription
+func (r *PushSubscriptionRepository) MarkUsed(subscriptionID uint, at time.Time) error {
+	return r.db.Model(&models.PushSubscription{}).
+		Where("subscriptionID = ?", subscriptionID).
+		Update("last_used", at).Error
+}
+
+// Deactivate stops deliveries to a subscription the push service no longer
+// accepts. The row is kept so a later re-subscription of the same endpoint
+// reactivates it.
+func (r *PushSubscriptionRepository) Deactivate(subscriptionID uint) error {
+	return r.db.Model(&models.PushSubscription{}).
+		Where("subscriptionID = ?", subscriptionID).
+		Update("is_active", false).Error
+}
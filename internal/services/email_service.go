@@ -50,11 +50,31 @@ type EmailData struct {
 	InvoiceURL   string
 	PaymentURL   string
 	SupportEmail string
+
+	// RecipientEmail overrides the customer's email, e.g. with their accounting contact
+	RecipientEmail string
+
+	// Payment reminder details, only set for reminder emails
+	ReminderLevel int
+	ReminderTitle string
+	DaysOverdue   int
+}
+
+// recipient returns the address customer emails are sent to
+func (d *EmailData) recipient() string {
+	if d.RecipientEmail != "" {
+		return d.RecipientEmail
+	}
+	if d.Customer != nil && d.Customer.Email != nil {
+		return *d.Customer.Email
+	}
+	return ""
 }
 
 // SendInvoiceEmail sends an invoice via email
 func (s *EmailService) SendInvoiceEmail(emailData *EmailData, pdfAttachment []byte) error {
-	if emailData.Customer == nil || emailData.Customer.Email == nil || *emailData.Customer.Email == "" {
+	recipient := emailData.recipient()
+	if recipient == "" {
 		return fmt.Errorf("customer email not available")
 	}
 
@@ -76,7 +96,7 @@ func (s *EmailService) SendInvoiceEmail(emailData *EmailData, pdfAttachment []by
 
 	// Send email
 	return s.sendEmail(
-		[]string{*emailData.Customer.Email},
+		[]string{recipient},
 		subject,
 		textBody,
 		htmlBody,
@@ -85,6 +105,89 @@ func (s *EmailService) SendInvoiceEmail(emailData *EmailData, pdfAttachment []by
 	)
 }
 
+// SendPaymentReminderEmail sends a payment reminder for an overdue invoice
+func (s *EmailService) SendPaymentReminderEmail(emailData *EmailData) error {
+	recipient := emailData.recipient()
+	if recipient == "" {
+		return fmt.Errorf("customer email not available")
+	}
+
+	subject := fmt.Sprintf("%s: Invoice %s from %s",
+		emailData.ReminderTitle, emailData.Invoice.InvoiceNumber, emailData.Company.CompanyName)
+
+	textBody, err := s.renderTemplate("reminder_text", reminderTextTemplate, emailData)
+	if err != nil {
+		return fmt.Errorf("failed to generate reminder text: %v", err)
+	}
+
+	htmlBody, err := s.renderTemplate("reminder_html", reminderHTMLTemplate, emailData)
+	if err != nil {
+		return fmt.Errorf("failed to generate reminder HTML: %v", err)
+	}
+
+	return s.sendEmail([]string{recipient}, subject, textBody, htmlBody, nil, "")
+}
+
+const reminderTextTemplate = `{{.ReminderTitle}} - Invoice {{.Invoice.InvoiceNumber}}
+
+Dear {{.Customer.GetDisplayName}},
+
+{{if eq .ReminderLevel 3}}Despite our previous reminders, invoice {{.Invoice.InvoiceNumber}} remains unpaid. This is our final notice before we take further steps to collect the outstanding amount.{{else if eq .ReminderLevel 2}}We have not yet received payment for invoice {{.Invoice.InvoiceNumber}}, despite our earlier reminder.{{else}}Our records show that invoice {{.Invoice.InvoiceNumber}} has not been paid yet. Perhaps this has been overlooked.{{end}}
+
+- Invoice Number: {{.Invoice.InvoiceNumber}}
+- Due Date: {{.Invoice.DueDate.Format "January 2, 2006"}} ({{.DaysOverdue}} days overdue)
+- Amount Due: {{.Settings.CurrencySymbol}}{{printf "%.2f" .Invoice.BalanceDue}}
+
+Please transfer the amount due at your earliest convenience. If you have already paid, please disregard this message.
+
+Best regards,
+{{.Company.CompanyName}}
+{{if .Company.Email}}Email: {{.Company.Email}}
+{{end}}{{if .Company.Phone}}Phone: {{.Company.Phone}}
+{{end}}`
+
+const reminderHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>{{.ReminderTitle}} - Invoice {{.Invoice.InvoiceNumber}}</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h2 style="color: {{if eq .ReminderLevel 3}}#dc3545{{else}}#fd7e14{{end}};">{{.ReminderTitle}}</h2>
+
+        <p>Dear {{.Customer.GetDisplayName}},</p>
+
+        <p>{{if eq .ReminderLevel 3}}Despite our previous reminders, invoice <strong>{{.Invoice.InvoiceNumber}}</strong> remains unpaid. This is our final notice before we take further steps to collect the outstanding amount.{{else if eq .ReminderLevel 2}}We have not yet received payment for invoice <strong>{{.Invoice.InvoiceNumber}}</strong>, despite our earlier reminder.{{else}}Our records show that invoice <strong>{{.Invoice.InvoiceNumber}}</strong> has not been paid yet. Perhaps this has been overlooked.{{end}}</p>
+
+        <div style="background-color: #f8f9fa; border-left: 4px solid #fd7e14; padding: 15px; margin: 20px 0;">
+            <p style="margin: 0;"><strong>Invoice Number:</strong> {{.Invoice.InvoiceNumber}}</p>
+            <p style="margin: 0;"><strong>Due Date:</strong> {{.Invoice.DueDate.Format "January 2, 2006"}} ({{.DaysOverdue}} days overdue)</p>
+            <p style="margin: 0;"><strong>Amount Due:</strong> {{.Settings.CurrencySymbol}}{{printf "%.2f" .Invoice.BalanceDue}}</p>
+        </div>
+
+        <p>Please transfer the amount due at your earliest convenience. If you have already paid, please disregard this message.</p>
+
+        <p>Best regards,<br>{{.Company.CompanyName}}</p>
+    </div>
+</body>
+</html>`
+
+// renderTemplate executes an HTML-escaped email template
+func (s *EmailService) renderTemplate(name, text string, data *EmailData) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
 // SendTestEmail sends a test email
 func (s *EmailService) SendTestEmail(toEmail string, testData *EmailData) error {
 	subject := "Test Email from RentalCore Invoice System"
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/models"
+	"go-barcode-webapp/internal/repository"
+)
+
+// InvoiceReminderService sends escalating payment reminders for overdue invoices
+type InvoiceReminderService struct {
+	invoiceRepo   *repository.InvoiceRepositoryNew
+	customerRepo  *repository.CustomerRepository
+	intervals     []int  // Days after the due date for the first reminder, then between reminders
+	recipientRole string // Customer contact role reminders go to, e.g. accounting
+}
+
+func NewInvoiceReminderService(invoiceRepo *repository.InvoiceRepositoryNew, customerRepo *repository.CustomerRepository, intervals []int, recipientRole string) *InvoiceReminderService {
+	if len(intervals) == 0 {
+		intervals = []int{7, 14, 14}
+	}
+	return &InvoiceReminderService{
+		invoiceRepo:   invoiceRepo,
+		customerRepo:  customerRepo,
+		intervals:     intervals,
+		recipientRole: recipientRole,
+	}
+}
+
+// Start runs the reminder check once a day in a background goroutine
+func (s *InvoiceReminderService) Start() {
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+
+		for {
+			if sent, err := s.SendDueReminders(time.Now()); err != nil {
+				logger.Errorf("Invoice reminders: %v", err)
+			} else if sent > 0 {
+				logger.Infof("Invoice reminders: sent %d payment reminder(s)", sent)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// SendDueReminders sends the next reminder for every overdue invoice whose
+// interval has elapsed and returns how many were sent. Failures for a single
+// invoice are logged and do not stop the run.
+func (s *InvoiceReminderService) SendDueReminders(now time.Time) (int, error) {
+	invoices, err := s.invoiceRepo.GetOverdueInvoices(now)
+	if err != nil {
+		return 0, err
+	}
+	if len(invoices) == 0 {
+		return 0, nil
+	}
+
+	company, err := s.invoiceRepo.GetCompanySettings()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load company settings: %v", err)
+	}
+	settings, err := s.invoiceRepo.GetAllInvoiceSettings()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load invoice settings: %v", err)
+	}
+	emailService := NewEmailServiceFromCompany(company)
+
+	sent := 0
+	for i := range invoices {
+		invoice := &invoices[i]
+
+		level, due, err := s.nextReminder(invoice, now)
+		if err != nil {
+			logger.Errorf("Invoice reminders: invoice %s: %v", invoice.InvoiceNumber, err)
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		if invoice.Customer == nil {
+			logger.Warnf("Invoice reminders: invoice %s has no customer, skipping", invoice.InvoiceNumber)
+			continue
+		}
+		recipient := s.customerRepo.GetContactEmail(invoice.Customer, s.recipientRole)
+		if recipient == "" {
+			logger.Warnf("Invoice reminders: invoice %s has no customer email, skipping", invoice.InvoiceNumber)
+			continue
+		}
+
+		emailData := &EmailData{
+			Invoice:        invoice,
+			Company:        company,
+			Customer:       invoice.Customer,
+			Settings:       settings,
+			RecipientEmail: recipient,
+			ReminderLevel:  level,
+			ReminderTitle:  models.ReminderLevelName(level),
+			DaysOverdue:    int(now.Sub(invoice.DueDate).Hours() / 24),
+		}
+		if err := emailService.SendPaymentReminderEmail(emailData); err != nil {
+			logger.Errorf("Invoice reminders: failed to send %s for invoice %s: %v",
+				emailData.ReminderTitle, invoice.InvoiceNumber, err)
+			continue
+		}
+
+		reminder := &models.InvoiceReminder{
+			InvoiceID:     invoice.InvoiceID,
+			ReminderLevel: level,
+			SentTo:        recipient,
+			AmountDue:     invoice.BalanceDue,
+			SentAt:        now,
+		}
+		if err := s.invoiceRepo.RecordReminder(reminder); err != nil {
+			logger.Errorf("Invoice reminders: invoice %s: %v", invoice.InvoiceNumber, err)
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// nextReminder returns the next reminder level for the invoice and whether its interval has elapsed
+func (s *InvoiceReminderService) nextReminder(invoice *models.Invoice, now time.Time) (int, bool, error) {
+	latest, err := s.invoiceRepo.GetLatestReminder(invoice.InvoiceID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if latest == nil {
+		return models.ReminderLevelFirst, !now.Before(invoice.DueDate.AddDate(0, 0, s.intervalFor(models.ReminderLevelFirst))), nil
+	}
+	if latest.ReminderLevel >= models.ReminderLevelFinal {
+		return 0, false, nil
+	}
+
+	level := latest.ReminderLevel + 1
+	return level, !now.Before(latest.SentAt.AddDate(0, 0, s.intervalFor(level))), nil
+}
+
+// intervalFor returns the configured waiting period before a reminder level,
+// reusing the last interval if fewer were configured
+func (s *InvoiceReminderService) intervalFor(level int) int {
+	if level-1 < len(s.intervals) {
+		return s.intervals[level-1]
+	}
+	return s.intervals[len(s.intervals)-1]
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package services
+
+import "os/exec"
+
+// startInProcessGroup does nothing on systems without Unix process groups
+func startInProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command; processes it started may outlive it
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
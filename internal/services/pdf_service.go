@@ -2,25 +2,62 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 
 	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
 	"go-barcode-webapp/internal/models"
 
 	"github.com/jung-kurt/gofpdf"
 )
 
+// ErrPDFTimeout is returned when Chrome or wkhtmltopdf didn't finish within
+// the configured PDF timeout and was killed
+var ErrPDFTimeout = errors.New("PDF generation timed out")
+
+// defaultPDFTimeout bounds one Chrome or wkhtmltopdf run when pdf.timeout
+// isn't set
+const defaultPDFTimeout = 30 * time.Second
+
+// PDFMethodError records why one PDF generation method failed
+type PDFMethodError struct {
+	Method string
+	Err    error
+}
+
+func (e PDFMethodError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Method, e.Err)
+}
+
+// PDFGenerationError is returned when every PDF generation method failed
+type PDFGenerationError struct {
+	Failures []PDFMethodError
+}
+
+func (e *PDFGenerationError) Error() string {
+	reasons := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		reasons[i] = failure.Error()
+	}
+	return "all PDF generation methods failed (" + strings.Join(reasons, "; ") + ")"
+}
+
+// pdfEngineOrder is the order the "auto" generator tries the PDF engines in
+var pdfEngineOrder = []string{"chrome", "wkhtmltopdf", "gofpdf"}
+
 type PDFServiceNew struct {
 	tempDir   string
 	pdfConfig *config.PDFConfig
+	engines   []string // Engines GenerateInvoicePDF tries, in order
 }
 
 func NewPDFServiceNew(pdfConfig *config.PDFConfig) *PDFServiceNew {
@@ -28,19 +65,63 @@ func NewPDFServiceNew(pdfConfig *config.PDFConfig) *PDFServiceNew {
 	
 	// Ensure temp directory exists
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		log.Printf("Warning: Could not create PDF temp directory: %v", err)
+		logger.Warnf("Could not create PDF temp directory: %v", err)
 		tempDir = os.TempDir()
 	}
 
 	return &PDFServiceNew{
 		tempDir:   tempDir,
 		pdfConfig: pdfConfig,
+		engines:   pdfEngines(pdfConfig),
 	}
 }
 
+// pdfEngines returns the engines allowed by pdf.generator: "auto" (or empty)
+// for all of them in pdfEngineOrder, or one engine name or a comma-separated
+// list of them in the order to try. Unknown names are ignored with a warning.
+func pdfEngines(pdfConfig *config.PDFConfig) []string {
+	if pdfConfig == nil {
+		return pdfEngineOrder
+	}
+	generator := strings.ToLower(strings.TrimSpace(pdfConfig.Generator))
+	if generator == "" || generator == "auto" {
+		return pdfEngineOrder
+	}
+
+	var engines []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(generator, ",") {
+		name = strings.TrimSpace(name)
+		if name == "chromium" {
+			name = "chrome"
+		}
+		known := false
+		for _, engine := range pdfEngineOrder {
+			if name == engine {
+				known = true
+				break
+			}
+		}
+		if !known {
+			logger.Warnf("PDFServiceNew: ignoring unknown PDF generator %q, use auto, chrome, wkhtmltopdf or gofpdf", name)
+			continue
+		}
+		if !seen[name] {
+			seen[name] = true
+			engines = append(engines, name)
+		}
+	}
+
+	if len(engines) == 0 {
+		logger.Warnf("PDFServiceNew: no usable PDF generator in %q, using auto", pdfConfig.Generator)
+		return pdfEngineOrder
+	}
+	return engines
+}
+
 // GenerateInvoicePDF generates a PDF from an invoice with robust error handling
 func (s *PDFServiceNew) GenerateInvoicePDF(invoice *models.Invoice, company *models.CompanySettings, settings *models.InvoiceSettings) ([]byte, error) {
-	log.Printf("PDFServiceNew: Generating PDF for invoice %s", invoice.InvoiceNumber)
+	logger.Debugf("PDFServiceNew: Generating PDF for invoice %s", invoice.InvoiceNumber)
 
 	// Validate inputs
 	if invoice == nil {
@@ -53,35 +134,58 @@ func (s *PDFServiceNew) GenerateInvoicePDF(invoice *models.Invoice, company *mod
 		settings = s.getDefaultInvoiceSettings()
 	}
 
-	// Try multiple PDF generation methods in order of preference
-	methods := []struct {
+	// Try the configured PDF generation methods in order of preference
+	type pdfMethod struct {
 		name string
 		fn   func(*models.Invoice, *models.CompanySettings, *models.InvoiceSettings) ([]byte, error)
-	}{
-		{"Chrome/Chromium", s.generateWithChrome},
-		{"wkhtmltopdf", s.generateWithWKHTMLToPDF},
-		{"gofpdf", s.generateWithGofpdf},
+	}
+	available := map[string]pdfMethod{
+		"chrome":      {"Chrome/Chromium", s.generateWithChrome},
+		"wkhtmltopdf": {"wkhtmltopdf", s.generateWithWKHTMLToPDF},
+		"gofpdf":      {"gofpdf", s.generateWithGofpdf},
+	}
+	engines := s.engines
+	if len(engines) == 0 {
+		engines = pdfEngineOrder
 	}
 
-	var lastErr error
-	for _, method := range methods {
+	var failures []PDFMethodError
+	for _, engine := range engines {
+		method := available[engine]
 		pdfBytes, err := method.fn(invoice, company, settings)
-		if err == nil && len(pdfBytes) > 0 {
-			// Validate that it's actually PDF content
-			if len(pdfBytes) >= 4 && string(pdfBytes[:4]) == "%PDF" {
-				log.Printf("PDFServiceNew: Successfully generated PDF using %s (%d bytes)", method.name, len(pdfBytes))
+		if err == nil {
+			switch {
+			case len(pdfBytes) == 0:
+				err = fmt.Errorf("returned no content")
+			case len(pdfBytes) < 4 || string(pdfBytes[:4]) != "%PDF":
+				err = fmt.Errorf("returned invalid PDF content")
+			default:
+				logger.Debugf("PDFServiceNew: Successfully generated PDF using %s (%d bytes)", method.name, len(pdfBytes))
 				return pdfBytes, nil
-			} else {
-				log.Printf("PDFServiceNew: %s returned invalid PDF content, trying next method", method.name)
-				lastErr = fmt.Errorf("%s returned invalid PDF content", method.name)
-				continue
 			}
 		}
-		lastErr = err
-		log.Printf("PDFServiceNew: %s failed: %v", method.name, err)
+		failures = append(failures, PDFMethodError{Method: method.name, Err: err})
+		logger.Warnf("PDFServiceNew: %s failed for invoice %s, trying next method: %v", method.name, invoice.InvoiceNumber, err)
 	}
 
-	return nil, fmt.Errorf("all PDF generation methods failed, last error: %v", lastErr)
+	genErr := &PDFGenerationError{Failures: failures}
+	logger.Errorf("PDFServiceNew: invoice %s: %v", invoice.InvoiceNumber, genErr)
+	return nil, genErr
+}
+
+// GenerateInvoiceHTML renders the invoice as standalone HTML, the same document
+// the PDF engines print. Used as a download fallback when no engine works.
+func (s *PDFServiceNew) GenerateInvoiceHTML(invoice *models.Invoice, company *models.CompanySettings, settings *models.InvoiceSettings) (string, error) {
+	if invoice == nil {
+		return "", fmt.Errorf("invoice cannot be nil")
+	}
+	if company == nil {
+		company = s.getDefaultCompanySettings()
+	}
+	if settings == nil {
+		settings = s.getDefaultInvoiceSettings()
+	}
+	return s.generateInvoiceHTML(invoice, company, settings)
 }
 
 // generateWithChrome uses Chrome/Chromium headless for PDF generation
@@ -123,7 +227,7 @@ func (s *PDFServiceNew) generateWithChrome(invoice *models.Invoice, company *mod
 	}
 
 	// Execute Chrome headless
-	cmd := exec.Command(chromePath,
+	err = s.runPDFCommand(chromePath,
 		"--headless",
 		"--disable-gpu",
 		"--no-sandbox",
@@ -136,12 +240,8 @@ func (s *PDFServiceNew) generateWithChrome(invoice *models.Invoice, company *mod
 		"--print-to-pdf-no-header",
 		"--virtual-time-budget=5000",
 		"file://"+htmlFile)
-
-	// Set timeout
-	cmd.SysProcAttr = &syscall.SysProcAttr{}
-	
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("Chrome PDF generation failed: %v", err)
+	if err != nil {
+		return nil, fmt.Errorf("Chrome PDF generation failed: %w", err)
 	}
 
 	// Check if PDF file exists and has content
@@ -191,7 +291,7 @@ func (s *PDFServiceNew) generateWithWKHTMLToPDF(invoice *models.Invoice, company
 	}
 
 	// Execute wkhtmltopdf
-	cmd := exec.Command("wkhtmltopdf",
+	err = s.runPDFCommand("wkhtmltopdf",
 		"--page-size", "A4",
 		"--margin-top", "1cm",
 		"--margin-bottom", "1cm",
@@ -201,9 +301,8 @@ func (s *PDFServiceNew) generateWithWKHTMLToPDF(invoice *models.Invoice, company
 		"--disable-smart-shrinking",
 		"--print-media-type",
 		htmlFile, pdfFile)
-
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("wkhtmltopdf failed: %v", err)
+	if err != nil {
+		return nil, fmt.Errorf("wkhtmltopdf failed: %w", err)
 	}
 
 	// Check if PDF file exists
@@ -224,6 +323,35 @@ func (s *PDFServiceNew) generateWithWKHTMLToPDF(invoice *models.Invoice, company
 	return pdfBytes, nil
 }
 
+// pdfTimeout returns how long one Chrome or wkhtmltopdf run may take
+func (s *PDFServiceNew) pdfTimeout() time.Duration {
+	if s.pdfConfig != nil && s.pdfConfig.Timeout > 0 {
+		return time.Duration(s.pdfConfig.Timeout) * time.Second
+	}
+	return defaultPDFTimeout
+}
+
+// runPDFCommand runs an external PDF engine. When it exceeds the PDF timeout
+// it is killed together with the helper processes it started (Chrome's
+// renderers), and ErrPDFTimeout is returned so the next method is tried.
+func (s *PDFServiceNew) runPDFCommand(name string, args ...string) error {
+	timeout := s.pdfTimeout()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	startInProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrPDFTimeout, timeout)
+	}
+	return err
+}
+
 // generateWithGofpdf creates a PDF using the gofpdf library (fallback)
 func (s *PDFServiceNew) generateWithGofpdf(invoice *models.Invoice, company *models.CompanySettings, settings *models.InvoiceSettings) ([]byte, error) {
 	pdf := gofpdf.New("P", "mm", "A4", "")
@@ -242,6 +370,10 @@ func (s *PDFServiceNew) generateWithGofpdf(invoice *models.Invoice, company *mod
 		pdf.Cell(0, 5, *company.AddressLine1)
 		pdf.Ln(5)
 	}
+	if addressLine2 := getStringValue(company.AddressLine2); addressLine2 != "" {
+		pdf.Cell(0, 5, addressLine2)
+		pdf.Ln(5)
+	}
 	if company.City != nil || company.PostalCode != nil {
 		address := ""
 		if company.PostalCode != nil {
@@ -250,11 +382,18 @@ func (s *PDFServiceNew) generateWithGofpdf(invoice *models.Invoice, company *mod
 		if company.City != nil {
 			address += *company.City
 		}
+		if state := getStringValue(company.State); state != "" {
+			address += ", " + state
+		}
 		if address != "" {
 			pdf.Cell(0, 5, address)
 			pdf.Ln(5)
 		}
 	}
+	if country := getStringValue(company.Country); country != "" {
+		pdf.Cell(0, 5, country)
+		pdf.Ln(5)
+	}
 	if company.Phone != nil {
 		pdf.Cell(0, 5, "Phone: "+*company.Phone)
 		pdf.Ln(5)
@@ -425,11 +564,29 @@ func (s *PDFServiceNew) generateWithGofpdf(invoice *models.Invoice, company *mod
 	pdf.Ln(10)
 	pdf.SetFont("Arial", "I", 8)
 	pdf.SetTextColor(100, 100, 100)
-	footerText := fmt.Sprintf("Generated on %s", time.Now().Format("02.01.2006 15:04:05"))
-	if company.TaxNumber != nil {
-		footerText += fmt.Sprintf(" | Tax Number: %s", *company.TaxNumber)
+	if company.FooterText != nil && *company.FooterText != "" {
+		pdf.MultiCell(0, 4, *company.FooterText, "", "L", false)
+		pdf.Ln(4)
 	}
-	pdf.Cell(0, 5, footerText)
+	// Same company details as the footer of the HTML template
+	var companyDetails []string
+	if taxNumber := getStringValue(company.TaxNumber); taxNumber != "" {
+		companyDetails = append(companyDetails, "Tax Number: "+taxNumber)
+	}
+	if vatNumber := getStringValue(company.VATNumber); vatNumber != "" {
+		companyDetails = append(companyDetails, "VAT Number: "+vatNumber)
+	}
+	if email := getStringValue(company.Email); email != "" {
+		companyDetails = append(companyDetails, email)
+	}
+	if website := getStringValue(company.Website); website != "" {
+		companyDetails = append(companyDetails, website)
+	}
+	if len(companyDetails) > 0 {
+		pdf.MultiCell(0, 4, strings.Join(companyDetails, " | "), "", "L", false)
+		pdf.Ln(4)
+	}
+	pdf.Cell(0, 5, fmt.Sprintf("Generated on %s", time.Now().Format("02.01.2006 15:04:05")))
 
 	// Generate PDF bytes
 	var buf bytes.Buffer
@@ -448,9 +605,8 @@ func (s *PDFServiceNew) generateWithGofpdf(invoice *models.Invoice, company *mod
 	return pdfBytes, nil
 }
 
-// generateInvoiceHTML creates clean, professional HTML for the invoice
-func (s *PDFServiceNew) generateInvoiceHTML(invoice *models.Invoice, company *models.CompanySettings, settings *models.InvoiceSettings) (string, error) {
-	tmplContent := `<!DOCTYPE html>
+// invoiceHTMLTemplate is the document the PDF engines print
+const invoiceHTMLTemplate = `<!DOCTYPE html>
 <html>
 <head>
     <meta charset="UTF-8">
@@ -755,7 +911,7 @@ func (s *PDFServiceNew) generateInvoiceHTML(invoice *models.Invoice, company *mo
                     <td><strong>Tax ({{printf "%.1f" .Invoice.TaxRate}}%):</strong></td>
                     <td class="text-right">{{.Settings.CurrencySymbol}}{{printf "%.2f" .Invoice.TaxAmount}}</td>
                 </tr>
-                {{if gt .Invoice.DiscountAmount 0}}
+                {{if gt .Invoice.DiscountAmount 0.0}}
                 <tr>
                     <td><strong>Discount:</strong></td>
                     <td class="text-right">-{{.Settings.CurrencySymbol}}{{printf "%.2f" .Invoice.DiscountAmount}}</td>
@@ -777,8 +933,24 @@ func (s *PDFServiceNew) generateInvoiceHTML(invoice *models.Invoice, company *mo
     </div>
     {{end}}
 
+    <!-- Payment Instructions -->
+    {{if or .Invoice.PaymentTerms .Company.IBAN}}
+    <div style="margin-bottom: 30px;">
+        <h3>Payment:</h3>
+        <div class="address-box" style="font-size: 11px;">
+            {{if .Invoice.PaymentTerms}}{{.Invoice.PaymentTerms}}<br>{{end}}
+            {{if .Company.IBAN}}
+            {{if .Company.AccountHolder}}<strong>Account Holder:</strong> {{.Company.AccountHolder}}<br>{{end}}
+            {{if .Company.BankName}}<strong>Bank:</strong> {{.Company.BankName}}<br>{{end}}
+            <strong>IBAN:</strong> {{.Company.IBAN}}{{if .Company.BIC}} | <strong>BIC:</strong> {{.Company.BIC}}{{end}}
+            {{end}}
+        </div>
+    </div>
+    {{end}}
+
     <!-- Footer -->
     <div class="footer-info">
+        {{if .Company.FooterText}}{{.Company.FooterText}}<br><br>{{end}}
         {{if .Company.TaxNumber}}<strong>Tax Number:</strong> {{.Company.TaxNumber}} | {{end}}
         {{if .Company.VATNumber}}<strong>VAT Number:</strong> {{.Company.VATNumber}} | {{end}}
         {{if .Company.Email}}{{.Company.Email}} | {{end}}
@@ -789,8 +961,24 @@ func (s *PDFServiceNew) generateInvoiceHTML(invoice *models.Invoice, company *mo
 </body>
 </html>`
 
-	// Create template
-	tmpl, err := template.New("invoice").Parse(tmplContent)
+// The invoice template is parsed on first use and shared by all renders;
+// executing a parsed template is safe from several goroutines
+var (
+	invoiceTemplateOnce sync.Once
+	invoiceTemplate     *template.Template
+	invoiceTemplateErr  error
+)
+
+func parsedInvoiceTemplate() (*template.Template, error) {
+	invoiceTemplateOnce.Do(func() {
+		invoiceTemplate, invoiceTemplateErr = template.New("invoice").Parse(invoiceHTMLTemplate)
+	})
+	return invoiceTemplate, invoiceTemplateErr
+}
+
+// generateInvoiceHTML creates clean, professional HTML for the invoice
+func (s *PDFServiceNew) generateInvoiceHTML(invoice *models.Invoice, company *models.CompanySettings, settings *models.InvoiceSettings) (string, error) {
+	tmpl, err := parsedInvoiceTemplate()
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %v", err)
 	}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go-barcode-webapp/internal/models"
+)
+
+// generatedOn matches the render timestamp in the invoice footer
+var generatedOn = regexp.MustCompile(`Generated on [0-9.: ]+`)
+
+func testInvoice(number string, discount float64) *models.Invoice {
+	notes := "Thank you for your business"
+	return &models.Invoice{
+		InvoiceNumber:  number,
+		Status:         "draft",
+		IssueDate:      time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		DueDate:        time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC),
+		Subtotal:       200,
+		TaxRate:        19,
+		TaxAmount:      38,
+		DiscountAmount: discount,
+		TotalAmount:    238 - discount,
+		Notes:          &notes,
+		LineItems: []models.InvoiceLineItem{
+			{ItemType: "device", Description: "Speaker", Quantity: 2, UnitPrice: 50, TotalPrice: 100},
+			{ItemType: "service", Description: "Setup", Quantity: 1, UnitPrice: 100, TotalPrice: 100},
+		},
+	}
+}
+
+func renderInvoice(t *testing.T, s *PDFServiceNew, invoice *models.Invoice) string {
+	t.Helper()
+	html, err := s.GenerateInvoiceHTML(invoice, nil, nil)
+	if err != nil {
+		t.Fatalf("GenerateInvoiceHTML(%s): %v", invoice.InvoiceNumber, err)
+	}
+	return generatedOn.ReplaceAllString(html, "Generated on")
+}
+
+func TestGenerateInvoiceHTMLIsRepeatable(t *testing.T) {
+	s := &PDFServiceNew{}
+	invoice := testInvoice("INV-1001", 10)
+
+	first := renderInvoice(t, s, invoice)
+	second := renderInvoice(t, s, invoice)
+	if first != second {
+		t.Error("two renders of the same invoice differ")
+	}
+
+	if !strings.Contains(first, "INV-1001") {
+		t.Error("rendered invoice lacks its number")
+	}
+	if !strings.Contains(first, "-€10.00") {
+		t.Error("rendered invoice lacks its discount")
+	}
+}
+
+func TestGenerateInvoiceHTMLSharedTemplate(t *testing.T) {
+	s := &PDFServiceNew{}
+	invoice := testInvoice("INV-1001", 0)
+
+	before := renderInvoice(t, s, invoice)
+	other := renderInvoice(t, s, testInvoice("INV-2002", 25))
+	after := renderInvoice(t, s, invoice)
+
+	if before != after {
+		t.Error("rendering another invoice changed the output of the first")
+	}
+	if strings.Contains(before, "INV-2002") || !strings.Contains(other, "INV-2002") {
+		t.Error("invoice data leaked between renders")
+	}
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-barcode-webapp/internal/config"
+	"go-barcode-webapp/internal/logger"
+	"go-barcode-webapp/internal/models"
+	"go-barcode-webapp/internal/repository"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrPushSubscriptionGone is returned when the push service reports that a
+// subscription expired or was unsubscribed (404 or 410)
+var ErrPushSubscriptionGone = errors.New("push subscription is no longer valid")
+
+// pushTTL is how long push services keep an undelivered notification
+const pushTTL = 24 * time.Hour
+
+// pushRecordSize is the aes128gcm record size; notifications fit in one record
+const pushRecordSize = 4096
+
+// PushNotification is the payload the service worker receives
+type PushNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url,omitempty"`
+	Tag   string `json:"tag,omitempty"`
+}
+
+// PushService sends Web Push notifications (RFC 8030) with VAPID
+// authentication (RFC 8292) and aes128gcm payload encryption (RFC 8291)
+type PushService struct {
+	repo       *repository.PushSubscriptionRepository
+	client     *http.Client
+	publicKey  []byte // Uncompressed P-256 point
+	privateKey *ecdsa.PrivateKey
+	subject    string
+}
+
+func NewPushService(pushConfig *config.PushConfig, repo *repository.PushSubscriptionRepository) (*PushService, error) {
+	if pushConfig.VAPIDPublicKey == "" || pushConfig.VAPIDPrivateKey == "" {
+		return nil, errors.New("push notifications need vapid_public_key and vapid_private_key")
+	}
+
+	rawPrivate, err := decodePushKey(pushConfig.VAPIDPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %v", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(rawPrivate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %v", err)
+	}
+	publicKey := key.PublicKey().Bytes()
+
+	configured, err := decodePushKey(pushConfig.VAPIDPublicKey)
+	if err != nil || !bytes.Equal(configured, publicKey) {
+		return nil, errors.New("VAPID public key does not belong to the private key")
+	}
+
+	subject := pushConfig.Subject
+	if subject == "" {
+		subject = "mailto:noreply@rentalcore.com"
+	}
+
+	return &PushService{
+		repo:   repo,
+		client: &http.Client{Timeout: 15 * time.Second},
+		privateKey: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(publicKey[1:33]),
+				Y:     new(big.Int).SetBytes(publicKey[33:65]),
+			},
+			D: new(big.Int).SetBytes(rawPrivate),
+		},
+		publicKey: publicKey,
+		subject:   subject,
+	}, nil
+}
+
+// PublicKey returns the base64url VAPID public key browsers subscribe with
+// (the applicationServerKey of PushManager.subscribe)
+func (s *PushService) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(s.publicKey)
+}
+
+// SendToUser delivers a notification to every active subscription of the
+// user and returns how many accepted it. Subscriptions the push service
+// reports as gone are deactivated; other failures are logged.
+func (s *PushService) SendToUser(userID uint, notification PushNotification) (int, error) {
+	subscriptions, err := s.repo.GetActiveByUser(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load push subscriptions: %v", err)
+	}
+
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		err := s.Send(subscription, payload)
+		switch {
+		case err == nil:
+			delivered++
+			if err := s.repo.MarkUsed(subscription.SubscriptionID, time.Now()); err != nil {
+				logger.Warnf("Push: failed to update subscription %d: %v", subscription.SubscriptionID, err)
+			}
+		case errors.Is(err, ErrPushSubscriptionGone):
+			logger.Infof("Push: subscription %d of user %d expired, deactivating", subscription.SubscriptionID, userID)
+			if err := s.repo.Deactivate(subscription.SubscriptionID); err != nil {
+				logger.Errorf("Push: failed to deactivate subscription %d: %v", subscription.SubscriptionID, err)
+			}
+		default:
+			logger.Errorf("Push: delivery to subscription %d of user %d failed: %v", subscription.SubscriptionID, userID, err)
+		}
+	}
+
+	return delivered, nil
+}
+
+// Send encrypts the payload for one subscription and posts it to its push
+// service. It returns ErrPushSubscriptionGone for 404 and 410 responses.
+func (s *PushService) Send(subscription *models.PushSubscription, payload []byte) error {
+	endpoint, err := url.Parse(subscription.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return fmt.Errorf("invalid endpoint %q", subscription.Endpoint)
+	}
+
+	body, err := encryptPushPayload(subscription.KeysP256dh, subscription.KeysAuth, payload)
+	if err != nil {
+		return err
+	}
+
+	token, err := s.vapidToken(endpoint.Scheme + "://" + endpoint.Host)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", fmt.Sprintf("%d", int(pushTTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+	req.Header.Set("Authorization", fmt.Sprintf("vapid t=%s, k=%s", token, s.PublicKey()))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrPushSubscriptionGone
+	case resp.StatusCode >= 300:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push service answered %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// vapidToken returns the ES256 signed JWT identifying this server to the push
+// service of the audience
+func (s *PushService) vapidToken(audience string) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": audience,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": s.subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, sig, err := ecdsa.Sign(rand.Reader, s.privateKey, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ValidatePushKeys checks that the browser's subscription keys are a P-256
+// public key and a 16 byte authentication secret
+func ValidatePushKeys(p256dh, auth string) error {
+	userPublic, err := decodePushKey(p256dh)
+	if err != nil {
+		return fmt.Errorf("p256dh: %v", err)
+	}
+	if _, err := ecdh.P256().NewPublicKey(userPublic); err != nil {
+		return fmt.Errorf("p256dh is not a P-256 public key")
+	}
+	authSecret, err := decodePushKey(auth)
+	if err != nil {
+		return fmt.Errorf("auth: %v", err)
+	}
+	if len(authSecret) != 16 {
+		return fmt.Errorf("auth must be 16 bytes")
+	}
+	return nil
+}
+
+// encryptPushPayload encrypts the payload for the subscription keys as a
+// single aes128gcm record (RFC 8291)
+func encryptPushPayload(p256dh, auth string, payload []byte) ([]byte, error) {
+	if err := ValidatePushKeys(p256dh, auth); err != nil {
+		return nil, err
+	}
+	userPublicBytes, _ := decodePushKey(p256dh)
+	authSecret, _ := decodePushKey(auth)
+
+	// A fresh key pair and salt per message
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return sealPushRecord(userPublicBytes, authSecret, serverKey, salt, payload)
+}
+
+// sealPushRecord encrypts the payload with the given server key pair and salt
+func sealPushRecord(userPublicBytes, authSecret []byte, serverKey *ecdh.PrivateKey, salt, payload []byte) ([]byte, error) {
+	userPublic, err := ecdh.P256().NewPublicKey(userPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+	serverPublic := serverKey.PublicKey().Bytes()
+	sharedSecret, err := serverKey.ECDH(userPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), userPublicBytes...)
+	keyInfo = append(keyInfo, serverPublic...)
+	ikm, err := hkdfExpand(sharedSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	contentKey, err := hkdfExpand(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdfExpand(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// The 0x02 delimiter marks the last (and only) record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	if len(plaintext)+gcm.Overhead() > pushRecordSize {
+		return nil, fmt.Errorf("push payload of %d bytes is too large", len(payload))
+	}
+
+	header := make([]byte, 0, 16+4+1+len(serverPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, pushRecordSize)
+	header = append(header, byte(len(serverPublic)))
+	header = append(header, serverPublic...)
+
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+func hkdfExpand(secret, salt, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodePushKey decodes base64url keys, padded or not, as browsers and key
+// generators emit both
+func decodePushKey(key string) ([]byte, error) {
+	key = strings.TrimRight(strings.TrimSpace(key), "=")
+	key = strings.NewReplacer("+", "-", "/", "_").Replace(key)
+	return base64.RawURLEncoding.DecodeString(key)
+}
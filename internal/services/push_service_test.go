@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"go-barcode-webapp/internal/config"
+)
+
+// Test vectors of RFC 8291 Appendix A
+const (
+	rfc8291Plaintext     = "V2hlbiBJIGdyb3cgdXAsIEkgd2FudCB0byBiZSBhIHdhdGVybWVsb24"
+	rfc8291ServerPrivate = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfc8291ServerPublic  = "BP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A8"
+	rfc8291UserPrivate   = "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"
+	rfc8291UserPublic    = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfc8291Salt          = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfc8291AuthSecret    = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfc8291SharedSecret  = "kyrL1jIIOHEzg3sM2ZWRHDRB62YACZhhSlknJ672kSs"
+	rfc8291IKM           = "S4lYMb_L0FxCeq0WhDx813KgSYqU26kOyzWUdsXYyrg"
+	rfc8291ContentKey    = "oIhVW04MRdy2XN9CiKLxTg"
+	rfc8291Nonce         = "4h_95klXJ5E_qnoN"
+	rfc8291Message       = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func decodeTestKey(t *testing.T, key string) []byte {
+	t.Helper()
+	decoded, err := decodePushKey(key)
+	if err != nil {
+		t.Fatalf("decodePushKey(%q): %v", key, err)
+	}
+	return decoded
+}
+
+// openTestRecord decrypts a single aes128gcm record
+func openTestRecord(contentKey, nonce, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func TestHKDFExpandRFC8291(t *testing.T) {
+	salt := decodeTestKey(t, rfc8291Salt)
+	ikm := decodeTestKey(t, rfc8291IKM)
+	keyInfo := append([]byte("WebPush: info\x00"), decodeTestKey(t, rfc8291UserPublic)...)
+	keyInfo = append(keyInfo, decodeTestKey(t, rfc8291ServerPublic)...)
+
+	tests := []struct {
+		name   string
+		secret []byte
+		salt   []byte
+		info   []byte
+		length int
+		want   string
+	}{
+		{"IKM", decodeTestKey(t, rfc8291SharedSecret), decodeTestKey(t, rfc8291AuthSecret), keyInfo, 32, rfc8291IKM},
+		{"content key", ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16, rfc8291ContentKey},
+		{"nonce", ikm, salt, []byte("Content-Encoding: nonce\x00"), 12, rfc8291Nonce},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := hkdfExpand(tt.secret, tt.salt, tt.info, tt.length)
+			if err != nil {
+				t.Fatalf("hkdfExpand: %v", err)
+			}
+			if encoded := base64.RawURLEncoding.EncodeToString(got); encoded != tt.want {
+				t.Errorf("hkdfExpand = %s; want %s", encoded, tt.want)
+			}
+		})
+	}
+}
+
+func TestSealPushRecordRFC8291(t *testing.T) {
+	serverKey, err := ecdh.P256().NewPrivateKey(decodeTestKey(t, rfc8291ServerPrivate))
+	if err != nil {
+		t.Fatalf("server key: %v", err)
+	}
+
+	got, err := sealPushRecord(decodeTestKey(t, rfc8291UserPublic), decodeTestKey(t, rfc8291AuthSecret),
+		serverKey, decodeTestKey(t, rfc8291Salt), decodeTestKey(t, rfc8291Plaintext))
+	if err != nil {
+		t.Fatalf("sealPushRecord: %v", err)
+	}
+	if encoded := base64.RawURLEncoding.EncodeToString(got); encoded != rfc8291Message {
+		t.Errorf("sealPushRecord = %s; want %s", encoded, rfc8291Message)
+	}
+}
+
+func TestEncryptPushPayloadRoundTrip(t *testing.T) {
+	userKey, err := ecdh.P256().NewPrivateKey(decodeTestKey(t, rfc8291UserPrivate))
+	if err != nil {
+		t.Fatalf("user key: %v", err)
+	}
+	authSecret := decodeTestKey(t, rfc8291AuthSecret)
+	payload := []byte(`{"title":"Job 42","body":"Devices are due back today"}`)
+
+	message, err := encryptPushPayload(rfc8291UserPublic, rfc8291AuthSecret, payload)
+	if err != nil {
+		t.Fatalf("encryptPushPayload: %v", err)
+	}
+	if rs := binary.BigEndian.Uint32(message[16:20]); rs != pushRecordSize {
+		t.Fatalf("record size = %d; want %d", rs, pushRecordSize)
+	}
+	salt, keyLength := message[:16], int(message[20])
+	serverPublicBytes, ciphertext := message[21:21+keyLength], message[21+keyLength:]
+
+	// Decrypt as the browser does, deriving the keys from the user's side
+	serverPublic, err := ecdh.P256().NewPublicKey(serverPublicBytes)
+	if err != nil {
+		t.Fatalf("server public key: %v", err)
+	}
+	sharedSecret, err := userKey.ECDH(serverPublic)
+	if err != nil {
+		t.Fatalf("ECDH: %v", err)
+	}
+	keyInfo := append(append([]byte("WebPush: info\x00"), userKey.PublicKey().Bytes()...), serverPublicBytes...)
+	ikm, _ := hkdfExpand(sharedSecret, authSecret, keyInfo, 32)
+	contentKey, _ := hkdfExpand(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce, _ := hkdfExpand(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+
+	plaintext, err := openTestRecord(contentKey, nonce, ciphertext)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if want := append(append([]byte{}, payload...), 0x02); !bytes.Equal(plaintext, want) {
+		t.Errorf("decrypted = %q; want %q", plaintext, want)
+	}
+
+	if _, err := encryptPushPayload(rfc8291UserPublic, rfc8291AuthSecret, make([]byte, pushRecordSize)); err == nil {
+		t.Error("encryptPushPayload accepted a payload larger than one record")
+	}
+}
+
+func TestVAPIDTokenSignature(t *testing.T) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	s, err := NewPushService(&config.PushConfig{
+		VAPIDPublicKey:  base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		VAPIDPrivateKey: base64.RawURLEncoding.EncodeToString(key.Bytes()),
+		Subject:         "mailto:ops@example.com",
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewPushService: %v", err)
+	}
+
+	token, err := s.vapidToken("https://push.example.net")
+	if err != nil {
+		t.Fatalf("vapidToken: %v", err)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token has %d parts; want 3", len(parts))
+	}
+
+	header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if string(header) != `{"typ":"JWT","alg":"ES256"}` {
+		t.Errorf("header = %s", header)
+	}
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	rawClaims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(rawClaims, &claims); err != nil {
+		t.Fatalf("claims: %v", err)
+	}
+	if claims.Aud != "https://push.example.net" || claims.Sub != "mailto:ops@example.com" {
+		t.Errorf("claims = %+v", claims)
+	}
+	// RFC 8292 allows at most 24 hours
+	if expires := time.Until(time.Unix(claims.Exp, 0)); expires <= 0 || expires > 24*time.Hour {
+		t.Errorf("token expires in %v", expires)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		t.Fatalf("signature is %d bytes (%v); want 64", len(signature), err)
+	}
+	publicBytes := key.PublicKey().Bytes()
+	publicKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(publicBytes[1:33]),
+		Y:     new(big.Int).SetBytes(publicBytes[33:65]),
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, sig := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(publicKey, digest[:], r, sig) {
+		t.Error("token signature does not verify with the VAPID public key")
+	}
+	tampered := sha256.Sum256([]byte(parts[0] + "." + parts[1] + "x"))
+	if ecdsa.Verify(publicKey, tampered[:], r, sig) {
+		t.Error("token signature verifies a different signing input")
+	}
+}
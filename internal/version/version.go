@@ -0,0 +1,42 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Build information, set at build time via
+//   -ldflags "-X go-barcode-webapp/internal/version.Version=... -X go-barcode-webapp/internal/version.Commit=... -X go-barcode-webapp/internal/version.BuildDate=..."
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// SchemaVersion is the database migration this binary expects to have been
+// applied. Bump it together with every new migration in migrations/, which
+// must also record itself in the schema_migrations table.
+const SchemaVersion = 48
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// String formats the build information for startup logs
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", i.Version, i.Commit, i.BuildDate, i.GoVersion)
+}
@@ -0,0 +1,5 @@
+-- Rollback migration 025: Remove consumable flag from products
+
+ALTER TABLE `products`
+DROP INDEX IF EXISTS `idx_products_is_consumable`,
+DROP COLUMN IF EXISTS `is_consumable`;
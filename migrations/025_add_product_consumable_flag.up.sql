@@ -0,0 +1,7 @@
+-- Migration 025: Add consumable flag to products
+-- Consumable products (cables, gels, ...) are not tracked per unit, so their
+-- devices are excluded from availability conflict blocking.
+
+ALTER TABLE `products`
+ADD COLUMN `is_consumable` BOOLEAN NOT NULL DEFAULT FALSE AFTER `pos_in_category`,
+ADD INDEX `idx_products_is_consumable` (`is_consumable`);
@@ -0,0 +1,2 @@
+-- Drop pricing_calendars table
+DROP TABLE IF EXISTS pricing_calendars;
@@ -0,0 +1,21 @@
+-- Seasonal pricing calendars: date ranges that adjust product day rates
+CREATE TABLE pricing_calendars (
+    calendar_id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    product_id INT DEFAULT NULL,
+    category_id INT DEFAULT NULL,
+    multiplier DECIMAL(6,3) DEFAULT NULL,
+    override_rate DECIMAL(12,2) DEFAULT NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by BIGINT UNSIGNED DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (product_id) REFERENCES products(productID) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(categoryID) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(userID) ON DELETE SET NULL,
+    INDEX idx_pricing_calendars_dates (is_active, start_date, end_date),
+    INDEX idx_pricing_calendars_product (product_id),
+    INDEX idx_pricing_calendars_category (category_id)
+);
@@ -0,0 +1,4 @@
+-- Remove product documents before narrowing the enum
+DELETE FROM documents WHERE entity_type = 'product';
+ALTER TABLE documents
+    MODIFY entity_type enum('job', 'device', 'customer', 'user', 'system') NOT NULL;
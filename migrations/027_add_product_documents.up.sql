@@ -0,0 +1,3 @@
+-- Allow documents (manuals, spec sheets) to be attached to products
+ALTER TABLE documents
+    MODIFY entity_type enum('job', 'device', 'customer', 'user', 'system', 'product') NOT NULL;
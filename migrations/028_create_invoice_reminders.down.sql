@@ -0,0 +1,2 @@
+-- Drop invoice_reminders table
+DROP TABLE IF EXISTS invoice_reminders;
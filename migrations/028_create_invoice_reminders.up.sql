@@ -0,0 +1,12 @@
+-- Track payment reminders sent for overdue invoices
+CREATE TABLE invoice_reminders (
+    reminder_id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    invoice_id BIGINT UNSIGNED NOT NULL,
+    reminder_level TINYINT NOT NULL,
+    sent_to VARCHAR(255) NOT NULL,
+    amount_due DECIMAL(12,2) NOT NULL DEFAULT 0.00,
+    sent_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(invoice_id) ON DELETE CASCADE,
+    UNIQUE KEY uk_invoice_reminders_level (invoice_id, reminder_level),
+    INDEX idx_invoice_reminders_sent_at (sent_at)
+);
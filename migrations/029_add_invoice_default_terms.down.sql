@@ -0,0 +1,2 @@
+-- Remove default invoice terms settings
+DELETE FROM `invoice_settings` WHERE `setting_key` IN ('default_terms_conditions', 'payment_instructions');
@@ -0,0 +1,4 @@
+-- Default terms and payment instructions applied to invoices that don't specify their own
+INSERT IGNORE INTO `invoice_settings` (`setting_key`, `setting_value`, `setting_type`, `description`) VALUES
+('default_terms_conditions', '', 'text', 'Default terms and conditions for new invoices'),
+('payment_instructions', '', 'text', 'Default payment instructions for new invoices');
@@ -0,0 +1,2 @@
+-- Drop schema_migrations table
+DROP TABLE IF EXISTS schema_migrations;
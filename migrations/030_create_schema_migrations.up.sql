@@ -0,0 +1,8 @@
+-- Track applied migrations so the server can tell when the schema is behind the binary.
+-- Every later migration records its own version here.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INT UNSIGNED NOT NULL PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (30);
@@ -0,0 +1,4 @@
+-- Drop customer_contacts table
+DROP TABLE IF EXISTS customer_contacts;
+
+DELETE FROM schema_migrations WHERE version = 31;
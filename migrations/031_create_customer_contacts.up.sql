@@ -0,0 +1,15 @@
+-- Contact persons per customer (booker, accountant, on-site contact, ...)
+CREATE TABLE customer_contacts (
+    contact_id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    customer_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    role ENUM('booking', 'accounting', 'on_site', 'other') NOT NULL DEFAULT 'other',
+    email VARCHAR(255) NULL,
+    phone VARCHAR(50) NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (customer_id) REFERENCES customers(customerID) ON DELETE CASCADE,
+    INDEX idx_customer_contacts_customer_role (customer_id, role)
+);
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (31);
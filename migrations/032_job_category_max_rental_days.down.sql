@@ -0,0 +1,5 @@
+-- Remove per-category maximum rental duration
+ALTER TABLE jobCategory
+    DROP COLUMN max_rental_days;
+
+DELETE FROM schema_migrations WHERE version = 32;
@@ -0,0 +1,5 @@
+-- Optional per-category maximum rental duration (overrides the global jobs.max_rental_days)
+ALTER TABLE jobCategory
+    ADD COLUMN max_rental_days INT UNSIGNED NULL;
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (32);
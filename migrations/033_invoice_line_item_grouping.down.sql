@@ -0,0 +1,4 @@
+-- Remove line item grouping setting
+DELETE FROM `invoice_settings` WHERE `setting_key` = 'line_item_grouping';
+
+DELETE FROM schema_migrations WHERE version = 33;
@@ -0,0 +1,5 @@
+-- How job devices are aggregated into invoice lines: per product (default) or per device
+INSERT IGNORE INTO `invoice_settings` (`setting_key`, `setting_value`, `setting_type`, `description`) VALUES
+('line_item_grouping', 'product', 'text', 'Invoice lines from jobs: one per product (product) or one per device with serial (device)');
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (33);
@@ -0,0 +1,5 @@
+-- Remove persistent session flag
+ALTER TABLE sessions
+    DROP COLUMN persistent;
+
+DELETE FROM schema_migrations WHERE version = 34;
@@ -0,0 +1,5 @@
+-- Mark "remember me" sessions whose cookie outlives the browser session
+ALTER TABLE sessions
+    ADD COLUMN persistent BOOLEAN NOT NULL DEFAULT FALSE;
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (34);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS category_utilization_targets;
+
+DELETE FROM schema_migrations WHERE version = 35;
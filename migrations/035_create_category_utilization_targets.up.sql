@@ -0,0 +1,12 @@
+-- Target utilization per equipment category, used by the utilization report
+CREATE TABLE category_utilization_targets (
+    category_id INT NOT NULL PRIMARY KEY,
+    target_percent DECIMAL(5,2) NOT NULL,
+    updated_by BIGINT UNSIGNED DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (category_id) REFERENCES categories(categoryID) ON DELETE CASCADE,
+    FOREIGN KEY (updated_by) REFERENCES users(userID) ON DELETE SET NULL
+);
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (35);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS damage_report_photos;
+DROP TABLE IF EXISTS damage_reports;
+
+DELETE FROM schema_migrations WHERE version = 36;
@@ -0,0 +1,34 @@
+-- Damage reports for devices, optionally tied to the job during which the damage occurred
+CREATE TABLE damage_reports (
+    report_id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    device_id VARCHAR(50) NOT NULL,
+    job_id INT DEFAULT NULL,
+    description TEXT NOT NULL,
+    severity ENUM('minor', 'moderate', 'major', 'critical') NOT NULL DEFAULT 'minor',
+    out_of_service BOOLEAN NOT NULL DEFAULT FALSE,
+    previous_device_status VARCHAR(50) DEFAULT NULL,
+    status ENUM('open', 'resolved') NOT NULL DEFAULT 'open',
+    resolution_notes TEXT DEFAULT NULL,
+    reported_by BIGINT UNSIGNED DEFAULT NULL,
+    resolved_by BIGINT UNSIGNED DEFAULT NULL,
+    resolved_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (device_id) REFERENCES devices(deviceID) ON DELETE CASCADE,
+    FOREIGN KEY (job_id) REFERENCES jobs(jobID) ON DELETE SET NULL,
+    FOREIGN KEY (reported_by) REFERENCES users(userID) ON DELETE SET NULL,
+    FOREIGN KEY (resolved_by) REFERENCES users(userID) ON DELETE SET NULL,
+    INDEX idx_damage_reports_device_status (device_id, status),
+    INDEX idx_damage_reports_status (status, created_at)
+);
+
+-- Photos attached to a damage report, stored as documents
+CREATE TABLE damage_report_photos (
+    report_id INT UNSIGNED NOT NULL,
+    document_id INT NOT NULL,
+    PRIMARY KEY (report_id, document_id),
+    FOREIGN KEY (report_id) REFERENCES damage_reports(report_id) ON DELETE CASCADE,
+    FOREIGN KEY (document_id) REFERENCES documents(documentID) ON DELETE CASCADE
+);
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (36);
@@ -0,0 +1,6 @@
+-- Remove uploaded invoice PDFs before narrowing the enum
+DELETE FROM documents WHERE entity_type = 'invoice';
+ALTER TABLE documents
+    MODIFY entity_type enum('job', 'device', 'customer', 'user', 'system', 'product') NOT NULL;
+
+DELETE FROM schema_migrations WHERE version = 37;
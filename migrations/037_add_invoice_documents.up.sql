@@ -0,0 +1,5 @@
+-- Allow final invoice PDFs edited outside RentalCore to be stored against invoices
+ALTER TABLE documents
+    MODIFY entity_type enum('job', 'device', 'customer', 'user', 'system', 'product', 'invoice') NOT NULL;
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (37);
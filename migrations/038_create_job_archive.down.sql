@@ -0,0 +1,6 @@
+-- Restore archived jobs through the API before rolling back, otherwise they are lost
+DROP TABLE IF EXISTS job_archive;
+
+DELETE FROM analytics_cache WHERE metric_name IN ('archived_job_revenue', 'archived_jobs');
+
+DELETE FROM schema_migrations WHERE version = 38;
@@ -0,0 +1,20 @@
+-- Completed jobs moved out of the operational tables by the retention policy.
+-- snapshot holds the job with its devices, pack events, rental equipment and
+-- attachment records so it can be restored unchanged.
+CREATE TABLE IF NOT EXISTS job_archive (
+    job_id INT NOT NULL PRIMARY KEY,
+    customer_id INT NOT NULL,
+    status_id INT NOT NULL,
+    start_date DATE NULL,
+    end_date DATE NULL,
+    revenue DECIMAL(12,2) NOT NULL DEFAULT 0.00,
+    device_count INT NOT NULL DEFAULT 0,
+    snapshot JSON NOT NULL,
+    archived_by BIGINT UNSIGNED NULL,
+    archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (archived_by) REFERENCES users(userID) ON DELETE SET NULL,
+    INDEX idx_job_archive_customer (customer_id),
+    INDEX idx_job_archive_end_date (end_date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (38);
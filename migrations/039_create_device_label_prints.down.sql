@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS device_label_prints;
+
+DELETE FROM schema_migrations WHERE version = 39;
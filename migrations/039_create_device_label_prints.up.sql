@@ -0,0 +1,14 @@
+-- Every time a device label (barcode/QR) is generated for printing
+CREATE TABLE device_label_prints (
+    print_id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    device_id VARCHAR(50) NOT NULL,
+    printed_by BIGINT UNSIGNED DEFAULT NULL,
+    output_format VARCHAR(10) NOT NULL,
+    label_format VARCHAR(20) NOT NULL,
+    printed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (device_id) REFERENCES devices(deviceID) ON DELETE CASCADE,
+    FOREIGN KEY (printed_by) REFERENCES users(userID) ON DELETE SET NULL,
+    INDEX idx_device_label_prints_device (device_id, printed_at)
+);
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (39);
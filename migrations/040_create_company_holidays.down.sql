@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS company_holidays;
+
+DELETE FROM schema_migrations WHERE version = 40;
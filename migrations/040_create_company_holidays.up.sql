@@ -0,0 +1,11 @@
+-- Days not charged when rental days are counted as business days
+CREATE TABLE company_holidays (
+    holiday_id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    holiday_date DATE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uniq_company_holidays_date (holiday_date)
+);
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (40);
@@ -0,0 +1,5 @@
+ALTER TABLE products
+    DROP COLUMN deposit_amount,
+    DROP COLUMN deposit_percent;
+
+DELETE FROM schema_migrations WHERE version = 41;
@@ -0,0 +1,6 @@
+-- Deposit required per device of a product, as a fixed amount or a percentage of its rental price
+ALTER TABLE products
+    ADD COLUMN deposit_amount DECIMAL(12,2) NULL DEFAULT NULL,
+    ADD COLUMN deposit_percent DECIMAL(5,2) NULL DEFAULT NULL;
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (41);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS invoice_billed_periods;
+
+DELETE FROM schema_migrations WHERE version = 42;
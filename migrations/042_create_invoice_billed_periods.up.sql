@@ -0,0 +1,16 @@
+-- Device-days of a job already put on an invoice, so later invoices for the
+-- same job don't bill them again. job_id has no foreign key so the records
+-- survive archiving the job.
+CREATE TABLE invoice_billed_periods (
+    billed_period_id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    invoice_id BIGINT UNSIGNED NOT NULL,
+    job_id INT NOT NULL,
+    device_id VARCHAR(50) NOT NULL,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (invoice_id) REFERENCES invoices(invoice_id) ON DELETE CASCADE,
+    INDEX idx_invoice_billed_periods_job_device (job_id, device_id)
+);
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (42);
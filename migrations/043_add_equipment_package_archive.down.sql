@@ -0,0 +1,6 @@
+ALTER TABLE equipment_packages
+    DROP INDEX idx_equipment_packages_archived,
+    DROP COLUMN is_archived,
+    DROP COLUMN archived_at;
+
+DELETE FROM schema_migrations WHERE version = 43;
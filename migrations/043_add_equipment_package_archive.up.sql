@@ -0,0 +1,7 @@
+-- Deleted equipment packages are archived instead of removed so jobs that used them keep their history
+ALTER TABLE equipment_packages
+    ADD COLUMN is_archived BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN archived_at DATETIME NULL DEFAULT NULL,
+    ADD INDEX idx_equipment_packages_archived (is_archived);
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (43);
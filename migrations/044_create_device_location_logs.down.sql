@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS device_location_logs;
+
+DELETE FROM schema_migrations WHERE version = 44;
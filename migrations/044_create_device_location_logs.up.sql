@@ -0,0 +1,17 @@
+-- Trail of where a device was reported to be, newest entry matching devices.current_location
+CREATE TABLE device_location_logs (
+    log_id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    device_id VARCHAR(50) NOT NULL,
+    location VARCHAR(100) NOT NULL,
+    previous_location VARCHAR(100) DEFAULT NULL,
+    gps_latitude DECIMAL(10,8) DEFAULT NULL,
+    gps_longitude DECIMAL(11,8) DEFAULT NULL,
+    moved_by BIGINT UNSIGNED DEFAULT NULL,
+    notes TEXT,
+    moved_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (device_id) REFERENCES devices(deviceID) ON DELETE CASCADE,
+    FOREIGN KEY (moved_by) REFERENCES users(userID) ON DELETE SET NULL,
+    INDEX idx_device_location_logs_device (device_id, moved_at)
+);
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (44);
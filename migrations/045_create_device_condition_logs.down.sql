@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS device_condition_logs;
+
+DELETE FROM schema_migrations WHERE version = 45;
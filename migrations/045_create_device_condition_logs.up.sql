@@ -0,0 +1,15 @@
+-- Every condition rating given to a device, for charting its condition over time
+CREATE TABLE device_condition_logs (
+    log_id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    device_id VARCHAR(50) NOT NULL,
+    rating DECIMAL(3,1) NOT NULL,
+    previous_rating DECIMAL(3,1) DEFAULT NULL,
+    notes TEXT,
+    rated_by BIGINT UNSIGNED DEFAULT NULL,
+    rated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (device_id) REFERENCES devices(deviceID) ON DELETE CASCADE,
+    FOREIGN KEY (rated_by) REFERENCES users(userID) ON DELETE SET NULL,
+    INDEX idx_device_condition_logs_device (device_id, rated_at)
+);
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (45);
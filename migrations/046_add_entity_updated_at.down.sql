@@ -0,0 +1,5 @@
+ALTER TABLE jobs DROP COLUMN updated_at;
+ALTER TABLE devices DROP COLUMN updated_at;
+ALTER TABLE customers DROP COLUMN updated_at;
+
+DELETE FROM schema_migrations WHERE version = 46;
@@ -0,0 +1,35 @@
+-- Last change of jobs, devices and customers, used to detect conflicting offline edits.
+-- Some installations already have updated_at on these tables, so each column
+-- is only added where it is missing.
+DROP PROCEDURE IF EXISTS add_entity_updated_at;
+
+DELIMITER $$
+
+CREATE PROCEDURE add_entity_updated_at()
+BEGIN
+    SET @jobs_exists = (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'jobs' AND COLUMN_NAME = 'updated_at');
+    SET @sql_jobs = IF(@jobs_exists = 0, 'ALTER TABLE `jobs` ADD COLUMN `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP', 'SELECT "jobs.updated_at already exists, skipping."');
+    PREPARE stmt_jobs FROM @sql_jobs;
+    EXECUTE stmt_jobs;
+    DEALLOCATE PREPARE stmt_jobs;
+
+    SET @devices_exists = (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'devices' AND COLUMN_NAME = 'updated_at');
+    SET @sql_devices = IF(@devices_exists = 0, 'ALTER TABLE `devices` ADD COLUMN `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP', 'SELECT "devices.updated_at already exists, skipping."');
+    PREPARE stmt_devices FROM @sql_devices;
+    EXECUTE stmt_devices;
+    DEALLOCATE PREPARE stmt_devices;
+
+    SET @customers_exists = (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'customers' AND COLUMN_NAME = 'updated_at');
+    SET @sql_customers = IF(@customers_exists = 0, 'ALTER TABLE `customers` ADD COLUMN `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP', 'SELECT "customers.updated_at already exists, skipping."');
+    PREPARE stmt_customers FROM @sql_customers;
+    EXECUTE stmt_customers;
+    DEALLOCATE PREPARE stmt_customers;
+END$$
+
+DELIMITER ;
+
+CALL add_entity_updated_at();
+
+DROP PROCEDURE add_entity_updated_at;
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (46);
@@ -0,0 +1,6 @@
+ALTER TABLE sessions
+    DROP COLUMN user_agent,
+    DROP COLUMN ip_address,
+    DROP COLUMN last_seen;
+
+DELETE FROM schema_migrations WHERE version = 47;
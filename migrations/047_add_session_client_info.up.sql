@@ -0,0 +1,7 @@
+-- Client a session was started from, shown in the session list of the profile
+ALTER TABLE sessions
+    ADD COLUMN user_agent VARCHAR(512) NULL,
+    ADD COLUMN ip_address VARCHAR(45) NULL,
+    ADD COLUMN last_seen DATETIME NULL;
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (47);
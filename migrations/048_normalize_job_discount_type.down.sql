@@ -0,0 +1,4 @@
+ALTER TABLE jobs
+    MODIFY COLUMN discount_type ENUM('percent', 'amount') NULL DEFAULT 'amount';
+
+DELETE FROM schema_migrations WHERE version = 48;
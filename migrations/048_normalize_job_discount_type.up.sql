@@ -0,0 +1,12 @@
+-- Jobs saved before discount types were validated may have no discount type.
+-- Revenue has always treated those discounts as fixed amounts, so they become
+-- 'amount' and the column no longer accepts NULL. updated_at is kept so the
+-- change doesn't look like an edit to offline sync.
+UPDATE jobs
+SET discount_type = 'amount', updated_at = updated_at
+WHERE discount_type IS NULL OR discount_type NOT IN ('percent', 'amount');
+
+ALTER TABLE jobs
+    MODIFY COLUMN discount_type ENUM('percent', 'amount') NOT NULL DEFAULT 'amount';
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (48);
@@ -0,0 +1,7 @@
+UPDATE offline_sync_queue SET synced = TRUE WHERE status IN ('failed', 'conflict');
+
+ALTER TABLE offline_sync_queue
+    DROP INDEX idx_user_status,
+    DROP COLUMN status;
+
+DELETE FROM schema_migrations WHERE version = 49;
@@ -0,0 +1,17 @@
+-- Offline sync queue items that were given up after repeated failures or that
+-- conflicted with a server change used to be marked synced. They get their own
+-- status so only replayed actions count as synced.
+ALTER TABLE offline_sync_queue
+    ADD COLUMN status ENUM('pending', 'synced', 'failed', 'conflict') NOT NULL DEFAULT 'pending' AFTER synced,
+    ADD INDEX idx_user_status (userID, status);
+
+UPDATE offline_sync_queue
+SET status = CASE
+        WHEN error_message LIKE 'conflict: %' THEN 'conflict'
+        WHEN error_message LIKE 'failed after %' THEN 'failed'
+        ELSE 'synced'
+    END,
+    synced = (error_message IS NULL OR error_message = '')
+WHERE synced = TRUE;
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (49);
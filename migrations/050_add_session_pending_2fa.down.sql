@@ -0,0 +1,4 @@
+ALTER TABLE sessions
+    DROP COLUMN pending_2fa;
+
+DELETE FROM schema_migrations WHERE version = 50;
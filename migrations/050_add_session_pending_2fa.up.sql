@@ -0,0 +1,6 @@
+-- Short-lived sessions that wait for the second factor of a login. They are
+-- not listed among a user's sessions and do not authenticate requests.
+ALTER TABLE sessions
+    ADD COLUMN pending_2fa BOOLEAN NOT NULL DEFAULT FALSE AFTER persistent;
+
+INSERT IGNORE INTO schema_migrations (version) VALUES (50);
@@ -0,0 +1,83 @@
+/*
+ * CSRF protection for forms and scripts
+ * Sends the csrf_token cookie set by the server with every form post and
+ * every same-origin fetch or XMLHttpRequest that changes data.
+ */
+(function () {
+    const FIELD_NAME = 'csrf_token';
+    const HEADER_NAME = 'X-CSRF-Token';
+    const SAFE_METHODS = ['GET', 'HEAD', 'OPTIONS'];
+
+    function csrfToken() {
+        const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]*)/);
+        return match ? decodeURIComponent(match[1]) : '';
+    }
+
+    function isSameOrigin(url) {
+        try {
+            return new URL(url, window.location.href).origin === window.location.origin;
+        } catch (e) {
+            return false;
+        }
+    }
+
+    function addTokenField(form) {
+        if ((form.getAttribute('method') || 'GET').toUpperCase() !== 'POST' || !isSameOrigin(form.action)) {
+            return;
+        }
+        let field = form.querySelector('input[name="' + FIELD_NAME + '"]');
+        if (!field) {
+            field = document.createElement('input');
+            field.type = 'hidden';
+            field.name = FIELD_NAME;
+            form.appendChild(field);
+        }
+        field.value = csrfToken();
+    }
+
+    // Forms: add the hidden field right before they are submitted
+    document.addEventListener('submit', function (event) {
+        if (event.target instanceof HTMLFormElement) {
+            addTokenField(event.target);
+        }
+    }, true);
+
+    // form.submit() skips the submit event, so add the field there as well
+    const originalSubmit = HTMLFormElement.prototype.submit;
+    HTMLFormElement.prototype.submit = function () {
+        addTokenField(this);
+        return originalSubmit.apply(this, arguments);
+    };
+
+    // fetch: add the header to same-origin requests that change data
+    if (window.fetch) {
+        const originalFetch = window.fetch;
+        window.fetch = function (input, init) {
+            const url = input instanceof Request ? input.url : String(input);
+            const method = ((init && init.method) || (input instanceof Request ? input.method : 'GET')).toUpperCase();
+            if (SAFE_METHODS.indexOf(method) === -1 && isSameOrigin(url)) {
+                init = Object.assign({}, init);
+                const headers = new Headers(init.headers || (input instanceof Request ? input.headers : undefined));
+                if (!headers.has(HEADER_NAME)) {
+                    headers.set(HEADER_NAME, csrfToken());
+                }
+                init.headers = headers;
+            }
+            return originalFetch.call(this, input, init);
+        };
+    }
+
+    // XMLHttpRequest: same for older scripts
+    const originalOpen = XMLHttpRequest.prototype.open;
+    const originalSend = XMLHttpRequest.prototype.send;
+    XMLHttpRequest.prototype.open = function (method, url) {
+        this._csrfNeeded = SAFE_METHODS.indexOf(String(method).toUpperCase()) === -1 && isSameOrigin(url);
+        return originalOpen.apply(this, arguments);
+    };
+    XMLHttpRequest.prototype.send = function () {
+        if (this._csrfNeeded) {
+            this.setRequestHeader(HEADER_NAME, csrfToken());
+        }
+        return originalSend.apply(this, arguments);
+    };
+})();